@@ -1,59 +1,135 @@
 package api
 
 import (
+	"bytes"
+	"context"
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"infinitrain/internal/clock"
 	"infinitrain/internal/config"
 	"infinitrain/internal/scheduler"
 	"infinitrain/pkg/job"
+	"io"
+	"log/slog"
+	"mime"
 	"net/http"
+	"net/url"
+	"os"
+	"sort"
 	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"gopkg.in/yaml.v3"
 )
 
 // Server holds the API server dependencies
 type Server struct {
 	config  *config.Config
 	store   job.Store
+	queue   job.Queue
 	manager job.JobManager
 	workers job.WorkerRegistry
+	metrics *prometheus.Registry
+	http    *httpMetrics
+	jobs    *jobMetrics
+	logger  *slog.Logger
+	limiter *rateLimiter // Nil when rate limiting is off
 }
 
 // NewServer creates a new API server
-func NewServer(cfg *config.Config, store job.Store, manager job.JobManager, workers job.WorkerRegistry) *Server {
+func NewServer(cfg *config.Config, store job.Store, queue job.Queue, manager job.JobManager, workers job.WorkerRegistry) *Server {
+	registry := prometheus.NewRegistry()
+
+	var limiter *rateLimiter
+	if cfg.API.RateLimit > 0 {
+		limiter = newRateLimiter(cfg.API.RateLimit, cfg.API.RateBurst, clock.Real())
+	}
+
 	return &Server{
 		config:  cfg,
 		store:   store,
+		queue:   queue,
 		manager: manager,
 		workers: workers,
+		metrics: registry,
+		http:    newHTTPMetrics(registry),
+		jobs:    newJobMetrics(registry, store, queue, manager),
+		logger:  slog.Default(),
+		limiter: limiter,
 	}
 }
 
+// SetLogger sets the logger requests are logged to
+func (s *Server) SetLogger(logger *slog.Logger) {
+	s.logger = logger
+}
+
 // SetupRoutes configures the HTTP routes
 func (s *Server) SetupRoutes() *mux.Router {
 	r := mux.NewRouter()
 
 	// API v1 routes
 	api := r.PathPrefix("/api/v1").Subrouter()
+	api.Use(s.authMiddleware)
 
 	// Job endpoints
 	api.HandleFunc("/jobs", s.handleSubmitJob).Methods("POST")
 	api.HandleFunc("/jobs", s.handleListJobs).Methods("GET")
+	api.HandleFunc("/jobs/batch", s.handleSubmitJobBatch).Methods("POST")
+	api.HandleFunc("/jobs/status", s.handleBatchJobStatus).Methods("POST")
+	api.HandleFunc("/jobs/search", s.handleSearchJobs).Methods("POST")
 	api.HandleFunc("/jobs/{id}", s.handleGetJob).Methods("GET")
 	api.HandleFunc("/jobs/{id}", s.handleCancelJob).Methods("DELETE")
+	api.HandleFunc("/jobs/{id}", s.handleUpdateJob).Methods("PATCH")
+	api.HandleFunc("/jobs/{id}/priority", s.handleUpdateJob).Methods("PATCH")
+	api.HandleFunc("/jobs/{id}/signal", s.handleSignalJob).Methods("POST")
+	api.HandleFunc("/jobs/{id}/requeue", s.handleRequeueJob).Methods("POST")
+	api.HandleFunc("/jobs/{id}/events", s.handleGetJobEvents).Methods("GET")
+	api.HandleFunc("/jobs/{id}/output", s.handleGetJobOutput).Methods("GET")
+	api.HandleFunc("/jobs/{id}/result", s.handleGetJobResult).Methods("GET")
+	api.HandleFunc("/jobs/{id}/result", s.handleSaveJobResult).Methods("POST")
+	api.HandleFunc("/jobs/{id}/timeout", s.handleExtendTimeout).Methods("PATCH")
+	api.HandleFunc("/jobs/{id}/schedulability", s.handleJobSchedulability).Methods("GET")
+	api.HandleFunc("/jobs/{id}/logs/stream", s.handleStreamJobLogs).Methods("GET")
+	api.HandleFunc("/jobs/{id}/watch", s.handleWatchJob).Methods("GET")
+	api.HandleFunc("/tags", s.handleListTags).Methods("GET")
+
+	// Schedule endpoints
+	api.HandleFunc("/schedules/preview", s.handlePreviewSchedule).Methods("POST")
 
 	// Worker endpoints
 	api.HandleFunc("/workers", s.handleListWorkers).Methods("GET")
+	api.HandleFunc("/workers", s.handleRegisterWorker).Methods("POST")
+	api.HandleFunc("/workers/{id}", s.handleDecommissionWorker).Methods("DELETE")
 	api.HandleFunc("/workers/{id}/heartbeat", s.handleWorkerHeartbeat).Methods("POST")
+	api.HandleFunc("/workers/{id}/drain", s.handleDrainWorker).Methods("POST")
+	api.HandleFunc("/workers/{id}/release", s.handleReleaseWorkerJobs).Methods("POST")
+	api.HandleFunc("/workers/{id}/jobs/next", s.handleClaimNextJob).Methods("POST")
+	api.HandleFunc("/workers/{id}/jobs/{jobID}/logfile", s.handleWorkerJobLogFile).Methods("GET")
 
 	// System endpoints
 	api.HandleFunc("/health", s.handleHealth).Methods("GET")
+	api.HandleFunc("/health/live", s.handleLiveness).Methods("GET")
+	api.HandleFunc("/health/ready", s.handleReadiness).Methods("GET")
 	api.HandleFunc("/metrics", s.handleMetrics).Methods("GET")
+	api.HandleFunc("/dashboard", s.handleDashboard).Methods("GET")
+
+	// Prometheus scrape endpoint
+	r.Handle("/metrics", promhttp.HandlerFor(s.metrics, promhttp.HandlerOpts{})).Methods("GET")
 
 	// Middleware
+	r.Use(s.http.middleware)
 	r.Use(s.loggingMiddleware)
 	r.Use(s.corsMiddleware)
+	r.Use(s.rateLimitMiddleware)
 
 	return r
 }
@@ -63,22 +139,187 @@ func (s *Server) SetupRoutes() *mux.Router {
 func (s *Server) handleSubmitJob(w http.ResponseWriter, r *http.Request) {
 	var request job.JobRequest
 
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		s.writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+	// ?dry_run=true checks the request and shows the resolved job without
+	// submitting it
+	dryRun := false
+	if value := r.URL.Query().Get("dry_run"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			s.writeError(w, r, http.StatusBadRequest, "invalid dry_run flag: "+value)
+			return
+		}
+		dryRun = parsed
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		r.Body = http.MaxBytesReader(w, r.Body, s.config.API.MaxBodyBytes)
+		if err := decodeMultipartJobRequest(r, &request, s.config.API.MaxBodyBytes); err != nil {
+			s.writeError(w, r, http.StatusBadRequest, "invalid multipart submission: "+err.Error())
+			return
+		}
+	} else if err := s.decodeJSON(w, r, &request); err != nil {
+		s.writeError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if dryRun {
+		s.handleDryRun(w, r, &request)
 		return
 	}
 
 	j, err := s.manager.Submit(r.Context(), &request)
+	if err != nil {
+		switch {
+		case job.IsValidationError(err):
+			s.writeError(w, r, http.StatusBadRequest, err.Error())
+		case job.IsConflictError(err):
+			s.writeError(w, r, http.StatusConflict, err.Error())
+		default:
+			s.writeError(w, r, http.StatusInternalServerError, "failed to submit job: "+err.Error())
+		}
+		return
+	}
+
+	s.writeResponse(w, r, http.StatusCreated, j)
+}
+
+// handleDryRun answers a dry-run submission with the job the request
+// resolves to, or the error submitting it would have met
+func (s *Server) handleDryRun(w http.ResponseWriter, r *http.Request, request *job.JobRequest) {
+	resolver, ok := s.manager.(interface {
+		DryRun(ctx context.Context, request *job.JobRequest) (*job.Job, error)
+	})
+	if !ok {
+		s.writeError(w, r, http.StatusNotImplemented, "job manager cannot dry-run submissions")
+		return
+	}
+
+	j, err := resolver.DryRun(r.Context(), request)
 	if err != nil {
 		if job.IsValidationError(err) {
-			s.writeError(w, http.StatusBadRequest, err.Error())
+			s.writeError(w, r, http.StatusBadRequest, err.Error())
 		} else {
-			s.writeError(w, http.StatusInternalServerError, "failed to submit job: "+err.Error())
+			s.writeError(w, r, http.StatusInternalServerError, "failed to resolve job: "+err.Error())
 		}
 		return
 	}
 
-	s.writeJSON(w, http.StatusCreated, j)
+	s.writeResponse(w, r, http.StatusOK, j)
+}
+
+// batchSubmitResult reports what happened to one job of a batch submission,
+// in the same position as its request
+type batchSubmitResult struct {
+	Index  int      `json:"index"`
+	Status int      `json:"status"` // HTTP status the job alone would have been answered with
+	Job    *job.Job `json:"job,omitempty"`
+	Error  string   `json:"error,omitempty"`
+}
+
+// handleSubmitJobBatch submits an array of jobs. Each is validated and
+// submitted on its own, so a bad request only fails its own entry: the
+// response is 201 when every job was created and 207 when any failed.
+func (s *Server) handleSubmitJobBatch(w http.ResponseWriter, r *http.Request) {
+	var requests []*job.JobRequest
+
+	if err := s.decodeJSON(w, r, &requests); err != nil {
+		s.writeError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(requests) == 0 {
+		s.writeError(w, r, http.StatusBadRequest, "at least one job is required")
+		return
+	}
+	if limit := s.config.API.MaxBatchSize; len(requests) > limit {
+		s.writeError(w, r, http.StatusBadRequest, fmt.Sprintf("batch of %d jobs exceeds the limit of %d", len(requests), limit))
+		return
+	}
+
+	results := make([]batchSubmitResult, len(requests))
+	status := http.StatusCreated
+	for i, request := range requests {
+		results[i].Index = i
+		if request == nil {
+			results[i].Status = http.StatusBadRequest
+			results[i].Error = "job request is required"
+			status = http.StatusMultiStatus
+			continue
+		}
+
+		j, err := s.manager.Submit(r.Context(), request)
+		if err != nil {
+			switch {
+			case job.IsValidationError(err):
+				results[i].Status = http.StatusBadRequest
+			case job.IsConflictError(err):
+				results[i].Status = http.StatusConflict
+			default:
+				results[i].Status = http.StatusInternalServerError
+			}
+			results[i].Error = err.Error()
+			status = http.StatusMultiStatus
+			continue
+		}
+
+		results[i].Status = http.StatusCreated
+		results[i].Job = j
+	}
+
+	s.writeResponse(w, r, status, map[string]interface{}{
+		"results": results,
+		"count":   len(results),
+	})
+}
+
+// decodeMultipartJobRequest reads a submission whose "job" part holds the
+// JobRequest as JSON and whose "script" file part holds the script body
+func decodeMultipartJobRequest(r *http.Request, request *job.JobRequest, maxBytes int64) error {
+	if err := r.ParseMultipartForm(maxBytes); err != nil {
+		return err
+	}
+
+	// The job part may be sent as a plain field or as a file
+	spec := []byte(r.FormValue("job"))
+	if len(spec) == 0 {
+		if data, err := readFormFile(r, "job"); err == nil {
+			spec = data
+		} else if err != http.ErrMissingFile {
+			return err
+		}
+	}
+	if len(spec) > 0 {
+		decoder := json.NewDecoder(bytes.NewReader(spec))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(request); err != nil {
+			return fmt.Errorf("invalid job part: %v", describeDecodeError(err))
+		}
+	}
+
+	script, err := readFormFile(r, "script")
+	if err != nil {
+		if err == http.ErrMissingFile {
+			return fmt.Errorf("script file part is required")
+		}
+		return err
+	}
+
+	if request.Type == "" {
+		request.Type = job.JobTypeScript
+	}
+	request.Script = string(script)
+	return nil
+}
+
+// readFormFile returns the contents of a parsed multipart file part
+func readFormFile(r *http.Request, name string) ([]byte, error) {
+	file, _, err := r.FormFile(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return io.ReadAll(file)
 }
 
 func (s *Server) handleListJobs(w http.ResponseWriter, r *http.Request) {
@@ -86,6 +327,10 @@ func (s *Server) handleListJobs(w http.ResponseWriter, r *http.Request) {
 	var filters []job.Filter
 
 	if status := r.URL.Query().Get("status"); status != "" {
+		if !job.JobStatus(status).IsValid() {
+			s.writeError(w, r, http.StatusBadRequest, "invalid status filter: "+status)
+			return
+		}
 		filters = append(filters, job.Filter{
 			Field:    "status",
 			Operator: "eq",
@@ -101,224 +346,1952 @@ func (s *Server) handleListJobs(w http.ResponseWriter, r *http.Request) {
 		})
 	}
 
-	// Parse limit
-	limit := 100 // default
+	// Runs of a recurring job
+	if parentID := r.URL.Query().Get("parent_id"); parentID != "" {
+		filters = append(filters, job.Filter{
+			Field:    "parent_id",
+			Operator: "eq",
+			Value:    parentID,
+		})
+	}
+
+	// Creation time bounds in RFC3339, both inclusive, e.g.
+	// ?created_after=2024-05-01T10:00:00Z for jobs from the last hour
+	for _, bound := range []struct {
+		param    string
+		operator string
+	}{
+		{"created_after", "gte"},
+		{"created_before", "lte"},
+	} {
+		raw := r.URL.Query().Get(bound.param)
+		if raw == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			s.writeError(w, r, http.StatusBadRequest, fmt.Sprintf("invalid %s filter, expected an RFC3339 timestamp: %s", bound.param, raw))
+			return
+		}
+		filters = append(filters, job.Filter{Field: "created_at", Operator: bound.operator, Value: parsed.UTC()})
+	}
+
+	// Presence filters on nullable fields, e.g. ?started=true&completed=false
+	// for jobs that are running
+	presence := []struct {
+		param string
+		field string
+	}{
+		{"started", "started_at"},
+		{"completed", "completed_at"},
+		{"assigned", "worker_id"},
+	}
+	for _, p := range presence {
+		raw := r.URL.Query().Get(p.param)
+		if raw == "" {
+			continue
+		}
+		set, err := strconv.ParseBool(raw)
+		if err != nil {
+			s.writeError(w, r, http.StatusBadRequest, fmt.Sprintf("invalid %s filter, expected true or false: %s", p.param, raw))
+			return
+		}
+		operator := "exists"
+		if !set {
+			operator = "nexists"
+		}
+		filters = append(filters, job.Filter{Field: p.field, Operator: operator})
+	}
+
+	// Each ?tag= names a tag the job must carry, so ?tag=nightly&tag=gpu
+	// selects jobs with both. Tags match exactly, including case.
+	for _, tag := range r.URL.Query()["tag"] {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			filters = append(filters, job.Filter{Field: "tags", Operator: "contains", Value: tag})
+		}
+	}
+
+	// Tag sets are comma-separated, e.g. ?tags_all=nightly,gpu for jobs with
+	// both tags or ?tags_any=nightly,gpu for jobs with either
+	for param, operator := range map[string]string{"tags_all": "hasall", "tags_any": "hasany"} {
+		raw := r.URL.Query().Get(param)
+		if raw == "" {
+			continue
+		}
+		var tags []interface{}
+		for _, tag := range strings.Split(raw, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+		filters = append(filters, job.Filter{Field: "tags", Operator: operator, Value: tags})
+	}
+
+	// Annotations are given as key=value pairs, e.g. ?annotation=commit=abc123
+	for _, annotation := range r.URL.Query()["annotation"] {
+		key, value, found := strings.Cut(annotation, "=")
+		if !found || key == "" {
+			s.writeError(w, r, http.StatusBadRequest, "invalid annotation filter, expected key=value: "+annotation)
+			return
+		}
+		filters = append(filters, job.Filter{
+			Field:    "annotation:" + key,
+			Operator: "eq",
+			Value:    value,
+		})
+	}
+
+	// Pages are chosen by ?cursor=, the next_cursor of the previous page,
+	// or by ?offset=
+	opts := job.ListOptions{Filters: filters, Cursor: r.URL.Query().Get("cursor")}
+	limit := 0
 	if l := r.URL.Query().Get("limit"); l != "" {
-		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
-			limit = parsed
+		parsed, err := strconv.Atoi(l)
+		if err != nil {
+			s.writeError(w, r, http.StatusBadRequest, "invalid limit: "+l)
+			return
+		}
+		limit = parsed
+	}
+	var err error
+	if opts.Limit, err = pageLimit(limit); err != nil {
+		s.writeError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if o := r.URL.Query().Get("offset"); o != "" {
+		parsed, err := strconv.Atoi(o)
+		if err != nil || parsed < 0 {
+			s.writeError(w, r, http.StatusBadRequest, "invalid offset: "+o)
+			return
 		}
+		opts.Offset = parsed
 	}
 
-	jobs, err := s.manager.ListJobs(r.Context(), filters...)
+	page, err := s.manager.ListJobsPage(r.Context(), opts)
 	if err != nil {
-		s.writeError(w, http.StatusInternalServerError, "failed to list jobs: "+err.Error())
+		if job.IsValidationError(err) {
+			s.writeError(w, r, http.StatusBadRequest, err.Error())
+		} else {
+			s.writeError(w, r, http.StatusInternalServerError, "failed to list jobs: "+err.Error())
+		}
 		return
 	}
 
-	// Apply limit
-	if len(jobs) > limit {
-		jobs = jobs[:limit]
-	}
+	s.setEffectivePriorities(r.Context(), page.Jobs...)
+	omitEvents(page.Jobs)
 
 	response := map[string]interface{}{
-		"jobs":  jobs,
-		"count": len(jobs),
+		"jobs":        page.Jobs,
+		"count":       len(page.Jobs),
+		"total":       page.Total,
+		"next_cursor": page.NextCursor,
 	}
 
-	s.writeJSON(w, http.StatusOK, response)
+	s.writeResponse(w, r, http.StatusOK, response)
 }
 
-func (s *Server) handleGetJob(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	jobID := vars["id"]
+// Page sizes of job listings
+const (
+	defaultPageLimit = 100
+	maxPageLimit     = 1000
+)
 
-	j, err := s.manager.GetJob(r.Context(), jobID)
+// pageLimit checks the page size asked of a job listing, where zero asks for
+// the default
+func pageLimit(limit int) (int, error) {
+	switch {
+	case limit == 0:
+		return defaultPageLimit, nil
+	case limit < 0 || limit > maxPageLimit:
+		return 0, fmt.Errorf("limit must be between 1 and %d", maxPageLimit)
+	}
+	return limit, nil
+}
+
+// handleSearchJobs lists jobs matching filters given as JSON, for queries the
+// list endpoint's query parameters can't express, e.g.
+// {"filters": [{"field": "priority", "operator": "gt", "value": 5}]}
+func (s *Server) handleSearchJobs(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Filters []job.Filter `json:"filters"`
+		Limit   int          `json:"limit"`
+		Cursor  string       `json:"cursor"` // The next_cursor of the previous page
+	}
+	if err := s.decodeJSON(w, r, &request); err != nil {
+		s.writeError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	opts := job.ListOptions{Filters: request.Filters, Cursor: request.Cursor}
+	var err error
+	if opts.Limit, err = pageLimit(request.Limit); err != nil {
+		s.writeError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	page, err := s.manager.ListJobsPage(r.Context(), opts)
 	if err != nil {
-		if job.IsJobNotFoundError(err) {
-			s.writeError(w, http.StatusNotFound, err.Error())
+		if job.IsValidationError(err) {
+			s.writeError(w, r, http.StatusBadRequest, err.Error())
 		} else {
-			s.writeError(w, http.StatusInternalServerError, "failed to get job: "+err.Error())
+			s.writeError(w, r, http.StatusInternalServerError, "failed to list jobs: "+err.Error())
 		}
 		return
 	}
 
-	s.writeJSON(w, http.StatusOK, j)
+	s.setEffectivePriorities(r.Context(), page.Jobs...)
+	omitEvents(page.Jobs)
+
+	response := map[string]interface{}{
+		"jobs":        page.Jobs,
+		"count":       len(page.Jobs),
+		"total":       page.Total,
+		"next_cursor": page.NextCursor,
+	}
+
+	s.writeResponse(w, r, http.StatusOK, response)
 }
 
-func (s *Server) handleCancelJob(w http.ResponseWriter, r *http.Request) {
+func (s *Server) handleGetJob(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	jobID := vars["id"]
 
-	err := s.manager.CancelJob(r.Context(), jobID)
+	j, err := s.manager.GetJob(r.Context(), jobID)
 	if err != nil {
 		if job.IsJobNotFoundError(err) {
-			s.writeError(w, http.StatusNotFound, err.Error())
+			s.writeError(w, r, http.StatusNotFound, err.Error())
 		} else {
-			s.writeError(w, http.StatusInternalServerError, "failed to cancel job: "+err.Error())
+			s.writeError(w, r, http.StatusInternalServerError, "failed to get job: "+err.Error())
 		}
 		return
 	}
 
-	s.writeJSON(w, http.StatusOK, map[string]string{"message": "job cancelled"})
+	s.setEffectivePriorities(r.Context(), j)
+	s.writeResponse(w, r, http.StatusOK, j)
 }
 
-// Worker Handlers
-
-func (s *Server) handleListWorkers(w http.ResponseWriter, r *http.Request) {
-	workers, err := s.workers.ListWorkers(r.Context())
-	if err != nil {
-		s.writeError(w, http.StatusInternalServerError, "failed to list workers: "+err.Error())
-		return
+// omitEvents drops the status history from jobs in a listing, where it
+// would repeat for every job; GET /jobs/{id}/events returns it for one job
+func omitEvents(jobs []*job.Job) {
+	for _, j := range jobs {
+		j.Events = nil
 	}
+}
 
-	// Convert to response format
-	var workerInfo []map[string]interface{}
-	for _, worker := range workers {
-		workerInfo = append(workerInfo, map[string]interface{}{
-			"id":           worker.ID(),
-			"healthy":      worker.IsHealthy(),
-			"capacity":     worker.GetCapacity(),
-			"current_load": worker.GetCurrentLoad(),
-			"can_accept":   worker.CanAcceptJob(),
-		})
+// setEffectivePriorities fills in the priority queued jobs are currently
+// dispatched by, when the queue ages priorities
+func (s *Server) setEffectivePriorities(ctx context.Context, jobs ...*job.Job) {
+	aging, ok := s.queue.(interface {
+		EffectivePriority(ctx context.Context, jobID string) (int, error)
+	})
+	if !ok {
+		return
 	}
 
-	response := map[string]interface{}{
-		"workers": workerInfo,
-		"count":   len(workerInfo),
+	for _, j := range jobs {
+		if j.Status != job.JobStatusQueued {
+			continue
+		}
+		if priority, err := aging.EffectivePriority(ctx, j.ID); err == nil {
+			j.EffectivePriority = priority
+		}
 	}
-
-	s.writeJSON(w, http.StatusOK, response)
 }
 
-func (s *Server) handleWorkerHeartbeat(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	workerID := vars["id"]
+// handleGetJobEvents returns the statuses a job has been through, oldest first
+func (s *Server) handleGetJobEvents(w http.ResponseWriter, r *http.Request) {
+	jobID := mux.Vars(r)["id"]
 
-	err := s.workers.Heartbeat(r.Context(), workerID)
+	j, err := s.manager.GetJob(r.Context(), jobID)
 	if err != nil {
-		if job.IsWorkerNotFoundError(err) {
-			s.writeError(w, http.StatusNotFound, err.Error())
+		if job.IsJobNotFoundError(err) {
+			s.writeError(w, r, http.StatusNotFound, err.Error())
 		} else {
-			s.writeError(w, http.StatusInternalServerError, "failed to update heartbeat: "+err.Error())
+			s.writeError(w, r, http.StatusInternalServerError, "failed to get job: "+err.Error())
 		}
 		return
 	}
 
-	s.writeJSON(w, http.StatusOK, map[string]string{"message": "heartbeat updated"})
+	events := j.Events
+	if events == nil {
+		events = []job.JobEvent{}
+	}
+
+	s.writeResponse(w, r, http.StatusOK, map[string]interface{}{
+		"job_id": j.ID,
+		"events": events,
+		"count":  len(events),
+	})
 }
 
-// System Handlers
+// handleGetJobOutput returns a finished job's output as plain text: all of
+// it, the last lines with ?tail=N, or the bytes from ?offset=N on, optionally
+// at most ?limit=N of them. The output comes from the job's result, so a job
+// that hasn't finished gets a 409. The X-Output-Size header gives the full
+// size, so a client can fetch a range at a time by passing it as the offset.
+func (s *Server) handleGetJobOutput(w http.ResponseWriter, r *http.Request) {
+	jobID := mux.Vars(r)["id"]
+	query := r.URL.Query()
 
-func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
-	// Basic health check
-	workers, err := s.workers.ListWorkers(r.Context())
+	params := map[string]int{}
+	for _, name := range []string{"tail", "offset", "limit"} {
+		value := query.Get(name)
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			s.writeError(w, r, http.StatusBadRequest, fmt.Sprintf("invalid %s: %s", name, value))
+			return
+		}
+		params[name] = parsed
+	}
+	_, tail := params["tail"]
+	_, offset := params["offset"]
+	_, limit := params["limit"]
+	if tail && (offset || limit) {
+		s.writeError(w, r, http.StatusBadRequest, "tail cannot be combined with offset or limit")
+		return
+	}
+
+	result, err := s.manager.GetJobResult(r.Context(), jobID)
 	if err != nil {
-		s.writeError(w, http.StatusServiceUnavailable, "failed to check workers: "+err.Error())
+		switch {
+		case job.IsJobNotFoundError(err):
+			s.writeError(w, r, http.StatusNotFound, err.Error())
+		case job.IsConflictError(err):
+			s.writeError(w, r, http.StatusConflict, err.Error())
+		default:
+			s.writeError(w, r, http.StatusInternalServerError, "failed to get job output: "+err.Error())
+		}
 		return
 	}
 
-	healthyWorkers := 0
-	for _, worker := range workers {
-		if worker.IsHealthy() {
-			healthyWorkers++
+	output := result.Output
+	if tail {
+		output = tailLines(output, params["tail"])
+	} else {
+		output = output[min(params["offset"], len(output)):]
+		if limit && len(output) > params["limit"] {
+			output = output[:params["limit"]]
 		}
 	}
 
-	health := map[string]interface{}{
-		"status":          "healthy",
-		"total_workers":   len(workers),
-		"healthy_workers": healthyWorkers,
-		"timestamp":       scheduler.Now(),
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("X-Output-Size", strconv.Itoa(len(result.Output)))
+	w.WriteHeader(http.StatusOK)
+	io.WriteString(w, output)
+}
+
+// tailLines returns the last n lines of output. A trailing newline ends the
+// last line rather than starting an empty one.
+func tailLines(output string, n int) string {
+	if n == 0 {
+		return ""
 	}
 
-	s.writeJSON(w, http.StatusOK, health)
+	end := strings.TrimSuffix(output, "\n")
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] == '\n' {
+			n--
+			if n == 0 {
+				return output[i+1:]
+			}
+		}
+	}
+	return output
 }
 
-func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
-	// Get job counts by status
-	statuses := []job.JobStatus{
-		job.JobStatusPending,
-		job.JobStatusQueued,
-		job.JobStatusRunning,
-		job.JobStatusCompleted,
-		job.JobStatusFailed,
-		job.JobStatusCancelled,
+// batchStatusRequest is the body accepted by the batch status endpoint
+type batchStatusRequest struct {
+	JobIDs []string `json:"job_ids"`
+}
+
+func (s *Server) handleBatchJobStatus(w http.ResponseWriter, r *http.Request) {
+	var request batchStatusRequest
+
+	if err := s.decodeJSON(w, r, &request); err != nil {
+		s.writeError(w, r, http.StatusBadRequest, err.Error())
+		return
 	}
 
-	jobCounts := make(map[string]int)
-	totalJobs := 0
+	if len(request.JobIDs) == 0 {
+		s.writeError(w, r, http.StatusBadRequest, "job_ids is required")
+		return
+	}
+	if limit := s.config.API.MaxBatchSize; len(request.JobIDs) > limit {
+		s.writeError(w, r, http.StatusBadRequest, fmt.Sprintf("%d job_ids exceed the limit of %d", len(request.JobIDs), limit))
+		return
+	}
 
-	for _, status := range statuses {
-		jobs, err := s.store.List(r.Context(), job.Filter{
-			Field:    "status",
-			Operator: "eq",
-			Value:    string(status),
-		})
-		if err == nil {
-			count := len(jobs)
-			jobCounts[string(status)] = count
-			totalJobs += count
-		}
+	// Fetch every requested job in a single store call
+	ids := make([]interface{}, 0, len(request.JobIDs))
+	for _, id := range request.JobIDs {
+		ids = append(ids, id)
 	}
 
-	// Get worker metrics
-	workers, _ := s.workers.ListWorkers(r.Context())
-	totalCapacity := 0
-	totalLoad := 0
-	healthyWorkers := 0
+	jobs, err := s.store.List(r.Context(), job.Filter{
+		Field:    "id",
+		Operator: "in",
+		Value:    ids,
+	})
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "failed to get job statuses: "+err.Error())
+		return
+	}
 
-	for _, worker := range workers {
-		totalCapacity += worker.GetCapacity()
-		totalLoad += worker.GetCurrentLoad()
-		if worker.IsHealthy() {
-			healthyWorkers++
+	found := make(map[string]*job.Job, len(jobs))
+	for _, j := range jobs {
+		found[j.ID] = j
+	}
+
+	// Missing IDs are reported per entry rather than failing the request
+	statuses := make(map[string]interface{}, len(request.JobIDs))
+	for _, id := range request.JobIDs {
+		j, ok := found[id]
+		if !ok {
+			statuses[id] = map[string]interface{}{
+				"error": job.NewJobNotFoundError(id).Error(),
+			}
+			continue
+		}
+
+		statuses[id] = map[string]interface{}{
+			"status":       j.Status,
+			"exit_code":    j.ExitCode,
+			"progress":     j.Progress,
+			"output_bytes": j.OutputBytes,
+			"output_lines": j.OutputLines,
 		}
 	}
 
-	metrics := map[string]interface{}{
-		"jobs": map[string]interface{}{
-			"total":     totalJobs,
-			"by_status": jobCounts,
-		},
-		"workers": map[string]interface{}{
-			"total":          len(workers),
-			"healthy":        healthyWorkers,
-			"total_capacity": totalCapacity,
-			"total_load":     totalLoad,
-			"utilization":    calculateUtilization(totalLoad, totalCapacity),
-		},
-		"timestamp": scheduler.Now(),
+	response := map[string]interface{}{
+		"jobs":  statuses,
+		"count": len(statuses),
 	}
 
-	s.writeJSON(w, http.StatusOK, metrics)
+	s.writeResponse(w, r, http.StatusOK, response)
 }
 
-// Helper methods
+func (s *Server) handleCancelJob(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	jobID := vars["id"]
 
-func (s *Server) writeJSON(w http.ResponseWriter, status int, data interface{}) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	json.NewEncoder(w).Encode(data)
+	err := s.manager.CancelJob(r.Context(), jobID)
+	if err != nil {
+		switch {
+		case job.IsJobNotFoundError(err):
+			s.writeError(w, r, http.StatusNotFound, err.Error())
+		case job.IsConflictError(err), job.IsValidationError(err):
+			// A job that finished while being cancelled fails its status change
+			s.writeError(w, r, http.StatusConflict, err.Error())
+		default:
+			s.writeError(w, r, http.StatusInternalServerError, "failed to cancel job: "+err.Error())
+		}
+		return
+	}
+
+	s.writeResponse(w, r, http.StatusOK, map[string]string{"message": "job cancelled"})
 }
 
-func (s *Server) writeError(w http.ResponseWriter, status int, message string) {
-	s.writeJSON(w, status, map[string]string{"error": message})
+// jobUpdateRequest is the body accepted by PATCH /jobs/{id}; fields left out
+// are left as they are
+type jobUpdateRequest struct {
+	Priority *int `json:"priority"`
 }
 
-// Middleware
+// handleUpdateJob applies a partial update to a job that hasn't been
+// dispatched yet. It also serves the older PATCH /jobs/{id}/priority.
+func (s *Server) handleUpdateJob(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	jobID := vars["id"]
 
-func (s *Server) loggingMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		fmt.Printf("[%s] %s %s\n", scheduler.Now().Format("2006-01-02 15:04:05"), r.Method, r.URL.Path)
-		next.ServeHTTP(w, r)
-	})
+	var request jobUpdateRequest
+	if err := s.decodeJSON(w, r, &request); err != nil {
+		s.writeError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if request.Priority == nil {
+		s.writeError(w, r, http.StatusBadRequest, "priority is required")
+		return
+	}
+
+	j, err := s.manager.UpdatePriority(r.Context(), jobID, *request.Priority)
+	if err != nil {
+		switch {
+		case job.IsJobNotFoundError(err):
+			s.writeError(w, r, http.StatusNotFound, err.Error())
+		case job.IsValidationError(err):
+			s.writeError(w, r, http.StatusBadRequest, err.Error())
+		case job.IsConflictError(err):
+			s.writeError(w, r, http.StatusConflict, err.Error())
+		default:
+			s.writeError(w, r, http.StatusInternalServerError, "failed to update priority: "+err.Error())
+		}
+		return
+	}
+
+	s.writeResponse(w, r, http.StatusOK, j)
+}
+
+// handleRequeueJob runs a failed job again under the same ID, keeping the
+// failed run in the job's history
+func (s *Server) handleRequeueJob(w http.ResponseWriter, r *http.Request) {
+	jobID := mux.Vars(r)["id"]
+
+	j, err := s.manager.Requeue(r.Context(), jobID)
+	if err != nil {
+		switch {
+		case job.IsJobNotFoundError(err):
+			s.writeError(w, r, http.StatusNotFound, err.Error())
+		case job.IsConflictError(err):
+			s.writeError(w, r, http.StatusConflict, err.Error())
+		default:
+			s.writeError(w, r, http.StatusInternalServerError, "failed to requeue job: "+err.Error())
+		}
+		return
+	}
+
+	s.writeResponse(w, r, http.StatusOK, j)
+}
+
+// handleGetJobResult returns the result of a finished job, including when it
+// started and completed and how long it ran
+func (s *Server) handleGetJobResult(w http.ResponseWriter, r *http.Request) {
+	jobID := mux.Vars(r)["id"]
+
+	result, err := s.manager.GetJobResult(r.Context(), jobID)
+	if err != nil {
+		switch {
+		case job.IsJobNotFoundError(err):
+			s.writeError(w, r, http.StatusNotFound, err.Error())
+		case job.IsConflictError(err):
+			s.writeError(w, r, http.StatusConflict, err.Error())
+		default:
+			s.writeError(w, r, http.StatusInternalServerError, "failed to get job result: "+err.Error())
+		}
+		return
+	}
+
+	s.writeResponse(w, r, http.StatusOK, result)
+}
+
+// handleSaveJobResult stores the result a worker reports after running a job
+// and moves the job on to completed, retrying or failed accordingly
+func (s *Server) handleSaveJobResult(w http.ResponseWriter, r *http.Request) {
+	jobID := mux.Vars(r)["id"]
+
+	saver, ok := s.manager.(interface {
+		SaveJobResult(ctx context.Context, result *job.JobResult) error
+	})
+	if !ok {
+		s.writeError(w, r, http.StatusNotImplemented, "job manager cannot store results")
+		return
+	}
+
+	var result job.JobResult
+	if err := s.decodeJSON(w, r, &result); err != nil {
+		s.writeError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if result.JobID != "" && result.JobID != jobID {
+		s.writeError(w, r, http.StatusBadRequest, "job_id does not match the URL")
+		return
+	}
+	result.JobID = jobID
+
+	j, err := s.manager.GetJob(r.Context(), jobID)
+	if err != nil {
+		if job.IsJobNotFoundError(err) {
+			s.writeError(w, r, http.StatusNotFound, err.Error())
+		} else {
+			s.writeError(w, r, http.StatusInternalServerError, "failed to get job: "+err.Error())
+		}
+		return
+	}
+
+	if err := saver.SaveJobResult(r.Context(), &result); err != nil {
+		switch {
+		case job.IsJobNotFoundError(err):
+			s.writeError(w, r, http.StatusNotFound, err.Error())
+		case job.IsValidationError(err):
+			s.writeError(w, r, http.StatusBadRequest, err.Error())
+		case job.IsConflictError(err):
+			s.writeError(w, r, http.StatusConflict, err.Error())
+		default:
+			s.writeError(w, r, http.StatusInternalServerError, "failed to save job result: "+err.Error())
+		}
+		return
+	}
+
+	// A result for a job that already finished only replaces the stored one
+	if j.IsRunning() {
+		s.jobs.observeResult(j, &result)
+	}
+	s.writeResponse(w, r, http.StatusOK, &result)
+}
+
+// handleJobSchedulability explains why a job isn't running: its status,
+// unfinished dependencies, a held mutex key or the lack of a worker
+func (s *Server) handleJobSchedulability(w http.ResponseWriter, r *http.Request) {
+	jobID := mux.Vars(r)["id"]
+
+	explainer, ok := s.manager.(interface {
+		ExplainScheduling(ctx context.Context, jobID string) (*job.Schedulability, error)
+	})
+	if !ok {
+		s.writeError(w, r, http.StatusNotImplemented, "job manager cannot explain scheduling")
+		return
+	}
+
+	explanation, err := explainer.ExplainScheduling(r.Context(), jobID)
+	if err != nil {
+		if job.IsJobNotFoundError(err) {
+			s.writeError(w, r, http.StatusNotFound, err.Error())
+		} else {
+			s.writeError(w, r, http.StatusInternalServerError, "failed to explain scheduling: "+err.Error())
+		}
+		return
+	}
+
+	s.writeResponse(w, r, http.StatusOK, explanation)
+}
+
+// handleStreamJobLogs follows a job's output as server-sent events, one
+// "output" event per line, ending with an "end" event carrying the final
+// status. A running job's recent output is replayed first; a finished job's
+// stored output is sent straight away.
+func (s *Server) handleStreamJobLogs(w http.ResponseWriter, r *http.Request) {
+	jobID := mux.Vars(r)["id"]
+
+	j, err := s.store.Get(r.Context(), jobID)
+	if err != nil {
+		if job.IsJobNotFoundError(err) {
+			s.writeError(w, r, http.StatusNotFound, err.Error())
+		} else {
+			s.writeError(w, r, http.StatusInternalServerError, "failed to get job: "+err.Error())
+		}
+		return
+	}
+
+	if j.IsTerminal() {
+		stream := newEventStream(w)
+		stream.Write([]byte(j.Output))
+		stream.End(j.Status)
+		return
+	}
+	if !j.IsRunning() {
+		s.writeError(w, r, http.StatusConflict, fmt.Sprintf("job %s has not started (status %s)", jobID, j.Status))
+		return
+	}
+
+	worker, err := s.workers.GetWorker(r.Context(), j.WorkerID)
+	if err != nil {
+		if job.IsWorkerNotFoundError(err) {
+			s.writeError(w, r, http.StatusConflict, "job "+jobID+" is not running on a registered worker")
+		} else {
+			s.writeError(w, r, http.StatusInternalServerError, "failed to get worker: "+err.Error())
+		}
+		return
+	}
+
+	follower, ok := worker.(interface {
+		FollowJobOutput(jobID string) ([]byte, <-chan []byte, func(), error)
+	})
+	if !ok {
+		s.writeError(w, r, http.StatusNotImplemented, "worker "+worker.ID()+" does not stream job output")
+		return
+	}
+
+	backlog, chunks, cancel, err := follower.FollowJobOutput(jobID)
+	if err != nil {
+		if job.IsJobNotFoundError(err) {
+			s.writeError(w, r, http.StatusConflict, "job "+jobID+" is not running on worker "+worker.ID())
+		} else {
+			s.writeError(w, r, http.StatusInternalServerError, "failed to follow job output: "+err.Error())
+		}
+		return
+	}
+	defer cancel()
+
+	stream := newEventStream(w)
+	stream.Write(backlog)
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case chunk, open := <-chunks:
+			if !open {
+				// The job finished, or this client fell too far behind
+				status := job.JobStatusRunning
+				if finished, err := s.store.Get(r.Context(), jobID); err == nil {
+					status = finished.Status
+				}
+				stream.End(status)
+				return
+			}
+			stream.Write(chunk)
+		}
+	}
+}
+
+// maxEventLine bounds how much of an unfinished line an event stream holds
+// back. Longer lines are sent in pieces of this size, so a job that never
+// prints a newline can't grow the buffer without limit.
+const maxEventLine = 64 * 1024
+
+// eventStream writes job output as server-sent events, one per line. A
+// partial line is held until the rest of it arrives, or until it reaches
+// maxEventLine.
+type eventStream struct {
+	w       http.ResponseWriter
+	flusher *http.ResponseController
+	partial []byte
+}
+
+func newEventStream(w http.ResponseWriter) *eventStream {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	stream := &eventStream{w: w, flusher: http.NewResponseController(w)}
+	stream.flusher.Flush()
+	return stream
+}
+
+// Write sends every complete line of output as an event, along with any
+// unfinished line that has grown to maxEventLine
+func (s *eventStream) Write(output []byte) {
+	s.partial = append(s.partial, output...)
+	for {
+		end := bytes.IndexByte(s.partial, '\n')
+		if end == -1 {
+			break
+		}
+		s.send(strings.TrimSuffix(string(s.partial[:end]), "\r"))
+		s.partial = s.partial[end+1:]
+	}
+	for len(s.partial) >= maxEventLine {
+		s.send(string(s.partial[:maxEventLine]))
+		s.partial = s.partial[maxEventLine:]
+	}
+	// Let go of the buffer the sent lines were read from
+	s.partial = append([]byte(nil), s.partial...)
+	s.flusher.Flush()
+}
+
+// send writes one line of output as an event
+func (s *eventStream) send(line string) {
+	fmt.Fprintf(s.w, "event: output\ndata: %s\n\n", line)
+}
+
+// End sends any final partial line, then an event with the job's status
+func (s *eventStream) End(status job.JobStatus) {
+	if len(s.partial) > 0 {
+		s.send(string(s.partial))
+		s.partial = nil
+	}
+	fmt.Fprintf(s.w, "event: end\ndata: %s\n\n", status)
+	s.flusher.Flush()
+}
+
+// watchUpgrader upgrades job watch requests to WebSocket connections
+func (s *Server) watchUpgrader() *websocket.Upgrader {
+	return &websocket.Upgrader{CheckOrigin: s.checkWatchOrigin}
+}
+
+// checkWatchOrigin decides whether a browser page may open a watch. With
+// tokens configured any origin is accepted, since a page can't attach a
+// bearer token to a WebSocket handshake. Without them the API is open to
+// anything that reaches it, so browsers must come from the API's own host.
+// Requests without an Origin header don't come from browsers and are
+// accepted.
+func (s *Server) checkWatchOrigin(r *http.Request) bool {
+	if len(s.config.Auth.Tokens) > 0 {
+		return true
+	}
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+// watchWriteTimeout bounds how long sending one status message to a
+// watching client may take
+const watchWriteTimeout = 10 * time.Second
+
+// jobStatusMessage is sent to watching clients when a job's status changes
+type jobStatusMessage struct {
+	JobID    string        `json:"job_id"`
+	Status   job.JobStatus `json:"status"`
+	WorkerID string        `json:"worker_id,omitempty"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// handleWatchJob upgrades to a WebSocket and sends the job's status, then a
+// message each time it changes. The connection is closed once the job
+// reaches a terminal status, or when the client goes away.
+func (s *Server) handleWatchJob(w http.ResponseWriter, r *http.Request) {
+	jobID := mux.Vars(r)["id"]
+
+	watcher, ok := s.store.(interface {
+		WatchStatus(jobID string) (<-chan *job.Job, func())
+	})
+	if !ok {
+		s.writeError(w, r, http.StatusNotImplemented, "the job store does not support watching jobs")
+		return
+	}
+
+	// Subscribe before reading the job so no change in between is missed
+	changes, cancel := watcher.WatchStatus(jobID)
+	defer cancel()
+
+	j, err := s.store.Get(r.Context(), jobID)
+	if err != nil {
+		if job.IsJobNotFoundError(err) {
+			s.writeError(w, r, http.StatusNotFound, err.Error())
+		} else {
+			s.writeError(w, r, http.StatusInternalServerError, "failed to get job: "+err.Error())
+		}
+		return
+	}
+
+	conn, err := s.watchUpgrader().Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has already replied to the client
+		return
+	}
+	defer conn.Close()
+
+	// Read until the client closes the connection, so control frames are
+	// handled and a disconnect ends the watch
+	disconnected := make(chan struct{})
+	go func() {
+		defer close(disconnected)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	send := func(j *job.Job) error {
+		conn.SetWriteDeadline(time.Now().Add(watchWriteTimeout))
+		return conn.WriteJSON(jobStatusMessage{JobID: j.ID, Status: j.Status, WorkerID: j.WorkerID, Error: j.Error})
+	}
+
+	last := j.Status
+	if err := send(j); err != nil {
+		return
+	}
+	for !j.IsTerminal() {
+		select {
+		case <-disconnected:
+			return
+		case <-r.Context().Done():
+			return
+		case j = <-changes:
+			if j.Status == last {
+				continue
+			}
+			last = j.Status
+			if err := send(j); err != nil {
+				return
+			}
+		}
+	}
+
+	conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, "job "+string(j.Status)),
+		time.Now().Add(watchWriteTimeout))
+}
+
+// handleSignalJob sends an OS signal such as SIGHUP to the processes of a
+// running command or script job through the worker running it
+func (s *Server) handleSignalJob(w http.ResponseWriter, r *http.Request) {
+	jobID := mux.Vars(r)["id"]
+
+	var request struct {
+		Signal string `json:"signal"`
+	}
+	if err := s.decodeJSON(w, r, &request); err != nil {
+		s.writeError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	sig, err := job.ParseSignal(request.Signal)
+	if err != nil {
+		s.writeError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	j, err := s.manager.GetJob(r.Context(), jobID)
+	if err != nil {
+		if job.IsJobNotFoundError(err) {
+			s.writeError(w, r, http.StatusNotFound, err.Error())
+		} else {
+			s.writeError(w, r, http.StatusInternalServerError, "failed to get job: "+err.Error())
+		}
+		return
+	}
+
+	if !j.CanBeSignaled() {
+		s.writeError(w, r, http.StatusBadRequest, fmt.Sprintf("cannot signal %s jobs; only command and script jobs run as processes", j.Type))
+		return
+	}
+	if !j.IsRunning() {
+		s.writeError(w, r, http.StatusConflict, fmt.Sprintf("cannot signal job %s in status %s", jobID, j.Status))
+		return
+	}
+
+	worker, err := s.workers.GetWorker(r.Context(), j.WorkerID)
+	if err != nil {
+		if job.IsWorkerNotFoundError(err) {
+			s.writeError(w, r, http.StatusConflict, "job "+jobID+" is not running on a registered worker")
+		} else {
+			s.writeError(w, r, http.StatusInternalServerError, "failed to get worker: "+err.Error())
+		}
+		return
+	}
+
+	signaler, ok := worker.(interface {
+		SignalJob(jobID string, sig syscall.Signal) error
+	})
+	if !ok {
+		s.writeError(w, r, http.StatusNotImplemented, "worker "+worker.ID()+" does not support signals")
+		return
+	}
+
+	if err := signaler.SignalJob(jobID, sig); err != nil {
+		switch {
+		case job.IsJobNotFoundError(err):
+			s.writeError(w, r, http.StatusConflict, "job "+jobID+" is no longer running on worker "+worker.ID())
+		case job.IsValidationError(err):
+			s.writeError(w, r, http.StatusBadRequest, err.Error())
+		default:
+			s.writeError(w, r, http.StatusInternalServerError, "failed to signal job: "+err.Error())
+		}
+		return
+	}
+
+	response := map[string]interface{}{
+		"job_id":    jobID,
+		"worker_id": worker.ID(),
+		"signal":    sig.String(),
+	}
+
+	s.writeResponse(w, r, http.StatusOK, response)
+}
+
+// handleExtendTimeout pushes back the deadline of a running job, e.g.
+// {"extend_by": "10m"}, so its worker doesn't kill it for timing out
+func (s *Server) handleExtendTimeout(w http.ResponseWriter, r *http.Request) {
+	jobID := mux.Vars(r)["id"]
+
+	var request struct {
+		ExtendBy string `json:"extend_by"`
+	}
+	if err := s.decodeJSON(w, r, &request); err != nil {
+		s.writeError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	extra, err := time.ParseDuration(request.ExtendBy)
+	if err != nil || extra <= 0 {
+		s.writeError(w, r, http.StatusBadRequest, "extend_by must be a positive duration, e.g. 10m")
+		return
+	}
+
+	j, err := s.manager.GetJob(r.Context(), jobID)
+	if err != nil {
+		if job.IsJobNotFoundError(err) {
+			s.writeError(w, r, http.StatusNotFound, err.Error())
+		} else {
+			s.writeError(w, r, http.StatusInternalServerError, "failed to get job: "+err.Error())
+		}
+		return
+	}
+
+	if !j.IsRunning() {
+		s.writeError(w, r, http.StatusConflict, fmt.Sprintf("cannot extend the timeout of job %s in status %s", jobID, j.Status))
+		return
+	}
+	if j.Timeout <= 0 {
+		s.writeError(w, r, http.StatusBadRequest, "job "+jobID+" has no timeout to extend")
+		return
+	}
+	if limit := s.config.Scheduler.MaxJobTimeout; limit > 0 && j.Timeout+extra > limit {
+		s.writeError(w, r, http.StatusBadRequest, fmt.Sprintf("extending job %s by %v would exceed the max job timeout of %v", jobID, extra, limit))
+		return
+	}
+
+	worker, err := s.workers.GetWorker(r.Context(), j.WorkerID)
+	if err != nil {
+		if job.IsWorkerNotFoundError(err) {
+			s.writeError(w, r, http.StatusConflict, "job "+jobID+" is not running on a registered worker")
+		} else {
+			s.writeError(w, r, http.StatusInternalServerError, "failed to get worker: "+err.Error())
+		}
+		return
+	}
+
+	extender, ok := worker.(interface {
+		ExtendJobTimeout(jobID string, extra time.Duration) (time.Time, error)
+	})
+	if !ok {
+		s.writeError(w, r, http.StatusNotImplemented, "worker "+worker.ID()+" does not support timeout extensions")
+		return
+	}
+
+	deadline, err := extender.ExtendJobTimeout(jobID, extra)
+	if err != nil {
+		switch {
+		case job.IsJobNotFoundError(err):
+			s.writeError(w, r, http.StatusConflict, "job "+jobID+" is no longer running on worker "+worker.ID())
+		case job.IsConflictError(err):
+			s.writeError(w, r, http.StatusConflict, err.Error())
+		default:
+			s.writeError(w, r, http.StatusInternalServerError, "failed to extend timeout: "+err.Error())
+		}
+		return
+	}
+
+	// Keep the stored job's timeout in line with what the worker enforces
+	j, err = s.extendStoredTimeout(r.Context(), jobID, extra)
+	if err != nil {
+		if job.IsConflictError(err) {
+			s.writeError(w, r, http.StatusConflict, err.Error())
+		} else {
+			s.writeError(w, r, http.StatusInternalServerError, "failed to update job: "+err.Error())
+		}
+		return
+	}
+
+	response := map[string]interface{}{
+		"job_id":    jobID,
+		"worker_id": worker.ID(),
+		"timeout":   j.Timeout.String(),
+		"deadline":  deadline.UTC(),
+	}
+	s.writeResponse(w, r, http.StatusOK, response)
+}
+
+// extendStoredTimeout adds extra to a running job's stored timeout. Stores
+// that can do this atomically are asked to, so a result or cancellation
+// saved while the worker was being asked isn't overwritten; others get a
+// fresh read, checked to still be running, followed by a write.
+func (s *Server) extendStoredTimeout(ctx context.Context, jobID string, extra time.Duration) (*job.Job, error) {
+	if extender, ok := s.store.(interface {
+		ExtendTimeout(ctx context.Context, jobID string, extra time.Duration) (*job.Job, error)
+	}); ok {
+		return extender.ExtendTimeout(ctx, jobID, extra)
+	}
+
+	j, err := s.store.Get(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if !j.IsRunning() {
+		return nil, job.NewConflictError(fmt.Sprintf("job %s is no longer running (status %s)", jobID, j.Status))
+	}
+	j.Timeout += extra
+	if err := s.store.Update(ctx, j); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// tagSummary is a tag in use and how many jobs carry it
+type tagSummary struct {
+	Tag      string         `json:"tag"`
+	Total    int            `json:"total"`
+	ByStatus map[string]int `json:"by_status"`
+}
+
+// handleListTags lists every tag in use with its job counts, optionally
+// scoped to one status with ?status=, so clients can discover tag filters
+func (s *Server) handleListTags(w http.ResponseWriter, r *http.Request) {
+	status := job.JobStatus(r.URL.Query().Get("status"))
+	if status != "" && !status.IsValid() {
+		s.writeError(w, r, http.StatusBadRequest, "invalid status filter: "+string(status))
+		return
+	}
+
+	counts, err := s.tagCounts(r)
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "failed to count tags: "+err.Error())
+		return
+	}
+
+	tags := make([]tagSummary, 0, len(counts))
+	for tag, byStatus := range counts {
+		summary := tagSummary{Tag: tag, ByStatus: make(map[string]int)}
+		for st, n := range byStatus {
+			if status != "" && st != status {
+				continue
+			}
+			summary.ByStatus[string(st)] = n
+			summary.Total += n
+		}
+		if summary.Total > 0 {
+			tags = append(tags, summary)
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Tag < tags[j].Tag })
+
+	response := map[string]interface{}{
+		"tags":  tags,
+		"count": len(tags),
+	}
+	s.writeResponse(w, r, http.StatusOK, response)
+}
+
+// tagCounts returns job counts per tag and status, from the store's own
+// counters when it keeps them and by scanning every job otherwise
+func (s *Server) tagCounts(r *http.Request) (map[string]map[job.JobStatus]int, error) {
+	if counter, ok := s.store.(interface {
+		TagCounts(ctx context.Context) (map[string]map[job.JobStatus]int, error)
+	}); ok {
+		return counter.TagCounts(r.Context())
+	}
+
+	jobs, err := s.store.List(r.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]map[job.JobStatus]int)
+	for _, j := range jobs {
+		seen := make(map[string]bool, len(j.Tags))
+		for _, tag := range j.Tags {
+			if seen[tag] {
+				continue
+			}
+			seen[tag] = true
+			if counts[tag] == nil {
+				counts[tag] = make(map[job.JobStatus]int)
+			}
+			counts[tag][j.Status]++
+		}
+	}
+	return counts, nil
+}
+
+// Schedule Handlers
+
+const (
+	defaultPreviewCount = 5
+	maxPreviewCount     = 100
+)
+
+// schedulePreviewRequest is the body accepted by the schedule preview endpoint
+type schedulePreviewRequest struct {
+	Cron     string `json:"cron"`
+	Timezone string `json:"timezone,omitempty"` // IANA zone name, defaults to UTC
+	Count    int    `json:"count,omitempty"`    // Number of fire times, defaults to 5
+	After    string `json:"after,omitempty"`    // RFC3339 start point, defaults to now
+}
+
+func (s *Server) handlePreviewSchedule(w http.ResponseWriter, r *http.Request) {
+	var request schedulePreviewRequest
+
+	if err := s.decodeJSON(w, r, &request); err != nil {
+		s.writeError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	count := request.Count
+	if count == 0 {
+		count = defaultPreviewCount
+	}
+	if count < 0 || count > maxPreviewCount {
+		s.writeError(w, r, http.StatusBadRequest, fmt.Sprintf("count must be between 1 and %d", maxPreviewCount))
+		return
+	}
+
+	after := scheduler.Now()
+	if request.After != "" {
+		parsed, err := time.Parse(time.RFC3339, request.After)
+		if err != nil {
+			s.writeError(w, r, http.StatusBadRequest, "invalid after timestamp: "+request.After)
+			return
+		}
+		after = parsed
+	}
+
+	schedule, err := job.ParseSchedule(request.Cron, request.Timezone)
+	if err != nil {
+		s.writeError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	fireTimes := schedule.NextN(after, count)
+	next := make([]string, 0, len(fireTimes))
+	for _, t := range fireTimes {
+		next = append(next, t.Format(time.RFC3339))
+	}
+
+	response := map[string]interface{}{
+		"cron":     schedule.Expression,
+		"timezone": schedule.Location.String(),
+		"next":     next,
+		"count":    len(next),
+	}
+
+	s.writeResponse(w, r, http.StatusOK, response)
+}
+
+// Worker Handlers
+
+// workerSummaries converts workers to their API response format, with the
+// age of their last heartbeat when the registry tracks it
+func (s *Server) workerSummaries(workers []job.Worker) []map[string]interface{} {
+	heartbeats, tracked := s.workers.(interface {
+		HeartbeatAge(workerID string) (time.Duration, bool)
+	})
+
+	var workerInfo []map[string]interface{}
+	for _, worker := range workers {
+		draining := false
+		if drainable, ok := worker.(interface{ IsDraining() bool }); ok {
+			draining = drainable.IsDraining()
+		}
+		summary := map[string]interface{}{
+			"id":           worker.ID(),
+			"healthy":      worker.IsHealthy(),
+			"capacity":     worker.GetCapacity(),
+			"current_load": worker.GetCurrentLoad(),
+			"can_accept":   worker.CanAcceptJob(),
+			"draining":     draining,
+			"labels":       worker.Labels(),
+		}
+		if tracked {
+			if age, ok := heartbeats.HeartbeatAge(worker.ID()); ok {
+				summary["heartbeat_age_seconds"] = age.Seconds()
+			}
+		}
+		workerInfo = append(workerInfo, summary)
+	}
+	return workerInfo
+}
+
+func (s *Server) handleListWorkers(w http.ResponseWriter, r *http.Request) {
+	workers, err := s.workers.ListWorkers(r.Context())
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "failed to list workers: "+err.Error())
+		return
+	}
+
+	workerInfo := s.workerSummaries(workers)
+
+	response := map[string]interface{}{
+		"workers": workerInfo,
+		"count":   len(workerInfo),
+	}
+
+	s.writeResponse(w, r, http.StatusOK, response)
+}
+
+// handleRegisterWorker adds a remote worker to the registry so jobs can be
+// dispatched to it
+func (s *Server) handleRegisterWorker(w http.ResponseWriter, r *http.Request) {
+	var registration job.WorkerRegistration
+	if err := s.decodeJSON(w, r, &registration); err != nil {
+		s.writeError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := registration.Validate(); err != nil {
+		s.writeError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	worker := scheduler.NewRemoteWorker(registration)
+	if err := s.workers.Register(r.Context(), worker); err != nil {
+		switch {
+		case job.IsValidationError(err):
+			s.writeError(w, r, http.StatusConflict, err.Error())
+		case errors.Is(err, job.ErrWorkerLimitReached):
+			s.writeError(w, r, http.StatusServiceUnavailable, err.Error())
+		default:
+			s.writeError(w, r, http.StatusInternalServerError, "failed to register worker: "+err.Error())
+		}
+		return
+	}
+
+	s.writeResponse(w, r, http.StatusCreated, s.workerSummaries([]job.Worker{worker})[0])
+}
+
+func (s *Server) handleDecommissionWorker(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	workerID := vars["id"]
+
+	requeue := true
+	if value := r.URL.Query().Get("requeue"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			s.writeError(w, r, http.StatusBadRequest, "invalid requeue flag: "+value)
+			return
+		}
+		requeue = parsed
+	}
+	force := false
+	if value := r.URL.Query().Get("force"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			s.writeError(w, r, http.StatusBadRequest, "invalid force flag: "+value)
+			return
+		}
+		force = parsed
+	}
+
+	worker, err := s.workers.GetWorker(r.Context(), workerID)
+	if err != nil {
+		if job.IsWorkerNotFoundError(err) {
+			s.writeError(w, r, http.StatusNotFound, err.Error())
+		} else {
+			s.writeError(w, r, http.StatusInternalServerError, "failed to get worker: "+err.Error())
+		}
+		return
+	}
+
+	// Guard against accidentally decommissioning a live worker
+	if worker.IsHealthy() && !force {
+		s.writeError(w, r, http.StatusConflict, "worker "+workerID+" is still healthy; use force=true to decommission it")
+		return
+	}
+
+	// A live worker is still running its jobs, so stop them there first or
+	// each would run twice once released
+	if worker.IsHealthy() {
+		running, err := s.manager.ListJobs(r.Context(),
+			job.Filter{Field: "worker_id", Operator: "eq", Value: workerID},
+			job.Filter{Field: "status", Operator: "eq", Value: string(job.JobStatusRunning)},
+		)
+		if err != nil {
+			s.writeError(w, r, http.StatusInternalServerError, "failed to list worker jobs: "+err.Error())
+			return
+		}
+		if len(running) > 0 {
+			canceller, ok := worker.(interface{ CancelJob(jobID string) error })
+			if !ok {
+				s.writeError(w, r, http.StatusConflict, fmt.Sprintf("worker %s cannot cancel its running jobs; drain it and wait for them to finish", workerID))
+				return
+			}
+			for _, j := range running {
+				// A job the worker no longer knows has already finished there
+				if err := canceller.CancelJob(j.ID); err != nil && !job.IsJobNotFoundError(err) {
+					s.writeError(w, r, http.StatusInternalServerError, fmt.Sprintf("failed to stop job %s on worker: %v", j.ID, err))
+					return
+				}
+			}
+		}
+	}
+
+	// Release the jobs before unregistering, so a failed release leaves the
+	// worker in place to decommission again rather than its jobs orphaned
+	affected, err := s.manager.ReleaseWorkerJobs(r.Context(), workerID, requeue)
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "failed to release worker jobs: "+err.Error())
+		return
+	}
+
+	if err := s.workers.Unregister(r.Context(), workerID); err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "failed to unregister worker: "+err.Error())
+		return
+	}
+
+	response := map[string]interface{}{
+		"worker_id": workerID,
+		"requeued":  requeue,
+		"jobs":      affected,
+		"count":     len(affected),
+	}
+
+	s.writeResponse(w, r, http.StatusOK, response)
+}
+
+// handleDrainWorker stops a worker from taking new jobs while letting its
+// running jobs finish, e.g. ahead of a redeploy
+func (s *Server) handleDrainWorker(w http.ResponseWriter, r *http.Request) {
+	workerID := mux.Vars(r)["id"]
+
+	worker, err := s.workers.GetWorker(r.Context(), workerID)
+	if err != nil {
+		if job.IsWorkerNotFoundError(err) {
+			s.writeError(w, r, http.StatusNotFound, err.Error())
+		} else {
+			s.writeError(w, r, http.StatusInternalServerError, "failed to get worker: "+err.Error())
+		}
+		return
+	}
+
+	drainable, ok := worker.(interface {
+		Drain(ctx context.Context) error
+	})
+	if !ok {
+		s.writeError(w, r, http.StatusNotImplemented, "worker "+workerID+" does not support draining")
+		return
+	}
+
+	if err := drainable.Drain(r.Context()); err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "failed to drain worker: "+err.Error())
+		return
+	}
+
+	s.writeResponse(w, r, http.StatusOK, s.workerSummaries([]job.Worker{worker})[0])
+}
+
+// handleReleaseWorkerJobs queues a worker's running jobs again. Workers call
+// it when they shut down before their jobs finish.
+func (s *Server) handleReleaseWorkerJobs(w http.ResponseWriter, r *http.Request) {
+	workerID := mux.Vars(r)["id"]
+
+	affected, err := s.manager.ReleaseWorkerJobs(r.Context(), workerID, true)
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "failed to release worker jobs: "+err.Error())
+		return
+	}
+
+	response := map[string]interface{}{
+		"worker_id": workerID,
+		"jobs":      affected,
+		"count":     len(affected),
+	}
+
+	s.writeResponse(w, r, http.StatusOK, response)
+}
+
+// handleClaimNextJob hands a polling worker the next queued job it can take,
+// already marked running on it, or answers 204 when there is none
+func (s *Server) handleClaimNextJob(w http.ResponseWriter, r *http.Request) {
+	workerID := mux.Vars(r)["id"]
+
+	dispatcher, ok := s.manager.(interface {
+		NextJobFor(ctx context.Context, workerID string) (*job.Job, error)
+	})
+	if !ok {
+		s.writeError(w, r, http.StatusNotImplemented, "job manager cannot hand jobs to polling workers")
+		return
+	}
+
+	j, err := dispatcher.NextJobFor(r.Context(), workerID)
+	switch {
+	case errors.Is(err, job.ErrQueueEmpty), errors.Is(err, job.ErrNoWorkerAvailable):
+		w.WriteHeader(http.StatusNoContent)
+		return
+	case job.IsWorkerNotFoundError(err):
+		s.writeError(w, r, http.StatusNotFound, err.Error())
+		return
+	case err != nil:
+		s.writeError(w, r, http.StatusInternalServerError, "failed to claim a job: "+err.Error())
+		return
+	}
+
+	s.writeResponse(w, r, http.StatusOK, j)
+}
+
+// handleWorkerHeartbeat records a worker's heartbeat. Workers registered
+// over the API may send {"load": n} with it to report how busy they are.
+func (s *Server) handleWorkerHeartbeat(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	workerID := vars["id"]
+
+	var report struct {
+		Load *int `json:"load"`
+	}
+	if r.ContentLength != 0 {
+		if err := s.decodeJSON(w, r, &report); err != nil {
+			s.writeError(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		if report.Load != nil && *report.Load < 0 {
+			s.writeError(w, r, http.StatusBadRequest, "load must not be negative")
+			return
+		}
+	}
+
+	err := s.workers.Heartbeat(r.Context(), workerID)
+	if err != nil {
+		if job.IsWorkerNotFoundError(err) {
+			s.writeError(w, r, http.StatusNotFound, err.Error())
+		} else {
+			s.writeError(w, r, http.StatusInternalServerError, "failed to update heartbeat: "+err.Error())
+		}
+		return
+	}
+
+	if report.Load != nil {
+		if worker, err := s.workers.GetWorker(r.Context(), workerID); err == nil {
+			if loaded, ok := worker.(interface{ SetLoad(int) }); ok {
+				loaded.SetLoad(*report.Load)
+			}
+		}
+	}
+
+	s.writeResponse(w, r, http.StatusOK, map[string]string{"message": "heartbeat updated"})
+}
+
+// handleWorkerJobLogFile streams the log file a worker kept for one of its jobs
+func (s *Server) handleWorkerJobLogFile(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	workerID, jobID := vars["id"], vars["jobID"]
+
+	worker, err := s.workers.GetWorker(r.Context(), workerID)
+	if err != nil {
+		if job.IsWorkerNotFoundError(err) {
+			s.writeError(w, r, http.StatusNotFound, err.Error())
+		} else {
+			s.writeError(w, r, http.StatusInternalServerError, "failed to get worker: "+err.Error())
+		}
+		return
+	}
+
+	logger, ok := worker.(interface {
+		JobLogFile(jobID string) (string, error)
+	})
+	if !ok {
+		s.writeError(w, r, http.StatusNotImplemented, "worker "+workerID+" does not keep job logs")
+		return
+	}
+
+	path, err := logger.JobLogFile(jobID)
+	if err != nil {
+		switch {
+		case job.IsJobNotFoundError(err):
+			s.writeError(w, r, http.StatusNotFound, "no log file for job "+jobID+" on worker "+workerID)
+		case job.IsValidationError(err):
+			s.writeError(w, r, http.StatusBadRequest, err.Error())
+		default:
+			s.writeError(w, r, http.StatusInternalServerError, "failed to find log file: "+err.Error())
+		}
+		return
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "failed to open log file: "+err.Error())
+		return
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	io.Copy(w, f)
+}
+
+// System Handlers
+
+// handleHealth reports worker counts along with the dependency checks, with
+// 503 when the store can't be reached
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	workers, err := s.workers.ListWorkers(r.Context())
+	if err != nil {
+		s.logger.Error("health check failed to list workers", "error", err)
+		s.writeError(w, r, http.StatusServiceUnavailable, "failed to check workers")
+		return
+	}
+
+	report := healthReport(workers)
+	checks, ready := s.checkDependencies(r.Context())
+	report["checks"] = checks
+
+	status := http.StatusOK
+	if !ready {
+		report["status"] = "unhealthy"
+		status = http.StatusServiceUnavailable
+	}
+	s.writeResponse(w, r, status, report)
+}
+
+// handleLiveness answers as long as the process is serving requests, for
+// restarting a scheduler that has hung
+func (s *Server) handleLiveness(w http.ResponseWriter, r *http.Request) {
+	s.writeResponse(w, r, http.StatusOK, map[string]interface{}{
+		"status":    "alive",
+		"timestamp": scheduler.Now(),
+	})
+}
+
+// handleReadiness reports whether the scheduler's dependencies can be
+// reached, with 503 when any can't, for taking it out of service until they
+// recover
+func (s *Server) handleReadiness(w http.ResponseWriter, r *http.Request) {
+	checks, ready := s.checkDependencies(r.Context())
+
+	response := map[string]interface{}{
+		"status":    "ready",
+		"checks":    checks,
+		"timestamp": scheduler.Now(),
+	}
+	status := http.StatusOK
+	if !ready {
+		response["status"] = "not ready"
+		status = http.StatusServiceUnavailable
+	}
+	s.writeResponse(w, r, status, response)
+}
+
+// healthCheckTimeout bounds each dependency check, so a hung backend fails
+// the check instead of hanging the probe
+const healthCheckTimeout = 2 * time.Second
+
+// checkDependencies checks each dependency the scheduler needs to serve
+// requests, returning "ok" or "unreachable" for each and whether all passed.
+// Health checks answer without a token, so the errors themselves, which can
+// name internal addresses, are only logged.
+func (s *Server) checkDependencies(ctx context.Context) (map[string]string, bool) {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	checks := map[string]string{"store": "ok"}
+	if err := s.store.Ping(ctx); err != nil {
+		s.logger.Error("health check failed to reach the store", "error", err)
+		checks["store"] = "unreachable"
+		return checks, false
+	}
+	return checks, true
+}
+
+// healthReport summarizes scheduler health from the registered workers
+func healthReport(workers []job.Worker) map[string]interface{} {
+	healthyWorkers := 0
+	for _, worker := range workers {
+		if worker.IsHealthy() {
+			healthyWorkers++
+		}
+	}
+
+	return map[string]interface{}{
+		"status":          "healthy",
+		"total_workers":   len(workers),
+		"healthy_workers": healthyWorkers,
+		"timestamp":       scheduler.Now(),
+	}
+}
+
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	workers, _ := s.workers.ListWorkers(r.Context())
+	s.writeResponse(w, r, http.StatusOK, s.metricsReport(r, workers))
+}
+
+// metricsReport builds the job, queue and worker metrics. Job counts are
+// read from the Prometheus registry so they match what /metrics exposes.
+func (s *Server) metricsReport(r *http.Request, workers []job.Worker) map[string]interface{} {
+	families, _ := s.metrics.Gather() // A failing collector only leaves out its own metrics
+
+	jobCounts := gatheredValues(families, jobsByStatusMetric, "status")
+	totalJobs := 0
+	for _, count := range jobCounts {
+		totalJobs += count
+	}
+
+	// Get worker metrics
+	totalCapacity := 0
+	totalLoad := 0
+	healthyWorkers := 0
+
+	for _, worker := range workers {
+		totalCapacity += worker.GetCapacity()
+		totalLoad += worker.GetCurrentLoad()
+		if worker.IsHealthy() {
+			healthyWorkers++
+		}
+	}
+
+	workerMetrics := map[string]interface{}{
+		"total":          len(workers),
+		"healthy":        healthyWorkers,
+		"total_capacity": totalCapacity,
+		"total_load":     totalLoad,
+		"utilization":    calculateUtilization(totalLoad, totalCapacity),
+	}
+	if limited, ok := s.workers.(interface{ MaxWorkers() int }); ok && limited.MaxWorkers() > 0 {
+		workerMetrics["max"] = limited.MaxWorkers()
+	}
+
+	metrics := map[string]interface{}{
+		"jobs": map[string]interface{}{
+			"total":     totalJobs,
+			"by_status": jobCounts,
+			"submitted": gatheredValues(families, jobsSubmittedMetric, "type"),
+			"completed": gatheredValues(families, jobsCompletedMetric, "type"),
+			"failed":    gatheredValues(families, jobsFailedMetric, "type"),
+		},
+		"queue":     s.queueMetrics(r),
+		"workers":   workerMetrics,
+		"timestamp": scheduler.Now(),
+	}
+
+	return metrics
+}
+
+// queueMetrics reports queue depth overall and by tenant, the oldest waiting
+// job and a cumulative histogram of enqueue-to-dispatch wait times in seconds
+func (s *Server) queueMetrics(r *http.Request) map[string]interface{} {
+	stats, err := s.queue.Stats(r.Context())
+	if err != nil {
+		return map[string]interface{}{"error": err.Error()}
+	}
+
+	buckets := make(map[string]int, len(stats.WaitBuckets)+1)
+	for _, bucket := range stats.WaitBuckets {
+		buckets[strconv.FormatFloat(bucket.UpperBound.Seconds(), 'g', -1, 64)] = bucket.Count
+	}
+	buckets["+Inf"] = stats.WaitCount
+
+	metrics := map[string]interface{}{
+		"depth":              stats.Depth,
+		"oldest_age_seconds": stats.OldestAge(scheduler.Now()).Seconds(),
+		"wait_seconds": map[string]interface{}{
+			"buckets": buckets,
+			"count":   stats.WaitCount,
+			"sum":     stats.WaitSum.Seconds(),
+		},
+	}
+	if stats.OldestJobID != "" {
+		metrics["oldest_job_id"] = stats.OldestJobID
+		metrics["oldest_enqueued_at"] = stats.OldestEnqueuedAt
+	}
+	if len(stats.TenantDepths) > 0 {
+		metrics["tenant_depths"] = stats.TenantDepths
+	}
+
+	return metrics
+}
+
+// Dashboard recent-jobs limits
+const (
+	defaultDashboardJobs = 20
+	maxDashboardJobs     = 500
+)
+
+// handleDashboard composes recent jobs, worker summaries, metrics and health
+// into one response so dashboards refresh with a single consistent call.
+// ?recent=N sets how many of the newest jobs are included and ?status=
+// restricts them to one status.
+func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	recent := defaultDashboardJobs
+	if raw := r.URL.Query().Get("recent"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 0 || parsed > maxDashboardJobs {
+			s.writeError(w, r, http.StatusBadRequest, fmt.Sprintf("recent must be between 0 and %d", maxDashboardJobs))
+			return
+		}
+		recent = parsed
+	}
+
+	var filters []job.Filter
+	if status := r.URL.Query().Get("status"); status != "" {
+		if !job.JobStatus(status).IsValid() {
+			s.writeError(w, r, http.StatusBadRequest, "invalid status filter: "+status)
+			return
+		}
+		filters = append(filters, job.Filter{Field: "status", Operator: "eq", Value: status})
+	}
+
+	jobs, err := s.manager.ListJobs(r.Context(), filters...)
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "failed to list jobs: "+err.Error())
+		return
+	}
+
+	// Newest first, with the ID as a tiebreaker so the order is stable
+	sort.Slice(jobs, func(i, j int) bool {
+		if !jobs[i].CreatedAt.Equal(jobs[j].CreatedAt) {
+			return jobs[i].CreatedAt.After(jobs[j].CreatedAt)
+		}
+		return jobs[i].ID < jobs[j].ID
+	})
+	if len(jobs) > recent {
+		jobs = jobs[:recent]
+	}
+	omitEvents(jobs)
+
+	workers, err := s.workers.ListWorkers(r.Context())
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "failed to list workers: "+err.Error())
+		return
+	}
+
+	response := map[string]interface{}{
+		"recent_jobs": jobs,
+		"workers":     s.workerSummaries(workers),
+		"metrics":     s.metricsReport(r, workers),
+		"health":      healthReport(workers),
+		"timestamp":   scheduler.Now(),
+	}
+
+	s.writeResponse(w, r, http.StatusOK, response)
+}
+
+// Helper methods
+
+// writeResponse writes data in the format negotiated from the request's
+// Accept header, falling back to JSON for anything other than YAML
+func (s *Server) writeResponse(w http.ResponseWriter, r *http.Request, status int, data interface{}) {
+	if acceptsYAML(r) {
+		s.writeYAML(w, status, data)
+		return
+	}
+	s.writeJSON(w, status, data)
+}
+
+func (s *Server) writeJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+// writeYAML writes data as YAML. Values are converted through their JSON form
+// first so field names and omitted fields match the JSON API exactly.
+func (s *Server) writeYAML(w http.ResponseWriter, status int, data interface{}) {
+	var generic interface{}
+
+	encoded, err := json.Marshal(data)
+	if err == nil {
+		err = json.Unmarshal(encoded, &generic)
+	}
+	if err != nil {
+		s.writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to encode response: " + err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/yaml")
+	w.WriteHeader(status)
+	yaml.NewEncoder(w).Encode(generic)
+}
+
+func (s *Server) writeError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	s.writeResponse(w, r, status, map[string]string{"error": message})
+}
+
+// decodeJSON reads a request body holding a single JSON value into v. Bodies
+// over the configured size are cut off rather than buffered, and fields v
+// doesn't have are rejected so a misspelt field isn't silently ignored. The
+// error is worded for the client.
+func (s *Server) decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	r.Body = http.MaxBytesReader(w, r.Body, s.config.API.MaxBodyBytes)
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return describeDecodeError(err)
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		if err != nil {
+			return describeDecodeError(err)
+		}
+		return fmt.Errorf("invalid JSON: unexpected data after the request body")
+	}
+	return nil
+}
+
+// describeDecodeError rewords a JSON decoding error for the client
+func describeDecodeError(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+
+	switch {
+	case errors.As(err, &maxBytesErr):
+		return fmt.Errorf("request body exceeds the limit of %d bytes", maxBytesErr.Limit)
+	case errors.Is(err, io.EOF):
+		return fmt.Errorf("request body is empty")
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return fmt.Errorf("invalid JSON: request body ends mid-value")
+	case errors.As(err, &syntaxErr):
+		return fmt.Errorf("invalid JSON at byte %d: %v", syntaxErr.Offset, syntaxErr)
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		return fmt.Errorf("invalid value for field %s: expected %s, got JSON %s", typeErr.Field, typeErr.Type, typeErr.Value)
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		return fmt.Errorf("unknown field %s", strings.TrimPrefix(err.Error(), "json: unknown field "))
+	default:
+		return fmt.Errorf("invalid JSON: %v", err)
+	}
+}
+
+// acceptsYAML reports whether the first supported media type in the Accept header is YAML
+func acceptsYAML(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		switch mediaType {
+		case "application/yaml", "application/x-yaml", "text/yaml":
+			return true
+		case "application/json", "*/*", "application/*":
+			return false
+		}
+	}
+	return false
+}
+
+// Middleware
+
+func (s *Server) loggingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		start := time.Now()
+		next.ServeHTTP(recorder, r)
+		s.logger.Info("request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", recorder.status,
+			"duration", time.Since(start))
+	})
+}
+
+// healthPaths lists the health check endpoints, which stay open without a
+// bearer token so probes don't need one
+var healthPaths = map[string]bool{
+	"/api/v1/health":       true,
+	"/api/v1/health/live":  true,
+	"/api/v1/health/ready": true,
+}
+
+// authMiddleware requires a configured bearer token on every API request
+// except health checks. It does nothing when no tokens are configured.
+func (s *Server) authMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(s.config.Auth.Tokens) == 0 || healthPaths[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !found || !s.validToken(token) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			s.writeError(w, r, http.StatusUnauthorized, "missing or invalid bearer token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// validToken reports whether token is one of the configured tokens,
+// comparing in constant time
+func (s *Server) validToken(token string) bool {
+	valid := false
+	for _, configured := range s.config.Auth.Tokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(configured)) == 1 {
+			valid = true
+		}
+	}
+	return valid
 }
 
 func (s *Server) corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 
 		if r.Method == "OPTIONS" {
@@ -0,0 +1,2376 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"infinitrain/internal/clock"
+	"infinitrain/internal/config"
+	"infinitrain/internal/scheduler"
+	"infinitrain/pkg/job"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"gopkg.in/yaml.v3"
+)
+
+// testEnv bundles a server with the in-memory components behind it
+type testEnv struct {
+	server   *Server
+	store    *scheduler.MemoryStore
+	queue    *scheduler.PriorityQueue
+	manager  *scheduler.Manager
+	registry *scheduler.MemoryWorkerRegistry
+}
+
+// newTestServer creates a server backed by an in-memory store and queue
+func newTestServer(t *testing.T) *testEnv {
+	t.Helper()
+
+	store := scheduler.NewMemoryStore()
+	queue := scheduler.NewPriorityQueue()
+	registry := scheduler.NewMemoryWorkerRegistry()
+	manager := scheduler.NewManager(store, queue, registry)
+
+	return &testEnv{
+		server:   NewServer(config.LoadConfig(), store, queue, manager, registry),
+		store:    store,
+		queue:    queue,
+		manager:  manager,
+		registry: registry,
+	}
+}
+
+// fakeWorker is a job.Worker with fixed load and health for handler tests
+type fakeWorker struct {
+	id      string
+	healthy bool
+}
+
+func (w *fakeWorker) ID() string                      { return w.id }
+func (w *fakeWorker) Start(ctx context.Context) error { return nil }
+func (w *fakeWorker) Stop(ctx context.Context) error  { return nil }
+func (w *fakeWorker) IsHealthy() bool                 { return w.healthy }
+func (w *fakeWorker) GetCapacity() int                { return 4 }
+func (w *fakeWorker) GetCurrentLoad() int             { return 0 }
+func (w *fakeWorker) CanAcceptJob() bool              { return w.healthy }
+func (w *fakeWorker) Labels() map[string]string       { return nil }
+
+// drainableWorker is a fakeWorker that supports draining
+type drainableWorker struct {
+	fakeWorker
+	draining bool
+}
+
+func (w *drainableWorker) Drain(ctx context.Context) error { w.draining = true; return nil }
+func (w *drainableWorker) IsDraining() bool                { return w.draining }
+func (w *drainableWorker) CanAcceptJob() bool              { return w.healthy && !w.draining }
+
+// cancellingWorker is a fakeWorker that records the jobs it is told to cancel
+type cancellingWorker struct {
+	fakeWorker
+	cancelled []string
+}
+
+func (w *cancellingWorker) CancelJob(jobID string) error {
+	w.cancelled = append(w.cancelled, jobID)
+	return nil
+}
+
+// seedJobs stores jobs directly, bypassing submission
+func seedJobs(t *testing.T, env *testEnv, jobs ...*job.Job) {
+	t.Helper()
+
+	for _, j := range jobs {
+		if j.CreatedAt.IsZero() {
+			j.CreatedAt = time.Now()
+		}
+		if err := env.store.Create(context.Background(), j); err != nil {
+			t.Fatalf("failed to seed job: %v", err)
+		}
+	}
+}
+
+// doRequest sends a request through the server's router and returns the recorder
+func doRequest(t *testing.T, server *Server, method, path string, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var reader bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&reader).Encode(body); err != nil {
+			t.Fatalf("failed to encode request body: %v", err)
+		}
+	}
+
+	req := httptest.NewRequest(method, path, &reader)
+	rec := httptest.NewRecorder()
+	server.SetupRoutes().ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHandleBatchJobStatus(t *testing.T) {
+	env := newTestServer(t)
+	ctx := context.Background()
+
+	seeded := []*job.Job{
+		{ID: "job-a", Type: job.JobTypeCommand, Status: job.JobStatusRunning, Progress: 40, CreatedAt: time.Now()},
+		{ID: "job-b", Type: job.JobTypeCommand, Status: job.JobStatusFailed, ExitCode: 2, CreatedAt: time.Now()},
+	}
+	for _, j := range seeded {
+		if err := env.store.Create(ctx, j); err != nil {
+			t.Fatalf("failed to seed job: %v", err)
+		}
+	}
+
+	rec := doRequest(t, env.server, http.MethodPost, "/api/v1/jobs/status", map[string]interface{}{
+		"job_ids": []string{"job-a", "job-b", "job-missing"},
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var response struct {
+		Jobs  map[string]map[string]interface{} `json:"jobs"`
+		Count int                               `json:"count"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if response.Count != 3 {
+		t.Errorf("Expected 3 entries, got %d", response.Count)
+	}
+
+	tests := []struct {
+		id       string
+		status   string
+		exitCode float64
+		progress float64
+		notFound bool
+	}{
+		{id: "job-a", status: "running", progress: 40},
+		{id: "job-b", status: "failed", exitCode: 2},
+		{id: "job-missing", notFound: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			entry, ok := response.Jobs[tt.id]
+			if !ok {
+				t.Fatalf("Expected entry for %s", tt.id)
+			}
+
+			if tt.notFound {
+				if entry["error"] == nil {
+					t.Errorf("Expected not-found error for %s, got %v", tt.id, entry)
+				}
+				return
+			}
+
+			if entry["status"] != tt.status {
+				t.Errorf("Expected status %v, got %v", tt.status, entry["status"])
+			}
+			if entry["exit_code"] != tt.exitCode {
+				t.Errorf("Expected exit code %v, got %v", tt.exitCode, entry["exit_code"])
+			}
+			if entry["progress"] != tt.progress {
+				t.Errorf("Expected progress %v, got %v", tt.progress, entry["progress"])
+			}
+		})
+	}
+}
+
+func TestHandleSubmitJobBatch(t *testing.T) {
+	env := newTestServer(t)
+	env.server.config.API.MaxBatchSize = 3
+
+	rec := doRequest(t, env.server, http.MethodPost, "/api/v1/jobs/batch", []map[string]interface{}{
+		{"type": "command", "command": "echo one"},
+		{"type": "command"},
+		{"type": "script", "script": "echo two"},
+	})
+	if rec.Code != http.StatusMultiStatus {
+		t.Fatalf("Expected status 207, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var response struct {
+		Results []batchSubmitResult `json:"results"`
+	}
+	json.NewDecoder(rec.Body).Decode(&response)
+	if len(response.Results) != 3 {
+		t.Fatalf("Expected 3 results, got %d", len(response.Results))
+	}
+	for i, want := range []int{http.StatusCreated, http.StatusBadRequest, http.StatusCreated} {
+		result := response.Results[i]
+		if result.Index != i || result.Status != want {
+			t.Errorf("Result %d: expected status %d, got %+v", i, want, result)
+		}
+		if (result.Job != nil) != (want == http.StatusCreated) || (result.Error != "") == (want == http.StatusCreated) {
+			t.Errorf("Result %d: expected a job or an error, got %+v", i, result)
+		}
+	}
+	if jobs, _ := env.store.List(context.Background()); len(jobs) != 2 {
+		t.Errorf("Expected the valid jobs to be stored, got %d jobs", len(jobs))
+	}
+
+	rec = doRequest(t, env.server, http.MethodPost, "/api/v1/jobs/batch", []map[string]interface{}{
+		{"type": "command", "command": "echo three"},
+	})
+	if rec.Code != http.StatusCreated {
+		t.Errorf("Expected status 201 when every job is created, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	for name, body := range map[string]interface{}{
+		"empty":    []map[string]interface{}{},
+		"too many": make([]map[string]interface{}, 4),
+		"object":   map[string]interface{}{"type": "command"},
+	} {
+		if rec := doRequest(t, env.server, http.MethodPost, "/api/v1/jobs/batch", body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", name, rec.Code)
+		}
+	}
+}
+
+func TestHandleBatchJobStatus_EmptyRequest(t *testing.T) {
+	env := newTestServer(t)
+
+	rec := doRequest(t, env.server, http.MethodPost, "/api/v1/jobs/status", map[string]interface{}{
+		"job_ids": []string{},
+	})
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", rec.Code)
+	}
+}
+
+func TestHandleBatchJobStatus_TooManyIDs(t *testing.T) {
+	env := newTestServer(t)
+	env.server.config.API.MaxBatchSize = 2
+
+	rec := doRequest(t, env.server, http.MethodPost, "/api/v1/jobs/status", map[string]interface{}{
+		"job_ids": []string{"job-a", "job-b", "job-c"},
+	})
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestHandleUpdatePriority(t *testing.T) {
+	env := newTestServer(t)
+	ctx := context.Background()
+
+	var submitted []*job.Job
+	for _, priority := range []int{5, 3, 1} {
+		j, err := env.manager.Submit(ctx, &job.JobRequest{
+			Type:     job.JobTypeCommand,
+			Command:  "true",
+			Priority: priority,
+		})
+		if err != nil {
+			t.Fatalf("Submit() error = %v", err)
+		}
+		submitted = append(submitted, j)
+	}
+	lowest := submitted[2]
+
+	rec := doRequest(t, env.server, http.MethodPatch, "/api/v1/jobs/"+lowest.ID, map[string]int{"priority": 10})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	// Browsers only send the PATCH if CORS allows it
+	if methods := rec.Header().Get("Access-Control-Allow-Methods"); !strings.Contains(methods, http.MethodPatch) {
+		t.Errorf("Expected CORS to allow PATCH, got %q", methods)
+	}
+
+	stored, err := env.store.Get(ctx, lowest.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if stored.Priority != 10 {
+		t.Errorf("Expected stored priority 10, got %d", stored.Priority)
+	}
+
+	next, err := env.queue.Dequeue(ctx)
+	if err != nil {
+		t.Fatalf("Dequeue() error = %v", err)
+	}
+	if next.ID != lowest.ID {
+		t.Errorf("Expected reprioritized job %s to dequeue first, got %s", lowest.ID, next.ID)
+	}
+}
+
+func TestHandleGetJob_EffectivePriority(t *testing.T) {
+	env := newTestServer(t)
+	ctx := context.Background()
+	env.queue.SetAging(time.Nanosecond, 9)
+
+	submitted, err := env.manager.Submit(ctx, &job.JobRequest{Type: job.JobTypeCommand, Command: "true", Priority: 2})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+
+	rec := doRequest(t, env.server, http.MethodGet, "/api/v1/jobs/"+submitted.ID, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var got job.Job
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if got.Priority != 2 || got.EffectivePriority != 9 {
+		t.Errorf("Expected priority 2 aged to 9, got %d and %d", got.Priority, got.EffectivePriority)
+	}
+
+	stored, err := env.store.Get(ctx, submitted.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if stored.EffectivePriority != 0 {
+		t.Errorf("Expected the effective priority to stay out of the store, got %d", stored.EffectivePriority)
+	}
+}
+
+func TestHandleRequeueJob(t *testing.T) {
+	env := newTestServer(t)
+	ctx := context.Background()
+	started := time.Now().UTC().Add(-time.Minute)
+	completed := started.Add(30 * time.Second)
+	seedJobs(t, env,
+		&job.Job{
+			ID: "job-failed", Type: job.JobTypeCommand, Status: job.JobStatusFailed, WorkerID: "worker-1",
+			StartedAt: &started, CompletedAt: &completed, Output: "partial", Error: "exit status 2", ExitCode: 2,
+		},
+		&job.Job{ID: "job-completed", Type: job.JobTypeCommand, Status: job.JobStatusCompleted},
+	)
+
+	rec := doRequest(t, env.server, http.MethodPost, "/api/v1/jobs/job-failed/requeue", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	stored, err := env.store.Get(ctx, "job-failed")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if stored.Status != job.JobStatusQueued {
+		t.Errorf("Expected queued status, got %s", stored.Status)
+	}
+	if stored.Output != "" || stored.Error != "" || stored.ExitCode != 0 || stored.StartedAt != nil || stored.CompletedAt != nil || stored.WorkerID != "" {
+		t.Errorf("Expected run fields to be cleared, got %+v", stored)
+	}
+	if len(stored.History) != 1 || stored.History[0].Error != "exit status 2" || stored.History[0].ExitCode != 2 {
+		t.Errorf("Expected the failed run in history, got %+v", stored.History)
+	}
+
+	next, err := env.queue.Dequeue(ctx)
+	if err != nil {
+		t.Fatalf("Dequeue() error = %v", err)
+	}
+	if next.ID != "job-failed" {
+		t.Errorf("Expected job-failed to re-enter the queue under its ID, got %s", next.ID)
+	}
+
+	for path, want := range map[string]int{
+		"/api/v1/jobs/job-completed/requeue": http.StatusConflict,
+		"/api/v1/jobs/job-missing/requeue":   http.StatusNotFound,
+	} {
+		if rec := doRequest(t, env.server, http.MethodPost, path, nil); rec.Code != want {
+			t.Errorf("POST %s: expected status %d, got %d", path, want, rec.Code)
+		}
+	}
+}
+
+func TestHandleGetJobEvents(t *testing.T) {
+	env := newTestServer(t)
+	ctx := context.Background()
+	submitted, err := env.manager.Submit(ctx, &job.JobRequest{Type: job.JobTypeCommand, Command: "true"})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+
+	j, err := env.store.Get(ctx, submitted.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	j.WorkerID = "worker-1"
+	if err := j.UpdateStatus(job.JobStatusRunning); err != nil {
+		t.Fatalf("UpdateStatus() error = %v", err)
+	}
+	j.Error = "exit status 1"
+	if err := j.UpdateStatus(job.JobStatusFailed); err != nil {
+		t.Fatalf("UpdateStatus() error = %v", err)
+	}
+	if err := env.store.Update(ctx, j); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	rec := doRequest(t, env.server, http.MethodGet, "/api/v1/jobs/"+submitted.ID+"/events", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var response struct {
+		JobID  string         `json:"job_id"`
+		Events []job.JobEvent `json:"events"`
+		Count  int            `json:"count"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if response.JobID != submitted.ID || response.Count != len(response.Events) {
+		t.Errorf("Unexpected response %+v", response)
+	}
+
+	var statuses []string
+	for _, event := range response.Events {
+		statuses = append(statuses, string(event.Status))
+	}
+	want := []string{"pending", "queued", "running", "failed"}
+	if strings.Join(statuses, ",") != strings.Join(want, ",") {
+		t.Fatalf("Expected events %v, got %v", want, statuses)
+	}
+	if running := response.Events[2]; running.WorkerID != "worker-1" {
+		t.Errorf("Expected the running event to name worker-1, got %+v", running)
+	}
+	if failed := response.Events[3]; failed.Message != "exit status 1" {
+		t.Errorf("Expected the failed event to carry the error, got %+v", failed)
+	}
+	for i := 1; i < len(response.Events); i++ {
+		if response.Events[i].Timestamp.Before(response.Events[i-1].Timestamp) {
+			t.Errorf("Expected events in order, got %+v", response.Events)
+		}
+	}
+
+	// Listings leave the history out; a single job still carries it
+	for _, path := range []string{"/api/v1/jobs", "/api/v1/dashboard"} {
+		if rec := doRequest(t, env.server, http.MethodGet, path, nil); strings.Contains(rec.Body.String(), `"events"`) {
+			t.Errorf("Expected GET %s to omit job events, got %s", path, rec.Body.String())
+		}
+	}
+	if rec := doRequest(t, env.server, http.MethodPost, "/api/v1/jobs/search", map[string]interface{}{}); strings.Contains(rec.Body.String(), `"events"`) {
+		t.Errorf("Expected a search to omit job events, got %s", rec.Body.String())
+	}
+	if rec := doRequest(t, env.server, http.MethodGet, "/api/v1/jobs/"+submitted.ID, nil); !strings.Contains(rec.Body.String(), `"events"`) {
+		t.Errorf("Expected a single job to carry its events, got %s", rec.Body.String())
+	}
+
+	if rec := doRequest(t, env.server, http.MethodGet, "/api/v1/jobs/job-missing/events", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for a missing job, got %d", rec.Code)
+	}
+}
+
+func TestHandleGetJobOutput(t *testing.T) {
+	env := newTestServer(t)
+	output := "one\ntwo\nthree\n"
+	seedJobs(t, env,
+		&job.Job{ID: "job-done", Type: job.JobTypeCommand, Status: job.JobStatusCompleted},
+		&job.Job{ID: "job-running", Type: job.JobTypeCommand, Status: job.JobStatusRunning},
+	)
+
+	// The output a worker reported is served, not only what the job carries
+	reported := job.JobResult{Status: job.JobStatusCompleted, Output: output}
+	if rec := doRequest(t, env.server, http.MethodPost, "/api/v1/jobs/job-done/result", reported); rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 saving the result, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{query: "", want: output},
+		{query: "?tail=2", want: "two\nthree\n"},
+		{query: "?tail=10", want: output},
+		{query: "?tail=0", want: ""},
+		{query: "?offset=4", want: "two\nthree\n"},
+		{query: "?offset=4&limit=3", want: "two"},
+		{query: "?offset=100", want: ""},
+	}
+	for _, tt := range tests {
+		rec := doRequest(t, env.server, http.MethodGet, "/api/v1/jobs/job-done/output"+tt.query, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s: expected status 200, got %d: %s", tt.query, rec.Code, rec.Body.String())
+		}
+		if got := rec.Body.String(); got != tt.want {
+			t.Errorf("GET %s: expected %q, got %q", tt.query, tt.want, got)
+		}
+		if contentType := rec.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/plain") {
+			t.Errorf("GET %s: expected plain text, got %s", tt.query, contentType)
+		}
+		if size := rec.Header().Get("X-Output-Size"); size != strconv.Itoa(len(output)) {
+			t.Errorf("GET %s: expected X-Output-Size %d, got %s", tt.query, len(output), size)
+		}
+	}
+
+	for path, want := range map[string]int{
+		"/api/v1/jobs/job-done/output?tail=1&offset=2": http.StatusBadRequest,
+		"/api/v1/jobs/job-done/output?tail=-1":         http.StatusBadRequest,
+		"/api/v1/jobs/job-done/output?offset=x":        http.StatusBadRequest,
+		"/api/v1/jobs/job-missing/output":              http.StatusNotFound,
+		"/api/v1/jobs/job-running/output":              http.StatusConflict,
+	} {
+		if rec := doRequest(t, env.server, http.MethodGet, path, nil); rec.Code != want {
+			t.Errorf("GET %s: expected status %d, got %d", path, want, rec.Code)
+		}
+	}
+}
+
+func TestHandleJobResult(t *testing.T) {
+	env := newTestServer(t)
+	started := time.Now().UTC().Add(-time.Minute).Truncate(time.Second)
+	completed := started.Add(30 * time.Second)
+	seedJobs(t, env,
+		&job.Job{ID: "job-done", Type: job.JobTypeCommand, Status: job.JobStatusCompleted, StartedAt: &started, CompletedAt: &completed, Output: "rebuilt"},
+		&job.Job{ID: "job-running", Type: job.JobTypeCommand, Status: job.JobStatusRunning},
+	)
+
+	// Without a stored result, the result is rebuilt from the job
+	rec := doRequest(t, env.server, http.MethodGet, "/api/v1/jobs/job-done/result", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var result job.JobResult
+	if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if result.Output != "rebuilt" || result.Duration != 30*time.Second || !result.StartedAt.Equal(started) || !result.CompletedAt.Equal(completed) {
+		t.Errorf("Expected the result rebuilt from the job, got %+v", result)
+	}
+
+	reported := job.JobResult{
+		Status: job.JobStatusCompleted, Output: "reported", ExitCode: 0,
+		StartedAt: started, CompletedAt: completed.Add(time.Second), Duration: 31 * time.Second,
+	}
+	if rec := doRequest(t, env.server, http.MethodPost, "/api/v1/jobs/job-done/result", reported); rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 saving the result, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = doRequest(t, env.server, http.MethodGet, "/api/v1/jobs/job-done/result", nil)
+	result = job.JobResult{}
+	if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if result.JobID != "job-done" || result.Output != "reported" || result.Duration != 31*time.Second {
+		t.Errorf("Expected the stored result, got %+v", result)
+	}
+
+	for _, tt := range []struct {
+		method string
+		path   string
+		body   interface{}
+		want   int
+	}{
+		{http.MethodGet, "/api/v1/jobs/job-running/result", nil, http.StatusConflict},
+		{http.MethodGet, "/api/v1/jobs/job-missing/result", nil, http.StatusNotFound},
+		{http.MethodPost, "/api/v1/jobs/job-missing/result", reported, http.StatusNotFound},
+		{http.MethodPost, "/api/v1/jobs/job-done/result", job.JobResult{Status: "bogus"}, http.StatusBadRequest},
+		{http.MethodPost, "/api/v1/jobs/job-done/result", job.JobResult{JobID: "job-running", Status: job.JobStatusCompleted}, http.StatusBadRequest},
+	} {
+		if rec := doRequest(t, env.server, tt.method, tt.path, tt.body); rec.Code != tt.want {
+			t.Errorf("%s %s: expected status %d, got %d", tt.method, tt.path, tt.want, rec.Code)
+		}
+	}
+}
+
+func TestHandleSaveJobResult_FinishesJob(t *testing.T) {
+	env := newTestServer(t)
+	ctx := context.Background()
+	env.registry.Register(ctx, &fakeWorker{id: "w1", healthy: true})
+	dispatcher := scheduler.NewDefaultScheduler(env.store, env.queue, env.registry)
+
+	// submit runs a job through the API and dispatches it to w1
+	submit := func(request job.JobRequest) string {
+		t.Helper()
+		rec := doRequest(t, env.server, http.MethodPost, "/api/v1/jobs", request)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("Expected status 201, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var submitted job.Job
+		if err := json.NewDecoder(rec.Body).Decode(&submitted); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if j, err := dispatcher.GetNextJob(ctx); err != nil || j.ID != submitted.ID {
+			t.Fatalf("Expected %s to be dispatched, got %v (%v)", submitted.ID, j, err)
+		}
+		return submitted.ID
+	}
+	status := func(id string) job.JobStatus {
+		t.Helper()
+		var j job.Job
+		if err := json.NewDecoder(doRequest(t, env.server, http.MethodGet, "/api/v1/jobs/"+id, nil).Body).Decode(&j); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return j.Status
+	}
+
+	retries := 1
+	completed := submit(job.JobRequest{Type: job.JobTypeCommand, Command: "true"})
+	retried := submit(job.JobRequest{Type: job.JobTypeCommand, Command: "false", Retries: &retries})
+
+	tests := []struct {
+		name   string
+		id     string
+		result job.JobResult
+		want   job.JobStatus
+	}{
+		{name: "completed", id: completed, result: job.JobResult{Status: job.JobStatusCompleted, Output: "ok"}, want: job.JobStatusCompleted},
+		{name: "failed with a retry left", id: retried, result: job.JobResult{Status: job.JobStatusFailed, Error: "exit status 1"}, want: job.JobStatusQueued},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := doRequest(t, env.server, http.MethodPost, "/api/v1/jobs/"+tt.id+"/result", tt.result); rec.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+			}
+			if got := status(tt.id); got != tt.want {
+				t.Errorf("Expected the job to be %s, got %s", tt.want, got)
+			}
+		})
+	}
+
+	// The retry runs again and fails for good
+	if j, err := dispatcher.GetNextJob(ctx); err != nil || j.ID != retried {
+		t.Fatalf("Expected the retry to be dispatched, got %v (%v)", j, err)
+	}
+	doRequest(t, env.server, http.MethodPost, "/api/v1/jobs/"+retried+"/result", job.JobResult{Status: job.JobStatusFailed, Error: "exit status 1"})
+	if got := status(retried); got != job.JobStatusFailed {
+		t.Errorf("Expected the job to fail once out of retries, got %s", got)
+	}
+
+	// A job that isn't running can't report a result
+	queued := &job.Job{ID: "job-queued", Type: job.JobTypeCommand, Status: job.JobStatusQueued}
+	seedJobs(t, env, queued)
+	if rec := doRequest(t, env.server, http.MethodPost, "/api/v1/jobs/job-queued/result", job.JobResult{Status: job.JobStatusCompleted}); rec.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 for a queued job, got %d", rec.Code)
+	}
+}
+
+func TestHandleUpdatePriority_Rejected(t *testing.T) {
+	env := newTestServer(t)
+	ctx := context.Background()
+
+	running := &job.Job{ID: "job-running", Type: job.JobTypeCommand, Status: job.JobStatusRunning, CreatedAt: time.Now()}
+	if err := env.store.Create(ctx, running); err != nil {
+		t.Fatalf("failed to seed job: %v", err)
+	}
+	seedJobs(t, env,
+		&job.Job{ID: "job-queued", Type: job.JobTypeCommand, Status: job.JobStatusQueued, Priority: 1},
+		&job.Job{ID: "job-done", Type: job.JobTypeCommand, Status: job.JobStatusCompleted, Priority: 1},
+	)
+
+	tests := []struct {
+		name     string
+		path     string
+		body     interface{}
+		wantCode int
+	}{
+		{name: "running job", path: "/api/v1/jobs/job-running/priority", body: map[string]int{"priority": 10}, wantCode: http.StatusConflict},
+		{name: "unknown job", path: "/api/v1/jobs/job-missing/priority", body: map[string]int{"priority": 10}, wantCode: http.StatusNotFound},
+		{name: "missing priority", path: "/api/v1/jobs/job-running/priority", body: map[string]int{}, wantCode: http.StatusBadRequest},
+		{name: "terminal job", path: "/api/v1/jobs/job-done", body: map[string]int{"priority": 10}, wantCode: http.StatusConflict},
+		{name: "priority too high", path: "/api/v1/jobs/job-queued", body: map[string]int{"priority": job.MaxPriority + 1}, wantCode: http.StatusBadRequest},
+		{name: "priority too low", path: "/api/v1/jobs/job-queued", body: map[string]int{"priority": 0}, wantCode: http.StatusBadRequest},
+		{name: "field that can't be updated", path: "/api/v1/jobs/job-queued", body: map[string]interface{}{"priority": 5, "command": "false"}, wantCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := doRequest(t, env.server, http.MethodPatch, tt.path, tt.body)
+			if rec.Code != tt.wantCode {
+				t.Errorf("Expected status %d, got %d: %s", tt.wantCode, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandleDrainWorker(t *testing.T) {
+	env := newTestServer(t)
+	ctx := context.Background()
+	env.registry.Register(ctx, &drainableWorker{fakeWorker: fakeWorker{id: "w1", healthy: true}})
+	env.registry.Register(ctx, &fakeWorker{id: "w2", healthy: true})
+
+	rec := doRequest(t, env.server, http.MethodPost, "/api/v1/workers/w1/drain", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = doRequest(t, env.server, http.MethodGet, "/api/v1/workers", nil)
+	var response struct {
+		Workers []struct {
+			ID        string `json:"id"`
+			Healthy   bool   `json:"healthy"`
+			CanAccept bool   `json:"can_accept"`
+			Draining  bool   `json:"draining"`
+		} `json:"workers"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	for _, worker := range response.Workers {
+		draining := worker.ID == "w1"
+		if worker.Draining != draining || worker.CanAccept == draining || !worker.Healthy {
+			t.Errorf("Expected %s to be healthy with draining=%v, got %+v", worker.ID, draining, worker)
+		}
+	}
+
+	for path, want := range map[string]int{
+		"/api/v1/workers/w2/drain":        http.StatusNotImplemented,
+		"/api/v1/workers/w-missing/drain": http.StatusNotFound,
+	} {
+		if rec := doRequest(t, env.server, http.MethodPost, path, nil); rec.Code != want {
+			t.Errorf("POST %s: expected status %d, got %d", path, want, rec.Code)
+		}
+	}
+}
+
+func TestHandleDrainWorker_Remote(t *testing.T) {
+	env := newTestServer(t)
+
+	drained := false
+	workerAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		drained = r.Method == http.MethodPost && r.URL.Path == "/drain"
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer workerAPI.Close()
+
+	registration := job.WorkerRegistration{ID: "remote-1", Address: workerAPI.URL, Capacity: 2}
+	if rec := doRequest(t, env.server, http.MethodPost, "/api/v1/workers", registration); rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec := doRequest(t, env.server, http.MethodPost, "/api/v1/workers/remote-1/drain", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if !drained {
+		t.Error("Expected the drain to reach the worker's API")
+	}
+
+	worker, err := env.registry.GetWorker(context.Background(), "remote-1")
+	if err != nil {
+		t.Fatalf("GetWorker() error = %v", err)
+	}
+	if worker.CanAcceptJob() {
+		t.Error("Expected a drained worker to stop accepting jobs")
+	}
+}
+
+func TestHandleRegisterWorker(t *testing.T) {
+	env := newTestServer(t)
+	ctx := context.Background()
+
+	registration := job.WorkerRegistration{ID: "remote-1", Capacity: 2, Labels: map[string]string{"gpu": "true"}}
+	rec := doRequest(t, env.server, http.MethodPost, "/api/v1/workers", registration)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	worker, err := env.registry.GetWorker(ctx, "remote-1")
+	if err != nil {
+		t.Fatalf("Expected the worker to be registered, got %v", err)
+	}
+	if worker.GetCapacity() != 2 || worker.Labels()["gpu"] != "true" || !worker.CanAcceptJob() {
+		t.Errorf("Expected an idle healthy worker with the registered capacity and labels")
+	}
+	if err := env.registry.Heartbeat(ctx, "remote-1"); err != nil {
+		t.Errorf("Expected the registered worker to accept heartbeats, got %v", err)
+	}
+
+	// Heartbeats carry the worker's load
+	if rec := doRequest(t, env.server, http.MethodPost, "/api/v1/workers/remote-1/heartbeat", map[string]int{"load": 2}); rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 for a heartbeat, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if worker.GetCurrentLoad() != 2 || worker.CanAcceptJob() {
+		t.Errorf("Expected the reported load to fill the worker, got load %d", worker.GetCurrentLoad())
+	}
+
+	for name, tt := range map[string]struct {
+		body interface{}
+		want int
+	}{
+		"duplicate":   {registration, http.StatusConflict},
+		"missing id":  {job.WorkerRegistration{Capacity: 1}, http.StatusBadRequest},
+		"no capacity": {job.WorkerRegistration{ID: "remote-2"}, http.StatusBadRequest},
+	} {
+		if rec := doRequest(t, env.server, http.MethodPost, "/api/v1/workers", tt.body); rec.Code != tt.want {
+			t.Errorf("%s: expected status %d, got %d", name, tt.want, rec.Code)
+		}
+	}
+	for name, tt := range map[string]struct {
+		path string
+		body interface{}
+		want int
+	}{
+		"negative load":  {"/api/v1/workers/remote-1/heartbeat", map[string]int{"load": -1}, http.StatusBadRequest},
+		"unknown worker": {"/api/v1/workers/remote-missing/heartbeat", map[string]int{"load": 1}, http.StatusNotFound},
+	} {
+		if rec := doRequest(t, env.server, http.MethodPost, tt.path, tt.body); rec.Code != tt.want {
+			t.Errorf("%s: expected status %d, got %d", name, tt.want, rec.Code)
+		}
+	}
+}
+
+func TestHandleListWorkers_HeartbeatAge(t *testing.T) {
+	env := newTestServer(t)
+	env.registry.Register(context.Background(), &fakeWorker{id: "w1", healthy: true})
+
+	rec := doRequest(t, env.server, http.MethodGet, "/api/v1/workers", nil)
+	var response struct {
+		Workers []map[string]interface{} `json:"workers"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(response.Workers) != 1 {
+		t.Fatalf("Expected one worker, got %v", response.Workers)
+	}
+	age, ok := response.Workers[0]["heartbeat_age_seconds"].(float64)
+	if !ok || age < 0 || age > 60 {
+		t.Errorf("Expected the age of the registration heartbeat, got %v", response.Workers[0]["heartbeat_age_seconds"])
+	}
+}
+
+func TestHandleReleaseWorkerJobs(t *testing.T) {
+	env := newTestServer(t)
+	ctx := context.Background()
+	seedJobs(t, env,
+		&job.Job{ID: "job-1", Status: job.JobStatusRunning, WorkerID: "w1"},
+		&job.Job{ID: "job-done", Status: job.JobStatusCompleted, WorkerID: "w1"},
+		&job.Job{ID: "job-other", Status: job.JobStatusRunning, WorkerID: "w2"},
+	)
+
+	rec := doRequest(t, env.server, http.MethodPost, "/api/v1/workers/w1/release", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	for id, want := range map[string]job.JobStatus{
+		"job-1":     job.JobStatusQueued,
+		"job-done":  job.JobStatusCompleted,
+		"job-other": job.JobStatusRunning,
+	} {
+		if j, _ := env.store.Get(ctx, id); j.Status != want {
+			t.Errorf("Expected %s to be %s, got %s", id, want, j.Status)
+		}
+	}
+	if size, _ := env.queue.Size(ctx); size != 1 {
+		t.Errorf("Expected the released job to be queued, got queue size %d", size)
+	}
+}
+
+func TestHandleClaimNextJob(t *testing.T) {
+	env := newTestServer(t)
+	ctx := context.Background()
+	env.registry.Register(ctx, &fakeWorker{id: "w1", healthy: true})
+
+	if rec := doRequest(t, env.server, http.MethodPost, "/api/v1/workers/w1/jobs/next", nil); rec.Code != http.StatusNoContent {
+		t.Errorf("Expected status 204 with nothing queued, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	submitted, err := env.manager.Submit(ctx, &job.JobRequest{Type: job.JobTypeCommand, Command: "true"})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	rec := doRequest(t, env.server, http.MethodPost, "/api/v1/workers/w1/jobs/next", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var claimed job.Job
+	json.NewDecoder(rec.Body).Decode(&claimed)
+	if claimed.ID != submitted.ID || claimed.Status != job.JobStatusRunning || claimed.WorkerID != "w1" {
+		t.Errorf("Expected %s running on w1, got %s in %s on %q", submitted.ID, claimed.ID, claimed.Status, claimed.WorkerID)
+	}
+
+	if rec := doRequest(t, env.server, http.MethodPost, "/api/v1/workers/missing/jobs/next", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an unknown worker, got %d", rec.Code)
+	}
+}
+
+func TestHandleDecommissionWorker(t *testing.T) {
+	tests := []struct {
+		name          string
+		healthy       bool
+		cancels       bool
+		query         string
+		wantCode      int
+		wantStatus    job.JobStatus
+		wantQueued    int
+		wantRemoved   bool
+		wantCancelled int
+	}{
+		{name: "requeue jobs of dead worker", query: "", wantCode: http.StatusOK, wantStatus: job.JobStatusQueued, wantQueued: 2, wantRemoved: true},
+		{name: "fail jobs of dead worker", query: "?requeue=false", wantCode: http.StatusOK, wantStatus: job.JobStatusFailed, wantRemoved: true},
+		{name: "healthy worker is guarded", healthy: true, wantCode: http.StatusConflict, wantStatus: job.JobStatusRunning},
+		{name: "healthy worker with force", healthy: true, cancels: true, query: "?force=true", wantCode: http.StatusOK, wantStatus: job.JobStatusQueued, wantQueued: 2, wantRemoved: true, wantCancelled: 2},
+		{name: "forced worker that cannot cancel", healthy: true, query: "?force=true", wantCode: http.StatusConflict, wantStatus: job.JobStatusRunning},
+		{name: "invalid force flag", healthy: true, cancels: true, query: "?force=yes", wantCode: http.StatusBadRequest, wantStatus: job.JobStatusRunning},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestServer(t)
+			ctx := context.Background()
+
+			worker := &cancellingWorker{fakeWorker: fakeWorker{id: "w1", healthy: tt.healthy}}
+			if tt.cancels {
+				env.registry.Register(ctx, worker)
+			} else {
+				env.registry.Register(ctx, &worker.fakeWorker)
+			}
+			env.registry.Register(ctx, &fakeWorker{id: "w2", healthy: true})
+			seedJobs(t, env,
+				&job.Job{ID: "job-1", Status: job.JobStatusRunning, WorkerID: "w1"},
+				&job.Job{ID: "job-2", Status: job.JobStatusRunning, WorkerID: "w1"},
+				&job.Job{ID: "job-done", Status: job.JobStatusCompleted, WorkerID: "w1"},
+				&job.Job{ID: "job-other", Status: job.JobStatusRunning, WorkerID: "w2"},
+			)
+
+			rec := doRequest(t, env.server, http.MethodDelete, "/api/v1/workers/w1"+tt.query, nil)
+			if rec.Code != tt.wantCode {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantCode, rec.Code, rec.Body.String())
+			}
+
+			for _, id := range []string{"job-1", "job-2"} {
+				j, _ := env.store.Get(ctx, id)
+				if j.Status != tt.wantStatus {
+					t.Errorf("Expected %s to be %s, got %s", id, tt.wantStatus, j.Status)
+				}
+			}
+
+			// Jobs that are finished or on other workers are untouched
+			if j, _ := env.store.Get(ctx, "job-other"); j.Status != job.JobStatusRunning {
+				t.Errorf("Expected job on other worker to keep running, got %s", j.Status)
+			}
+			if j, _ := env.store.Get(ctx, "job-done"); j.Status != job.JobStatusCompleted {
+				t.Errorf("Expected completed job to be untouched, got %s", j.Status)
+			}
+
+			if size, _ := env.queue.Size(ctx); size != tt.wantQueued {
+				t.Errorf("Expected %d queued jobs, got %d", tt.wantQueued, size)
+			}
+
+			// A live worker's running jobs are stopped there before requeueing
+			if len(worker.cancelled) != tt.wantCancelled {
+				t.Errorf("Expected %d jobs cancelled on the worker, got %v", tt.wantCancelled, worker.cancelled)
+			}
+
+			_, err := env.registry.GetWorker(ctx, "w1")
+			if removed := job.IsWorkerNotFoundError(err); removed != tt.wantRemoved {
+				t.Errorf("Expected worker removed = %v, got %v", tt.wantRemoved, removed)
+			}
+
+			if tt.wantCode == http.StatusOK {
+				var response struct {
+					Jobs []string `json:"jobs"`
+				}
+				json.NewDecoder(rec.Body).Decode(&response)
+				if len(response.Jobs) != 2 {
+					t.Errorf("Expected 2 affected jobs, got %v", response.Jobs)
+				}
+			}
+		})
+	}
+}
+
+func TestGetJob_ContentNegotiation(t *testing.T) {
+	env := newTestServer(t)
+	seedJobs(t, env, &job.Job{ID: "job-1", Type: job.JobTypeCommand, Command: "echo hi", Status: job.JobStatusQueued})
+
+	tests := []struct {
+		name            string
+		accept          string
+		wantContentType string
+	}{
+		{name: "default", accept: "", wantContentType: "application/json"},
+		{name: "json", accept: "application/json", wantContentType: "application/json"},
+		{name: "yaml", accept: "application/yaml", wantContentType: "application/yaml"},
+		{name: "yaml preferred in list", accept: "text/yaml, application/json;q=0.5", wantContentType: "application/yaml"},
+		{name: "unsupported falls back to json", accept: "text/html", wantContentType: "application/json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/jobs/job-1", nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rec := httptest.NewRecorder()
+			env.server.SetupRoutes().ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d", rec.Code)
+			}
+			if got := rec.Header().Get("Content-Type"); got != tt.wantContentType {
+				t.Fatalf("Expected content type %s, got %s", tt.wantContentType, got)
+			}
+
+			var decoded map[string]interface{}
+			if tt.wantContentType == "application/yaml" {
+				if err := yaml.Unmarshal(rec.Body.Bytes(), &decoded); err != nil {
+					t.Fatalf("failed to decode YAML: %v", err)
+				}
+			} else if err := json.Unmarshal(rec.Body.Bytes(), &decoded); err != nil {
+				t.Fatalf("failed to decode JSON: %v", err)
+			}
+
+			// Both formats use the same field names
+			if decoded["id"] != "job-1" || decoded["command"] != "echo hi" || decoded["status"] != "queued" {
+				t.Errorf("Unexpected job document: %v", decoded)
+			}
+		})
+	}
+}
+
+func TestHandlePreviewSchedule(t *testing.T) {
+	env := newTestServer(t)
+
+	tests := []struct {
+		name     string
+		body     map[string]interface{}
+		wantNext []string
+	}{
+		{
+			name: "weekday mornings in UTC",
+			body: map[string]interface{}{"cron": "30 9 * * 1-5", "count": 3, "after": "2024-03-08T12:00:00Z"},
+			wantNext: []string{
+				"2024-03-11T09:30:00Z",
+				"2024-03-12T09:30:00Z",
+				"2024-03-13T09:30:00Z",
+			},
+		},
+		{
+			name: "midnight in a named timezone",
+			body: map[string]interface{}{"cron": "0 0 * * *", "timezone": "Asia/Tokyo", "count": 2, "after": "2024-01-01T00:00:00Z"},
+			wantNext: []string{
+				"2024-01-02T00:00:00+09:00",
+				"2024-01-03T00:00:00+09:00",
+			},
+		},
+		{
+			name: "descriptor with default count",
+			body: map[string]interface{}{"cron": "@hourly", "after": "2024-01-01T10:15:00Z"},
+			wantNext: []string{
+				"2024-01-01T11:00:00Z",
+				"2024-01-01T12:00:00Z",
+				"2024-01-01T13:00:00Z",
+				"2024-01-01T14:00:00Z",
+				"2024-01-01T15:00:00Z",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := doRequest(t, env.server, http.MethodPost, "/api/v1/schedules/preview", tt.body)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+			}
+
+			var response struct {
+				Next  []string `json:"next"`
+				Count int      `json:"count"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+
+			if response.Count != len(tt.wantNext) || len(response.Next) != len(tt.wantNext) {
+				t.Fatalf("Expected %d fire times, got %v", len(tt.wantNext), response.Next)
+			}
+			for i, want := range tt.wantNext {
+				if response.Next[i] != want {
+					t.Errorf("Fire time %d = %s, want %s", i, response.Next[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestHandlePreviewSchedule_Invalid(t *testing.T) {
+	env := newTestServer(t)
+
+	tests := []struct {
+		name string
+		body map[string]interface{}
+	}{
+		{name: "malformed expression", body: map[string]interface{}{"cron": "not a cron"}},
+		{name: "out of range field", body: map[string]interface{}{"cron": "61 * * * *"}},
+		{name: "missing expression", body: map[string]interface{}{}},
+		{name: "unknown timezone", body: map[string]interface{}{"cron": "0 * * * *", "timezone": "Mars/Olympus"}},
+		{name: "count too large", body: map[string]interface{}{"cron": "0 * * * *", "count": 1000}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := doRequest(t, env.server, http.MethodPost, "/api/v1/schedules/preview", tt.body)
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d: %s", rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandleSubmitJob_ImmediateMode(t *testing.T) {
+	tests := []struct {
+		name     string
+		healthy  bool
+		wantCode int
+	}{
+		{name: "no available worker", healthy: false, wantCode: http.StatusConflict},
+		{name: "available worker", healthy: true, wantCode: http.StatusCreated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestServer(t)
+			env.registry.Register(context.Background(), &fakeWorker{id: "w1", healthy: tt.healthy})
+
+			rec := doRequest(t, env.server, http.MethodPost, "/api/v1/jobs", map[string]interface{}{
+				"type":            "command",
+				"command":         "true",
+				"scheduling_mode": "immediate",
+			})
+			if rec.Code != tt.wantCode {
+				t.Errorf("Expected status %d, got %d: %s", tt.wantCode, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestJobTimestampsAreUTC(t *testing.T) {
+	env := newTestServer(t)
+	ctx := context.Background()
+
+	// A job written with a non-UTC timestamp is normalized by the store
+	offset := time.FixedZone("UTC+5", 5*60*60)
+	startedAt := time.Date(2024, 6, 1, 17, 0, 0, 0, offset)
+	seedJobs(t, env, &job.Job{
+		ID:        "job-local",
+		Type:      job.JobTypeCommand,
+		Status:    job.JobStatusRunning,
+		CreatedAt: time.Date(2024, 6, 1, 16, 0, 0, 0, offset),
+		StartedAt: &startedAt,
+	})
+
+	rec := doRequest(t, env.server, http.MethodPost, "/api/v1/jobs", map[string]interface{}{
+		"type":    "command",
+		"command": "true",
+	})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var submitted map[string]interface{}
+	json.NewDecoder(rec.Body).Decode(&submitted)
+
+	stored, err := env.store.Get(ctx, submitted["id"].(string))
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if stored.CreatedAt.Location() != time.UTC {
+		t.Errorf("Expected stored created_at in UTC, got %v", stored.CreatedAt.Location())
+	}
+
+	tests := []struct {
+		id   string
+		want map[string]string // Expected value per field, empty for any UTC time
+	}{
+		{id: submitted["id"].(string), want: map[string]string{"created_at": ""}},
+		{id: "job-local", want: map[string]string{
+			"created_at": "2024-06-01T11:00:00Z",
+			"started_at": "2024-06-01T12:00:00Z",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			rec := doRequest(t, env.server, http.MethodGet, "/api/v1/jobs/"+tt.id, nil)
+
+			var decoded map[string]interface{}
+			if err := json.NewDecoder(rec.Body).Decode(&decoded); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+
+			for field, want := range tt.want {
+				value, _ := decoded[field].(string)
+				if !strings.HasSuffix(value, "Z") {
+					t.Errorf("Expected %s to serialize with a Z suffix, got %q", field, value)
+				}
+				if _, err := time.Parse(time.RFC3339, value); err != nil {
+					t.Errorf("Expected %s to be RFC3339, got %q", field, value)
+				}
+				if want != "" && value != want {
+					t.Errorf("Expected %s = %s, got %s", field, want, value)
+				}
+			}
+		})
+	}
+}
+
+func TestJobAnnotations(t *testing.T) {
+	env := newTestServer(t)
+
+	for _, sha := range []string{"abc123", "def456"} {
+		rec := doRequest(t, env.server, http.MethodPost, "/api/v1/jobs", map[string]interface{}{
+			"type":        "command",
+			"command":     "true",
+			"annotations": map[string]string{"commit": sha, "triggered_by": "ci"},
+		})
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("Expected status 201, got %d: %s", rec.Code, rec.Body.String())
+		}
+
+		var created job.Job
+		json.NewDecoder(rec.Body).Decode(&created)
+
+		rec = doRequest(t, env.server, http.MethodGet, "/api/v1/jobs/"+created.ID, nil)
+		var fetched job.Job
+		json.NewDecoder(rec.Body).Decode(&fetched)
+		if fetched.Annotations["commit"] != sha || fetched.Annotations["triggered_by"] != "ci" {
+			t.Errorf("Expected annotations to round-trip, got %v", fetched.Annotations)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		query     string
+		wantCode  int
+		wantCount int
+	}{
+		{name: "matching value", query: "?annotation=commit=abc123", wantCode: http.StatusOK, wantCount: 1},
+		{name: "shared value", query: "?annotation=triggered_by=ci", wantCode: http.StatusOK, wantCount: 2},
+		{name: "combined filters", query: "?annotation=triggered_by=ci&annotation=commit=def456", wantCode: http.StatusOK, wantCount: 1},
+		{name: "unknown key", query: "?annotation=owner=ci", wantCode: http.StatusOK, wantCount: 0},
+		{name: "malformed filter", query: "?annotation=commit", wantCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := doRequest(t, env.server, http.MethodGet, "/api/v1/jobs"+tt.query, nil)
+			if rec.Code != tt.wantCode {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantCode, rec.Code, rec.Body.String())
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+
+			var response struct {
+				Count int `json:"count"`
+			}
+			json.NewDecoder(rec.Body).Decode(&response)
+			if response.Count != tt.wantCount {
+				t.Errorf("Expected %d jobs, got %d", tt.wantCount, response.Count)
+			}
+		})
+	}
+}
+
+func TestHandleMetrics_Queue(t *testing.T) {
+	env := newTestServer(t)
+	ctx := context.Background()
+
+	// Seed jobs that have been waiting for ten and five minutes
+	fake := clock.NewFake(time.Now().Add(-10 * time.Minute))
+	queue := scheduler.NewPriorityQueueWithClock(fake)
+	env.server.queue = queue
+
+	for _, id := range []string{"job-oldest", "job-dispatched", "job-newer"} {
+		priority := 1
+		if id == "job-dispatched" {
+			priority = 5
+		}
+		if err := queue.Enqueue(ctx, &job.Job{ID: id, Priority: priority, CreatedAt: time.Now()}); err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+		if id == "job-dispatched" {
+			fake.Advance(5 * time.Minute)
+		}
+	}
+	if _, err := queue.Dequeue(ctx); err != nil {
+		t.Fatalf("Dequeue() error = %v", err)
+	}
+
+	rec := doRequest(t, env.server, http.MethodGet, "/api/v1/metrics", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var response struct {
+		Queue struct {
+			Depth            int     `json:"depth"`
+			OldestJobID      string  `json:"oldest_job_id"`
+			OldestAgeSeconds float64 `json:"oldest_age_seconds"`
+			WaitSeconds      struct {
+				Buckets map[string]int `json:"buckets"`
+				Count   int            `json:"count"`
+			} `json:"wait_seconds"`
+		} `json:"queue"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	queueMetrics := response.Queue
+	if queueMetrics.Depth != 2 {
+		t.Errorf("Expected depth 2, got %d", queueMetrics.Depth)
+	}
+	if queueMetrics.OldestJobID != "job-oldest" {
+		t.Errorf("Expected oldest job job-oldest, got %s", queueMetrics.OldestJobID)
+	}
+	if age := queueMetrics.OldestAgeSeconds; age < 600 || age > 660 {
+		t.Errorf("Expected oldest age around 600s, got %v", age)
+	}
+	if queueMetrics.WaitSeconds.Count != 1 || queueMetrics.WaitSeconds.Buckets["300"] != 1 || queueMetrics.WaitSeconds.Buckets["60"] != 0 {
+		t.Errorf("Expected a single 5m wait in the histogram, got %+v", queueMetrics.WaitSeconds)
+	}
+}
+
+func TestHandleMetrics_CountsPendingAndQueuedSeparately(t *testing.T) {
+	env := newTestServer(t)
+	seedJobs(t, env,
+		&job.Job{ID: "job-pending", Status: job.JobStatusPending},
+		&job.Job{ID: "job-queued-1", Status: job.JobStatusQueued},
+		&job.Job{ID: "job-queued-2", Status: job.JobStatusQueued},
+	)
+
+	rec := doRequest(t, env.server, http.MethodGet, "/api/v1/metrics", nil)
+
+	var response struct {
+		Jobs struct {
+			ByStatus map[string]int `json:"by_status"`
+		} `json:"jobs"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if response.Jobs.ByStatus["pending"] != 1 || response.Jobs.ByStatus["queued"] != 2 {
+		t.Errorf("Expected 1 pending and 2 queued, got %v", response.Jobs.ByStatus)
+	}
+}
+
+func TestHandleRetryingStatus(t *testing.T) {
+	env := newTestServer(t)
+	seedJobs(t, env,
+		&job.Job{ID: "job-retrying", Status: job.JobStatusRetrying, Retries: 2, RetryCount: 1},
+		&job.Job{ID: "job-queued", Status: job.JobStatusQueued},
+	)
+
+	rec := doRequest(t, env.server, http.MethodGet, "/api/v1/metrics", nil)
+	var metrics struct {
+		Jobs struct {
+			Total    int            `json:"total"`
+			ByStatus map[string]int `json:"by_status"`
+		} `json:"jobs"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&metrics); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if metrics.Jobs.ByStatus["retrying"] != 1 || metrics.Jobs.ByStatus["queued"] != 1 {
+		t.Errorf("Expected 1 retrying and 1 queued, got %v", metrics.Jobs.ByStatus)
+	}
+
+	rec = doRequest(t, env.server, http.MethodGet, "/api/v1/jobs?status=retrying", nil)
+	var list struct {
+		Jobs []job.Job `json:"jobs"`
+	}
+	json.NewDecoder(rec.Body).Decode(&list)
+	if len(list.Jobs) != 1 || list.Jobs[0].ID != "job-retrying" {
+		t.Errorf("Expected only job-retrying, got %+v", list.Jobs)
+	}
+
+	for _, path := range []string{"/api/v1/jobs?status=bogus", "/api/v1/dashboard?status=bogus"} {
+		if rec := doRequest(t, env.server, http.MethodGet, path, nil); rec.Code != http.StatusBadRequest {
+			t.Errorf("GET %s: expected status 400, got %d", path, rec.Code)
+		}
+	}
+}
+
+func TestHandleListTags(t *testing.T) {
+	env := newTestServer(t)
+	seedJobs(t, env,
+		&job.Job{ID: "job-1", Status: job.JobStatusQueued, Tags: []string{"deploy", "prod"}},
+		&job.Job{ID: "job-2", Status: job.JobStatusFailed, Tags: []string{"deploy", "staging"}},
+		&job.Job{ID: "job-3", Status: job.JobStatusQueued, Tags: []string{"prod"}},
+		&job.Job{ID: "job-4", Status: job.JobStatusCompleted},
+	)
+
+	tests := []struct {
+		name  string
+		query string
+		want  []tagSummary
+	}{
+		{
+			name: "all statuses",
+			want: []tagSummary{
+				{Tag: "deploy", Total: 2, ByStatus: map[string]int{"queued": 1, "failed": 1}},
+				{Tag: "prod", Total: 2, ByStatus: map[string]int{"queued": 2}},
+				{Tag: "staging", Total: 1, ByStatus: map[string]int{"failed": 1}},
+			},
+		},
+		{
+			name:  "scoped to a status",
+			query: "?status=failed",
+			want: []tagSummary{
+				{Tag: "deploy", Total: 1, ByStatus: map[string]int{"failed": 1}},
+				{Tag: "staging", Total: 1, ByStatus: map[string]int{"failed": 1}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := doRequest(t, env.server, http.MethodGet, "/api/v1/tags"+tt.query, nil)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+			}
+
+			var response struct {
+				Tags []tagSummary `json:"tags"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if fmt.Sprint(response.Tags) != fmt.Sprint(tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, response.Tags)
+			}
+		})
+	}
+
+	if rec := doRequest(t, env.server, http.MethodGet, "/api/v1/tags?status=bogus", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an invalid status, got %d", rec.Code)
+	}
+}
+
+// doMultipartRequest submits a job as multipart/form-data with the given parts
+func doMultipartRequest(t *testing.T, server *Server, fields, files map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for name, value := range fields {
+		writer.WriteField(name, value)
+	}
+	for name, content := range files {
+		part, err := writer.CreateFormFile(name, name+".sh")
+		if err != nil {
+			t.Fatalf("failed to create file part: %v", err)
+		}
+		part.Write([]byte(content))
+	}
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/jobs", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	rec := httptest.NewRecorder()
+	server.SetupRoutes().ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHandleSubmitJob_Multipart(t *testing.T) {
+	env := newTestServer(t)
+	script := "#!/bin/bash\nset -euo pipefail\necho \"quotes \\\"and\\\" backslashes \\\\ survive\"\n"
+
+	rec := doMultipartRequest(t, env.server,
+		map[string]string{"job": `{"type": "script", "priority": 7, "tags": ["upload"]}`},
+		map[string]string{"script": script},
+	)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var created job.Job
+	json.NewDecoder(rec.Body).Decode(&created)
+
+	stored, err := env.store.Get(context.Background(), created.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if stored.Script != script {
+		t.Errorf("Expected stored script to match the upload, got %q", stored.Script)
+	}
+	if stored.Type != job.JobTypeScript || stored.Priority != 7 || len(stored.Tags) != 1 {
+		t.Errorf("Expected job fields from the job part, got %+v", stored)
+	}
+}
+
+func TestHandleSubmitJob_MultipartInvalid(t *testing.T) {
+	env := newTestServer(t)
+
+	tests := []struct {
+		name   string
+		fields map[string]string
+		files  map[string]string
+	}{
+		{name: "missing script part", fields: map[string]string{"job": `{"type": "script"}`}},
+		{name: "malformed job part", fields: map[string]string{"job": `{"type":`}, files: map[string]string{"script": "echo hi"}},
+		{name: "oversized script", files: map[string]string{"script": strings.Repeat("#", int(env.server.config.API.MaxBodyBytes)+1)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := doMultipartRequest(t, env.server, tt.fields, tt.files)
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d: %s", rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandleSubmitJob_DryRun(t *testing.T) {
+	env := newTestServer(t)
+	ctx := context.Background()
+
+	request := map[string]interface{}{"type": "command", "command": "make test", "timeout": "90s", "priority": 4}
+	rec := doRequest(t, env.server, http.MethodPost, "/api/v1/jobs?dry_run=true", request)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resolved job.Job
+	if err := json.Unmarshal(rec.Body.Bytes(), &resolved); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resolved.ID != "" || resolved.Command != "make test" || resolved.Timeout != 90*time.Second || resolved.Priority != 4 || resolved.Cost != 1 {
+		t.Errorf("Expected the resolved job without an ID, got %+v", resolved)
+	}
+	if env.store.Count(ctx) != 0 {
+		t.Errorf("Expected nothing to be stored, got %d jobs", env.store.Count(ctx))
+	}
+	if size, _ := env.queue.Size(ctx); size != 0 {
+		t.Errorf("Expected nothing to be queued, got %d jobs", size)
+	}
+
+	for name, tt := range map[string]struct {
+		path string
+		body interface{}
+	}{
+		"bad timeout":        {"/api/v1/jobs?dry_run=true", map[string]interface{}{"type": "command", "command": "true", "timeout": "soon"}},
+		"missing command":    {"/api/v1/jobs?dry_run=true", map[string]interface{}{"type": "command"}},
+		"unknown dependency": {"/api/v1/jobs?dry_run=true", map[string]interface{}{"type": "command", "command": "true", "depends_on": []string{"job-missing"}}},
+		"invalid flag":       {"/api/v1/jobs?dry_run=maybe", request},
+	} {
+		if rec := doRequest(t, env.server, http.MethodPost, tt.path, tt.body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d: %s", name, rec.Code, rec.Body.String())
+		}
+	}
+	if env.store.Count(ctx) != 0 {
+		t.Errorf("Expected rejected dry runs to store nothing, got %d jobs", env.store.Count(ctx))
+	}
+}
+
+func TestHandleSubmitJob_RejectsMalformedBodies(t *testing.T) {
+	env := newTestServer(t)
+	env.server.config.API.MaxBodyBytes = 256
+
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{name: "unknown field", body: `{"type": "command", "comand": "true"}`, want: `unknown field "comand"`},
+		{name: "wrong type", body: `{"type": "command", "command": "true", "priority": "high"}`, want: "invalid value for field priority"},
+		{name: "oversized", body: `{"type": "command", "command": "` + strings.Repeat("x", 300) + `"}`, want: "exceeds the limit of 256 bytes"},
+		{name: "trailing data", body: `{"type": "command", "command": "true"} {}`, want: "unexpected data after the request body"},
+		{name: "truncated", body: `{"type": "command"`, want: "ends mid-value"},
+		{name: "empty", body: ``, want: "request body is empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/jobs", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			env.server.SetupRoutes().ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("Expected status 400, got %d: %s", rec.Code, rec.Body.String())
+			}
+			var response map[string]string
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if !strings.Contains(response["error"], tt.want) {
+				t.Errorf("Expected an error containing %q, got %q", tt.want, response["error"])
+			}
+		})
+	}
+
+	if count := env.store.Count(context.Background()); count != 0 {
+		t.Errorf("Expected no jobs to be stored, got %d", count)
+	}
+}
+
+func TestHandleListJobs_PresenceFilters(t *testing.T) {
+	env := newTestServer(t)
+	started := time.Now().UTC()
+	completed := started.Add(time.Minute)
+	seedJobs(t, env,
+		&job.Job{ID: "job-queued", Type: job.JobTypeCommand, Status: job.JobStatusQueued},
+		&job.Job{ID: "job-running", Type: job.JobTypeCommand, Status: job.JobStatusRunning, WorkerID: "worker-1", StartedAt: &started},
+		&job.Job{ID: "job-completed", Type: job.JobTypeCommand, Status: job.JobStatusCompleted, WorkerID: "worker-1", StartedAt: &started, CompletedAt: &completed},
+	)
+
+	tests := []struct {
+		name     string
+		query    string
+		wantCode int
+		wantIDs  []string
+	}{
+		{name: "running", query: "?started=true&completed=false", wantCode: http.StatusOK, wantIDs: []string{"job-running"}},
+		{name: "never started", query: "?started=false", wantCode: http.StatusOK, wantIDs: []string{"job-queued"}},
+		{name: "unassigned", query: "?assigned=false", wantCode: http.StatusOK, wantIDs: []string{"job-queued"}},
+		{name: "invalid value", query: "?started=maybe", wantCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := doRequest(t, env.server, http.MethodGet, "/api/v1/jobs"+tt.query, nil)
+			if rec.Code != tt.wantCode {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantCode, rec.Code, rec.Body.String())
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+
+			var response struct {
+				Jobs []job.Job `json:"jobs"`
+			}
+			json.NewDecoder(rec.Body).Decode(&response)
+			if len(response.Jobs) != len(tt.wantIDs) {
+				t.Fatalf("Expected %d jobs, got %d", len(tt.wantIDs), len(response.Jobs))
+			}
+			for i, j := range response.Jobs {
+				if j.ID != tt.wantIDs[i] {
+					t.Errorf("Expected job %s, got %s", tt.wantIDs[i], j.ID)
+				}
+			}
+		})
+	}
+}
+
+func TestHandleListJobs_CreatedRange(t *testing.T) {
+	env := newTestServer(t)
+	base := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	seedJobs(t, env,
+		&job.Job{ID: "job-early", Type: job.JobTypeCommand, Status: job.JobStatusQueued, CreatedAt: base},
+		&job.Job{ID: "job-middle", Type: job.JobTypeCommand, Status: job.JobStatusQueued, CreatedAt: base.Add(time.Hour)},
+		&job.Job{ID: "job-late", Type: job.JobTypeCommand, Status: job.JobStatusQueued, CreatedAt: base.Add(2 * time.Hour)},
+	)
+
+	tests := []struct {
+		name     string
+		query    string
+		wantCode int
+		wantIDs  []string
+	}{
+		{name: "after", query: "?created_after=2024-05-01T11:00:00Z", wantCode: http.StatusOK, wantIDs: []string{"job-late", "job-middle"}},
+		{name: "before", query: "?created_before=2024-05-01T11:00:00Z", wantCode: http.StatusOK, wantIDs: []string{"job-early", "job-middle"}},
+		{name: "between", query: "?created_after=2024-05-01T10:30:00Z&created_before=2024-05-01T13:30:00%2B02:00", wantCode: http.StatusOK, wantIDs: []string{"job-middle"}},
+		{name: "invalid after", query: "?created_after=yesterday", wantCode: http.StatusBadRequest},
+		{name: "invalid before", query: "?created_before=2024-05-01", wantCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := doRequest(t, env.server, http.MethodGet, "/api/v1/jobs"+tt.query, nil)
+			if rec.Code != tt.wantCode {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantCode, rec.Code, rec.Body.String())
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+
+			var response struct {
+				Jobs []job.Job `json:"jobs"`
+			}
+			json.NewDecoder(rec.Body).Decode(&response)
+			ids := make([]string, 0, len(response.Jobs))
+			for _, j := range response.Jobs {
+				ids = append(ids, j.ID)
+			}
+			sort.Strings(ids)
+
+			if strings.Join(ids, ",") != strings.Join(tt.wantIDs, ",") {
+				t.Errorf("Expected %v, got %v", tt.wantIDs, ids)
+			}
+		})
+	}
+}
+
+func TestHandleListJobs_Pagination(t *testing.T) {
+	env := newTestServer(t)
+	created := time.Now().UTC()
+	// job-b and job-c share a creation time, so they are ordered by ID
+	seedJobs(t, env,
+		&job.Job{ID: "job-c", Type: job.JobTypeCommand, Status: job.JobStatusQueued, CreatedAt: created.Add(time.Second)},
+		&job.Job{ID: "job-a", Type: job.JobTypeCommand, Status: job.JobStatusQueued, CreatedAt: created},
+		&job.Job{ID: "job-d", Type: job.JobTypeCommand, Status: job.JobStatusQueued, CreatedAt: created.Add(2 * time.Second)},
+		&job.Job{ID: "job-b", Type: job.JobTypeCommand, Status: job.JobStatusQueued, CreatedAt: created.Add(time.Second)},
+		&job.Job{ID: "job-e", Type: job.JobTypeCommand, Status: job.JobStatusQueued, CreatedAt: created.Add(3 * time.Second)},
+	)
+
+	type page struct {
+		Jobs       []*job.Job `json:"jobs"`
+		Total      int        `json:"total"`
+		NextCursor string     `json:"next_cursor"`
+	}
+	list := func(query string) page {
+		t.Helper()
+		rec := doRequest(t, env.server, http.MethodGet, "/api/v1/jobs"+query, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s: expected status 200, got %d: %s", query, rec.Code, rec.Body.String())
+		}
+		var p page
+		if err := json.NewDecoder(rec.Body).Decode(&p); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return p
+	}
+
+	// Following cursors visits every job once, in order
+	var ids []string
+	query := "?limit=2"
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatal("Expected the cursor to run out after three pages")
+		}
+		p := list(query)
+		if p.Total != 5 {
+			t.Errorf("Expected a total of 5, got %d", p.Total)
+		}
+		for _, j := range p.Jobs {
+			ids = append(ids, j.ID)
+		}
+		if p.NextCursor == "" {
+			break
+		}
+		query = "?limit=2&cursor=" + p.NextCursor
+	}
+	if strings.Join(ids, ",") != "job-a,job-b,job-c,job-d,job-e" {
+		t.Errorf("Expected every job once in creation order, got %v", ids)
+	}
+
+	if p := list("?limit=2&offset=3"); len(p.Jobs) != 2 || p.Jobs[0].ID != "job-d" || p.NextCursor != "" {
+		t.Errorf("Expected the last two jobs at offset 3, got %+v", p)
+	}
+
+	for _, query := range []string{"?cursor=not-a-cursor", "?offset=-1", "?limit=-1", "?limit=ten", "?limit=1001"} {
+		if rec := doRequest(t, env.server, http.MethodGet, "/api/v1/jobs"+query, nil); rec.Code != http.StatusBadRequest {
+			t.Errorf("GET %s: expected status 400, got %d", query, rec.Code)
+		}
+	}
+}
+
+func TestHandleListJobs_TagFilters(t *testing.T) {
+	env := newTestServer(t)
+	seedJobs(t, env,
+		&job.Job{ID: "job-a", Type: job.JobTypeCommand, Status: job.JobStatusQueued, Tags: []string{"a"}},
+		&job.Job{ID: "job-ab", Type: job.JobTypeCommand, Status: job.JobStatusQueued, Tags: []string{"a", "b"}},
+		&job.Job{ID: "job-c", Type: job.JobTypeCommand, Status: job.JobStatusQueued, Tags: []string{"c"}},
+	)
+
+	tests := []struct {
+		name    string
+		query   string
+		wantIDs []string
+	}{
+		{name: "all of", query: "?tags_all=a,b", wantIDs: []string{"job-ab"}},
+		{name: "any of", query: "?tags_any=b,c", wantIDs: []string{"job-ab", "job-c"}},
+		{name: "combined", query: "?tags_all=a&tags_any=b,c", wantIDs: []string{"job-ab"}},
+		{name: "single tag", query: "?tag=a", wantIDs: []string{"job-a", "job-ab"}},
+		{name: "repeated tags", query: "?tag=a&tag=b", wantIDs: []string{"job-ab"}},
+		{name: "case-sensitive", query: "?tag=A", wantIDs: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := doRequest(t, env.server, http.MethodGet, "/api/v1/jobs"+tt.query, nil)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+			}
+
+			var response struct {
+				Jobs []job.Job `json:"jobs"`
+			}
+			json.NewDecoder(rec.Body).Decode(&response)
+			ids := make([]string, 0, len(response.Jobs))
+			for _, j := range response.Jobs {
+				ids = append(ids, j.ID)
+			}
+			sort.Strings(ids)
+
+			if strings.Join(ids, ",") != strings.Join(tt.wantIDs, ",") {
+				t.Errorf("Expected %v, got %v", tt.wantIDs, ids)
+			}
+		})
+	}
+}
+
+// unreachableStore is a store whose backend is down. It records pings made
+// without a deadline.
+type unreachableStore struct {
+	*scheduler.MemoryStore
+	unbounded *int
+}
+
+func (s unreachableStore) Ping(ctx context.Context) error {
+	if _, ok := ctx.Deadline(); !ok {
+		*s.unbounded++
+	}
+	return fmt.Errorf("dial tcp 10.0.0.5:6379: connection refused")
+}
+
+func TestHandleHealth(t *testing.T) {
+	env := newTestServer(t)
+
+	for _, path := range []string{"/api/v1/health", "/api/v1/health/live", "/api/v1/health/ready"} {
+		if rec := doRequest(t, env.server, http.MethodGet, path, nil); rec.Code != http.StatusOK {
+			t.Errorf("Expected %s to answer 200 with the store up, got %d: %s", path, rec.Code, rec.Body.String())
+		}
+	}
+
+	// With the store down the scheduler is alive but not ready
+	unbounded := 0
+	env.server.store = unreachableStore{env.store, &unbounded}
+	if rec := doRequest(t, env.server, http.MethodGet, "/api/v1/health/live", nil); rec.Code != http.StatusOK {
+		t.Errorf("Expected liveness to ignore the store, got %d", rec.Code)
+	}
+	for _, path := range []string{"/api/v1/health", "/api/v1/health/ready"} {
+		rec := doRequest(t, env.server, http.MethodGet, path, nil)
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Fatalf("Expected %s to answer 503, got %d", path, rec.Code)
+		}
+		var response struct {
+			Status string            `json:"status"`
+			Checks map[string]string `json:"checks"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if response.Status == "healthy" || response.Status == "ready" || response.Checks["store"] != "unreachable" {
+			t.Errorf("Expected %s to report the store unreachable, got %+v", path, response)
+		}
+		if strings.Contains(rec.Body.String(), "10.0.0.5") {
+			t.Errorf("Expected %s not to reveal the store's address, got %s", path, rec.Body.String())
+		}
+	}
+	if unbounded != 0 {
+		t.Errorf("Expected every ping to have a deadline, %d had none", unbounded)
+	}
+}
+
+func TestHandleDashboard(t *testing.T) {
+	env := newTestServer(t)
+	if err := env.registry.Register(context.Background(), &fakeWorker{id: "worker-1", healthy: true}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	base := time.Now().UTC().Add(-time.Hour)
+	seedJobs(t, env,
+		&job.Job{ID: "job-oldest", Type: job.JobTypeCommand, Status: job.JobStatusFailed, CreatedAt: base},
+		&job.Job{ID: "job-middle", Type: job.JobTypeCommand, Status: job.JobStatusCompleted, CreatedAt: base.Add(time.Minute)},
+		&job.Job{ID: "job-newest", Type: job.JobTypeCommand, Status: job.JobStatusQueued, CreatedAt: base.Add(2 * time.Minute)},
+	)
+
+	type dashboard struct {
+		RecentJobs []job.Job `json:"recent_jobs"`
+		Workers    []struct {
+			ID string `json:"id"`
+		} `json:"workers"`
+		Metrics struct {
+			Jobs struct {
+				Total int `json:"total"`
+			} `json:"jobs"`
+		} `json:"metrics"`
+		Health struct {
+			Status         string `json:"status"`
+			HealthyWorkers int    `json:"healthy_workers"`
+		} `json:"health"`
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		wantCode int
+		wantJobs []string
+	}{
+		{name: "default", query: "", wantCode: http.StatusOK, wantJobs: []string{"job-newest", "job-middle", "job-oldest"}},
+		{name: "limited", query: "?recent=2", wantCode: http.StatusOK, wantJobs: []string{"job-newest", "job-middle"}},
+		{name: "status filter", query: "?status=failed", wantCode: http.StatusOK, wantJobs: []string{"job-oldest"}},
+		{name: "invalid limit", query: "?recent=-1", wantCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := doRequest(t, env.server, http.MethodGet, "/api/v1/dashboard"+tt.query, nil)
+			if rec.Code != tt.wantCode {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantCode, rec.Code, rec.Body.String())
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+
+			var response dashboard
+			if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+				t.Fatalf("failed to decode dashboard: %v", err)
+			}
+
+			if len(response.RecentJobs) != len(tt.wantJobs) {
+				t.Fatalf("Expected %d recent jobs, got %d", len(tt.wantJobs), len(response.RecentJobs))
+			}
+			for i, j := range response.RecentJobs {
+				if j.ID != tt.wantJobs[i] {
+					t.Errorf("Expected recent job %d to be %s, got %s", i, tt.wantJobs[i], j.ID)
+				}
+			}
+
+			// The other sections are unaffected by the recent-jobs options
+			if len(response.Workers) != 1 || response.Workers[0].ID != "worker-1" {
+				t.Errorf("Expected worker-1 in the worker summaries, got %+v", response.Workers)
+			}
+			if response.Metrics.Jobs.Total != 3 {
+				t.Errorf("Expected metrics to count 3 jobs, got %d", response.Metrics.Jobs.Total)
+			}
+			if response.Health.Status != "healthy" || response.Health.HealthyWorkers != 1 {
+				t.Errorf("Expected a healthy report with 1 worker, got %+v", response.Health)
+			}
+		})
+	}
+}
+
+// signalingWorker is a fakeWorker that records the signals sent to its jobs
+type signalingWorker struct {
+	fakeWorker
+	signals map[string]syscall.Signal
+}
+
+func (w *signalingWorker) SignalJob(jobID string, sig syscall.Signal) error {
+	w.signals[jobID] = sig
+	return nil
+}
+
+// extendingWorker is a fakeWorker that records timeout extensions of its jobs
+type extendingWorker struct {
+	fakeWorker
+	extended map[string]time.Duration
+	onExtend func(jobID string) // Called while the extension is in flight
+}
+
+func (w *extendingWorker) ExtendJobTimeout(jobID string, extra time.Duration) (time.Time, error) {
+	w.extended[jobID] += extra
+	if w.onExtend != nil {
+		w.onExtend(jobID)
+	}
+	return time.Now().Add(extra), nil
+}
+
+func TestHandleExtendTimeout(t *testing.T) {
+	env := newTestServer(t)
+	worker := &extendingWorker{fakeWorker: fakeWorker{id: "worker-1", healthy: true}, extended: map[string]time.Duration{}}
+	if err := env.registry.Register(context.Background(), worker); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	seedJobs(t, env,
+		&job.Job{ID: "job-running", Type: job.JobTypeCommand, Status: job.JobStatusRunning, WorkerID: "worker-1", Timeout: time.Minute},
+		&job.Job{ID: "job-unbounded", Type: job.JobTypeCommand, Status: job.JobStatusRunning, WorkerID: "worker-1"},
+		&job.Job{ID: "job-queued", Type: job.JobTypeCommand, Status: job.JobStatusQueued, Timeout: time.Minute},
+		&job.Job{ID: "job-finishing", Type: job.JobTypeCommand, Status: job.JobStatusRunning, WorkerID: "worker-1", Timeout: time.Minute},
+	)
+
+	// job-finishing completes while its worker is being asked to extend it
+	worker.onExtend = func(jobID string) {
+		if jobID == "job-finishing" {
+			env.store.UpdateStatus(context.Background(), jobID, job.JobStatusCompleted)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		jobID    string
+		extendBy string
+		wantCode int
+	}{
+		{name: "running job", jobID: "job-running", extendBy: "5m", wantCode: http.StatusOK},
+		{name: "past the max timeout", jobID: "job-running", extendBy: "24h", wantCode: http.StatusBadRequest},
+		{name: "invalid duration", jobID: "job-running", extendBy: "soon", wantCode: http.StatusBadRequest},
+		{name: "negative duration", jobID: "job-running", extendBy: "-1m", wantCode: http.StatusBadRequest},
+		{name: "no timeout", jobID: "job-unbounded", extendBy: "5m", wantCode: http.StatusBadRequest},
+		{name: "not running", jobID: "job-queued", extendBy: "5m", wantCode: http.StatusConflict},
+		{name: "unknown job", jobID: "job-missing", extendBy: "5m", wantCode: http.StatusNotFound},
+		{name: "finished meanwhile", jobID: "job-finishing", extendBy: "5m", wantCode: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := doRequest(t, env.server, http.MethodPatch, "/api/v1/jobs/"+tt.jobID+"/timeout", map[string]string{"extend_by": tt.extendBy})
+			if rec.Code != tt.wantCode {
+				t.Errorf("Expected status %d, got %d: %s", tt.wantCode, rec.Code, rec.Body.String())
+			}
+		})
+	}
+
+	if worker.extended["job-running"] != 5*time.Minute {
+		t.Errorf("Expected the worker to extend job-running by 5m, got %v", worker.extended["job-running"])
+	}
+	if stored, _ := env.store.Get(context.Background(), "job-running"); stored.Timeout != 6*time.Minute {
+		t.Errorf("Expected the stored timeout to become 6m, got %v", stored.Timeout)
+	}
+	if stored, _ := env.store.Get(context.Background(), "job-finishing"); stored.Status != job.JobStatusCompleted || stored.Timeout != time.Minute {
+		t.Errorf("Expected the finished job to be left alone, got %s with timeout %v", stored.Status, stored.Timeout)
+	}
+	for _, id := range []string{"job-unbounded", "job-queued"} {
+		if _, ok := worker.extended[id]; ok {
+			t.Errorf("Expected %s not to be extended", id)
+		}
+	}
+}
+
+func TestHandleSignalJob(t *testing.T) {
+	env := newTestServer(t)
+	worker := &signalingWorker{fakeWorker: fakeWorker{id: "worker-1", healthy: true}, signals: map[string]syscall.Signal{}}
+	if err := env.registry.Register(context.Background(), worker); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	seedJobs(t, env,
+		&job.Job{ID: "job-running", Type: job.JobTypeScript, Status: job.JobStatusRunning, WorkerID: "worker-1"},
+		&job.Job{ID: "job-queued", Type: job.JobTypeCommand, Status: job.JobStatusQueued},
+		&job.Job{ID: "job-http", Type: job.JobTypeHTTP, Status: job.JobStatusRunning, WorkerID: "worker-1"},
+	)
+
+	tests := []struct {
+		name     string
+		jobID    string
+		signal   string
+		wantCode int
+	}{
+		{name: "running script", jobID: "job-running", signal: "hup", wantCode: http.StatusOK},
+		{name: "unknown signal", jobID: "job-running", signal: "SIGWINCH", wantCode: http.StatusBadRequest},
+		{name: "http job", jobID: "job-http", signal: "SIGTERM", wantCode: http.StatusBadRequest},
+		{name: "not running", jobID: "job-queued", signal: "SIGTERM", wantCode: http.StatusConflict},
+		{name: "unknown job", jobID: "job-missing", signal: "SIGTERM", wantCode: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := doRequest(t, env.server, http.MethodPost, "/api/v1/jobs/"+tt.jobID+"/signal", map[string]string{"signal": tt.signal})
+			if rec.Code != tt.wantCode {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantCode, rec.Code, rec.Body.String())
+			}
+		})
+	}
+
+	if len(worker.signals) != 1 || worker.signals["job-running"] != syscall.SIGHUP {
+		t.Errorf("Expected only SIGHUP to reach job-running, got %v", worker.signals)
+	}
+}
+
+func TestHandleSearchJobs(t *testing.T) {
+	env := newTestServer(t)
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	seedJobs(t, env,
+		&job.Job{ID: "job-low", Type: job.JobTypeCommand, Status: job.JobStatusQueued, Priority: 1, CreatedAt: base},
+		&job.Job{ID: "job-mid", Type: job.JobTypeCommand, Status: job.JobStatusQueued, Priority: 5, CreatedAt: base.Add(time.Hour)},
+		&job.Job{ID: "job-high", Type: job.JobTypeCommand, Status: job.JobStatusQueued, Priority: 9, CreatedAt: base.Add(2 * time.Hour)},
+	)
+
+	// Filters go through JSON, so numbers arrive as float64 and dates as strings
+	filter := func(field, operator string, value interface{}) map[string]interface{} {
+		return map[string]interface{}{"field": field, "operator": operator, "value": value}
+	}
+
+	tests := []struct {
+		name     string
+		filters  []map[string]interface{}
+		wantCode int
+		wantIDs  []string
+	}{
+		{name: "priority gt", filters: []map[string]interface{}{filter("priority", "gt", 4)}, wantCode: http.StatusOK, wantIDs: []string{"job-high", "job-mid"}},
+		{name: "priority eq", filters: []map[string]interface{}{filter("priority", "eq", 9)}, wantCode: http.StatusOK, wantIDs: []string{"job-high"}},
+		{name: "priority in", filters: []map[string]interface{}{filter("priority", "in", []int{1, 9})}, wantCode: http.StatusOK, wantIDs: []string{"job-high", "job-low"}},
+		{name: "created_at lt", filters: []map[string]interface{}{filter("created_at", "lt", "2024-05-01T13:30:00Z")}, wantCode: http.StatusOK, wantIDs: []string{"job-low", "job-mid"}},
+		{name: "created_at gte with offset", filters: []map[string]interface{}{filter("created_at", "gte", "2024-05-01T15:00:00+01:00")}, wantCode: http.StatusOK, wantIDs: []string{"job-high"}},
+		{name: "fractional priority", filters: []map[string]interface{}{filter("priority", "gt", 2.5)}, wantCode: http.StatusBadRequest},
+		{name: "malformed date", filters: []map[string]interface{}{filter("created_at", "lt", "yesterday")}, wantCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := doRequest(t, env.server, http.MethodPost, "/api/v1/jobs/search", map[string]interface{}{"filters": tt.filters})
+			if rec.Code != tt.wantCode {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantCode, rec.Code, rec.Body.String())
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+
+			var response struct {
+				Jobs []job.Job `json:"jobs"`
+			}
+			json.NewDecoder(rec.Body).Decode(&response)
+
+			ids := make([]string, 0, len(response.Jobs))
+			for _, j := range response.Jobs {
+				ids = append(ids, j.ID)
+			}
+			sort.Strings(ids)
+			if strings.Join(ids, ",") != strings.Join(tt.wantIDs, ",") {
+				t.Errorf("Expected %v, got %v", tt.wantIDs, ids)
+			}
+		})
+	}
+
+	// Limits are checked as on the list endpoint
+	for _, limit := range []int{-1, 1001} {
+		if rec := doRequest(t, env.server, http.MethodPost, "/api/v1/jobs/search", map[string]interface{}{"limit": limit}); rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for limit %d, got %d", limit, rec.Code)
+		}
+	}
+}
+
+func TestHandleJobSchedulability(t *testing.T) {
+	env := newTestServer(t)
+	submitted, err := env.manager.Submit(context.Background(), &job.JobRequest{Type: job.JobTypeCommand, Command: "true"})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+
+	rec := doRequest(t, env.server, http.MethodGet, "/api/v1/jobs/"+submitted.ID+"/schedulability", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var explanation job.Schedulability
+	if err := json.Unmarshal(rec.Body.Bytes(), &explanation); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if explanation.Dispatchable || len(explanation.Blockers) != 1 || explanation.Blockers[0].Reason != job.BlockedNoWorker {
+		t.Errorf("Expected the job to be blocked on workers only, got %+v", explanation)
+	}
+	if explanation.Position != 1 {
+		t.Errorf("Expected position 1, got %d", explanation.Position)
+	}
+
+	if rec := doRequest(t, env.server, http.MethodGet, "/api/v1/jobs/job-missing/schedulability", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for a missing job, got %d", rec.Code)
+	}
+}
+
+// streamingWorker is a fakeWorker whose running jobs' output is fed by the test
+type streamingWorker struct {
+	fakeWorker
+	backlog string
+	chunks  chan []byte
+}
+
+func (w *streamingWorker) FollowJobOutput(jobID string) ([]byte, <-chan []byte, func(), error) {
+	return []byte(w.backlog), w.chunks, func() {}, nil
+}
+
+func TestHandleStreamJobLogs(t *testing.T) {
+	env := newTestServer(t)
+	worker := &streamingWorker{fakeWorker: fakeWorker{id: "worker-1", healthy: true}, backlog: "one\ntw", chunks: make(chan []byte, 2)}
+	if err := env.registry.Register(context.Background(), worker); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	seedJobs(t, env,
+		&job.Job{ID: "job-running", Type: job.JobTypeCommand, Status: job.JobStatusRunning, WorkerID: "worker-1"},
+		&job.Job{ID: "job-done", Type: job.JobTypeCommand, Status: job.JobStatusCompleted, Output: "done\n"},
+		&job.Job{ID: "job-queued", Type: job.JobTypeCommand, Status: job.JobStatusQueued},
+	)
+
+	// The job writes the rest of a line and a partial one, then finishes
+	worker.chunks <- []byte("o\nthr")
+	worker.chunks <- []byte("ee")
+	close(worker.chunks)
+
+	rec := doRequest(t, env.server, http.MethodGet, "/api/v1/jobs/job-running/logs/stream", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if contentType := rec.Header().Get("Content-Type"); contentType != "text/event-stream" {
+		t.Errorf("Expected an event stream, got %s", contentType)
+	}
+	want := "event: output\ndata: one\n\n" +
+		"event: output\ndata: two\n\n" +
+		"event: output\ndata: three\n\n" +
+		"event: end\ndata: running\n\n"
+	if rec.Body.String() != want {
+		t.Errorf("Expected events %q, got %q", want, rec.Body.String())
+	}
+
+	rec = doRequest(t, env.server, http.MethodGet, "/api/v1/jobs/job-done/logs/stream", nil)
+	if want := "event: output\ndata: done\n\nevent: end\ndata: completed\n\n"; rec.Body.String() != want {
+		t.Errorf("Expected the stored output of a finished job, got %q", rec.Body.String())
+	}
+
+	for path, want := range map[string]int{
+		"/api/v1/jobs/job-queued/logs/stream":  http.StatusConflict,
+		"/api/v1/jobs/job-missing/logs/stream": http.StatusNotFound,
+	} {
+		if rec := doRequest(t, env.server, http.MethodGet, path, nil); rec.Code != want {
+			t.Errorf("GET %s: expected status %d, got %d", path, want, rec.Code)
+		}
+	}
+}
+
+func TestEventStream_LongLines(t *testing.T) {
+	rec := httptest.NewRecorder()
+	stream := newEventStream(rec)
+
+	// A line without a newline is sent once it reaches the cap
+	long := strings.Repeat("x", maxEventLine+10)
+	stream.Write([]byte(long))
+	if len(stream.partial) != 10 {
+		t.Errorf("Expected only the 10 bytes past the cap to be held back, got %d", len(stream.partial))
+	}
+	stream.End(job.JobStatusCompleted)
+
+	want := "event: output\ndata: " + long[:maxEventLine] + "\n\n" +
+		"event: output\ndata: " + long[maxEventLine:] + "\n\n" +
+		"event: end\ndata: completed\n\n"
+	if rec.Body.String() != want {
+		t.Errorf("Expected the long line split at %d bytes, got %d bytes of events", maxEventLine, rec.Body.Len())
+	}
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	env := newTestServer(t)
+	env.server.config.Auth.Tokens = []string{"first", "second"}
+	router := env.server.SetupRoutes()
+
+	tests := []struct {
+		name          string
+		path          string
+		authorization string
+		want          int
+	}{
+		{name: "missing token", path: "/api/v1/jobs", want: http.StatusUnauthorized},
+		{name: "wrong token", path: "/api/v1/jobs", authorization: "Bearer third", want: http.StatusUnauthorized},
+		{name: "wrong scheme", path: "/api/v1/jobs", authorization: "Basic second", want: http.StatusUnauthorized},
+		{name: "any configured token", path: "/api/v1/jobs", authorization: "Bearer second", want: http.StatusOK},
+		{name: "health is open", path: "/api/v1/health", want: http.StatusOK},
+		{name: "readiness is open", path: "/api/v1/health/ready", want: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("Expected %d, got %d: %s", tt.want, rec.Code, rec.Body.String())
+			}
+			if tt.want == http.StatusUnauthorized && !strings.Contains(rec.Body.String(), `"error"`) {
+				t.Errorf("Expected a JSON error body, got %s", rec.Body.String())
+			}
+		})
+	}
+
+	// Without tokens the API stays open
+	env.server.config.Auth.Tokens = nil
+	if rec := doRequest(t, env.server, http.MethodGet, "/api/v1/jobs", nil); rec.Code != http.StatusOK {
+		t.Errorf("Expected the API to be open without tokens, got %d", rec.Code)
+	}
+}
+
+func TestHandleWatchJob(t *testing.T) {
+	env := newTestServer(t)
+	seedJobs(t, env, &job.Job{ID: "job-1", Type: job.JobTypeCommand, Status: job.JobStatusQueued})
+
+	server := httptest.NewServer(env.server.SetupRoutes())
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/v1/jobs/job-1/watch"
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	read := func() jobStatusMessage {
+		t.Helper()
+		var message jobStatusMessage
+		if err := conn.ReadJSON(&message); err != nil {
+			t.Fatalf("ReadJSON() error = %v", err)
+		}
+		return message
+	}
+
+	if message := read(); message.JobID != "job-1" || message.Status != job.JobStatusQueued {
+		t.Errorf("Expected the current status first, got %+v", message)
+	}
+
+	ctx := context.Background()
+	if err := env.store.UpdateStatus(ctx, "job-1", job.JobStatusRunning); err != nil {
+		t.Fatalf("UpdateStatus() error = %v", err)
+	}
+	if message := read(); message.Status != job.JobStatusRunning {
+		t.Errorf("Expected a running message, got %+v", message)
+	}
+
+	j, _ := env.store.Get(ctx, "job-1")
+	j.Priority = 5 // not a status change, so nothing is sent
+	if err := env.store.Update(ctx, j); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	j.Status = job.JobStatusFailed
+	j.Error = "exit status 1"
+	if err := env.store.Update(ctx, j); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if message := read(); message.Status != job.JobStatusFailed || message.Error != "exit status 1" {
+		t.Errorf("Expected a failed message with the error, got %+v", message)
+	}
+
+	if _, _, err := conn.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+		t.Errorf("Expected the server to close the connection once the job finished, got %v", err)
+	}
+
+	if rec := doRequest(t, env.server, http.MethodGet, "/api/v1/jobs/job-missing/watch", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 watching a missing job, got %d", rec.Code)
+	}
+}
+
+func TestHandleWatchJob_Origin(t *testing.T) {
+	tests := []struct {
+		name   string
+		tokens []string
+		origin string
+		want   bool
+	}{
+		{name: "no origin", want: true},
+		{name: "same host", origin: "http://api.example:8080", want: true},
+		{name: "other host", origin: "http://evil.example"},
+		{name: "malformed origin", origin: "http://[::1"},
+		{name: "other host with tokens", tokens: []string{"secret"}, origin: "http://evil.example", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestServer(t)
+			env.server.config.Auth.Tokens = tt.tokens
+
+			req := httptest.NewRequest(http.MethodGet, "http://api.example:8080/api/v1/jobs/job-1/watch", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if got := env.server.checkWatchOrigin(req); got != tt.want {
+				t.Errorf("checkWatchOrigin() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
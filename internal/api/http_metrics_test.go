@@ -0,0 +1,108 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"infinitrain/pkg/job"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHTTPMetrics(t *testing.T) {
+	env := newTestServer(t)
+	seedJobs(t, env,
+		&job.Job{ID: "job-a", Type: job.JobTypeCommand, Status: job.JobStatusQueued},
+		&job.Job{ID: "job-b", Type: job.JobTypeCommand, Status: job.JobStatusQueued},
+	)
+
+	doRequest(t, env.server, http.MethodGet, "/api/v1/jobs/job-a", nil)
+	doRequest(t, env.server, http.MethodGet, "/api/v1/jobs/job-b", nil)
+	doRequest(t, env.server, http.MethodGet, "/api/v1/jobs/job-missing", nil)
+
+	rec := doRequest(t, env.server, http.MethodGet, "/metrics", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+	body, _ := io.ReadAll(rec.Body)
+	exposition := string(body)
+
+	// Requests for different job IDs share the route template's series
+	want := []string{
+		`infinitrain_http_requests_total{code="200",method="GET",route="/api/v1/jobs/{id}"} 2`,
+		`infinitrain_http_requests_total{code="404",method="GET",route="/api/v1/jobs/{id}"} 1`,
+		`infinitrain_http_request_duration_seconds_count{code="200",method="GET",route="/api/v1/jobs/{id}"} 2`,
+		`infinitrain_http_request_duration_seconds_bucket{code="200",method="GET",route="/api/v1/jobs/{id}",le="+Inf"} 2`,
+		`infinitrain_http_requests_in_flight{method="GET",route="/metrics"} 1`,
+	}
+	for _, line := range want {
+		if !strings.Contains(exposition, line) {
+			t.Errorf("Expected metrics to contain %s", line)
+		}
+	}
+
+	if strings.Contains(exposition, "job-a") {
+		t.Error("Expected raw job IDs not to appear as label values")
+	}
+}
+
+func TestJobMetrics(t *testing.T) {
+	env := newTestServer(t)
+
+	rec := doRequest(t, env.server, http.MethodPost, "/api/v1/jobs", map[string]string{"type": "command", "command": "true", "tenant": "research"})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var submitted job.Job
+	json.NewDecoder(rec.Body).Decode(&submitted)
+	seedJobs(t, env, &job.Job{ID: "job-done", Type: job.JobTypeHTTP, Status: job.JobStatusRunning, WorkerID: "w1"})
+
+	// Runs submitted by cron go through the manager rather than the API
+	if _, err := env.manager.Submit(context.Background(), &job.JobRequest{Type: job.JobTypeCommand, Command: "true"}); err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+
+	// A result reported again is stored but not counted twice
+	result := job.JobResult{Status: job.JobStatusCompleted, Duration: 2 * time.Second}
+	for i := 0; i < 2; i++ {
+		if rec := doRequest(t, env.server, http.MethodPost, "/api/v1/jobs/job-done/result", result); rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+
+	rec = doRequest(t, env.server, http.MethodGet, "/metrics", nil)
+	body, _ := io.ReadAll(rec.Body)
+	exposition := string(body)
+	for _, line := range []string{
+		`infinitrain_jobs_submitted_total{type="command"} 2`,
+		`infinitrain_jobs_completed_total{type="http"} 1`,
+		`infinitrain_job_duration_seconds_sum{status="completed",type="http"} 2`,
+		`infinitrain_jobs_queued 2`,
+		`infinitrain_jobs_queued_by_tenant{tenant="research"} 1`,
+		`infinitrain_jobs{status="queued"} 2`,
+		`infinitrain_jobs{status="completed"} 1`,
+	} {
+		if !strings.Contains(exposition, line) {
+			t.Errorf("Expected metrics to contain %s", line)
+		}
+	}
+
+	// The JSON report reads the same registry
+	rec = doRequest(t, env.server, http.MethodGet, "/api/v1/metrics", nil)
+	var report struct {
+		Jobs struct {
+			Total     int            `json:"total"`
+			ByStatus  map[string]int `json:"by_status"`
+			Submitted map[string]int `json:"submitted"`
+			Completed map[string]int `json:"completed"`
+		} `json:"jobs"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if report.Jobs.Total != 3 || report.Jobs.ByStatus["queued"] != 2 || report.Jobs.Submitted["command"] != 2 || report.Jobs.Completed["http"] != 1 {
+		t.Errorf("Expected the JSON report to match the registry, got %+v", report.Jobs)
+	}
+}
@@ -0,0 +1,196 @@
+package api
+
+import (
+	"context"
+	"infinitrain/pkg/job"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Names of the job metrics the JSON metrics report reads back
+const (
+	jobsByStatusMetric  = "infinitrain_jobs"
+	jobsSubmittedMetric = "infinitrain_jobs_submitted_total"
+	jobsCompletedMetric = "infinitrain_jobs_completed_total"
+	jobsFailedMetric    = "infinitrain_jobs_failed_total"
+)
+
+// jobMetrics holds the Prometheus collectors describing jobs. Counters and
+// the duration histogram are fed as workers report results; submissions,
+// the per-status and queue gauges are read when scraped.
+type jobMetrics struct {
+	completed *prometheus.CounterVec
+	failed    *prometheus.CounterVec
+	duration  *prometheus.HistogramVec
+}
+
+// newJobMetrics creates the job collectors and registers them
+func newJobMetrics(registry prometheus.Registerer, store job.Store, queue job.Queue, manager job.JobManager) *jobMetrics {
+	m := &jobMetrics{
+		completed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: jobsCompletedMetric,
+			Help: "Job runs that completed, by type.",
+		}, []string{"type"}),
+		failed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: jobsFailedMetric,
+			Help: "Job runs that failed, by type.",
+		}, []string{"type"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "infinitrain_job_duration_seconds",
+			Help:    "How long finished job runs took, by type and status.",
+			Buckets: prometheus.ExponentialBuckets(0.1, 4, 10), // 100ms to ~7h
+		}, []string{"type", "status"}),
+	}
+
+	queued := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "infinitrain_jobs_queued",
+		Help: "Jobs waiting in the run queue.",
+	}, func() float64 {
+		size, err := queue.Size(context.Background())
+		if err != nil {
+			return 0
+		}
+		return float64(size)
+	})
+
+	registry.MustRegister(m.completed, m.failed, m.duration, queued, &jobStatusCollector{store: store}, &queueTenantCollector{queue: queue})
+	if counter, ok := manager.(submittedCounter); ok {
+		registry.MustRegister(&submittedCollector{manager: counter})
+	}
+	return m
+}
+
+// observeResult counts a finished run and records how long it took. Callers
+// only pass results that finished a running job, so a result reported again
+// isn't counted twice.
+func (m *jobMetrics) observeResult(j *job.Job, result *job.JobResult) {
+	switch result.Status {
+	case job.JobStatusCompleted:
+		m.completed.WithLabelValues(string(j.Type)).Inc()
+	case job.JobStatusFailed:
+		m.failed.WithLabelValues(string(j.Type)).Inc()
+	default:
+		return
+	}
+	m.duration.WithLabelValues(string(j.Type), string(result.Status)).Observe(result.Duration.Seconds())
+}
+
+// submittedCounter is implemented by job managers that count the jobs they
+// store, so runs submitted by cron are counted as well as API submissions
+type submittedCounter interface {
+	SubmittedCounts() map[job.JobType]int
+}
+
+// submittedCollector reports the manager's submission counts as a counter
+type submittedCollector struct {
+	manager submittedCounter
+}
+
+var jobsSubmittedDesc = prometheus.NewDesc(jobsSubmittedMetric, "Jobs submitted, by type.", []string{"type"}, nil)
+
+func (c *submittedCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- jobsSubmittedDesc
+}
+
+func (c *submittedCollector) Collect(ch chan<- prometheus.Metric) {
+	for jobType, n := range c.manager.SubmittedCounts() {
+		ch <- prometheus.MustNewConstMetric(jobsSubmittedDesc, prometheus.CounterValue, float64(n), string(jobType))
+	}
+}
+
+// statusCounter is implemented by stores that count jobs by status without
+// loading them
+type statusCounter interface {
+	StatusCounts(ctx context.Context) (map[job.JobStatus]int, error)
+}
+
+// jobStatusCollector reports how many jobs are in each status on every
+// scrape, counted by the store when it can and from one listing otherwise
+type jobStatusCollector struct {
+	store job.Store
+}
+
+var jobsByStatusDesc = prometheus.NewDesc(jobsByStatusMetric, "Jobs in the store, by status.", []string{"status"}, nil)
+
+func (c *jobStatusCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- jobsByStatusDesc
+}
+
+func (c *jobStatusCollector) Collect(ch chan<- prometheus.Metric) {
+	counts, err := c.counts(context.Background())
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(jobsByStatusDesc, err)
+		return
+	}
+
+	for _, status := range job.JobStatuses {
+		ch <- prometheus.MustNewConstMetric(jobsByStatusDesc, prometheus.GaugeValue, float64(counts[status]), string(status))
+	}
+}
+
+// counts returns the number of jobs in each status
+func (c *jobStatusCollector) counts(ctx context.Context) (map[job.JobStatus]int, error) {
+	if counter, ok := c.store.(statusCounter); ok {
+		return counter.StatusCounts(ctx)
+	}
+
+	jobs, err := c.store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[job.JobStatus]int)
+	for _, j := range jobs {
+		counts[j.Status]++
+	}
+	return counts, nil
+}
+
+// queueTenantCollector reports how many jobs each tenant has waiting in the
+// run queue, read from the queue's stats on every scrape
+type queueTenantCollector struct {
+	queue job.Queue
+}
+
+var queuedByTenantDesc = prometheus.NewDesc("infinitrain_jobs_queued_by_tenant", "Jobs waiting in the run queue, by tenant.", []string{"tenant"}, nil)
+
+func (c *queueTenantCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- queuedByTenantDesc
+}
+
+func (c *queueTenantCollector) Collect(ch chan<- prometheus.Metric) {
+	stats, err := c.queue.Stats(context.Background())
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(queuedByTenantDesc, err)
+		return
+	}
+
+	for tenant, depth := range stats.TenantDepths {
+		ch <- prometheus.MustNewConstMetric(queuedByTenantDesc, prometheus.GaugeValue, float64(depth), tenant)
+	}
+}
+
+// gatheredValues returns the values of a gathered counter or gauge keyed by
+// one of its labels. A metric that hasn't been observed yet gives an empty map.
+func gatheredValues(families []*dto.MetricFamily, name, label string) map[string]int {
+	values := make(map[string]int)
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			var key string
+			for _, pair := range metric.GetLabel() {
+				if pair.GetName() == label {
+					key = pair.GetValue()
+				}
+			}
+			value := metric.GetGauge().GetValue()
+			if metric.GetCounter() != nil {
+				value = metric.GetCounter().GetValue()
+			}
+			values[key] = int(value)
+		}
+	}
+	return values
+}
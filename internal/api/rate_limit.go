@@ -0,0 +1,133 @@
+package api
+
+import (
+	"infinitrain/internal/clock"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rateLimitSweepInterval is how often buckets that have refilled are dropped,
+// so clients that went quiet don't hold memory
+const rateLimitSweepInterval = time.Minute
+
+// tokenBucket holds the tokens one client has left
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter is a token bucket per client. Each client may make burst
+// requests at once and then rate requests per second.
+type rateLimiter struct {
+	rate      float64
+	burst     float64
+	clock     clock.Clock
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+	mutex     sync.Mutex
+}
+
+func newRateLimiter(rate float64, burst int, c clock.Clock) *rateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{
+		rate:      rate,
+		burst:     float64(burst),
+		clock:     c,
+		buckets:   make(map[string]*tokenBucket),
+		lastSweep: c.Now(),
+	}
+}
+
+// allow takes a token from the client's bucket. When the bucket is empty it
+// reports how long until the next token is available.
+func (l *rateLimiter) allow(key string) (bool, time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.clock.Now()
+	l.sweep(now)
+
+	bucket, exists := l.buckets[key]
+	if !exists {
+		bucket = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[key] = bucket
+	}
+
+	bucket.tokens = math.Min(l.burst, bucket.tokens+now.Sub(bucket.last).Seconds()*l.rate)
+	bucket.last = now
+
+	if bucket.tokens < 1 {
+		wait := time.Duration((1 - bucket.tokens) / l.rate * float64(time.Second))
+		return false, wait
+	}
+	bucket.tokens--
+	return true, 0
+}
+
+// sweep drops the buckets that would be full by now
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < rateLimitSweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	refill := time.Duration(l.burst / l.rate * float64(time.Second))
+	for key, bucket := range l.buckets {
+		if now.Sub(bucket.last) >= refill {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// rateLimitExempt lists the paths never rate limited, so health checks and
+// metric scrapes keep working while a client is throttled
+var rateLimitExempt = map[string]bool{
+	"/api/v1/health":       true,
+	"/api/v1/health/live":  true,
+	"/api/v1/health/ready": true,
+	"/api/v1/metrics":      true,
+	"/metrics":             true,
+}
+
+// rateLimitMiddleware rejects requests from clients that have used up their
+// bucket with 429 and a Retry-After header. It does nothing unless a rate
+// is configured.
+func (s *Server) rateLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.limiter == nil || rateLimitExempt[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		allowed, wait := s.limiter.allow(s.rateLimitKey(r))
+		if !allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			s.writeError(w, r, http.StatusTooManyRequests, "rate limit exceeded")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// rateLimitKey identifies the client a request counts against: its bearer
+// token when it is one of the configured tokens, otherwise its IP address.
+// Keying on tokens that aren't checked would let a client dodge its limit by
+// sending a new made-up token with each request.
+func (s *Server) rateLimitKey(r *http.Request) string {
+	token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if found && len(s.config.Auth.Tokens) > 0 && s.validToken(token) {
+		return "token:" + token
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
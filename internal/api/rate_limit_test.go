@@ -0,0 +1,107 @@
+package api
+
+import (
+	"infinitrain/internal/clock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimitMiddleware(t *testing.T) {
+	env := newTestServer(t)
+	fake := clock.NewFake(time.Now())
+	env.server.limiter = newRateLimiter(2, 3, fake)
+	router := env.server.SetupRoutes()
+
+	request := func(path, remoteAddr, authorization string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = remoteAddr
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// The burst is allowed, then the client is throttled
+	for i := 0; i < 3; i++ {
+		if rec := request("/api/v1/jobs", "10.0.0.1:5000", ""); rec.Code != http.StatusOK {
+			t.Fatalf("Request %d: expected 200 within the burst, got %d", i+1, rec.Code)
+		}
+	}
+	rec := request("/api/v1/jobs", "10.0.0.1:5001", "")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429 past the burst, got %d", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") != "1" {
+		t.Errorf("Expected Retry-After 1, got %q", rec.Header().Get("Retry-After"))
+	}
+
+	// Other clients and exempt paths have their own allowance, but a bearer
+	// token that isn't checked against any configured tokens doesn't
+	if rec := request("/api/v1/jobs", "10.0.0.2:5000", ""); rec.Code != http.StatusOK {
+		t.Errorf("Expected another IP to be allowed, got %d", rec.Code)
+	}
+	if rec := request("/api/v1/jobs", "10.0.0.1:5000", "Bearer made-up"); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected an unchecked bearer token to count against its IP, got %d", rec.Code)
+	}
+	for _, path := range []string{"/api/v1/health", "/metrics"} {
+		if rec := request(path, "10.0.0.1:5000", ""); rec.Code == http.StatusTooManyRequests {
+			t.Errorf("Expected %s to be exempt from rate limiting", path)
+		}
+	}
+
+	// Tokens refill at the configured rate
+	fake.Advance(500 * time.Millisecond)
+	if rec := request("/api/v1/jobs", "10.0.0.1:5000", ""); rec.Code != http.StatusOK {
+		t.Errorf("Expected a request to be allowed after refilling, got %d", rec.Code)
+	}
+	if rec := request("/api/v1/jobs", "10.0.0.1:5000", ""); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected only one refilled token, got %d", rec.Code)
+	}
+}
+
+func TestRateLimitKey(t *testing.T) {
+	env := newTestServer(t)
+	env.server.config.Auth.Tokens = []string{"ci"}
+
+	tests := []struct {
+		name          string
+		authorization string
+		want          string
+	}{
+		{name: "valid token", authorization: "Bearer ci", want: "token:ci"},
+		{name: "invalid token", authorization: "Bearer made-up", want: "ip:10.0.0.1"},
+		{name: "no token", want: "ip:10.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/jobs", nil)
+			req.RemoteAddr = "10.0.0.1:5000"
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			if got := env.server.rateLimitKey(req); got != tt.want {
+				t.Errorf("Expected key %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRateLimiter_SweepsIdleBuckets(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	limiter := newRateLimiter(1, 2, fake)
+
+	limiter.allow("idle")
+	fake.Advance(rateLimitSweepInterval)
+	limiter.allow("active")
+
+	if _, exists := limiter.buckets["idle"]; exists {
+		t.Errorf("Expected the refilled bucket to be dropped")
+	}
+	if _, exists := limiter.buckets["active"]; !exists {
+		t.Errorf("Expected the active bucket to be kept")
+	}
+}
@@ -0,0 +1,189 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock abstracts time so that time-dependent code can be tested deterministically
+type Clock interface {
+	// Now returns the current time
+	Now() time.Time
+
+	// After waits for the duration to elapse and then sends the current time on the returned channel
+	After(d time.Duration) <-chan time.Time
+
+	// NewTicker returns a ticker that fires every d
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker is the subset of time.Ticker used by the application
+type Ticker interface {
+	// C returns the channel on which ticks are delivered
+	C() <-chan time.Time
+
+	// Stop turns off the ticker
+	Stop()
+}
+
+// Real returns a Clock backed by the time package
+func Real() Clock {
+	return realClock{}
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return &realTicker{ticker: time.NewTicker(d)}
+}
+
+type realTicker struct {
+	ticker *time.Ticker
+}
+
+func (t *realTicker) C() <-chan time.Time {
+	return t.ticker.C
+}
+
+func (t *realTicker) Stop() {
+	t.ticker.Stop()
+}
+
+// Fake is a Clock that only moves when Advance is called, for use in tests
+type Fake struct {
+	mutex   sync.Mutex
+	now     time.Time
+	waiters []*fakeWaiter
+	changed chan struct{}
+}
+
+// fakeWaiter is a pending After call or an active ticker
+type fakeWaiter struct {
+	deadline time.Time
+	period   time.Duration // Zero for one-shot waiters
+	ch       chan time.Time
+	stopped  bool
+}
+
+// NewFake creates a fake clock starting at the given time
+func NewFake(now time.Time) *Fake {
+	return &Fake{
+		now:     now,
+		changed: make(chan struct{}),
+	}
+}
+
+// Now returns the fake clock's current time
+func (f *Fake) Now() time.Time {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.now
+}
+
+// After returns a channel that fires once the clock has been advanced by d
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	return f.addWaiter(d, 0).ch
+}
+
+// NewTicker returns a ticker that fires each time the clock advances past a multiple of d
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	return &fakeTicker{clock: f, waiter: f.addWaiter(d, d)}
+}
+
+// Advance moves the clock forward, firing any timers and tickers that come due
+func (f *Fake) Advance(d time.Duration) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.now = f.now.Add(d)
+
+	active := f.waiters[:0]
+	for _, w := range f.waiters {
+		if w.stopped {
+			continue
+		}
+
+		if !w.deadline.After(f.now) {
+			// Drop the tick if the receiver hasn't consumed the previous one, like time.Ticker
+			select {
+			case w.ch <- f.now:
+			default:
+			}
+
+			if w.period == 0 {
+				continue
+			}
+			for !w.deadline.After(f.now) {
+				w.deadline = w.deadline.Add(w.period)
+			}
+		}
+		active = append(active, w)
+	}
+	f.waiters = active
+}
+
+// WaitForWaiters blocks until at least n timers or tickers are pending, so tests
+// can advance the clock only once the code under test is waiting on it
+func (f *Fake) WaitForWaiters(n int) {
+	for {
+		f.mutex.Lock()
+		count := 0
+		for _, w := range f.waiters {
+			if !w.stopped {
+				count++
+			}
+		}
+		changed := f.changed
+		f.mutex.Unlock()
+
+		if count >= n {
+			return
+		}
+		<-changed
+	}
+}
+
+// addWaiter registers a new timer or ticker and wakes anyone in WaitForWaiters
+func (f *Fake) addWaiter(d, period time.Duration) *fakeWaiter {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	w := &fakeWaiter{
+		deadline: f.now.Add(d),
+		period:   period,
+		ch:       make(chan time.Time, 1),
+	}
+	f.waiters = append(f.waiters, w)
+	f.notify()
+	return w
+}
+
+// notify wakes goroutines blocked in WaitForWaiters; callers must hold the mutex
+func (f *Fake) notify() {
+	close(f.changed)
+	f.changed = make(chan struct{})
+}
+
+type fakeTicker struct {
+	clock  *Fake
+	waiter *fakeWaiter
+}
+
+func (t *fakeTicker) C() <-chan time.Time {
+	return t.waiter.ch
+}
+
+func (t *fakeTicker) Stop() {
+	t.clock.mutex.Lock()
+	defer t.clock.mutex.Unlock()
+	t.waiter.stopped = true
+	t.clock.notify()
+}
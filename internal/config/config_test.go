@@ -0,0 +1,114 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeConfigFile writes YAML to a temporary config file and returns its path
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	return path
+}
+
+func TestLoadConfigFromFile(t *testing.T) {
+	path := writeConfigFile(t, `
+scheduler:
+  port: 9090
+  retry_backoff: 5s
+  job_retention:
+    completed: 1h
+worker:
+  max_concurrent_jobs: 8
+  labels:
+    gpu: "true"
+`)
+	t.Setenv("SCHEDULER_PORT", "7070")
+
+	cfg, err := LoadConfigFromFile(path)
+	if err != nil {
+		t.Fatalf("LoadConfigFromFile() error = %v", err)
+	}
+
+	if cfg.Scheduler.Port != 7070 {
+		t.Errorf("Expected the environment to override the file's port, got %d", cfg.Scheduler.Port)
+	}
+	if cfg.Scheduler.RetryBackoff != 5*time.Second || cfg.Worker.MaxConcurrentJobs != 8 || cfg.Worker.Labels["gpu"] != "true" {
+		t.Errorf("Expected settings from the file, got %+v", cfg)
+	}
+	if cfg.Scheduler.Host != "0.0.0.0" || cfg.Scheduler.JobRetention["failed"] != 7*24*time.Hour {
+		t.Errorf("Expected defaults for settings the file leaves out, got %+v", cfg.Scheduler)
+	}
+	if cfg.Worker.ListenAddress != "127.0.0.1:8081" {
+		t.Errorf("Expected the unauthenticated worker API on loopback by default, got %s", cfg.Worker.ListenAddress)
+	}
+	if cfg.Worker.MaxOutputBytes != 1<<20 {
+		t.Errorf("Expected job output capped at 1 MiB by default, got %d", cfg.Worker.MaxOutputBytes)
+	}
+	if cfg.Scheduler.JobRetention["completed"] != time.Hour {
+		t.Errorf("Expected the file's completed retention, got %v", cfg.Scheduler.JobRetention["completed"])
+	}
+}
+
+func TestLoadConfigFromFile_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{name: "invalid YAML", content: "scheduler: [", want: "invalid config file"},
+		{name: "unknown field", content: "scheduler:\n  prot: 9090\n", want: "field prot not found"},
+		{name: "fails validation", content: "scheduler:\n  port: 70000\n", want: "invalid scheduler port"},
+		{name: "invalid log level", content: "logging:\n  level: verbose\n", want: "invalid log level"},
+		{name: "gRPC port clash", content: "scheduler:\n  port: 9000\n  grpc_port: 9000\n", want: "already used by the REST API"},
+		{name: "negative priority ceiling", content: "scheduler:\n  priority_ceiling: -5\n", want: "priority ceiling cannot be negative"},
+		{name: "retry multiplier below 1", content: "scheduler:\n  retry_multiplier: 0.5\n", want: "scheduler retry multiplier must be at least 1"},
+		{name: "invalid queue mode", content: "scheduler:\n  queue_mode: fifo\n", want: "invalid scheduler queue mode: fifo"},
+		{name: "default timeout over the cap", content: "scheduler:\n  job_timeout: 2h\n  max_job_timeout: 1h\n", want: "scheduler job timeout cannot exceed the max job timeout"},
+		{name: "negative worker max timeout", content: "worker:\n  max_job_timeout: -1h\n", want: "worker max job timeout cannot be negative"},
+		{name: "tracing sample ratio", content: "tracing:\n  sample_ratio: 1.5\n", want: "tracing sample ratio must be between 0 and 1"},
+		{name: "zero body limit", content: "api:\n  max_body_bytes: 0\n", want: "API max body bytes must be positive"},
+		{name: "negative postgres pool", content: "postgres:\n  max_conns: -1\n", want: "postgres max conns cannot be negative"},
+		{name: "invalid HTTP retry status", content: "worker:\n  http_retry_statuses: [503, 42]\n", want: "invalid worker HTTP retry status: 42"},
+		{name: "relative allowed root", content: "worker:\n  allowed_roots: [data]\n", want: "allowed roots must be absolute"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadConfigFromFile(writeConfigFile(t, tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestLoad_ConfigPath(t *testing.T) {
+	flagPath := writeConfigFile(t, "scheduler:\n  port: 9001\n")
+	envPath := writeConfigFile(t, "scheduler:\n  port: 9002\n")
+	t.Setenv("INFINITRAIN_CONFIG", envPath)
+
+	cfg, err := Load([]string{"--config", flagPath})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Scheduler.Port != 9001 {
+		t.Errorf("Expected the --config flag to win, got port %d", cfg.Scheduler.Port)
+	}
+
+	cfg, err = Load(nil)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Scheduler.Port != 9002 {
+		t.Errorf("Expected INFINITRAIN_CONFIG to be used, got port %d", cfg.Scheduler.Port)
+	}
+}
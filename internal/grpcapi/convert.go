@@ -0,0 +1,146 @@
+package grpcapi
+
+import (
+	"infinitrain/pkg/job"
+	"infinitrain/pkg/pb"
+	"time"
+
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// jobRequestFromProto converts a request to the form the manager submits
+func jobRequestFromProto(request *pb.JobRequest) *job.JobRequest {
+	converted := &job.JobRequest{
+		Type:         job.JobType(request.GetType()),
+		Command:      request.GetCommand(),
+		Shell:        request.GetShell(),
+		Steps:        request.GetSteps(),
+		StepRetry:    job.StepRetryMode(request.GetStepRetry()),
+		Script:       request.GetScript(),
+		URL:          request.GetUrl(),
+		Method:       request.GetMethod(),
+		Body:         request.GetBody(),
+		HTTPTimeout:  request.GetHttpTimeout(),
+		ResponsePath: request.GetResponsePath(),
+		FilePath:     request.GetFilePath(),
+		FileContent:  request.GetFileContent(),
+		WorkingDir:   request.GetWorkingDir(),
+		Timeout:      request.GetTimeout(),
+		Priority:     int(request.GetPriority()),
+		Cost:         int(request.GetCost()),
+		Tags:         request.GetTags(),
+		Tenant:       request.GetTenant(),
+		Environment:  request.GetEnvironment(),
+		Secrets:      request.GetSecrets(),
+		Interpolate:  request.GetInterpolate(),
+		Annotations:  request.GetAnnotations(),
+		MutexKey:     request.GetMutexKey(),
+		AffinityKey:  request.GetAffinityKey(),
+		NodeSelector: request.GetNodeSelector(),
+		DependsOn:    request.GetDependsOn(),
+		Schedule:     request.GetSchedule(),
+		Timezone:     request.GetTimezone(),
+		Scheduling:   job.SchedulingMode(request.GetSchedulingMode()),
+		Durable:      request.GetDurable(),
+		LogOverflow:  job.LogOverflow(request.GetLogOverflow()),
+	}
+	if request.Retries != nil {
+		retries := int(request.GetRetries())
+		converted.Retries = &retries
+	}
+	if policy := request.GetRetryPolicy(); policy != nil {
+		converted.RetryPolicy = &job.RetryPolicy{
+			BaseDelay:  policy.GetBaseDelay().AsDuration(),
+			MaxDelay:   policy.GetMaxDelay().AsDuration(),
+			Multiplier: policy.GetMultiplier(),
+			Jitter:     policy.GetJitter(),
+		}
+		if policy.MaxAttempts != nil {
+			attempts := int(policy.GetMaxAttempts())
+			converted.RetryPolicy.MaxAttempts = &attempts
+		}
+	}
+	return converted
+}
+
+// jobToProto converts a job for a response
+func jobToProto(j *job.Job) *pb.Job {
+	return &pb.Job{
+		Id:                j.ID,
+		Type:              string(j.Type),
+		Command:           j.Command,
+		Shell:             j.Shell,
+		Steps:             j.Steps,
+		Script:            j.Script,
+		Url:               j.URL,
+		Method:            j.Method,
+		FilePath:          j.FilePath,
+		WorkingDir:        j.WorkingDir,
+		Timeout:           durationpb.New(j.Timeout),
+		Retries:           int32(j.Retries),
+		RetryCount:        int32(j.RetryCount),
+		Priority:          int32(j.Priority),
+		Cost:              int32(j.Cost),
+		Tags:              j.Tags,
+		Tenant:            j.Tenant,
+		Environment:       j.Environment,
+		Secrets:           j.Secrets,
+		Annotations:       j.Annotations,
+		NodeSelector:      j.NodeSelector,
+		DependsOn:         j.DependsOn,
+		Schedule:          j.Schedule,
+		ParentId:          j.ParentID,
+		WorkerId:          j.WorkerID,
+		Status:            string(j.Status),
+		CreatedAt:         timestamppb.New(j.CreatedAt),
+		StartedAt:         optionalTimestamp(j.StartedAt),
+		CompletedAt:       optionalTimestamp(j.CompletedAt),
+		Output:            j.Output,
+		OutputBytes:       j.OutputBytes,
+		OutputTruncated:   j.Truncated,
+		Error:             j.Error,
+		ExitCode:          int32(j.ExitCode),
+		TerminationReason: string(j.Termination),
+		Progress:          int32(j.Progress),
+	}
+}
+
+// jobResultToProto converts a job result for a response
+func jobResultToProto(result *job.JobResult) *pb.JobResult {
+	return &pb.JobResult{
+		JobId:             result.JobID,
+		Status:            string(result.Status),
+		Output:            result.Output,
+		OutputBytes:       result.OutputBytes,
+		OutputLines:       result.OutputLines,
+		OutputTruncated:   result.Truncated,
+		Error:             result.Error,
+		ExitCode:          int32(result.ExitCode),
+		TerminationReason: string(result.Termination),
+		StartedAt:         optionalTimestamp(&result.StartedAt),
+		CompletedAt:       optionalTimestamp(&result.CompletedAt),
+		Duration:          durationpb.New(result.Duration),
+		Stdout:            result.Stdout,
+		Stderr:            result.Stderr,
+	}
+}
+
+// statusUpdateToProto converts a job to the update WatchJob streams
+func statusUpdateToProto(j *job.Job) *pb.JobStatusUpdate {
+	return &pb.JobStatusUpdate{
+		JobId:    j.ID,
+		Status:   string(j.Status),
+		WorkerId: j.WorkerID,
+		Error:    j.Error,
+	}
+}
+
+// optionalTimestamp converts a time that may be unset, leaving the field
+// empty rather than sending the zero time
+func optionalTimestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil || t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
@@ -0,0 +1,288 @@
+// Package grpcapi serves the scheduler's job operations over gRPC. It sits
+// alongside the REST API in internal/api and shares its store, manager and
+// worker registry, so jobs submitted through either are the same jobs.
+package grpcapi
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"infinitrain/internal/config"
+	"infinitrain/internal/scheduler"
+	"infinitrain/pkg/job"
+	"infinitrain/pkg/pb"
+	"net"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Server implements pb.JobServiceServer
+type Server struct {
+	pb.UnimplementedJobServiceServer
+
+	config  *config.Config
+	store   job.Store
+	manager job.JobManager
+	workers job.WorkerRegistry
+	grpc    *grpc.Server
+}
+
+// NewServer creates a gRPC server backed by the given store, manager and
+// worker registry
+func NewServer(cfg *config.Config, store job.Store, manager job.JobManager, workers job.WorkerRegistry) *Server {
+	s := &Server{
+		config:  cfg,
+		store:   store,
+		manager: manager,
+		workers: workers,
+	}
+	s.grpc = grpc.NewServer(
+		grpc.UnaryInterceptor(s.authUnary),
+		grpc.StreamInterceptor(s.authStream),
+	)
+	pb.RegisterJobServiceServer(s.grpc, s)
+	return s
+}
+
+// authUnary rejects calls without one of the configured bearer tokens in
+// their "authorization" metadata, like the REST API's auth middleware. It
+// does nothing when no tokens are configured.
+func (s *Server) authUnary(ctx context.Context, request any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := s.authenticate(ctx); err != nil {
+		return nil, err
+	}
+	return handler(ctx, request)
+}
+
+// authStream is authUnary for streaming calls
+func (s *Server) authStream(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := s.authenticate(stream.Context()); err != nil {
+		return err
+	}
+	return handler(srv, stream)
+}
+
+// authenticate checks the bearer token a call carries against the
+// configured tokens, comparing in constant time
+func (s *Server) authenticate(ctx context.Context) error {
+	if len(s.config.Auth.Tokens) == 0 {
+		return nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get("authorization") {
+		token, found := strings.CutPrefix(value, "Bearer ")
+		if !found {
+			continue
+		}
+		valid := false
+		for _, configured := range s.config.Auth.Tokens {
+			if subtle.ConstantTimeCompare([]byte(token), []byte(configured)) == 1 {
+				valid = true
+			}
+		}
+		if valid {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "missing or invalid bearer token")
+}
+
+// Serve accepts connections on the listener until Stop is called
+func (s *Server) Serve(listener net.Listener) error {
+	return s.grpc.Serve(listener)
+}
+
+// ListenAndServe listens on the configured gRPC address and serves on it
+func (s *Server) ListenAndServe() error {
+	listener, err := net.Listen("tcp", s.config.GetGRPCAddress())
+	if err != nil {
+		return err
+	}
+	return s.Serve(listener)
+}
+
+// Stop stops accepting connections and waits for in-flight RPCs to finish.
+// Open WatchJob streams are ended when the context is done.
+func (s *Server) Stop(ctx context.Context) {
+	stopped := make(chan struct{})
+	go func() {
+		s.grpc.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		s.grpc.Stop()
+	}
+}
+
+// SubmitJob validates and submits a job
+func (s *Server) SubmitJob(ctx context.Context, request *pb.SubmitJobRequest) (*pb.Job, error) {
+	if request.GetJob() == nil {
+		return nil, status.Error(codes.InvalidArgument, "job is required")
+	}
+
+	j, err := s.manager.Submit(ctx, jobRequestFromProto(request.GetJob()))
+	if err != nil {
+		return nil, statusError(err, "failed to submit job")
+	}
+	return jobToProto(j), nil
+}
+
+// GetJob returns a job by ID
+func (s *Server) GetJob(ctx context.Context, request *pb.GetJobRequest) (*pb.Job, error) {
+	j, err := s.manager.GetJob(ctx, request.GetId())
+	if err != nil {
+		return nil, statusError(err, "failed to get job")
+	}
+	return jobToProto(j), nil
+}
+
+// ListJobs returns one page of the jobs matching the request's filters
+func (s *Server) ListJobs(ctx context.Context, request *pb.ListJobsRequest) (*pb.ListJobsResponse, error) {
+	var filters []job.Filter
+	if request.GetStatus() != "" {
+		if !job.JobStatus(request.GetStatus()).IsValid() {
+			return nil, status.Error(codes.InvalidArgument, "invalid status filter: "+request.GetStatus())
+		}
+		filters = append(filters, job.Filter{Field: "status", Operator: "eq", Value: request.GetStatus()})
+	}
+	if request.GetWorkerId() != "" {
+		filters = append(filters, job.Filter{Field: "worker_id", Operator: "eq", Value: request.GetWorkerId()})
+	}
+	for _, tag := range request.GetTags() {
+		filters = append(filters, job.Filter{Field: "tags", Operator: "contains", Value: tag})
+	}
+	if request.GetLimit() < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid limit: %d", request.GetLimit())
+	}
+
+	page, err := s.manager.ListJobsPage(ctx, job.ListOptions{
+		Filters: filters,
+		Limit:   int(request.GetLimit()),
+		Cursor:  request.GetCursor(),
+	})
+	if err != nil {
+		return nil, statusError(err, "failed to list jobs")
+	}
+
+	response := &pb.ListJobsResponse{Total: int32(page.Total), NextCursor: page.NextCursor}
+	for _, j := range page.Jobs {
+		response.Jobs = append(response.Jobs, jobToProto(j))
+	}
+	return response, nil
+}
+
+// CancelJob cancels a job that hasn't finished
+func (s *Server) CancelJob(ctx context.Context, request *pb.CancelJobRequest) (*pb.CancelJobResponse, error) {
+	if err := s.manager.CancelJob(ctx, request.GetId()); err != nil {
+		if job.IsValidationError(err) {
+			// Matches the REST API, which reports these as conflicts
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
+		}
+		return nil, statusError(err, "failed to cancel job")
+	}
+	return &pb.CancelJobResponse{}, nil
+}
+
+// GetJobResult returns the result of a job's latest run
+func (s *Server) GetJobResult(ctx context.Context, request *pb.GetJobResultRequest) (*pb.JobResult, error) {
+	result, err := s.manager.GetJobResult(ctx, request.GetJobId())
+	if err != nil {
+		return nil, statusError(err, "failed to get job result")
+	}
+	return jobResultToProto(result), nil
+}
+
+// RegisterWorker adds a remote worker to the registry
+func (s *Server) RegisterWorker(ctx context.Context, request *pb.RegisterWorkerRequest) (*pb.RegisterWorkerResponse, error) {
+	registration := job.WorkerRegistration{
+		ID:       request.GetId(),
+		Capacity: int(request.GetCapacity()),
+		Labels:   request.GetLabels(),
+		Address:  request.GetAddress(),
+	}
+	if err := registration.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	worker := scheduler.NewRemoteWorker(registration)
+	if err := s.workers.Register(ctx, worker); err != nil {
+		switch {
+		case job.IsValidationError(err):
+			return nil, status.Error(codes.AlreadyExists, err.Error())
+		case errors.Is(err, job.ErrWorkerLimitReached):
+			return nil, status.Error(codes.ResourceExhausted, err.Error())
+		default:
+			return nil, status.Error(codes.Internal, "failed to register worker: "+err.Error())
+		}
+	}
+
+	return &pb.RegisterWorkerResponse{
+		Id:       worker.ID(),
+		Capacity: int32(worker.GetCapacity()),
+		Labels:   worker.Labels(),
+		Address:  worker.Address(),
+	}, nil
+}
+
+// WatchJob sends the job's status, then an update each time it changes,
+// returning once the job has finished
+func (s *Server) WatchJob(request *pb.WatchJobRequest, stream grpc.ServerStreamingServer[pb.JobStatusUpdate]) error {
+	watcher, ok := s.store.(interface {
+		WatchStatus(jobID string) (<-chan *job.Job, func())
+	})
+	if !ok {
+		return status.Error(codes.Unimplemented, "the job store does not support watching jobs")
+	}
+
+	// Subscribe before reading the job so no change in between is missed
+	changes, cancel := watcher.WatchStatus(request.GetId())
+	defer cancel()
+
+	j, err := s.store.Get(stream.Context(), request.GetId())
+	if err != nil {
+		return statusError(err, "failed to get job")
+	}
+
+	last := j.Status
+	if err := stream.Send(statusUpdateToProto(j)); err != nil {
+		return err
+	}
+	for !j.IsTerminal() {
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case j = <-changes:
+			if j.Status == last {
+				continue
+			}
+			last = j.Status
+			if err := stream.Send(statusUpdateToProto(j)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// statusError maps a job error to the gRPC status closest to the HTTP status
+// the REST API answers with
+func statusError(err error, message string) error {
+	switch {
+	case job.IsJobNotFoundError(err):
+		return status.Error(codes.NotFound, err.Error())
+	case job.IsValidationError(err):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case job.IsConflictError(err):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	default:
+		return status.Error(codes.Internal, message+": "+err.Error())
+	}
+}
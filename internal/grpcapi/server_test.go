@@ -0,0 +1,230 @@
+package grpcapi
+
+import (
+	"context"
+	"infinitrain/internal/config"
+	"infinitrain/internal/scheduler"
+	"infinitrain/pkg/job"
+	"infinitrain/pkg/pb"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// testEnv holds a running gRPC server, a client connected to it and the
+// components behind the server
+type testEnv struct {
+	client   pb.JobServiceClient
+	store    *scheduler.MemoryStore
+	registry *scheduler.MemoryWorkerRegistry
+}
+
+// newTestServer serves the job service over an in-memory connection
+func newTestServer(t *testing.T) *testEnv {
+	return newTestServerWithConfig(t, config.LoadConfig())
+}
+
+// newTestServerWithConfig serves the job service with the given config
+func newTestServerWithConfig(t *testing.T, cfg *config.Config) *testEnv {
+	t.Helper()
+
+	store := scheduler.NewMemoryStore()
+	queue := scheduler.NewPriorityQueue()
+	registry := scheduler.NewMemoryWorkerRegistry()
+	manager := scheduler.NewManager(store, queue, registry)
+	server := NewServer(cfg, store, manager, registry)
+
+	listener := bufconn.Listen(1 << 20)
+	go server.Serve(listener)
+	t.Cleanup(func() { server.Stop(context.Background()) })
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return &testEnv{client: pb.NewJobServiceClient(conn), store: store, registry: registry}
+}
+
+// expectCode fails the test unless err carries the given status code
+func expectCode(t *testing.T, err error, code codes.Code) {
+	t.Helper()
+	if status.Code(err) != code {
+		t.Errorf("Expected status %s, got %v", code, err)
+	}
+}
+
+func TestServer_JobLifecycle(t *testing.T) {
+	env := newTestServer(t)
+	ctx := context.Background()
+
+	retries := int32(2)
+	submitted, err := env.client.SubmitJob(ctx, &pb.SubmitJobRequest{Job: &pb.JobRequest{
+		Type:    "command",
+		Command: "echo hi",
+		Timeout: "90s",
+		Retries: &retries,
+		Tags:    []string{"nightly"},
+		Tenant:  "research",
+	}})
+	if err != nil {
+		t.Fatalf("SubmitJob() error = %v", err)
+	}
+	if submitted.GetId() == "" || submitted.GetStatus() != string(job.JobStatusQueued) {
+		t.Errorf("Expected a queued job with an ID, got %+v", submitted)
+	}
+	if submitted.GetRetries() != 2 || submitted.GetTimeout().AsDuration() != 90*time.Second || submitted.GetTenant() != "research" {
+		t.Errorf("Expected the requested retries, timeout and tenant, got %+v", submitted)
+	}
+
+	_, err = env.client.SubmitJob(ctx, &pb.SubmitJobRequest{Job: &pb.JobRequest{Type: "command"}})
+	expectCode(t, err, codes.InvalidArgument)
+
+	attempts := int32(3)
+	backoff, err := env.client.SubmitJob(ctx, &pb.SubmitJobRequest{Job: &pb.JobRequest{
+		Type:        "command",
+		Command:     "false",
+		RetryPolicy: &pb.RetryPolicy{BaseDelay: durationpb.New(5 * time.Second), MaxAttempts: &attempts},
+	}})
+	if err != nil {
+		t.Fatalf("SubmitJob() error = %v", err)
+	}
+	if stored, _ := env.store.Get(ctx, backoff.GetId()); stored.RetryPolicy == nil || stored.RetryPolicy.BaseDelay != 5*time.Second || stored.Retries != 2 {
+		t.Errorf("Expected the job to carry its retry policy, got %+v", stored)
+	}
+
+	got, err := env.client.GetJob(ctx, &pb.GetJobRequest{Id: submitted.GetId()})
+	if err != nil {
+		t.Fatalf("GetJob() error = %v", err)
+	}
+	if got.GetCommand() != "echo hi" || got.GetCreatedAt() == nil || got.GetStartedAt() != nil {
+		t.Errorf("Expected the submitted job back, got %+v", got)
+	}
+	_, err = env.client.GetJob(ctx, &pb.GetJobRequest{Id: "missing"})
+	expectCode(t, err, codes.NotFound)
+
+	if _, err := env.client.SubmitJob(ctx, &pb.SubmitJobRequest{Job: &pb.JobRequest{Type: "command", Command: "true"}}); err != nil {
+		t.Fatalf("SubmitJob() error = %v", err)
+	}
+	listed, err := env.client.ListJobs(ctx, &pb.ListJobsRequest{Tags: []string{"nightly"}})
+	if err != nil {
+		t.Fatalf("ListJobs() error = %v", err)
+	}
+	if listed.GetTotal() != 1 || len(listed.GetJobs()) != 1 || listed.GetJobs()[0].GetId() != submitted.GetId() {
+		t.Errorf("Expected only the tagged job, got %+v", listed)
+	}
+	_, err = env.client.ListJobs(ctx, &pb.ListJobsRequest{Status: "sleeping"})
+	expectCode(t, err, codes.InvalidArgument)
+
+	_, err = env.client.GetJobResult(ctx, &pb.GetJobResultRequest{JobId: submitted.GetId()})
+	expectCode(t, err, codes.FailedPrecondition)
+
+	if _, err := env.client.CancelJob(ctx, &pb.CancelJobRequest{Id: submitted.GetId()}); err != nil {
+		t.Fatalf("CancelJob() error = %v", err)
+	}
+	_, err = env.client.CancelJob(ctx, &pb.CancelJobRequest{Id: submitted.GetId()})
+	expectCode(t, err, codes.FailedPrecondition)
+}
+
+func TestServer_RegisterWorker(t *testing.T) {
+	env := newTestServer(t)
+	ctx := context.Background()
+
+	request := &pb.RegisterWorkerRequest{Id: "worker-1", Capacity: 4, Labels: map[string]string{"gpu": "true"}}
+	registered, err := env.client.RegisterWorker(ctx, request)
+	if err != nil {
+		t.Fatalf("RegisterWorker() error = %v", err)
+	}
+	if registered.GetId() != "worker-1" || registered.GetCapacity() != 4 || registered.GetLabels()["gpu"] != "true" {
+		t.Errorf("Expected the registered worker back, got %+v", registered)
+	}
+	if _, err := env.registry.GetWorker(ctx, "worker-1"); err != nil {
+		t.Errorf("Expected the worker in the registry, got %v", err)
+	}
+
+	_, err = env.client.RegisterWorker(ctx, request)
+	expectCode(t, err, codes.AlreadyExists)
+	_, err = env.client.RegisterWorker(ctx, &pb.RegisterWorkerRequest{Id: "worker-2"})
+	expectCode(t, err, codes.InvalidArgument)
+}
+
+func TestServer_WatchJob(t *testing.T) {
+	env := newTestServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := env.store.Create(ctx, &job.Job{ID: "job-1", Type: job.JobTypeCommand, Status: job.JobStatusQueued}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	stream, err := env.client.WatchJob(ctx, &pb.WatchJobRequest{Id: "job-1"})
+	if err != nil {
+		t.Fatalf("WatchJob() error = %v", err)
+	}
+	if update, err := stream.Recv(); err != nil || update.GetStatus() != string(job.JobStatusQueued) {
+		t.Fatalf("Expected the current status first, got %+v, %v", update, err)
+	}
+
+	if err := env.store.UpdateStatus(ctx, "job-1", job.JobStatusRunning); err != nil {
+		t.Fatalf("UpdateStatus() error = %v", err)
+	}
+	if update, err := stream.Recv(); err != nil || update.GetStatus() != string(job.JobStatusRunning) {
+		t.Errorf("Expected a running update, got %+v, %v", update, err)
+	}
+
+	if err := env.store.UpdateStatus(ctx, "job-1", job.JobStatusCompleted); err != nil {
+		t.Fatalf("UpdateStatus() error = %v", err)
+	}
+	if update, err := stream.Recv(); err != nil || update.GetStatus() != string(job.JobStatusCompleted) {
+		t.Errorf("Expected a completed update, got %+v, %v", update, err)
+	}
+	if _, err := stream.Recv(); err != io.EOF {
+		t.Errorf("Expected the stream to end once the job finished, got %v", err)
+	}
+
+	missing, err := env.client.WatchJob(ctx, &pb.WatchJobRequest{Id: "missing"})
+	if err != nil {
+		t.Fatalf("WatchJob() error = %v", err)
+	}
+	_, err = missing.Recv()
+	expectCode(t, err, codes.NotFound)
+}
+
+func TestServer_BearerToken(t *testing.T) {
+	cfg := config.LoadConfig()
+	cfg.Auth.Tokens = []string{"secret"}
+	env := newTestServerWithConfig(t, cfg)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := env.client.GetJob(ctx, &pb.GetJobRequest{Id: "job-1"})
+	expectCode(t, err, codes.Unauthenticated)
+
+	wrong := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer wrong")
+	_, err = env.client.GetJob(wrong, &pb.GetJobRequest{Id: "job-1"})
+	expectCode(t, err, codes.Unauthenticated)
+
+	stream, err := env.client.WatchJob(ctx, &pb.WatchJobRequest{Id: "job-1"})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	expectCode(t, err, codes.Unauthenticated)
+
+	authorized := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer secret")
+	_, err = env.client.GetJob(authorized, &pb.GetJobRequest{Id: "job-1"})
+	expectCode(t, err, codes.NotFound)
+}
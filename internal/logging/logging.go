@@ -0,0 +1,79 @@
+// Package logging builds the structured logger described by the logging
+// configuration
+package logging
+
+import (
+	"fmt"
+	"infinitrain/internal/config"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// New creates a logger that writes lines at or above the configured level,
+// in the configured format, to stdout, stderr or the file the output names.
+// The returned closer closes that file; it is a no-op for the standard
+// streams.
+func New(cfg *config.LoggingConfig) (*slog.Logger, io.Closer, error) {
+	level, err := ParseLevel(cfg.Level)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var out io.WriteCloser
+	switch cfg.Output {
+	case "", "stdout":
+		out = nopCloser{os.Stdout}
+	case "stderr":
+		out = nopCloser{os.Stderr}
+	default:
+		if err := os.MkdirAll(filepath.Dir(cfg.Output), 0755); err != nil {
+			return nil, nil, fmt.Errorf("failed to create log directory: %w", err)
+		}
+		file, err := os.OpenFile(cfg.Output, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open log file: %w", err)
+		}
+		out = file
+	}
+
+	options := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch cfg.Format {
+	case "", "json":
+		handler = slog.NewJSONHandler(out, options)
+	case "text":
+		handler = slog.NewTextHandler(out, options)
+	default:
+		out.Close()
+		return nil, nil, fmt.Errorf("invalid log format: %s", cfg.Format)
+	}
+
+	return slog.New(handler), out, nil
+}
+
+// ParseLevel converts a configured level name (debug, info, warn or error)
+// to a slog level
+func ParseLevel(name string) (slog.Level, error) {
+	switch strings.ToLower(name) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return 0, fmt.Errorf("invalid log level: %s", name)
+	}
+}
+
+// nopCloser keeps the standard streams open when the logger is closed
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
@@ -0,0 +1,75 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"infinitrain/internal/config"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNew_WritesToFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "infinitrain.log")
+	logger, closer, err := New(&config.LoggingConfig{Level: "warn", Format: "json", Output: path})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	logger.Info("below the level")
+	logger.Warn("job failed", "job_id", "job-1", "status", "failed")
+	if err := closer.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read log file: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("Expected only the warning to be written, got %q", data)
+	}
+
+	var entry map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatalf("Expected a JSON line, got %q: %v", lines[0], err)
+	}
+	if entry["msg"] != "job failed" || entry["job_id"] != "job-1" || entry["status"] != "failed" {
+		t.Errorf("Expected the message and its fields, got %v", entry)
+	}
+}
+
+func TestNew_Errors(t *testing.T) {
+	for _, cfg := range []config.LoggingConfig{
+		{Level: "verbose"},
+		{Format: "xml"},
+	} {
+		if _, _, err := New(&cfg); err == nil {
+			t.Errorf("Expected an error for %+v", cfg)
+		}
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := map[string]slog.Level{
+		"":        slog.LevelInfo,
+		"debug":   slog.LevelDebug,
+		"INFO":    slog.LevelInfo,
+		"warning": slog.LevelWarn,
+		"error":   slog.LevelError,
+	}
+	for name, want := range tests {
+		got, err := ParseLevel(name)
+		if err != nil || got != want {
+			t.Errorf("ParseLevel(%q) = %v, %v, want %v", name, got, err, want)
+		}
+	}
+
+	logger, _, _ := New(&config.LoggingConfig{Level: "error", Output: "stderr"})
+	if logger.Enabled(context.Background(), slog.LevelWarn) {
+		t.Errorf("Expected warnings to be suppressed at the error level")
+	}
+}
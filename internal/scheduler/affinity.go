@@ -0,0 +1,66 @@
+package scheduler
+
+import (
+	"infinitrain/internal/clock"
+	"sync"
+	"time"
+)
+
+// defaultAffinityTTL is how long a worker stays preferred for an affinity key
+// after last being assigned a job with it
+const defaultAffinityTTL = 30 * time.Minute
+
+// affinityTracker remembers which worker last ran a job with each affinity
+// key, so related jobs can reuse the state left on that worker
+type affinityTracker struct {
+	mutex   sync.Mutex
+	ttl     time.Duration
+	clock   clock.Clock
+	workers map[string]affinityEntry
+}
+
+// affinityEntry is the worker last assigned a key and when
+type affinityEntry struct {
+	workerID   string
+	assignedAt time.Time
+}
+
+func newAffinityTracker(ttl time.Duration, c clock.Clock) *affinityTracker {
+	return &affinityTracker{
+		ttl:     ttl,
+		clock:   c,
+		workers: make(map[string]affinityEntry),
+	}
+}
+
+// Lookup returns the worker last assigned a job with the key, unless the
+// mapping has expired
+func (a *affinityTracker) Lookup(key string) (string, bool) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	entry, exists := a.workers[key]
+	if !exists {
+		return "", false
+	}
+	if a.clock.Now().Sub(entry.assignedAt) > a.ttl {
+		delete(a.workers, key)
+		return "", false
+	}
+	return entry.workerID, true
+}
+
+// Record notes that a job with the key was assigned to the worker, and
+// drops expired mappings so the tracker doesn't grow without bound
+func (a *affinityTracker) Record(key, workerID string) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	now := a.clock.Now()
+	for k, entry := range a.workers {
+		if now.Sub(entry.assignedAt) > a.ttl {
+			delete(a.workers, k)
+		}
+	}
+	a.workers[key] = affinityEntry{workerID: workerID, assignedAt: now}
+}
@@ -0,0 +1,132 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"infinitrain/internal/clock"
+	"infinitrain/pkg/job"
+	"log/slog"
+	"time"
+)
+
+// CronScheduler submits a run of each recurring job whenever its cron
+// schedule fires. Recurring jobs stay pending in the store as templates;
+// each carries its next run time, so schedules survive a scheduler restart
+// and a run missed while it was down is submitted once on the next check.
+// Cancelling a recurring job stops its future runs.
+type CronScheduler struct {
+	store   job.Store
+	manager job.JobManager
+	clock   clock.Clock
+	logger  *slog.Logger
+}
+
+// NewCronScheduler creates a cron scheduler that submits runs through the
+// manager, like any other job
+func NewCronScheduler(store job.Store, manager job.JobManager) *CronScheduler {
+	return NewCronSchedulerWithClock(store, manager, clock.Real())
+}
+
+// NewCronSchedulerWithClock creates a cron scheduler that reads the time
+// from the given clock
+func NewCronSchedulerWithClock(store job.Store, manager job.JobManager, c clock.Clock) *CronScheduler {
+	return &CronScheduler{
+		store:   store,
+		manager: manager,
+		clock:   c,
+		logger:  slog.Default(),
+	}
+}
+
+// SetLogger sets the logger the cron scheduler reports failures to
+func (cs *CronScheduler) SetLogger(logger *slog.Logger) {
+	cs.logger = logger
+}
+
+// Run checks for due runs every interval until the context is cancelled
+func (cs *CronScheduler) Run(ctx context.Context, interval time.Duration) {
+	ticker := cs.clock.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			if _, err := cs.Tick(ctx); err != nil {
+				cs.logger.Error("cron scheduler check failed", "error", err)
+			}
+		}
+	}
+}
+
+// Tick submits a run of every recurring job that is due and returns the
+// submitted runs. A schedule seen for the first time is only given its next
+// run time.
+func (cs *CronScheduler) Tick(ctx context.Context) ([]*job.Job, error) {
+	templates, err := cs.store.List(ctx, job.Filter{Field: "status", Operator: "eq", Value: string(job.JobStatusPending)})
+	if err != nil {
+		return nil, err
+	}
+
+	now := cs.clock.Now().UTC()
+	var runs []*job.Job
+	for _, template := range templates {
+		if !template.IsRecurring() {
+			continue
+		}
+		if template.NextRunAt != nil && now.Before(*template.NextRunAt) {
+			continue
+		}
+
+		run, err := cs.advance(ctx, template.ID, now)
+		if err != nil {
+			cs.logger.Error("failed to advance recurring job", "job_id", template.ID, "error", err)
+			continue
+		}
+		if run != nil {
+			runs = append(runs, run)
+		}
+	}
+
+	return runs, nil
+}
+
+// advance moves a recurring job's next run time past now and, unless it was
+// only just scheduled, submits a run. The job is re-read first so one
+// cancelled since the listing is left alone. The next run time is stored
+// before submitting, so a failed submission skips that run rather than
+// being retried on every check.
+func (cs *CronScheduler) advance(ctx context.Context, jobID string, now time.Time) (*job.Job, error) {
+	template, err := cs.store.Get(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if template.Status != job.JobStatusPending {
+		return nil, nil
+	}
+
+	schedule, err := job.ParseSchedule(template.Schedule, template.Timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	due := template.NextRunAt != nil
+	next := schedule.Next(now).UTC()
+	template.NextRunAt = &next
+	if next.IsZero() {
+		template.NextRunAt = nil // The schedule never fires again, e.g. on 30 February
+	}
+	if err := cs.store.Update(ctx, template); err != nil {
+		return nil, fmt.Errorf("failed to store next run time: %w", err)
+	}
+	if !due {
+		return nil, nil
+	}
+
+	run, err := cs.manager.Submit(ctx, template.RunRequest())
+	if err != nil {
+		return nil, fmt.Errorf("failed to submit run: %w", err)
+	}
+	return run, nil
+}
@@ -0,0 +1,114 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"infinitrain/pkg/job"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ExplainScheduling reports whether a job can be dispatched right now and
+// lists everything that stops it: its status, unfinished dependencies, a
+// held mutex key and the lack of a worker with room for it. Every blocker is
+// listed, not just the first the scheduler would hit.
+func (m *Manager) ExplainScheduling(ctx context.Context, jobID string) (*job.Schedulability, error) {
+	j, err := m.store.Get(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	explanation := &job.Schedulability{JobID: j.ID, Status: j.Status}
+	block := func(reason job.BlockReason, detail string) {
+		explanation.Blockers = append(explanation.Blockers, job.Blocker{Reason: reason, Detail: detail})
+	}
+
+	if j.Status != job.JobStatusQueued {
+		block(job.BlockedNotQueued, notQueuedDetail(j))
+	}
+
+	if explanation.Dependencies, err = dependencyStates(ctx, m.store, j, m.cascade); err != nil {
+		return nil, err
+	}
+	var unmet []string
+	for _, state := range explanation.Dependencies {
+		switch {
+		case state.Satisfied:
+		case state.Status == "":
+			unmet = append(unmet, state.JobID+" (not found)")
+		default:
+			unmet = append(unmet, fmt.Sprintf("%s (%s)", state.JobID, state.Status))
+		}
+	}
+	if len(unmet) > 0 {
+		block(job.BlockedDependency, "waiting for "+strings.Join(unmet, ", "))
+	}
+
+	if j.MutexKey != "" && !j.IsTerminal() && j.Status != job.JobStatusRunning {
+		held, err := heldMutexKeys(ctx, m.store)
+		if err != nil {
+			return nil, err
+		}
+		if held[j.MutexKey] {
+			block(job.BlockedMutex, "mutex key "+j.MutexKey+" is held by a running job")
+		}
+	}
+
+	if !j.IsTerminal() && j.Status != job.JobStatusRunning {
+		worker, err := m.workers.GetLeastLoadedWorker(ctx, j.NodeSelector, j.Weight())
+		switch {
+		case err == nil && canTake(worker, j):
+			explanation.WorkerID = worker.ID()
+		case err == nil || err == job.ErrNoWorkerAvailable:
+			detail := fmt.Sprintf("no available worker has room for a job of cost %d", j.Weight())
+			if len(j.NodeSelector) > 0 {
+				detail += " and labels " + formatSelector(j.NodeSelector)
+			}
+			block(job.BlockedNoWorker, detail)
+		default:
+			return nil, err
+		}
+	}
+
+	if positioned, ok := m.queue.(interface {
+		Position(ctx context.Context, jobID string) (int, error)
+	}); ok && j.Status == job.JobStatusQueued {
+		position, err := positioned.Position(ctx, j.ID)
+		if err != nil && !job.IsJobNotFoundError(err) {
+			return nil, err
+		}
+		explanation.Position = position
+	}
+
+	explanation.Dispatchable = len(explanation.Blockers) == 0
+	return explanation, nil
+}
+
+// formatSelector renders a node selector as sorted key=value pairs
+func formatSelector(selector map[string]string) string {
+	pairs := make([]string, 0, len(selector))
+	for key, value := range selector {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// notQueuedDetail describes why a job outside the run queue isn't waiting
+// for dispatch
+func notQueuedDetail(j *job.Job) string {
+	switch j.Status {
+	case job.JobStatusPending:
+		return "job has not been admitted to the run queue yet"
+	case job.JobStatusRunning:
+		return "job is already running on worker " + j.WorkerID
+	case job.JobStatusRetrying:
+		if j.RetryAt != nil {
+			return "job is backing off and will be requeued for a retry at " + j.RetryAt.Format(time.RFC3339)
+		}
+		return "job is waiting to be requeued for a retry"
+	default:
+		return "job has finished with status " + string(j.Status)
+	}
+}
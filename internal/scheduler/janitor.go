@@ -0,0 +1,154 @@
+package scheduler
+
+import (
+	"context"
+	"infinitrain/internal/clock"
+	"infinitrain/internal/config"
+	"infinitrain/pkg/job"
+	"log/slog"
+	"time"
+)
+
+// Janitor purges terminal jobs from the store once they have been finished
+// for longer than the retention window configured for their status, so
+// noisy cancelled jobs can go quickly while completed and failed jobs are
+// kept for audit. Jobs an unfinished job still depends on are kept until it
+// finishes, so its dependencies can still be checked.
+type Janitor struct {
+	store     job.Store
+	retention map[job.JobStatus]time.Duration
+	clock     clock.Clock
+	logger    *slog.Logger
+}
+
+// NewJanitor creates a janitor with a retention window per terminal status.
+// Jobs whose status has no window are never purged.
+func NewJanitor(store job.Store, retention map[job.JobStatus]time.Duration, c clock.Clock) *Janitor {
+	return &Janitor{
+		store:     store,
+		retention: retention,
+		clock:     c,
+		logger:    slog.Default(),
+	}
+}
+
+// SetLogger sets the logger the janitor reports its sweeps to
+func (jn *Janitor) SetLogger(logger *slog.Logger) {
+	jn.logger = logger
+}
+
+// NewJanitorFromConfig creates a janitor using the scheduler's configured
+// retention windows and the real clock
+func NewJanitorFromConfig(store job.Store, cfg *config.SchedulerConfig) *Janitor {
+	retention := make(map[job.JobStatus]time.Duration, len(cfg.JobRetention))
+	for status, window := range cfg.JobRetention {
+		retention[job.JobStatus(status)] = window
+	}
+	return NewJanitor(store, retention, clock.Real())
+}
+
+// Run sweeps the store every interval until the context is cancelled
+func (jn *Janitor) Run(ctx context.Context, interval time.Duration) {
+	ticker := jn.clock.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			purged, err := jn.Sweep(ctx)
+			if err != nil {
+				jn.logger.Error("janitor sweep failed", "error", err)
+			} else if len(purged) > 0 {
+				jn.logger.Info("janitor purged jobs", "count", len(purged))
+			}
+		}
+	}
+}
+
+// Sweep deletes every terminal job that has outlived its status's retention
+// window and returns the IDs of the deleted jobs. Stores that can delete old
+// jobs themselves are asked to, one status at a time, and keep the jobs that
+// unfinished jobs depend on; others are listed and the expired jobs that no
+// unfinished job depends on deleted one by one.
+func (jn *Janitor) Sweep(ctx context.Context) ([]string, error) {
+	if pruner, ok := jn.store.(interface {
+		DeleteOlderThan(ctx context.Context, cutoff time.Time, statuses ...job.JobStatus) ([]string, error)
+	}); ok {
+		now := jn.clock.Now().UTC()
+		var purged []string
+		for status, window := range jn.retention {
+			deleted, err := pruner.DeleteOlderThan(ctx, now.Add(-window), status)
+			purged = append(purged, deleted...)
+			if err != nil {
+				return purged, err
+			}
+		}
+		return purged, nil
+	}
+
+	statuses := make([]interface{}, 0, len(jn.retention))
+	for status := range jn.retention {
+		statuses = append(statuses, string(status))
+	}
+	if len(statuses) == 0 {
+		return nil, nil
+	}
+
+	jobs, err := jn.store.List(ctx, job.Filter{Field: "status", Operator: "in", Value: statuses})
+	if err != nil {
+		return nil, err
+	}
+	needed, err := unfinishedDependencies(ctx, jn.store)
+	if err != nil {
+		return nil, err
+	}
+
+	now := jn.clock.Now().UTC()
+	var purged []string
+	for _, j := range jobs {
+		window, ok := jn.retention[j.Status]
+		if !ok || !j.IsTerminal() || now.Sub(finishedAt(j)) < window || needed[j.ID] {
+			continue
+		}
+
+		if err := jn.store.Delete(ctx, j.ID); err != nil && !job.IsJobNotFoundError(err) {
+			return purged, err
+		}
+		purged = append(purged, j.ID)
+	}
+
+	return purged, nil
+}
+
+// unfinishedDependencies returns the IDs of the jobs that unfinished jobs
+// depend on
+func unfinishedDependencies(ctx context.Context, store job.Store) (map[string]bool, error) {
+	unfinished, err := store.List(ctx, job.Filter{Field: "status", Operator: "in", Value: []interface{}{
+		string(job.JobStatusPending),
+		string(job.JobStatusQueued),
+		string(job.JobStatusRunning),
+		string(job.JobStatusRetrying),
+	}})
+	if err != nil {
+		return nil, err
+	}
+
+	needed := make(map[string]bool)
+	for _, j := range unfinished {
+		for _, dependency := range j.DependsOn {
+			needed[dependency] = true
+		}
+	}
+	return needed, nil
+}
+
+// finishedAt returns when a job reached its terminal state, falling back to
+// its creation time for jobs stored without a completion timestamp
+func finishedAt(j *job.Job) time.Time {
+	if j.CompletedAt != nil {
+		return *j.CompletedAt
+	}
+	return j.CreatedAt
+}
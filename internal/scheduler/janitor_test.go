@@ -0,0 +1,108 @@
+package scheduler
+
+import (
+	"context"
+	"infinitrain/internal/clock"
+	"infinitrain/pkg/job"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJanitor_PerStatusRetention(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+	store := NewMemoryStore()
+
+	finished := func(ago time.Duration) *time.Time {
+		at := fake.Now().Add(-ago)
+		return &at
+	}
+	jobs := []*job.Job{
+		{ID: "cancelled-old", Status: job.JobStatusCancelled, CompletedAt: finished(20 * time.Minute)},
+		{ID: "cancelled-recent", Status: job.JobStatusCancelled, CompletedAt: finished(5 * time.Minute)},
+		{ID: "completed-old", Status: job.JobStatusCompleted, CompletedAt: finished(20 * time.Minute)},
+		{ID: "failed-old", Status: job.JobStatusFailed, CompletedAt: finished(20 * time.Minute)},
+		{ID: "failed-needed", Status: job.JobStatusFailed, CompletedAt: finished(20 * time.Minute)},
+		{ID: "running", Status: job.JobStatusRunning, CreatedAt: fake.Now().Add(-time.Hour), DependsOn: []string{"failed-needed"}},
+	}
+	for _, j := range jobs {
+		if err := store.Create(ctx, j); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	janitor := NewJanitor(store, map[job.JobStatus]time.Duration{
+		job.JobStatusCancelled: 15 * time.Minute,
+		job.JobStatusCompleted: 7 * 24 * time.Hour,
+		job.JobStatusFailed:    7 * 24 * time.Hour,
+	}, fake)
+
+	sweep := func(want ...string) {
+		t.Helper()
+
+		purged, err := janitor.Sweep(ctx)
+		if err != nil {
+			t.Fatalf("Sweep() error = %v", err)
+		}
+		sort.Strings(purged)
+		if strings.Join(purged, ",") != strings.Join(want, ",") {
+			t.Errorf("Expected %v to be purged, got %v", want, purged)
+		}
+		for _, id := range purged {
+			if exists, _ := store.Exists(ctx, id); exists {
+				t.Errorf("Expected %s to be deleted from the store", id)
+			}
+		}
+	}
+
+	// Only the cancelled job past the short window goes
+	sweep("cancelled-old")
+
+	fake.Advance(10 * time.Minute)
+	sweep("cancelled-recent")
+
+	// Completed and failed jobs stay until their much longer window passes
+	fake.Advance(7 * 24 * time.Hour)
+	sweep("completed-old", "failed-old")
+
+	if exists, _ := store.Exists(ctx, "running"); !exists {
+		t.Error("Expected the running job to be kept")
+	}
+	if exists, _ := store.Exists(ctx, "failed-needed"); !exists {
+		t.Error("Expected the dependency of the running job to be kept")
+	}
+}
+
+func TestJanitor_StoreWithoutDeleteOlderThan(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+	memory := NewMemoryStore()
+	// Hides the memory store's DeleteOlderThan so jobs are listed and deleted
+	store := struct{ job.Store }{memory}
+
+	old, recent := fake.Now().Add(-time.Hour), fake.Now()
+	for _, j := range []*job.Job{
+		{ID: "completed-old", Status: job.JobStatusCompleted, CompletedAt: &old},
+		{ID: "completed-new", Status: job.JobStatusCompleted, CompletedAt: &recent},
+		{ID: "completed-needed", Status: job.JobStatusCompleted, CompletedAt: &old},
+		{ID: "queued-old", Status: job.JobStatusQueued, CreatedAt: old, DependsOn: []string{"completed-needed"}},
+	} {
+		if err := memory.Create(ctx, j); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	janitor := NewJanitor(store, map[job.JobStatus]time.Duration{job.JobStatusCompleted: 30 * time.Minute}, fake)
+	purged, err := janitor.Sweep(ctx)
+	if err != nil {
+		t.Fatalf("Sweep() error = %v", err)
+	}
+	if len(purged) != 1 || purged[0] != "completed-old" {
+		t.Errorf("Expected only completed-old to be purged, got %v", purged)
+	}
+	if memory.Count(ctx) != 3 {
+		t.Errorf("Expected 3 jobs left, got %d", memory.Count(ctx))
+	}
+}
@@ -0,0 +1,951 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"infinitrain/internal/config"
+	"infinitrain/internal/tracing"
+	"infinitrain/pkg/job"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RecoveryPolicy decides what happens at startup to jobs that were running
+// on a worker that is no longer registered
+type RecoveryPolicy string
+
+const (
+	RecoveryRequeue RecoveryPolicy = "requeue" // Put orphaned jobs back in the queue
+	RecoveryFail    RecoveryPolicy = "fail"    // Mark orphaned jobs as failed
+)
+
+// DeliveryMode decides how workers learn that a job has been queued
+type DeliveryMode string
+
+const (
+	DeliveryPoll DeliveryMode = "poll" // Workers find new jobs on their next poll
+	DeliveryPush DeliveryMode = "push" // An idle worker is woken as soon as a job is queued
+)
+
+// DependencyPolicy decides what happens to the jobs depending on a job that
+// is cancelled
+type DependencyPolicy string
+
+const (
+	DependentsCancel DependencyPolicy = "cancel" // Cancel dependents, and theirs in turn
+	DependentsFail   DependencyPolicy = "fail"   // Mark dependents failed, and theirs in turn
+	DependentsIgnore DependencyPolicy = "ignore" // Leave dependents to run
+)
+
+// RecoveryReport lists the jobs touched by Recover
+type RecoveryReport struct {
+	Enqueued []string `json:"enqueued"` // Queued jobs re-added to the queue
+	Requeued []string `json:"requeued"` // Orphaned running jobs put back in the queue
+	Failed   []string `json:"failed"`   // Orphaned running jobs marked failed
+	Resumed  []string `json:"resumed"`  // Retrying jobs moved back to the queue
+}
+
+// Manager is the default implementation of the job.JobManager interface,
+// persisting jobs in a store and admitting them to the run queue
+type Manager struct {
+	store     job.Store
+	queue     job.Queue
+	workers   job.WorkerRegistry
+	scheduler job.Scheduler // Finishes runs as their results are reported
+	ids       job.IDGenerator
+	delivery  DeliveryMode
+	retries   map[job.JobType]int // Retries for requests that don't set them
+	cascade   DependencyPolicy    // What happens to dependents of a cancelled job
+	timeout   time.Duration       // Timeout for requests that leave it unset or zero; 0 keeps the request's own default
+	maxTime   time.Duration       // Longest timeout a job may have; 0 for no limit
+	logger    *slog.Logger
+
+	submitted   map[job.JobType]int // Jobs stored by Submit, cron runs included
+	submittedMu sync.Mutex
+}
+
+// NewManager creates a new job manager that uses the default ID generator
+func NewManager(store job.Store, queue job.Queue, workers job.WorkerRegistry) *Manager {
+	return NewManagerWithIDGenerator(store, queue, workers, job.DefaultIDGenerator)
+}
+
+// NewManagerWithIDGenerator creates a new job manager that assigns job IDs
+// from the given generator
+func NewManagerWithIDGenerator(store job.Store, queue job.Queue, workers job.WorkerRegistry, ids job.IDGenerator) *Manager {
+	return &Manager{
+		store:     store,
+		queue:     queue,
+		workers:   workers,
+		scheduler: NewDefaultScheduler(store, queue, workers),
+		ids:       ids,
+		delivery:  DeliveryPoll,
+		cascade:   DependentsCancel,
+		logger:    slog.Default(),
+		submitted: make(map[job.JobType]int),
+	}
+}
+
+// SetLogger sets the logger the manager reports what it did to
+func (m *Manager) SetLogger(logger *slog.Logger) {
+	m.logger = logger
+}
+
+// SetScheduler sets the scheduler that marks jobs completed or failed when
+// their workers report results. It should share the manager's store and
+// queue.
+func (m *Manager) SetScheduler(s job.Scheduler) {
+	m.scheduler = s
+}
+
+// SetDeliveryMode switches between waiting for workers to poll and pushing
+// a wake-up to an idle worker whenever a job is queued
+func (m *Manager) SetDeliveryMode(mode DeliveryMode) {
+	m.delivery = mode
+}
+
+// SetDefaultRetries sets the retry count given to jobs of each type whose
+// request leaves retries unset. Types without an entry default to the
+// scheduler's max attempts, or none.
+func (m *Manager) SetDefaultRetries(retries map[job.JobType]int) {
+	m.retries = retries
+}
+
+// SetJobTimeouts sets the timeout given to jobs submitted without one, or
+// with a zero one, and the longest timeout any job may have. Longer timeouts
+// are cut down to max rather than rejected. A zero max leaves them uncapped.
+func (m *Manager) SetJobTimeouts(defaultTimeout, max time.Duration) {
+	m.timeout = defaultTimeout
+	m.maxTime = max
+}
+
+// SetDependencyPolicy sets what happens to the jobs depending on a job when
+// it is cancelled
+func (m *Manager) SetDependencyPolicy(policy DependencyPolicy) {
+	m.cascade = policy
+	if s, ok := m.scheduler.(*DefaultScheduler); ok {
+		s.SetDependencyPolicy(policy)
+	}
+}
+
+// Start prepares the manager after a scheduler restart, replaying jobs left
+// in the store by the previous run when recovery is enabled. Queued jobs are
+// recovered straight away. Running jobs are only judged orphaned once the
+// worker timeout has passed, giving workers that outlived the restart time
+// to register again; that check runs in the background until ctx is done,
+// as does the loop returning retrying jobs to the queue once their backoff
+// has elapsed.
+func (m *Manager) Start(ctx context.Context, cfg *config.SchedulerConfig) error {
+	if cfg.JobDelivery != "" {
+		m.SetDeliveryMode(DeliveryMode(cfg.JobDelivery))
+	}
+
+	retries := make(map[job.JobType]int, len(cfg.DefaultRetries))
+	for jobType, count := range cfg.DefaultRetries {
+		retries[job.JobType(jobType)] = count
+	}
+	m.SetDefaultRetries(retries)
+	m.SetJobTimeouts(cfg.JobTimeout, cfg.MaxJobTimeout)
+
+	if s, ok := m.scheduler.(*DefaultScheduler); ok {
+		s.SetRetryPolicy(retryPolicyFromConfig(cfg))
+		go m.resumeRetriesEvery(ctx, s, retryResumeInterval(cfg.RetryBackoff))
+	}
+
+	if cfg.CancelledDependency != "" {
+		m.SetDependencyPolicy(DependencyPolicy(cfg.CancelledDependency))
+	}
+
+	if m.delivery == DeliveryPush {
+		if err := m.listenQueued(ctx); err != nil {
+			return err
+		}
+	}
+
+	if !cfg.RecoverOnStartup {
+		return nil
+	}
+
+	report := &RecoveryReport{}
+	if err := m.recoverQueued(ctx, report); err != nil {
+		return fmt.Errorf("failed to recover jobs: %w", err)
+	}
+	m.logger.Info("recovered queued jobs", "enqueued", len(report.Enqueued), "resumed", len(report.Resumed))
+
+	go m.recoverOrphansAfter(ctx, RecoveryPolicy(cfg.RecoveryPolicy), cfg.WorkerTimeout)
+	return nil
+}
+
+// retryResumeInterval is how often retrying jobs are checked for an elapsed
+// backoff: every backoff period, but at least once a second so shorter
+// per-job delays aren't overshot by much
+func retryResumeInterval(backoff time.Duration) time.Duration {
+	if backoff <= 0 || backoff > time.Second {
+		return time.Second
+	}
+	return backoff
+}
+
+// resumeRetriesEvery returns retrying jobs whose backoff has elapsed to the
+// queue every interval until ctx is done, so they run again even while
+// nothing is dispatching. In push mode a worker is woken for each.
+func (m *Manager) resumeRetriesEvery(ctx context.Context, s *DefaultScheduler, interval time.Duration) {
+	ticker := s.clock.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		}
+
+		resumed, err := s.ResumeRetries(ctx)
+		if err != nil {
+			m.logger.Error("failed to resume retrying jobs", "error", err)
+		}
+		if len(resumed) == 0 {
+			continue
+		}
+		m.logger.Info("resumed retrying jobs", "jobs", resumed)
+		if m.delivery == DeliveryPush {
+			for _, id := range resumed {
+				if j, err := m.store.Get(ctx, id); err == nil {
+					m.notifyIdleWorker(ctx, j)
+				}
+			}
+		}
+	}
+}
+
+// recoverOrphansAfter waits out the grace period, then recovers the running
+// jobs whose workers haven't come back
+func (m *Manager) recoverOrphansAfter(ctx context.Context, policy RecoveryPolicy, grace time.Duration) {
+	timer := time.NewTimer(grace)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return
+	case <-timer.C:
+	}
+
+	report := &RecoveryReport{}
+	if err := m.recoverOrphans(ctx, policy, report); err != nil {
+		m.logger.Error("failed to recover orphaned jobs", "error", err)
+		return
+	}
+	m.logger.Info("recovered orphaned jobs", "requeued", len(report.Requeued), "failed", len(report.Failed))
+}
+
+// Recover rebuilds the run queue from the store: queued jobs are re-added,
+// retrying jobs whose backoff has elapsed are resumed, and running jobs whose
+// worker is no longer registered are requeued or failed according to the
+// policy. Orphaned durable jobs are always requeued. Retrying jobs still
+// backing off are left for the scheduler to resume at their retry time.
+func (m *Manager) Recover(ctx context.Context, policy RecoveryPolicy) (*RecoveryReport, error) {
+	report := &RecoveryReport{}
+	if err := m.recoverQueued(ctx, report); err != nil {
+		return report, err
+	}
+	if err := m.recoverOrphans(ctx, policy, report); err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+// recoverQueued re-adds queued jobs to the run queue and resumes retrying
+// jobs whose backoff has elapsed
+func (m *Manager) recoverQueued(ctx context.Context, report *RecoveryReport) error {
+	jobs, err := m.store.List(ctx, job.Filter{
+		Field:    "status",
+		Operator: "in",
+		Value: []interface{}{
+			string(job.JobStatusQueued),
+			string(job.JobStatusRetrying),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, j := range jobs {
+		switch j.Status {
+		case job.JobStatusQueued:
+			if err := m.enqueueRecovered(ctx, j); err != nil {
+				return err
+			}
+			report.Enqueued = append(report.Enqueued, j.ID)
+
+		case job.JobStatusRetrying:
+			if j.RetryAt != nil && now.Before(*j.RetryAt) {
+				continue
+			}
+			if err := j.UpdateStatus(job.JobStatusQueued); err != nil {
+				return err
+			}
+			if err := m.store.Update(ctx, j); err != nil {
+				return err
+			}
+			if err := m.enqueueRecovered(ctx, j); err != nil {
+				return err
+			}
+			report.Resumed = append(report.Resumed, j.ID)
+		}
+	}
+	return nil
+}
+
+// recoverOrphans requeues or fails the running jobs whose worker is no
+// longer registered, according to the policy
+func (m *Manager) recoverOrphans(ctx context.Context, policy RecoveryPolicy, report *RecoveryReport) error {
+	jobs, err := m.store.List(ctx, job.Filter{Field: "status", Operator: "eq", Value: string(job.JobStatusRunning)})
+	if err != nil {
+		return err
+	}
+
+	for _, j := range jobs {
+		if _, err := m.workers.GetWorker(ctx, j.WorkerID); err == nil {
+			continue // Its worker is still around and will report back
+		} else if !job.IsWorkerNotFoundError(err) {
+			return err
+		}
+
+		// Durable jobs are guaranteed to run at least once, so they are
+		// requeued whatever the policy
+		if policy == RecoveryFail && !j.Durable {
+			j.Error = fmt.Sprintf("worker %s was lost while the scheduler restarted", j.WorkerID)
+			if err := j.UpdateStatus(job.JobStatusFailed); err != nil {
+				return err
+			}
+			if err := m.store.Update(ctx, j); err != nil {
+				return err
+			}
+			report.Failed = append(report.Failed, j.ID)
+			continue
+		}
+
+		if err := j.Requeue(); err != nil {
+			return err
+		}
+		if err := m.store.Update(ctx, j); err != nil {
+			return err
+		}
+		if err := m.enqueueRecovered(ctx, j); err != nil {
+			return err
+		}
+		report.Requeued = append(report.Requeued, j.ID)
+	}
+
+	// Jobs waiting on a failed one can never run now. They may have been
+	// requeued already, so this waits until every orphan has been handled.
+	for _, id := range report.Failed {
+		if err := abandonDependents(ctx, m.store, m.queue, id, job.JobStatusFailed, "failed"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// enqueueRecovered adds a recovered job to the queue unless it is already there
+func (m *Manager) enqueueRecovered(ctx context.Context, j *job.Job) error {
+	if _, err := m.queue.PeekMatching(ctx, func(queued *job.Job) bool { return queued.ID == j.ID }); err == nil {
+		return nil
+	}
+	return m.queue.Enqueue(ctx, j)
+}
+
+// Submit submits a new job
+func (m *Manager) Submit(ctx context.Context, request *job.JobRequest) (*job.Job, error) {
+	j, err := request.ToJobWithGenerator(m.ids)
+	if err != nil {
+		return nil, err
+	}
+
+	// The submission starts the trace that follows the job to its worker
+	ctx, span := tracing.Start(ctx, j, "job.submit")
+	j.TraceParent = tracing.Inject(ctx)
+
+	submitted, err := m.submit(ctx, request, j)
+	tracing.End(span, err)
+	return submitted, err
+}
+
+// DryRun validates a job request and returns the job Submit would create
+// from it, without an ID, storing or queueing nothing
+func (m *Manager) DryRun(ctx context.Context, request *job.JobRequest) (*job.Job, error) {
+	j, err := request.ToJobWithGenerator(job.IDGeneratorFunc(func() string { return "" }))
+	if err != nil {
+		return nil, err
+	}
+	if err := m.resolve(ctx, request, j); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// resolve fills in a new job's defaults and checks its dependencies
+func (m *Manager) resolve(ctx context.Context, request *job.JobRequest, j *job.Job) error {
+	// An explicit retry count, even zero, wins over the per-type default,
+	// which wins over the scheduler's max attempts
+	if request.Retries == nil && (request.RetryPolicy == nil || request.RetryPolicy.MaxAttempts == nil) {
+		j.Retries = m.defaultRetries(j.Type)
+	}
+
+	// A zero timeout would let the job run forever
+	if m.timeout > 0 && (request.Timeout == "" || j.Timeout <= 0) {
+		j.Timeout = m.timeout
+	}
+	if m.maxTime > 0 && j.Timeout > m.maxTime {
+		// Dry runs have no ID and nothing to report
+		if j.ID != "" {
+			m.logger.Info("capped job timeout", "job_id", j.ID, "requested", j.Timeout, "timeout", m.maxTime)
+		}
+		j.Timeout = m.maxTime
+	}
+
+	for _, dependency := range j.DependsOn {
+		exists, err := m.store.Exists(ctx, dependency)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return job.NewValidationError("unknown dependency: " + dependency)
+		}
+	}
+	return checkDependencyCycle(ctx, m.store, j)
+}
+
+// defaultRetries returns the retries of a job of the given type whose
+// request leaves them unset
+func (m *Manager) defaultRetries(jobType job.JobType) int {
+	if retries, ok := m.retries[jobType]; ok {
+		return retries
+	}
+	if s, ok := m.scheduler.(*DefaultScheduler); ok {
+		retries, _ := s.policy.Retries()
+		return retries
+	}
+	return 0
+}
+
+// submit checks and stores a new job, then queues it unless it is recurring
+func (m *Manager) submit(ctx context.Context, request *job.JobRequest, j *job.Job) (*job.Job, error) {
+	if err := m.resolve(ctx, request, j); err != nil {
+		return nil, err
+	}
+
+	// Immediate jobs fail fast rather than waiting for a worker to free up
+	if j.Scheduling == job.SchedulingModeImmediate && !j.IsRecurring() {
+		if _, err := m.workers.GetLeastLoadedWorker(ctx, j.NodeSelector, j.Weight()); err != nil {
+			if err == job.ErrNoWorkerAvailable {
+				return nil, job.NewConflictError("no worker can accept the job right now")
+			}
+			return nil, err
+		}
+	}
+
+	// Custom ID generators may hand out an ID that is already taken
+	exists, err := m.store.Exists(ctx, j.ID)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, job.NewConflictError("job already exists: " + j.ID)
+	}
+
+	if err := m.store.Create(ctx, j); err != nil {
+		return nil, fmt.Errorf("failed to store job: %w", err)
+	}
+	m.submittedMu.Lock()
+	m.submitted[j.Type]++
+	m.submittedMu.Unlock()
+
+	// A recurring job stays pending as the template for its runs, which the
+	// cron scheduler submits as it fires
+	if j.IsRecurring() {
+		return j, nil
+	}
+
+	// Admit the job to the run queue so the scheduler can dispatch it
+	return m.Enqueue(ctx, j.ID)
+}
+
+// SubmittedCounts returns how many jobs of each type have been submitted
+// since the manager was created, whether through the API or by cron
+func (m *Manager) SubmittedCounts() map[job.JobType]int {
+	m.submittedMu.Lock()
+	defer m.submittedMu.Unlock()
+
+	counts := make(map[job.JobType]int, len(m.submitted))
+	for jobType, n := range m.submitted {
+		counts[jobType] = n
+	}
+	return counts
+}
+
+// Enqueue admits a pending job to the run queue. Pending jobs have been
+// accepted and stored but are not yet eligible for dispatch; queued jobs are
+// in the run queue waiting for a worker.
+func (m *Manager) Enqueue(ctx context.Context, jobID string) (*job.Job, error) {
+	j, err := m.store.Get(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	if j.Status != job.JobStatusPending {
+		return nil, job.NewConflictError(fmt.Sprintf("cannot enqueue job %s in status %s", jobID, j.Status))
+	}
+	if j.IsRecurring() {
+		return nil, job.NewConflictError(fmt.Sprintf("job %s is recurring; its runs are enqueued by its schedule", jobID))
+	}
+
+	if err := j.UpdateStatus(job.JobStatusQueued); err != nil {
+		return nil, err
+	}
+	if err := m.store.Update(ctx, j); err != nil {
+		return nil, fmt.Errorf("failed to queue job: %w", err)
+	}
+
+	if err := m.queue.Enqueue(ctx, j); err != nil {
+		return nil, fmt.Errorf("failed to enqueue job: %w", err)
+	}
+
+	if m.delivery == DeliveryPush {
+		m.notifyIdleWorker(ctx, j)
+	}
+
+	return j, nil
+}
+
+// Requeue runs a failed job again under the same ID. Unlike submitting a
+// copy, the job keeps its ID and records the failed run in its history.
+func (m *Manager) Requeue(ctx context.Context, jobID string) (*job.Job, error) {
+	j, err := m.store.Get(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	if j.Status != job.JobStatusFailed {
+		return nil, job.NewConflictError(fmt.Sprintf("cannot requeue job %s in status %s", jobID, j.Status))
+	}
+
+	if err := j.Reset(); err != nil {
+		return nil, err
+	}
+	if err := m.store.Update(ctx, j); err != nil {
+		return nil, fmt.Errorf("failed to reset job: %w", err)
+	}
+
+	if err := m.queue.Enqueue(ctx, j); err != nil {
+		return nil, fmt.Errorf("failed to enqueue job: %w", err)
+	}
+
+	if m.delivery == DeliveryPush {
+		m.notifyIdleWorker(ctx, j)
+	}
+
+	return j, nil
+}
+
+// listenQueued wakes an idle worker for each job queued by any scheduler
+// sharing the store, for stores that announce them, until ctx is done
+func (m *Manager) listenQueued(ctx context.Context) error {
+	listener, ok := m.store.(interface {
+		ListenQueued(ctx context.Context) (<-chan string, error)
+	})
+	if !ok {
+		return nil
+	}
+
+	queued, err := listener.ListenQueued(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to listen for queued jobs: %w", err)
+	}
+	go func() {
+		for id := range queued {
+			if j, err := m.store.Get(ctx, id); err == nil && j.Status == job.JobStatusQueued {
+				m.notifyIdleWorker(ctx, j)
+			}
+		}
+	}()
+	return nil
+}
+
+// notifyIdleWorker wakes the least loaded worker that can take the job so it
+// claims it immediately. Workers that cannot be notified, or a lack of free
+// capacity, leave the job to be picked up by the next poll.
+func (m *Manager) notifyIdleWorker(ctx context.Context, j *job.Job) {
+	worker, err := m.workers.GetLeastLoadedWorker(ctx, j.NodeSelector, j.Weight())
+	if err != nil || !canTake(worker, j) {
+		return
+	}
+
+	if notifier, ok := worker.(interface{ Notify() }); ok {
+		notifier.Notify()
+	}
+}
+
+// GetJob retrieves a job by ID
+func (m *Manager) GetJob(ctx context.Context, jobID string) (*job.Job, error) {
+	return m.store.Get(ctx, jobID)
+}
+
+// ListJobs lists jobs with optional filtering. Filter values are normalized
+// to the types of the fields they target first, so filters decoded from
+// JSON compare correctly in any store.
+func (m *Manager) ListJobs(ctx context.Context, filters ...job.Filter) ([]*job.Job, error) {
+	normalized, err := job.NormalizeFilters(filters)
+	if err != nil {
+		return nil, err
+	}
+	return m.store.List(ctx, normalized...)
+}
+
+// ListJobsPage lists one page of jobs
+func (m *Manager) ListJobsPage(ctx context.Context, opts job.ListOptions) (*job.JobPage, error) {
+	normalized, err := job.NormalizeFilters(opts.Filters)
+	if err != nil {
+		return nil, err
+	}
+	opts.Filters = normalized
+	return m.store.ListPage(ctx, opts)
+}
+
+// CancelJob cancels a running or pending job
+func (m *Manager) CancelJob(ctx context.Context, jobID string) error {
+	j, err := m.store.Get(ctx, jobID)
+	if err != nil {
+		return err
+	}
+	if j.IsTerminal() {
+		return job.NewConflictError(fmt.Sprintf("cannot cancel job %s in status %s", jobID, j.Status))
+	}
+
+	if err := m.store.UpdateStatus(ctx, jobID, job.JobStatusCancelled); err != nil {
+		return err
+	}
+
+	if j.IsRunning() {
+		m.stopRunningJob(ctx, j)
+	}
+
+	// The job may already have left the queue
+	if err := m.queue.Remove(ctx, jobID); err != nil && !job.IsJobNotFoundError(err) {
+		return err
+	}
+
+	switch m.cascade {
+	case DependentsCancel:
+		return abandonDependents(ctx, m.store, m.queue, jobID, job.JobStatusCancelled, "was cancelled")
+	case DependentsFail:
+		return abandonDependents(ctx, m.store, m.queue, jobID, job.JobStatusFailed, "was cancelled")
+	default:
+		return nil
+	}
+}
+
+// stopRunningJob asks the worker running a cancelled job to cancel its
+// context, killing the job's processes. The job is already cancelled in the
+// store, so a worker that can't be reached only delays the stop: the result
+// it reports later can't move the job out of its terminal status.
+func (m *Manager) stopRunningJob(ctx context.Context, j *job.Job) {
+	worker, err := m.workers.GetWorker(ctx, j.WorkerID)
+	if err != nil {
+		return
+	}
+	canceller, ok := worker.(interface{ CancelJob(jobID string) error })
+	if !ok {
+		return
+	}
+	// A job that isn't found has finished on its own in the meantime
+	if err := canceller.CancelJob(j.ID); err != nil && !job.IsJobNotFoundError(err) {
+		m.logger.Warn("failed to stop cancelled job on its worker", "job_id", j.ID, "worker_id", j.WorkerID, "error", err)
+	}
+}
+
+// abandonDependents moves every unfinished job that depends, directly or
+// through other jobs, on the given one to status, recording which
+// dependency gave up and why. Each job is visited at most once, so the walk
+// ends even if the dependency graph has a cycle.
+func abandonDependents(ctx context.Context, store job.Store, queue job.Queue, rootID string, status job.JobStatus, rootReason string) error {
+	jobs, err := store.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	dependents := make(map[string][]*job.Job)
+	for _, j := range jobs {
+		if j.IsTerminal() {
+			continue
+		}
+		for _, dependency := range j.DependsOn {
+			dependents[dependency] = append(dependents[dependency], j)
+		}
+	}
+
+	visited := map[string]bool{rootID: true}
+	reasons := map[string]string{rootID: rootReason}
+	pending := []string{rootID}
+	for len(pending) > 0 {
+		id := pending[0]
+		pending = pending[1:]
+
+		for _, j := range dependents[id] {
+			if visited[j.ID] {
+				continue
+			}
+			visited[j.ID] = true
+
+			reason := fmt.Sprintf("dependency %s %s", id, reasons[id])
+			if err := j.Abandon(status, reason); err != nil {
+				return err
+			}
+			if err := store.Update(ctx, j); err != nil {
+				return err
+			}
+			if err := queue.Remove(ctx, j.ID); err != nil && !job.IsJobNotFoundError(err) {
+				return err
+			}
+
+			reasons[j.ID] = "was " + string(status)
+			pending = append(pending, j.ID)
+		}
+	}
+
+	return nil
+}
+
+// checkDependencyCycle rejects a job whose dependencies lead back to it,
+// following the stored dependency graph from each of its dependencies
+func checkDependencyCycle(ctx context.Context, store job.Store, j *job.Job) error {
+	visited := make(map[string]bool)
+	var walk func(id string, path []string) error
+	walk = func(id string, path []string) error {
+		path = append(path, id)
+		if id == j.ID {
+			return job.NewValidationError("circular dependency: " + strings.Join(path, " -> "))
+		}
+		if visited[id] {
+			return nil
+		}
+		visited[id] = true
+
+		dependency, err := store.Get(ctx, id)
+		if job.IsJobNotFoundError(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		for _, next := range dependency.DependsOn {
+			if err := walk(next, path); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, dependency := range j.DependsOn {
+		if err := walk(dependency, []string{j.ID}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetJobResult gets the result of a finished job. The result the worker
+// reported is returned when one was stored for the job's final status;
+// otherwise it is rebuilt from the job's own fields.
+func (m *Manager) GetJobResult(ctx context.Context, jobID string) (*job.JobResult, error) {
+	j, err := m.store.Get(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !j.IsTerminal() {
+		return nil, job.NewConflictError(fmt.Sprintf("job %s has not finished (status %s)", jobID, j.Status))
+	}
+
+	stored, err := m.store.GetResult(ctx, jobID)
+	switch {
+	case err == nil && stored.Status == j.Status:
+		return stored, nil
+	case err != nil && !job.IsJobNotFoundError(err):
+		return nil, err
+	}
+
+	result := &job.JobResult{
+		JobID:       j.ID,
+		Status:      j.Status,
+		Output:      j.Output,
+		OutputBytes: j.OutputBytes,
+		OutputLines: j.OutputLines,
+		Truncated:   j.Truncated,
+		LogDropped:  j.LogDropped,
+		Error:       j.Error,
+		ExitCode:    j.ExitCode,
+		Termination: j.Termination,
+		LogFile:     j.LogFile,
+		Duration:    j.GetDuration(),
+	}
+	if j.StartedAt != nil {
+		result.StartedAt = *j.StartedAt
+	}
+	if j.CompletedAt != nil {
+		result.CompletedAt = *j.CompletedAt
+	}
+
+	return result, nil
+}
+
+// SaveJobResult stores the result a worker reported for a job's run and
+// finishes the run: a completed result completes the job, and a failed one
+// retries it while it has retries left or fails it. A result for a job that
+// has already finished, e.g. one cancelled while it ran, or a cancelled
+// result is only stored; the job's status is left as it is.
+func (m *Manager) SaveJobResult(ctx context.Context, result *job.JobResult) error {
+	if result.JobID == "" {
+		return job.NewValidationError("job_id is required")
+	}
+	if !result.Status.IsValid() {
+		return job.NewValidationError("invalid result status: " + string(result.Status))
+	}
+
+	j, err := m.store.Get(ctx, result.JobID)
+	if err != nil {
+		return err
+	}
+	if j.IsTerminal() || result.Status == job.JobStatusCancelled {
+		return m.store.SaveResult(ctx, result)
+	}
+	if !j.IsRunning() {
+		return job.NewConflictError(fmt.Sprintf("cannot report a result for job %s in status %s", j.ID, j.Status))
+	}
+
+	switch result.Status {
+	case job.JobStatusCompleted:
+		return m.scheduler.MarkCompleted(ctx, j.ID, result)
+	case job.JobStatusFailed:
+		if err := m.store.SaveResult(ctx, result); err != nil {
+			return err
+		}
+		var cause error
+		if result.Error != "" {
+			cause = errors.New(result.Error)
+		}
+		return m.scheduler.MarkFailed(ctx, j.ID, cause)
+	default:
+		return job.NewValidationError("a result must be completed, failed or cancelled, not " + string(result.Status))
+	}
+}
+
+// UpdatePriority changes the priority of a job that has not started yet and
+// reorders the queue so the change takes effect on the next dequeue
+func (m *Manager) UpdatePriority(ctx context.Context, jobID string, priority int) (*job.Job, error) {
+	if err := job.ValidatePriority(priority); err != nil {
+		return nil, err
+	}
+
+	// Change both under the dispatch lock, so the job isn't handed out with
+	// its new priority in only one of them
+	if s, ok := m.scheduler.(*DefaultScheduler); ok {
+		return s.Reprioritize(ctx, jobID, priority)
+	}
+	return reprioritize(ctx, m.store, m.queue, jobID, priority)
+}
+
+// NextJobFor hands a worker polling the scheduler the next queued job it can
+// take, marking it running on that worker. The manager's scheduler must be
+// able to dispatch to a given worker, as DefaultScheduler can.
+func (m *Manager) NextJobFor(ctx context.Context, workerID string) (*job.Job, error) {
+	dispatcher, ok := m.scheduler.(interface {
+		NextJobFor(ctx context.Context, workerID string) (*job.Job, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("scheduler cannot dispatch to a given worker")
+	}
+	return dispatcher.NextJobFor(ctx, workerID)
+}
+
+// reprioritize sets a pending or queued job's priority in the store and then
+// in the queue
+func reprioritize(ctx context.Context, store job.Store, queue job.Queue, jobID string, priority int) (*job.Job, error) {
+	j, err := store.Get(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !j.IsPending() {
+		return nil, job.NewConflictError(fmt.Sprintf("cannot change priority of job %s in status %s", jobID, j.Status))
+	}
+
+	j.Priority = priority
+	if err := store.Update(ctx, j); err != nil {
+		return nil, err
+	}
+
+	if err := queue.Reprioritize(ctx, jobID, priority); err != nil && !job.IsJobNotFoundError(err) {
+		return nil, err
+	}
+
+	return j, nil
+}
+
+// RequeueStaleWorkerJobs puts the running jobs of a worker that stopped
+// sending heartbeats back in the queue. It is meant as the stale worker
+// callback of MemoryWorkerRegistry.RunHealthChecks and RunReaper.
+func (m *Manager) RequeueStaleWorkerJobs(ctx context.Context, workerID string) {
+	requeued, err := m.ReleaseWorkerJobs(ctx, workerID, true)
+	if err != nil {
+		m.logger.Error("failed to requeue jobs of stale worker", "worker_id", workerID, "error", err)
+		return
+	}
+	if len(requeued) > 0 {
+		m.logger.Info("requeued jobs of stale worker", "worker_id", workerID, "jobs", requeued)
+	}
+}
+
+// ReleaseWorkerJobs requeues (or fails) every job running on a worker that
+// has gone away and returns the IDs of the affected jobs
+func (m *Manager) ReleaseWorkerJobs(ctx context.Context, workerID string, requeue bool) ([]string, error) {
+	orphaned, err := m.store.List(ctx,
+		job.Filter{Field: "worker_id", Operator: "eq", Value: workerID},
+		job.Filter{Field: "status", Operator: "eq", Value: string(job.JobStatusRunning)},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	affected := make([]string, 0, len(orphaned))
+	for _, j := range orphaned {
+		if requeue {
+			if err := j.Requeue(); err != nil {
+				return affected, err
+			}
+		} else {
+			j.Error = fmt.Sprintf("worker %s went away while the job was running", workerID)
+			if err := j.UpdateStatus(job.JobStatusFailed); err != nil {
+				return affected, err
+			}
+		}
+
+		if err := m.store.Update(ctx, j); err != nil {
+			return affected, err
+		}
+
+		if requeue {
+			if err := m.queue.Enqueue(ctx, j); err != nil {
+				return affected, err
+			}
+		} else if err := abandonDependents(ctx, m.store, m.queue, j.ID, job.JobStatusFailed, "failed"); err != nil {
+			return affected, err
+		}
+
+		affected = append(affected, j.ID)
+	}
+
+	return affected, nil
+}
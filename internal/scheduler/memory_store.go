@@ -2,21 +2,30 @@ package scheduler
 
 import (
 	"context"
+	"fmt"
 	"infinitrain/pkg/job"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 )
 
 // MemoryStore is a simple in-memory implementation of the job.Store interface
 type MemoryStore struct {
-	jobs   map[string]*job.Job
-	mutex  sync.RWMutex
+	jobs    map[string]*job.Job
+	results map[string]*job.JobResult
+	tags    map[string]map[job.JobStatus]int // Jobs per tag and status, kept up to date on every write
+	events  *StatusBroker
+	mutex   sync.RWMutex
 }
 
 // NewMemoryStore creates a new in-memory job store
 func NewMemoryStore() *MemoryStore {
 	return &MemoryStore{
-		jobs: make(map[string]*job.Job),
+		jobs:    make(map[string]*job.Job),
+		results: make(map[string]*job.JobResult),
+		tags:    make(map[string]map[job.JobStatus]int),
+		events:  NewStatusBroker(),
 	}
 }
 
@@ -32,7 +41,10 @@ func (s *MemoryStore) Create(ctx context.Context, j *job.Job) error {
 
 	// Create a copy to avoid mutations
 	jobCopy := *j
+	jobCopy.NormalizeTimestamps()
 	s.jobs[j.ID] = &jobCopy
+	s.countTags(&jobCopy, 1)
+	s.events.Publish(&jobCopy)
 
 	return nil
 }
@@ -52,18 +64,37 @@ func (s *MemoryStore) Get(ctx context.Context, jobID string) (*job.Job, error) {
 	return &jobCopy, nil
 }
 
+// Ping always succeeds; the store is in memory
+func (s *MemoryStore) Ping(ctx context.Context) error {
+	return nil
+}
+
+// Exists reports whether a job is stored, without copying it
+func (s *MemoryStore) Exists(ctx context.Context, jobID string) (bool, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	_, exists := s.jobs[jobID]
+	return exists, nil
+}
+
 // Update updates an existing job
 func (s *MemoryStore) Update(ctx context.Context, j *job.Job) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	if _, exists := s.jobs[j.ID]; !exists {
+	existing, exists := s.jobs[j.ID]
+	if !exists {
 		return job.NewJobNotFoundError(j.ID)
 	}
 
 	// Create a copy to avoid mutations
 	jobCopy := *j
+	jobCopy.NormalizeTimestamps()
+	s.countTags(existing, -1)
 	s.jobs[j.ID] = &jobCopy
+	s.countTags(&jobCopy, 1)
+	s.events.Publish(&jobCopy)
 
 	return nil
 }
@@ -73,14 +104,50 @@ func (s *MemoryStore) Delete(ctx context.Context, jobID string) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	if _, exists := s.jobs[jobID]; !exists {
+	existing, exists := s.jobs[jobID]
+	if !exists {
 		return job.NewJobNotFoundError(jobID)
 	}
 
+	s.countTags(existing, -1)
 	delete(s.jobs, jobID)
+	delete(s.results, jobID)
 	return nil
 }
 
+// DeleteOlderThan deletes the terminal jobs that finished by the cutoff,
+// limited to the given statuses when any are given, and returns their IDs.
+// Jobs without a completion time are aged by their creation time, and jobs
+// an unfinished job depends on are kept.
+func (s *MemoryStore) DeleteOlderThan(ctx context.Context, cutoff time.Time, statuses ...job.JobStatus) ([]string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	needed := make(map[string]bool)
+	for _, j := range s.jobs {
+		if !j.IsTerminal() {
+			for _, dependency := range j.DependsOn {
+				needed[dependency] = true
+			}
+		}
+	}
+
+	var deleted []string
+	for id, j := range s.jobs {
+		if !j.IsTerminal() || finishedAt(j).After(cutoff) || needed[id] {
+			continue
+		}
+		if len(statuses) > 0 && !slices.Contains(statuses, j.Status) {
+			continue
+		}
+		s.countTags(j, -1)
+		delete(s.jobs, id)
+		delete(s.results, id)
+		deleted = append(deleted, id)
+	}
+	return deleted, nil
+}
+
 // List returns jobs with optional filtering
 func (s *MemoryStore) List(ctx context.Context, filters ...job.Filter) ([]*job.Job, error) {
 	s.mutex.RLock()
@@ -89,7 +156,7 @@ func (s *MemoryStore) List(ctx context.Context, filters ...job.Filter) ([]*job.J
 	var result []*job.Job
 
 	for _, j := range s.jobs {
-		if s.matchesFilters(j, filters) {
+		if matchesFilters(j, filters) {
 			// Return a copy to avoid mutations
 			jobCopy := *j
 			result = append(result, &jobCopy)
@@ -99,6 +166,31 @@ func (s *MemoryStore) List(ctx context.Context, filters ...job.Filter) ([]*job.J
 	return result, nil
 }
 
+// ListPage returns one page of the jobs matching the options' filters,
+// copying only the jobs on the page
+func (s *MemoryStore) ListPage(ctx context.Context, opts job.ListOptions) (*job.JobPage, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var matching []*job.Job
+	for _, j := range s.jobs {
+		if matchesFilters(j, opts.Filters) {
+			matching = append(matching, j)
+		}
+	}
+
+	page, err := job.Paginate(matching, opts)
+	if err != nil {
+		return nil, err
+	}
+	for i, j := range page.Jobs {
+		// Return a copy to avoid mutations
+		jobCopy := *j
+		page.Jobs[i] = &jobCopy
+	}
+	return page, nil
+}
+
 // UpdateStatus updates the status of a job
 func (s *MemoryStore) UpdateStatus(ctx context.Context, jobID string, status job.JobStatus) error {
 	s.mutex.Lock()
@@ -110,17 +202,160 @@ func (s *MemoryStore) UpdateStatus(ctx context.Context, jobID string, status job
 	}
 
 	// Update the status and timestamps
-	if err := j.UpdateStatus(status); err != nil {
-		return err
+	s.countTags(j, -1)
+	err := j.UpdateStatus(status)
+	s.countTags(j, 1)
+	if err == nil {
+		s.events.Publish(j)
 	}
 
+	return err
+}
+
+// AssignWorker moves a queued job to running on the given worker in one
+// step, so a cancellation landing at the same time can't be overwritten. A
+// job that is no longer queued gives a conflict error.
+func (s *MemoryStore) AssignWorker(ctx context.Context, jobID, workerID string) (*job.Job, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	j, exists := s.jobs[jobID]
+	if !exists {
+		return nil, job.NewJobNotFoundError(jobID)
+	}
+	if j.Status != job.JobStatusQueued {
+		return nil, job.NewConflictError(fmt.Sprintf("job %s is no longer queued (status %s)", jobID, j.Status))
+	}
+
+	s.countTags(j, -1)
+	j.WorkerID = workerID
+	err := j.UpdateStatus(job.JobStatusRunning)
+	s.countTags(j, 1)
+	if err != nil {
+		return nil, err
+	}
+	s.events.Publish(j)
+
+	jobCopy := *j
+	return &jobCopy, nil
+}
+
+// ExtendTimeout adds extra to a running job's timeout under the store's
+// lock, so it can't overwrite a change made since the job was read. A job
+// that is no longer running gives a conflict error.
+func (s *MemoryStore) ExtendTimeout(ctx context.Context, jobID string, extra time.Duration) (*job.Job, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	j, exists := s.jobs[jobID]
+	if !exists {
+		return nil, job.NewJobNotFoundError(jobID)
+	}
+	if !j.IsRunning() {
+		return nil, job.NewConflictError(fmt.Sprintf("job %s is no longer running (status %s)", jobID, j.Status))
+	}
+
+	j.Timeout += extra
+	s.events.Publish(j)
+
+	jobCopy := *j
+	return &jobCopy, nil
+}
+
+// WatchStatus returns a channel receiving the job after each change to it.
+// Call cancel to stop watching.
+func (s *MemoryStore) WatchStatus(jobID string) (<-chan *job.Job, func()) {
+	return s.events.Subscribe(jobID)
+}
+
+// SaveResult stores the result of a job's latest run
+func (s *MemoryStore) SaveResult(ctx context.Context, result *job.JobResult) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.jobs[result.JobID]; !exists {
+		return job.NewJobNotFoundError(result.JobID)
+	}
+
+	// Create a copy to avoid mutations
+	resultCopy := *result
+	s.results[result.JobID] = &resultCopy
 	return nil
 }
 
+// GetResult retrieves the stored result of a job
+func (s *MemoryStore) GetResult(ctx context.Context, jobID string) (*job.JobResult, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	result, exists := s.results[jobID]
+	if !exists {
+		return nil, job.NewJobNotFoundError(jobID)
+	}
+
+	// Return a copy to avoid mutations
+	resultCopy := *result
+	return &resultCopy, nil
+}
+
+// TagCounts returns how many jobs carry each tag, by status, from counters
+// maintained as jobs are written rather than by scanning every job
+func (s *MemoryStore) TagCounts(ctx context.Context) (map[string]map[job.JobStatus]int, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	counts := make(map[string]map[job.JobStatus]int, len(s.tags))
+	for tag, byStatus := range s.tags {
+		copied := make(map[job.JobStatus]int, len(byStatus))
+		for status, n := range byStatus {
+			copied[status] = n
+		}
+		counts[tag] = copied
+	}
+	return counts, nil
+}
+
+// StatusCounts returns how many jobs are in each status
+func (s *MemoryStore) StatusCounts(ctx context.Context) (map[job.JobStatus]int, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	counts := make(map[job.JobStatus]int)
+	for _, j := range s.jobs {
+		counts[j.Status]++
+	}
+	return counts, nil
+}
+
+// countTags adds delta to the counters of each of the job's tags in its
+// current status; callers must hold the write lock
+func (s *MemoryStore) countTags(j *job.Job, delta int) {
+	seen := make(map[string]bool, len(j.Tags))
+	for _, tag := range j.Tags {
+		if seen[tag] {
+			continue // A tag listed twice still marks the job once
+		}
+		seen[tag] = true
+
+		byStatus := s.tags[tag]
+		if byStatus == nil {
+			byStatus = make(map[job.JobStatus]int)
+			s.tags[tag] = byStatus
+		}
+		byStatus[j.Status] += delta
+		if byStatus[j.Status] <= 0 {
+			delete(byStatus, j.Status)
+		}
+		if len(byStatus) == 0 {
+			delete(s.tags, tag)
+		}
+	}
+}
+
 // matchesFilters checks if a job matches the given filters
-func (s *MemoryStore) matchesFilters(j *job.Job, filters []job.Filter) bool {
+func matchesFilters(j *job.Job, filters []job.Filter) bool {
 	for _, filter := range filters {
-		if !s.matchesFilter(j, filter) {
+		if !matchesFilter(j, filter) {
 			return false
 		}
 	}
@@ -128,7 +363,12 @@ func (s *MemoryStore) matchesFilters(j *job.Job, filters []job.Filter) bool {
 }
 
 // matchesFilter checks if a job matches a single filter
-func (s *MemoryStore) matchesFilter(j *job.Job, filter job.Filter) bool {
+func matchesFilter(j *job.Job, filter job.Filter) bool {
+	// Tags are a set rather than a scalar, so they have their own operators
+	if filter.Field == "tags" {
+		return matchesTagFilter(j.Tags, filter)
+	}
+
 	var fieldValue interface{}
 
 	// Extract field value from job
@@ -141,6 +381,8 @@ func (s *MemoryStore) matchesFilter(j *job.Job, filter job.Filter) bool {
 		fieldValue = string(j.Status)
 	case "worker_id":
 		fieldValue = j.WorkerID
+	case "parent_id":
+		fieldValue = j.ParentID
 	case "priority":
 		fieldValue = j.Priority
 	case "created_at":
@@ -157,8 +399,22 @@ func (s *MemoryStore) matchesFilter(j *job.Job, filter job.Filter) bool {
 		} else {
 			fieldValue = nil
 		}
+	case "retry_at":
+		if j.RetryAt != nil {
+			fieldValue = *j.RetryAt
+		} else {
+			fieldValue = nil
+		}
 	default:
-		return false // Unknown field
+		key, ok := strings.CutPrefix(filter.Field, "annotation:")
+		if !ok {
+			return false // Unknown field
+		}
+		if value, exists := j.Annotations[key]; exists {
+			fieldValue = value
+		} else {
+			fieldValue = nil
+		}
 	}
 
 	// Apply operator
@@ -168,13 +424,13 @@ func (s *MemoryStore) matchesFilter(j *job.Job, filter job.Filter) bool {
 	case "ne":
 		return fieldValue != filter.Value
 	case "gt":
-		return s.compareValues(fieldValue, filter.Value) > 0
+		return compareValues(fieldValue, filter.Value) > 0
 	case "lt":
-		return s.compareValues(fieldValue, filter.Value) < 0
+		return compareValues(fieldValue, filter.Value) < 0
 	case "gte":
-		return s.compareValues(fieldValue, filter.Value) >= 0
+		return compareValues(fieldValue, filter.Value) >= 0
 	case "lte":
-		return s.compareValues(fieldValue, filter.Value) <= 0
+		return compareValues(fieldValue, filter.Value) <= 0
 	case "in":
 		if slice, ok := filter.Value.([]interface{}); ok {
 			for _, v := range slice {
@@ -191,13 +447,91 @@ func (s *MemoryStore) matchesFilter(j *job.Job, filter job.Filter) bool {
 			}
 		}
 		return false
+	case "exists":
+		return isSet(fieldValue)
+	case "nexists":
+		return !isSet(fieldValue)
+	default:
+		return false // Unknown operator
+	}
+}
+
+// matchesTagFilter applies a set operator to a job's tags: contains requires
+// one given tag, hasall every listed tag, hasany and in at least one, and
+// exists/nexists test for any tags. Tags match exactly, so case matters.
+func matchesTagFilter(tags []string, filter job.Filter) bool {
+	switch filter.Operator {
+	case "exists":
+		return len(tags) > 0
+	case "nexists":
+		return len(tags) == 0
+	case "contains":
+		tag, ok := filter.Value.(string)
+		return ok && slices.Contains(tags, tag)
+	case "hasall", "hasany", "in":
 	default:
 		return false // Unknown operator
 	}
+
+	wanted, ok := tagValues(filter.Value)
+	if !ok {
+		return false
+	}
+
+	have := make(map[string]struct{}, len(tags))
+	for _, tag := range tags {
+		have[tag] = struct{}{}
+	}
+
+	matchAll := filter.Operator == "hasall"
+	for _, tag := range wanted {
+		_, found := have[tag]
+		if found && !matchAll {
+			return true
+		}
+		if !found && matchAll {
+			return false
+		}
+	}
+	return matchAll
+}
+
+// tagValues converts a tag filter value, as decoded from JSON or built in
+// code, to a list of tags
+func tagValues(value interface{}) ([]string, bool) {
+	switch v := value.(type) {
+	case []string:
+		return v, true
+	case []interface{}:
+		tags := make([]string, 0, len(v))
+		for _, item := range v {
+			tag, ok := item.(string)
+			if !ok {
+				return nil, false
+			}
+			tags = append(tags, tag)
+		}
+		return tags, true
+	default:
+		return nil, false
+	}
+}
+
+// isSet reports whether a nullable field has a value; unset timestamps are
+// nil and an unassigned worker ID is empty
+func isSet(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case string:
+		return v != ""
+	default:
+		return true
+	}
 }
 
 // compareValues compares two values for ordering operations
-func (s *MemoryStore) compareValues(a, b interface{}) int {
+func compareValues(a, b interface{}) int {
 	switch va := a.(type) {
 	case int:
 		if vb, ok := b.(int); ok {
@@ -232,9 +566,9 @@ func (s *MemoryStore) compareValues(a, b interface{}) int {
 
 // contains checks if a string contains a substring (case-insensitive)
 func contains(str, substr string) bool {
-	return len(str) >= len(substr) && 
-		   (str == substr || 
-		    (len(substr) > 0 && findSubstring(str, substr)))
+	return len(str) >= len(substr) &&
+		(str == substr ||
+			(len(substr) > 0 && findSubstring(str, substr)))
 }
 
 // Simple substring search (case-insensitive)
@@ -292,4 +626,6 @@ func (s *MemoryStore) Clear(ctx context.Context) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 	s.jobs = make(map[string]*job.Job)
-} 
\ No newline at end of file
+	s.results = make(map[string]*job.JobResult)
+	s.tags = make(map[string]map[job.JobStatus]int)
+}
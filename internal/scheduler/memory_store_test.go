@@ -0,0 +1,351 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"infinitrain/pkg/job"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore_Exists(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	if err := store.Create(ctx, &job.Job{ID: "job-1", Status: job.JobStatusQueued}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	tests := []struct {
+		id   string
+		want bool
+	}{
+		{id: "job-1", want: true},
+		{id: "job-missing", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			exists, err := store.Exists(ctx, tt.id)
+			if err != nil {
+				t.Fatalf("Exists() error = %v", err)
+			}
+			if exists != tt.want {
+				t.Errorf("Exists(%s) = %v, want %v", tt.id, exists, tt.want)
+			}
+
+			// Unlike Get, the check must not copy the job
+			if allocs := testing.AllocsPerRun(100, func() { store.Exists(ctx, tt.id) }); allocs != 0 {
+				t.Errorf("Expected Exists not to allocate, got %v allocations", allocs)
+			}
+		})
+	}
+}
+
+func TestMemoryStore_PresenceOperators(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	started := Now()
+	completed := started.Add(time.Minute)
+
+	jobs := []*job.Job{
+		{ID: "job-queued", Status: job.JobStatusQueued},
+		{ID: "job-running", Status: job.JobStatusRunning, WorkerID: "worker-1", StartedAt: &started},
+		{ID: "job-completed", Status: job.JobStatusCompleted, WorkerID: "worker-1", StartedAt: &started, CompletedAt: &completed},
+	}
+	for _, j := range jobs {
+		if err := store.Create(ctx, j); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		filters []job.Filter
+		want    []string
+	}{
+		{
+			name: "started but not completed",
+			filters: []job.Filter{
+				{Field: "started_at", Operator: "exists"},
+				{Field: "completed_at", Operator: "nexists"},
+			},
+			want: []string{"job-running"},
+		},
+		{
+			name:    "never started",
+			filters: []job.Filter{{Field: "started_at", Operator: "nexists"}},
+			want:    []string{"job-queued"},
+		},
+		{
+			name:    "assigned to a worker",
+			filters: []job.Filter{{Field: "worker_id", Operator: "exists"}},
+			want:    []string{"job-completed", "job-running"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matched, err := store.List(ctx, tt.filters...)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+
+			ids := make([]string, 0, len(matched))
+			for _, j := range matched {
+				ids = append(ids, j.ID)
+			}
+			sort.Strings(ids)
+
+			if strings.Join(ids, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Expected %v, got %v", tt.want, ids)
+			}
+		})
+	}
+}
+
+func TestMemoryStore_TagSetOperators(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	jobs := []*job.Job{
+		{ID: "job-untagged", Status: job.JobStatusQueued},
+		{ID: "job-a", Status: job.JobStatusQueued, Tags: []string{"a"}},
+		{ID: "job-ab", Status: job.JobStatusQueued, Tags: []string{"a", "b"}},
+		{ID: "job-abc", Status: job.JobStatusQueued, Tags: []string{"c", "b", "a"}},
+		{ID: "job-c", Status: job.JobStatusQueued, Tags: []string{"c"}},
+	}
+	for _, j := range jobs {
+		if err := store.Create(ctx, j); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter job.Filter
+		want   []string
+	}{
+		{
+			name:   "hasall requires every tag",
+			filter: job.Filter{Field: "tags", Operator: "hasall", Value: []interface{}{"a", "b"}},
+			want:   []string{"job-ab", "job-abc"},
+		},
+		{
+			name:   "hasany requires one tag",
+			filter: job.Filter{Field: "tags", Operator: "hasany", Value: []interface{}{"b", "c"}},
+			want:   []string{"job-ab", "job-abc", "job-c"},
+		},
+		{
+			name:   "hasany with no overlap",
+			filter: job.Filter{Field: "tags", Operator: "hasany", Value: []interface{}{"d"}},
+			want:   []string{},
+		},
+		{
+			name:   "contains one tag",
+			filter: job.Filter{Field: "tags", Operator: "contains", Value: "c"},
+			want:   []string{"job-abc", "job-c"},
+		},
+		{
+			name:   "in requires one tag",
+			filter: job.Filter{Field: "tags", Operator: "in", Value: []interface{}{"b", "d"}},
+			want:   []string{"job-ab", "job-abc"},
+		},
+		{
+			name:   "untagged",
+			filter: job.Filter{Field: "tags", Operator: "nexists"},
+			want:   []string{"job-untagged"},
+		},
+		{
+			name:   "non-string tags match nothing",
+			filter: job.Filter{Field: "tags", Operator: "hasall", Value: []interface{}{1.0}},
+			want:   []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matched, err := store.List(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+
+			ids := make([]string, 0, len(matched))
+			for _, j := range matched {
+				ids = append(ids, j.ID)
+			}
+			sort.Strings(ids)
+
+			if strings.Join(ids, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Expected %v, got %v", tt.want, ids)
+			}
+		})
+	}
+}
+
+func TestMemoryStore_TagCounts(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	for _, j := range []*job.Job{
+		{ID: "job-1", Status: job.JobStatusQueued, Tags: []string{"a", "b"}},
+		{ID: "job-2", Status: job.JobStatusQueued, Tags: []string{"a", "a"}},
+		{ID: "job-3", Status: job.JobStatusRunning, Tags: []string{"b"}},
+	} {
+		if err := store.Create(ctx, j); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	// Counters follow status changes, updates and deletes
+	if err := store.UpdateStatus(ctx, "job-1", job.JobStatusCancelled); err != nil {
+		t.Fatalf("UpdateStatus() error = %v", err)
+	}
+	if err := store.Update(ctx, &job.Job{ID: "job-3", Status: job.JobStatusCompleted, Tags: []string{"c"}}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if err := store.Delete(ctx, "job-2"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	counts, err := store.TagCounts(ctx)
+	if err != nil {
+		t.Fatalf("TagCounts() error = %v", err)
+	}
+	want := map[string]map[job.JobStatus]int{
+		"a": {job.JobStatusCancelled: 1},
+		"b": {job.JobStatusCancelled: 1},
+		"c": {job.JobStatusCompleted: 1},
+	}
+	if fmt.Sprint(counts) != fmt.Sprint(want) {
+		t.Errorf("Expected %v, got %v", want, counts)
+	}
+}
+
+func TestMemoryStore_DeleteOlderThan(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	cutoff := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	before, after := cutoff.Add(-time.Minute), cutoff.Add(time.Minute)
+	for _, j := range []*job.Job{
+		{ID: "completed-old", Status: job.JobStatusCompleted, CompletedAt: &before, Tags: []string{"nightly"}},
+		{ID: "completed-new", Status: job.JobStatusCompleted, CompletedAt: &after},
+		{ID: "failed-old", Status: job.JobStatusFailed, CompletedAt: &before},
+		{ID: "cancelled-unstamped", Status: job.JobStatusCancelled, CreatedAt: before},
+		{ID: "queued-old", Status: job.JobStatusQueued, CreatedAt: before},
+	} {
+		if err := store.Create(ctx, j); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+	if err := store.SaveResult(ctx, &job.JobResult{JobID: "completed-old", Status: job.JobStatusCompleted}); err != nil {
+		t.Fatalf("SaveResult() error = %v", err)
+	}
+
+	deleted, err := store.DeleteOlderThan(ctx, cutoff, job.JobStatusCompleted)
+	if err != nil {
+		t.Fatalf("DeleteOlderThan() error = %v", err)
+	}
+	if len(deleted) != 1 || deleted[0] != "completed-old" {
+		t.Errorf("Expected only completed-old to be deleted, got %v", deleted)
+	}
+	if _, err := store.GetResult(ctx, "completed-old"); !job.IsJobNotFoundError(err) {
+		t.Errorf("Expected the deleted job's result to go too, got %v", err)
+	}
+	if counts, _ := store.TagCounts(ctx); len(counts) != 0 {
+		t.Errorf("Expected no tag counts left, got %v", counts)
+	}
+
+	// With no statuses given every terminal status is eligible
+	deleted, _ = store.DeleteOlderThan(ctx, cutoff)
+	sort.Strings(deleted)
+	if strings.Join(deleted, ",") != "cancelled-unstamped,failed-old" {
+		t.Errorf("Expected the remaining old terminal jobs to be deleted, got %v", deleted)
+	}
+	if store.Count(ctx) != 2 {
+		t.Errorf("Expected the new and queued jobs to be kept, got %d jobs", store.Count(ctx))
+	}
+}
+
+// checkListPage pages through jobs created out of ID order, by filters a
+// store's indexes answer and by ones they don't
+func checkListPage(t *testing.T, store job.Store) {
+	t.Helper()
+	ctx := context.Background()
+
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	statuses := []job.JobStatus{job.JobStatusQueued, job.JobStatusRunning, job.JobStatusQueued, job.JobStatusCompleted, job.JobStatusQueued}
+	for i, status := range statuses {
+		j := &job.Job{ID: fmt.Sprintf("job-%d", i), Status: status, Priority: i % 3 * 5, CreatedAt: base.Add(time.Duration(len(statuses)-i) * time.Minute)}
+		if err := store.Create(ctx, j); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	// pages follows cursors to the end, returning the IDs in order and the
+	// total each page reported
+	pages := func(limit int, filters ...job.Filter) (string, int) {
+		t.Helper()
+		var ids []string
+		total := -1
+		opts := job.ListOptions{Filters: filters, Limit: limit}
+		for range len(statuses) + 1 {
+			page, err := store.ListPage(ctx, opts)
+			if err != nil {
+				t.Fatalf("ListPage() error = %v", err)
+			}
+			if total != -1 && page.Total != total {
+				t.Errorf("Expected every page to report a total of %d, got %d", total, page.Total)
+			}
+			total = page.Total
+			if len(page.Jobs) > limit {
+				t.Errorf("Expected at most %d jobs a page, got %d", limit, len(page.Jobs))
+			}
+			for _, j := range page.Jobs {
+				ids = append(ids, j.ID)
+			}
+			if page.NextCursor == "" {
+				return strings.Join(ids, ","), total
+			}
+			opts.Cursor = page.NextCursor
+		}
+		t.Fatal("Expected the cursor to run out")
+		return "", 0
+	}
+
+	tests := []struct {
+		name      string
+		limit     int
+		filters   []job.Filter
+		want      string
+		wantTotal int
+	}{
+		{name: "all", limit: 2, want: "job-4,job-3,job-2,job-1,job-0", wantTotal: 5},
+		{name: "indexed", limit: 2, filters: []job.Filter{{Field: "status", Operator: "eq", Value: string(job.JobStatusQueued)}}, want: "job-4,job-2,job-0", wantTotal: 3},
+		{name: "unindexed", limit: 1, filters: []job.Filter{{Field: "priority", Operator: "gte", Value: 5}}, want: "job-4,job-2,job-1", wantTotal: 3},
+	}
+	for _, tt := range tests {
+		if got, total := pages(tt.limit, tt.filters...); got != tt.want || total != tt.wantTotal {
+			t.Errorf("%s: expected %s of %d, got %s of %d", tt.name, tt.want, tt.wantTotal, got, total)
+		}
+	}
+
+	page, err := store.ListPage(ctx, job.ListOptions{Limit: 2, Offset: 4})
+	if err != nil || len(page.Jobs) != 1 || page.Jobs[0].ID != "job-0" || page.NextCursor != "" {
+		t.Errorf("Expected only the oldest job at offset 4, got %+v, %v", page, err)
+	}
+}
+
+func TestMemoryStore_ListPage(t *testing.T) {
+	store := NewMemoryStore()
+	checkListPage(t, store)
+
+	// Pages hold copies, as List does
+	page, _ := store.ListPage(context.Background(), job.ListOptions{Limit: 1})
+	page.Jobs[0].Priority = 99
+	if stored, _ := store.Get(context.Background(), page.Jobs[0].ID); stored.Priority == 99 {
+		t.Errorf("Expected ListPage to return copies")
+	}
+}
@@ -0,0 +1,742 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"infinitrain/internal/config"
+	"infinitrain/pkg/job"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// postgresQueuedChannel is the LISTEN/NOTIFY channel announcing the IDs of
+// jobs that entered the queued status
+const postgresQueuedChannel = "infinitrain_jobs_queued"
+
+// postgresClaimBatch is how many queued jobs ClaimNext locks and considers
+// at once, so jobs the caller can't take yet don't stall the ones behind them
+const postgresClaimBatch = 32
+
+// postgresSchema creates the tables and indexes the store uses. The job is
+// kept as JSON text; the columns beside it duplicate the fields that are
+// filtered and ordered on so they can be indexed.
+const postgresSchema = `
+CREATE TABLE IF NOT EXISTS infinitrain_jobs (
+	id           TEXT PRIMARY KEY,
+	status       TEXT NOT NULL,
+	worker_id    TEXT NOT NULL DEFAULT '',
+	priority     INTEGER NOT NULL DEFAULT 0,
+	created_at   TIMESTAMPTZ NOT NULL,
+	completed_at TIMESTAMPTZ,
+	data         TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS infinitrain_jobs_status_idx ON infinitrain_jobs (status);
+CREATE INDEX IF NOT EXISTS infinitrain_jobs_worker_idx ON infinitrain_jobs (worker_id) WHERE worker_id <> '';
+CREATE INDEX IF NOT EXISTS infinitrain_jobs_claim_idx ON infinitrain_jobs (priority DESC, created_at, id) WHERE status = 'queued';
+ALTER TABLE infinitrain_jobs ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+UPDATE infinitrain_jobs SET tags = ARRAY(SELECT DISTINCT jsonb_array_elements_text(data::jsonb->'tags'))
+	WHERE tags = '{}' AND data::jsonb ? 'tags';
+CREATE INDEX IF NOT EXISTS infinitrain_jobs_tags_idx ON infinitrain_jobs USING GIN (tags);
+CREATE TABLE IF NOT EXISTS infinitrain_job_results (
+	job_id TEXT PRIMARY KEY REFERENCES infinitrain_jobs (id) ON DELETE CASCADE,
+	data   TEXT NOT NULL
+);
+`
+
+// PostgresStore is a PostgreSQL implementation of the job.Store interface,
+// letting several schedulers share one durable store. Writes run in
+// transactions that lock the job's row, ClaimNext hands each queued job to
+// exactly one caller, and jobs entering the queue are announced with NOTIFY
+// so waiting schedulers can wake without polling.
+type PostgresStore struct {
+	pool   *pgxpool.Pool
+	events *StatusBroker
+}
+
+// NewPostgresStore creates a store using an existing connection pool. Call
+// Migrate before using it on a fresh database.
+func NewPostgresStore(pool *pgxpool.Pool) *PostgresStore {
+	return &PostgresStore{pool: pool, events: NewStatusBroker()}
+}
+
+// NewPostgresStoreFromConfig connects to the configured database and creates
+// the store's tables if they don't exist yet
+func NewPostgresStoreFromConfig(ctx context.Context, cfg *config.PostgresConfig) (*PostgresStore, error) {
+	poolConfig, err := pgxpool.ParseConfig(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid postgres URL: %w", err)
+	}
+	if cfg.MaxConns > 0 {
+		poolConfig.MaxConns = int32(cfg.MaxConns)
+	}
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to postgres: %w", err)
+	}
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to connect to postgres: %w", err)
+	}
+
+	store := NewPostgresStore(pool)
+	if err := store.Migrate(ctx); err != nil {
+		pool.Close()
+		return nil, err
+	}
+	return store, nil
+}
+
+// Migrate creates the store's tables and indexes if they don't exist
+func (s *PostgresStore) Migrate(ctx context.Context) error {
+	if _, err := s.pool.Exec(ctx, postgresSchema); err != nil {
+		return fmt.Errorf("failed to create postgres schema: %w", err)
+	}
+	return nil
+}
+
+// Close closes the connection pool
+func (s *PostgresStore) Close() {
+	s.pool.Close()
+}
+
+// Create stores a new job
+func (s *PostgresStore) Create(ctx context.Context, j *job.Job) error {
+	jobCopy := *j
+	jobCopy.NormalizeTimestamps()
+	args, err := postgresJobArgs(&jobCopy)
+	if err != nil {
+		return err
+	}
+
+	err = pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, `
+			INSERT INTO infinitrain_jobs (id, status, worker_id, priority, created_at, completed_at, data, tags)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			ON CONFLICT (id) DO NOTHING`, args...)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return job.NewValidationError("job already exists: " + j.ID)
+		}
+		return notifyIfQueued(ctx, tx, &jobCopy)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.events.Publish(&jobCopy)
+	return nil
+}
+
+// Get retrieves a job by ID
+func (s *PostgresStore) Get(ctx context.Context, jobID string) (*job.Job, error) {
+	var data string
+	err := s.pool.QueryRow(ctx, `SELECT data FROM infinitrain_jobs WHERE id = $1`, jobID).Scan(&data)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, job.NewJobNotFoundError(jobID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodePostgresJob(jobID, data)
+}
+
+// Ping checks that the database can be reached
+func (s *PostgresStore) Ping(ctx context.Context) error {
+	return s.pool.Ping(ctx)
+}
+
+// Exists reports whether a job is stored without loading it
+func (s *PostgresStore) Exists(ctx context.Context, jobID string) (bool, error) {
+	var exists bool
+	err := s.pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM infinitrain_jobs WHERE id = $1)`, jobID).Scan(&exists)
+	return exists, err
+}
+
+// Update updates an existing job
+func (s *PostgresStore) Update(ctx context.Context, j *job.Job) error {
+	jobCopy := *j
+	jobCopy.NormalizeTimestamps()
+
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		return updatePostgresJob(ctx, tx, &jobCopy)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.events.Publish(&jobCopy)
+	return nil
+}
+
+// Delete removes a job and its result from storage
+func (s *PostgresStore) Delete(ctx context.Context, jobID string) error {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM infinitrain_jobs WHERE id = $1`, jobID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return job.NewJobNotFoundError(jobID)
+	}
+	return nil
+}
+
+// List returns jobs with optional filtering. Equality filters on status and
+// worker_id and status "in" filters are answered by the database; every
+// filter is then applied to the jobs it returns.
+func (s *PostgresStore) List(ctx context.Context, filters ...job.Filter) ([]*job.Job, error) {
+	where, args := postgresWhere(filters)
+	rows, err := s.pool.Query(ctx, `SELECT id, data FROM infinitrain_jobs`+where+` ORDER BY created_at, id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*job.Job
+	for rows.Next() {
+		var id, data string
+		if err := rows.Scan(&id, &data); err != nil {
+			return nil, err
+		}
+		j, err := decodePostgresJob(id, data)
+		if err != nil {
+			return nil, err
+		}
+		if matchesFilters(j, filters) {
+			result = append(result, j)
+		}
+	}
+	return result, rows.Err()
+}
+
+// ListPage returns one page of the jobs matching the options' filters. When
+// the indexed columns answer every filter the database counts the matches
+// and returns only the page; other filters need every match loaded.
+func (s *PostgresStore) ListPage(ctx context.Context, opts job.ListOptions) (*job.JobPage, error) {
+	if !postgresAnswers(opts.Filters) {
+		jobs, err := s.List(ctx, opts.Filters...)
+		if err != nil {
+			return nil, err
+		}
+		return job.Paginate(jobs, opts)
+	}
+
+	where, args := postgresWhere(opts.Filters)
+	page := &job.JobPage{}
+	if err := s.pool.QueryRow(ctx, `SELECT count(*) FROM infinitrain_jobs`+where, args...).Scan(&page.Total); err != nil {
+		return nil, err
+	}
+
+	query := `SELECT id, data FROM infinitrain_jobs` + where
+	if opts.Cursor != "" {
+		createdAt, id, err := job.DecodeCursor(opts.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		// The column keeps microseconds, so compare at that precision
+		args = append(args, createdAt.Truncate(time.Microsecond), id)
+		if where == "" {
+			query += " WHERE "
+		} else {
+			query += " AND "
+		}
+		query += fmt.Sprintf("(created_at, id) > ($%d, $%d)", len(args)-1, len(args))
+	}
+	query += " ORDER BY created_at, id"
+	if opts.Limit > 0 {
+		// One more than the page shows whether another follows
+		args = append(args, opts.Limit+1)
+		query += " LIMIT $" + strconv.Itoa(len(args))
+	}
+	if opts.Cursor == "" && opts.Offset > 0 {
+		args = append(args, opts.Offset)
+		query += " OFFSET $" + strconv.Itoa(len(args))
+	}
+
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, data string
+		if err := rows.Scan(&id, &data); err != nil {
+			return nil, err
+		}
+		j, err := decodePostgresJob(id, data)
+		if err != nil {
+			return nil, err
+		}
+		page.Jobs = append(page.Jobs, j)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if opts.Limit > 0 && len(page.Jobs) > opts.Limit {
+		page.Jobs = page.Jobs[:opts.Limit]
+		page.NextCursor = job.EncodeCursor(page.Jobs[opts.Limit-1])
+	}
+	return page, nil
+}
+
+// UpdateStatus updates the status of a job
+func (s *PostgresStore) UpdateStatus(ctx context.Context, jobID string, status job.JobStatus) error {
+	var updated *job.Job
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		j, err := lockPostgresJob(ctx, tx, jobID)
+		if err != nil {
+			return err
+		}
+
+		// Update the status and timestamps
+		if err := j.UpdateStatus(status); err != nil {
+			return err
+		}
+		if err := updatePostgresJob(ctx, tx, j); err != nil {
+			return err
+		}
+		updated = j
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.events.Publish(updated)
+	return nil
+}
+
+// AssignWorker moves a queued job to running on the given worker in one
+// transaction, so a cancellation landing at the same time can't be
+// overwritten. A job that is no longer queued gives a conflict error.
+func (s *PostgresStore) AssignWorker(ctx context.Context, jobID, workerID string) (*job.Job, error) {
+	var assigned *job.Job
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		j, err := lockPostgresJob(ctx, tx, jobID)
+		if err != nil {
+			return err
+		}
+		if j.Status != job.JobStatusQueued {
+			return job.NewConflictError(fmt.Sprintf("job %s is no longer queued (status %s)", jobID, j.Status))
+		}
+
+		j.WorkerID = workerID
+		if err := j.UpdateStatus(job.JobStatusRunning); err != nil {
+			return err
+		}
+		if err := updatePostgresJob(ctx, tx, j); err != nil {
+			return err
+		}
+		assigned = j
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.events.Publish(assigned)
+	return assigned, nil
+}
+
+// ExtendTimeout adds extra to a running job's timeout in one transaction,
+// so it can't overwrite a change made since the job was read. A job that is
+// no longer running gives a conflict error.
+func (s *PostgresStore) ExtendTimeout(ctx context.Context, jobID string, extra time.Duration) (*job.Job, error) {
+	var extended *job.Job
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		j, err := lockPostgresJob(ctx, tx, jobID)
+		if err != nil {
+			return err
+		}
+		if !j.IsRunning() {
+			return job.NewConflictError(fmt.Sprintf("job %s is no longer running (status %s)", jobID, j.Status))
+		}
+
+		j.Timeout += extra
+		if err := updatePostgresJob(ctx, tx, j); err != nil {
+			return err
+		}
+		extended = j
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.events.Publish(extended)
+	return extended, nil
+}
+
+// ClaimNext assigns the highest-priority queued job that eligible accepts to
+// the worker and marks it running. Rows another scheduler is claiming are
+// skipped rather than waited for, so concurrent callers never get the same
+// job. Only the first postgresClaimBatch queued jobs are considered; a nil
+// eligible accepts any job. job.ErrQueueEmpty is returned when none fits.
+func (s *PostgresStore) ClaimNext(ctx context.Context, workerID string, eligible func(*job.Job) bool) (*job.Job, error) {
+	var claimed *job.Job
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			SELECT id, data FROM infinitrain_jobs
+			WHERE status = $1
+			ORDER BY priority DESC, created_at, id
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED`, string(job.JobStatusQueued), postgresClaimBatch)
+		if err != nil {
+			return err
+		}
+
+		var candidates []*job.Job
+		for rows.Next() {
+			var id, data string
+			if err := rows.Scan(&id, &data); err != nil {
+				rows.Close()
+				return err
+			}
+			j, err := decodePostgresJob(id, data)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			candidates = append(candidates, j)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, j := range candidates {
+			if eligible != nil && !eligible(j) {
+				continue
+			}
+			j.WorkerID = workerID
+			if err := j.UpdateStatus(job.JobStatusRunning); err != nil {
+				return err
+			}
+			if err := updatePostgresJob(ctx, tx, j); err != nil {
+				return err
+			}
+			claimed = j
+			return nil
+		}
+		return job.ErrQueueEmpty
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.events.Publish(claimed)
+	return claimed, nil
+}
+
+// ListenQueued returns a channel receiving the ID of each job that enters
+// the queued status, written by this or any other scheduler sharing the
+// database. IDs are dropped rather than delivered late when the receiver
+// falls behind, as they only serve to wake it. The channel is closed when
+// the context is done or the listening connection fails, after which the
+// caller should fall back to polling or listen again.
+func (s *PostgresStore) ListenQueued(ctx context.Context) (<-chan string, error) {
+	conn, err := s.pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.Exec(ctx, "LISTEN "+postgresQueuedChannel); err != nil {
+		conn.Release()
+		return nil, err
+	}
+
+	queued := make(chan string, statusSubscriberBuffer)
+	go func() {
+		defer close(queued)
+		// A wait interrupted by the context closes the connection, so the
+		// pool discards it instead of reusing a connection still listening
+		defer conn.Release()
+
+		for {
+			notification, err := conn.Conn().WaitForNotification(ctx)
+			if err != nil {
+				return
+			}
+			select {
+			case queued <- notification.Payload:
+			default:
+			}
+		}
+	}()
+	return queued, nil
+}
+
+// WatchStatus returns a channel receiving the job after each change this
+// store makes to it. Changes written by other schedulers sharing the
+// database are not seen. Call cancel to stop watching.
+func (s *PostgresStore) WatchStatus(jobID string) (<-chan *job.Job, func()) {
+	return s.events.Subscribe(jobID)
+}
+
+// DeleteOlderThan deletes the terminal jobs that finished by the cutoff,
+// limited to the given statuses when any are given, and returns their IDs.
+// Jobs without a completion time are aged by their creation time, and jobs
+// an unfinished job depends on are kept.
+func (s *PostgresStore) DeleteOlderThan(ctx context.Context, cutoff time.Time, statuses ...job.JobStatus) ([]string, error) {
+	if len(statuses) == 0 {
+		statuses = []job.JobStatus{job.JobStatusCompleted, job.JobStatusFailed, job.JobStatusCancelled}
+	}
+	var terminal []string
+	for _, status := range statuses {
+		if (&job.Job{Status: status}).IsTerminal() {
+			terminal = append(terminal, string(status))
+		}
+	}
+	if len(terminal) == 0 {
+		return nil, nil
+	}
+
+	rows, err := s.pool.Query(ctx, `
+		DELETE FROM infinitrain_jobs old
+		WHERE old.status = ANY($1) AND COALESCE(old.completed_at, old.created_at) <= $2
+			AND NOT EXISTS (
+				SELECT 1 FROM infinitrain_jobs dependent
+				WHERE dependent.status NOT IN ('completed', 'failed', 'cancelled')
+					AND dependent.data::jsonb->'depends_on' ? old.id
+			)
+		RETURNING old.id`, terminal, cutoff)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+// StatusCounts returns how many jobs are in each status, counted by the
+// database from the status index
+func (s *PostgresStore) StatusCounts(ctx context.Context) (map[job.JobStatus]int, error) {
+	rows, err := s.pool.Query(ctx, `SELECT status, count(*) FROM infinitrain_jobs GROUP BY status`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[job.JobStatus]int)
+	for rows.Next() {
+		var status string
+		var n int
+		if err := rows.Scan(&status, &n); err != nil {
+			return nil, err
+		}
+		counts[job.JobStatus(status)] = n
+	}
+	return counts, rows.Err()
+}
+
+// TagCounts returns how many jobs carry each tag, by status, grouped by the
+// database from the tags column
+func (s *PostgresStore) TagCounts(ctx context.Context) (map[string]map[job.JobStatus]int, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT tag, status, count(*)
+		FROM infinitrain_jobs, unnest(tags) AS tag
+		GROUP BY tag, status`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]map[job.JobStatus]int)
+	for rows.Next() {
+		var tag, status string
+		var n int
+		if err := rows.Scan(&tag, &status, &n); err != nil {
+			return nil, err
+		}
+		if counts[tag] == nil {
+			counts[tag] = make(map[job.JobStatus]int)
+		}
+		counts[tag][job.JobStatus(status)] = n
+	}
+	return counts, rows.Err()
+}
+
+// SaveResult stores the result of a job's latest run as JSON next to the job
+func (s *PostgresStore) SaveResult(ctx context.Context, result *job.JobResult) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to encode result of job %s: %w", result.JobID, err)
+	}
+
+	tag, err := s.pool.Exec(ctx, `
+		INSERT INTO infinitrain_job_results (job_id, data)
+		SELECT id, $2::text FROM infinitrain_jobs WHERE id = $1
+		ON CONFLICT (job_id) DO UPDATE SET data = EXCLUDED.data`, result.JobID, string(data))
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return job.NewJobNotFoundError(result.JobID)
+	}
+	return nil
+}
+
+// GetResult retrieves the stored result of a job
+func (s *PostgresStore) GetResult(ctx context.Context, jobID string) (*job.JobResult, error) {
+	var data string
+	err := s.pool.QueryRow(ctx, `SELECT data FROM infinitrain_job_results WHERE job_id = $1`, jobID).Scan(&data)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, job.NewJobNotFoundError(jobID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var result job.JobResult
+	if err := json.Unmarshal([]byte(data), &result); err != nil {
+		return nil, fmt.Errorf("failed to decode result of job %s: %w", jobID, err)
+	}
+	return &result, nil
+}
+
+// lockPostgresJob loads a job and locks its row until the transaction ends
+func lockPostgresJob(ctx context.Context, tx pgx.Tx, jobID string) (*job.Job, error) {
+	var data string
+	err := tx.QueryRow(ctx, `SELECT data FROM infinitrain_jobs WHERE id = $1 FOR UPDATE`, jobID).Scan(&data)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, job.NewJobNotFoundError(jobID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodePostgresJob(jobID, data)
+}
+
+// updatePostgresJob writes an existing job, announcing it if it is queued
+func updatePostgresJob(ctx context.Context, tx pgx.Tx, j *job.Job) error {
+	args, err := postgresJobArgs(j)
+	if err != nil {
+		return err
+	}
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE infinitrain_jobs
+		SET status = $2, worker_id = $3, priority = $4, created_at = $5, completed_at = $6, data = $7, tags = $8
+		WHERE id = $1`, args...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return job.NewJobNotFoundError(j.ID)
+	}
+	return notifyIfQueued(ctx, tx, j)
+}
+
+// notifyIfQueued announces a queued job to listening schedulers. The
+// notification is only delivered if the transaction commits.
+func notifyIfQueued(ctx context.Context, tx pgx.Tx, j *job.Job) error {
+	if j.Status != job.JobStatusQueued {
+		return nil
+	}
+	_, err := tx.Exec(ctx, `SELECT pg_notify($1, $2)`, postgresQueuedChannel, j.ID)
+	return err
+}
+
+// postgresJobArgs returns a job's column values in table order
+func postgresJobArgs(j *job.Job) ([]any, error) {
+	data, err := json.Marshal(j)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode job %s: %w", j.ID, err)
+	}
+	return []any{j.ID, string(j.Status), j.WorkerID, j.Priority, j.CreatedAt, j.CompletedAt, string(data), distinctTags(j.Tags)}, nil
+}
+
+// distinctTags returns the tags without repeats, never nil so the tags
+// column is always set
+func distinctTags(tags []string) []string {
+	distinct := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if !slices.Contains(distinct, tag) {
+			distinct = append(distinct, tag)
+		}
+	}
+	return distinct
+}
+
+// postgresWhere builds the WHERE clause for the filters the indexed columns
+// can answer: status and worker_id equality and lists, and tag matches. Other
+// filters are left for the caller to apply.
+func postgresWhere(filters []job.Filter) (string, []any) {
+	var conditions []string
+	var args []any
+	for _, filter := range filters {
+		if condition, arg, ok := postgresCondition(filter); ok {
+			args = append(args, arg)
+			conditions = append(conditions, strings.Replace(condition, "?", "$"+strconv.Itoa(len(args)), 1))
+		}
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// postgresCondition returns the SQL condition answering a filter from the
+// indexed columns, with ? in place of its argument, if there is one
+func postgresCondition(filter job.Filter) (string, any, bool) {
+	if filter.Field == "tags" {
+		switch filter.Operator {
+		case "contains":
+			if tag, ok := filter.Value.(string); ok {
+				return "tags @> ?", []string{tag}, true
+			}
+		case "hasall":
+			if values, ok := tagValues(filter.Value); ok {
+				return "tags @> ?", values, true
+			}
+		case "hasany", "in":
+			if values, ok := tagValues(filter.Value); ok {
+				return "tags && ?", values, true
+			}
+		}
+		return "", nil, false
+	}
+	if filter.Field != "id" && filter.Field != "status" && filter.Field != "worker_id" {
+		return "", nil, false
+	}
+	switch filter.Operator {
+	case "eq":
+		if value, ok := filter.Value.(string); ok {
+			return filter.Field + " = ?", value, true
+		}
+	case "in":
+		if values, ok := tagValues(filter.Value); ok {
+			return filter.Field + " = ANY(?)", values, true
+		}
+	}
+	return "", nil, false
+}
+
+// postgresAnswers reports whether postgresWhere answers every filter, so the
+// database's rows need no further filtering
+func postgresAnswers(filters []job.Filter) bool {
+	for _, filter := range filters {
+		if _, _, ok := postgresCondition(filter); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// decodePostgresJob decodes a job stored as JSON
+func decodePostgresJob(jobID, data string) (*job.Job, error) {
+	var j job.Job
+	if err := json.Unmarshal([]byte(data), &j); err != nil {
+		return nil, fmt.Errorf("failed to decode job %s: %w", jobID, err)
+	}
+	return &j, nil
+}
@@ -0,0 +1,243 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"infinitrain/internal/config"
+	"infinitrain/pkg/job"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestPostgresStore connects to the database named by
+// INFINITRAIN_TEST_POSTGRES_URL and empties the store's tables, skipping the
+// test when no database is given
+func newTestPostgresStore(t *testing.T) *PostgresStore {
+	t.Helper()
+
+	url := os.Getenv("INFINITRAIN_TEST_POSTGRES_URL")
+	if url == "" {
+		t.Skip("INFINITRAIN_TEST_POSTGRES_URL is not set")
+	}
+
+	ctx := context.Background()
+	store, err := NewPostgresStoreFromConfig(ctx, &config.PostgresConfig{URL: url})
+	if err != nil {
+		t.Fatalf("NewPostgresStoreFromConfig() error = %v", err)
+	}
+	t.Cleanup(store.Close)
+
+	if _, err := store.pool.Exec(ctx, "TRUNCATE infinitrain_jobs CASCADE"); err != nil {
+		t.Fatalf("failed to empty the jobs table: %v", err)
+	}
+	return store
+}
+
+func TestPostgresWhere(t *testing.T) {
+	where, args := postgresWhere([]job.Filter{
+		{Field: "status", Operator: "in", Value: []interface{}{"completed", "failed"}},
+		{Field: "priority", Operator: "gte", Value: 5},
+		{Field: "worker_id", Operator: "eq", Value: "worker-1"},
+	})
+
+	if where != " WHERE status = ANY($1) AND worker_id = $2" {
+		t.Errorf("Unexpected WHERE clause %q", where)
+	}
+	if len(args) != 2 || len(args[0].([]string)) != 2 || args[1] != "worker-1" {
+		t.Errorf("Unexpected arguments %v", args)
+	}
+
+	where, args = postgresWhere([]job.Filter{
+		{Field: "tags", Operator: "contains", Value: "nightly"},
+		{Field: "tags", Operator: "hasany", Value: []interface{}{"a", "b"}},
+	})
+	if where != " WHERE tags @> $1 AND tags && $2" || len(args) != 2 || len(args[1].([]string)) != 2 {
+		t.Errorf("Expected tag filters to use the tags column, got %q %v", where, args)
+	}
+
+	where, args = postgresWhere([]job.Filter{{Field: "id", Operator: "in", Value: []interface{}{"job-1", "job-2"}}})
+	if where != " WHERE id = ANY($1)" || len(args) != 1 || len(args[0].([]string)) != 2 {
+		t.Errorf("Expected an id filter to use the primary key, got %q %v", where, args)
+	}
+
+	if where, args := postgresWhere([]job.Filter{{Field: "command", Operator: "eq", Value: "true"}}); where != "" || args != nil {
+		t.Errorf("Expected no WHERE clause for unindexed filters, got %q %v", where, args)
+	}
+
+	// Only filters the columns answer in full let the database page
+	if !postgresAnswers([]job.Filter{{Field: "status", Operator: "eq", Value: "queued"}, {Field: "tags", Operator: "contains", Value: "a"}}) {
+		t.Errorf("Expected status and tag filters to be answered by the database")
+	}
+	if postgresAnswers([]job.Filter{{Field: "status", Operator: "eq", Value: "queued"}, {Field: "priority", Operator: "gte", Value: 5}}) {
+		t.Errorf("Expected a priority filter to need the jobs loaded")
+	}
+}
+
+func TestPostgresStore_ListPage(t *testing.T) {
+	checkListPage(t, newTestPostgresStore(t))
+}
+
+func TestPostgresStore_CRUD(t *testing.T) {
+	ctx := context.Background()
+	store := newTestPostgresStore(t)
+
+	j := &job.Job{ID: "job-1", Type: job.JobTypeCommand, Command: "true", Status: job.JobStatusQueued, Tags: []string{"nightly"}}
+	if err := store.Create(ctx, j); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := store.Create(ctx, j); !job.IsValidationError(err) {
+		t.Errorf("Expected a validation error creating a duplicate, got %v", err)
+	}
+
+	got, err := store.Get(ctx, "job-1")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got.Command != "true" || got.Status != job.JobStatusQueued || len(got.Tags) != 1 {
+		t.Errorf("Expected the stored job back, got %+v", got)
+	}
+
+	got.Priority = 7
+	if err := store.Update(ctx, got); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if err := store.UpdateStatus(ctx, "job-1", job.JobStatusRunning); err != nil {
+		t.Fatalf("UpdateStatus() error = %v", err)
+	}
+	if err := store.UpdateStatus(ctx, "job-1", job.JobStatusQueued); !job.IsValidationError(err) {
+		t.Errorf("Expected an invalid transition to be rejected, got %v", err)
+	}
+
+	running, err := store.List(ctx, job.Filter{Field: "status", Operator: "eq", Value: string(job.JobStatusRunning)})
+	if err != nil || len(running) != 1 || running[0].Priority != 7 || running[0].StartedAt == nil {
+		t.Errorf("Expected the updated job listed as running, got %+v, %v", running, err)
+	}
+
+	if err := store.SaveResult(ctx, &job.JobResult{JobID: "job-1", Status: job.JobStatusCompleted, Output: "ok"}); err != nil {
+		t.Fatalf("SaveResult() error = %v", err)
+	}
+	if result, err := store.GetResult(ctx, "job-1"); err != nil || result.Output != "ok" {
+		t.Errorf("GetResult() = %+v, %v, want the saved result", result, err)
+	}
+
+	if err := store.Delete(ctx, "job-1"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	for name, err := range map[string]error{
+		"Get":          func() error { _, err := store.Get(ctx, "job-1"); return err }(),
+		"GetResult":    func() error { _, err := store.GetResult(ctx, "job-1"); return err }(),
+		"SaveResult":   store.SaveResult(ctx, &job.JobResult{JobID: "job-1"}),
+		"Update":       store.Update(ctx, &job.Job{ID: "job-1"}),
+		"Delete":       store.Delete(ctx, "job-1"),
+		"UpdateStatus": store.UpdateStatus(ctx, "job-1", job.JobStatusRunning),
+	} {
+		if !job.IsJobNotFoundError(err) {
+			t.Errorf("%s: expected a not found error, got %v", name, err)
+		}
+	}
+}
+
+func TestPostgresStore_TagCounts(t *testing.T) {
+	ctx := context.Background()
+	store := newTestPostgresStore(t)
+
+	for _, j := range []*job.Job{
+		{ID: "job-1", Status: job.JobStatusQueued, Tags: []string{"a", "b"}},
+		{ID: "job-2", Status: job.JobStatusQueued, Tags: []string{"a", "a"}},
+		{ID: "job-3", Status: job.JobStatusRunning, Tags: []string{"b"}},
+	} {
+		if err := store.Create(ctx, j); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+	if err := store.UpdateStatus(ctx, "job-1", job.JobStatusCancelled); err != nil {
+		t.Fatalf("UpdateStatus() error = %v", err)
+	}
+
+	counts, err := store.TagCounts(ctx)
+	if err != nil {
+		t.Fatalf("TagCounts() error = %v", err)
+	}
+	want := map[string]map[job.JobStatus]int{
+		"a": {job.JobStatusCancelled: 1, job.JobStatusQueued: 1},
+		"b": {job.JobStatusCancelled: 1, job.JobStatusRunning: 1},
+	}
+	if fmt.Sprint(counts) != fmt.Sprint(want) {
+		t.Errorf("Expected %v, got %v", want, counts)
+	}
+}
+
+func TestPostgresStore_ClaimNext(t *testing.T) {
+	ctx := context.Background()
+	store := newTestPostgresStore(t)
+
+	const jobs = 20
+	for i := 0; i < jobs; i++ {
+		if err := store.Create(ctx, &job.Job{ID: "job-" + string(rune('a'+i)), Status: job.JobStatusQueued, Priority: i % 3}); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	// Concurrent claimers must never get the same job
+	var mutex sync.Mutex
+	claimed := make(map[string]int)
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				j, err := store.ClaimNext(ctx, "worker", nil)
+				if err == job.ErrQueueEmpty {
+					return
+				}
+				if err != nil {
+					t.Errorf("ClaimNext() error = %v", err)
+					return
+				}
+				mutex.Lock()
+				claimed[j.ID]++
+				mutex.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(claimed) != jobs {
+		t.Errorf("Expected all %d jobs claimed, got %d", jobs, len(claimed))
+	}
+	for id, count := range claimed {
+		if count != 1 {
+			t.Errorf("Expected %s to be claimed once, got %d", id, count)
+		}
+	}
+}
+
+func TestPostgresStore_ListenQueued(t *testing.T) {
+	store := newTestPostgresStore(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	queued, err := store.ListenQueued(ctx)
+	if err != nil {
+		t.Fatalf("ListenQueued() error = %v", err)
+	}
+
+	if err := store.Create(ctx, &job.Job{ID: "job-pending", Status: job.JobStatusPending}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := store.UpdateStatus(ctx, "job-pending", job.JobStatusQueued); err != nil {
+		t.Fatalf("UpdateStatus() error = %v", err)
+	}
+
+	select {
+	case id := <-queued:
+		if id != "job-pending" {
+			t.Errorf("Expected a notification for job-pending, got %s", id)
+		}
+	case <-ctx.Done():
+		t.Fatal("Expected a notification once the job was queued")
+	}
+}
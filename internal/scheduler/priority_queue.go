@@ -0,0 +1,489 @@
+package scheduler
+
+import (
+	"container/heap"
+	"context"
+	"infinitrain/internal/clock"
+	"infinitrain/internal/config"
+	"infinitrain/pkg/job"
+	"sort"
+	"sync"
+	"time"
+)
+
+// waitBucketBounds are the upper bounds of the queue wait time histogram
+var waitBucketBounds = []time.Duration{
+	1 * time.Second,
+	5 * time.Second,
+	30 * time.Second,
+	1 * time.Minute,
+	5 * time.Minute,
+	15 * time.Minute,
+	1 * time.Hour,
+}
+
+// PriorityQueue is an in-memory implementation of the job.Queue interface that
+// orders jobs by priority (higher first) and then by creation time. With aging
+// on, a job's effective priority rises the longer it waits so a steady stream
+// of high-priority jobs can't starve it. In fair-share mode tenants take turns
+// instead, and priority only orders each tenant's own jobs.
+type PriorityQueue struct {
+	items       jobHeap
+	index       map[string]*queueItem
+	seq         uint64
+	clock       clock.Clock
+	agingEvery  time.Duration // Wait that earns a queued job one priority point; 0 disables aging
+	ceiling     int           // Highest priority aging raises a job to; 0 for no limit
+	waitBuckets []int         // Cumulative counts per waitBucketBounds entry
+	waitCount   int
+	waitSum     time.Duration
+	mutex       sync.Mutex
+
+	// Fair-share dispatch takes turns between tenants rather than going by
+	// priority alone
+	fair    bool
+	tenants map[string]int    // Queued jobs per tenant
+	served  map[string]uint64 // Turn each tenant with queued jobs was last dispatched on
+	turn    uint64            // Dispatches so far, numbering the turns in served
+}
+
+// queueItem is a job's entry in the heap
+type queueItem struct {
+	job        *job.Job
+	seq        uint64    // Insertion order, used as a final tiebreaker
+	enqueuedAt time.Time // When the job (re-)entered the queue
+	effective  int       // Priority the job is ordered by, after aging
+	index      int       // Position in the heap, maintained by heap.Interface
+}
+
+// NewPriorityQueue creates a new empty priority queue
+func NewPriorityQueue() *PriorityQueue {
+	return NewPriorityQueueWithClock(clock.Real())
+}
+
+// NewPriorityQueueWithClock creates a new empty priority queue that timestamps
+// enqueues and dispatches with the given clock
+func NewPriorityQueueWithClock(c clock.Clock) *PriorityQueue {
+	return &PriorityQueue{
+		index:       make(map[string]*queueItem),
+		clock:       c,
+		tenants:     make(map[string]int),
+		served:      make(map[string]uint64),
+		waitBuckets: make([]int, len(waitBucketBounds)),
+	}
+}
+
+// NewPriorityQueueFromConfig creates a queue using the configured priority
+// aging and queue mode
+func NewPriorityQueueFromConfig(cfg *config.SchedulerConfig) *PriorityQueue {
+	q := NewPriorityQueue()
+	q.SetAging(cfg.PriorityAgingInterval, cfg.PriorityCeiling)
+	q.SetFairShare(cfg.QueueMode == "fair")
+	return q
+}
+
+// SetFairShare turns fair-share dispatch on or off. With it on, tenants with
+// queued jobs take turns: the next job is the best one of the tenant served
+// longest ago, so a tenant flooding the queue can't hold up the others. Jobs
+// without a tenant share one turn.
+func (q *PriorityQueue) SetFairShare(enabled bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.fair = enabled
+}
+
+// SetAging makes queued jobs gain one priority point for every interval they
+// wait, up to ceiling. Jobs submitted above the ceiling keep their priority.
+// A zero interval turns aging off; a zero ceiling leaves it unbounded.
+func (q *PriorityQueue) SetAging(interval time.Duration, ceiling int) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.agingEvery = interval
+	q.ceiling = ceiling
+	q.reorder()
+}
+
+// EffectivePriority returns the priority a queued job is currently ordered by
+func (q *PriorityQueue) EffectivePriority(ctx context.Context, jobID string) (int, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	item, exists := q.index[jobID]
+	if !exists {
+		return 0, job.NewJobNotFoundError(jobID)
+	}
+	return q.effectivePriority(item, q.clock.Now()), nil
+}
+
+// effectivePriority is a job's submitted priority plus what it has earned
+// by waiting until now
+func (q *PriorityQueue) effectivePriority(item *queueItem, now time.Time) int {
+	priority := item.job.Priority
+	if q.agingEvery <= 0 {
+		return priority
+	}
+
+	aged := priority + int(now.Sub(item.enqueuedAt)/q.agingEvery)
+	if q.ceiling > 0 && aged > q.ceiling {
+		aged = max(q.ceiling, priority)
+	}
+	return aged
+}
+
+// reorder brings every job's effective priority up to date and restores heap
+// order. Aging moves jobs at different times, so the heap is rebuilt rather
+// than fixed; callers must hold the mutex.
+func (q *PriorityQueue) reorder() {
+	now := q.clock.Now()
+	for _, item := range q.items {
+		item.effective = q.effectivePriority(item, now)
+	}
+	heap.Init(&q.items)
+}
+
+// age reorders the queue when aging is on; callers must hold the mutex
+func (q *PriorityQueue) age() {
+	if q.agingEvery > 0 {
+		q.reorder()
+	}
+}
+
+// Enqueue adds a job to the queue
+func (q *PriorityQueue) Enqueue(ctx context.Context, j *job.Job) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if _, exists := q.index[j.ID]; exists {
+		return job.NewValidationError("job already queued: " + j.ID)
+	}
+
+	q.seq++
+	item := &queueItem{job: j, seq: q.seq, enqueuedAt: q.clock.Now().UTC(), effective: j.Priority}
+	heap.Push(&q.items, item)
+	q.index[j.ID] = item
+	q.tenants[j.Tenant]++
+
+	return nil
+}
+
+// Dequeue removes and returns the highest-priority job
+func (q *PriorityQueue) Dequeue(ctx context.Context) (*job.Job, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if len(q.items) == 0 {
+		return nil, job.ErrQueueEmpty
+	}
+
+	q.age()
+	item := q.findMatching(matchAll)
+	q.take(item)
+
+	return item.job, nil
+}
+
+// Peek returns the highest-priority job without removing it
+func (q *PriorityQueue) Peek(ctx context.Context) (*job.Job, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if len(q.items) == 0 {
+		return nil, job.ErrQueueEmpty
+	}
+
+	q.age()
+	return q.findMatching(matchAll).job, nil
+}
+
+// PeekMatching returns the highest-priority job accepted by match without
+// removing it, or job.ErrQueueEmpty if no queued job matches
+func (q *PriorityQueue) PeekMatching(ctx context.Context, match func(*job.Job) bool) (*job.Job, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.age()
+	item := q.findMatching(match)
+	if item == nil {
+		return nil, job.ErrQueueEmpty
+	}
+
+	return item.job, nil
+}
+
+// DequeueMatching removes and returns the highest-priority job accepted by
+// match, or job.ErrQueueEmpty if no queued job matches
+func (q *PriorityQueue) DequeueMatching(ctx context.Context, match func(*job.Job) bool) (*job.Job, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.age()
+	item := q.findMatching(match)
+	if item == nil {
+		return nil, job.ErrQueueEmpty
+	}
+
+	q.take(item)
+
+	return item.job, nil
+}
+
+// matchAll accepts every job
+func matchAll(*job.Job) bool { return true }
+
+// take removes a dispatched item from the queue, recording its wait and,
+// for fair-share turns, when its tenant was served; callers must hold the
+// mutex
+func (q *PriorityQueue) take(item *queueItem) {
+	q.remove(item)
+	q.observeWait(q.clock.Now().Sub(item.enqueuedAt))
+
+	if _, queued := q.tenants[item.job.Tenant]; queued {
+		q.turn++
+		q.served[item.job.Tenant] = q.turn
+	}
+}
+
+// remove deletes an item from the heap and index. A tenant left with no
+// queued jobs is forgotten, so it gets the next turn when it submits again;
+// callers must hold the mutex.
+func (q *PriorityQueue) remove(item *queueItem) {
+	heap.Remove(&q.items, item.index)
+	delete(q.index, item.job.ID)
+
+	tenant := item.job.Tenant
+	if q.tenants[tenant]--; q.tenants[tenant] <= 0 {
+		delete(q.tenants, tenant)
+		delete(q.served, tenant)
+	}
+}
+
+// findMatching returns the first item in dispatch order accepted by match;
+// callers must hold the mutex
+func (q *PriorityQueue) findMatching(match func(*job.Job) bool) *queueItem {
+	if len(q.items) == 0 {
+		return nil
+	}
+	// The head is the common case and needs no sorting
+	if !q.fair && match(q.items[0].job) {
+		return q.items[0]
+	}
+
+	var found *queueItem
+	for _, item := range q.ordered() {
+		if !match(item.job) {
+			continue
+		}
+		if !q.fair {
+			return item
+		}
+		// The tenant served longest ago goes next, with its best job; never
+		// served tenants have turn 0 and ties go to the better job
+		if found == nil || q.served[item.job.Tenant] < q.served[found.job.Tenant] {
+			found = item
+		}
+	}
+	return found
+}
+
+// ordered returns the queued items sorted by priority order; callers must
+// hold the mutex
+func (q *PriorityQueue) ordered() jobHeap {
+	ordered := make(jobHeap, len(q.items))
+	copy(ordered, q.items)
+	sort.Slice(ordered, ordered.Less)
+	return ordered
+}
+
+// Size returns the number of jobs in the queue
+func (q *PriorityQueue) Size(ctx context.Context) (int, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return len(q.items), nil
+}
+
+// IsEmpty returns true if the queue is empty
+func (q *PriorityQueue) IsEmpty(ctx context.Context) (bool, error) {
+	size, err := q.Size(ctx)
+	return size == 0, err
+}
+
+// Remove removes a job from the queue by ID
+func (q *PriorityQueue) Remove(ctx context.Context, jobID string) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	item, exists := q.index[jobID]
+	if !exists {
+		return job.NewJobNotFoundError(jobID)
+	}
+
+	q.remove(item)
+
+	return nil
+}
+
+// Reprioritize changes the priority of a queued job and restores heap order
+// so the new priority takes effect on the next dequeue
+func (q *PriorityQueue) Reprioritize(ctx context.Context, jobID string, priority int) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	item, exists := q.index[jobID]
+	if !exists {
+		return job.NewJobNotFoundError(jobID)
+	}
+
+	item.job.Priority = priority
+	item.effective = q.effectivePriority(item, q.clock.Now())
+	heap.Fix(&q.items, item.index)
+
+	return nil
+}
+
+// Position returns a queued job's place in dispatch order, starting at 1
+func (q *PriorityQueue) Position(ctx context.Context, jobID string) (int, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	item, exists := q.index[jobID]
+	if !exists {
+		return 0, job.NewJobNotFoundError(jobID)
+	}
+
+	q.age()
+	if q.fair {
+		return q.fairPosition(item), nil
+	}
+
+	position := 1
+	for i := range q.items {
+		if q.items.Less(i, item.index) {
+			position++
+		}
+	}
+	return position, nil
+}
+
+// fairPosition returns an item's place in fair-share dispatch order, where
+// each round gives every tenant with jobs left one turn, in the order their
+// turns come up; callers must hold the mutex
+func (q *PriorityQueue) fairPosition(item *queueItem) int {
+	// Each tenant's jobs in priority order, and the tenants in the order
+	// their first turn comes up
+	var tenants []string
+	jobs := make(map[string][]*queueItem)
+	for _, queued := range q.ordered() {
+		tenant := queued.job.Tenant
+		if _, seen := jobs[tenant]; !seen {
+			tenants = append(tenants, tenant)
+		}
+		jobs[tenant] = append(jobs[tenant], queued)
+	}
+	sort.SliceStable(tenants, func(i, j int) bool {
+		return q.served[tenants[i]] < q.served[tenants[j]]
+	})
+
+	// The item goes out in the round after its tenant's better jobs, behind
+	// every earlier round's jobs and the tenants ahead of its own this round
+	round := 0
+	for jobs[item.job.Tenant][round] != item {
+		round++
+	}
+	position := 1
+	ahead := true
+	for _, tenant := range tenants {
+		if tenant == item.job.Tenant {
+			ahead = false
+		}
+		position += min(len(jobs[tenant]), round)
+		if ahead && len(jobs[tenant]) > round {
+			position++
+		}
+	}
+	return position
+}
+
+// Stats returns the queue depth, oldest waiting job and the histogram of how
+// long dequeued jobs waited
+func (q *PriorityQueue) Stats(ctx context.Context) (*job.QueueStats, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	stats := &job.QueueStats{
+		Depth:       len(q.items),
+		WaitBuckets: make([]job.HistogramBucket, len(waitBucketBounds)),
+		WaitCount:   q.waitCount,
+		WaitSum:     q.waitSum,
+	}
+
+	for i, bound := range waitBucketBounds {
+		stats.WaitBuckets[i] = job.HistogramBucket{UpperBound: bound, Count: q.waitBuckets[i]}
+	}
+
+	if len(q.tenants) > 0 {
+		stats.TenantDepths = make(map[string]int, len(q.tenants))
+		for tenant, depth := range q.tenants {
+			stats.TenantDepths[tenant] = depth
+		}
+	}
+
+	for _, item := range q.items {
+		if stats.OldestJobID == "" || item.enqueuedAt.Before(stats.OldestEnqueuedAt) {
+			stats.OldestJobID = item.job.ID
+			stats.OldestEnqueuedAt = item.enqueuedAt
+		}
+	}
+
+	return stats, nil
+}
+
+// observeWait records a dispatched job's time in the queue; callers must hold the mutex
+func (q *PriorityQueue) observeWait(wait time.Duration) {
+	q.waitCount++
+	q.waitSum += wait
+	for i, bound := range waitBucketBounds {
+		if wait <= bound {
+			q.waitBuckets[i]++
+		}
+	}
+}
+
+// jobHeap implements heap.Interface over queue items
+type jobHeap []*queueItem
+
+func (h jobHeap) Len() int { return len(h) }
+
+func (h jobHeap) Less(i, j int) bool {
+	a, b := h[i], h[j]
+	if a.effective != b.effective {
+		return a.effective > b.effective
+	}
+	if !a.job.CreatedAt.Equal(b.job.CreatedAt) {
+		return a.job.CreatedAt.Before(b.job.CreatedAt)
+	}
+	return a.seq < b.seq
+}
+
+func (h jobHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *jobHeap) Push(x interface{}) {
+	item := x.(*queueItem)
+	item.index = len(*h)
+	*h = append(*h, item)
+}
+
+func (h *jobHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	item.index = -1
+	*h = old[:n-1]
+	return item
+}
@@ -0,0 +1,284 @@
+package scheduler
+
+import (
+	"context"
+	"infinitrain/internal/clock"
+	"infinitrain/pkg/job"
+	"sync"
+	"testing"
+	"time"
+)
+
+// enqueueJobs adds jobs with the given priorities to the queue in order
+func enqueueJobs(t *testing.T, q *PriorityQueue, priorities ...int) []*job.Job {
+	t.Helper()
+
+	base := time.Now()
+	jobs := make([]*job.Job, 0, len(priorities))
+	for i, priority := range priorities {
+		j := &job.Job{
+			ID:        job.GenerateJobID(),
+			Priority:  priority,
+			CreatedAt: base.Add(time.Duration(i) * time.Millisecond),
+		}
+		if err := q.Enqueue(context.Background(), j); err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+		jobs = append(jobs, j)
+	}
+	return jobs
+}
+
+func TestPriorityQueue_Ordering(t *testing.T) {
+	q := NewPriorityQueue()
+	ctx := context.Background()
+
+	if _, err := q.Dequeue(ctx); err != job.ErrQueueEmpty {
+		t.Errorf("Expected ErrQueueEmpty from an empty queue, got %v", err)
+	}
+
+	// Higher priorities first, and creation order within a priority
+	jobs := enqueueJobs(t, q, 1, 5, 1, 5)
+	want := []string{jobs[1].ID, jobs[3].ID, jobs[0].ID, jobs[2].ID}
+
+	if head, err := q.Peek(ctx); err != nil || head.ID != want[0] {
+		t.Errorf("Peek() = %v, %v, want %s", head, err, want[0])
+	}
+	for _, id := range want {
+		j, err := q.Dequeue(ctx)
+		if err != nil {
+			t.Fatalf("Dequeue() error = %v", err)
+		}
+		if j.ID != id {
+			t.Errorf("Expected %s, got %s", id, j.ID)
+		}
+	}
+
+	if empty, _ := q.IsEmpty(ctx); !empty {
+		t.Errorf("Expected the queue to be empty")
+	}
+}
+
+func TestPriorityQueue_ConcurrentDequeue(t *testing.T) {
+	q := NewPriorityQueue()
+	ctx := context.Background()
+	priorities := make([]int, 200)
+	for i := range priorities {
+		priorities[i] = i % 7
+	}
+	enqueueJobs(t, q, priorities...)
+
+	// Every job is handed to exactly one of the competing consumers
+	var mutex sync.Mutex
+	seen := make(map[string]int)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				j, err := q.Dequeue(ctx)
+				if err == job.ErrQueueEmpty {
+					return
+				}
+				if err != nil {
+					t.Errorf("Dequeue() error = %v", err)
+					return
+				}
+				mutex.Lock()
+				seen[j.ID]++
+				mutex.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(seen) != len(priorities) {
+		t.Errorf("Expected %d distinct jobs, got %d", len(priorities), len(seen))
+	}
+	for id, count := range seen {
+		if count != 1 {
+			t.Errorf("Expected %s to be dequeued once, got %d", id, count)
+		}
+	}
+}
+
+func TestPriorityQueue_Reprioritize(t *testing.T) {
+	q := NewPriorityQueue()
+	ctx := context.Background()
+	jobs := enqueueJobs(t, q, 5, 3, 1)
+
+	if err := q.Reprioritize(ctx, jobs[2].ID, 10); err != nil {
+		t.Fatalf("Reprioritize() error = %v", err)
+	}
+
+	want := []string{jobs[2].ID, jobs[0].ID, jobs[1].ID}
+	for _, id := range want {
+		j, err := q.Dequeue(ctx)
+		if err != nil {
+			t.Fatalf("Dequeue() error = %v", err)
+		}
+		if j.ID != id {
+			t.Errorf("Expected %s, got %s", id, j.ID)
+		}
+	}
+
+	if err := q.Reprioritize(ctx, "job-missing", 1); !job.IsJobNotFoundError(err) {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+}
+
+func TestPriorityQueue_Aging(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	q := NewPriorityQueueWithClock(fake)
+	q.SetAging(time.Minute, 8)
+
+	low := enqueueJobs(t, q, 1)[0]
+	fake.Advance(3*time.Minute + 30*time.Second)
+
+	if priority, err := q.EffectivePriority(ctx, low.ID); err != nil || priority != 4 {
+		t.Errorf("Expected effective priority 4 after 3m30s, got %d (%v)", priority, err)
+	}
+
+	// Past the ceiling, the low job ties with a fresh ceiling-priority job and
+	// wins on age; a job submitted above the ceiling still goes first
+	fake.Advance(time.Hour)
+	jobs := enqueueJobs(t, q, 8, 20)
+	if priority, _ := q.EffectivePriority(ctx, low.ID); priority != 8 {
+		t.Errorf("Expected effective priority capped at 8, got %d", priority)
+	}
+	if priority, _ := q.EffectivePriority(ctx, jobs[1].ID); priority != 20 {
+		t.Errorf("Expected a job above the ceiling to keep priority 20, got %d", priority)
+	}
+
+	for _, want := range []*job.Job{jobs[1], low, jobs[0]} {
+		j, err := q.Dequeue(ctx)
+		if err != nil {
+			t.Fatalf("Dequeue() error = %v", err)
+		}
+		if j.ID != want.ID {
+			t.Errorf("Expected %s (priority %d), got %s (priority %d)", want.ID, want.Priority, j.ID, j.Priority)
+		}
+	}
+	if low.Priority != 1 {
+		t.Errorf("Expected the submitted priority to stay 1, got %d", low.Priority)
+	}
+
+	if _, err := q.EffectivePriority(ctx, low.ID); !job.IsJobNotFoundError(err) {
+		t.Errorf("Expected not found error for a dequeued job, got %v", err)
+	}
+}
+
+func TestPriorityQueue_FairShare(t *testing.T) {
+	ctx := context.Background()
+	q := NewPriorityQueue()
+	q.SetFairShare(true)
+
+	// A floods the queue with high-priority jobs before B and C submit
+	base := time.Now()
+	enqueue := func(id, tenant string, priority int) {
+		t.Helper()
+		j := &job.Job{ID: id, Tenant: tenant, Priority: priority, CreatedAt: base.Add(time.Duration(q.seq) * time.Millisecond)}
+		if err := q.Enqueue(ctx, j); err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+	}
+	enqueue("a1", "a", 9)
+	enqueue("a2", "a", 9)
+	enqueue("a3", "a", 9)
+	enqueue("a4", "a", 9)
+	enqueue("b-low", "b", 1)
+	enqueue("b-high", "b", 5)
+	enqueue("c1", "c", 1)
+
+	if stats, _ := q.Stats(ctx); stats.TenantDepths["a"] != 4 || stats.TenantDepths["b"] != 2 || stats.TenantDepths["c"] != 1 {
+		t.Errorf("Expected tenant depths a=4 b=2 c=1, got %v", stats.TenantDepths)
+	}
+
+	// Tenants take turns, each sending its best job
+	want := []string{"a1", "b-high", "c1", "a2", "b-low", "a3", "a4"}
+	for i, id := range want {
+		if position, err := q.Position(ctx, id); err != nil || position != i+1 {
+			t.Errorf("Position(%s) = %d, %v, want %d", id, position, err, i+1)
+		}
+	}
+	if head, err := q.Peek(ctx); err != nil || head.ID != want[0] {
+		t.Errorf("Peek() = %v, %v, want %s", head, err, want[0])
+	}
+
+	dequeue := func(want ...string) {
+		t.Helper()
+		for _, id := range want {
+			j, err := q.Dequeue(ctx)
+			if err != nil {
+				t.Fatalf("Dequeue() error = %v", err)
+			}
+			if j.ID != id {
+				t.Errorf("Expected %s, got %s", id, j.ID)
+			}
+		}
+	}
+	dequeue(want[:4]...)
+
+	// C ran out of jobs, so when it submits again it gets the next turn
+	enqueue("c2", "c", 1)
+	dequeue("c2", "b-low", "a3", "a4")
+
+	if stats, _ := q.Stats(ctx); stats.Depth != 0 || len(stats.TenantDepths) != 0 {
+		t.Errorf("Expected an empty queue, got depth %d by tenant %v", stats.Depth, stats.TenantDepths)
+	}
+}
+
+func TestPriorityQueue_Stats(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(start)
+	q := NewPriorityQueueWithClock(fake)
+
+	jobs := enqueueJobs(t, q, 1)
+	fake.Advance(2 * time.Minute)
+	jobs = append(jobs, enqueueJobs(t, q, 5, 3)...)
+	fake.Advance(10 * time.Second)
+
+	stats, err := q.Stats(ctx)
+	if err != nil {
+		t.Fatalf("Stats() error = %v", err)
+	}
+	if stats.Depth != 3 {
+		t.Errorf("Expected depth 3, got %d", stats.Depth)
+	}
+	if stats.OldestJobID != jobs[0].ID {
+		t.Errorf("Expected oldest job %s, got %s", jobs[0].ID, stats.OldestJobID)
+	}
+	if age := stats.OldestAge(fake.Now()); age != 2*time.Minute+10*time.Second {
+		t.Errorf("Expected oldest age 2m10s, got %v", age)
+	}
+
+	// Dequeue order is by priority: waits of 10s, 10s, then 2m10s
+	for range jobs {
+		if _, err := q.Dequeue(ctx); err != nil {
+			t.Fatalf("Dequeue() error = %v", err)
+		}
+	}
+
+	stats, _ = q.Stats(ctx)
+	if stats.Depth != 0 || stats.OldestJobID != "" {
+		t.Errorf("Expected empty queue stats, got depth %d oldest %q", stats.Depth, stats.OldestJobID)
+	}
+	if stats.WaitCount != 3 || stats.WaitSum != 2*time.Minute+30*time.Second {
+		t.Errorf("Expected 3 waits totalling 2m30s, got %d totalling %v", stats.WaitCount, stats.WaitSum)
+	}
+
+	want := map[time.Duration]int{
+		5 * time.Second:  0,
+		30 * time.Second: 2,
+		time.Minute:      2,
+		5 * time.Minute:  3,
+	}
+	for _, bucket := range stats.WaitBuckets {
+		if count, ok := want[bucket.UpperBound]; ok && bucket.Count != count {
+			t.Errorf("Expected %d waits <= %v, got %d", count, bucket.UpperBound, bucket.Count)
+		}
+	}
+}
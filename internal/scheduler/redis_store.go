@@ -0,0 +1,729 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"infinitrain/internal/config"
+	"infinitrain/pkg/job"
+	"slices"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisKeyPrefix namespaces every key the store writes
+const redisKeyPrefix = "infinitrain:"
+
+// redisMaxTxRetries bounds how often a write is retried when another client
+// changes the job between reading and writing it
+const redisMaxTxRetries = 10
+
+// Fields of a job's hash. The job itself is kept as JSON; status, worker and
+// tags are duplicated so index maintenance can read them without decoding
+// it, and the creation time so listings can be ordered without decoding it.
+const (
+	redisFieldData      = "data"
+	redisFieldStatus    = "status"
+	redisFieldWorkerID  = "worker_id"
+	redisFieldTags      = "tags"
+	redisFieldCreatedAt = "created_at"
+)
+
+// RedisStore is a Redis implementation of the job.Store interface. Each job
+// is a hash, and sets index job IDs overall, by status, by worker and by tag
+// and status so the common listings and counts don't scan every job.
+type RedisStore struct {
+	client *redis.Client
+	events *StatusBroker
+}
+
+// NewRedisStore creates a store using an existing Redis client
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{client: client, events: NewStatusBroker()}
+}
+
+// NewRedisStoreFromConfig connects to the configured Redis server. The
+// password, database and pool size override those in the URL when set.
+func NewRedisStoreFromConfig(ctx context.Context, cfg *config.RedisConfig) (*RedisStore, error) {
+	options, err := redis.ParseURL(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis URL: %w", err)
+	}
+	if cfg.Password != "" {
+		options.Password = cfg.Password
+	}
+	if cfg.DB != 0 {
+		options.DB = cfg.DB
+	}
+	if cfg.PoolSize > 0 {
+		options.PoolSize = cfg.PoolSize
+	}
+
+	client := redis.NewClient(options)
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+
+	return NewRedisStore(client), nil
+}
+
+// Close closes the connection to Redis
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
+
+// Create stores a new job
+func (s *RedisStore) Create(ctx context.Context, j *job.Job) error {
+	key := redisJobKey(j.ID)
+
+	return s.transaction(ctx, key, func(tx *redis.Tx) error {
+		exists, err := tx.Exists(ctx, key).Result()
+		if err != nil {
+			return err
+		}
+		if exists > 0 {
+			return job.NewValidationError("job already exists: " + j.ID)
+		}
+
+		jobCopy := *j
+		jobCopy.NormalizeTimestamps()
+		if err := s.write(ctx, tx, &jobCopy, nil); err != nil {
+			return err
+		}
+		s.events.Publish(&jobCopy)
+		return nil
+	})
+}
+
+// Get retrieves a job by ID
+func (s *RedisStore) Get(ctx context.Context, jobID string) (*job.Job, error) {
+	data, err := s.client.HGet(ctx, redisJobKey(jobID), redisFieldData).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, job.NewJobNotFoundError(jobID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Every read decodes a fresh job, so callers can't mutate stored state
+	return decodeRedisJob(jobID, data)
+}
+
+// Ping checks that Redis can be reached
+func (s *RedisStore) Ping(ctx context.Context) error {
+	return s.client.Ping(ctx).Err()
+}
+
+// Exists reports whether a job is stored without loading it
+func (s *RedisStore) Exists(ctx context.Context, jobID string) (bool, error) {
+	n, err := s.client.Exists(ctx, redisJobKey(jobID)).Result()
+	return n > 0, err
+}
+
+// Update updates an existing job
+func (s *RedisStore) Update(ctx context.Context, j *job.Job) error {
+	key := redisJobKey(j.ID)
+
+	return s.transaction(ctx, key, func(tx *redis.Tx) error {
+		previous, err := s.indexed(ctx, tx, j.ID)
+		if err != nil {
+			return err
+		}
+
+		jobCopy := *j
+		jobCopy.NormalizeTimestamps()
+		if err := s.write(ctx, tx, &jobCopy, previous); err != nil {
+			return err
+		}
+		s.events.Publish(&jobCopy)
+		return nil
+	})
+}
+
+// Delete removes a job from storage
+func (s *RedisStore) Delete(ctx context.Context, jobID string) error {
+	key := redisJobKey(jobID)
+
+	return s.transaction(ctx, key, func(tx *redis.Tx) error {
+		previous, err := s.indexed(ctx, tx, jobID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Del(ctx, key, redisResultKey(jobID))
+			unindexRedisJob(ctx, pipe, jobID, previous)
+			return nil
+		})
+		return err
+	})
+}
+
+// List returns jobs with optional filtering. Filters on id read just the
+// named jobs and equality filters on status and worker_id are answered from
+// the index sets; every other filter is applied to the jobs those return.
+func (s *RedisStore) List(ctx context.Context, filters ...job.Filter) ([]*job.Job, error) {
+	ids, err := s.candidateIDs(ctx, filters)
+	if err != nil {
+		return nil, err
+	}
+
+	jobs, err := s.load(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*job.Job
+	for _, j := range jobs {
+		if matchesFilters(j, filters) {
+			result = append(result, j)
+		}
+	}
+	return result, nil
+}
+
+// candidateIDs returns the IDs of the jobs that may match the filters. Jobs
+// named by id filters are the candidates when there are any; otherwise the
+// index sets narrow them.
+func (s *RedisStore) candidateIDs(ctx context.Context, filters []job.Filter) ([]string, error) {
+	if ids, ok := redisIDs(filters); ok {
+		return ids, nil
+	}
+	indexes, _ := redisIndexes(filters)
+	return s.indexedIDs(ctx, indexes)
+}
+
+// redisIDs returns the IDs every eq or in filter on id names, if there are
+// such filters
+func redisIDs(filters []job.Filter) ([]string, bool) {
+	var ids []string
+	found := false
+	for _, filter := range filters {
+		named, ok := redisIDFilter(filter)
+		if !ok {
+			continue
+		}
+
+		seen := make(map[string]bool, len(named))
+		var kept []string
+		for _, id := range named {
+			if seen[id] || (found && !slices.Contains(ids, id)) {
+				continue
+			}
+			seen[id] = true
+			kept = append(kept, id)
+		}
+		ids, found = kept, true
+	}
+	return ids, found
+}
+
+// redisIDFilter returns the IDs an eq or in filter on id names
+func redisIDFilter(filter job.Filter) ([]string, bool) {
+	if filter.Field != "id" {
+		return nil, false
+	}
+	switch filter.Operator {
+	case "eq":
+		if id, ok := filter.Value.(string); ok {
+			return []string{id}, true
+		}
+	case "in":
+		return tagValues(filter.Value)
+	}
+	return nil, false
+}
+
+// redisIndexes returns the index sets answering the filters' equality
+// matches on status and worker_id, and whether they and the id filters
+// answer every filter
+func redisIndexes(filters []job.Filter) ([]string, bool) {
+	var indexes []string
+	all := true
+	for _, filter := range filters {
+		if _, ok := redisIDFilter(filter); ok {
+			continue
+		}
+		value, ok := filter.Value.(string)
+		switch {
+		case filter.Operator == "eq" && ok && filter.Field == "status":
+			indexes = append(indexes, redisStatusKey(job.JobStatus(value)))
+		case filter.Operator == "eq" && ok && filter.Field == "worker_id":
+			indexes = append(indexes, redisWorkerKey(value))
+		default:
+			all = false
+		}
+	}
+	return indexes, all
+}
+
+// indexedIDs returns the IDs of the jobs in every one of the index sets, or
+// of all jobs when there are none
+func (s *RedisStore) indexedIDs(ctx context.Context, indexes []string) ([]string, error) {
+	if len(indexes) > 0 {
+		return s.client.SInter(ctx, indexes...).Result()
+	}
+	return s.client.SMembers(ctx, redisKeyPrefix+"jobs").Result()
+}
+
+// load reads and decodes the jobs with the given IDs, in order, leaving out
+// any deleted since their IDs were read
+func (s *RedisStore) load(ctx context.Context, ids []string) ([]*job.Job, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	commands := make([]*redis.StringCmd, len(ids))
+	_, err := s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, id := range ids {
+			commands[i] = pipe.HGet(ctx, redisJobKey(id), redisFieldData)
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+
+	var result []*job.Job
+	for i, command := range commands {
+		data, err := command.Result()
+		if errors.Is(err, redis.Nil) {
+			continue // Deleted since the index was read
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		j, err := decodeRedisJob(ids[i], data)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, j)
+	}
+
+	return result, nil
+}
+
+// ListPage returns one page of the jobs matching the options' filters. When
+// the id filters or the index sets answer every filter, the matches are ordered by the
+// creation times kept beside them and only the page's jobs are loaded; other
+// filters need every match loaded.
+func (s *RedisStore) ListPage(ctx context.Context, opts job.ListOptions) (*job.JobPage, error) {
+	// Named jobs are read without consulting the index sets, so those must
+	// then be checked against each job
+	indexes, all := redisIndexes(opts.Filters)
+	if _, named := redisIDs(opts.Filters); !all || (named && len(indexes) > 0) {
+		jobs, err := s.List(ctx, opts.Filters...)
+		if err != nil {
+			return nil, err
+		}
+		return job.Paginate(jobs, opts)
+	}
+
+	ids, err := s.candidateIDs(ctx, opts.Filters)
+	if err != nil {
+		return nil, err
+	}
+
+	matching, err := s.creationOrder(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := job.Paginate(matching, opts)
+	if err != nil {
+		return nil, err
+	}
+	pageIDs := make([]string, len(page.Jobs))
+	for i, j := range page.Jobs {
+		pageIDs[i] = j.ID
+	}
+	if page.Jobs, err = s.load(ctx, pageIDs); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// creationOrder returns placeholder jobs carrying just the ID and creation
+// time of each job that still exists, enough for job.Paginate to order them.
+// Jobs stored before the creation time was kept beside them are decoded.
+func (s *RedisStore) creationOrder(ctx context.Context, ids []string) ([]*job.Job, error) {
+	commands := make([]*redis.SliceCmd, len(ids))
+	_, err := s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, id := range ids {
+			commands[i] = pipe.HMGet(ctx, redisJobKey(id), redisFieldStatus, redisFieldCreatedAt)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	placeholders := make([]*job.Job, 0, len(ids))
+	var undated []string
+	for i, command := range commands {
+		values := command.Val()
+		if values[0] == nil {
+			continue // Deleted since the index was read
+		}
+		createdAt, ok := values[1].(string)
+		if !ok {
+			undated = append(undated, ids[i])
+			continue
+		}
+		at, err := time.Parse(time.RFC3339Nano, createdAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode creation time of job %s: %w", ids[i], err)
+		}
+		placeholders = append(placeholders, &job.Job{ID: ids[i], CreatedAt: at})
+	}
+
+	older, err := s.load(ctx, undated)
+	if err != nil {
+		return nil, err
+	}
+	for _, j := range older {
+		placeholders = append(placeholders, &job.Job{ID: j.ID, CreatedAt: j.CreatedAt})
+	}
+	return placeholders, nil
+}
+
+// StatusCounts returns how many jobs are in each status from the sizes of
+// the status index sets
+func (s *RedisStore) StatusCounts(ctx context.Context) (map[job.JobStatus]int, error) {
+	commands := make([]*redis.IntCmd, len(job.JobStatuses))
+	_, err := s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, status := range job.JobStatuses {
+			commands[i] = pipe.SCard(ctx, redisStatusKey(status))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[job.JobStatus]int, len(commands))
+	for i, command := range commands {
+		counts[job.JobStatuses[i]] = int(command.Val())
+	}
+	return counts, nil
+}
+
+// TagCounts returns how many jobs carry each tag, by status, from the sizes
+// of the tag index sets. Tags no job carries any more are left out.
+func (s *RedisStore) TagCounts(ctx context.Context) (map[string]map[job.JobStatus]int, error) {
+	tags, err := s.client.SMembers(ctx, redisKeyPrefix+"tags").Result()
+	if err != nil {
+		return nil, err
+	}
+
+	commands := make([][]*redis.IntCmd, len(tags))
+	_, err = s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, tag := range tags {
+			commands[i] = make([]*redis.IntCmd, len(job.JobStatuses))
+			for k, status := range job.JobStatuses {
+				commands[i][k] = pipe.SCard(ctx, redisTagKey(tag, status))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]map[job.JobStatus]int)
+	for i, tag := range tags {
+		for k, command := range commands[i] {
+			if n := command.Val(); n > 0 {
+				if counts[tag] == nil {
+					counts[tag] = make(map[job.JobStatus]int)
+				}
+				counts[tag][job.JobStatuses[k]] = int(n)
+			}
+		}
+	}
+	return counts, nil
+}
+
+// UpdateStatus updates the status of a job
+func (s *RedisStore) UpdateStatus(ctx context.Context, jobID string, status job.JobStatus) error {
+	key := redisJobKey(jobID)
+
+	return s.transaction(ctx, key, func(tx *redis.Tx) error {
+		data, err := tx.HGet(ctx, key, redisFieldData).Result()
+		if errors.Is(err, redis.Nil) {
+			return job.NewJobNotFoundError(jobID)
+		}
+		if err != nil {
+			return err
+		}
+
+		j, err := decodeRedisJob(jobID, data)
+		if err != nil {
+			return err
+		}
+		previous := &redisIndexEntry{status: j.Status, workerID: j.WorkerID, tags: j.Tags}
+
+		// Update the status and timestamps
+		if err := j.UpdateStatus(status); err != nil {
+			return err
+		}
+		if err := s.write(ctx, tx, j, previous); err != nil {
+			return err
+		}
+		s.events.Publish(j)
+		return nil
+	})
+}
+
+// AssignWorker moves a queued job to running on the given worker in one
+// transaction, so a cancellation landing at the same time can't be
+// overwritten. A job that is no longer queued gives a conflict error.
+func (s *RedisStore) AssignWorker(ctx context.Context, jobID, workerID string) (*job.Job, error) {
+	key := redisJobKey(jobID)
+
+	var assigned *job.Job
+	err := s.transaction(ctx, key, func(tx *redis.Tx) error {
+		data, err := tx.HGet(ctx, key, redisFieldData).Result()
+		if errors.Is(err, redis.Nil) {
+			return job.NewJobNotFoundError(jobID)
+		}
+		if err != nil {
+			return err
+		}
+
+		j, err := decodeRedisJob(jobID, data)
+		if err != nil {
+			return err
+		}
+		if j.Status != job.JobStatusQueued {
+			return job.NewConflictError(fmt.Sprintf("job %s is no longer queued (status %s)", jobID, j.Status))
+		}
+		previous := &redisIndexEntry{status: j.Status, workerID: j.WorkerID, tags: j.Tags}
+
+		j.WorkerID = workerID
+		if err := j.UpdateStatus(job.JobStatusRunning); err != nil {
+			return err
+		}
+		if err := s.write(ctx, tx, j, previous); err != nil {
+			return err
+		}
+		s.events.Publish(j)
+		assigned = j
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return assigned, nil
+}
+
+// ExtendTimeout adds extra to a running job's timeout in one transaction,
+// so it can't overwrite a change made since the job was read. A job that is
+// no longer running gives a conflict error.
+func (s *RedisStore) ExtendTimeout(ctx context.Context, jobID string, extra time.Duration) (*job.Job, error) {
+	key := redisJobKey(jobID)
+
+	var extended *job.Job
+	err := s.transaction(ctx, key, func(tx *redis.Tx) error {
+		data, err := tx.HGet(ctx, key, redisFieldData).Result()
+		if errors.Is(err, redis.Nil) {
+			return job.NewJobNotFoundError(jobID)
+		}
+		if err != nil {
+			return err
+		}
+
+		j, err := decodeRedisJob(jobID, data)
+		if err != nil {
+			return err
+		}
+		if !j.IsRunning() {
+			return job.NewConflictError(fmt.Sprintf("job %s is no longer running (status %s)", jobID, j.Status))
+		}
+		previous := &redisIndexEntry{status: j.Status, workerID: j.WorkerID, tags: j.Tags}
+
+		j.Timeout += extra
+		if err := s.write(ctx, tx, j, previous); err != nil {
+			return err
+		}
+		s.events.Publish(j)
+		extended = j
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return extended, nil
+}
+
+// WatchStatus returns a channel receiving the job after each change this
+// store makes to it. Changes written by other processes sharing the Redis
+// server are not seen. Call cancel to stop watching.
+func (s *RedisStore) WatchStatus(jobID string) (<-chan *job.Job, func()) {
+	return s.events.Subscribe(jobID)
+}
+
+// SaveResult stores the result of a job's latest run as JSON next to the job
+func (s *RedisStore) SaveResult(ctx context.Context, result *job.JobResult) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to encode result of job %s: %w", result.JobID, err)
+	}
+
+	key := redisJobKey(result.JobID)
+	return s.transaction(ctx, key, func(tx *redis.Tx) error {
+		exists, err := tx.Exists(ctx, key).Result()
+		if err != nil {
+			return err
+		}
+		if exists == 0 {
+			return job.NewJobNotFoundError(result.JobID)
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, redisResultKey(result.JobID), data, 0)
+			return nil
+		})
+		return err
+	})
+}
+
+// GetResult retrieves the stored result of a job
+func (s *RedisStore) GetResult(ctx context.Context, jobID string) (*job.JobResult, error) {
+	data, err := s.client.Get(ctx, redisResultKey(jobID)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, job.NewJobNotFoundError(jobID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var result job.JobResult
+	if err := json.Unmarshal([]byte(data), &result); err != nil {
+		return nil, fmt.Errorf("failed to decode result of job %s: %w", jobID, err)
+	}
+	return &result, nil
+}
+
+// redisIndexEntry is the indexed state of a stored job
+type redisIndexEntry struct {
+	status   job.JobStatus
+	workerID string
+	tags     []string
+}
+
+// indexed returns the indexed state of a stored job, or a not found error
+func (s *RedisStore) indexed(ctx context.Context, tx *redis.Tx, jobID string) (*redisIndexEntry, error) {
+	values, err := tx.HMGet(ctx, redisJobKey(jobID), redisFieldStatus, redisFieldWorkerID, redisFieldTags).Result()
+	if err != nil {
+		return nil, err
+	}
+	if values[0] == nil {
+		return nil, job.NewJobNotFoundError(jobID)
+	}
+
+	entry := &redisIndexEntry{status: job.JobStatus(values[0].(string))}
+	if workerID, ok := values[1].(string); ok {
+		entry.workerID = workerID
+	}
+	if tags, ok := values[2].(string); ok {
+		if err := json.Unmarshal([]byte(tags), &entry.tags); err != nil {
+			return nil, fmt.Errorf("failed to decode tags of job %s: %w", jobID, err)
+		}
+	}
+	return entry, nil
+}
+
+// write stores a job and moves it between index sets if its status, worker
+// or tags changed from previous, which is nil for a new job
+func (s *RedisStore) write(ctx context.Context, tx *redis.Tx, j *job.Job, previous *redisIndexEntry) error {
+	data, err := json.Marshal(j)
+	if err != nil {
+		return fmt.Errorf("failed to encode job %s: %w", j.ID, err)
+	}
+	tags, err := json.Marshal(j.Tags)
+	if err != nil {
+		return fmt.Errorf("failed to encode tags of job %s: %w", j.ID, err)
+	}
+
+	_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, redisJobKey(j.ID),
+			redisFieldData, data,
+			redisFieldStatus, string(j.Status),
+			redisFieldWorkerID, j.WorkerID,
+			redisFieldTags, tags,
+			redisFieldCreatedAt, j.CreatedAt.Format(time.RFC3339Nano),
+		)
+		if previous != nil {
+			unindexRedisJob(ctx, pipe, j.ID, previous)
+		}
+		pipe.SAdd(ctx, redisKeyPrefix+"jobs", j.ID)
+		pipe.SAdd(ctx, redisStatusKey(j.Status), j.ID)
+		if j.WorkerID != "" {
+			pipe.SAdd(ctx, redisWorkerKey(j.WorkerID), j.ID)
+		}
+		for _, tag := range j.Tags {
+			pipe.SAdd(ctx, redisKeyPrefix+"tags", tag)
+			pipe.SAdd(ctx, redisTagKey(tag, j.Status), j.ID)
+		}
+		return nil
+	})
+	return err
+}
+
+// transaction runs fn with the job's key watched, retrying when another
+// client writes the job first
+func (s *RedisStore) transaction(ctx context.Context, key string, fn func(tx *redis.Tx) error) error {
+	for i := 0; i < redisMaxTxRetries; i++ {
+		err := s.client.Watch(ctx, fn, key)
+		if !errors.Is(err, redis.TxFailedErr) {
+			return err
+		}
+	}
+	return fmt.Errorf("failed to write %s: too many concurrent updates", key)
+}
+
+// unindexRedisJob removes a job from the index sets it was in
+func unindexRedisJob(ctx context.Context, pipe redis.Pipeliner, jobID string, entry *redisIndexEntry) {
+	pipe.SRem(ctx, redisKeyPrefix+"jobs", jobID)
+	pipe.SRem(ctx, redisStatusKey(entry.status), jobID)
+	if entry.workerID != "" {
+		pipe.SRem(ctx, redisWorkerKey(entry.workerID), jobID)
+	}
+	for _, tag := range entry.tags {
+		pipe.SRem(ctx, redisTagKey(tag, entry.status), jobID)
+	}
+}
+
+// decodeRedisJob decodes a job stored as JSON
+func decodeRedisJob(jobID, data string) (*job.Job, error) {
+	var j job.Job
+	if err := json.Unmarshal([]byte(data), &j); err != nil {
+		return nil, fmt.Errorf("failed to decode job %s: %w", jobID, err)
+	}
+	return &j, nil
+}
+
+func redisJobKey(jobID string) string {
+	return redisKeyPrefix + "job:" + jobID
+}
+
+func redisResultKey(jobID string) string {
+	return redisJobKey(jobID) + ":result"
+}
+
+func redisStatusKey(status job.JobStatus) string {
+	return redisKeyPrefix + "jobs:status:" + string(status)
+}
+
+func redisTagKey(tag string, status job.JobStatus) string {
+	return redisKeyPrefix + "jobs:tag:" + tag + ":" + string(status)
+}
+
+func redisWorkerKey(workerID string) string {
+	return redisKeyPrefix + "jobs:worker:" + workerID
+}
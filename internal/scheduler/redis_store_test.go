@@ -0,0 +1,335 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"infinitrain/pkg/job"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// newTestRedisStore creates a store backed by an in-process Redis server
+func newTestRedisStore(t *testing.T) (*RedisStore, *miniredis.Miniredis) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	store := NewRedisStore(redis.NewClient(&redis.Options{Addr: server.Addr()}))
+	t.Cleanup(func() { store.Close() })
+	return store, server
+}
+
+func TestRedisStore_CRUD(t *testing.T) {
+	ctx := context.Background()
+	store, _ := newTestRedisStore(t)
+
+	j := &job.Job{ID: "job-1", Type: job.JobTypeCommand, Command: "true", Status: job.JobStatusQueued, Tags: []string{"nightly"}}
+	if err := store.Create(ctx, j); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := store.Create(ctx, j); !job.IsValidationError(err) {
+		t.Errorf("Expected a validation error creating a duplicate, got %v", err)
+	}
+
+	got, err := store.Get(ctx, "job-1")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got.Command != "true" || got.Status != job.JobStatusQueued || len(got.Tags) != 1 {
+		t.Errorf("Expected the stored job back, got %+v", got)
+	}
+
+	// Mutating a returned job must not change what is stored
+	got.Tags[0] = "changed"
+	got.Status = job.JobStatusFailed
+	again, _ := store.Get(ctx, "job-1")
+	if again.Tags[0] != "nightly" || again.Status != job.JobStatusQueued {
+		t.Errorf("Expected reads to return copies, got %+v", again)
+	}
+
+	if exists, err := store.Exists(ctx, "job-1"); err != nil || !exists {
+		t.Errorf("Exists() = %v, %v, want true", exists, err)
+	}
+
+	again.Priority = 7
+	if err := store.Update(ctx, again); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if updated, _ := store.Get(ctx, "job-1"); updated.Priority != 7 {
+		t.Errorf("Expected priority 7 after update, got %d", updated.Priority)
+	}
+
+	if err := store.SaveResult(ctx, &job.JobResult{JobID: "job-1", Status: job.JobStatusCompleted, Output: "ok"}); err != nil {
+		t.Fatalf("SaveResult() error = %v", err)
+	}
+	if result, err := store.GetResult(ctx, "job-1"); err != nil || result.Output != "ok" {
+		t.Errorf("GetResult() = %+v, %v, want the saved result", result, err)
+	}
+
+	if err := store.Delete(ctx, "job-1"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	for name, err := range map[string]error{
+		"Get":          func() error { _, err := store.Get(ctx, "job-1"); return err }(),
+		"GetResult":    func() error { _, err := store.GetResult(ctx, "job-1"); return err }(),
+		"SaveResult":   store.SaveResult(ctx, &job.JobResult{JobID: "job-1"}),
+		"Update":       store.Update(ctx, &job.Job{ID: "job-1"}),
+		"Delete":       store.Delete(ctx, "job-1"),
+		"UpdateStatus": store.UpdateStatus(ctx, "job-1", job.JobStatusRunning),
+	} {
+		if !job.IsJobNotFoundError(err) {
+			t.Errorf("%s: expected a not found error, got %v", name, err)
+		}
+	}
+	if exists, _ := store.Exists(ctx, "job-1"); exists {
+		t.Errorf("Expected the deleted job not to exist")
+	}
+}
+
+func TestRedisStore_ListUsesIndexes(t *testing.T) {
+	ctx := context.Background()
+	store, server := newTestRedisStore(t)
+
+	for _, j := range []*job.Job{
+		{ID: "job-queued", Status: job.JobStatusQueued, Priority: 1},
+		{ID: "job-running-1", Status: job.JobStatusRunning, WorkerID: "worker-1", Priority: 5},
+		{ID: "job-running-2", Status: job.JobStatusRunning, WorkerID: "worker-2", Priority: 1},
+		{ID: "job-done", Status: job.JobStatusCompleted, WorkerID: "worker-1", Priority: 5},
+	} {
+		if err := store.Create(ctx, j); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	status := job.Filter{Field: "status", Operator: "eq", Value: string(job.JobStatusRunning)}
+	worker := job.Filter{Field: "worker_id", Operator: "eq", Value: "worker-1"}
+	priority := job.Filter{Field: "priority", Operator: "gte", Value: 5}
+	named := job.Filter{Field: "id", Operator: "in", Value: []interface{}{"job-queued", "job-running-1", "job-running-1", "job-missing"}}
+
+	tests := []struct {
+		name    string
+		filters []job.Filter
+		want    []string
+	}{
+		{"all", nil, []string{"job-done", "job-queued", "job-running-1", "job-running-2"}},
+		{"status", []job.Filter{status}, []string{"job-running-1", "job-running-2"}},
+		{"worker", []job.Filter{worker}, []string{"job-done", "job-running-1"}},
+		{"status and worker", []job.Filter{status, worker}, []string{"job-running-1"}},
+		{"unindexed filter", []job.Filter{priority}, []string{"job-done", "job-running-1"}},
+		{"ids", []job.Filter{named}, []string{"job-queued", "job-running-1"}},
+		{"ids and status", []job.Filter{named, status}, []string{"job-running-1"}},
+		{"ids and id", []job.Filter{named, {Field: "id", Operator: "eq", Value: "job-queued"}}, []string{"job-queued"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jobs, err := store.List(ctx, tt.filters...)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			var ids []string
+			for _, j := range jobs {
+				ids = append(ids, j.ID)
+			}
+			sort.Strings(ids)
+			if len(ids) != len(tt.want) {
+				t.Fatalf("Expected %v, got %v", tt.want, ids)
+			}
+			for i := range ids {
+				if ids[i] != tt.want[i] {
+					t.Errorf("Expected %v, got %v", tt.want, ids)
+					break
+				}
+			}
+		})
+	}
+
+	// Status changes move the job between index sets
+	if err := store.UpdateStatus(ctx, "job-queued", job.JobStatusRunning); err != nil {
+		t.Fatalf("UpdateStatus() error = %v", err)
+	}
+	if err := store.UpdateStatus(ctx, "job-done", job.JobStatusRunning); !job.IsValidationError(err) {
+		t.Errorf("Expected an invalid transition to be rejected, got %v", err)
+	}
+	queued, _ := server.SMembers(redisStatusKey(job.JobStatusQueued))
+	running, _ := server.SMembers(redisStatusKey(job.JobStatusRunning))
+	if len(queued) != 0 || len(running) != 3 {
+		t.Errorf("Expected the job to move from the queued to the running index, got queued=%v running=%v", queued, running)
+	}
+	if j, _ := store.Get(ctx, "job-queued"); j.StartedAt == nil {
+		t.Errorf("Expected UpdateStatus to set the start time")
+	}
+
+	if err := store.Delete(ctx, "job-running-1"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if members, _ := server.SMembers(redisWorkerKey("worker-1")); len(members) != 1 || members[0] != "job-done" {
+		t.Errorf("Expected the deleted job to leave the worker index, got %v", members)
+	}
+
+	counts, err := store.StatusCounts(ctx)
+	if err != nil {
+		t.Fatalf("StatusCounts() error = %v", err)
+	}
+	if counts[job.JobStatusRunning] != 2 || counts[job.JobStatusCompleted] != 1 || counts[job.JobStatusQueued] != 0 {
+		t.Errorf("Expected the status counts to follow the index sets, got %v", counts)
+	}
+}
+
+func TestRedisStore_ListByID(t *testing.T) {
+	ctx := context.Background()
+	store, server := newTestRedisStore(t)
+
+	for _, j := range []*job.Job{
+		{ID: "job-1", Status: job.JobStatusQueued},
+		{ID: "job-2", Status: job.JobStatusRunning},
+		{ID: "job-3", Status: job.JobStatusRunning},
+	} {
+		if err := store.Create(ctx, j); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	// Named jobs are read by key rather than from the set of every job
+	server.Del(redisKeyPrefix + "jobs")
+
+	named := job.Filter{Field: "id", Operator: "in", Value: []interface{}{"job-1", "job-2", "job-2", "job-missing"}}
+	jobs, err := store.List(ctx, named)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(jobs) != 2 || jobs[0].ID != "job-1" || jobs[1].ID != "job-2" {
+		t.Errorf("Expected job-1 and job-2, got %v", jobs)
+	}
+
+	running := job.Filter{Field: "status", Operator: "eq", Value: string(job.JobStatusRunning)}
+	if jobs, _ := store.List(ctx, named, running); len(jobs) != 1 || jobs[0].ID != "job-2" {
+		t.Errorf("Expected the status filter to apply to the named jobs, got %v", jobs)
+	}
+
+	page, err := store.ListPage(ctx, job.ListOptions{Filters: []job.Filter{named}, Limit: 1})
+	if err != nil {
+		t.Fatalf("ListPage() error = %v", err)
+	}
+	if page.Total != 2 || len(page.Jobs) != 1 || page.Jobs[0].ID != "job-1" {
+		t.Errorf("Expected the first of two named jobs, got %+v", page)
+	}
+}
+
+func TestRedisStore_AssignWorker(t *testing.T) {
+	ctx := context.Background()
+	store, server := newTestRedisStore(t)
+
+	if err := store.Create(ctx, &job.Job{ID: "job-1", Status: job.JobStatusQueued}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	assigned, err := store.AssignWorker(ctx, "job-1", "worker-1")
+	if err != nil {
+		t.Fatalf("AssignWorker() error = %v", err)
+	}
+	if assigned.Status != job.JobStatusRunning || assigned.WorkerID != "worker-1" || assigned.StartedAt == nil {
+		t.Errorf("Expected the job running on worker-1, got %+v", assigned)
+	}
+	if members, _ := server.SMembers(redisWorkerKey("worker-1")); len(members) != 1 || members[0] != "job-1" {
+		t.Errorf("Expected the job in the worker index, got %v", members)
+	}
+
+	if _, err := store.AssignWorker(ctx, "job-1", "worker-2"); !job.IsConflictError(err) {
+		t.Errorf("Expected a conflict assigning a running job, got %v", err)
+	}
+	if _, err := store.AssignWorker(ctx, "missing", "worker-1"); !job.IsJobNotFoundError(err) {
+		t.Errorf("Expected a not found error, got %v", err)
+	}
+}
+
+func TestRedisStore_ExtendTimeout(t *testing.T) {
+	ctx := context.Background()
+	store, _ := newTestRedisStore(t)
+
+	for _, j := range []*job.Job{
+		{ID: "job-running", Status: job.JobStatusRunning, WorkerID: "worker-1", Timeout: time.Minute},
+		{ID: "job-done", Status: job.JobStatusCompleted, Timeout: time.Minute},
+	} {
+		if err := store.Create(ctx, j); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	extended, err := store.ExtendTimeout(ctx, "job-running", 5*time.Minute)
+	if err != nil {
+		t.Fatalf("ExtendTimeout() error = %v", err)
+	}
+	if stored, _ := store.Get(ctx, "job-running"); extended.Timeout != 6*time.Minute || stored.Timeout != 6*time.Minute {
+		t.Errorf("Expected the timeout to become 6m, got %v (stored %v)", extended.Timeout, stored.Timeout)
+	}
+
+	if _, err := store.ExtendTimeout(ctx, "job-done", time.Minute); !job.IsConflictError(err) {
+		t.Errorf("Expected a conflict extending a finished job, got %v", err)
+	}
+	if _, err := store.ExtendTimeout(ctx, "missing", time.Minute); !job.IsJobNotFoundError(err) {
+		t.Errorf("Expected a not found error, got %v", err)
+	}
+}
+
+func TestRedisStore_TagCounts(t *testing.T) {
+	ctx := context.Background()
+	store, _ := newTestRedisStore(t)
+
+	for _, j := range []*job.Job{
+		{ID: "job-1", Status: job.JobStatusQueued, Tags: []string{"a", "b"}},
+		{ID: "job-2", Status: job.JobStatusQueued, Tags: []string{"a", "a"}},
+		{ID: "job-3", Status: job.JobStatusRunning, Tags: []string{"b"}},
+	} {
+		if err := store.Create(ctx, j); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	// The tag index sets follow status changes, updates and deletes
+	if err := store.UpdateStatus(ctx, "job-1", job.JobStatusCancelled); err != nil {
+		t.Fatalf("UpdateStatus() error = %v", err)
+	}
+	if err := store.Update(ctx, &job.Job{ID: "job-3", Status: job.JobStatusCompleted, Tags: []string{"c"}}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if err := store.Delete(ctx, "job-2"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	counts, err := store.TagCounts(ctx)
+	if err != nil {
+		t.Fatalf("TagCounts() error = %v", err)
+	}
+	want := map[string]map[job.JobStatus]int{
+		"a": {job.JobStatusCancelled: 1},
+		"b": {job.JobStatusCancelled: 1},
+		"c": {job.JobStatusCompleted: 1},
+	}
+	if fmt.Sprint(counts) != fmt.Sprint(want) {
+		t.Errorf("Expected %v, got %v", want, counts)
+	}
+}
+
+func TestRedisStore_ListPage(t *testing.T) {
+	ctx := context.Background()
+	store, server := newTestRedisStore(t)
+	checkListPage(t, store)
+
+	// Jobs stored before their creation time was kept beside them still sort
+	server.HDel(redisJobKey("job-3"), redisFieldCreatedAt)
+	page, err := store.ListPage(ctx, job.ListOptions{Limit: 3})
+	if err != nil {
+		t.Fatalf("ListPage() error = %v", err)
+	}
+	var ids []string
+	for _, j := range page.Jobs {
+		ids = append(ids, j.ID)
+	}
+	if fmt.Sprint(ids) != "[job-4 job-3 job-2]" || page.Total != 5 {
+		t.Errorf("Expected the first three of 5 jobs in creation order, got %v of %d", ids, page.Total)
+	}
+}
@@ -0,0 +1,186 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"infinitrain/pkg/job"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// remoteWorkerTimeout bounds calls the scheduler makes to a remote worker's API
+const remoteWorkerTimeout = 10 * time.Second
+
+// RemoteWorker is the scheduler's record of a worker process that registered
+// over the API. The process runs elsewhere, so starting and stopping are
+// no-ops; health follows its heartbeats.
+type RemoteWorker struct {
+	registration job.WorkerRegistration
+	healthy      bool
+	draining     bool
+	load         int
+	mutex        sync.RWMutex
+}
+
+// NewRemoteWorker creates a healthy, idle worker from a registration
+func NewRemoteWorker(registration job.WorkerRegistration) *RemoteWorker {
+	return &RemoteWorker{registration: registration, healthy: true}
+}
+
+// ID returns the worker's ID
+func (w *RemoteWorker) ID() string {
+	return w.registration.ID
+}
+
+// Start does nothing: the worker process manages itself
+func (w *RemoteWorker) Start(ctx context.Context) error {
+	return nil
+}
+
+// Stop does nothing: the worker process manages itself
+func (w *RemoteWorker) Stop(ctx context.Context) error {
+	return nil
+}
+
+// IsHealthy returns whether the worker is healthy
+func (w *RemoteWorker) IsHealthy() bool {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+	return w.healthy
+}
+
+// SetHealthy sets whether the worker is healthy
+func (w *RemoteWorker) SetHealthy(healthy bool) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.healthy = healthy
+}
+
+// GetCapacity returns the capacity the worker registered with
+func (w *RemoteWorker) GetCapacity() int {
+	return w.registration.Capacity
+}
+
+// GetCurrentLoad returns the worker's last known load
+func (w *RemoteWorker) GetCurrentLoad() int {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+	return w.load
+}
+
+// SetLoad records the worker's current load
+func (w *RemoteWorker) SetLoad(load int) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.load = load
+}
+
+// CanAcceptJob returns true if the worker is healthy, not draining and has
+// spare capacity
+func (w *RemoteWorker) CanAcceptJob() bool {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+	return w.healthy && !w.draining && w.load < w.registration.Capacity
+}
+
+// Labels returns the labels the worker registered with
+func (w *RemoteWorker) Labels() map[string]string {
+	return w.registration.Labels
+}
+
+// Address returns the base URL of the worker's own API, empty if it has none
+func (w *RemoteWorker) Address() string {
+	return w.registration.Address
+}
+
+// CancelJob asks the worker to cancel a running job through its API, which
+// kills the job's processes
+func (w *RemoteWorker) CancelJob(jobID string) error {
+	if w.registration.Address == "" {
+		return fmt.Errorf("worker %s registered without an address", w.registration.ID)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), remoteWorkerTimeout)
+	defer cancel()
+
+	resp, err := w.post(ctx, "/cancel/"+url.PathEscape(jobID))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusNotFound:
+		return job.NewJobNotFoundError(jobID)
+	default:
+		return fmt.Errorf("worker %s refused to cancel job %s: %s", w.registration.ID, jobID, resp.Status)
+	}
+}
+
+// Drain asks the worker through its API to finish its running jobs without
+// claiming new ones, and stops dispatching to it here
+func (w *RemoteWorker) Drain(ctx context.Context) error {
+	if w.registration.Address == "" {
+		return fmt.Errorf("worker %s registered without an address", w.registration.ID)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, remoteWorkerTimeout)
+	defer cancel()
+
+	resp, err := w.post(ctx, "/drain")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("worker %s refused to drain: %s", w.registration.ID, resp.Status)
+	}
+
+	w.mutex.Lock()
+	w.draining = true
+	w.mutex.Unlock()
+	return nil
+}
+
+// IsDraining returns true once the worker has been drained
+func (w *RemoteWorker) IsDraining() bool {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+	return w.draining
+}
+
+// Notify wakes the worker through its API so it claims newly queued jobs
+// without waiting for its next poll. The request is sent in the background:
+// a worker that can't be reached finds the job on its next poll anyway.
+func (w *RemoteWorker) Notify() {
+	if w.registration.Address == "" {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), remoteWorkerTimeout)
+		defer cancel()
+		if resp, err := w.post(ctx, "/notify"); err == nil {
+			resp.Body.Close()
+		}
+	}()
+}
+
+// post sends an empty POST request to a path of the worker's API
+func (w *RemoteWorker) post(ctx context.Context, path string) (*http.Response, error) {
+	endpoint := strings.TrimSuffix(w.registration.Address, "/") + path
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request to worker %s failed: %w", w.registration.ID, err)
+	}
+	return resp, nil
+}
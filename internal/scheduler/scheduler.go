@@ -0,0 +1,588 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"infinitrain/internal/clock"
+	"infinitrain/internal/config"
+	"infinitrain/internal/tracing"
+	"infinitrain/pkg/job"
+	"math/rand/v2"
+	"sort"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DefaultScheduler is the default implementation of the job.Scheduler
+// interface, dispatching queued jobs to the least-loaded available worker
+type DefaultScheduler struct {
+	store    job.Store
+	queue    job.Queue
+	workers  job.WorkerRegistry
+	affinity *affinityTracker
+	clock    clock.Clock
+	policy   job.RetryPolicy  // Retry delays for jobs without a policy of their own
+	cascade  DependencyPolicy // What happens to dependents of a cancelled job
+	random   func() float64   // Source of retry jitter, in [0, 1)
+	mutex    sync.Mutex       // Serializes assignment so each job is handed out once
+}
+
+// NewDefaultScheduler creates a new scheduler
+func NewDefaultScheduler(store job.Store, queue job.Queue, workers job.WorkerRegistry) *DefaultScheduler {
+	return NewDefaultSchedulerWithAffinity(store, queue, workers, defaultAffinityTTL, clock.Real())
+}
+
+// NewDefaultSchedulerWithAffinity creates a scheduler that keeps sending jobs
+// with the same affinity key to the same worker for up to ttl after the last one
+func NewDefaultSchedulerWithAffinity(store job.Store, queue job.Queue, workers job.WorkerRegistry, ttl time.Duration, c clock.Clock) *DefaultScheduler {
+	return &DefaultScheduler{
+		store:    store,
+		queue:    queue,
+		workers:  workers,
+		affinity: newAffinityTracker(ttl, c),
+		clock:    c,
+		cascade:  DependentsCancel,
+		random:   rand.Float64,
+	}
+}
+
+// NewDefaultSchedulerFromConfig creates a scheduler using the configured
+// affinity TTL and retry policy
+func NewDefaultSchedulerFromConfig(store job.Store, queue job.Queue, workers job.WorkerRegistry, cfg *config.SchedulerConfig) *DefaultScheduler {
+	s := NewDefaultSchedulerWithAffinity(store, queue, workers, cfg.AffinityTTL, clock.Real())
+	s.SetRetryPolicy(retryPolicyFromConfig(cfg))
+	if cfg.CancelledDependency != "" {
+		s.SetDependencyPolicy(DependencyPolicy(cfg.CancelledDependency))
+	}
+	return s
+}
+
+// retryPolicyFromConfig returns the configured retry delays and attempts
+func retryPolicyFromConfig(cfg *config.SchedulerConfig) job.RetryPolicy {
+	policy := job.RetryPolicy{
+		BaseDelay:  cfg.RetryBackoff,
+		MaxDelay:   cfg.RetryBackoffMax,
+		Multiplier: cfg.RetryMultiplier,
+		Jitter:     cfg.RetryJitter,
+	}
+	if cfg.MaxAttempts > 0 {
+		attempts := cfg.MaxAttempts
+		policy.MaxAttempts = &attempts
+	}
+	return policy
+}
+
+// SetDependencyPolicy sets what happens to the jobs depending on a job when
+// it is cancelled. Under DependentsIgnore a cancelled dependency no longer
+// holds its dependents back.
+func (s *DefaultScheduler) SetDependencyPolicy(policy DependencyPolicy) {
+	s.cascade = policy
+}
+
+// SetRetryBackoff sets how long a failed job waits before each retry: base
+// for the first, doubling for each one after, up to max, with no jitter. A
+// zero base requeues failed jobs straight away.
+func (s *DefaultScheduler) SetRetryBackoff(base, max time.Duration) {
+	s.SetRetryPolicy(job.RetryPolicy{BaseDelay: base, MaxDelay: max})
+}
+
+// SetRetryPolicy sets the retry delays for failed jobs that don't carry a
+// policy of their own. The policy's MaxAttempts gives the retries of jobs
+// submitted without any, once the manager finds no default for their type;
+// a job's retries are fixed when it is submitted.
+func (s *DefaultScheduler) SetRetryPolicy(policy job.RetryPolicy) {
+	s.policy = policy
+}
+
+// Schedule schedules a job for execution, admitting it from pending to
+// queued if it has not been admitted yet
+func (s *DefaultScheduler) Schedule(ctx context.Context, j *job.Job) error {
+	if j.Status == job.JobStatusPending {
+		if err := j.UpdateStatus(job.JobStatusQueued); err != nil {
+			return err
+		}
+		if err := s.store.Update(ctx, j); err != nil {
+			return err
+		}
+	}
+
+	return s.queue.Enqueue(ctx, j)
+}
+
+// Cancel cancels a scheduled job
+func (s *DefaultScheduler) Cancel(ctx context.Context, jobID string) error {
+	if err := s.store.UpdateStatus(ctx, jobID, job.JobStatusCancelled); err != nil {
+		return err
+	}
+
+	if err := s.queue.Remove(ctx, jobID); err != nil && !job.IsJobNotFoundError(err) {
+		return err
+	}
+
+	return nil
+}
+
+// Reprioritize changes a pending or queued job's priority in the store and
+// the queue while holding the assignment lock, so GetNextJob sees either the
+// old priority in both or the new one in both
+func (s *DefaultScheduler) Reprioritize(ctx context.Context, jobID string, priority int) (*job.Job, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return reprioritize(ctx, s.store, s.queue, jobID, priority)
+}
+
+// GetNextJob pops the highest-priority queued job, assigns it to the
+// least-loaded available worker and marks it running. The job stays queued
+// and job.ErrNoWorkerAvailable is returned if no worker can take it. Jobs
+// whose mutex key is held by a running job are skipped until it finishes,
+// as are jobs with a dependency that hasn't completed and jobs whose node
+// selector no available worker matches. Jobs with an affinity key go to the
+// worker that last ran that key while it can take them. A queued job found
+// to have been cancelled in the meantime is dropped and the next one tried.
+func (s *DefaultScheduler) GetNextJob(ctx context.Context) (*job.Job, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for {
+		j, err := s.dispatchNext(ctx, nil)
+		if !job.IsConflictError(err) {
+			return j, err
+		}
+	}
+}
+
+// NextJobFor pops the highest-priority queued job the given worker can take
+// and marks it running on that worker, for workers that poll for their own
+// jobs. The same jobs are held back as by GetNextJob, and a job with an
+// affinity key is left for the worker that last ran the key while it can
+// take it. job.ErrQueueEmpty is returned if there is nothing for the worker.
+func (s *DefaultScheduler) NextJobFor(ctx context.Context, workerID string) (*job.Job, error) {
+	worker, err := s.workers.GetWorker(ctx, workerID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for {
+		j, err := s.dispatchNext(ctx, worker)
+		if !job.IsConflictError(err) {
+			return j, err
+		}
+	}
+}
+
+// dispatchNext dequeues and assigns a single job, to target if it is given
+// and otherwise to the worker selectWorker picks. It gives a conflict error
+// if the job it dequeued was no longer queued in the store.
+func (s *DefaultScheduler) dispatchNext(ctx context.Context, target job.Worker) (*job.Job, error) {
+	if _, err := s.resumeRetries(ctx); err != nil {
+		return nil, err
+	}
+
+	held, err := heldMutexKeys(ctx, s.store)
+	if err != nil {
+		return nil, err
+	}
+	available := []job.Worker{target}
+	if target == nil {
+		if available, err = s.workers.GetAvailableWorkers(ctx); err != nil {
+			return nil, err
+		}
+	}
+	eligible := func(j *job.Job) bool {
+		if j.MutexKey != "" && held[j.MutexKey] {
+			return false
+		}
+		if len(j.NodeSelector) > 0 && !selectorSatisfied(available, j) {
+			return false
+		}
+		if target != nil && (!canTake(target, j) || !s.preferred(ctx, j, target)) {
+			return false
+		}
+		return len(j.DependsOn) == 0 || dependenciesMet(ctx, s.store, j, s.cascade)
+	}
+
+	if claimer, ok := s.store.(jobClaimer); ok {
+		return s.claim(ctx, claimer, available, eligible)
+	}
+
+	peeked, err := s.queue.PeekMatching(ctx, eligible)
+	if err != nil {
+		return nil, err
+	}
+
+	worker := target
+	if worker == nil {
+		if worker, err = s.selectWorker(ctx, peeked); err != nil {
+			return nil, err
+		}
+	}
+
+	// Take the job the worker was chosen for, even if another was queued since
+	next, err := s.queue.DequeueMatching(ctx, func(j *job.Job) bool { return j.ID == peeked.ID })
+	if err != nil {
+		return nil, err
+	}
+
+	assignCtx, span := tracing.Start(ctx, next, "job.assign", trace.WithAttributes(attribute.String("worker.id", worker.ID())))
+	j, err := s.assign(assignCtx, next.ID, worker.ID())
+	tracing.End(span, err)
+	if err != nil {
+		return nil, err
+	}
+	traceQueued(ctx, j)
+
+	if j.AffinityKey != "" {
+		s.affinity.Record(j.AffinityKey, j.WorkerID)
+	}
+
+	return j, nil
+}
+
+// jobClaimer is a store that can hand each queued job to exactly one caller,
+// such as PostgresStore, letting several schedulers share it
+type jobClaimer interface {
+	ClaimNext(ctx context.Context, workerID string, eligible func(*job.Job) bool) (*job.Job, error)
+}
+
+// claim dispatches through a store that claims jobs itself rather than from
+// the scheduler's own queue, which only holds the jobs this scheduler
+// admitted. Each available worker, least loaded first, claims the first
+// queued job it can take, leaving jobs with an affinity key to the worker
+// that last ran the key while it can take them. The claimed job is then
+// dropped from the local queue.
+func (s *DefaultScheduler) claim(ctx context.Context, claimer jobClaimer, available []job.Worker, eligible func(*job.Job) bool) (*job.Job, error) {
+	if len(available) == 0 {
+		return nil, job.ErrNoWorkerAvailable
+	}
+
+	workers := append([]job.Worker(nil), available...)
+	sort.SliceStable(workers, func(a, b int) bool {
+		return workers[a].GetCurrentLoad() < workers[b].GetCurrentLoad()
+	})
+
+	for _, worker := range workers {
+		j, err := claimer.ClaimNext(ctx, worker.ID(), func(j *job.Job) bool {
+			return canTake(worker, j) && job.MatchesSelector(worker.Labels(), j.NodeSelector) && eligible(j) && s.preferred(ctx, j, worker)
+		})
+		if errors.Is(err, job.ErrQueueEmpty) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if err := s.queue.Remove(ctx, j.ID); err != nil && !job.IsJobNotFoundError(err) {
+			return nil, err
+		}
+		traceQueued(ctx, j)
+		if j.AffinityKey != "" {
+			s.affinity.Record(j.AffinityKey, j.WorkerID)
+		}
+		return j, nil
+	}
+	return nil, job.ErrQueueEmpty
+}
+
+// preferred reports whether a job with an affinity key may go to the worker:
+// any worker may take it unless the one that last ran its key can
+func (s *DefaultScheduler) preferred(ctx context.Context, j *job.Job, worker job.Worker) bool {
+	if j.AffinityKey == "" {
+		return true
+	}
+	workerID, ok := s.affinity.Lookup(j.AffinityKey)
+	if !ok || workerID == worker.ID() {
+		return true
+	}
+	last, err := s.workers.GetWorker(ctx, workerID)
+	return err != nil || !canTake(last, j) || !job.MatchesSelector(last.Labels(), j.NodeSelector)
+}
+
+// assign moves a dequeued job to running on the worker. Stores that can do
+// this atomically are asked to, so a job cancelled after it was dequeued is
+// never marked running; others get a read followed by a write.
+func (s *DefaultScheduler) assign(ctx context.Context, jobID, workerID string) (*job.Job, error) {
+	if assigner, ok := s.store.(interface {
+		AssignWorker(ctx context.Context, jobID, workerID string) (*job.Job, error)
+	}); ok {
+		return assigner.AssignWorker(ctx, jobID, workerID)
+	}
+
+	j, err := s.store.Get(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	j.WorkerID = workerID
+	if err := j.UpdateStatus(job.JobStatusRunning); err != nil {
+		return nil, err
+	}
+	if err := s.store.Update(ctx, j); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// traceQueued adds a span to the job's trace covering its latest wait in the
+// queue, which ended when it was assigned
+func traceQueued(ctx context.Context, j *job.Job) {
+	var queued, assigned time.Time
+	for i := len(j.Events) - 1; i >= 0 && queued.IsZero(); i-- {
+		switch j.Events[i].Status {
+		case job.JobStatusRunning:
+			assigned = j.Events[i].Timestamp
+		case job.JobStatusQueued:
+			queued = j.Events[i].Timestamp
+		}
+	}
+	if queued.IsZero() || assigned.IsZero() {
+		return
+	}
+
+	_, span := tracing.Start(ctx, j, "job.queued", trace.WithTimestamp(queued))
+	span.End(trace.WithTimestamp(assigned))
+}
+
+// selectWorker picks the worker for a job: the one that last ran its
+// affinity key if that worker is still available and has room for the job,
+// otherwise the least-loaded available worker. Either must match the job's
+// node selector.
+func (s *DefaultScheduler) selectWorker(ctx context.Context, j *job.Job) (job.Worker, error) {
+	if j.AffinityKey != "" {
+		if workerID, ok := s.affinity.Lookup(j.AffinityKey); ok {
+			worker, err := s.workers.GetWorker(ctx, workerID)
+			if err == nil && canTake(worker, j) && job.MatchesSelector(worker.Labels(), j.NodeSelector) {
+				return worker, nil
+			}
+		}
+	}
+
+	return s.workers.GetLeastLoadedWorker(ctx, j.NodeSelector, j.Weight())
+}
+
+// selectorSatisfied reports whether any of the workers matches the job's
+// node selector and has room for it
+func selectorSatisfied(workers []job.Worker, j *job.Job) bool {
+	for _, worker := range workers {
+		if job.MatchesSelector(worker.Labels(), j.NodeSelector) && canTake(worker, j) {
+			return true
+		}
+	}
+	return false
+}
+
+// canTake reports whether a worker is available and has room for the job.
+// Workers that don't judge costs themselves have room while their load plus
+// the job's cost fits their capacity.
+func canTake(worker job.Worker, j *job.Job) bool {
+	if !worker.CanAcceptJob() {
+		return false
+	}
+	if sized, ok := worker.(interface{ CanAcceptCost(int) bool }); ok {
+		return sized.CanAcceptCost(j.Weight())
+	}
+	return currentLoad(worker)+j.Weight() <= worker.GetCapacity()
+}
+
+// heldMutexKeys returns the mutex keys of all running jobs
+func heldMutexKeys(ctx context.Context, store job.Store) (map[string]bool, error) {
+	running, err := store.List(ctx, job.Filter{Field: "status", Operator: "eq", Value: string(job.JobStatusRunning)})
+	if err != nil {
+		return nil, err
+	}
+
+	held := make(map[string]bool)
+	for _, j := range running {
+		if j.MutexKey != "" {
+			held[j.MutexKey] = true
+		}
+	}
+	return held, nil
+}
+
+// dependencyStates looks up each of a job's dependencies. A completed
+// dependency is satisfied, as is a cancelled one when the policy leaves
+// dependents to run; one that no longer exists never is.
+func dependencyStates(ctx context.Context, store job.Store, j *job.Job, policy DependencyPolicy) ([]job.DependencyState, error) {
+	states := make([]job.DependencyState, 0, len(j.DependsOn))
+	for _, id := range j.DependsOn {
+		state := job.DependencyState{JobID: id}
+		dependency, err := store.Get(ctx, id)
+		switch {
+		case err == nil:
+			state.Status = dependency.Status
+			state.Satisfied = dependency.Status == job.JobStatusCompleted ||
+				(policy == DependentsIgnore && dependency.Status == job.JobStatusCancelled)
+		case !job.IsJobNotFoundError(err):
+			return nil, err
+		}
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+// dependenciesMet reports whether every dependency of a job has completed,
+// or was cancelled while the policy leaves dependents to run. Lookup errors
+// count as unmet so the job waits rather than running early.
+func dependenciesMet(ctx context.Context, store job.Store, j *job.Job, policy DependencyPolicy) bool {
+	states, err := dependencyStates(ctx, store, j, policy)
+	if err != nil {
+		return false
+	}
+	for _, state := range states {
+		if !state.Satisfied {
+			return false
+		}
+	}
+	return true
+}
+
+// MarkCompleted marks a job as completed
+func (s *DefaultScheduler) MarkCompleted(ctx context.Context, jobID string, result *job.JobResult) error {
+	j, err := s.store.Get(ctx, jobID)
+	if err != nil {
+		return err
+	}
+
+	if result != nil {
+		j.Output = result.Output
+		j.OutputBytes = result.OutputBytes
+		j.OutputLines = result.OutputLines
+		j.Truncated = result.Truncated
+		j.LogDropped = result.LogDropped
+		j.Error = result.Error
+		j.ExitCode = result.ExitCode
+		j.Termination = result.Termination
+		j.LogFile = result.LogFile
+	}
+
+	if err := j.UpdateStatus(job.JobStatusCompleted); err != nil {
+		return err
+	}
+
+	if err := s.store.Update(ctx, j); err != nil {
+		return err
+	}
+
+	if result == nil {
+		return nil
+	}
+	resultCopy := *result
+	resultCopy.JobID = jobID
+	resultCopy.Status = job.JobStatusCompleted
+	return s.store.SaveResult(ctx, &resultCopy)
+}
+
+// MarkFailed marks a job as failed, or sends it back to the queue through
+// the retrying status while it has retries left
+func (s *DefaultScheduler) MarkFailed(ctx context.Context, jobID string, cause error) error {
+	j, err := s.store.Get(ctx, jobID)
+	if err != nil {
+		return err
+	}
+
+	if cause != nil {
+		j.Error = cause.Error()
+	}
+
+	if j.IsRunning() && j.CanRetry() {
+		return s.retry(ctx, j)
+	}
+
+	if err := j.UpdateStatus(job.JobStatusFailed); err != nil {
+		return fmt.Errorf("failed to mark job %s failed: %w", jobID, err)
+	}
+
+	if err := s.store.Update(ctx, j); err != nil {
+		return err
+	}
+
+	// Jobs waiting on this one can never run now
+	return abandonDependents(ctx, s.store, s.queue, jobID, job.JobStatusFailed, "failed")
+}
+
+// retry moves a failed run to the retrying status. Without a backoff the job
+// goes straight back to the queue; otherwise it waits there until its retry
+// time, when the next GetNextJob call requeues it.
+func (s *DefaultScheduler) retry(ctx context.Context, j *job.Job) error {
+	if err := j.UpdateStatus(job.JobStatusRetrying); err != nil {
+		return fmt.Errorf("failed to retry job %s: %w", j.ID, err)
+	}
+	j.RetryCount++
+
+	delay := s.retryDelay(j)
+	if delay > 0 {
+		retryAt := s.clock.Now().UTC().Add(delay)
+		j.RetryAt = &retryAt
+		return s.store.Update(ctx, j)
+	}
+
+	if err := s.store.Update(ctx, j); err != nil {
+		return err
+	}
+	return s.requeueRetry(ctx, j)
+}
+
+// retryDelay returns the backoff before the job's next retry, using the
+// job's own retry policy in place of the scheduler's when it has one
+func (s *DefaultScheduler) retryDelay(j *job.Job) time.Duration {
+	policy := &s.policy
+	if j.RetryPolicy != nil {
+		policy = j.RetryPolicy
+	}
+	return policy.Delay(j.RetryCount, s.random())
+}
+
+// ResumeRetries returns every retrying job whose backoff has elapsed to the
+// queue, holding the assignment lock, and returns their IDs
+func (s *DefaultScheduler) ResumeRetries(ctx context.Context) ([]string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.resumeRetries(ctx)
+}
+
+// resumeRetries returns every retrying job whose backoff has elapsed to the
+// queue, and returns their IDs. Stores look retrying jobs up by status, so
+// this reads only those rather than every job.
+func (s *DefaultScheduler) resumeRetries(ctx context.Context) ([]string, error) {
+	now := s.clock.Now().UTC()
+	retrying, err := s.store.List(ctx,
+		job.Filter{Field: "status", Operator: "eq", Value: string(job.JobStatusRetrying)},
+		job.Filter{Field: "retry_at", Operator: "lte", Value: now},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var resumed []string
+	for _, j := range retrying {
+		if j.RetryAt != nil && now.Before(*j.RetryAt) {
+			continue
+		}
+		if err := s.requeueRetry(ctx, j); err != nil {
+			return resumed, err
+		}
+		resumed = append(resumed, j.ID)
+	}
+	return resumed, nil
+}
+
+// requeueRetry moves a retrying job back to the queue for another attempt
+func (s *DefaultScheduler) requeueRetry(ctx context.Context, j *job.Job) error {
+	if err := j.Requeue(); err != nil {
+		return fmt.Errorf("failed to requeue job %s: %w", j.ID, err)
+	}
+	if err := s.store.Update(ctx, j); err != nil {
+		return err
+	}
+
+	return s.queue.Enqueue(ctx, j)
+}
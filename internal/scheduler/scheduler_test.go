@@ -0,0 +1,1723 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"infinitrain/internal/clock"
+	"infinitrain/internal/config"
+	"infinitrain/pkg/job"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// newTestScheduler wires a scheduler and manager over in-memory components
+func newTestScheduler(t *testing.T, workers ...*fakeWorker) (*DefaultScheduler, *Manager, *MemoryStore) {
+	t.Helper()
+
+	store := NewMemoryStore()
+	queue := NewPriorityQueue()
+	registry := newTestRegistry(t, workers...)
+
+	return NewDefaultScheduler(store, queue, registry), NewManager(store, queue, registry), store
+}
+
+func TestDefaultScheduler_GetNextJobAssignsLeastLoadedWorker(t *testing.T) {
+	ctx := context.Background()
+	scheduler, manager, store := newTestScheduler(t,
+		&fakeWorker{id: "busy", capacity: 4, load: 3, healthy: true},
+		&fakeWorker{id: "idle", capacity: 4, load: 0, healthy: true},
+	)
+
+	submitted, err := manager.Submit(ctx, &job.JobRequest{Type: job.JobTypeCommand, Command: "true"})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+
+	next, err := scheduler.GetNextJob(ctx)
+	if err != nil {
+		t.Fatalf("GetNextJob() error = %v", err)
+	}
+
+	if next.ID != submitted.ID {
+		t.Errorf("Expected job %s, got %s", submitted.ID, next.ID)
+	}
+	if next.WorkerID != "idle" {
+		t.Errorf("Expected job assigned to idle worker, got %s", next.WorkerID)
+	}
+
+	stored, _ := store.Get(ctx, submitted.ID)
+	if stored.Status != job.JobStatusRunning || stored.WorkerID != "idle" {
+		t.Errorf("Expected stored job running on idle, got %s on %s", stored.Status, stored.WorkerID)
+	}
+}
+
+func TestManager_NextJobFor(t *testing.T) {
+	ctx := context.Background()
+	scheduler, manager, store := newTestScheduler(t,
+		&fakeWorker{id: "small", capacity: 2, healthy: true},
+		&fakeWorker{id: "idle", capacity: 4, healthy: true},
+	)
+	manager.SetScheduler(scheduler)
+
+	heavy, err := manager.Submit(ctx, &job.JobRequest{Type: job.JobTypeCommand, Command: "true", Priority: 9, Cost: 3})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	light, err := manager.Submit(ctx, &job.JobRequest{Type: job.JobTypeCommand, Command: "true", Priority: 1})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+
+	// The polling worker gets the best job it has room for, even though
+	// another worker is less loaded
+	next, err := manager.NextJobFor(ctx, "small")
+	if err != nil {
+		t.Fatalf("NextJobFor() error = %v", err)
+	}
+	if next.ID != light.ID || next.WorkerID != "small" {
+		t.Errorf("Expected %s on small, got %s on %s", light.ID, next.ID, next.WorkerID)
+	}
+	if _, err := manager.NextJobFor(ctx, "small"); !errors.Is(err, job.ErrQueueEmpty) {
+		t.Errorf("Expected nothing more for small, got %v", err)
+	}
+
+	next, err = manager.NextJobFor(ctx, "idle")
+	if err != nil || next.ID != heavy.ID {
+		t.Fatalf("Expected %s for idle, got %+v, %v", heavy.ID, next, err)
+	}
+	if stored, _ := store.Get(ctx, heavy.ID); stored.Status != job.JobStatusRunning || stored.WorkerID != "idle" {
+		t.Errorf("Expected the job running on idle, got %s on %s", stored.Status, stored.WorkerID)
+	}
+
+	if _, err := manager.NextJobFor(ctx, "missing"); !job.IsWorkerNotFoundError(err) {
+		t.Errorf("Expected a not found error for an unknown worker, got %v", err)
+	}
+}
+
+func TestDefaultScheduler_GetNextJobWithoutWorkersKeepsJobQueued(t *testing.T) {
+	ctx := context.Background()
+	scheduler, manager, _ := newTestScheduler(t,
+		&fakeWorker{id: "full", capacity: 1, load: 1, healthy: true},
+	)
+
+	if _, err := manager.Submit(ctx, &job.JobRequest{Type: job.JobTypeCommand, Command: "true"}); err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+
+	if _, err := scheduler.GetNextJob(ctx); err != job.ErrNoWorkerAvailable {
+		t.Fatalf("Expected ErrNoWorkerAvailable, got %v", err)
+	}
+
+	if size, _ := scheduler.queue.Size(ctx); size != 1 {
+		t.Errorf("Expected job to remain queued, queue size %d", size)
+	}
+}
+
+// racingQueue enqueues a job right after the first peek, as a submission
+// landing while the scheduler picks a worker would
+type racingQueue struct {
+	*PriorityQueue
+	late *job.Job
+}
+
+func (q *racingQueue) PeekMatching(ctx context.Context, match func(*job.Job) bool) (*job.Job, error) {
+	peeked, err := q.PriorityQueue.PeekMatching(ctx, match)
+	if q.late != nil {
+		q.PriorityQueue.Enqueue(ctx, q.late)
+		q.late = nil
+	}
+	return peeked, err
+}
+
+func TestDefaultScheduler_GetNextJobTakesThePeekedJob(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	urgent := &job.Job{ID: "job-urgent", Status: job.JobStatusQueued, Priority: 10}
+	queue := &racingQueue{PriorityQueue: NewPriorityQueue(), late: urgent}
+	scheduler := NewDefaultScheduler(store, queue, newTestRegistry(t, &fakeWorker{id: "w1", capacity: 2, healthy: true}))
+
+	first := &job.Job{ID: "job-first", Status: job.JobStatusQueued}
+	for _, j := range []*job.Job{first, urgent} {
+		if err := store.Create(ctx, j); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+	queue.PriorityQueue.Enqueue(ctx, first)
+
+	// The urgent job is queued after job-first was peeked and a worker
+	// chosen for it, so job-first must be the one dispatched and the urgent
+	// job must stay queued rather than be dropped
+	j, err := scheduler.GetNextJob(ctx)
+	if err != nil {
+		t.Fatalf("GetNextJob() error = %v", err)
+	}
+	if j.ID != "job-first" {
+		t.Errorf("Expected the peeked job-first to be dispatched, got %s", j.ID)
+	}
+	if _, err := queue.PeekMatching(ctx, func(queued *job.Job) bool { return queued.ID == "job-urgent" }); err != nil {
+		t.Errorf("Expected job-urgent to stay queued, got %v", err)
+	}
+}
+
+func TestDefaultScheduler_GetNextJobDoesNotRunCancelledJob(t *testing.T) {
+	ctx := context.Background()
+	scheduler, manager, store := newTestScheduler(t, &fakeWorker{id: "worker-1", capacity: 4, healthy: true})
+
+	submitted, err := manager.Submit(ctx, &job.JobRequest{Type: job.JobTypeCommand, Command: "true", Priority: 5})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	other, err := manager.Submit(ctx, &job.JobRequest{Type: job.JobTypeCommand, Command: "true"})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	// Cancelled in the store while still in the queue, as when a
+	// cancellation races with dispatch
+	if err := store.UpdateStatus(ctx, submitted.ID, job.JobStatusCancelled); err != nil {
+		t.Fatalf("UpdateStatus() error = %v", err)
+	}
+
+	next, err := scheduler.GetNextJob(ctx)
+	if err != nil {
+		t.Fatalf("GetNextJob() error = %v", err)
+	}
+	if next.ID != other.ID {
+		t.Errorf("Expected the cancelled job to be skipped for %s, got %s", other.ID, next.ID)
+	}
+	if stored, _ := store.Get(ctx, submitted.ID); stored.Status != job.JobStatusCancelled || stored.WorkerID != "" {
+		t.Errorf("Expected the job to stay cancelled and unassigned, got %s on %q", stored.Status, stored.WorkerID)
+	}
+}
+
+func TestDefaultScheduler_TracesJobLifecycle(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	ctx := context.Background()
+	scheduler, manager, store := newTestScheduler(t, &fakeWorker{id: "worker-1", capacity: 4, healthy: true})
+
+	submitted, err := manager.Submit(ctx, &job.JobRequest{Type: job.JobTypeCommand, Command: "true"})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	if stored, _ := store.Get(ctx, submitted.ID); stored.TraceParent == "" {
+		t.Fatal("Expected the stored job to carry its trace")
+	}
+	if _, err := scheduler.GetNextJob(ctx); err != nil {
+		t.Fatalf("GetNextJob() error = %v", err)
+	}
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	submit := spans["job.submit"]
+	if submit == nil {
+		t.Fatalf("Expected a job.submit span, got %v", spans)
+	}
+	for _, name := range []string{"job.queued", "job.assign"} {
+		span := spans[name]
+		if span == nil {
+			t.Errorf("Expected a %s span", name)
+			continue
+		}
+		if span.SpanContext().TraceID() != submit.SpanContext().TraceID() || span.Parent().SpanID() != submit.SpanContext().SpanID() {
+			t.Errorf("Expected %s to continue the submission's trace", name)
+		}
+	}
+	if queued := spans["job.queued"]; queued != nil && queued.EndTime().Before(queued.StartTime()) {
+		t.Errorf("Expected the queued span to end after it starts, got %v to %v", queued.StartTime(), queued.EndTime())
+	}
+}
+
+func TestManager_SubmitImmediate(t *testing.T) {
+	tests := []struct {
+		name      string
+		workers   []*fakeWorker
+		wantErr   bool
+		wantQueue int
+	}{
+		{
+			name: "rejected when all workers are busy",
+			workers: []*fakeWorker{
+				{id: "full", capacity: 1, load: 1, healthy: true},
+				{id: "sick", capacity: 4, load: 0, healthy: false},
+			},
+			wantErr: true,
+		},
+		{
+			name: "accepted when a worker is free",
+			workers: []*fakeWorker{
+				{id: "full", capacity: 1, load: 1, healthy: true},
+				{id: "free", capacity: 2, load: 1, healthy: true},
+			},
+			wantQueue: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			scheduler, manager, store := newTestScheduler(t, tt.workers...)
+
+			_, err := manager.Submit(ctx, &job.JobRequest{
+				Type:       job.JobTypeCommand,
+				Command:    "true",
+				Scheduling: job.SchedulingModeImmediate,
+			})
+			if tt.wantErr {
+				if !job.IsConflictError(err) {
+					t.Fatalf("Expected conflict error, got %v", err)
+				}
+				if count := store.Count(ctx); count != 0 {
+					t.Errorf("Expected rejected job not to be stored, got %d jobs", count)
+				}
+			} else if err != nil {
+				t.Fatalf("Submit() error = %v", err)
+			}
+
+			if size, _ := scheduler.queue.Size(ctx); size != tt.wantQueue {
+				t.Errorf("Expected queue size %d, got %d", tt.wantQueue, size)
+			}
+		})
+	}
+}
+
+func TestManager_SubmitQueueModeWaitsForWorker(t *testing.T) {
+	ctx := context.Background()
+	scheduler, manager, _ := newTestScheduler(t,
+		&fakeWorker{id: "full", capacity: 1, load: 1, healthy: true},
+	)
+
+	if _, err := manager.Submit(ctx, &job.JobRequest{Type: job.JobTypeCommand, Command: "true"}); err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+
+	if size, _ := scheduler.queue.Size(ctx); size != 1 {
+		t.Errorf("Expected job to be queued, queue size %d", size)
+	}
+}
+
+func TestDefaultScheduler_AnnotationsDoNotAffectAssignment(t *testing.T) {
+	ctx := context.Background()
+	scheduler, manager, _ := newTestScheduler(t,
+		&fakeWorker{id: "busy", capacity: 4, load: 2, healthy: true, labels: map[string]string{"gpu": "true"}},
+		&fakeWorker{id: "idle", capacity: 4, load: 0, healthy: true},
+	)
+
+	// Annotations that look like worker labels must not steer the job
+	if _, err := manager.Submit(ctx, &job.JobRequest{
+		Type:        job.JobTypeCommand,
+		Command:     "true",
+		Annotations: map[string]string{"gpu": "true"},
+	}); err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+
+	next, err := scheduler.GetNextJob(ctx)
+	if err != nil {
+		t.Fatalf("GetNextJob() error = %v", err)
+	}
+	if next.WorkerID != "idle" {
+		t.Errorf("Expected least-loaded worker regardless of annotations, got %s", next.WorkerID)
+	}
+	if next.Annotations["gpu"] != "true" {
+		t.Errorf("Expected annotations to be kept on the dispatched job, got %v", next.Annotations)
+	}
+}
+
+// sequentialIDs is a deterministic job.IDGenerator for tests
+type sequentialIDs struct {
+	next int
+}
+
+func (g *sequentialIDs) NewID() string {
+	g.next++
+	return fmt.Sprintf("job-%d", g.next)
+}
+
+func TestManager_SubmitUsesIDGenerator(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	manager := NewManagerWithIDGenerator(store, NewPriorityQueue(), newTestRegistry(t), &sequentialIDs{})
+
+	for _, want := range []string{"job-1", "job-2", "job-3"} {
+		j, err := manager.Submit(ctx, &job.JobRequest{Type: job.JobTypeCommand, Command: "true"})
+		if err != nil {
+			t.Fatalf("Submit() error = %v", err)
+		}
+		if j.ID != want {
+			t.Errorf("Expected ID %s, got %s", want, j.ID)
+		}
+		if _, err := store.Get(ctx, want); err != nil {
+			t.Errorf("Expected job stored under %s: %v", want, err)
+		}
+	}
+
+	// Rejected submissions don't consume an ID
+	if _, err := manager.Submit(ctx, &job.JobRequest{Type: job.JobTypeCommand}); !job.IsValidationError(err) {
+		t.Fatalf("Expected validation error, got %v", err)
+	}
+	j, _ := manager.Submit(ctx, &job.JobRequest{Type: job.JobTypeCommand, Command: "true"})
+	if j.ID != "job-4" {
+		t.Errorf("Expected ID job-4 after a rejected submission, got %s", j.ID)
+	}
+}
+
+func TestDefaultScheduler_MutexKeySerializesJobs(t *testing.T) {
+	ctx := context.Background()
+	scheduler, manager, _ := newTestScheduler(t,
+		&fakeWorker{id: "w1", capacity: 10, healthy: true},
+	)
+
+	submit := func(mutexKey string, priority int) *job.Job {
+		t.Helper()
+		j, err := manager.Submit(ctx, &job.JobRequest{
+			Type:     job.JobTypeCommand,
+			Command:  "migrate",
+			MutexKey: mutexKey,
+			Priority: priority,
+		})
+		if err != nil {
+			t.Fatalf("Submit() error = %v", err)
+		}
+		return j
+	}
+
+	first := submit("db-main", 10)
+	second := submit("db-main", 9)
+	other := submit("db-replica", 1)
+
+	dispatch := func() *job.Job {
+		t.Helper()
+		next, err := scheduler.GetNextJob(ctx)
+		if err != nil {
+			t.Fatalf("GetNextJob() error = %v", err)
+		}
+		return next
+	}
+
+	if next := dispatch(); next.ID != first.ID {
+		t.Fatalf("Expected %s first, got %s", first.ID, next.ID)
+	}
+
+	// The second db-main job outranks the replica job but must wait
+	if next := dispatch(); next.ID != other.ID {
+		t.Fatalf("Expected job with a different key %s to run concurrently, got %s", other.ID, next.ID)
+	}
+
+	if _, err := scheduler.GetNextJob(ctx); err != job.ErrQueueEmpty {
+		t.Fatalf("Expected the blocked job to stay queued, got %v", err)
+	}
+	if size, _ := scheduler.queue.Size(ctx); size != 1 {
+		t.Errorf("Expected 1 job still queued, got %d", size)
+	}
+
+	if err := scheduler.MarkCompleted(ctx, first.ID, nil); err != nil {
+		t.Fatalf("MarkCompleted() error = %v", err)
+	}
+
+	if next := dispatch(); next.ID != second.ID {
+		t.Errorf("Expected %s once the key was released, got %s", second.ID, next.ID)
+	}
+}
+
+func TestManager_Recover(t *testing.T) {
+	tests := []struct {
+		name       string
+		policy     RecoveryPolicy
+		wantStatus map[string]job.JobStatus
+		wantQueued []string
+	}{
+		{
+			name:   "requeue orphaned jobs",
+			policy: RecoveryRequeue,
+			wantStatus: map[string]job.JobStatus{
+				"job-queued":   job.JobStatusQueued,
+				"job-orphaned": job.JobStatusQueued,
+				"job-durable":  job.JobStatusQueued,
+				"job-live":     job.JobStatusRunning,
+				"job-retrying": job.JobStatusQueued,
+				"job-backoff":  job.JobStatusRetrying, // Resumed by the scheduler at its retry time
+				"job-done":     job.JobStatusCompleted,
+			},
+			wantQueued: []string{"job-queued", "job-orphaned", "job-durable", "job-retrying"},
+		},
+		{
+			name:   "fail orphaned jobs",
+			policy: RecoveryFail,
+			wantStatus: map[string]job.JobStatus{
+				"job-queued":   job.JobStatusQueued,
+				"job-orphaned": job.JobStatusFailed,
+				"job-durable":  job.JobStatusQueued, // Durable jobs are requeued whatever the policy
+				"job-live":     job.JobStatusRunning,
+				"job-retrying": job.JobStatusQueued,
+				"job-backoff":  job.JobStatusRetrying, // Resumed by the scheduler at its retry time
+				"job-done":     job.JobStatusCompleted,
+			},
+			wantQueued: []string{"job-queued", "job-durable", "job-retrying"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			scheduler, manager, store := newTestScheduler(t,
+				&fakeWorker{id: "live", capacity: 1, healthy: true},
+			)
+
+			// State left behind by a previous scheduler process
+			later := time.Now().Add(time.Hour)
+			for _, j := range []*job.Job{
+				{ID: "job-queued", Status: job.JobStatusQueued},
+				{ID: "job-orphaned", Status: job.JobStatusRunning, WorkerID: "gone"},
+				{ID: "job-durable", Status: job.JobStatusRunning, WorkerID: "gone", Durable: true},
+				{ID: "job-live", Status: job.JobStatusRunning, WorkerID: "live"},
+				{ID: "job-retrying", Status: job.JobStatusRetrying},
+				{ID: "job-backoff", Status: job.JobStatusRetrying, RetryAt: &later},
+				{ID: "job-done", Status: job.JobStatusCompleted},
+			} {
+				if err := store.Create(ctx, j); err != nil {
+					t.Fatalf("failed to seed job: %v", err)
+				}
+			}
+
+			// The queued job survived in the queue; recovery must not duplicate it
+			queued, _ := store.Get(ctx, "job-queued")
+			scheduler.queue.Enqueue(ctx, queued)
+
+			if _, err := manager.Recover(ctx, tt.policy); err != nil {
+				t.Fatalf("Recover() error = %v", err)
+			}
+
+			for id, want := range tt.wantStatus {
+				j, _ := store.Get(ctx, id)
+				if j.Status != want {
+					t.Errorf("Expected %s to be %s, got %s", id, want, j.Status)
+				}
+			}
+
+			if orphaned, _ := store.Get(ctx, "job-orphaned"); tt.policy == RecoveryRequeue && orphaned.WorkerID != "" {
+				t.Errorf("Expected requeued job to lose its worker assignment, got %s", orphaned.WorkerID)
+			}
+
+			if size, _ := scheduler.queue.Size(ctx); size != len(tt.wantQueued) {
+				t.Errorf("Expected %d queued jobs, got %d", len(tt.wantQueued), size)
+			}
+			for _, id := range tt.wantQueued {
+				if _, err := scheduler.queue.PeekMatching(ctx, func(j *job.Job) bool { return j.ID == id }); err != nil {
+					t.Errorf("Expected %s in the queue", id)
+				}
+			}
+		})
+	}
+}
+
+func TestManager_FailedOrphansAbandonDependents(t *testing.T) {
+	tests := []struct {
+		name string
+		fail func(ctx context.Context, manager *Manager) error
+	}{
+		{
+			name: "recovery",
+			fail: func(ctx context.Context, manager *Manager) error {
+				_, err := manager.Recover(ctx, RecoveryFail)
+				return err
+			},
+		},
+		{
+			name: "released worker",
+			fail: func(ctx context.Context, manager *Manager) error {
+				_, err := manager.ReleaseWorkerJobs(ctx, "gone", false)
+				return err
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			scheduler, manager, store := newTestScheduler(t)
+
+			for _, j := range []*job.Job{
+				{ID: "job-orphaned", Status: job.JobStatusRunning, WorkerID: "gone"},
+				{ID: "job-child", Status: job.JobStatusQueued, DependsOn: []string{"job-orphaned"}},
+				{ID: "job-grandchild", Status: job.JobStatusQueued, DependsOn: []string{"job-child"}},
+			} {
+				if err := store.Create(ctx, j); err != nil {
+					t.Fatalf("failed to seed job: %v", err)
+				}
+			}
+
+			if err := tt.fail(ctx, manager); err != nil {
+				t.Fatalf("failing orphaned jobs: %v", err)
+			}
+			for _, id := range []string{"job-orphaned", "job-child", "job-grandchild"} {
+				if j, _ := store.Get(ctx, id); j.Status != job.JobStatusFailed {
+					t.Errorf("Expected %s to be failed, got %s", id, j.Status)
+				}
+			}
+			if size, _ := scheduler.queue.Size(ctx); size != 0 {
+				t.Errorf("Expected no dependents left in the queue, got %d", size)
+			}
+		})
+	}
+}
+
+func TestManager_SubmitAdmitsJobToQueue(t *testing.T) {
+	ctx := context.Background()
+	scheduler, manager, store := newTestScheduler(t,
+		&fakeWorker{id: "w1", capacity: 1, healthy: true},
+	)
+
+	submitted, err := manager.Submit(ctx, &job.JobRequest{Type: job.JobTypeCommand, Command: "true"})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+
+	stored, _ := store.Get(ctx, submitted.ID)
+	if submitted.Status != job.JobStatusQueued || stored.Status != job.JobStatusQueued {
+		t.Fatalf("Expected submitted job to be queued, got %s (stored %s)", submitted.Status, stored.Status)
+	}
+
+	next, err := scheduler.GetNextJob(ctx)
+	if err != nil {
+		t.Fatalf("GetNextJob() error = %v", err)
+	}
+	if next.ID != submitted.ID {
+		t.Errorf("Expected %s to be dequeued, got %s", submitted.ID, next.ID)
+	}
+}
+
+func TestManager_Enqueue(t *testing.T) {
+	ctx := context.Background()
+	scheduler, manager, store := newTestScheduler(t)
+
+	for _, j := range []*job.Job{
+		{ID: "job-pending", Status: job.JobStatusPending},
+		{ID: "job-running", Status: job.JobStatusRunning},
+	} {
+		if err := store.Create(ctx, j); err != nil {
+			t.Fatalf("failed to seed job: %v", err)
+		}
+	}
+
+	// Pending jobs are not dispatchable until admitted
+	if size, _ := scheduler.queue.Size(ctx); size != 0 {
+		t.Fatalf("Expected empty queue before enqueue, got %d", size)
+	}
+
+	j, err := manager.Enqueue(ctx, "job-pending")
+	if err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	if j.Status != job.JobStatusQueued {
+		t.Errorf("Expected queued status, got %s", j.Status)
+	}
+	if next, err := scheduler.queue.Dequeue(ctx); err != nil || next.ID != "job-pending" {
+		t.Errorf("Expected job-pending to be dequeuable, got %v, %v", next, err)
+	}
+
+	if _, err := manager.Enqueue(ctx, "job-running"); !job.IsConflictError(err) {
+		t.Errorf("Expected conflict enqueuing a running job, got %v", err)
+	}
+	if _, err := manager.Enqueue(ctx, "job-missing"); !job.IsJobNotFoundError(err) {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+}
+
+func TestManager_UpdatePriorityHoldsDispatchLock(t *testing.T) {
+	ctx := context.Background()
+	scheduler, manager, store := newTestScheduler(t)
+	manager.SetScheduler(scheduler)
+
+	submitted, err := manager.Submit(ctx, &job.JobRequest{Type: job.JobTypeCommand, Command: "true", Priority: 1})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+
+	// While a dispatch holds the lock the change waits, touching neither the
+	// store nor the queue
+	scheduler.mutex.Lock()
+	done := make(chan error, 1)
+	go func() {
+		_, err := manager.UpdatePriority(ctx, submitted.ID, 9)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		t.Fatalf("Expected UpdatePriority to wait for the dispatch lock, got %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if stored, _ := store.Get(ctx, submitted.ID); stored.Priority != 1 {
+		t.Errorf("Expected the stored priority unchanged during dispatch, got %d", stored.Priority)
+	}
+	scheduler.mutex.Unlock()
+
+	if err := <-done; err != nil {
+		t.Fatalf("UpdatePriority() error = %v", err)
+	}
+	stored, _ := store.Get(ctx, submitted.ID)
+	queued, _ := scheduler.queue.Peek(ctx)
+	if stored.Priority != 9 || queued == nil || queued.Priority != 9 {
+		t.Errorf("Expected priority 9 in the store and the queue, got %d and %+v", stored.Priority, queued)
+	}
+}
+
+func TestManager_SubmitRejectsDuplicateID(t *testing.T) {
+	ctx := context.Background()
+	fixed := job.IDGeneratorFunc(func() string { return "job-fixed" })
+	manager := NewManagerWithIDGenerator(NewMemoryStore(), NewPriorityQueue(), newTestRegistry(t), fixed)
+
+	if _, err := manager.Submit(ctx, &job.JobRequest{Type: job.JobTypeCommand, Command: "true"}); err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	if _, err := manager.Submit(ctx, &job.JobRequest{Type: job.JobTypeCommand, Command: "true"}); !job.IsConflictError(err) {
+		t.Errorf("Expected conflict for a duplicate ID, got %v", err)
+	}
+}
+
+func TestManager_SubmitDefaultRetries(t *testing.T) {
+	ctx := context.Background()
+	scheduler, manager, _ := newTestScheduler(t)
+	manager.SetScheduler(scheduler)
+	manager.SetDefaultRetries(map[job.JobType]int{job.JobTypeHTTP: 3})
+	three := 3
+	scheduler.SetRetryPolicy(job.RetryPolicy{MaxAttempts: &three})
+
+	none, five := 0, 5
+	tests := []struct {
+		name    string
+		request *job.JobRequest
+		want    int
+	}{
+		{"http uses type default", &job.JobRequest{Type: job.JobTypeHTTP, URL: "http://example.com"}, 3},
+		{"command uses scheduler max attempts", &job.JobRequest{Type: job.JobTypeCommand, Command: "true"}, 2},
+		{"explicit zero wins", &job.JobRequest{Type: job.JobTypeHTTP, URL: "http://example.com", Retries: &none}, 0},
+		{"policy max attempts win", &job.JobRequest{Type: job.JobTypeHTTP, URL: "http://example.com", RetryPolicy: &job.RetryPolicy{MaxAttempts: &five}}, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j, err := manager.Submit(ctx, tt.request)
+			if err != nil {
+				t.Fatalf("Submit() error = %v", err)
+			}
+			if j.Retries != tt.want {
+				t.Errorf("Expected %d retries, got %d", tt.want, j.Retries)
+			}
+		})
+	}
+}
+
+func TestManager_SubmitJobTimeouts(t *testing.T) {
+	ctx := context.Background()
+	_, manager, _ := newTestScheduler(t)
+	manager.SetJobTimeouts(30*time.Minute, time.Hour)
+
+	tests := []struct {
+		name    string
+		timeout string
+		want    time.Duration
+	}{
+		{"unset uses the default", "", 30 * time.Minute},
+		{"zero uses the default", "0s", 30 * time.Minute},
+		{"within the cap is kept", "10m", 10 * time.Minute},
+		{"over the cap is clamped", "48h", time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j, err := manager.Submit(ctx, &job.JobRequest{Type: job.JobTypeCommand, Command: "true", Timeout: tt.timeout})
+			if err != nil {
+				t.Fatalf("Submit() error = %v", err)
+			}
+			if j.Timeout != tt.want {
+				t.Errorf("Expected a timeout of %v, got %v", tt.want, j.Timeout)
+			}
+		})
+	}
+}
+
+func TestManager_CancelJobDependents(t *testing.T) {
+	ctx := context.Background()
+
+	// test and lint depend on fetch; deploy depends on test.
+	// unrelated has no dependencies.
+	submit := func(t *testing.T, manager *Manager, dependsOn ...string) string {
+		t.Helper()
+		j, err := manager.Submit(ctx, &job.JobRequest{Type: job.JobTypeCommand, Command: "true", DependsOn: dependsOn})
+		if err != nil {
+			t.Fatalf("Submit() error = %v", err)
+		}
+		return j.ID
+	}
+
+	tests := []struct {
+		policy DependencyPolicy
+		want   job.JobStatus
+	}{
+		{DependentsCancel, job.JobStatusCancelled},
+		{DependentsFail, job.JobStatusFailed},
+		{DependentsIgnore, job.JobStatusQueued},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			scheduler, manager, store := newTestScheduler(t, &fakeWorker{id: "w1", capacity: 10, healthy: true})
+			manager.SetDependencyPolicy(tt.policy)
+			scheduler.SetDependencyPolicy(tt.policy)
+
+			fetch := submit(t, manager)
+			test := submit(t, manager, fetch)
+			lint := submit(t, manager, fetch)
+			deploy := submit(t, manager, test)
+			unrelated := submit(t, manager)
+
+			if err := manager.CancelJob(ctx, fetch); err != nil {
+				t.Fatalf("CancelJob() error = %v", err)
+			}
+
+			reasons := map[string]string{
+				test:   "dependency " + fetch + " was cancelled",
+				lint:   "dependency " + fetch + " was cancelled",
+				deploy: "dependency " + test + " was " + string(tt.want),
+			}
+			for id, reason := range reasons {
+				j, _ := store.Get(ctx, id)
+				if j.Status != tt.want {
+					t.Errorf("Expected %s to be %s, got %s", id, tt.want, j.Status)
+				}
+				if tt.policy == DependentsIgnore {
+					if len(j.History) != 0 {
+						t.Errorf("Expected no history for %s, got %+v", id, j.History)
+					}
+					continue
+				}
+				if len(j.History) != 1 || j.History[0].Reason != reason || j.History[0].Status != tt.want {
+					t.Errorf("Expected history reason %q for %s, got %+v", reason, id, j.History)
+				}
+				if j.Error != reason {
+					t.Errorf("Expected error %q for %s, got %q", reason, id, j.Error)
+				}
+			}
+
+			j, _ := store.Get(ctx, unrelated)
+			if j.Status != job.JobStatusQueued {
+				t.Errorf("Expected unrelated job to stay queued, got %s", j.Status)
+			}
+
+			// Ignored dependents run without waiting for the cancelled job
+			if tt.policy == DependentsIgnore {
+				next, err := scheduler.GetNextJob(ctx)
+				if err != nil || next.ID != test {
+					t.Errorf("Expected %s to be dispatched despite its cancelled dependency, got %v (%v)", test, next, err)
+				}
+			}
+		})
+	}
+}
+
+func TestManager_CancelJobDependencyCycle(t *testing.T) {
+	ctx := context.Background()
+	_, manager, store := newTestScheduler(t)
+
+	// A cycle can't be submitted, but nothing stops one being stored
+	for _, j := range []*job.Job{
+		{ID: "job-a", Status: job.JobStatusQueued, DependsOn: []string{"job-b"}},
+		{ID: "job-b", Status: job.JobStatusPending, DependsOn: []string{"job-a"}},
+	} {
+		if err := store.Create(ctx, j); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	if err := manager.CancelJob(ctx, "job-a"); err != nil {
+		t.Fatalf("CancelJob() error = %v", err)
+	}
+
+	b, _ := store.Get(ctx, "job-b")
+	if b.Status != job.JobStatusCancelled {
+		t.Errorf("Expected job-b to be cancelled, got %s", b.Status)
+	}
+	a, _ := store.Get(ctx, "job-a")
+	if len(a.History) != 0 {
+		t.Errorf("Expected the cancelled job not to be revisited, got %+v", a.History)
+	}
+}
+
+// cancellingWorker records the jobs it is asked to cancel
+type cancellingWorker struct {
+	fakeWorker
+	cancelled []string
+}
+
+func (w *cancellingWorker) CancelJob(jobID string) error {
+	w.cancelled = append(w.cancelled, jobID)
+	return nil
+}
+
+func TestManager_CancelJobStopsRunningJob(t *testing.T) {
+	ctx := context.Background()
+
+	worker := &cancellingWorker{fakeWorker: fakeWorker{id: "worker-1", capacity: 1, healthy: true}}
+	store := NewMemoryStore()
+	queue := NewPriorityQueue()
+	registry := NewMemoryWorkerRegistry()
+	registry.Register(ctx, worker)
+	manager := NewManager(store, queue, registry)
+
+	for _, j := range []*job.Job{
+		{ID: "job-running", Status: job.JobStatusRunning, WorkerID: "worker-1"},
+		{ID: "job-queued", Status: job.JobStatusQueued},
+		{ID: "job-done", Status: job.JobStatusCompleted, WorkerID: "worker-1"},
+	} {
+		if err := store.Create(ctx, j); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	for _, id := range []string{"job-running", "job-queued"} {
+		if err := manager.CancelJob(ctx, id); err != nil {
+			t.Fatalf("CancelJob(%s) error = %v", id, err)
+		}
+		if j, _ := store.Get(ctx, id); j.Status != job.JobStatusCancelled {
+			t.Errorf("Expected %s to be cancelled, got %s", id, j.Status)
+		}
+	}
+	if len(worker.cancelled) != 1 || worker.cancelled[0] != "job-running" {
+		t.Errorf("Expected only the running job to be cancelled on its worker, got %v", worker.cancelled)
+	}
+
+	if err := manager.CancelJob(ctx, "job-done"); !job.IsConflictError(err) {
+		t.Errorf("Expected a conflict cancelling a finished job, got %v", err)
+	}
+	if j, _ := store.Get(ctx, "job-done"); j.Status != job.JobStatusCompleted {
+		t.Errorf("Expected the finished job to keep its status, got %s", j.Status)
+	}
+}
+
+func TestManager_PushNotifiesRemoteWorkers(t *testing.T) {
+	ctx := context.Background()
+
+	notified := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		notified <- r.Method + " " + r.URL.Path
+	}))
+	defer server.Close()
+
+	registry := NewMemoryWorkerRegistry()
+	registry.Register(ctx, NewRemoteWorker(job.WorkerRegistration{ID: "worker-1", Capacity: 1, Address: server.URL}))
+	manager := NewManager(NewMemoryStore(), NewPriorityQueue(), registry)
+	manager.SetDeliveryMode(DeliveryPush)
+
+	if _, err := manager.Submit(ctx, &job.JobRequest{Type: job.JobTypeCommand, Command: "true"}); err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+
+	select {
+	case request := <-notified:
+		if request != "POST /notify" {
+			t.Errorf("Expected the worker's notify endpoint to be called, got %s", request)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the remote worker to be notified of the queued job")
+	}
+}
+
+func TestManager_SubmitRejectsUnknownDependency(t *testing.T) {
+	_, manager, _ := newTestScheduler(t)
+
+	_, err := manager.Submit(context.Background(), &job.JobRequest{Type: job.JobTypeCommand, Command: "true", DependsOn: []string{"job-missing"}})
+	if !job.IsValidationError(err) {
+		t.Errorf("Expected a validation error, got %v", err)
+	}
+}
+
+func TestManager_SubmitRejectsDependencyCycle(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	fixed := job.IDGeneratorFunc(func() string { return "job-c" })
+	manager := NewManagerWithIDGenerator(store, NewPriorityQueue(), newTestRegistry(t), fixed)
+
+	// job-a already waits on the ID the generator is about to hand out
+	for _, j := range []*job.Job{
+		{ID: "job-a", Status: job.JobStatusQueued, DependsOn: []string{"job-c"}},
+		{ID: "job-b", Status: job.JobStatusQueued, DependsOn: []string{"job-a"}},
+	} {
+		if err := store.Create(ctx, j); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	_, err := manager.Submit(ctx, &job.JobRequest{Type: job.JobTypeCommand, Command: "true", DependsOn: []string{"job-b"}})
+	if !job.IsValidationError(err) || !strings.Contains(err.Error(), "job-c -> job-b -> job-a -> job-c") {
+		t.Errorf("Expected a circular dependency error, got %v", err)
+	}
+}
+
+func TestDefaultScheduler_MarkFailedFailsDependents(t *testing.T) {
+	ctx := context.Background()
+	sched, manager, store := newTestScheduler(t)
+
+	build, err := manager.Submit(ctx, &job.JobRequest{Type: job.JobTypeCommand, Command: "make"})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	test, err := manager.Submit(ctx, &job.JobRequest{Type: job.JobTypeCommand, Command: "make test", DependsOn: []string{build.ID}})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	if err := store.UpdateStatus(ctx, build.ID, job.JobStatusRunning); err != nil {
+		t.Fatalf("UpdateStatus() error = %v", err)
+	}
+
+	if err := sched.MarkFailed(ctx, build.ID, errors.New("exit status 2")); err != nil {
+		t.Fatalf("MarkFailed() error = %v", err)
+	}
+
+	j, _ := store.Get(ctx, test.ID)
+	if j.Status != job.JobStatusFailed || j.Error != "dependency "+build.ID+" failed" {
+		t.Errorf("Expected the dependent to fail, got %s: %q", j.Status, j.Error)
+	}
+}
+
+// statusRecordingStore records the status of every job update
+type statusRecordingStore struct {
+	*MemoryStore
+	statuses []job.JobStatus
+}
+
+func (s *statusRecordingStore) Update(ctx context.Context, j *job.Job) error {
+	s.statuses = append(s.statuses, j.Status)
+	return s.MemoryStore.Update(ctx, j)
+}
+
+func TestDefaultScheduler_MarkFailedRetries(t *testing.T) {
+	ctx := context.Background()
+	store := &statusRecordingStore{MemoryStore: NewMemoryStore()}
+	queue := NewPriorityQueue()
+	scheduler := NewDefaultScheduler(store, queue, newTestRegistry(t))
+
+	if err := store.Create(ctx, &job.Job{ID: "job-flaky", Status: job.JobStatusRunning, WorkerID: "w1", Retries: 1}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	// The first failure goes through retrying back to the queue
+	if err := scheduler.MarkFailed(ctx, "job-flaky", fmt.Errorf("exit status 1")); err != nil {
+		t.Fatalf("MarkFailed() error = %v", err)
+	}
+	want := []job.JobStatus{job.JobStatusRetrying, job.JobStatusQueued}
+	if fmt.Sprint(store.statuses) != fmt.Sprint(want) {
+		t.Errorf("Expected transitions %v, got %v", want, store.statuses)
+	}
+
+	retried, _ := store.Get(ctx, "job-flaky")
+	if retried.Status != job.JobStatusQueued || retried.RetryCount != 1 || retried.WorkerID != "" {
+		t.Errorf("Expected an unassigned queued job with one retry used, got %+v", retried)
+	}
+	if next, err := queue.Dequeue(ctx); err != nil || next.ID != "job-flaky" {
+		t.Fatalf("Expected job-flaky back in the queue, got %v, %v", next, err)
+	}
+
+	// With its retries used up, the next failure is final
+	retried.UpdateStatus(job.JobStatusRunning)
+	store.MemoryStore.Update(ctx, retried)
+	if err := scheduler.MarkFailed(ctx, "job-flaky", fmt.Errorf("exit status 1")); err != nil {
+		t.Fatalf("MarkFailed() error = %v", err)
+	}
+	if failed, _ := store.Get(ctx, "job-flaky"); failed.Status != job.JobStatusFailed {
+		t.Errorf("Expected job to fail once retries are exhausted, got %s", failed.Status)
+	}
+}
+
+func TestDefaultScheduler_AffinityKey(t *testing.T) {
+	ctx := context.Background()
+	w1 := &fakeWorker{id: "w1", capacity: 2, healthy: true}
+	w2 := &fakeWorker{id: "w2", capacity: 2, healthy: true}
+	store := NewMemoryStore()
+	queue := NewPriorityQueue()
+	fake := clock.NewFake(time.Now())
+	scheduler := NewDefaultSchedulerWithAffinity(store, queue, newTestRegistry(t, w1, w2), time.Minute, fake)
+	manager := NewManager(store, queue, newTestRegistry(t))
+
+	next := func(key string, cost ...int) string {
+		t.Helper()
+		request := &job.JobRequest{Type: job.JobTypeCommand, Command: "true", AffinityKey: key}
+		if len(cost) > 0 {
+			request.Cost = cost[0]
+		}
+		if _, err := manager.Submit(ctx, request); err != nil {
+			t.Fatalf("Submit() error = %v", err)
+		}
+		j, err := scheduler.GetNextJob(ctx)
+		if err != nil {
+			t.Fatalf("GetNextJob() error = %v", err)
+		}
+		return j.WorkerID
+	}
+
+	if got := next("deploy"); got != "w1" {
+		t.Fatalf("Expected the first job on the least-loaded w1, got %s", got)
+	}
+
+	// w1 is now busier than w2, but the key keeps related jobs on it
+	w1.load = 1
+	if got := next("deploy"); got != "w1" {
+		t.Errorf("Expected the same-key job to stick to w1, got %s", got)
+	}
+	if got := next(""); got != "w2" {
+		t.Errorf("Expected a job without a key on the least-loaded w2, got %s", got)
+	}
+
+	// A full worker falls back to normal selection, which becomes the new preference
+	w1.load = 2
+	if got := next("deploy"); got != "w2" {
+		t.Errorf("Expected a fallback to w2 while w1 is full, got %s", got)
+	}
+	w1.load = 0
+	w2.load = 1
+	if got := next("deploy"); got != "w2" {
+		t.Errorf("Expected the key to follow its last worker w2, got %s", got)
+	}
+
+	// Once the mapping expires the key no longer matters
+	fake.Advance(2 * time.Minute)
+	if got := next("deploy"); got != "w1" {
+		t.Errorf("Expected an expired key to use the least-loaded w1, got %s", got)
+	}
+
+	// Room is judged by cost, so a job too big for what is left on its key's
+	// worker goes to one it fits on
+	w1.load = 1
+	w2.load = 0
+	if got := next("deploy", 2); got != "w2" {
+		t.Errorf("Expected the cost 2 job on w2, the only worker with room for it, got %s", got)
+	}
+}
+
+func TestManager_ExplainScheduling(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		workers  []*fakeWorker
+		setup    func(t *testing.T, manager *Manager, store *MemoryStore) string
+		want     []job.BlockReason
+		position int
+	}{
+		{
+			name:    "dispatchable",
+			workers: []*fakeWorker{{id: "w1", capacity: 2, healthy: true}},
+			setup: func(t *testing.T, manager *Manager, store *MemoryStore) string {
+				if _, err := manager.Submit(ctx, &job.JobRequest{Type: job.JobTypeCommand, Command: "true", Priority: 5}); err != nil {
+					t.Fatalf("Submit() error = %v", err)
+				}
+				j, err := manager.Submit(ctx, &job.JobRequest{Type: job.JobTypeCommand, Command: "true"})
+				if err != nil {
+					t.Fatalf("Submit() error = %v", err)
+				}
+				return j.ID
+			},
+			position: 2,
+		},
+		{
+			name:    "not queued",
+			workers: []*fakeWorker{{id: "w1", capacity: 2, healthy: true}},
+			setup: func(t *testing.T, manager *Manager, store *MemoryStore) string {
+				store.Create(ctx, &job.Job{ID: "job-running", Status: job.JobStatusRunning, WorkerID: "w1"})
+				return "job-running"
+			},
+			want: []job.BlockReason{job.BlockedNotQueued},
+		},
+		{
+			name:    "dependency",
+			workers: []*fakeWorker{{id: "w1", capacity: 2, healthy: true}},
+			setup: func(t *testing.T, manager *Manager, store *MemoryStore) string {
+				store.Create(ctx, &job.Job{ID: "job-build", Status: job.JobStatusRunning, WorkerID: "w1"})
+				j, err := manager.Submit(ctx, &job.JobRequest{Type: job.JobTypeCommand, Command: "true", DependsOn: []string{"job-build"}})
+				if err != nil {
+					t.Fatalf("Submit() error = %v", err)
+				}
+				return j.ID
+			},
+			want:     []job.BlockReason{job.BlockedDependency},
+			position: 1,
+		},
+		{
+			name:    "ignored cancelled dependency",
+			workers: []*fakeWorker{{id: "w1", capacity: 2, healthy: true}},
+			setup: func(t *testing.T, manager *Manager, store *MemoryStore) string {
+				manager.SetDependencyPolicy(DependentsIgnore)
+				store.Create(ctx, &job.Job{ID: "job-build", Status: job.JobStatusCancelled})
+				j, err := manager.Submit(ctx, &job.JobRequest{Type: job.JobTypeCommand, Command: "true", DependsOn: []string{"job-build"}})
+				if err != nil {
+					t.Fatalf("Submit() error = %v", err)
+				}
+				return j.ID
+			},
+			position: 1,
+		},
+		{
+			name:    "mutex held",
+			workers: []*fakeWorker{{id: "w1", capacity: 2, healthy: true}},
+			setup: func(t *testing.T, manager *Manager, store *MemoryStore) string {
+				store.Create(ctx, &job.Job{ID: "job-migrate", Status: job.JobStatusRunning, WorkerID: "w1", MutexKey: "db"})
+				j, err := manager.Submit(ctx, &job.JobRequest{Type: job.JobTypeCommand, Command: "true", MutexKey: "db"})
+				if err != nil {
+					t.Fatalf("Submit() error = %v", err)
+				}
+				return j.ID
+			},
+			want:     []job.BlockReason{job.BlockedMutex},
+			position: 1,
+		},
+		{
+			name:    "no worker",
+			workers: []*fakeWorker{{id: "w1", capacity: 2, load: 2, healthy: true}},
+			setup: func(t *testing.T, manager *Manager, store *MemoryStore) string {
+				j, err := manager.Submit(ctx, &job.JobRequest{Type: job.JobTypeCommand, Command: "true"})
+				if err != nil {
+					t.Fatalf("Submit() error = %v", err)
+				}
+				return j.ID
+			},
+			want:     []job.BlockReason{job.BlockedNoWorker},
+			position: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, manager, store := newTestScheduler(t, tt.workers...)
+			jobID := tt.setup(t, manager, store)
+
+			explanation, err := manager.ExplainScheduling(ctx, jobID)
+			if err != nil {
+				t.Fatalf("ExplainScheduling() error = %v", err)
+			}
+
+			var got []job.BlockReason
+			for _, blocker := range explanation.Blockers {
+				got = append(got, blocker.Reason)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("Expected blockers %v, got %+v", tt.want, explanation.Blockers)
+			}
+			if explanation.Dispatchable != (len(tt.want) == 0) {
+				t.Errorf("Expected dispatchable %v, got %v", len(tt.want) == 0, explanation.Dispatchable)
+			}
+			if explanation.Position != tt.position {
+				t.Errorf("Expected position %d, got %d", tt.position, explanation.Position)
+			}
+		})
+	}
+}
+
+func TestDefaultScheduler_GetNextJobWaitsForDependencies(t *testing.T) {
+	ctx := context.Background()
+	scheduler, manager, store := newTestScheduler(t, &fakeWorker{id: "w1", capacity: 2, healthy: true})
+
+	store.Create(ctx, &job.Job{ID: "job-build", Status: job.JobStatusRunning, WorkerID: "w1"})
+	deploy, err := manager.Submit(ctx, &job.JobRequest{Type: job.JobTypeCommand, Command: "true", DependsOn: []string{"job-build"}})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+
+	if _, err := scheduler.GetNextJob(ctx); err != job.ErrQueueEmpty {
+		t.Fatalf("Expected the dependent job to wait, got %v", err)
+	}
+
+	if err := scheduler.MarkCompleted(ctx, "job-build", nil); err != nil {
+		t.Fatalf("MarkCompleted() error = %v", err)
+	}
+	next, err := scheduler.GetNextJob(ctx)
+	if err != nil {
+		t.Fatalf("GetNextJob() error = %v", err)
+	}
+	if next.ID != deploy.ID {
+		t.Errorf("Expected %s once its dependency completed, got %s", deploy.ID, next.ID)
+	}
+}
+
+func TestDefaultScheduler_GetNextJobMatchesNodeSelector(t *testing.T) {
+	ctx := context.Background()
+	gpu := &fakeWorker{id: "gpu", capacity: 2, healthy: false, labels: map[string]string{"gpu": "true"}}
+	scheduler, manager, store := newTestScheduler(t,
+		&fakeWorker{id: "cpu", capacity: 2, healthy: true},
+		gpu,
+	)
+
+	training, err := manager.Submit(ctx, &job.JobRequest{
+		Type: job.JobTypeCommand, Command: "train", Priority: 10, NodeSelector: map[string]string{"gpu": "true"},
+	})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	plain, err := manager.Submit(ctx, &job.JobRequest{Type: job.JobTypeCommand, Command: "true"})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+
+	// With no GPU worker available, the selective job waits without holding
+	// up the job behind it
+	next, err := scheduler.GetNextJob(ctx)
+	if err != nil {
+		t.Fatalf("GetNextJob() error = %v", err)
+	}
+	if next.ID != plain.ID || next.WorkerID != "cpu" {
+		t.Errorf("Expected %s on cpu, got %s on %s", plain.ID, next.ID, next.WorkerID)
+	}
+	if _, err := scheduler.GetNextJob(ctx); err != job.ErrQueueEmpty {
+		t.Fatalf("Expected no dispatchable job, got %v", err)
+	}
+	if stored, _ := store.Get(ctx, training.ID); stored.Status != job.JobStatusQueued {
+		t.Errorf("Expected the selective job to stay queued, got %s", stored.Status)
+	}
+
+	gpu.healthy = true
+	next, err = scheduler.GetNextJob(ctx)
+	if err != nil {
+		t.Fatalf("GetNextJob() error = %v", err)
+	}
+	if next.ID != training.ID || next.WorkerID != "gpu" {
+		t.Errorf("Expected %s on gpu, got %s on %s", training.ID, next.ID, next.WorkerID)
+	}
+}
+
+func TestCronScheduler_Tick(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2024, 3, 1, 12, 1, 0, 0, time.UTC))
+	_, manager, store := newTestScheduler(t)
+	cron := NewCronSchedulerWithClock(store, manager, fake)
+
+	parent, err := manager.Submit(ctx, &job.JobRequest{Type: job.JobTypeCommand, Command: "backup", Tags: []string{"nightly"}, Schedule: "*/5 * * * *"})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	if parent.Status != job.JobStatusPending {
+		t.Errorf("Expected the recurring job to stay pending, got %s", parent.Status)
+	}
+	if size, _ := manager.queue.Size(ctx); size != 0 {
+		t.Errorf("Expected the recurring job not to be queued, got queue size %d", size)
+	}
+
+	// The first check only schedules the next run
+	if runs, err := cron.Tick(ctx); err != nil || len(runs) != 0 {
+		t.Fatalf("Tick() = %v, %v, want no runs", runs, err)
+	}
+	stored, _ := store.Get(ctx, parent.ID)
+	if want := time.Date(2024, 3, 1, 12, 5, 0, 0, time.UTC); stored.NextRunAt == nil || !stored.NextRunAt.Equal(want) {
+		t.Fatalf("Expected next run at %v, got %v", want, stored.NextRunAt)
+	}
+
+	fake.Advance(3 * time.Minute)
+	if runs, _ := cron.Tick(ctx); len(runs) != 0 {
+		t.Errorf("Expected no run before the schedule fires, got %d", len(runs))
+	}
+
+	fake.Advance(2 * time.Minute)
+	runs, err := cron.Tick(ctx)
+	if err != nil {
+		t.Fatalf("Tick() error = %v", err)
+	}
+	if len(runs) != 1 {
+		t.Fatalf("Expected one run, got %d", len(runs))
+	}
+	run := runs[0]
+	if run.ParentID != parent.ID || run.Command != "backup" || run.Schedule != "" || run.Status != job.JobStatusQueued {
+		t.Errorf("Expected a queued run of %s, got %+v", parent.ID, run)
+	}
+	stored, _ = store.Get(ctx, parent.ID)
+	if want := time.Date(2024, 3, 1, 12, 10, 0, 0, time.UTC); !stored.NextRunAt.Equal(want) {
+		t.Errorf("Expected next run at %v, got %v", want, stored.NextRunAt)
+	}
+
+	children, _ := store.List(ctx, job.Filter{Field: "parent_id", Operator: "eq", Value: parent.ID})
+	if len(children) != 1 || children[0].ID != run.ID {
+		t.Errorf("Expected the run to be listed by parent_id, got %v", children)
+	}
+
+	// Cancelling the recurring job stops future runs
+	if err := manager.CancelJob(ctx, parent.ID); err != nil {
+		t.Fatalf("CancelJob() error = %v", err)
+	}
+	fake.Advance(10 * time.Minute)
+	if runs, _ := cron.Tick(ctx); len(runs) != 0 {
+		t.Errorf("Expected no runs after cancelling, got %d", len(runs))
+	}
+}
+
+func TestManager_SaveJobResultRetries(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+	store := NewMemoryStore()
+	queue := NewPriorityQueue()
+	registry := newTestRegistry(t, &fakeWorker{id: "w1", capacity: 2, healthy: true})
+	scheduler := NewDefaultSchedulerWithAffinity(store, queue, registry, defaultAffinityTTL, fake)
+	scheduler.SetRetryBackoff(10*time.Second, time.Minute)
+	manager := NewManager(store, queue, registry)
+	manager.SetScheduler(scheduler)
+
+	retries := 1
+	submitted, err := manager.Submit(ctx, &job.JobRequest{Type: job.JobTypeCommand, Command: "false", Retries: &retries})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	if _, err := scheduler.GetNextJob(ctx); err != nil {
+		t.Fatalf("GetNextJob() error = %v", err)
+	}
+
+	// The failed run waits out its backoff in retrying, then is queued again
+	failed := &job.JobResult{JobID: submitted.ID, Status: job.JobStatusFailed, Error: "exit status 1"}
+	if err := manager.SaveJobResult(ctx, failed); err != nil {
+		t.Fatalf("SaveJobResult() error = %v", err)
+	}
+	if j, _ := store.Get(ctx, submitted.ID); j.Status != job.JobStatusRetrying || j.RetryAt == nil || j.Error != "exit status 1" {
+		t.Fatalf("Expected the job to be retrying with its error, got %s at %v (%q)", j.Status, j.RetryAt, j.Error)
+	}
+	if _, err := scheduler.GetNextJob(ctx); err != job.ErrQueueEmpty {
+		t.Fatalf("Expected the job to wait out its backoff, got %v", err)
+	}
+
+	fake.Advance(10 * time.Second)
+	next, err := scheduler.GetNextJob(ctx)
+	if err != nil || next.ID != submitted.ID {
+		t.Fatalf("Expected the retry to be dispatched, got %v (%v)", next, err)
+	}
+	var statuses []job.JobStatus
+	for _, event := range next.Events {
+		statuses = append(statuses, event.Status)
+	}
+	want := []job.JobStatus{job.JobStatusPending, job.JobStatusQueued, job.JobStatusRunning, job.JobStatusRetrying, job.JobStatusQueued, job.JobStatusRunning}
+	if fmt.Sprint(statuses) != fmt.Sprint(want) {
+		t.Errorf("Expected transitions %v, got %v", want, statuses)
+	}
+
+	// The retry completes the job
+	completed := &job.JobResult{JobID: submitted.ID, Status: job.JobStatusCompleted, Output: "done"}
+	if err := manager.SaveJobResult(ctx, completed); err != nil {
+		t.Fatalf("SaveJobResult() error = %v", err)
+	}
+	if j, _ := store.Get(ctx, submitted.ID); j.Status != job.JobStatusCompleted || j.Output != "done" || j.RetryCount != 1 {
+		t.Errorf("Expected the job completed on its retry, got %+v", j)
+	}
+}
+
+func TestManager_MaxAttemptsCountsRuns(t *testing.T) {
+	ctx := context.Background()
+	scheduler, manager, store := newTestScheduler(t, &fakeWorker{id: "w1", capacity: 1, healthy: true})
+	manager.SetScheduler(scheduler)
+
+	// max_attempts counts the first run, so three attempts are two retries
+	attempts := 3
+	submitted, err := manager.Submit(ctx, &job.JobRequest{Type: job.JobTypeCommand, Command: "false", RetryPolicy: &job.RetryPolicy{MaxAttempts: &attempts}})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+
+	runs := 0
+	for {
+		if _, err := scheduler.GetNextJob(ctx); err == job.ErrQueueEmpty {
+			break
+		} else if err != nil {
+			t.Fatalf("GetNextJob() error = %v", err)
+		}
+		runs++
+		failed := &job.JobResult{JobID: submitted.ID, Status: job.JobStatusFailed, Error: "exit status 1"}
+		if err := manager.SaveJobResult(ctx, failed); err != nil {
+			t.Fatalf("SaveJobResult() error = %v", err)
+		}
+	}
+
+	if runs != attempts {
+		t.Errorf("Expected %d runs, got %d", attempts, runs)
+	}
+	if j, _ := store.Get(ctx, submitted.ID); j.Status != job.JobStatusFailed {
+		t.Errorf("Expected the job to fail after its last attempt, got %s", j.Status)
+	}
+}
+
+func TestDefaultScheduler_RetryBackoff(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+	store := NewMemoryStore()
+	scheduler := NewDefaultSchedulerWithAffinity(store, NewPriorityQueue(), newTestRegistry(t, &fakeWorker{id: "w1", capacity: 2, healthy: true}), defaultAffinityTTL, fake)
+	scheduler.SetRetryBackoff(10*time.Second, 15*time.Second)
+
+	if err := store.Create(ctx, &job.Job{ID: "job-flaky", Status: job.JobStatusRunning, WorkerID: "w1", Retries: 3}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	// Each retry waits twice as long as the one before, up to the maximum
+	for _, delay := range []time.Duration{10 * time.Second, 15 * time.Second, 15 * time.Second} {
+		if err := scheduler.MarkFailed(ctx, "job-flaky", fmt.Errorf("exit status 1")); err != nil {
+			t.Fatalf("MarkFailed() error = %v", err)
+		}
+		j, _ := store.Get(ctx, "job-flaky")
+		if j.Status != job.JobStatusRetrying || j.RetryAt == nil || !j.RetryAt.Equal(fake.Now().Add(delay)) {
+			t.Fatalf("Expected the job to retry after %v, got %s at %v", delay, j.Status, j.RetryAt)
+		}
+
+		fake.Advance(delay - time.Second)
+		if _, err := scheduler.GetNextJob(ctx); err != job.ErrQueueEmpty {
+			t.Fatalf("Expected the job to wait out its backoff, got %v", err)
+		}
+
+		fake.Advance(time.Second)
+		next, err := scheduler.GetNextJob(ctx)
+		if err != nil {
+			t.Fatalf("GetNextJob() error = %v", err)
+		}
+		if next.ID != "job-flaky" || next.RetryAt != nil {
+			t.Errorf("Expected the job to be dispatched again with its retry time cleared, got %+v", next)
+		}
+	}
+
+	// Out of retries, the job fails for good
+	if err := scheduler.MarkFailed(ctx, "job-flaky", fmt.Errorf("exit status 1")); err != nil {
+		t.Fatalf("MarkFailed() error = %v", err)
+	}
+	if j, _ := store.Get(ctx, "job-flaky"); j.Status != job.JobStatusFailed || j.RetryCount != 3 {
+		t.Errorf("Expected the job to fail after 3 retries, got %s after %d", j.Status, j.RetryCount)
+	}
+}
+
+func TestDefaultScheduler_JobRetryPolicy(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+	store := NewMemoryStore()
+	scheduler := NewDefaultSchedulerWithAffinity(store, NewPriorityQueue(), newTestRegistry(t, &fakeWorker{id: "w1", capacity: 2, healthy: true}), defaultAffinityTTL, fake)
+	scheduler.SetRetryPolicy(job.RetryPolicy{BaseDelay: time.Hour, Jitter: 1})
+	scheduler.random = func() float64 { return 0.5 }
+
+	seed := func(id string, policy *job.RetryPolicy) {
+		t.Helper()
+		if err := store.Create(ctx, &job.Job{ID: id, Status: job.JobStatusRunning, WorkerID: "w1", Retries: 3, RetryPolicy: policy}); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+	seed("job-default", nil)
+	seed("job-own", &job.RetryPolicy{BaseDelay: 10 * time.Second, Multiplier: 3, Jitter: 0.5})
+
+	for id, want := range map[string]time.Duration{
+		// Full jitter puts the delay halfway through the scheduler's hour
+		"job-default": 30 * time.Minute,
+		// The job's own policy replaces the scheduler's: 5s fixed plus half the jittered 5s
+		"job-own": 7500 * time.Millisecond,
+	} {
+		if err := scheduler.MarkFailed(ctx, id, fmt.Errorf("exit status 1")); err != nil {
+			t.Fatalf("MarkFailed() error = %v", err)
+		}
+		j, _ := store.Get(ctx, id)
+		if j.Status != job.JobStatusRetrying || j.RetryAt == nil || !j.RetryAt.Equal(fake.Now().Add(want)) {
+			t.Errorf("Expected %s to retry after %v, got %s at %v", id, want, j.Status, j.RetryAt)
+		}
+	}
+}
+
+// claimingStore is a MemoryStore that claims queued jobs itself, as
+// PostgresStore does, and announces jobs queued by other schedulers
+type claimingStore struct {
+	*MemoryStore
+	queued chan string
+}
+
+func (s *claimingStore) ClaimNext(ctx context.Context, workerID string, eligible func(*job.Job) bool) (*job.Job, error) {
+	queued, _ := s.List(ctx, job.Filter{Field: "status", Operator: "eq", Value: string(job.JobStatusQueued)})
+	sort.Slice(queued, func(a, b int) bool { return queued[a].Priority > queued[b].Priority })
+	for _, j := range queued {
+		if eligible(j) {
+			return s.AssignWorker(ctx, j.ID, workerID)
+		}
+	}
+	return nil, job.ErrQueueEmpty
+}
+
+func (s *claimingStore) ListenQueued(ctx context.Context) (<-chan string, error) {
+	return s.queued, nil
+}
+
+func TestDefaultScheduler_ClaimsThroughStore(t *testing.T) {
+	ctx := context.Background()
+	store := &claimingStore{MemoryStore: NewMemoryStore()}
+	queue := NewPriorityQueue()
+	scheduler := NewDefaultScheduler(store, queue, newTestRegistry(t,
+		&fakeWorker{id: "busy", capacity: 2, load: 1, healthy: true},
+		&fakeWorker{id: "gpu", capacity: 2, healthy: true, labels: map[string]string{"gpu": "true"}},
+	))
+
+	// A job another scheduler queued is only in the store, not the local queue
+	for _, j := range []*job.Job{
+		{ID: "job-local", Status: job.JobStatusPending, Priority: 1},
+		{ID: "job-remote", Status: job.JobStatusQueued, Priority: 5, NodeSelector: map[string]string{"gpu": "true"}},
+	} {
+		if err := store.Create(ctx, j); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+	local, _ := store.Get(ctx, "job-local")
+	if err := scheduler.Schedule(ctx, local); err != nil {
+		t.Fatalf("Schedule() error = %v", err)
+	}
+
+	// The least-loaded worker claims first, and only what it can take
+	first, err := scheduler.GetNextJob(ctx)
+	if err != nil || first.ID != "job-remote" || first.WorkerID != "gpu" {
+		t.Fatalf("Expected job-remote claimed for gpu, got %+v (%v)", first, err)
+	}
+	second, err := scheduler.GetNextJob(ctx)
+	if err != nil || second.ID != "job-local" || second.Status != job.JobStatusRunning {
+		t.Fatalf("Expected job-local claimed next, got %+v (%v)", second, err)
+	}
+	if size, _ := queue.Size(ctx); size != 0 {
+		t.Errorf("Expected claimed jobs to leave the local queue, got %d queued", size)
+	}
+	if _, err := scheduler.GetNextJob(ctx); err != job.ErrQueueEmpty {
+		t.Errorf("Expected an empty queue, got %v", err)
+	}
+}
+
+// notifiedWorker counts the wake-ups it receives
+type notifiedWorker struct {
+	fakeWorker
+	notified chan struct{}
+}
+
+func (w *notifiedWorker) Notify() { w.notified <- struct{}{} }
+
+func TestManager_PushWakesWorkersForStoreQueuedJobs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := &claimingStore{MemoryStore: NewMemoryStore(), queued: make(chan string, 1)}
+	registry := NewMemoryWorkerRegistry()
+	worker := &notifiedWorker{fakeWorker: fakeWorker{id: "w1", capacity: 1, healthy: true}, notified: make(chan struct{}, 1)}
+	registry.Register(ctx, worker)
+	manager := NewManager(store, NewPriorityQueue(), registry)
+
+	cfg := config.LoadConfig().Scheduler
+	cfg.JobDelivery = string(DeliveryPush)
+	cfg.RecoverOnStartup = false
+	if err := manager.Start(ctx, &cfg); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	// Another scheduler queued the job and the store announced it
+	if err := store.Create(ctx, &job.Job{ID: "job-remote", Status: job.JobStatusQueued}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	store.queued <- "job-remote"
+
+	select {
+	case <-worker.notified:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the worker to be woken for the announced job")
+	}
+}
+
+func TestManager_StartResumesRetries(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	fake := clock.NewFake(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+	store := NewMemoryStore()
+	queue := NewPriorityQueue()
+	registry := newTestRegistry(t)
+	manager := NewManager(store, queue, registry)
+	manager.SetScheduler(NewDefaultSchedulerWithAffinity(store, queue, registry, defaultAffinityTTL, fake))
+
+	retryAt := fake.Now().Add(5 * time.Second)
+	if err := store.Create(ctx, &job.Job{ID: "job-retrying", Status: job.JobStatusRetrying, RetryAt: &retryAt, Retries: 1}); err != nil {
+		t.Fatalf("failed to seed job: %v", err)
+	}
+
+	cfg := config.LoadConfig().Scheduler
+	cfg.RecoverOnStartup = false
+	if err := manager.Start(ctx, &cfg); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	// Nothing dispatches, yet the job is queued once its backoff has passed
+	fake.WaitForWaiters(1)
+	fake.Advance(4 * time.Second)
+	if j, _ := store.Get(ctx, "job-retrying"); j.Status != job.JobStatusRetrying {
+		t.Errorf("Expected the job to wait out its backoff, got %s", j.Status)
+	}
+	fake.Advance(time.Second)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if j, _ := store.Get(ctx, "job-retrying"); j.Status == job.JobStatusQueued {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the retrying job to be queued again")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if size, _ := queue.Size(ctx); size != 1 {
+		t.Errorf("Expected the resumed job in the queue, got size %d", size)
+	}
+}
+
+func TestManager_StartWaitsForWorkersBeforeRecoveringOrphans(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := NewMemoryStore()
+	queue := NewPriorityQueue()
+	registry := NewMemoryWorkerRegistry()
+	manager := NewManager(store, queue, registry)
+	for _, j := range []*job.Job{
+		{ID: "job-queued", Status: job.JobStatusQueued},
+		{ID: "job-returning", Status: job.JobStatusRunning, WorkerID: "w1"},
+		{ID: "job-orphaned", Status: job.JobStatusRunning, WorkerID: "gone"},
+	} {
+		if err := store.Create(ctx, j); err != nil {
+			t.Fatalf("failed to seed job: %v", err)
+		}
+	}
+
+	cfg := config.LoadConfig().Scheduler
+	cfg.WorkerTimeout = 100 * time.Millisecond
+	if err := manager.Start(ctx, &cfg); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	// Queued jobs are back straight away, but no worker has had time to
+	// register yet, so running jobs are left alone
+	if size, _ := queue.Size(ctx); size != 1 {
+		t.Errorf("Expected only the queued job in the queue, got %d", size)
+	}
+	if orphaned, _ := store.Get(ctx, "job-orphaned"); orphaned.Status != job.JobStatusRunning {
+		t.Errorf("Expected the orphan to be left running during the grace period, got %s", orphaned.Status)
+	}
+
+	registry.Register(ctx, &fakeWorker{id: "w1", capacity: 1, healthy: true})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if orphaned, _ := store.Get(ctx, "job-orphaned"); orphaned.Status == job.JobStatusQueued {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the orphan to be requeued after the grace period")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if returning, _ := store.Get(ctx, "job-returning"); returning.Status != job.JobStatusRunning {
+		t.Errorf("Expected the job of the re-registered worker to stay running, got %s", returning.Status)
+	}
+}
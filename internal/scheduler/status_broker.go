@@ -0,0 +1,85 @@
+package scheduler
+
+import (
+	"infinitrain/pkg/job"
+	"sync"
+)
+
+// statusSubscriberBuffer is how many status changes a watcher may fall
+// behind by before the oldest undelivered ones are dropped
+const statusSubscriberBuffer = 16
+
+// StatusBroker fans job status changes out to watchers of each job. Stores
+// publish to it after every successful write. Publishing never blocks: a
+// watcher that falls behind loses its oldest undelivered changes, never the
+// latest, so it always ends up seeing the job's current state.
+type StatusBroker struct {
+	mutex       sync.Mutex
+	subscribers map[string]map[chan *job.Job]struct{}
+}
+
+// NewStatusBroker creates a broker with no watchers
+func NewStatusBroker() *StatusBroker {
+	return &StatusBroker{subscribers: make(map[string]map[chan *job.Job]struct{})}
+}
+
+// Subscribe returns a channel receiving a copy of the job after each change
+// published for it. Call cancel to stop receiving; the channel is closed.
+func (b *StatusBroker) Subscribe(jobID string) (changes <-chan *job.Job, cancel func()) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	subscriber := make(chan *job.Job, statusSubscriberBuffer)
+	if b.subscribers[jobID] == nil {
+		b.subscribers[jobID] = make(map[chan *job.Job]struct{})
+	}
+	b.subscribers[jobID][subscriber] = struct{}{}
+
+	cancel = func() {
+		b.mutex.Lock()
+		defer b.mutex.Unlock()
+		if _, exists := b.subscribers[jobID][subscriber]; exists {
+			delete(b.subscribers[jobID], subscriber)
+			if len(b.subscribers[jobID]) == 0 {
+				delete(b.subscribers, jobID)
+			}
+			close(subscriber)
+		}
+	}
+
+	return subscriber, cancel
+}
+
+// Publish sends a copy of the job to everyone watching it
+func (b *StatusBroker) Publish(j *job.Job) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for subscriber := range b.subscribers[j.ID] {
+		jobCopy := *j
+		for sent := false; !sent; {
+			select {
+			case subscriber <- &jobCopy:
+				sent = true
+			default:
+				// Make room by dropping the oldest change
+				select {
+				case <-subscriber:
+				default:
+				}
+			}
+		}
+	}
+}
+
+// Watchers returns how many subscriptions are open, across all jobs
+func (b *StatusBroker) Watchers() int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	count := 0
+	for _, subscribers := range b.subscribers {
+		count += len(subscribers)
+	}
+	return count
+}
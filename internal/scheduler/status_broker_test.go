@@ -0,0 +1,40 @@
+package scheduler
+
+import (
+	"infinitrain/pkg/job"
+	"testing"
+)
+
+func TestStatusBroker(t *testing.T) {
+	broker := NewStatusBroker()
+	changes, cancel := broker.Subscribe("job-1")
+	other, cancelOther := broker.Subscribe("job-2")
+	defer cancelOther()
+
+	// A watcher that falls behind keeps the latest changes
+	for i := 0; i < statusSubscriberBuffer+3; i++ {
+		broker.Publish(&job.Job{ID: "job-1", Status: job.JobStatusRunning, Priority: i})
+	}
+	broker.Publish(&job.Job{ID: "job-1", Status: job.JobStatusCompleted})
+
+	var last *job.Job
+	for len(changes) > 0 {
+		last = <-changes
+	}
+	if last == nil || last.Status != job.JobStatusCompleted {
+		t.Errorf("Expected the latest change to be delivered, got %+v", last)
+	}
+	if len(other) != 0 {
+		t.Errorf("Expected watchers of other jobs to receive nothing, got %d changes", len(other))
+	}
+
+	cancel()
+	cancel()
+	if _, open := <-changes; open {
+		t.Errorf("Expected cancel to close the channel")
+	}
+	if watchers := broker.Watchers(); watchers != 1 {
+		t.Errorf("Expected 1 watcher left, got %d", watchers)
+	}
+	broker.Publish(&job.Job{ID: "job-1", Status: job.JobStatusFailed})
+}
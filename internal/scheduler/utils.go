@@ -2,7 +2,7 @@ package scheduler
 
 import "time"
 
-// Now returns the current time - useful for testing and consistency
+// Now returns the current time in UTC - useful for testing and consistency
 func Now() time.Time {
-	return time.Now()
+	return time.Now().UTC()
 }
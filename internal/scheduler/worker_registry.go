@@ -0,0 +1,326 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"infinitrain/internal/clock"
+	"infinitrain/pkg/job"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryWorkerRegistry is an in-memory implementation of the job.WorkerRegistry interface
+type MemoryWorkerRegistry struct {
+	workers    map[string]job.Worker
+	lastSeen   map[string]time.Time
+	stale      map[string]bool // Workers marked unhealthy for missing heartbeats
+	maxWorkers int             // Zero means unlimited
+	clock      clock.Clock
+	logger     *slog.Logger
+	mutex      sync.RWMutex
+}
+
+// NewMemoryWorkerRegistry creates a new in-memory worker registry with no
+// limit on the number of workers
+func NewMemoryWorkerRegistry() *MemoryWorkerRegistry {
+	return NewMemoryWorkerRegistryWithLimit(0, clock.Real())
+}
+
+// NewMemoryWorkerRegistryWithLimit creates an in-memory worker registry that
+// holds at most maxWorkers workers (zero for no limit) and times heartbeats
+// with the given clock
+func NewMemoryWorkerRegistryWithLimit(maxWorkers int, c clock.Clock) *MemoryWorkerRegistry {
+	return &MemoryWorkerRegistry{
+		workers:    make(map[string]job.Worker),
+		lastSeen:   make(map[string]time.Time),
+		stale:      make(map[string]bool),
+		maxWorkers: maxWorkers,
+		clock:      c,
+		logger:     slog.Default(),
+	}
+}
+
+// SetLogger sets the logger the reaper reports reaped workers to
+func (r *MemoryWorkerRegistry) SetLogger(logger *slog.Logger) {
+	r.logger = logger
+}
+
+// MaxWorkers returns the registry's worker limit, zero meaning unlimited
+func (r *MemoryWorkerRegistry) MaxWorkers() int {
+	return r.maxWorkers
+}
+
+// Register adds a worker to the registry
+func (r *MemoryWorkerRegistry) Register(ctx context.Context, worker job.Worker) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, exists := r.workers[worker.ID()]; exists {
+		return job.NewValidationError("worker already registered: " + worker.ID())
+	}
+
+	// Guard against runaway registration filling memory with phantom workers
+	if r.maxWorkers > 0 && len(r.workers) >= r.maxWorkers {
+		return fmt.Errorf("cannot register worker %s: %w (%d)", worker.ID(), job.ErrWorkerLimitReached, r.maxWorkers)
+	}
+
+	r.workers[worker.ID()] = worker
+	r.lastSeen[worker.ID()] = r.clock.Now().UTC()
+
+	return nil
+}
+
+// Unregister removes a worker from the registry
+func (r *MemoryWorkerRegistry) Unregister(ctx context.Context, workerID string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, exists := r.workers[workerID]; !exists {
+		return job.NewWorkerNotFoundError(workerID)
+	}
+
+	delete(r.workers, workerID)
+	delete(r.lastSeen, workerID)
+	delete(r.stale, workerID)
+
+	return nil
+}
+
+// GetWorker returns a worker by ID
+func (r *MemoryWorkerRegistry) GetWorker(ctx context.Context, workerID string) (job.Worker, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	worker, exists := r.workers[workerID]
+	if !exists {
+		return nil, job.NewWorkerNotFoundError(workerID)
+	}
+
+	return worker, nil
+}
+
+// ListWorkers returns all registered workers ordered by ID
+func (r *MemoryWorkerRegistry) ListWorkers(ctx context.Context) ([]job.Worker, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	workers := make([]job.Worker, 0, len(r.workers))
+	for _, worker := range r.workers {
+		workers = append(workers, worker)
+	}
+
+	sort.Slice(workers, func(i, j int) bool {
+		return workers[i].ID() < workers[j].ID()
+	})
+
+	return workers, nil
+}
+
+// GetAvailableWorkers returns workers that can accept new jobs
+func (r *MemoryWorkerRegistry) GetAvailableWorkers(ctx context.Context) ([]job.Worker, error) {
+	workers, err := r.ListWorkers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	available := workers[:0]
+	for _, worker := range workers {
+		if worker.CanAcceptJob() && !r.stale[worker.ID()] {
+			available = append(available, worker)
+		}
+	}
+
+	return available, nil
+}
+
+// GetLeastLoadedWorker returns the available worker with the lowest
+// load-to-capacity ratio whose labels match the selector and whose load
+// leaves room for a job of the given cost. Ties are broken by worker ID so
+// the choice is deterministic.
+func (r *MemoryWorkerRegistry) GetLeastLoadedWorker(ctx context.Context, selector map[string]string, cost int) (job.Worker, error) {
+	workers, err := r.GetAvailableWorkers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var best job.Worker
+	var bestRatio float64
+
+	// Workers are ordered by ID, so keeping the first of equal ratios breaks ties
+	for _, worker := range workers {
+		if worker.GetCapacity() <= 0 || !job.MatchesSelector(worker.Labels(), selector) {
+			continue
+		}
+		load := currentLoad(worker)
+		if load+cost > worker.GetCapacity() {
+			continue
+		}
+
+		ratio := float64(load) / float64(worker.GetCapacity())
+		if best == nil || ratio < bestRatio {
+			best = worker
+			bestRatio = ratio
+		}
+	}
+
+	if best == nil {
+		return nil, job.ErrNoWorkerAvailable
+	}
+
+	return best, nil
+}
+
+// Heartbeat updates the last seen time for a worker
+func (r *MemoryWorkerRegistry) Heartbeat(ctx context.Context, workerID string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	worker, exists := r.workers[workerID]
+	if !exists {
+		return job.NewWorkerNotFoundError(workerID)
+	}
+
+	r.lastSeen[workerID] = r.clock.Now().UTC()
+
+	// Keep in-process workers' own heartbeat in sync
+	if hb, ok := worker.(interface{ UpdateHeartbeat() }); ok {
+		hb.UpdateHeartbeat()
+	}
+
+	// A stale worker that is heard from again is healthy again
+	if r.stale[workerID] {
+		delete(r.stale, workerID)
+		if settable, ok := worker.(interface{ SetHealthy(bool) }); ok {
+			settable.SetHealthy(true)
+		}
+	}
+
+	return nil
+}
+
+// LastSeen returns when a worker last registered or sent a heartbeat
+func (r *MemoryWorkerRegistry) LastSeen(workerID string) (time.Time, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	seen, exists := r.lastSeen[workerID]
+	return seen, exists
+}
+
+// HeartbeatAge returns how long ago a worker last registered or sent a
+// heartbeat
+func (r *MemoryWorkerRegistry) HeartbeatAge(workerID string) (time.Duration, bool) {
+	seen, exists := r.LastSeen(workerID)
+	if !exists {
+		return 0, false
+	}
+	return r.clock.Now().Sub(seen), true
+}
+
+// MarkStale marks every worker that has not registered or sent a heartbeat
+// within the timeout unhealthy, so no more jobs are dispatched to it, and
+// returns the IDs of the workers newly marked. Unlike ReapStale the workers
+// stay registered and recover on their next heartbeat.
+func (r *MemoryWorkerRegistry) MarkStale(ctx context.Context, timeout time.Duration) []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	cutoff := r.clock.Now().UTC().Add(-timeout)
+
+	var marked []string
+	for id, seen := range r.lastSeen {
+		if !seen.Before(cutoff) || r.stale[id] {
+			continue
+		}
+		r.stale[id] = true
+		if settable, ok := r.workers[id].(interface{ SetHealthy(bool) }); ok {
+			settable.SetHealthy(false)
+		}
+		marked = append(marked, id)
+	}
+
+	sort.Strings(marked)
+	return marked
+}
+
+// RunHealthChecks marks stale workers unhealthy every interval until the
+// context is cancelled, calling onStale for each newly stale worker
+func (r *MemoryWorkerRegistry) RunHealthChecks(ctx context.Context, interval, timeout time.Duration, onStale func(ctx context.Context, workerID string)) {
+	ticker := r.clock.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			for _, id := range r.MarkStale(ctx, timeout) {
+				if onStale != nil {
+					onStale(ctx, id)
+				}
+			}
+		}
+	}
+}
+
+// ReapStale unregisters every worker that has not registered or sent a
+// heartbeat within the timeout, freeing its slot, and returns their IDs.
+// The jobs they were running are left to the caller to release.
+func (r *MemoryWorkerRegistry) ReapStale(ctx context.Context, timeout time.Duration) []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	cutoff := r.clock.Now().UTC().Add(-timeout)
+
+	var reaped []string
+	for id, seen := range r.lastSeen {
+		if seen.Before(cutoff) {
+			delete(r.workers, id)
+			delete(r.lastSeen, id)
+			delete(r.stale, id)
+			reaped = append(reaped, id)
+		}
+	}
+
+	sort.Strings(reaped)
+	return reaped
+}
+
+// RunReaper reaps stale workers every interval until the context is
+// cancelled, calling onReaped for each reaped worker so its jobs can be
+// released
+func (r *MemoryWorkerRegistry) RunReaper(ctx context.Context, interval, timeout time.Duration, onReaped func(ctx context.Context, workerID string)) {
+	ticker := r.clock.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			reaped := r.ReapStale(ctx, timeout)
+			if len(reaped) > 0 {
+				r.logger.Info("reaped stale workers", "workers", reaped)
+			}
+			for _, id := range reaped {
+				if onReaped != nil {
+					onReaped(ctx, id)
+				}
+			}
+		}
+	}
+}
+
+// currentLoad returns a worker's weighted load when it tracks job costs,
+// falling back to its job count
+func currentLoad(worker job.Worker) int {
+	if weighted, ok := worker.(interface{ GetWeightedLoad() int }); ok {
+		return weighted.GetWeightedLoad()
+	}
+	return worker.GetCurrentLoad()
+}
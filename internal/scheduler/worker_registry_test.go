@@ -0,0 +1,258 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"infinitrain/internal/clock"
+	"infinitrain/pkg/job"
+	"testing"
+	"time"
+)
+
+// fakeWorker is a job.Worker with fixed load and health for registry tests
+type fakeWorker struct {
+	id       string
+	capacity int
+	load     int
+	healthy  bool
+	labels   map[string]string
+}
+
+func (w *fakeWorker) ID() string                      { return w.id }
+func (w *fakeWorker) Start(ctx context.Context) error { return nil }
+func (w *fakeWorker) Stop(ctx context.Context) error  { return nil }
+func (w *fakeWorker) IsHealthy() bool                 { return w.healthy }
+func (w *fakeWorker) GetCapacity() int                { return w.capacity }
+func (w *fakeWorker) GetCurrentLoad() int             { return w.load }
+func (w *fakeWorker) CanAcceptJob() bool              { return w.healthy && w.load < w.capacity }
+func (w *fakeWorker) Labels() map[string]string       { return w.labels }
+func (w *fakeWorker) SetHealthy(healthy bool)         { w.healthy = healthy }
+
+// newTestRegistry creates a registry with the given workers registered
+func newTestRegistry(t *testing.T, workers ...*fakeWorker) *MemoryWorkerRegistry {
+	t.Helper()
+
+	registry := NewMemoryWorkerRegistry()
+	for _, w := range workers {
+		if err := registry.Register(context.Background(), w); err != nil {
+			t.Fatalf("Register() error = %v", err)
+		}
+	}
+	return registry
+}
+
+func TestMemoryWorkerRegistry_GetLeastLoadedWorker(t *testing.T) {
+	tests := []struct {
+		name     string
+		workers  []*fakeWorker
+		selector map[string]string
+		cost     int
+		want     string
+		wantErr  error
+	}{
+		{
+			name: "lowest load ratio wins",
+			workers: []*fakeWorker{
+				{id: "w1", capacity: 4, load: 3, healthy: true},
+				{id: "w2", capacity: 10, load: 2, healthy: true},
+				{id: "w3", capacity: 2, load: 1, healthy: true},
+			},
+			want: "w2",
+		},
+		{
+			name: "unhealthy and full workers are skipped",
+			workers: []*fakeWorker{
+				{id: "w1", capacity: 4, load: 0, healthy: false},
+				{id: "w2", capacity: 2, load: 2, healthy: true},
+				{id: "w3", capacity: 4, load: 3, healthy: true},
+			},
+			want: "w3",
+		},
+		{
+			name: "ties broken by worker ID",
+			workers: []*fakeWorker{
+				{id: "w-b", capacity: 4, load: 1, healthy: true},
+				{id: "w-a", capacity: 8, load: 2, healthy: true},
+			},
+			want: "w-a",
+		},
+		{
+			name: "selector filters by labels",
+			workers: []*fakeWorker{
+				{id: "cpu", capacity: 4, load: 0, healthy: true},
+				{id: "gpu", capacity: 4, load: 3, healthy: true, labels: map[string]string{"gpu": "true"}},
+			},
+			selector: map[string]string{"gpu": "true"},
+			want:     "gpu",
+		},
+		{
+			name: "workers without room for the cost are skipped",
+			workers: []*fakeWorker{
+				{id: "w1", capacity: 4, load: 2, healthy: true},
+				{id: "w2", capacity: 10, load: 6, healthy: true},
+			},
+			cost: 3,
+			want: "w2",
+		},
+		{
+			name: "no available worker",
+			workers: []*fakeWorker{
+				{id: "w1", capacity: 1, load: 1, healthy: true},
+			},
+			wantErr: job.ErrNoWorkerAvailable,
+		},
+		{
+			name: "cost larger than any free capacity",
+			workers: []*fakeWorker{
+				{id: "w1", capacity: 4, load: 1, healthy: true},
+			},
+			cost:    4,
+			wantErr: job.ErrNoWorkerAvailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := newTestRegistry(t, tt.workers...)
+
+			worker, err := registry.GetLeastLoadedWorker(context.Background(), tt.selector, tt.cost)
+			if tt.wantErr != nil {
+				if err != tt.wantErr {
+					t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetLeastLoadedWorker() error = %v", err)
+			}
+
+			if worker.ID() != tt.want {
+				t.Errorf("Expected worker %s, got %s", tt.want, worker.ID())
+			}
+		})
+	}
+}
+
+func TestMemoryWorkerRegistry_HeartbeatUnknownWorker(t *testing.T) {
+	registry := newTestRegistry(t)
+
+	if err := registry.Heartbeat(context.Background(), "missing"); !job.IsWorkerNotFoundError(err) {
+		t.Errorf("Expected worker not found error, got %v", err)
+	}
+}
+
+func TestMemoryWorkerRegistry_MaxWorkers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	fake := clock.NewFake(time.Now())
+	registry := NewMemoryWorkerRegistryWithLimit(2, fake)
+
+	for _, id := range []string{"dead", "alive"} {
+		if err := registry.Register(ctx, &fakeWorker{id: id, capacity: 1, healthy: true}); err != nil {
+			t.Fatalf("Register(%s) error = %v", id, err)
+		}
+	}
+
+	extra := &fakeWorker{id: "extra", capacity: 1, healthy: true}
+	if err := registry.Register(ctx, extra); !errors.Is(err, job.ErrWorkerLimitReached) {
+		t.Fatalf("Expected worker limit error at the cap, got %v", err)
+	}
+
+	// The reaped worker's job goes back in the queue
+	store := NewMemoryStore()
+	manager := NewManager(store, NewPriorityQueue(), registry)
+	if err := store.Create(ctx, &job.Job{ID: "job-dead", Status: job.JobStatusRunning, WorkerID: "dead"}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	// Only "alive" keeps sending heartbeats, so the reaper frees the other slot
+	go registry.RunReaper(ctx, 10*time.Second, 30*time.Second, manager.RequeueStaleWorkerJobs)
+	fake.WaitForWaiters(1)
+	for i := 0; i < 4; i++ {
+		fake.Advance(10 * time.Second)
+		if err := registry.Heartbeat(ctx, "alive"); err != nil {
+			t.Fatalf("Heartbeat() error = %v", err)
+		}
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		err := registry.Register(ctx, extra)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, job.ErrWorkerLimitReached) || time.Now().After(deadline) {
+			t.Fatalf("Expected registration to succeed once the dead worker was reaped, got %v", err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if _, err := registry.GetWorker(ctx, "dead"); !job.IsWorkerNotFoundError(err) {
+		t.Errorf("Expected the dead worker to be reaped, got %v", err)
+	}
+	if _, err := registry.GetWorker(ctx, "alive"); err != nil {
+		t.Errorf("Expected the live worker to stay registered, got %v", err)
+	}
+
+	for {
+		if j, _ := store.Get(ctx, "job-dead"); j.Status == job.JobStatusQueued {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the reaped worker's job to be requeued")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestMemoryWorkerRegistry_MarkStale(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Now())
+	registry := NewMemoryWorkerRegistryWithLimit(0, fake)
+	manager := NewManager(NewMemoryStore(), NewPriorityQueue(), registry)
+
+	quiet := &fakeWorker{id: "quiet", capacity: 2, healthy: true}
+	alive := &fakeWorker{id: "alive", capacity: 2, healthy: true}
+	for _, w := range []*fakeWorker{quiet, alive} {
+		if err := registry.Register(ctx, w); err != nil {
+			t.Fatalf("Register() error = %v", err)
+		}
+	}
+	if err := manager.store.Create(ctx, &job.Job{ID: "job-1", Status: job.JobStatusRunning, WorkerID: "quiet"}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	fake.Advance(45 * time.Second)
+	registry.Heartbeat(ctx, "alive")
+	fake.Advance(30 * time.Second)
+
+	if age, _ := registry.HeartbeatAge("quiet"); age != 75*time.Second {
+		t.Errorf("Expected a heartbeat age of 75s, got %v", age)
+	}
+
+	stale := registry.MarkStale(ctx, time.Minute)
+	if len(stale) != 1 || stale[0] != "quiet" || quiet.healthy || !alive.healthy {
+		t.Fatalf("Expected only the quiet worker to be marked unhealthy, got %v", stale)
+	}
+	if again := registry.MarkStale(ctx, time.Minute); len(again) != 0 {
+		t.Errorf("Expected a stale worker to be reported once, got %v", again)
+	}
+	if _, err := registry.GetWorker(ctx, "quiet"); err != nil {
+		t.Errorf("Expected the stale worker to stay registered, got %v", err)
+	}
+
+	for _, id := range stale {
+		manager.RequeueStaleWorkerJobs(ctx, id)
+	}
+	if j, _ := manager.store.Get(ctx, "job-1"); j.Status != job.JobStatusQueued || j.WorkerID != "" {
+		t.Errorf("Expected the stale worker's job to be queued again, got %s on %q", j.Status, j.WorkerID)
+	}
+
+	if err := registry.Heartbeat(ctx, "quiet"); err != nil {
+		t.Fatalf("Heartbeat() error = %v", err)
+	}
+	if !quiet.healthy {
+		t.Errorf("Expected a heartbeat to make the stale worker healthy again")
+	}
+}
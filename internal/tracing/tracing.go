@@ -0,0 +1,100 @@
+// Package tracing records OpenTelemetry spans over a job's life, from
+// submission through queueing and assignment to execution on a worker. The
+// trace travels between processes on the job itself, so every step of a job
+// lands in one trace.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"infinitrain/internal/config"
+	"infinitrain/pkg/job"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName identifies the spans this module creates
+const tracerName = "infinitrain"
+
+// propagator encodes span contexts as W3C traceparent headers
+var propagator = propagation.TraceContext{}
+
+// Setup installs a tracer provider that exports spans to the configured OTLP
+// endpoint, naming the service after cfg.ServiceName or else the given
+// role. With no endpoint nothing is installed and spans cost next to
+// nothing. The returned function flushes buffered spans and stops the
+// exporter.
+func Setup(ctx context.Context, cfg *config.TracingConfig, role string) (func(context.Context) error, error) {
+	if cfg.OTLPEndpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(cfg.OTLPEndpoint))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+
+	service := cfg.ServiceName
+	if service == "" {
+		service = role
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", service))),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// Start starts a span for work on a job. It continues the trace the job
+// carries, nesting under the span in ctx when that is already part of the
+// same trace.
+func Start(ctx context.Context, j *job.Job, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	if carried := trace.SpanContextFromContext(Extract(context.Background(), j.TraceParent)); carried.IsValid() {
+		if trace.SpanContextFromContext(ctx).TraceID() != carried.TraceID() {
+			ctx = trace.ContextWithRemoteSpanContext(ctx, carried)
+		}
+	}
+
+	opts = append(opts, trace.WithAttributes(
+		attribute.String("job.id", j.ID),
+		attribute.String("job.type", string(j.Type)),
+	))
+	return otel.Tracer(tracerName).Start(ctx, name, opts...)
+}
+
+// End marks the span failed if err is set, then ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Inject returns the traceparent of the span in ctx, or an empty string when
+// the span isn't being recorded
+func Inject(ctx context.Context) string {
+	carrier := propagation.MapCarrier{}
+	propagator.Inject(ctx, carrier)
+	return carrier.Get("traceparent")
+}
+
+// Extract returns ctx with the span a traceparent names as its remote parent.
+// An empty or malformed traceparent leaves ctx as it is.
+func Extract(ctx context.Context, traceParent string) context.Context {
+	if traceParent == "" {
+		return ctx
+	}
+	return propagator.Extract(ctx, propagation.MapCarrier{"traceparent": traceParent})
+}
@@ -0,0 +1,91 @@
+package tracing
+
+import (
+	"context"
+	"infinitrain/internal/config"
+	"infinitrain/pkg/job"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recordSpans installs a tracer provider that keeps ended spans in memory
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func TestSetup_NoEndpoint(t *testing.T) {
+	previous := otel.GetTracerProvider()
+
+	shutdown, err := Setup(context.Background(), &config.TracingConfig{SampleRatio: 1}, "scheduler")
+	if err != nil {
+		t.Fatalf("Setup() error = %v", err)
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Errorf("shutdown() error = %v", err)
+	}
+	if otel.GetTracerProvider() != previous {
+		t.Error("Expected no tracer provider to be installed without an endpoint")
+	}
+
+	// Without a provider spans aren't recorded, so there is nothing to carry
+	j := &job.Job{ID: "job-1", Type: job.JobTypeCommand}
+	ctx, span := Start(context.Background(), j, "job.submit")
+	defer span.End()
+	if parent := Inject(ctx); parent != "" {
+		t.Errorf("Expected no traceparent, got %q", parent)
+	}
+}
+
+func TestStart_ContinuesJobTrace(t *testing.T) {
+	recorder := recordSpans(t)
+	j := &job.Job{ID: "job-1", Type: job.JobTypeCommand}
+
+	ctx, submit := Start(context.Background(), j, "job.submit")
+	j.TraceParent = Inject(ctx)
+	submit.End()
+	if j.TraceParent == "" {
+		t.Fatal("Expected the submission to produce a traceparent")
+	}
+
+	// A worker picks the job up with a context of its own
+	ctx, run := Start(context.Background(), j, "job.run")
+	_, execute := Start(ctx, j, "job.execute")
+	execute.End()
+	End(run, nil)
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("Expected 3 spans, got %d", len(spans))
+	}
+	submitted, executed, ran := spans[0], spans[1], spans[2]
+	for _, span := range spans[1:] {
+		if span.SpanContext().TraceID() != submitted.SpanContext().TraceID() {
+			t.Errorf("Expected %s to join the submission's trace", span.Name())
+		}
+	}
+	if ran.Parent().SpanID() != submitted.SpanContext().SpanID() {
+		t.Errorf("Expected job.run to be a child of job.submit")
+	}
+	if executed.Parent().SpanID() != ran.SpanContext().SpanID() {
+		t.Errorf("Expected job.execute to nest under job.run")
+	}
+}
+
+func TestExtract_Malformed(t *testing.T) {
+	for _, parent := range []string{"", "not-a-traceparent", "00-00000000000000000000000000000000-0000000000000000-01"} {
+		ctx := Extract(context.Background(), parent)
+		if trace.SpanContextFromContext(ctx).IsValid() {
+			t.Errorf("Expected %q to carry no span", parent)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"infinitrain/pkg/job"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// SchedulerClient is the worker's view of the scheduler
+type SchedulerClient interface {
+	// Heartbeat reports that the worker is alive
+	Heartbeat(ctx context.Context, workerID string) error
+}
+
+// httpSchedulerClient talks to the scheduler's REST API
+type httpSchedulerClient struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+// newHTTPSchedulerClient creates a scheduler client for the given base URL.
+// A non-empty token is sent as a bearer token with every request.
+func newHTTPSchedulerClient(baseURL, token string) *httpSchedulerClient {
+	return &httpSchedulerClient{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		token:   token,
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+// Heartbeat reports that the worker is alive
+func (c *httpSchedulerClient) Heartbeat(ctx context.Context, workerID string) error {
+	path := fmt.Sprintf("/api/v1/workers/%s/heartbeat", url.PathEscape(workerID))
+	return c.post(ctx, path, nil)
+}
+
+// HeartbeatLoad reports that the worker is alive along with its current
+// load, which the scheduler weighs when placing jobs
+func (c *httpSchedulerClient) HeartbeatLoad(ctx context.Context, workerID string, load int) error {
+	body, err := json.Marshal(map[string]int{"load": load})
+	if err != nil {
+		return fmt.Errorf("failed to encode heartbeat: %v", err)
+	}
+
+	path := fmt.Sprintf("/api/v1/workers/%s/heartbeat", url.PathEscape(workerID))
+	return c.post(ctx, path, body)
+}
+
+// Register asks the scheduler to add the worker to its registry
+func (c *httpSchedulerClient) Register(ctx context.Context, registration *job.WorkerRegistration) error {
+	body, err := json.Marshal(registration)
+	if err != nil {
+		return fmt.Errorf("failed to encode registration: %v", err)
+	}
+	return c.post(ctx, "/api/v1/workers", body)
+}
+
+// ReleaseJobs hands the worker's unfinished jobs back to the scheduler to be
+// queued again
+func (c *httpSchedulerClient) ReleaseJobs(ctx context.Context, workerID string) error {
+	path := fmt.Sprintf("/api/v1/workers/%s/release", url.PathEscape(workerID))
+	return c.post(ctx, path, nil)
+}
+
+// SaveResult reports a finished job's result for the scheduler to store
+func (c *httpSchedulerClient) SaveResult(ctx context.Context, result *job.JobResult) error {
+	body, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to encode result: %v", err)
+	}
+
+	path := fmt.Sprintf("/api/v1/jobs/%s/result", url.PathEscape(result.JobID))
+	return c.post(ctx, path, body)
+}
+
+// PollJob claims the next queued job the worker can take, already marked
+// running on it, or returns nil when there is none
+func (c *httpSchedulerClient) PollJob(ctx context.Context, workerID string) (*job.Job, error) {
+	var j *job.Job
+	path := fmt.Sprintf("/api/v1/workers/%s/jobs/next", url.PathEscape(workerID))
+	if err := c.send(ctx, path, nil, &j); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// post sends a POST request with an optional JSON body and converts non-2xx
+// responses into errors
+func (c *httpSchedulerClient) post(ctx context.Context, path string, body []byte) error {
+	return c.send(ctx, path, body, nil)
+}
+
+// send is post that also decodes a response body into out, when out is not
+// nil and the scheduler sent content
+func (c *httpSchedulerClient) send(ctx context.Context, path string, body []byte, out any) error {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request to scheduler failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var body struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&body)
+		return &statusError{StatusCode: resp.StatusCode, Message: body.Error}
+	}
+
+	if out != nil && resp.StatusCode != http.StatusNoContent {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return fmt.Errorf("failed to decode scheduler response: %v", err)
+		}
+	}
+	return nil
+}
+
+// statusError is an error response from the scheduler
+type statusError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("scheduler returned status %d: %s", e.StatusCode, e.Message)
+}
+
+// isNotFound reports whether err is the scheduler answering 404, e.g. to a
+// heartbeat from a worker it no longer has registered
+func isNotFound(err error) bool {
+	var status *statusError
+	return errors.As(err, &status) && status.StatusCode == http.StatusNotFound
+}
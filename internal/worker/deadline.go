@@ -0,0 +1,94 @@
+package worker
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// jobDeadline enforces a job's timeout. Unlike a context deadline it can be
+// pushed back while the job runs, and it can warn a little before it expires
+// so operators have time to extend it.
+type jobDeadline struct {
+	mutex    sync.Mutex
+	deadline time.Time
+	timeout  time.Duration // The job's timeout including extensions
+	lead     time.Duration // How long before the deadline the warning fires
+	expire   *time.Timer
+	warn     *time.Timer
+	expired  bool
+}
+
+// startJobDeadline calls onExpire once timeout elapses. With a warning
+// fraction in (0, 1), onWarn is called when that fraction of the timeout has
+// passed, and again ahead of each extended deadline.
+func startJobDeadline(timeout time.Duration, warnFraction float64, onWarn func(deadline time.Time), onExpire func()) *jobDeadline {
+	d := &jobDeadline{deadline: time.Now().Add(timeout), timeout: timeout}
+
+	d.expire = time.AfterFunc(timeout, func() {
+		d.mutex.Lock()
+		d.expired = true
+		d.mutex.Unlock()
+		onExpire()
+	})
+
+	if warnFraction > 0 && warnFraction < 1 {
+		d.lead = timeout - time.Duration(float64(timeout)*warnFraction)
+		d.warn = time.AfterFunc(timeout-d.lead, func() {
+			onWarn(d.Deadline())
+		})
+	}
+
+	return d
+}
+
+// Deadline returns when the job will be killed
+func (d *jobDeadline) Deadline() time.Time {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.deadline
+}
+
+// errDeadlineExpired is returned extending a deadline that has already passed
+var errDeadlineExpired = errors.New("deadline has expired")
+
+// errTimeoutLimit is returned extending a deadline past the longest timeout
+// a job may have
+var errTimeoutLimit = errors.New("timeout would exceed the limit")
+
+// Extend pushes the deadline back and returns the new one. It fails if the
+// deadline has already passed and the job is being killed, or if the job's
+// timeout would grow past limit, when limit isn't zero.
+func (d *jobDeadline) Extend(extra, limit time.Duration) (time.Time, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if limit > 0 && d.timeout+extra > limit {
+		return d.deadline, errTimeoutLimit
+	}
+	if d.expired || !d.expire.Stop() {
+		return d.deadline, errDeadlineExpired
+	}
+
+	d.timeout += extra
+	d.deadline = d.deadline.Add(extra)
+	remaining := time.Until(d.deadline)
+	d.expire.Reset(remaining)
+
+	if d.warn != nil {
+		d.warn.Stop()
+		if remaining > d.lead {
+			d.warn.Reset(remaining - d.lead)
+		}
+	}
+
+	return d.deadline, nil
+}
+
+// Stop releases the timers once the job has finished
+func (d *jobDeadline) Stop() {
+	d.expire.Stop()
+	if d.warn != nil {
+		d.warn.Stop()
+	}
+}
@@ -3,60 +3,227 @@ package worker
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"infinitrain/internal/config"
+	"infinitrain/internal/tracing"
 	"infinitrain/pkg/job"
 	"io"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// Exit codes following shell and GNU timeout conventions
+const (
+	exitCodeTimeout         = 124 // The job's timeout elapsed
+	exitCodeCommandNotFound = 127 // The binary doesn't exist
+	exitCodeSignalBase      = 128 // Added to the signal number for killed processes
+)
+
+// defaultFileMode is the permission mode of files created by write and
+// append operations, unless a job sets FILE_MODE
+const defaultFileMode os.FileMode = 0o644
+
+// Binary content handling for file reads, selected with FILE_BINARY_MODE
+const (
+	binaryModeBase64 = "base64" // Return binary content base64-encoded
+	binaryModeRefuse = "refuse" // Fail the job instead of returning binary content
+)
+
+// processWaitDelay is how long a killed job's output pipes may stay open
+// before Wait gives up on them
+const processWaitDelay = 5 * time.Second
+
+// defaultHTTPTimeout bounds HTTP job requests when the job has no timeout
+const defaultHTTPTimeout = 30 * time.Second
+
+// defaultHTTPRetryStatuses are the response statuses HTTP jobs retry when
+// the worker doesn't configure its own
+var defaultHTTPRetryStatuses = []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}
+
+// defaultHTTPRetryBackoff is the delay before an HTTP job's first request
+// retry when the worker doesn't configure one
+const defaultHTTPRetryBackoff = 1 * time.Second
+
+// jobLogDir is the directory under the working directory holding job logs
+const jobLogDir = "logs"
+
+// Handling of undefined variables when interpolating job fields
+const (
+	unknownVariablesEmpty = "empty" // Expand to an empty string
+	unknownVariablesError = "error" // Fail the job
 )
 
 // JobExecutor implements the job.Executor interface
 type JobExecutor struct {
-	workingDir string
+	config      *config.WorkerConfig
+	workingDir  string
+	processes   map[string]int           // Process group of each running command or script job
+	deadlines   map[string]*jobDeadline  // Timeout of each running job that has one
+	streams     map[string]*outputStream // Live output of each running job
+	processMux  sync.Mutex
+	warnTimeout func(j *job.Job, deadline time.Time)
+	logger      *slog.Logger
+	secrets     SecretSource // Nil when the worker has no secrets
+	logDropped  atomic.Int64 // Output dropped from job logs since the worker started
 }
 
 // NewJobExecutor creates a new job executor
-func NewJobExecutor(workingDir string) *JobExecutor {
-	return &JobExecutor{
-		workingDir: workingDir,
+func NewJobExecutor(cfg *config.WorkerConfig) *JobExecutor {
+	e := &JobExecutor{
+		config:     cfg,
+		workingDir: cfg.WorkingDirectory,
+		processes:  make(map[string]int),
+		deadlines:  make(map[string]*jobDeadline),
+		streams:    make(map[string]*outputStream),
+		logger:     slog.Default(),
 	}
+	e.warnTimeout = e.logTimeoutWarning
+	if cfg.SecretsFile != "" {
+		e.secrets = &envFileSecrets{path: cfg.SecretsFile}
+	}
+	return e
+}
+
+// SetSecretSource sets where the secrets jobs name are looked up
+func (e *JobExecutor) SetSecretSource(secrets SecretSource) {
+	e.secrets = secrets
+}
+
+// SetLogger sets the logger the executor writes to
+func (e *JobExecutor) SetLogger(logger *slog.Logger) {
+	e.logger = logger
 }
 
 // Execute runs a job and returns the result
 func (e *JobExecutor) Execute(ctx context.Context, j *job.Job) (*job.JobResult, error) {
+	ctx, span := tracing.Start(ctx, j, "job.execute")
+	result, err := e.execute(ctx, j)
+	if result != nil {
+		span.SetAttributes(
+			attribute.String("job.status", string(result.Status)),
+			attribute.Int("job.exit_code", result.ExitCode),
+		)
+		if result.Error != "" {
+			span.SetStatus(codes.Error, result.Error)
+		}
+	}
+	tracing.End(span, err)
+	return result, err
+}
+
+// execute runs a job in the directory and under the deadline it asks for
+func (e *JobExecutor) execute(ctx context.Context, j *job.Job) (*job.JobResult, error) {
 	startTime := time.Now()
 
-	// Create timeout context if job has timeout
+	// Enforce the job's timeout with a deadline that can be extended while it runs
 	if j.Timeout > 0 {
-		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(ctx, j.Timeout)
-		defer cancel()
+		var cancel context.CancelCauseFunc
+		ctx, cancel = context.WithCancelCause(ctx)
+		defer cancel(nil)
+
+		deadline := startJobDeadline(j.Timeout, e.config.TimeoutWarning,
+			func(at time.Time) { e.warnTimeout(j, at) },
+			func() { cancel(context.DeadlineExceeded) },
+		)
+		e.trackDeadline(j.ID, deadline)
+		defer e.untrackDeadline(j.ID, deadline)
 	}
 
 	var output string
+	var streams processOutput
+	var response *job.HTTPResponse
 	var err error
 	var exitCode int
+	var steps []job.StepResult
+
+	// Expand variable references before anything reads the affected fields
+	if j.Interpolate {
+		if j, err = e.interpolateJob(j); err != nil {
+			return nil, err
+		}
+	}
+
+	// Resolve the directory command and script jobs run in
+	dir, err := e.resolveWorkingDir(j)
+	if err != nil {
+		return nil, err
+	}
+
+	// The directories may have been removed since the worker started
+	if j.Type == job.JobTypeCommand || j.Type == job.JobTypeScript {
+		if err := e.prepareDirectories(j, dir); err != nil {
+			return nil, err
+		}
+	}
+
+	if !e.CanExecute(j.Type) {
+		return nil, fmt.Errorf("unsupported job type: %s", j.Type)
+	}
+
+	// Keep a copy of the output on the worker host for operators, counting
+	// it on the way so the counts cover output that is later truncated. The
+	// log is written through a bounded buffer so slow storage doesn't stall
+	// the job.
+	logFile, logPath := e.createJobLog(j.ID)
+	defer logFile.Close()
+	logBuffer := newLogPipe(logFile, e.logBufferSize(), e.logOverflow(j))
+
+	// Let clients follow the output while the job runs
+	stream := newOutputStream(e.streamBacklog())
+	e.trackStream(j.ID, stream)
+	defer e.untrackStream(j.ID, stream)
+
+	// Secret values are masked before the output reaches the log or clients
+	secretValues := e.jobSecretValues(j)
+	masked := newSecretMasker(io.MultiWriter(logBuffer, stream), secretValues)
+	counted := &outputCounter{Writer: masked}
 
 	// Execute based on job type
 	switch j.Type {
 	case job.JobTypeCommand:
-		output, exitCode, err = e.executeCommand(ctx, j)
+		if len(j.Steps) > 0 {
+			streams, exitCode, steps, err = e.executeSteps(ctx, j, dir, counted)
+		} else {
+			streams, exitCode, err = e.executeCommand(ctx, j, j.Command, dir, counted)
+		}
+		output = streams.combined
 	case job.JobTypeScript:
-		output, exitCode, err = e.executeScript(ctx, j)
+		streams, exitCode, err = e.executeScript(ctx, j, dir, counted)
+		output = streams.combined
 	case job.JobTypeHTTP:
-		output, exitCode, err = e.executeHTTP(ctx, j)
+		output, response, exitCode, err = e.executeHTTP(ctx, j)
+		streams.truncated = response != nil && response.Truncated
+		io.WriteString(counted, output)
 	case job.JobTypeFile:
 		output, exitCode, err = e.executeFile(ctx, j)
-	default:
-		return nil, fmt.Errorf("unsupported job type: %s", j.Type)
+		io.WriteString(counted, output)
 	}
 
+	if m, ok := masked.(*secretMasker); ok {
+		m.Flush()
+	}
+	logBuffer.Close()
+	e.logDropped.Add(logBuffer.Dropped())
+
 	endTime := time.Now()
 	duration := endTime.Sub(startTime)
+	termination := terminationReason(ctx, j.Type, err)
 
 	// Determine final status
 	status := job.JobStatusCompleted
@@ -67,18 +234,33 @@ func (e *JobExecutor) Execute(ctx context.Context, j *job.Job) (*job.JobResult,
 		if exitCode == 0 {
 			exitCode = 1 // Default error exit code
 		}
+		if termination == job.TerminationTimeout {
+			errorMessage = job.NewTimeoutError(j.ID, j.Timeout).Error()
+			exitCode = exitCodeTimeout
+		}
 	}
 
 	result := &job.JobResult{
 		JobID:       j.ID,
 		Status:      status,
 		Output:      output,
+		OutputBytes: counted.Bytes(),
+		OutputLines: counted.Lines(),
+		Truncated:   streams.truncated,
+		LogDropped:  logBuffer.Dropped(),
 		Error:       errorMessage,
 		ExitCode:    exitCode,
-		StartedAt:   startTime,
-		CompletedAt: endTime,
+		Termination: termination,
+		LogFile:     logPath,
+		StartedAt:   startTime.UTC(),
+		CompletedAt: endTime.UTC(),
 		Duration:    duration,
+		Steps:       steps,
+		Stdout:      streams.stdout,
+		Stderr:      streams.stderr,
+		Response:    response,
 	}
+	maskResult(result, secretValues)
 
 	return result, nil
 }
@@ -98,60 +280,309 @@ func (e *JobExecutor) Name() string {
 	return "default-executor"
 }
 
+// resolveWorkingDir returns the directory a job should run in, validating any
+// per-job override against the worker's allowed roots
+func (e *JobExecutor) resolveWorkingDir(j *job.Job) (string, error) {
+	if j.WorkingDir == "" {
+		return e.workingDir, nil
+	}
+
+	dir := filepath.Clean(j.WorkingDir)
+	if !filepath.IsAbs(dir) {
+		return "", job.NewValidationError("working_dir must be an absolute path: " + j.WorkingDir)
+	}
+
+	for _, root := range e.config.AllowedWorkingDirs {
+		if isWithinRoot(root, dir) {
+			return dir, nil
+		}
+	}
+
+	return "", job.NewValidationError("working_dir is not within an allowed root: " + j.WorkingDir)
+}
+
+// interpolateJob returns a copy of the job with $VAR and ${VAR} references in
+// its command, URL and file path expanded from the job's own environment.
+// The worker's environment is never consulted.
+func (e *JobExecutor) interpolateJob(j *job.Job) (*job.Job, error) {
+	strict := e.config.UnknownVariables == unknownVariablesError
+	expanded := *j
+
+	fields := []struct {
+		name  string
+		value *string
+	}{
+		{"command", &expanded.Command},
+		{"url", &expanded.URL},
+		{"file_path", &expanded.FilePath},
+	}
+	for _, field := range fields {
+		value, err := expandVariables(*field.value, j.Environment, strict)
+		if err != nil {
+			return nil, job.NewExecutionError(j.ID, fmt.Sprintf("failed to interpolate %s: %v", field.name, err), nil)
+		}
+		*field.value = value
+	}
+
+	return &expanded, nil
+}
+
+// terminationReason describes how a job ended. Timeouts and cancellations
+// apply to every job type; the remaining reasons describe a process's exit.
+func terminationReason(ctx context.Context, jobType job.JobType, err error) job.TerminationReason {
+	switch context.Cause(ctx) {
+	case context.DeadlineExceeded:
+		return job.TerminationTimeout
+	case context.Canceled:
+		return job.TerminationCancelled
+	}
+
+	if jobType != job.JobTypeCommand && jobType != job.JobTypeScript {
+		return ""
+	}
+
+	if err == nil {
+		return job.TerminationExited
+	}
+
+	var exitError *exec.ExitError
+	if !errors.As(err, &exitError) {
+		return job.TerminationNotRun
+	}
+	if status, ok := exitError.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return job.TerminationSignaled
+	}
+	return job.TerminationExited
+}
+
+// prepareDirectories makes sure the executor's working directory exists right
+// before the job runs, recreating it if needed. A working directory the job
+// overrides to must already exist: creating it would run the job somewhere
+// empty instead of in the checkout or data it expects.
+func (e *JobExecutor) prepareDirectories(j *job.Job, dir string) error {
+	if err := ensureDirectory(e.workingDir); err != nil {
+		return job.NewExecutionError(j.ID, "working directory unavailable: "+e.workingDir, err)
+	}
+	if dir == e.workingDir {
+		return nil
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return job.NewExecutionError(j.ID, "working directory unavailable: "+dir, err)
+	}
+	if !info.IsDir() {
+		return job.NewExecutionError(j.ID, "working directory is not a directory: "+dir, nil)
+	}
+	return nil
+}
+
+// executeSteps runs each step of a multi-step job in order, starting from the
+// job's resume step and stopping at the first step that fails
+func (e *JobExecutor) executeSteps(ctx context.Context, j *job.Job, dir string, logw io.Writer) (processOutput, int, []job.StepResult, error) {
+	var output, stdout, stderr strings.Builder
+	var steps []job.StepResult
+	truncated := false
+	collected := func() processOutput {
+		return processOutput{combined: output.String(), stdout: stdout.String(), stderr: stderr.String(), truncated: truncated}
+	}
+
+	for i := j.ResumeStep; i < len(j.Steps); i++ {
+		header := fmt.Sprintf("---STEP %d: %s---\n", i+1, j.Steps[i])
+		io.WriteString(logw, header)
+		stepOutput, exitCode, err := e.executeCommand(ctx, j, j.Steps[i], dir, logw)
+
+		steps = append(steps, job.StepResult{
+			Index:    i,
+			Command:  j.Steps[i],
+			Output:   stepOutput.combined,
+			ExitCode: exitCode,
+		})
+		output.WriteString(header + stepOutput.combined)
+		stdout.WriteString(stepOutput.stdout)
+		stderr.WriteString(stepOutput.stderr)
+		truncated = truncated || stepOutput.truncated
+
+		if err != nil {
+			return collected(), exitCode, steps, fmt.Errorf("step %d failed: %w", i+1, err)
+		}
+
+		j.Progress = (i + 1) * 100 / len(j.Steps)
+	}
+
+	return collected(), 0, steps, nil
+}
+
 // executeCommand executes a shell command
-func (e *JobExecutor) executeCommand(ctx context.Context, j *job.Job) (string, int, error) {
-	// Parse command and arguments
-	parts := strings.Fields(j.Command)
-	if len(parts) == 0 {
-		return "", 1, fmt.Errorf("empty command")
+func (e *JobExecutor) executeCommand(ctx context.Context, j *job.Job, command, dir string, logw io.Writer) (processOutput, int, error) {
+	if strings.TrimSpace(command) == "" {
+		return processOutput{}, 1, fmt.Errorf("empty command")
+	}
+
+	// Shell commands get pipes, globs and redirection, but also let anyone
+	// who can submit jobs inject arbitrary shell, so workers must opt in.
+	// Other commands are split into arguments honouring quotes and escapes.
+	var parts []string
+	if j.Shell {
+		if !e.config.AllowShell {
+			return processOutput{}, 1, job.NewValidationError("shell commands are not allowed on this worker")
+		}
+		parts = []string{e.shell(), "-c", command}
+	} else {
+		var err error
+		if parts, err = splitCommand(command); err != nil {
+			return processOutput{}, 1, job.NewValidationError(err.Error())
+		}
+		if len(parts) == 0 {
+			return processOutput{}, 1, fmt.Errorf("empty command")
+		}
+	}
+
+	// Fail clearly if the binary doesn't exist, rather than deep inside Run
+	if !commandExists(parts[0], dir) {
+		return processOutput{}, exitCodeCommandNotFound, job.NewExecutionError(j.ID, "command not found: "+parts[0], nil)
 	}
 
 	cmd := exec.CommandContext(ctx, parts[0], parts[1:]...)
-	cmd.Dir = e.workingDir
+	cmd.Dir = dir
 
-	// Set environment variables
+	// Set environment variables, secrets included
+	env, err := e.jobEnvironment(j)
+	if err != nil {
+		return processOutput{}, 1, err
+	}
 	cmd.Env = os.Environ()
-	for key, value := range j.Environment {
+	for key, value := range env {
 		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", key, value))
 	}
 
-	// Capture output
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	// Capture output, teeing it to the job's log file as it is produced
+	stdout := &limitedBuffer{limit: e.config.MaxOutputBytes}
+	stderr := &limitedBuffer{limit: e.config.MaxOutputBytes}
+	cmd.Stdout = io.MultiWriter(stdout, logw)
+	cmd.Stderr = io.MultiWriter(stderr, logw)
+
+	err = e.runProcess(j.ID, cmd)
+
+	exitCode := processExitCode(ctx, err)
 
-	err := cmd.Run()
+	return newProcessOutput(stdout, stderr), exitCode, err
+}
+
+// processOutput is what a command or script wrote to each stream, along
+// with the combined output reported as the job's output
+type processOutput struct {
+	combined  string
+	stdout    string
+	stderr    string
+	truncated bool // Either stream went past the worker's output limit
+}
 
-	// Combine stdout and stderr
-	output := stdout.String()
+// newProcessOutput combines a process's streams, appending stderr after a
+// delimiter when both have content
+func newProcessOutput(stdout, stderr *limitedBuffer) processOutput {
+	combined := stdout.String()
 	if stderr.Len() > 0 {
-		if output != "" {
-			output += "\n---STDERR---\n"
+		if combined != "" {
+			combined += "\n---STDERR---\n"
 		}
-		output += stderr.String()
+		combined += stderr.String()
 	}
+	return processOutput{
+		combined:  combined,
+		stdout:    stdout.String(),
+		stderr:    stderr.String(),
+		truncated: stdout.truncated || stderr.truncated,
+	}
+}
 
-	exitCode := 0
-	if err != nil {
-		if exitError, ok := err.(*exec.ExitError); ok {
-			exitCode = exitError.ExitCode()
-		} else {
-			exitCode = 1
-		}
+// shell returns the shell that runs shell command jobs
+func (e *JobExecutor) shell() string {
+	if e.config.Shell == "" {
+		return "/bin/sh"
 	}
+	return e.config.Shell
+}
 
-	return output, exitCode, err
+// logBufferSize returns how many output chunks are buffered between a job
+// and its log file
+func (e *JobExecutor) logBufferSize() int {
+	if e.config.LogBufferSize < 1 {
+		return defaultLogBufferSize
+	}
+	return e.config.LogBufferSize
+}
+
+// logOverflow returns what happens to a job's output when its log buffer is
+// full: the job's own policy, else the worker's, else block
+func (e *JobExecutor) logOverflow(j *job.Job) job.LogOverflow {
+	if j.LogOverflow != "" {
+		return j.LogOverflow
+	}
+	if e.config.LogOverflow != "" {
+		return job.LogOverflow(e.config.LogOverflow)
+	}
+	return job.LogOverflowBlock
+}
+
+// streamBacklog returns how much recent output is kept for clients that
+// start following a job after it has begun
+func (e *JobExecutor) streamBacklog() int {
+	if e.config.StreamBacklog < 1 {
+		return defaultStreamBacklog
+	}
+	return e.config.StreamBacklog
+}
+
+// SubscribeOutput follows a running job's output: it returns the recent
+// output and a channel receiving new output until the job finishes. Call
+// cancel when done following.
+func (e *JobExecutor) SubscribeOutput(jobID string) ([]byte, <-chan []byte, func(), error) {
+	e.processMux.Lock()
+	stream, exists := e.streams[jobID]
+	e.processMux.Unlock()
+
+	if !exists {
+		return nil, nil, nil, job.NewJobNotFoundError(jobID)
+	}
+
+	backlog, chunks, cancel := stream.Subscribe()
+	return backlog, chunks, cancel, nil
+}
+
+// trackStream makes a running job's output available to SubscribeOutput
+func (e *JobExecutor) trackStream(jobID string, stream *outputStream) {
+	e.processMux.Lock()
+	defer e.processMux.Unlock()
+	e.streams[jobID] = stream
+}
+
+// untrackStream ends a finished job's subscriptions
+func (e *JobExecutor) untrackStream(jobID string, stream *outputStream) {
+	e.processMux.Lock()
+	if e.streams[jobID] == stream {
+		delete(e.streams, jobID)
+	}
+	e.processMux.Unlock()
+
+	stream.Close()
+}
+
+// LogBytesDropped returns how much job output has been left out of log
+// files since the worker started because the log writer fell behind
+func (e *JobExecutor) LogBytesDropped() int64 {
+	return e.logDropped.Load()
 }
 
 // executeScript executes a script
-func (e *JobExecutor) executeScript(ctx context.Context, j *job.Job) (string, int, error) {
+func (e *JobExecutor) executeScript(ctx context.Context, j *job.Job, dir string, logw io.Writer) (processOutput, int, error) {
 	// Create temporary script file
 	scriptFile := filepath.Join(e.workingDir, fmt.Sprintf("script_%s.sh", j.ID))
 
 	// Write script content to file
 	err := os.WriteFile(scriptFile, []byte(j.Script), 0755)
 	if err != nil {
-		return "", 1, fmt.Errorf("failed to write script file: %v", err)
+		return processOutput{}, 1, job.NewExecutionError(j.ID, "failed to write script file to "+e.workingDir, err)
 	}
 
 	// Clean up script file after execution
@@ -161,89 +592,373 @@ func (e *JobExecutor) executeScript(ctx context.Context, j *job.Job) (string, in
 
 	// Execute script
 	cmd := exec.CommandContext(ctx, "/bin/bash", scriptFile)
-	cmd.Dir = e.workingDir
+	cmd.Dir = dir
 
-	// Set environment variables
+	// Set environment variables, secrets included
+	env, err := e.jobEnvironment(j)
+	if err != nil {
+		return processOutput{}, 1, err
+	}
 	cmd.Env = os.Environ()
-	for key, value := range j.Environment {
+	for key, value := range env {
 		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", key, value))
 	}
 
-	// Capture output
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	// Capture output, teeing it to the job's log file as it is produced
+	stdout := &limitedBuffer{limit: e.config.MaxOutputBytes}
+	stderr := &limitedBuffer{limit: e.config.MaxOutputBytes}
+	cmd.Stdout = io.MultiWriter(stdout, logw)
+	cmd.Stderr = io.MultiWriter(stderr, logw)
 
-	err = cmd.Run()
+	err = e.runProcess(j.ID, cmd)
 
-	// Combine stdout and stderr
-	output := stdout.String()
-	if stderr.Len() > 0 {
-		if output != "" {
-			output += "\n---STDERR---\n"
+	exitCode := processExitCode(ctx, err)
+
+	return newProcessOutput(stdout, stderr), exitCode, err
+}
+
+// LogPath returns where the log of the given job is kept on this host
+func (e *JobExecutor) LogPath(jobID string) string {
+	return filepath.Join(e.workingDir, jobLogDir, jobID+".log")
+}
+
+// createJobLog creates the job's log file. Log problems never fail the job:
+// if the file can't be created the output is only returned inline and the
+// path is left empty.
+func (e *JobExecutor) createJobLog(jobID string) (io.WriteCloser, string) {
+	path := e.LogPath(jobID)
+	if err := ensureDirectory(filepath.Dir(path)); err == nil {
+		if f, err := os.Create(path); err == nil {
+			return bestEffortWriter{f}, path
 		}
-		output += stderr.String()
 	}
 
-	exitCode := 0
-	if err != nil {
-		if exitError, ok := err.(*exec.ExitError); ok {
-			exitCode = exitError.ExitCode()
-		} else {
-			exitCode = 1
-		}
+	e.logger.Warn("could not create job log file, keeping output inline only", "job_id", jobID, "path", path)
+	return bestEffortWriter{nopWriteCloser{io.Discard}}, ""
+}
+
+// runProcess runs a job's command in its own process group, remembering the
+// group while it runs so Signal can reach the command and its children
+func (e *JobExecutor) runProcess(jobID string, cmd *exec.Cmd) error {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	// On timeout or cancellation kill the whole process group, not just the
+	// leader, and stop waiting for output once the group is dead even if an
+	// escaped grandchild still holds the pipes
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
 	}
+	cmd.WaitDelay = processWaitDelay
 
-	return output, exitCode, err
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	e.processMux.Lock()
+	e.processes[jobID] = cmd.Process.Pid
+	e.processMux.Unlock()
+
+	defer func() {
+		e.processMux.Lock()
+		delete(e.processes, jobID)
+		e.processMux.Unlock()
+	}()
+
+	return cmd.Wait()
+}
+
+// Signal sends a signal to the process group of a running command or script job
+func (e *JobExecutor) Signal(jobID string, sig syscall.Signal) error {
+	e.processMux.Lock()
+	pgid, exists := e.processes[jobID]
+	e.processMux.Unlock()
+
+	if !exists {
+		return job.NewJobNotFoundError(jobID)
+	}
+
+	if err := syscall.Kill(-pgid, sig); err != nil {
+		return fmt.Errorf("failed to send %v to job %s: %v", sig, jobID, err)
+	}
+	return nil
+}
+
+// ExtendTimeout pushes back the deadline of a running job and returns the
+// new deadline. Jobs without a timeout, or whose timeout has already
+// expired, cannot be extended, nor can a job's timeout grow past the
+// worker's max job timeout.
+func (e *JobExecutor) ExtendTimeout(jobID string, extra time.Duration) (time.Time, error) {
+	if extra <= 0 {
+		return time.Time{}, job.NewValidationError("timeout extension must be positive")
+	}
+
+	e.processMux.Lock()
+	deadline, exists := e.deadlines[jobID]
+	e.processMux.Unlock()
+
+	if !exists {
+		return time.Time{}, job.NewJobNotFoundError(jobID)
+	}
+
+	extended, err := deadline.Extend(extra, e.config.MaxJobTimeout)
+	switch {
+	case errors.Is(err, errTimeoutLimit):
+		return time.Time{}, job.NewValidationError(fmt.Sprintf("extending job %s by %v would exceed the max job timeout of %v", jobID, extra, e.config.MaxJobTimeout))
+	case err != nil:
+		return time.Time{}, job.NewConflictError(fmt.Sprintf("job %s has already timed out", jobID))
+	}
+	return extended, nil
+}
+
+// trackDeadline records a running job's deadline so it can be extended
+func (e *JobExecutor) trackDeadline(jobID string, deadline *jobDeadline) {
+	e.processMux.Lock()
+	e.deadlines[jobID] = deadline
+	e.processMux.Unlock()
+}
+
+// untrackDeadline stops a finished job's deadline and forgets it
+func (e *JobExecutor) untrackDeadline(jobID string, deadline *jobDeadline) {
+	deadline.Stop()
+
+	e.processMux.Lock()
+	delete(e.deadlines, jobID)
+	e.processMux.Unlock()
+}
+
+// logTimeoutWarning tells operators a job is about to be killed for running
+// past its timeout, while there is still time to extend it
+func (e *JobExecutor) logTimeoutWarning(j *job.Job, deadline time.Time) {
+	e.logger.Warn("job is close to its timeout and will be killed unless extended",
+		"job_id", j.ID, "timeout", j.Timeout, "deadline", deadline.UTC())
 }
 
 // executeHTTP executes an HTTP request
-func (e *JobExecutor) executeHTTP(ctx context.Context, j *job.Job) (string, int, error) {
+func (e *JobExecutor) executeHTTP(ctx context.Context, j *job.Job) (string, *job.HTTPResponse, int, error) {
 	client := &http.Client{
-		Timeout: 30 * time.Second,
+		Timeout: httpTimeout(j),
 	}
 
-	// Create request
-	req, err := http.NewRequestWithContext(ctx, j.Method, j.URL, nil)
+	// Set headers from environment, secrets included
+	env, err := e.jobEnvironment(j)
 	if err != nil {
-		return "", 1, fmt.Errorf("failed to create HTTP request: %v", err)
+		return "", nil, 1, err
 	}
-
-	// Set headers from environment
-	for key, value := range j.Environment {
+	header := make(http.Header)
+	for key, value := range env {
 		if strings.HasPrefix(key, "HTTP_HEADER_") {
 			headerName := strings.TrimPrefix(key, "HTTP_HEADER_")
-			req.Header.Set(headerName, value)
+			header.Set(headerName, value)
+		}
+	}
+
+	// Connection errors and retryable statuses are retried up to the job's
+	// retries, each attempt within the job's context so a cancel or timeout
+	// ends the loop, even while waiting between attempts
+	var resp *http.Response
+	var body []byte
+	var truncated bool
+	attempts := 0
+	for {
+		attempts++
+		req, err := http.NewRequestWithContext(ctx, j.Method, j.URL, strings.NewReader(j.Body))
+		if err != nil {
+			return "", nil, 1, fmt.Errorf("failed to create HTTP request: %v", err)
+		}
+		req.Header = header.Clone()
+
+		resp, body, truncated, err = doHTTPRequest(client, req, e.config.MaxOutputBytes)
+		retryable := err != nil || e.retryableHTTPStatus(resp.StatusCode)
+		if !retryable || attempts > j.Retries || ctx.Err() != nil {
+			if err != nil {
+				return attemptsNote(attempts), nil, 1, fmt.Errorf("HTTP request failed%s: %v", afterAttempts(attempts), err)
+			}
+			break
+		}
+
+		timer := time.NewTimer(e.httpRetryDelay(attempts, resp))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return attemptsNote(attempts), nil, 1, fmt.Errorf("HTTP request cancelled while waiting to retry%s: %v", afterAttempts(attempts), context.Cause(ctx))
+		case <-timer.C:
 		}
 	}
+	response := &job.HTTPResponse{StatusCode: resp.StatusCode, Headers: resp.Header, Body: string(body), Truncated: truncated}
+	if truncated {
+		body = append(body, truncationMarker...)
+	}
 
-	// Execute request
+	// Consider 2xx status codes as success
+	if resp.StatusCode >= 400 {
+		return attemptsNote(attempts) + formatHTTPOutput(resp, body), response, 1,
+			fmt.Errorf("HTTP request returned status %d%s", resp.StatusCode, afterAttempts(attempts))
+	}
+
+	if !isJSONContentType(resp.Header.Get("Content-Type")) {
+		return attemptsNote(attempts) + formatHTTPOutput(resp, body), response, 0, nil
+	}
+
+	if j.ResponsePath != "" {
+		output, err := extractResponseValue(body, j.ResponsePath)
+		if err != nil {
+			return attemptsNote(attempts) + formatHTTPOutput(resp, body), response, 1, err
+		}
+		return attemptsNote(attempts) + output, response, 0, nil
+	}
+
+	// Pretty-print JSON bodies, falling back to the raw bytes if they don't parse
+	var pretty bytes.Buffer
+	if err := json.Indent(&pretty, body, "", "  "); err == nil {
+		body = pretty.Bytes()
+	}
+	return attemptsNote(attempts) + formatHTTPOutput(resp, body), response, 0, nil
+}
+
+// doHTTPRequest sends a request and reads up to limit bytes of the response
+// body, reporting whether there was more. A limit of zero reads it all.
+func doHTTPRequest(client *http.Client, req *http.Request, limit int) (*http.Response, []byte, bool, error) {
 	resp, err := client.Do(req)
 	if err != nil {
-		return "", 1, fmt.Errorf("HTTP request failed: %v", err)
+		return nil, nil, false, err
 	}
 	defer resp.Body.Close()
 
-	// Read response body
-	body, err := io.ReadAll(resp.Body)
+	var reader io.Reader = resp.Body
+	if limit > 0 {
+		reader = io.LimitReader(resp.Body, int64(limit)+1)
+	}
+	body, err := io.ReadAll(reader)
 	if err != nil {
-		return "", 1, fmt.Errorf("failed to read response body: %v", err)
+		return nil, nil, false, fmt.Errorf("failed to read response body: %v", err)
+	}
+	if limit > 0 && len(body) > limit {
+		return resp, body[:limit], true, nil
+	}
+	return resp, body, false, nil
+}
+
+// retryableHTTPStatus reports whether an HTTP job retries a response status
+func (e *JobExecutor) retryableHTTPStatus(status int) bool {
+	statuses := e.config.HTTPRetryStatuses
+	if len(statuses) == 0 {
+		statuses = defaultHTTPRetryStatuses
+	}
+	return slices.Contains(statuses, status)
+}
+
+// httpRetryDelay is how long to wait before retrying a request: the delay
+// the response's Retry-After header asks for, otherwise the backoff doubled
+// for each attempt so far, either capped at the configured maximum so a
+// server can't hold a job's slot for as long as it likes
+func (e *JobExecutor) httpRetryDelay(attempts int, resp *http.Response) time.Duration {
+	limit := e.config.HTTPRetryBackoffMax
+	if resp != nil {
+		if delay, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			if limit > 0 && delay > limit {
+				delay = limit
+			}
+			return delay
+		}
+	}
+
+	delay := e.config.HTTPRetryBackoff
+	if delay <= 0 {
+		delay = defaultHTTPRetryBackoff
+	}
+	for i := 1; i < attempts && (limit <= 0 || delay < limit); i++ {
+		delay *= 2
+	}
+	if limit > 0 && delay > limit {
+		delay = limit
+	}
+	return delay
+}
+
+// parseRetryAfter parses a Retry-After header given either as seconds or as
+// an HTTP date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(at.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// attemptsNote records in an HTTP job's output how many requests it took,
+// when it took more than one
+func attemptsNote(attempts int) string {
+	if attempts <= 1 {
+		return ""
+	}
+	return fmt.Sprintf("Attempts: %d\n", attempts)
+}
+
+// afterAttempts qualifies an HTTP job's error with the requests it took,
+// when it took more than one
+func afterAttempts(attempts int) string {
+	if attempts <= 1 {
+		return ""
+	}
+	return fmt.Sprintf(" after %d attempts", attempts)
+}
+
+// httpTimeout is how long an HTTP job's request may take: its own HTTP
+// timeout if set, otherwise the job's timeout
+func httpTimeout(j *job.Job) time.Duration {
+	switch {
+	case j.HTTPTimeout > 0:
+		return j.HTTPTimeout
+	case j.Timeout > 0:
+		return j.Timeout
+	default:
+		return defaultHTTPTimeout
 	}
+}
 
-	// Format output
+// formatHTTPOutput renders an HTTP job's response status and body
+func formatHTTPOutput(resp *http.Response, body []byte) string {
 	output := fmt.Sprintf("Status: %d %s\n", resp.StatusCode, resp.Status)
 	if len(body) > 0 {
 		output += fmt.Sprintf("Body: %s", string(body))
 	}
+	return output
+}
 
-	// Consider 2xx status codes as success
-	exitCode := 0
-	if resp.StatusCode >= 400 {
-		exitCode = 1
-		err = fmt.Errorf("HTTP request returned status %d", resp.StatusCode)
+// extractResponseValue selects the value at a response path from a JSON
+// body. Strings are returned as-is and other values as indented JSON.
+func extractResponseValue(body []byte, expression string) (string, error) {
+	path, err := job.ParseResponsePath(expression)
+	if err != nil {
+		return "", err
 	}
 
-	return output, exitCode, err
+	var document interface{}
+	if err := json.Unmarshal(body, &document); err != nil {
+		return "", fmt.Errorf("response is not valid JSON: %v", err)
+	}
+
+	value, err := path.Extract(document)
+	if err != nil {
+		return "", err
+	}
+
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	encoded, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode extracted value: %v", err)
+	}
+	return string(encoded), nil
 }
 
 // executeFile executes file operations
@@ -254,32 +969,190 @@ func (e *JobExecutor) executeFile(ctx context.Context, j *job.Job) (string, int,
 		operation = op
 	}
 
-	filePath := j.FilePath
-	if !filepath.IsAbs(filePath) {
-		filePath = filepath.Join(e.workingDir, filePath)
+	resolve := e.resolveFilePath
+	if operation == "delete" {
+		resolve = e.resolveDeletePath
+	}
+	filePath, err := resolve(j.FilePath)
+	if err != nil {
+		return "", 1, err
 	}
 
 	switch operation {
 	case "read":
-		return e.readFile(filePath)
+		binaryMode := binaryModeBase64
+		if mode, exists := j.Environment["FILE_BINARY_MODE"]; exists {
+			binaryMode = mode
+		}
+		return e.readFile(filePath, binaryMode)
 	case "stat":
 		return e.statFile(filePath)
 	case "list":
 		return e.listDirectory(filePath)
+	case "write", "append":
+		content := j.FileContent
+		if content == "" {
+			content = j.Environment["FILE_CONTENT"]
+		}
+		mode := defaultFileMode
+		if raw, exists := j.Environment["FILE_MODE"]; exists {
+			parsed, err := strconv.ParseUint(raw, 8, 32)
+			if err != nil || parsed > 0o777 {
+				return "", 1, fmt.Errorf("invalid FILE_MODE, expected octal permissions such as 0644: %s", raw)
+			}
+			mode = os.FileMode(parsed)
+		}
+		return e.writeFile(filePath, content, mode, operation == "append")
+	case "delete":
+		return e.deleteFile(filePath, j.Environment["FILE_RECURSIVE"] == "true")
 	default:
 		return "", 1, fmt.Errorf("unsupported file operation: %s", operation)
 	}
 }
 
-// readFile reads a file and returns its content
-func (e *JobExecutor) readFile(filePath string) (string, int, error) {
+// resolveFilePath returns the path a file job works on. Relative paths are
+// taken from the worker's working directory. The path, with symlinks
+// resolved, must lie within one of the worker's allowed roots, which default
+// to the working directory, so neither ".." nor a link can reach past them.
+func (e *JobExecutor) resolveFilePath(path string) (string, error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(e.workingDir, path)
+	}
+	resolved, err := resolveSymlinks(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve file path %s: %v", path, err)
+	}
+
+	roots := e.fileRoots()
+	for _, root := range roots {
+		// Roots may be symlinks themselves, e.g. /tmp on macOS
+		if real, err := resolveSymlinks(root); err == nil && isWithinRoot(real, resolved) {
+			return resolved, nil
+		}
+	}
+	return "", fmt.Errorf("file path %s is outside the allowed roots %s", path, strings.Join(roots, ", "))
+}
+
+// resolveDeletePath returns the path a delete job removes. It is resolved
+// like any file job's, except that a symlink at the path itself is kept, so
+// deleting it unlinks the link rather than removing what it points to.
+func (e *JobExecutor) resolveDeletePath(path string) (string, error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(e.workingDir, path)
+	}
+	path = filepath.Clean(path)
+
+	info, err := os.Lstat(path)
+	if err != nil || info.Mode()&os.ModeSymlink == 0 {
+		return e.resolveFilePath(path)
+	}
+	parent, err := e.resolveFilePath(filepath.Dir(path))
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(parent, filepath.Base(path)), nil
+}
+
+// fileRoots returns the directories file jobs are confined to
+func (e *JobExecutor) fileRoots() []string {
+	if len(e.config.AllowedRoots) == 0 {
+		return []string{e.workingDir}
+	}
+	return e.config.AllowedRoots
+}
+
+// writeFile writes content to a file, replacing it or appending to it. A new
+// file is created with the given mode; an existing file keeps its own. The
+// path has its symlinks resolved already, so one that is a symlink now was
+// swapped in since and is refused.
+func (e *JobExecutor) writeFile(filePath, content string, mode os.FileMode, appendTo bool) (string, int, error) {
+	if maxBytes := e.config.MaxFileBytes; maxBytes > 0 && int64(len(content)) > maxBytes {
+		return "", 1, fmt.Errorf("content is %d bytes, exceeding the %d byte limit", len(content), maxBytes)
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC | syscall.O_NOFOLLOW
+	if appendTo {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND | syscall.O_NOFOLLOW
+	}
+	file, err := os.OpenFile(filePath, flags, mode)
+	if err != nil {
+		return "", 1, fmt.Errorf("failed to open file: %v", err)
+	}
+	if _, err := file.WriteString(content); err != nil {
+		file.Close()
+		return "", 1, fmt.Errorf("failed to write file: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		return "", 1, fmt.Errorf("failed to write file: %v", err)
+	}
+
+	verb := "Wrote"
+	if appendTo {
+		verb = "Appended"
+	}
+	return fmt.Sprintf("%s %d bytes to %s", verb, len(content), filePath), 0, nil
+}
+
+// deleteFile removes a file or an empty directory, or a directory and
+// everything in it when recursive is set. Neither the working directory nor
+// any of the allowed roots is ever removed.
+func (e *JobExecutor) deleteFile(filePath string, recursive bool) (string, int, error) {
+	if workingDir, err := resolveSymlinks(e.workingDir); err == nil && filePath == workingDir {
+		return "", 1, fmt.Errorf("refusing to delete the working directory %s", filePath)
+	}
+	for _, root := range e.fileRoots() {
+		if real, err := resolveSymlinks(root); err == nil && filePath == real {
+			return "", 1, fmt.Errorf("refusing to delete the allowed root %s", root)
+		}
+	}
+
+	remove := os.Remove
+	if recursive {
+		// RemoveAll succeeds on missing paths, so check first to report them
+		if _, err := os.Lstat(filePath); err != nil {
+			return "", 1, fmt.Errorf("failed to delete file: %v", err)
+		}
+		remove = os.RemoveAll
+	}
+	if err := remove(filePath); err != nil {
+		return "", 1, fmt.Errorf("failed to delete file: %v", err)
+	}
+	return "Deleted " + filePath, 0, nil
+}
+
+// readFile reads a file and returns its content. Text is returned as is;
+// binary content is base64-encoded or refused depending on binaryMode.
+func (e *JobExecutor) readFile(filePath, binaryMode string) (string, int, error) {
+	if binaryMode != binaryModeBase64 && binaryMode != binaryModeRefuse {
+		return "", 1, fmt.Errorf("unsupported binary mode: %s", binaryMode)
+	}
+
+	// Check the size up front so oversized files are never loaded into memory
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return "", 1, fmt.Errorf("failed to read file: %v", err)
+	}
+	if maxBytes := e.config.MaxFileBytes; maxBytes > 0 && info.Size() > maxBytes {
+		return "", 1, fmt.Errorf("file %s is %d bytes, exceeding the %d byte limit", filePath, info.Size(), maxBytes)
+	}
+
 	content, err := os.ReadFile(filePath)
 	if err != nil {
 		return "", 1, fmt.Errorf("failed to read file: %v", err)
 	}
 
-	output := fmt.Sprintf("File: %s\nSize: %d bytes\nContent:\n%s",
-		filePath, len(content), string(content))
+	if isText(content) {
+		output := fmt.Sprintf("File: %s\nSize: %d bytes\nContent:\n%s",
+			filePath, len(content), string(content))
+		return output, 0, nil
+	}
+
+	if binaryMode == binaryModeRefuse {
+		return "", 1, fmt.Errorf("file %s contains binary content", filePath)
+	}
+
+	output := fmt.Sprintf("File: %s\nSize: %d bytes\nEncoding: base64\nContent:\n%s",
+		filePath, len(content), base64.StdEncoding.EncodeToString(content))
 
 	return output, 0, nil
 }
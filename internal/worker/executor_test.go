@@ -0,0 +1,1515 @@
+package worker
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"infinitrain/internal/config"
+	"infinitrain/pkg/job"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// newTestExecutor creates an executor rooted in a temporary working directory
+func newTestExecutor(t *testing.T) (*JobExecutor, *config.WorkerConfig) {
+	t.Helper()
+
+	cfg := &config.WorkerConfig{
+		ID:                "test-worker",
+		MaxConcurrentJobs: 2,
+		WorkingDirectory:  t.TempDir(),
+	}
+	return NewJobExecutor(cfg), cfg
+}
+
+func TestJobExecutor_WorkingDirOverride(t *testing.T) {
+	allowedRoot := t.TempDir()
+	checkout := filepath.Join(allowedRoot, "checkout")
+	if err := ensureDirectory(checkout); err != nil {
+		t.Fatalf("failed to create checkout: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		workingDir string
+		wantDir    func(cfg *config.WorkerConfig) string
+		wantErr    func(error) bool
+	}{
+		{
+			name:       "allowed override",
+			workingDir: checkout,
+			wantDir:    func(*config.WorkerConfig) string { return checkout },
+		},
+		{
+			name:       "missing override",
+			workingDir: filepath.Join(allowedRoot, "missing"),
+			wantErr:    job.IsExecutionError,
+		},
+		{
+			name:       "override outside allowlist",
+			workingDir: t.TempDir(),
+			wantErr:    job.IsValidationError,
+		},
+		{
+			name:       "escape via parent reference",
+			workingDir: filepath.Join(allowedRoot, "..", "elsewhere"),
+			wantErr:    job.IsValidationError,
+		},
+		{
+			name:    "default when unset",
+			wantDir: func(cfg *config.WorkerConfig) string { return cfg.WorkingDirectory },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor, cfg := newTestExecutor(t)
+			cfg.AllowedWorkingDirs = []string{allowedRoot}
+
+			j := &job.Job{
+				ID:         "job-pwd",
+				Type:       job.JobTypeCommand,
+				Command:    "pwd",
+				WorkingDir: tt.workingDir,
+			}
+
+			result, err := executor.Execute(context.Background(), j)
+			if tt.wantErr != nil {
+				if !tt.wantErr(err) {
+					t.Fatalf("Unexpected error %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+
+			if got := strings.TrimSpace(result.Output); got != tt.wantDir(cfg) {
+				t.Errorf("Expected job to run in %s, got %s", tt.wantDir(cfg), got)
+			}
+		})
+	}
+
+	if _, err := os.Stat(filepath.Join(allowedRoot, "missing")); !os.IsNotExist(err) {
+		t.Errorf("Expected a missing working directory not to be created, got %v", err)
+	}
+}
+
+func TestJobExecutor_StepsResumeFromFailedStep(t *testing.T) {
+	executor, cfg := newTestExecutor(t)
+	marker := filepath.Join(cfg.WorkingDirectory, "ready")
+
+	j := &job.Job{
+		ID:    "job-steps",
+		Type:  job.JobTypeCommand,
+		Steps: []string{"echo one", "test -f " + marker, "echo three"},
+	}
+
+	result, err := executor.Execute(context.Background(), j)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	if result.Status != job.JobStatusFailed {
+		t.Fatalf("Expected status %v, got %v", job.JobStatusFailed, result.Status)
+	}
+	if len(result.Steps) != 2 {
+		t.Fatalf("Expected 2 step results, got %d", len(result.Steps))
+	}
+	if result.Steps[0].ExitCode != 0 || result.Steps[1].ExitCode == 0 {
+		t.Errorf("Expected step 1 to pass and step 2 to fail, got %+v", result.Steps)
+	}
+
+	j.RecordStepProgress(result)
+	if j.ResumeStep != 1 {
+		t.Fatalf("Expected retry to resume from step index 1, got %d", j.ResumeStep)
+	}
+
+	// Fix the underlying issue and retry
+	if err := os.WriteFile(marker, nil, 0644); err != nil {
+		t.Fatalf("failed to create marker: %v", err)
+	}
+
+	result, err = executor.Execute(context.Background(), j)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	if result.Status != job.JobStatusCompleted {
+		t.Fatalf("Expected status %v, got %v: %s", job.JobStatusCompleted, result.Status, result.Error)
+	}
+	if len(result.Steps) != 2 || result.Steps[0].Index != 1 || result.Steps[1].Index != 2 {
+		t.Errorf("Expected retry to run steps 1 and 2, got %+v", result.Steps)
+	}
+	if strings.Contains(result.Output, "one") {
+		t.Errorf("Expected the first step not to run again, got output %q", result.Output)
+	}
+}
+
+func TestJobExecutor_CommandNotFound(t *testing.T) {
+	tests := []struct {
+		name         string
+		command      string
+		wantStatus   job.JobStatus
+		wantExitCode int
+		wantError    string
+	}{
+		{
+			name:         "missing binary",
+			command:      "definitely-not-a-real-binary --flag",
+			wantStatus:   job.JobStatusFailed,
+			wantExitCode: 127,
+			wantError:    "command not found: definitely-not-a-real-binary",
+		},
+		{
+			name:       "present binary",
+			command:    "echo hello",
+			wantStatus: job.JobStatusCompleted,
+		},
+		{
+			name:         "present binary that fails",
+			command:      "false",
+			wantStatus:   job.JobStatusFailed,
+			wantExitCode: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor, _ := newTestExecutor(t)
+
+			result, err := executor.Execute(context.Background(), &job.Job{
+				ID:      "job-lookup",
+				Type:    job.JobTypeCommand,
+				Command: tt.command,
+			})
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+
+			if result.Status != tt.wantStatus {
+				t.Errorf("Expected status %v, got %v", tt.wantStatus, result.Status)
+			}
+			if result.ExitCode != tt.wantExitCode {
+				t.Errorf("Expected exit code %d, got %d", tt.wantExitCode, result.ExitCode)
+			}
+			if !strings.Contains(result.Error, tt.wantError) {
+				t.Errorf("Expected error containing %q, got %q", tt.wantError, result.Error)
+			}
+		})
+	}
+}
+
+func TestJobExecutor_ReadFile(t *testing.T) {
+	binary := []byte{0x89, 'P', 'N', 'G', 0x00, 0xff, 0xfe}
+
+	tests := []struct {
+		name        string
+		content     []byte
+		binaryMode  string
+		wantStatus  job.JobStatus
+		wantContent string
+		wantError   string
+	}{
+		{
+			name:        "utf-8 text",
+			content:     []byte("héllo wörld\n"),
+			wantStatus:  job.JobStatusCompleted,
+			wantContent: "Content:\nhéllo wörld\n",
+		},
+		{
+			name:        "binary is base64-encoded by default",
+			content:     binary,
+			wantStatus:  job.JobStatusCompleted,
+			wantContent: "Encoding: base64\nContent:\n" + base64.StdEncoding.EncodeToString(binary),
+		},
+		{
+			name:       "binary refused",
+			content:    binary,
+			binaryMode: "refuse",
+			wantStatus: job.JobStatusFailed,
+			wantError:  "contains binary content",
+		},
+		{
+			name:       "oversized file",
+			content:    []byte(strings.Repeat("a", 65)),
+			wantStatus: job.JobStatusFailed,
+			wantError:  "exceeding the 64 byte limit",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor, cfg := newTestExecutor(t)
+			cfg.MaxFileBytes = 64
+
+			path := filepath.Join(cfg.WorkingDirectory, "input")
+			if err := os.WriteFile(path, tt.content, 0644); err != nil {
+				t.Fatalf("failed to write file: %v", err)
+			}
+
+			j := &job.Job{ID: "job-read", Type: job.JobTypeFile, FilePath: path}
+			if tt.binaryMode != "" {
+				j.Environment = map[string]string{"FILE_BINARY_MODE": tt.binaryMode}
+			}
+
+			result, err := executor.Execute(context.Background(), j)
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+
+			if result.Status != tt.wantStatus {
+				t.Fatalf("Expected status %v, got %v: %s", tt.wantStatus, result.Status, result.Error)
+			}
+			if !strings.HasSuffix(result.Output, tt.wantContent) {
+				t.Errorf("Expected output ending in %q, got %q", tt.wantContent, result.Output)
+			}
+			if !strings.Contains(result.Error, tt.wantError) {
+				t.Errorf("Expected error containing %q, got %q", tt.wantError, result.Error)
+			}
+		})
+	}
+}
+
+func TestJobExecutor_WriteAndDeleteFiles(t *testing.T) {
+	executor, cfg := newTestExecutor(t)
+	run := func(path string, env map[string]string, content string) *job.JobResult {
+		t.Helper()
+		result, err := executor.Execute(context.Background(), &job.Job{
+			ID: "job-file", Type: job.JobTypeFile, FilePath: path, FileContent: content, Environment: env,
+		})
+		if err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+		return result
+	}
+	path := filepath.Join(cfg.WorkingDirectory, "notes.txt")
+
+	if result := run("notes.txt", map[string]string{"FILE_OPERATION": "write", "FILE_MODE": "0600"}, "first\n"); result.Status != job.JobStatusCompleted {
+		t.Fatalf("Expected write to complete, got %s: %s", result.Status, result.Error)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected a file with mode 0600, got %v, %v", info, err)
+	}
+
+	run("notes.txt", map[string]string{"FILE_OPERATION": "append", "FILE_CONTENT": "second\n"}, "")
+	if data, _ := os.ReadFile(path); string(data) != "first\nsecond\n" {
+		t.Errorf("Expected appended content, got %q", data)
+	}
+
+	if result := run("notes.txt", map[string]string{"FILE_OPERATION": "delete"}, ""); result.Status != job.JobStatusCompleted {
+		t.Fatalf("Expected delete to complete, got %s: %s", result.Status, result.Error)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected the file to be deleted, got %v", err)
+	}
+
+	nested := filepath.Join(cfg.WorkingDirectory, "nested")
+	os.MkdirAll(filepath.Join(nested, "inner"), 0o755)
+	if result := run("nested", map[string]string{"FILE_OPERATION": "delete"}, ""); result.Status != job.JobStatusFailed {
+		t.Errorf("Expected deleting a non-empty directory to fail without FILE_RECURSIVE")
+	}
+	if result := run("nested", map[string]string{"FILE_OPERATION": "delete", "FILE_RECURSIVE": "true"}, ""); result.Status != job.JobStatusCompleted {
+		t.Errorf("Expected a recursive delete to complete, got %s: %s", result.Status, result.Error)
+	}
+
+	outside := filepath.Join(t.TempDir(), "outside.txt")
+	for name, tt := range map[string]struct {
+		path string
+		env  map[string]string
+		want string
+	}{
+		"relative escape":   {"../escape.txt", map[string]string{"FILE_OPERATION": "write"}, "outside the allowed roots"},
+		"absolute outside":  {outside, map[string]string{"FILE_OPERATION": "write"}, "outside the allowed roots"},
+		"working directory": {".", map[string]string{"FILE_OPERATION": "delete"}, "refusing to delete"},
+		"invalid mode":      {"notes.txt", map[string]string{"FILE_OPERATION": "write", "FILE_MODE": "rw"}, "invalid FILE_MODE"},
+	} {
+		if result := run(tt.path, tt.env, "x"); result.Status != job.JobStatusFailed || !strings.Contains(result.Error, tt.want) {
+			t.Errorf("%s: expected a failure containing %q, got %s: %q", name, tt.want, result.Status, result.Error)
+		}
+	}
+
+	cfg.AllowedRoots = []string{cfg.WorkingDirectory, filepath.Dir(outside)}
+	if result := run(outside, map[string]string{"FILE_OPERATION": "write"}, "x"); result.Status != job.JobStatusCompleted {
+		t.Errorf("Expected a path in an allowed root to be used, got %s: %s", result.Status, result.Error)
+	}
+}
+
+func TestJobExecutor_FileRoots(t *testing.T) {
+	executor, cfg := newTestExecutor(t)
+	secret := filepath.Join(t.TempDir(), "secret.txt")
+	os.WriteFile(secret, []byte("hunter2"), 0o644)
+	os.WriteFile(filepath.Join(cfg.WorkingDirectory, "public.txt"), []byte("hello"), 0o644)
+	if err := os.Symlink(filepath.Dir(secret), filepath.Join(cfg.WorkingDirectory, "link")); err != nil {
+		t.Fatalf("failed to create symlink: %v", err)
+	}
+
+	read := func(path string) *job.JobResult {
+		t.Helper()
+		result, err := executor.Execute(context.Background(), &job.Job{ID: "job-read", Type: job.JobTypeFile, FilePath: path})
+		if err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+		return result
+	}
+
+	if result := read("public.txt"); result.Status != job.JobStatusCompleted {
+		t.Errorf("Expected a file in the working directory to be read, got %s: %s", result.Status, result.Error)
+	}
+	for _, path := range []string{"/etc/passwd", secret, "link/secret.txt", "link/missing.txt"} {
+		if result := read(path); result.Status != job.JobStatusFailed || !strings.Contains(result.Error, "outside the allowed roots") {
+			t.Errorf("%s: expected the path to be rejected, got %s: %q", path, result.Status, result.Error)
+		}
+	}
+
+	cfg.AllowedRoots = []string{filepath.Dir(secret)}
+	if result := read("link/secret.txt"); result.Status != job.JobStatusCompleted || !strings.Contains(result.Output, "hunter2") {
+		t.Errorf("Expected a link into an allowed root to be followed, got %s: %s", result.Status, result.Error)
+	}
+	if result := read("public.txt"); result.Status != job.JobStatusFailed {
+		t.Errorf("Expected the working directory to be excluded once roots are configured")
+	}
+}
+
+func TestJobExecutor_FileWriteDanglingSymlink(t *testing.T) {
+	executor, cfg := newTestExecutor(t)
+	outside := filepath.Join(t.TempDir(), "planted.txt")
+	if err := os.Symlink(outside, filepath.Join(cfg.WorkingDirectory, "dangling")); err != nil {
+		t.Fatalf("failed to create symlink: %v", err)
+	}
+
+	for _, operation := range []string{"write", "append"} {
+		result, err := executor.Execute(context.Background(), &job.Job{
+			ID:          "job-write",
+			Type:        job.JobTypeFile,
+			FilePath:    "dangling",
+			FileContent: "x",
+			Environment: map[string]string{"FILE_OPERATION": operation},
+		})
+		if err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+		if result.Status != job.JobStatusFailed || !strings.Contains(result.Error, "missing target") {
+			t.Errorf("%s: expected the dangling symlink to be refused, got %s: %q", operation, result.Status, result.Error)
+		}
+	}
+	if _, err := os.Stat(outside); !os.IsNotExist(err) {
+		t.Errorf("Expected nothing to be written outside the working directory, got %v", err)
+	}
+
+	// A link swapped in after the path was resolved isn't followed either
+	if _, _, err := executor.writeFile(filepath.Join(cfg.WorkingDirectory, "dangling"), "x", 0o644, false); err == nil {
+		t.Errorf("Expected writing through a symlink to fail")
+	}
+	if _, err := os.Stat(outside); !os.IsNotExist(err) {
+		t.Errorf("Expected nothing to be written through the symlink, got %v", err)
+	}
+}
+
+func TestJobExecutor_FileDeleteRootsAndSymlinks(t *testing.T) {
+	executor, cfg := newTestExecutor(t)
+	root := t.TempDir()
+	target := filepath.Join(root, "data")
+	os.MkdirAll(target, 0o755)
+	os.WriteFile(filepath.Join(target, "keep.txt"), []byte("keep"), 0o644)
+	cfg.AllowedRoots = []string{cfg.WorkingDirectory, root}
+
+	for _, link := range []string{"link", "dangling"} {
+		pointsTo := target
+		if link == "dangling" {
+			pointsTo = filepath.Join(root, "missing")
+		}
+		if err := os.Symlink(pointsTo, filepath.Join(cfg.WorkingDirectory, link)); err != nil {
+			t.Fatalf("failed to create symlink: %v", err)
+		}
+	}
+
+	remove := func(path string) *job.JobResult {
+		t.Helper()
+		result, err := executor.Execute(context.Background(), &job.Job{
+			ID: "job-delete", Type: job.JobTypeFile, FilePath: path,
+			Environment: map[string]string{"FILE_OPERATION": "delete", "FILE_RECURSIVE": "true"},
+		})
+		if err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+		return result
+	}
+
+	// Every allowed root is kept, not just the working directory
+	if result := remove(root); result.Status != job.JobStatusFailed || !strings.Contains(result.Error, "refusing to delete the allowed root") {
+		t.Errorf("Expected deleting an allowed root to be refused, got %s: %q", result.Status, result.Error)
+	}
+	if _, err := os.Stat(filepath.Join(target, "keep.txt")); err != nil {
+		t.Errorf("Expected the allowed root to be left alone, got %v", err)
+	}
+
+	// A symlink is unlinked rather than followed to what it points to
+	for _, link := range []string{"link", "dangling"} {
+		if result := remove(link); result.Status != job.JobStatusCompleted {
+			t.Errorf("%s: expected the symlink to be deleted, got %s: %s", link, result.Status, result.Error)
+		}
+		if _, err := os.Lstat(filepath.Join(cfg.WorkingDirectory, link)); !os.IsNotExist(err) {
+			t.Errorf("%s: expected the symlink to be gone, got %v", link, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(target, "keep.txt")); err != nil {
+		t.Errorf("Expected the symlink's target to be left alone, got %v", err)
+	}
+}
+
+func TestJobExecutor_RecreatesMissingWorkingDir(t *testing.T) {
+	executor, cfg := newTestExecutor(t)
+
+	// Simulate the directory being cleaned up after the worker started
+	if err := os.RemoveAll(cfg.WorkingDirectory); err != nil {
+		t.Fatalf("failed to remove working directory: %v", err)
+	}
+
+	result, err := executor.Execute(context.Background(), &job.Job{
+		ID:     "job-recreate",
+		Type:   job.JobTypeScript,
+		Script: "pwd",
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	if result.Status != job.JobStatusCompleted {
+		t.Fatalf("Expected status %v, got %v: %s", job.JobStatusCompleted, result.Status, result.Error)
+	}
+	if got := strings.TrimSpace(result.Output); got != cfg.WorkingDirectory {
+		t.Errorf("Expected script to run in recreated %s, got %s", cfg.WorkingDirectory, got)
+	}
+}
+
+func TestJobExecutor_UnusableWorkingDir(t *testing.T) {
+	executor, cfg := newTestExecutor(t)
+
+	// A regular file where the directory should be can't be replaced
+	if err := os.RemoveAll(cfg.WorkingDirectory); err != nil {
+		t.Fatalf("failed to remove working directory: %v", err)
+	}
+	if err := os.WriteFile(cfg.WorkingDirectory, nil, 0644); err != nil {
+		t.Fatalf("failed to create blocking file: %v", err)
+	}
+
+	for _, jobType := range []job.JobType{job.JobTypeCommand, job.JobTypeScript} {
+		t.Run(string(jobType), func(t *testing.T) {
+			_, err := executor.Execute(context.Background(), &job.Job{
+				ID:      "job-blocked",
+				Type:    jobType,
+				Command: "true",
+				Script:  "true",
+			})
+
+			if !job.IsExecutionError(err) {
+				t.Fatalf("Expected execution error, got %v", err)
+			}
+			if !strings.Contains(err.Error(), "working directory unavailable: "+cfg.WorkingDirectory) {
+				t.Errorf("Expected error naming the directory, got %q", err.Error())
+			}
+		})
+	}
+}
+
+func TestJobExecutor_TerminationReason(t *testing.T) {
+	tests := []struct {
+		name         string
+		job          *job.Job
+		wantExitCode int
+		wantReason   job.TerminationReason
+	}{
+		{
+			name:         "normal exit 1",
+			job:          &job.Job{Type: job.JobTypeCommand, Command: "false"},
+			wantExitCode: 1,
+			wantReason:   job.TerminationExited,
+		},
+		{
+			name:         "timeout",
+			job:          &job.Job{Type: job.JobTypeCommand, Command: "sleep 5", Timeout: 100 * time.Millisecond},
+			wantExitCode: 124,
+			wantReason:   job.TerminationTimeout,
+		},
+		{
+			name:         "killed by SIGKILL",
+			job:          &job.Job{Type: job.JobTypeScript, Script: "kill -9 $$"},
+			wantExitCode: 137,
+			wantReason:   job.TerminationSignaled,
+		},
+		{
+			name:         "missing binary",
+			job:          &job.Job{Type: job.JobTypeCommand, Command: "definitely-not-a-real-binary"},
+			wantExitCode: 127,
+			wantReason:   job.TerminationNotRun,
+		},
+		{
+			name:       "success",
+			job:        &job.Job{Type: job.JobTypeCommand, Command: "true"},
+			wantReason: job.TerminationExited,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor, _ := newTestExecutor(t)
+			tt.job.ID = "job-termination"
+
+			result, err := executor.Execute(context.Background(), tt.job)
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+
+			if result.ExitCode != tt.wantExitCode {
+				t.Errorf("Expected exit code %d, got %d", tt.wantExitCode, result.ExitCode)
+			}
+			if result.Termination != tt.wantReason {
+				t.Errorf("Expected termination reason %q, got %q", tt.wantReason, result.Termination)
+			}
+		})
+	}
+}
+
+func TestJobExecutor_TimeoutKillsProcessGroup(t *testing.T) {
+	executor, _ := newTestExecutor(t)
+
+	// The background sleep inherits the job's output, so Wait would block on
+	// it for the full 30s if only the script itself were killed
+	j := &job.Job{ID: "job-timeout", Type: job.JobTypeScript, Script: "sleep 30 &\nsleep 30", Timeout: 100 * time.Millisecond}
+
+	start := time.Now()
+	result, err := executor.Execute(context.Background(), j)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("Expected the whole process group to be killed promptly, took %v", elapsed)
+	}
+	if want := job.NewTimeoutError(j.ID, j.Timeout).Error(); result.Error != want {
+		t.Errorf("Expected error %q, got %q", want, result.Error)
+	}
+	if result.ExitCode != exitCodeTimeout || result.Termination != job.TerminationTimeout {
+		t.Errorf("Expected a timeout exit, got code %d and reason %q", result.ExitCode, result.Termination)
+	}
+}
+
+func TestJobExecutor_CancelKillsChildren(t *testing.T) {
+	executor, cfg := newTestExecutor(t)
+	pidFile := filepath.Join(cfg.WorkingDirectory, "child.pid")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		waitForFile(t, pidFile)
+		cancel()
+	}()
+
+	script := "sleep 30 >/dev/null 2>&1 &\necho $! > " + pidFile + ".tmp && mv " + pidFile + ".tmp " + pidFile + "\nwait"
+	result, err := executor.Execute(ctx, &job.Job{ID: "job-children", Type: job.JobTypeScript, Script: script})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Termination != job.TerminationCancelled {
+		t.Errorf("Expected the job to be cancelled, got %q", result.Termination)
+	}
+
+	data, err := os.ReadFile(pidFile)
+	if err != nil {
+		t.Fatalf("failed to read child PID: %v", err)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		t.Fatalf("invalid child PID %q: %v", data, err)
+	}
+
+	// The child is reparented once bash dies; give init a moment to reap it
+	deadline := time.Now().Add(2 * time.Second)
+	for syscall.Kill(pid, 0) == nil {
+		if time.Now().After(deadline) {
+			syscall.Kill(pid, syscall.SIGKILL)
+			t.Fatalf("Expected the script's child %d to be killed with it", pid)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// waitForFile waits until a file exists
+func waitForFile(t *testing.T, path string) {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if _, err := os.Stat(path); err == nil {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Errorf("timed out waiting for %s", path)
+}
+
+func TestJobExecutor_Secrets(t *testing.T) {
+	executor, cfg := newTestExecutor(t)
+	secretsFile := filepath.Join(t.TempDir(), "secrets.env")
+	content := "# deploy credentials\nexport API_TOKEN=\"s3cret\"\nDB_PASSWORD=hunter2\n"
+	if err := os.WriteFile(secretsFile, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write secrets file: %v", err)
+	}
+
+	j := &job.Job{ID: "job-secrets", Type: job.JobTypeScript, Script: "echo $API_TOKEN $DB_PASSWORD $REGION ${#API_TOKEN}",
+		Environment: map[string]string{"REGION": "eu"}, Secrets: []string{"API_TOKEN", "DB_PASSWORD"}}
+
+	result, err := executor.Execute(context.Background(), j)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Status != job.JobStatusFailed || !strings.Contains(result.Error, "no secret source") {
+		t.Errorf("Expected the job to fail without a secret source, got %s: %q", result.Status, result.Error)
+	}
+
+	cfg.SecretsFile = secretsFile
+	executor = NewJobExecutor(cfg)
+	result, err = executor.Execute(context.Background(), j)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	// The secrets reach the job but are masked in everything it reports
+	if result.Output != "*** *** eu 6\n" {
+		t.Errorf("Expected the secrets masked in the output, got %q: %s", result.Output, result.Error)
+	}
+	if logged, _ := os.ReadFile(result.LogFile); string(logged) != result.Output {
+		t.Errorf("Expected the secrets masked in the log, got %q", logged)
+	}
+	if _, exists := j.Environment["API_TOKEN"]; exists {
+		t.Errorf("Expected secrets to stay out of the job's own environment")
+	}
+
+	j.Secrets = []string{"MISSING"}
+	result, _ = executor.Execute(context.Background(), j)
+	if result.Status != job.JobStatusFailed || !strings.Contains(result.Error, "secret MISSING is not available") {
+		t.Errorf("Expected a missing secret to fail the job, got %s: %q", result.Status, result.Error)
+	}
+}
+
+func TestSecretMasker_SplitWrites(t *testing.T) {
+	var out strings.Builder
+	masker := newSecretMasker(&out, []string{"hunter2"}).(*secretMasker)
+	for _, chunk := range []string{"pass=hun", "ter2 ok", " hunter", "2"} {
+		masker.Write([]byte(chunk))
+	}
+	masker.Flush()
+	if out.String() != "pass=*** ok ***" {
+		t.Errorf("Expected secrets split across writes to be masked, got %q", out.String())
+	}
+}
+
+func TestJobExecutor_Interpolation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("path=" + r.URL.Path))
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	t.Setenv("INTERPOLATION_WORKER_ONLY", "leaked")
+
+	tests := []struct {
+		name        string
+		job         func(dir string) *job.Job
+		unknown     string
+		wantStatus  job.JobStatus
+		wantContent string
+		wantError   string
+	}{
+		{
+			name: "command",
+			job: func(dir string) *job.Job {
+				return &job.Job{Type: job.JobTypeCommand, Command: "echo $GREETING ${NAME}"}
+			},
+			wantStatus:  job.JobStatusCompleted,
+			wantContent: "hello world",
+		},
+		{
+			name: "url",
+			job: func(dir string) *job.Job {
+				return &job.Job{Type: job.JobTypeHTTP, Method: "GET", URL: "http://$HOST/${NAME}/health"}
+			},
+			wantStatus:  job.JobStatusCompleted,
+			wantContent: "path=/world/health",
+		},
+		{
+			name: "file path",
+			job: func(dir string) *job.Job {
+				return &job.Job{Type: job.JobTypeFile, FilePath: "${DIR}/$NAME.txt"}
+			},
+			wantStatus:  job.JobStatusCompleted,
+			wantContent: "from file",
+		},
+		{
+			name: "worker environment is not used",
+			job: func(dir string) *job.Job {
+				return &job.Job{Type: job.JobTypeCommand, Command: "echo [${INTERPOLATION_WORKER_ONLY}]"}
+			},
+			wantStatus:  job.JobStatusCompleted,
+			wantContent: "[]",
+		},
+		{
+			name: "unknown variable errors when strict",
+			job: func(dir string) *job.Job {
+				return &job.Job{Type: job.JobTypeCommand, Command: "echo $MISSING"}
+			},
+			unknown:   "error",
+			wantError: "failed to interpolate command: undefined variable: MISSING",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor, cfg := newTestExecutor(t)
+			cfg.UnknownVariables = tt.unknown
+			if err := os.WriteFile(filepath.Join(cfg.WorkingDirectory, "world.txt"), []byte("from file"), 0644); err != nil {
+				t.Fatalf("failed to write file: %v", err)
+			}
+
+			j := tt.job(cfg.WorkingDirectory)
+			j.ID = "job-interpolate"
+			j.Interpolate = true
+			j.Environment = map[string]string{
+				"GREETING": "hello",
+				"NAME":     "world",
+				"HOST":     host,
+				"DIR":      cfg.WorkingDirectory,
+			}
+
+			result, err := executor.Execute(context.Background(), j)
+			if tt.wantError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+					t.Fatalf("Expected error containing %q, got %v", tt.wantError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+
+			if result.Status != tt.wantStatus {
+				t.Fatalf("Expected status %v, got %v: %s", tt.wantStatus, result.Status, result.Error)
+			}
+			if !strings.Contains(result.Output, tt.wantContent) {
+				t.Errorf("Expected output containing %q, got %q", tt.wantContent, result.Output)
+			}
+		})
+	}
+
+	// Without the flag references are passed through untouched
+	executor, _ := newTestExecutor(t)
+	j := &job.Job{ID: "job-literal", Type: job.JobTypeCommand, Command: "echo $NAME", Environment: map[string]string{"NAME": "world"}}
+	result, err := executor.Execute(context.Background(), j)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if !strings.Contains(result.Output, "$NAME") {
+		t.Errorf("Expected uninterpolated output, got %q", result.Output)
+	}
+	if j.Command != "echo $NAME" {
+		t.Errorf("Expected the job to be left unmodified, got %q", j.Command)
+	}
+}
+
+func TestJobExecutor_LogFile(t *testing.T) {
+	executor, cfg := newTestExecutor(t)
+
+	j := &job.Job{
+		ID:     "job-logged",
+		Type:   job.JobTypeScript,
+		Script: "echo to stdout\necho to stderr >&2\necho done\n",
+	}
+	result, err := executor.Execute(context.Background(), j)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	if result.LogFile != executor.LogPath(j.ID) {
+		t.Fatalf("Expected log file %s, got %q", executor.LogPath(j.ID), result.LogFile)
+	}
+
+	data, err := os.ReadFile(result.LogFile)
+	if err != nil {
+		t.Fatalf("failed to read log file: %v", err)
+	}
+	for _, line := range []string{"to stdout", "to stderr", "done"} {
+		if !strings.Contains(string(data), line+"\n") {
+			t.Errorf("Expected log file to contain %q, got %q", line, data)
+		}
+	}
+
+	// Output of jobs that don't run a process is written once they finish
+	path := filepath.Join(cfg.WorkingDirectory, "input.txt")
+	os.WriteFile(path, []byte("file content"), 0644)
+	result, err = executor.Execute(context.Background(), &job.Job{ID: "job-file-logged", Type: job.JobTypeFile, FilePath: path})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Status != job.JobStatusCompleted {
+		t.Fatalf("Expected the file job to complete, got %s: %s", result.Status, result.Error)
+	}
+	if data, _ := os.ReadFile(result.LogFile); string(data) != result.Output {
+		t.Errorf("Expected log file to hold the full output %q, got %q", result.Output, data)
+	}
+}
+
+func TestJobExecutor_TimeoutWarningAndExtension(t *testing.T) {
+	executor, cfg := newTestExecutor(t)
+	cfg.TimeoutWarning = 0.5
+	cfg.MaxJobTimeout = 2 * time.Second
+
+	warned := make(chan time.Time, 2)
+	executor.warnTimeout = func(j *job.Job, deadline time.Time) { warned <- deadline }
+
+	j := &job.Job{ID: "job-slow", Type: job.JobTypeCommand, Command: "sleep 0.6", Timeout: 400 * time.Millisecond}
+	results := make(chan *job.JobResult, 1)
+	go func() {
+		result, err := executor.Execute(context.Background(), j)
+		if err != nil {
+			t.Errorf("Execute() error = %v", err)
+		}
+		results <- result
+	}()
+
+	// The warning fires halfway to the deadline, leaving time to extend it
+	var deadline time.Time
+	select {
+	case deadline = <-warned:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected a timeout warning")
+	}
+
+	// 400ms plus 2s would take the timeout past the worker's max
+	if _, err := executor.ExtendTimeout(j.ID, 2*time.Second); !job.IsValidationError(err) {
+		t.Errorf("Expected a validation error extending past the max timeout, got %v", err)
+	}
+
+	extended, err := executor.ExtendTimeout(j.ID, time.Second)
+	if err != nil {
+		t.Fatalf("ExtendTimeout() error = %v", err)
+	}
+	if got := extended.Sub(deadline); got != time.Second {
+		t.Errorf("Expected the deadline to move by 1s, moved by %v", got)
+	}
+
+	result := <-results
+	if result.Status != job.JobStatusCompleted || result.Termination == job.TerminationTimeout {
+		t.Errorf("Expected the extended job to complete, got %s (%s): %s", result.Status, result.Termination, result.Error)
+	}
+
+	if _, err := executor.ExtendTimeout(j.ID, time.Second); !job.IsJobNotFoundError(err) {
+		t.Errorf("Expected not found extending a finished job, got %v", err)
+	}
+}
+
+func TestJobExecutor_HTTPResponsePath(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/text" {
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte(`{"not": "parsed"}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Write([]byte(`{"data":{"items":[{"id":"item-1","size":3},{"id":"item-2"}]}}`))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name         string
+		path         string
+		responsePath string
+		wantStatus   job.JobStatus
+		wantOutput   string
+		wantError    string
+	}{
+		{
+			name:         "nested string field",
+			path:         "/json",
+			responsePath: "$.data.items[1].id",
+			wantStatus:   job.JobStatusCompleted,
+			wantOutput:   "item-2",
+		},
+		{
+			name:         "nested object",
+			path:         "/json",
+			responsePath: "data.items[0]",
+			wantStatus:   job.JobStatusCompleted,
+			wantOutput:   "{\n  \"id\": \"item-1\",\n  \"size\": 3\n}",
+		},
+		{
+			name:       "pretty-printed without a path",
+			path:       "/json",
+			wantStatus: job.JobStatusCompleted,
+			wantOutput: "Status: 200 200 OK\nBody: {\n  \"data\": {",
+		},
+		{
+			name:         "non-JSON response is returned raw",
+			path:         "/text",
+			responsePath: "$.not",
+			wantStatus:   job.JobStatusCompleted,
+			wantOutput:   "Status: 200 200 OK\nBody: {\"not\": \"parsed\"}",
+		},
+		{
+			name:         "missing field fails the job",
+			path:         "/json",
+			responsePath: "$.data.total",
+			wantStatus:   job.JobStatusFailed,
+			wantError:    `response_path $.data.total: field "total" not found`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor, _ := newTestExecutor(t)
+			j := &job.Job{ID: "job-http", Type: job.JobTypeHTTP, Method: "GET", URL: server.URL + tt.path, ResponsePath: tt.responsePath}
+
+			result, err := executor.Execute(context.Background(), j)
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if result.Status != tt.wantStatus {
+				t.Fatalf("Expected status %s, got %s: %s", tt.wantStatus, result.Status, result.Error)
+			}
+			if !strings.HasPrefix(result.Output, tt.wantOutput) {
+				t.Errorf("Expected output starting %q, got %q", tt.wantOutput, result.Output)
+			}
+			if result.Error != tt.wantError {
+				t.Errorf("Expected error %q, got %q", tt.wantError, result.Error)
+			}
+		})
+	}
+}
+
+func TestJobExecutor_HTTPBodyAndTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(200 * time.Millisecond)
+		}
+		body, _ := io.ReadAll(r.Body)
+		fmt.Fprintf(w, "%s %s", r.Method, body)
+	}))
+	defer server.Close()
+
+	executor, _ := newTestExecutor(t)
+	j := &job.Job{ID: "job-post", Type: job.JobTypeHTTP, Method: http.MethodPost, URL: server.URL, Body: `{"id":1}`}
+	result, err := executor.Execute(context.Background(), j)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Status != job.JobStatusCompleted || !strings.Contains(result.Output, `POST {"id":1}`) {
+		t.Errorf("Expected the body to be sent, got %s: %q", result.Status, result.Output)
+	}
+
+	slow := &job.Job{ID: "job-slow", Type: job.JobTypeHTTP, Method: http.MethodGet, URL: server.URL + "/slow", Timeout: time.Minute, HTTPTimeout: 20 * time.Millisecond}
+	result, err = executor.Execute(context.Background(), slow)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Status != job.JobStatusFailed || !strings.Contains(result.Error, "Client.Timeout") {
+		t.Errorf("Expected the HTTP timeout to fail the job, got %s: %q", result.Status, result.Error)
+	}
+
+	if got := httpTimeout(&job.Job{Timeout: time.Minute}); got != time.Minute {
+		t.Errorf("Expected the job timeout as the fallback, got %v", got)
+	}
+}
+
+func TestJobExecutor_HTTPBodyLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("x", 64)))
+	}))
+	defer server.Close()
+
+	executor, cfg := newTestExecutor(t)
+	cfg.MaxOutputBytes = 16
+
+	result, err := executor.Execute(context.Background(), &job.Job{ID: "job-big", Type: job.JobTypeHTTP, Method: http.MethodGet, URL: server.URL})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Response == nil || result.Response.Body != strings.Repeat("x", 16) || !result.Response.Truncated {
+		t.Errorf("Expected the body cut off at 16 bytes, got %+v", result.Response)
+	}
+	if !result.Truncated || !strings.Contains(result.Output, truncationMarker) || result.Status != job.JobStatusCompleted {
+		t.Errorf("Expected a completed job flagged truncated, got %s with truncated=%v: %q", result.Status, result.Truncated, result.Output)
+	}
+}
+
+func TestJobExecutor_ShellCommand(t *testing.T) {
+	tests := []struct {
+		name       string
+		shell      bool
+		allowShell bool
+		wantStatus job.JobStatus
+		wantOutput string
+		wantError  string
+	}{
+		{
+			name:       "shell runs the pipeline",
+			shell:      true,
+			allowShell: true,
+			wantStatus: job.JobStatusCompleted,
+			wantOutput: "HI\n",
+		},
+		{
+			name:       "without shell the pipe is literal",
+			wantStatus: job.JobStatusCompleted,
+			wantOutput: "hi | tr a-z A-Z\n",
+		},
+		{
+			name:       "shell refused unless allowed",
+			shell:      true,
+			wantStatus: job.JobStatusFailed,
+			wantError:  "shell commands are not allowed on this worker",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor, cfg := newTestExecutor(t)
+			cfg.AllowShell = tt.allowShell
+
+			j := &job.Job{ID: "job-shell", Type: job.JobTypeCommand, Command: "echo hi | tr a-z A-Z", Shell: tt.shell}
+			result, err := executor.Execute(context.Background(), j)
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if result.Status != tt.wantStatus {
+				t.Fatalf("Expected status %s, got %s: %s", tt.wantStatus, result.Status, result.Error)
+			}
+			if result.Output != tt.wantOutput {
+				t.Errorf("Expected output %q, got %q", tt.wantOutput, result.Output)
+			}
+			if result.Error != tt.wantError {
+				t.Errorf("Expected error %q, got %q", tt.wantError, result.Error)
+			}
+		})
+	}
+}
+
+func TestJobExecutor_OutputCounts(t *testing.T) {
+	tests := []struct {
+		name       string
+		script     string
+		maxOutput  int
+		wantOutput string
+		wantBytes  int64
+		wantLines  int64
+		wantCut    bool
+	}{
+		{
+			name:       "full output",
+			script:     "printf 'one\\ntwo\\nthree\\n'",
+			wantOutput: "one\ntwo\nthree\n",
+			wantBytes:  14,
+			wantLines:  3,
+		},
+		{
+			name:       "final line without newline",
+			script:     "printf 'one\\ntwo'",
+			wantOutput: "one\ntwo",
+			wantBytes:  7,
+			wantLines:  2,
+		},
+		{
+			name:       "truncated output keeps full counts",
+			script:     "printf 'one\\ntwo\\nthree\\n'",
+			maxOutput:  4,
+			wantOutput: "one\n...[truncated]",
+			wantBytes:  14,
+			wantLines:  3,
+			wantCut:    true,
+		},
+		{
+			name:       "output at the limit is kept whole",
+			script:     "printf 'one\n'",
+			maxOutput:  4,
+			wantOutput: "one\n",
+			wantBytes:  4,
+			wantLines:  1,
+		},
+		{
+			name:       "stdout and stderr both count",
+			script:     "echo out; echo err >&2",
+			wantOutput: "out\n\n---STDERR---\nerr\n",
+			wantBytes:  8,
+			wantLines:  2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor, cfg := newTestExecutor(t)
+			cfg.MaxOutputBytes = tt.maxOutput
+
+			result, err := executor.Execute(context.Background(), &job.Job{ID: "job-counts", Type: job.JobTypeScript, Script: tt.script})
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if result.Output != tt.wantOutput {
+				t.Errorf("Expected output %q, got %q", tt.wantOutput, result.Output)
+			}
+			if result.OutputBytes != tt.wantBytes || result.OutputLines != tt.wantLines {
+				t.Errorf("Expected %d bytes and %d lines, got %d and %d", tt.wantBytes, tt.wantLines, result.OutputBytes, result.OutputLines)
+			}
+			if result.Truncated != tt.wantCut || result.Status != job.JobStatusCompleted {
+				t.Errorf("Expected a completed job with truncated=%v, got %s with truncated=%v", tt.wantCut, result.Status, result.Truncated)
+			}
+		})
+	}
+}
+
+func TestJobExecutor_SeparateStreams(t *testing.T) {
+	executor, _ := newTestExecutor(t)
+
+	result, err := executor.Execute(context.Background(), &job.Job{ID: "job-streams", Type: job.JobTypeScript, Script: "echo out; echo err >&2"})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Stdout != "out\n" || result.Stderr != "err\n" {
+		t.Errorf("Expected separate streams, got stdout %q and stderr %q", result.Stdout, result.Stderr)
+	}
+
+	steps := &job.Job{ID: "job-steps", Type: job.JobTypeCommand, Steps: []string{"echo one", "echo two"}}
+	result, err = executor.Execute(context.Background(), steps)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Stdout != "one\ntwo\n" || result.Stderr != "" {
+		t.Errorf("Expected the steps' stdout without headers, got stdout %q and stderr %q", result.Stdout, result.Stderr)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", "abc")
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("queued"))
+	}))
+	defer server.Close()
+
+	result, err = executor.Execute(context.Background(), &job.Job{ID: "job-http", Type: job.JobTypeHTTP, Method: http.MethodGet, URL: server.URL})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	response := result.Response
+	if response == nil || response.StatusCode != http.StatusAccepted || response.Body != "queued" || response.Headers["X-Request-Id"][0] != "abc" {
+		t.Errorf("Expected the response's status, headers and body, got %+v", response)
+	}
+}
+
+// slowSink is a log writer that can't keep up: each write waits for a tick
+type slowSink struct {
+	strings.Builder
+	tick <-chan time.Time
+}
+
+func (s *slowSink) Write(p []byte) (int, error) {
+	<-s.tick
+	return s.Builder.Write(p)
+}
+
+func TestLogPipe_Overflow(t *testing.T) {
+	const chunks = 50
+	chunk := []byte("0123456789")
+
+	tests := []struct {
+		overflow    job.LogOverflow
+		wantDropped bool
+	}{
+		{job.LogOverflowBlock, false},
+		{job.LogOverflowDrop, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.overflow), func(t *testing.T) {
+			ticker := time.NewTicker(time.Millisecond)
+			defer ticker.Stop()
+			sink := &slowSink{tick: ticker.C}
+			pipe := newLogPipe(sink, 4, tt.overflow)
+
+			for i := 0; i < chunks; i++ {
+				if n, err := pipe.Write(chunk); n != len(chunk) || err != nil {
+					t.Fatalf("Write() = %d, %v", n, err)
+				}
+			}
+			pipe.Close()
+
+			written := int64(sink.Len())
+			if written+pipe.Dropped() != chunks*int64(len(chunk)) {
+				t.Errorf("Expected %d bytes written or dropped, got %d written and %d dropped", chunks*len(chunk), written, pipe.Dropped())
+			}
+			if tt.wantDropped != (pipe.Dropped() > 0) {
+				t.Errorf("Expected dropped bytes: %v, got %d", tt.wantDropped, pipe.Dropped())
+			}
+			if pipe.Dropped()%int64(len(chunk)) != 0 {
+				t.Errorf("Expected whole chunks to be dropped, got %d bytes", pipe.Dropped())
+			}
+		})
+	}
+}
+
+func TestJobExecutor_LogOverflowPolicy(t *testing.T) {
+	executor, cfg := newTestExecutor(t)
+	cfg.LogOverflow = string(job.LogOverflowDrop)
+
+	// The worker's policy applies unless the job sets its own
+	if got := executor.logOverflow(&job.Job{}); got != job.LogOverflowDrop {
+		t.Errorf("Expected the worker's policy, got %s", got)
+	}
+	if got := executor.logOverflow(&job.Job{LogOverflow: job.LogOverflowBlock}); got != job.LogOverflowBlock {
+		t.Errorf("Expected the job's policy, got %s", got)
+	}
+
+	result, err := executor.Execute(context.Background(), &job.Job{ID: "job-log", Type: job.JobTypeScript, Script: "echo hello", LogOverflow: job.LogOverflowBlock})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	logged, err := os.ReadFile(result.LogFile)
+	if err != nil {
+		t.Fatalf("failed to read job log: %v", err)
+	}
+	if string(logged) != "hello\n" || result.LogDropped != 0 {
+		t.Errorf("Expected the full output in the log, got %q with %d bytes dropped", logged, result.LogDropped)
+	}
+}
+
+func TestJobExecutor_SubscribeOutput(t *testing.T) {
+	executor, _ := newTestExecutor(t)
+	j := &job.Job{ID: "job-stream", Type: job.JobTypeScript, Script: "echo one; sleep 0.2; echo two"}
+
+	if _, _, _, err := executor.SubscribeOutput(j.ID); !job.IsJobNotFoundError(err) {
+		t.Errorf("Expected a not found error before the job starts, got %v", err)
+	}
+
+	done := make(chan *job.JobResult, 1)
+	go func() {
+		result, _ := executor.Execute(context.Background(), j)
+		done <- result
+	}()
+
+	var backlog []byte
+	var chunks <-chan []byte
+	waitFor(t, func() bool {
+		var cancel func()
+		var err error
+		backlog, chunks, cancel, err = executor.SubscribeOutput(j.ID)
+		if err != nil {
+			return false
+		}
+		if len(backlog) == 0 {
+			cancel() // Subscribe again once the first line is in the backlog
+			return false
+		}
+		return true
+	})
+
+	followed := string(backlog)
+	for chunk := range chunks {
+		followed += string(chunk)
+	}
+	if followed != "one\ntwo\n" {
+		t.Errorf("Expected to follow the whole output, got %q", followed)
+	}
+
+	// The final output is still returned in full
+	if result := <-done; result.Output != "one\ntwo\n" {
+		t.Errorf("Expected the result output to be populated, got %q", result.Output)
+	}
+}
+
+func TestOutputStream_BoundsMemory(t *testing.T) {
+	stream := newOutputStream(8)
+
+	_, slow, _ := stream.Subscribe()
+	_, fast, cancel := stream.Subscribe()
+	defer cancel()
+
+	for i := 0; i < streamSubscriberBuffer+1; i++ {
+		stream.Write([]byte("0123456789\n"))
+		<-fast
+	}
+
+	// Only the most recent output is kept for late subscribers
+	if backlog, _, cancel := stream.Subscribe(); string(backlog) != "3456789\n" {
+		t.Errorf("Expected the backlog to keep the last 8 bytes, got %q", backlog)
+	} else {
+		cancel()
+	}
+
+	// A subscriber that stops reading is disconnected rather than buffered for
+	received := 0
+	for range slow {
+		received++
+	}
+	if received != streamSubscriberBuffer {
+		t.Errorf("Expected the slow subscriber to get %d chunks before being dropped, got %d", streamSubscriberBuffer, received)
+	}
+
+	stream.Close()
+	if _, open := <-fast; open {
+		t.Errorf("Expected Close to end subscriptions")
+	}
+}
+
+func TestJobExecutor_HTTPRetries(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := requests.Add(1)
+		switch r.URL.Path {
+		case "/flaky":
+			if n < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			fmt.Fprint(w, "ok")
+		case "/flaky-json":
+			if n < 2 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"status": "ok"}`)
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case "/down":
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusBadGateway)
+		case "/later":
+			w.Header().Set("Retry-After", "60")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	executor, cfg := newTestExecutor(t)
+	cfg.HTTPRetryBackoff = time.Millisecond
+
+	tests := []struct {
+		name         string
+		path         string
+		responsePath string
+		retries      int
+		wantStatus   job.JobStatus
+		wantRequests int32
+		wantOutput   string
+		wantError    string
+	}{
+		{name: "retried until it succeeds", path: "/flaky", retries: 3, wantStatus: job.JobStatusCompleted, wantRequests: 3, wantOutput: "Attempts: 3\n"},
+		{name: "gives up after the job's retries", path: "/flaky", retries: 1, wantStatus: job.JobStatusFailed, wantRequests: 2, wantError: "status 503 after 2 attempts"},
+		{name: "other statuses are not retried", path: "/missing", retries: 3, wantStatus: job.JobStatusFailed, wantRequests: 1, wantError: "status 404"},
+		{name: "extracted output notes the attempts", path: "/flaky-json", responsePath: "status", retries: 1, wantStatus: job.JobStatusCompleted, wantRequests: 2, wantOutput: "Attempts: 2\nok"},
+		{name: "Retry-After is honoured", path: "/down", retries: 2, wantStatus: job.JobStatusFailed, wantRequests: 3, wantOutput: "Attempts: 3\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests.Store(0)
+			j := &job.Job{ID: "job-retry", Type: job.JobTypeHTTP, Method: http.MethodGet, URL: server.URL + tt.path, ResponsePath: tt.responsePath, Retries: tt.retries}
+			result, err := executor.Execute(context.Background(), j)
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if result.Status != tt.wantStatus || requests.Load() != tt.wantRequests {
+				t.Errorf("Expected %s after %d requests, got %s after %d: %s", tt.wantStatus, tt.wantRequests, result.Status, requests.Load(), result.Error)
+			}
+			if !strings.HasPrefix(result.Output, tt.wantOutput) || !strings.Contains(result.Error, tt.wantError) {
+				t.Errorf("Expected output starting %q and error containing %q, got %q and %q", tt.wantOutput, tt.wantError, result.Output, result.Error)
+			}
+		})
+	}
+
+	// A cancel aborts the wait before the next attempt
+	requests.Store(0)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	time.AfterFunc(50*time.Millisecond, cancel)
+	j := &job.Job{ID: "job-later", Type: job.JobTypeHTTP, Method: http.MethodGet, URL: server.URL + "/later", Retries: 5}
+	started := time.Now()
+	result, err := executor.Execute(ctx, j)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if time.Since(started) > 5*time.Second || requests.Load() != 1 || !strings.Contains(result.Error, "cancelled while waiting to retry") {
+		t.Errorf("Expected the cancel to end the wait after one request, got %d requests: %s", requests.Load(), result.Error)
+	}
+
+	if delay := executor.httpRetryDelay(4, nil); delay != 8*time.Millisecond {
+		t.Errorf("Expected the backoff to double per attempt, got %v", delay)
+	}
+	cfg.HTTPRetryBackoffMax = 5 * time.Millisecond
+	if delay := executor.httpRetryDelay(4, nil); delay != 5*time.Millisecond {
+		t.Errorf("Expected the backoff to be capped, got %v", delay)
+	}
+	later := &http.Response{Header: http.Header{"Retry-After": []string{"3600"}}}
+	if delay := executor.httpRetryDelay(1, later); delay != 5*time.Millisecond {
+		t.Errorf("Expected Retry-After to be capped too, got %v", delay)
+	}
+}
+
+func TestSplitCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		want    []string
+		wantErr string
+	}{
+		{name: "plain words", command: "  ls  -la\t/tmp ", want: []string{"ls", "-la", "/tmp"}},
+		{name: "double quotes", command: `echo "hello world"`, want: []string{"echo", "hello world"}},
+		{name: "single quotes keep escapes", command: `echo 'a \"b\" $HOME'`, want: []string{"echo", `a \"b\" $HOME`}},
+		{name: "escaped spaces", command: `cat my\ file.txt`, want: []string{"cat", "my file.txt"}},
+		{name: "escapes inside double quotes", command: `echo "say \"hi\" \n"`, want: []string{"echo", `say "hi" \n`}},
+		{name: "adjacent quoted parts join", command: `echo pre"mid dle"'post'`, want: []string{"echo", "premid dlepost"}},
+		{name: "empty quoted argument", command: `printf '%s|' "" x`, want: []string{"printf", "%s|", "", "x"}},
+		{name: "escaped newline continues", command: "echo a\\\nb", want: []string{"echo", "ab"}},
+		{name: "unterminated double quote", command: `echo "oops`, wantErr: "unterminated double quote"},
+		{name: "unterminated single quote", command: `echo 'oops`, wantErr: "unterminated single quote"},
+		{name: "trailing backslash", command: `echo oops\`, wantErr: "unfinished escape"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := splitCommand(tt.command)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("splitCommand() error = %v", err)
+			}
+			if strings.Join(got, "\x00") != strings.Join(tt.want, "\x00") || len(got) != len(tt.want) {
+				t.Errorf("splitCommand(%q) = %q, want %q", tt.command, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJobExecutor_QuotedCommand(t *testing.T) {
+	executor, _ := newTestExecutor(t)
+
+	j := &job.Job{ID: "job-quoted", Type: job.JobTypeCommand, Command: `printf "%s|" "hello world" it\'s`}
+	result, err := executor.Execute(context.Background(), j)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Status != job.JobStatusCompleted || result.Output != "hello world|it's|" {
+		t.Errorf("Expected quoted arguments to stay whole, got %s: %q %s", result.Status, result.Output, result.Error)
+	}
+
+	j = &job.Job{ID: "job-unterminated", Type: job.JobTypeCommand, Command: `echo "oops`}
+	result, _ = executor.Execute(context.Background(), j)
+	if result.Status != job.JobStatusFailed || !strings.Contains(result.Error, "unterminated double quote") {
+		t.Errorf("Expected an unterminated quote to fail the job, got %s: %s", result.Status, result.Error)
+	}
+}
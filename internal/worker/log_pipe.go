@@ -0,0 +1,70 @@
+package worker
+
+import (
+	"infinitrain/pkg/job"
+	"io"
+	"sync/atomic"
+)
+
+// defaultLogBufferSize is how many output chunks are buffered between a job
+// and its log file when the worker config leaves it unset
+const defaultLogBufferSize = 256
+
+// logPipe hands a job's output to its log writer through a bounded buffer,
+// so a slow disk doesn't stall the job while the buffer has room. When it is
+// full, the overflow policy either blocks the job's output or drops it.
+type logPipe struct {
+	chunks   chan []byte
+	overflow job.LogOverflow
+	dropped  atomic.Int64
+	done     chan struct{}
+}
+
+// newLogPipe starts copying buffered output to the sink until Close
+func newLogPipe(sink io.Writer, size int, overflow job.LogOverflow) *logPipe {
+	p := &logPipe{
+		chunks:   make(chan []byte, size),
+		overflow: overflow,
+		done:     make(chan struct{}),
+	}
+
+	go func() {
+		defer close(p.done)
+		for chunk := range p.chunks {
+			sink.Write(chunk)
+		}
+	}()
+
+	return p
+}
+
+// Write buffers a copy of p, since callers may reuse it. Output is never
+// refused: dropped bytes are counted rather than reported as an error.
+func (p *logPipe) Write(b []byte) (int, error) {
+	chunk := append([]byte(nil), b...)
+
+	if p.overflow == job.LogOverflowDrop {
+		select {
+		case p.chunks <- chunk:
+		default:
+			p.dropped.Add(int64(len(chunk)))
+		}
+		return len(b), nil
+	}
+
+	p.chunks <- chunk
+	return len(b), nil
+}
+
+// Close waits for the buffered output to reach the sink. The pipe must not
+// be written to afterwards.
+func (p *logPipe) Close() error {
+	close(p.chunks)
+	<-p.done
+	return nil
+}
+
+// Dropped returns how many bytes were discarded because the buffer was full
+func (p *logPipe) Dropped() int64 {
+	return p.dropped.Load()
+}
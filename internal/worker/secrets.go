@@ -0,0 +1,180 @@
+package worker
+
+import (
+	"bufio"
+	"fmt"
+	"infinitrain/pkg/job"
+	"io"
+	"os"
+	"strings"
+)
+
+// SecretSource supplies the values of the secrets jobs name. Values are
+// looked up on the worker when a job runs and never pass through the
+// scheduler.
+type SecretSource interface {
+	// Lookup returns a secret's value, reporting false if the source
+	// doesn't have it
+	Lookup(name string) (string, bool, error)
+}
+
+// envFileSecrets reads secrets from a file of KEY=VALUE lines. The file is
+// read on every lookup so rotated secrets are picked up without a restart.
+type envFileSecrets struct {
+	path string
+}
+
+// Lookup returns the value of the named secret from the file
+func (s *envFileSecrets) Lookup(name string) (string, bool, error) {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to open secrets file: %v", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, found := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		if !found || strings.TrimSpace(key) != name {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		return value, true, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return "", false, fmt.Errorf("failed to read secrets file: %v", err)
+	}
+	return "", false, nil
+}
+
+// jobEnvironment returns the job's environment with its secrets resolved
+// into it. Jobs without secrets get their own environment back.
+func (e *JobExecutor) jobEnvironment(j *job.Job) (map[string]string, error) {
+	if len(j.Secrets) == 0 {
+		return j.Environment, nil
+	}
+	if e.secrets == nil {
+		return nil, job.NewExecutionError(j.ID, "job needs secrets but this worker has no secret source", nil)
+	}
+
+	env := make(map[string]string, len(j.Environment)+len(j.Secrets))
+	for key, value := range j.Environment {
+		env[key] = value
+	}
+	for _, name := range j.Secrets {
+		value, found, err := e.secrets.Lookup(name)
+		if err != nil {
+			return nil, job.NewExecutionError(j.ID, "failed to look up secret "+name, err)
+		}
+		if !found {
+			return nil, job.NewExecutionError(j.ID, "secret "+name+" is not available on this worker", nil)
+		}
+		env[name] = value
+	}
+	return env, nil
+}
+
+// secretMask replaces secret values in a job's output
+const secretMask = "***"
+
+// jobSecretValues returns the values of the secrets a job names so they can
+// be masked in its output. Secrets that can't be looked up are left out; the
+// job fails on them when its environment is resolved.
+func (e *JobExecutor) jobSecretValues(j *job.Job) []string {
+	if e.secrets == nil {
+		return nil
+	}
+	var values []string
+	for _, name := range j.Secrets {
+		if value, found, err := e.secrets.Lookup(name); err == nil && found && value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// secretMasker replaces secret values in output with secretMask. It holds
+// back the tail of what it has been given that could be the start of a
+// secret split across writes, until the next write or Flush. Writes must be
+// serialized by the caller.
+type secretMasker struct {
+	w        io.Writer
+	replacer *strings.Replacer
+	holdback int // Bytes held back, one less than the longest secret
+	pending  []byte
+}
+
+// newSecretMasker masks values in what is written through to w, returning w
+// itself when there is nothing to mask
+func newSecretMasker(w io.Writer, values []string) io.Writer {
+	if len(values) == 0 {
+		return w
+	}
+	m := &secretMasker{w: w, replacer: secretReplacer(values)}
+	for _, value := range values {
+		if len(value)-1 > m.holdback {
+			m.holdback = len(value) - 1
+		}
+	}
+	return m
+}
+
+func (m *secretMasker) Write(p []byte) (int, error) {
+	masked := m.replacer.Replace(string(m.pending) + string(p))
+	cut := len(masked) - m.holdback
+	if cut <= 0 {
+		m.pending = []byte(masked)
+		return len(p), nil
+	}
+	m.pending = []byte(masked[cut:])
+	if _, err := io.WriteString(m.w, masked[:cut]); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush writes out what has been held back
+func (m *secretMasker) Flush() error {
+	if len(m.pending) == 0 {
+		return nil
+	}
+	_, err := m.w.Write(m.pending)
+	m.pending = nil
+	return err
+}
+
+// secretReplacer replaces each of the values with secretMask
+func secretReplacer(values []string) *strings.Replacer {
+	pairs := make([]string, 0, 2*len(values))
+	for _, value := range values {
+		pairs = append(pairs, value, secretMask)
+	}
+	return strings.NewReplacer(pairs...)
+}
+
+// maskResult replaces secret values in everything the result carries back to
+// the scheduler
+func maskResult(result *job.JobResult, values []string) {
+	if len(values) == 0 {
+		return
+	}
+	replacer := secretReplacer(values)
+	result.Output = replacer.Replace(result.Output)
+	result.Stdout = replacer.Replace(result.Stdout)
+	result.Stderr = replacer.Replace(result.Stderr)
+	result.Error = replacer.Replace(result.Error)
+	for i := range result.Steps {
+		result.Steps[i].Command = replacer.Replace(result.Steps[i].Command)
+		result.Steps[i].Output = replacer.Replace(result.Steps[i].Output)
+	}
+	if result.Response != nil {
+		result.Response.Body = replacer.Replace(result.Response.Body)
+	}
+}
@@ -0,0 +1,230 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"infinitrain/pkg/job"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// maxRequestBytes caps request bodies; the worker API only takes small commands
+const maxRequestBytes = 64 << 10
+
+// Server exposes a worker's local API so the scheduler can inspect it and cancel jobs
+type Server struct {
+	worker     *Worker
+	httpServer *http.Server
+	listener   net.Listener
+}
+
+// NewServer creates a new worker API server
+func NewServer(w *Worker) *Server {
+	return &Server{
+		worker: w,
+	}
+}
+
+// SetupRoutes configures the HTTP routes
+func (s *Server) SetupRoutes() *mux.Router {
+	r := mux.NewRouter()
+
+	r.HandleFunc("/info", s.handleInfo).Methods("GET")
+	r.HandleFunc("/jobs", s.handleListJobs).Methods("GET")
+	r.HandleFunc("/jobs/{jobID}/logfile", s.handleJobLogFile).Methods("GET")
+	r.HandleFunc("/cancel/{jobID}", s.handleCancelJob).Methods("POST")
+	r.HandleFunc("/signal/{jobID}", s.handleSignalJob).Methods("POST")
+	r.HandleFunc("/jobs/{jobID}/timeout", s.handleExtendTimeout).Methods("PATCH")
+	r.HandleFunc("/notify", s.handleNotify).Methods("POST")
+	r.HandleFunc("/drain", s.handleDrain).Methods("POST")
+
+	return r
+}
+
+// Start begins serving on the given address in the background
+func (s *Server) Start(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %v", address, err)
+	}
+
+	s.listener = listener
+	s.httpServer = &http.Server{Handler: s.SetupRoutes()}
+
+	go func() {
+		if err := s.httpServer.Serve(listener); err != nil && err != http.ErrServerClosed {
+			s.worker.logger.Error("worker API server failed", "error", err)
+		}
+	}()
+
+	return nil
+}
+
+// Addr returns the address the server is listening on
+func (s *Server) Addr() string {
+	if s.listener == nil {
+		return ""
+	}
+	return s.listener.Addr().String()
+}
+
+// Shutdown gracefully stops the server
+func (s *Server) Shutdown(ctx context.Context) error {
+	if s.httpServer == nil {
+		return nil
+	}
+	return s.httpServer.Shutdown(ctx)
+}
+
+func (s *Server) handleInfo(w http.ResponseWriter, r *http.Request) {
+	s.writeJSON(w, http.StatusOK, s.worker.GetInfo())
+}
+
+func (s *Server) handleListJobs(w http.ResponseWriter, r *http.Request) {
+	jobs := s.worker.GetCurrentJobs()
+
+	response := map[string]interface{}{
+		"jobs":  jobs,
+		"count": len(jobs),
+	}
+
+	s.writeJSON(w, http.StatusOK, response)
+}
+
+// handleJobLogFile streams a job's log file from this host
+func (s *Server) handleJobLogFile(w http.ResponseWriter, r *http.Request) {
+	jobID := mux.Vars(r)["jobID"]
+
+	path, err := s.worker.JobLogFile(jobID)
+	if err != nil {
+		switch {
+		case job.IsJobNotFoundError(err):
+			s.writeError(w, http.StatusNotFound, "no log file for job "+jobID)
+		case job.IsValidationError(err):
+			s.writeError(w, http.StatusBadRequest, err.Error())
+		default:
+			s.writeError(w, http.StatusInternalServerError, "failed to find log file: "+err.Error())
+		}
+		return
+	}
+
+	if err := streamFile(w, path); err != nil {
+		s.writeError(w, http.StatusInternalServerError, "failed to read log file: "+err.Error())
+	}
+}
+
+func (s *Server) handleCancelJob(w http.ResponseWriter, r *http.Request) {
+	jobID := mux.Vars(r)["jobID"]
+
+	if err := s.worker.CancelJob(jobID); err != nil {
+		if job.IsJobNotFoundError(err) {
+			s.writeError(w, http.StatusNotFound, err.Error())
+		} else {
+			s.writeError(w, http.StatusInternalServerError, "failed to cancel job: "+err.Error())
+		}
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]string{"message": "job cancelled"})
+}
+
+func (s *Server) handleSignalJob(w http.ResponseWriter, r *http.Request) {
+	jobID := mux.Vars(r)["jobID"]
+
+	var request struct {
+		Signal string `json:"signal"`
+	}
+	if err := decodeJSON(w, r, &request); err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+		return
+	}
+
+	sig, err := job.ParseSignal(request.Signal)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := s.worker.SignalJob(jobID, sig); err != nil {
+		switch {
+		case job.IsJobNotFoundError(err):
+			s.writeError(w, http.StatusNotFound, err.Error())
+		case job.IsValidationError(err):
+			s.writeError(w, http.StatusBadRequest, err.Error())
+		default:
+			s.writeError(w, http.StatusInternalServerError, "failed to signal job: "+err.Error())
+		}
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]string{"message": "signal sent"})
+}
+
+func (s *Server) handleExtendTimeout(w http.ResponseWriter, r *http.Request) {
+	jobID := mux.Vars(r)["jobID"]
+
+	var request struct {
+		ExtendBy string `json:"extend_by"`
+	}
+	if err := decodeJSON(w, r, &request); err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+		return
+	}
+
+	extra, err := time.ParseDuration(request.ExtendBy)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid extend_by duration: "+request.ExtendBy)
+		return
+	}
+
+	deadline, err := s.worker.ExtendJobTimeout(jobID, extra)
+	if err != nil {
+		switch {
+		case job.IsJobNotFoundError(err):
+			s.writeError(w, http.StatusNotFound, err.Error())
+		case job.IsValidationError(err):
+			s.writeError(w, http.StatusBadRequest, err.Error())
+		case job.IsConflictError(err):
+			s.writeError(w, http.StatusConflict, err.Error())
+		default:
+			s.writeError(w, http.StatusInternalServerError, "failed to extend timeout: "+err.Error())
+		}
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{"deadline": deadline.UTC()})
+}
+
+// handleNotify lets a remote scheduler push a wake-up so the worker claims
+// newly queued jobs without waiting for its poll interval
+func (s *Server) handleNotify(w http.ResponseWriter, r *http.Request) {
+	s.worker.Notify()
+	s.writeJSON(w, http.StatusAccepted, map[string]string{"message": "poll scheduled"})
+}
+
+func (s *Server) handleDrain(w http.ResponseWriter, r *http.Request) {
+	s.worker.Drain(r.Context())
+	s.writeJSON(w, http.StatusOK, s.worker.GetInfo())
+}
+
+func (s *Server) writeJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (s *Server) writeError(w http.ResponseWriter, status int, message string) {
+	s.writeJSON(w, status, map[string]string{"error": message})
+}
+
+// decodeJSON reads a size-limited request body into v, rejecting fields v
+// doesn't have
+func decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
@@ -0,0 +1,140 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"infinitrain/pkg/job"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestServer_InfoAndCancel(t *testing.T) {
+	w, fake := newTestWorker(t)
+	w.config.ListenAddress = "127.0.0.1:0"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := w.Start(ctx); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	baseURL := "http://" + w.server.Addr()
+
+	// GET /info reports the worker's identity and capacity
+	resp, err := http.Get(baseURL + "/info")
+	if err != nil {
+		t.Fatalf("GET /info error = %v", err)
+	}
+	var info map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&info)
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if info["id"] != w.ID() {
+		t.Errorf("Expected id %s, got %v", w.ID(), info["id"])
+	}
+	if info["capacity"] != float64(w.GetCapacity()) {
+		t.Errorf("Expected capacity %d, got %v", w.GetCapacity(), info["capacity"])
+	}
+
+	// POST /notify wakes the polling loop without advancing the clock
+	resp, err = http.Post(baseURL+"/notify", "application/json", nil)
+	if err != nil {
+		t.Fatalf("POST /notify error = %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("Expected status 202, got %d", resp.StatusCode)
+	}
+	waitFor(t, func() bool { return !w.GetLastPoll().IsZero() })
+
+	// POST /cancel/{jobID} kills a running job
+	done := startJob(t, w, "sleep 30")
+	jobID := w.GetCurrentJobs()[0].ID
+
+	resp, err = http.Post(baseURL+"/cancel/"+jobID, "application/json", nil)
+	if err != nil {
+		t.Fatalf("POST /cancel error = %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	select {
+	case result := <-done:
+		if result.Status != job.JobStatusFailed {
+			t.Errorf("Expected cancelled job to fail, got %v", result.Status)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected cancelled job to stop")
+	}
+
+	// Cancelling a job the worker isn't running is a 404
+	resp, err = http.Post(baseURL+"/cancel/"+jobID, "application/json", nil)
+	if err != nil {
+		t.Fatalf("POST /cancel error = %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", resp.StatusCode)
+	}
+
+	// Stop shuts the server down along with the worker
+	stopped := make(chan error, 1)
+	go func() { stopped <- w.Stop(context.Background()) }()
+	fake.WaitForWaiters(4)
+	fake.Advance(w.config.ShutdownPollInterval)
+	if err := <-stopped; err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+
+	if _, err := http.Get(baseURL + "/info"); err == nil {
+		t.Error("Expected the worker API to be unreachable after Stop")
+	}
+}
+
+func TestServer_JobLogFile(t *testing.T) {
+	w, _ := newTestWorker(t)
+	server := httptest.NewServer(NewServer(w).SetupRoutes())
+	defer server.Close()
+
+	j := &job.Job{ID: "job-log", Type: job.JobTypeCommand, Command: "echo served from disk", Status: job.JobStatusQueued}
+	if _, err := w.ExecuteJob(context.Background(), j); err != nil {
+		t.Fatalf("ExecuteJob() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		jobID    string
+		wantCode int
+		wantBody string
+	}{
+		{name: "existing log", jobID: "job-log", wantCode: http.StatusOK, wantBody: "served from disk\n"},
+		{name: "unknown job", jobID: "job-missing", wantCode: http.StatusNotFound},
+		{name: "dotted job ID", jobID: "job..secret", wantCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Get(server.URL + "/jobs/" + tt.jobID + "/logfile")
+			if err != nil {
+				t.Fatalf("GET logfile error = %v", err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.wantCode {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantCode, resp.StatusCode, body)
+			}
+			if tt.wantBody != "" && string(body) != tt.wantBody {
+				t.Errorf("Expected body %q, got %q", tt.wantBody, body)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package worker
+
+import "sync"
+
+// defaultStreamBacklog is how many of a running job's most recent output
+// bytes are kept for subscribers that join late, when the worker config
+// leaves it unset
+const defaultStreamBacklog = 64 * 1024
+
+// streamSubscriberBuffer is how many chunks a subscriber may fall behind by
+// before it is disconnected
+const streamSubscriberBuffer = 64
+
+// outputStream fans a running job's output out to live subscribers as it is
+// written. Memory stays bounded however much a job prints: only the most
+// recent output is kept for late subscribers, and a subscriber that can't
+// keep up is disconnected rather than buffered for.
+type outputStream struct {
+	mutex       sync.Mutex
+	backlog     []byte
+	limit       int
+	subscribers map[chan []byte]struct{}
+	closed      bool
+}
+
+func newOutputStream(limit int) *outputStream {
+	return &outputStream{
+		limit:       limit,
+		subscribers: make(map[chan []byte]struct{}),
+	}
+}
+
+// Write sends a copy of p to every subscriber and adds it to the backlog.
+// It never blocks on subscribers.
+func (s *outputStream) Write(p []byte) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.backlog = append(s.backlog, p...)
+	if excess := len(s.backlog) - s.limit; excess > 0 {
+		s.backlog = append(s.backlog[:0], s.backlog[excess:]...)
+	}
+
+	for subscriber := range s.subscribers {
+		select {
+		case subscriber <- append([]byte(nil), p...):
+		default:
+			delete(s.subscribers, subscriber)
+			close(subscriber)
+		}
+	}
+	return len(p), nil
+}
+
+// Subscribe returns the recent output and a channel receiving everything
+// written after it. The channel is closed when the job finishes, or early
+// if the subscriber falls too far behind. Call cancel to stop receiving.
+func (s *outputStream) Subscribe() (backlog []byte, chunks <-chan []byte, cancel func()) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	subscriber := make(chan []byte, streamSubscriberBuffer)
+	if s.closed {
+		close(subscriber)
+	} else {
+		s.subscribers[subscriber] = struct{}{}
+	}
+
+	cancel = func() {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		if _, exists := s.subscribers[subscriber]; exists {
+			delete(s.subscribers, subscriber)
+			close(subscriber)
+		}
+	}
+
+	return append([]byte(nil), s.backlog...), subscriber, cancel
+}
+
+// Close ends every subscription once the job has finished
+func (s *outputStream) Close() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.closed = true
+	for subscriber := range s.subscribers {
+		close(subscriber)
+	}
+	s.subscribers = nil
+}
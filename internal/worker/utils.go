@@ -1,8 +1,20 @@
 package worker
 
 import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unicode/utf8"
 )
 
 // ensureDirectory creates a directory if it doesn't exist
@@ -15,10 +27,316 @@ func ensureDirectory(dir string) error {
 	dir = filepath.Clean(dir)
 
 	// Check if directory exists
-	if _, err := os.Stat(dir); err == nil {
+	if info, err := os.Stat(dir); err == nil {
+		if !info.IsDir() {
+			return fmt.Errorf("%s exists and is not a directory", dir)
+		}
 		return nil // Directory already exists
 	}
 
 	// Create directory with proper permissions
 	return os.MkdirAll(dir, 0755)
 }
+
+// resolveSymlinks returns the absolute path with every symlink resolved. A
+// path that doesn't exist yet, such as a file about to be written, resolves
+// its nearest existing parent and keeps the rest as given. A dangling symlink
+// is an error: writing through it would create its target, wherever that is.
+func resolveSymlinks(path string) (string, error) {
+	path = filepath.Clean(path)
+	resolved, err := filepath.EvalSymlinks(path)
+	if err == nil {
+		return resolved, nil
+	}
+	if !os.IsNotExist(err) {
+		return "", err
+	}
+	if info, lstatErr := os.Lstat(path); lstatErr == nil && info.Mode()&os.ModeSymlink != 0 {
+		return "", fmt.Errorf("%s is a symlink to a missing target", path)
+	}
+
+	parent := filepath.Dir(path)
+	if parent == path {
+		return "", err
+	}
+	resolvedParent, err := resolveSymlinks(parent)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(resolvedParent, filepath.Base(path)), nil
+}
+
+// isWithinRoot reports whether path is root itself or nested beneath it
+func isWithinRoot(root, path string) bool {
+	rel, err := filepath.Rel(filepath.Clean(root), filepath.Clean(path))
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// splitCommand splits a command into arguments the way a POSIX shell would,
+// without expanding anything. Whitespace separates arguments unless quoted or
+// escaped; single quotes keep everything literally, and inside double quotes
+// a backslash only escapes $, `, ", \ and newline.
+func splitCommand(command string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inArg := false // Whether an argument has started, so "" gives an empty one
+
+	for i := 0; i < len(command); i++ {
+		c := command[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		case c == '\\':
+			if i+1 == len(command) {
+				return nil, fmt.Errorf("command ends with an unfinished escape")
+			}
+			i++
+			if command[i] != '\n' { // An escaped newline continues the line
+				current.WriteByte(command[i])
+				inArg = true
+			}
+		case c == '\'':
+			end := strings.IndexByte(command[i+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("command has an unterminated single quote")
+			}
+			current.WriteString(command[i+1 : i+1+end])
+			i += end + 1
+			inArg = true
+		case c == '"':
+			closed := false
+			for i++; i < len(command); i++ {
+				if command[i] == '"' {
+					closed = true
+					break
+				}
+				if command[i] == '\\' && i+1 < len(command) && strings.IndexByte("$`\"\\\n", command[i+1]) >= 0 {
+					i++
+					if command[i] == '\n' {
+						continue
+					}
+				}
+				current.WriteByte(command[i])
+			}
+			if !closed {
+				return nil, fmt.Errorf("command has an unterminated double quote")
+			}
+			inArg = true
+		default:
+			current.WriteByte(c)
+			inArg = true
+		}
+	}
+
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+// commandExists reports whether name resolves to an executable, looking up
+// relative paths against dir the same way the command will be run
+func commandExists(name, dir string) bool {
+	if strings.Contains(name, string(filepath.Separator)) && !filepath.IsAbs(name) {
+		name = filepath.Join(dir, name)
+	}
+
+	_, err := exec.LookPath(name)
+	return err == nil
+}
+
+// processExitCode maps the error from running a process to an exit code: 124
+// if the job's deadline killed it, 128+signal if a signal killed it, and the
+// process's own code otherwise
+func processExitCode(ctx context.Context, err error) int {
+	if err == nil {
+		return 0
+	}
+
+	if context.Cause(ctx) == context.DeadlineExceeded {
+		return exitCodeTimeout
+	}
+
+	exitError, ok := err.(*exec.ExitError)
+	if !ok {
+		return 1
+	}
+	if status, ok := exitError.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return exitCodeSignalBase + int(status.Signal())
+	}
+	return exitError.ExitCode()
+}
+
+// isText reports whether content looks like text: valid UTF-8 with no null bytes
+func isText(content []byte) bool {
+	return utf8.Valid(content) && bytes.IndexByte(content, 0) == -1
+}
+
+// expandVariables replaces $VAR and ${VAR} in s with values from env.
+// Undefined variables expand to an empty string, or are an error when strict.
+func expandVariables(s string, env map[string]string, strict bool) (string, error) {
+	var missing []string
+	expanded := os.Expand(s, func(name string) string {
+		value, exists := env[name]
+		if !exists {
+			missing = append(missing, name)
+		}
+		return value
+	})
+
+	if strict && len(missing) > 0 {
+		return "", fmt.Errorf("undefined variable: %s", strings.Join(missing, ", "))
+	}
+	return expanded, nil
+}
+
+// bestEffortWriter ignores write errors so a failing copy, such as a job log
+// on a full disk, can't break the io.MultiWriter capturing job output
+type bestEffortWriter struct {
+	io.WriteCloser
+}
+
+func (w bestEffortWriter) Write(p []byte) (int, error) {
+	w.WriteCloser.Write(p)
+	return len(p), nil
+}
+
+// truncationMarker ends output that was cut off at the worker's limit
+const truncationMarker = "...[truncated]"
+
+// limitedBuffer keeps the first limit bytes written to it and drops the
+// rest, so a chatty job can't exhaust the worker's memory. A limit of zero
+// keeps everything.
+type limitedBuffer struct {
+	bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	if b.limit > 0 && b.Len()+len(p) > b.limit {
+		p = p[:max(b.limit-b.Len(), 0)]
+		b.truncated = true
+	}
+	b.Buffer.Write(p)
+	return n, nil
+}
+
+// String returns the kept output, marked when some of it was dropped
+func (b *limitedBuffer) String() string {
+	if b.truncated {
+		return b.Buffer.String() + truncationMarker
+	}
+	return b.Buffer.String()
+}
+
+// outputCounter counts the bytes and lines passing through it. Stdout and
+// stderr are copied concurrently, so writes are serialized.
+type outputCounter struct {
+	io.Writer
+	mutex sync.Mutex
+	bytes int64
+	lines int64
+	last  byte
+}
+
+func (c *outputCounter) Write(p []byte) (int, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.bytes += int64(len(p))
+	c.lines += int64(bytes.Count(p, []byte{'\n'}))
+	if len(p) > 0 {
+		c.last = p[len(p)-1]
+	}
+	return c.Writer.Write(p)
+}
+
+// Bytes returns how many bytes have been written
+func (c *outputCounter) Bytes() int64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.bytes
+}
+
+// Lines returns how many lines have been written, counting a final line
+// without a trailing newline
+func (c *outputCounter) Lines() int64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.bytes > 0 && c.last != '\n' {
+		return c.lines + 1
+	}
+	return c.lines
+}
+
+// nopWriteCloser adds a no-op Close to a writer
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// removeFilesOlderThan deletes files in dir with the given extension that were
+// last modified before cutoff and returns how many were removed. A missing
+// directory has nothing to remove.
+func removeFilesOlderThan(dir, ext string, cutoff time.Time) (int, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	removed := 0
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ext {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil || !info.ModTime().Before(cutoff) {
+			continue
+		}
+
+		if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil && !os.IsNotExist(err) {
+			return removed, err
+		}
+		removed++
+	}
+
+	return removed, nil
+}
+
+// streamFile writes a text file to an HTTP response
+func streamFile(w http.ResponseWriter, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// isJSONContentType reports whether a Content-Type header names JSON,
+// including structured suffixes such as application/problem+json
+func isJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
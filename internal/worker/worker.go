@@ -3,10 +3,28 @@ package worker
 import (
 	"context"
 	"fmt"
+	"infinitrain/internal/clock"
 	"infinitrain/internal/config"
+	"infinitrain/internal/tracing"
 	"infinitrain/pkg/job"
+	"log/slog"
+	"math/rand"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
+	"syscall"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	defaultShutdownTimeout  = 30 * time.Second
+	minShutdownPollInterval = 10 * time.Millisecond
+	logCleanupInterval      = 1 * time.Hour
 )
 
 // Worker represents a worker node that can execute jobs
@@ -14,12 +32,22 @@ type Worker struct {
 	id             string
 	config         *config.WorkerConfig
 	executor       job.Executor
+	server         *Server
+	scheduler      SchedulerClient
+	clock          clock.Clock
+	random         func() float64
+	logger         *slog.Logger
 	currentJobs    map[string]*job.Job
+	jobCancels     map[string]context.CancelFunc
 	currentJobsMux sync.RWMutex
 	isRunning      bool
 	isHealthy      bool
+	draining       bool
 	lastHeartbeat  time.Time
+	lastPoll       time.Time
+	pollInterval   time.Duration
 	heartbeatMux   sync.RWMutex
+	wake           chan struct{}
 }
 
 // NewWorker creates a new worker instance
@@ -28,9 +56,24 @@ func NewWorker(cfg *config.WorkerConfig, executor job.Executor) *Worker {
 		id:            cfg.ID,
 		config:        cfg,
 		executor:      executor,
+		scheduler:     newHTTPSchedulerClient(cfg.SchedulerURL, cfg.SchedulerToken),
+		clock:         clock.Real(),
+		random:        rand.Float64,
+		logger:        slog.Default().With("worker_id", cfg.ID),
 		currentJobs:   make(map[string]*job.Job),
+		jobCancels:    make(map[string]context.CancelFunc),
 		isHealthy:     true,
-		lastHeartbeat: time.Now(),
+		lastHeartbeat: time.Now().UTC(),
+		wake:          make(chan struct{}, 1),
+	}
+}
+
+// SetLogger sets the logger the worker, and its executor if it logs, write
+// to. Every line carries the worker's ID.
+func (w *Worker) SetLogger(logger *slog.Logger) {
+	w.logger = logger.With("worker_id", w.id)
+	if logging, ok := w.executor.(interface{ SetLogger(*slog.Logger) }); ok {
+		logging.SetLogger(w.logger)
 	}
 }
 
@@ -48,7 +91,18 @@ func (w *Worker) Start(ctx context.Context) error {
 		return fmt.Errorf("failed to create working directory: %v", err)
 	}
 
-	fmt.Printf("Worker %s started\n", w.id)
+	// Expose the worker's own API for inspection and cancellation
+	if w.config.ListenAddress != "" {
+		w.server = NewServer(w)
+		if err := w.server.Start(w.config.ListenAddress); err != nil {
+			return err
+		}
+	}
+
+	w.logger.Info("worker started")
+
+	// Join the scheduler before the first heartbeat
+	w.register(ctx)
 
 	// Start heartbeat routine
 	go w.heartbeatLoop(ctx)
@@ -56,30 +110,53 @@ func (w *Worker) Start(ctx context.Context) error {
 	// Start job polling routine
 	go w.jobPollingLoop(ctx)
 
+	// Start job log cleanup routine
+	if w.config.LogRetention > 0 {
+		go w.logCleanupLoop(ctx)
+	}
+
 	return nil
 }
 
-// Stop stops the worker gracefully
+// Stop stops the worker gracefully. Jobs still running once the configured
+// shutdown timeout elapses are cancelled and handed back to the scheduler to
+// be queued again.
 func (w *Worker) Stop(ctx context.Context) error {
 	w.isRunning = false
 
+	// Keep the API up while draining so jobs can still be cancelled remotely
+	defer w.stopServer(ctx)
+
+	shutdownTimeout := w.config.ShutdownTimeout
+	if shutdownTimeout <= 0 {
+		shutdownTimeout = defaultShutdownTimeout
+	}
+	// Unless configured, check for finished jobs a thirtieth of the way
+	// through the timeout, so short timeouts aren't overshot
+	pollInterval := w.config.ShutdownPollInterval
+	if pollInterval <= 0 {
+		pollInterval = max(shutdownTimeout/30, minShutdownPollInterval)
+	}
+
 	// Wait for current jobs to complete or timeout
-	timeout := time.After(30 * time.Second)
-	ticker := time.NewTicker(1 * time.Second)
+	timeout := w.clock.After(shutdownTimeout)
+	ticker := w.clock.NewTicker(pollInterval)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-timeout:
-			fmt.Printf("Worker %s stopped with timeout, cancelling remaining jobs\n", w.id)
+			cancelled := w.cancelAllJobs()
+			w.releaseJobs(ctx)
+			w.logger.Warn("worker stopped at shutdown timeout", "cancelled_jobs", cancelled)
 			return nil
-		case <-ticker.C:
+		case <-ticker.C():
 			if w.GetCurrentLoad() == 0 {
-				fmt.Printf("Worker %s stopped gracefully\n", w.id)
+				w.logger.Info("worker stopped gracefully")
 				return nil
 			}
 		case <-ctx.Done():
-			fmt.Printf("Worker %s stopped due to context cancellation\n", w.id)
+			w.logger.Warn("worker stop interrupted", "error", ctx.Err())
 			return ctx.Err()
 		}
 	}
@@ -104,48 +181,190 @@ func (w *Worker) GetCurrentLoad() int {
 	return len(w.currentJobs)
 }
 
-// CanAcceptJob returns true if the worker can accept a new job
+// GetWeightedLoad returns the summed cost of the jobs being executed, which
+// is what the capacity is measured against
+func (w *Worker) GetWeightedLoad() int {
+	w.currentJobsMux.RLock()
+	defer w.currentJobsMux.RUnlock()
+	return w.weightedLoad()
+}
+
+// weightedLoad sums the cost of running jobs; callers must hold currentJobsMux
+func (w *Worker) weightedLoad() int {
+	load := 0
+	for _, j := range w.currentJobs {
+		load += j.Weight()
+	}
+	return load
+}
+
+// CanAcceptJob returns true if the worker can accept a new job of the default cost
 func (w *Worker) CanAcceptJob() bool {
-	return w.IsHealthy() && w.GetCurrentLoad() < w.GetCapacity()
+	return w.CanAcceptCost(1)
 }
 
-// ExecuteJob executes a job
+// CanAcceptCost returns true if the worker has room for a job of the given cost
+func (w *Worker) CanAcceptCost(cost int) bool {
+	return w.IsHealthy() && !w.IsDraining() && w.GetWeightedLoad()+cost <= w.GetCapacity()
+}
+
+// Drain stops the worker from taking new jobs while the ones it is running
+// finish. The worker stays healthy, so its running jobs aren't reclaimed.
+func (w *Worker) Drain(ctx context.Context) error {
+	w.heartbeatMux.Lock()
+	alreadyDraining := w.draining
+	w.draining = true
+	w.heartbeatMux.Unlock()
+
+	if !alreadyDraining {
+		w.logger.Info("worker draining", "running_jobs", w.GetCurrentLoad())
+	}
+	return nil
+}
+
+// IsDraining returns true once the worker has been asked to drain
+func (w *Worker) IsDraining() bool {
+	w.heartbeatMux.RLock()
+	defer w.heartbeatMux.RUnlock()
+	return w.draining
+}
+
+// Labels returns the labels describing this worker
+func (w *Worker) Labels() map[string]string {
+	return w.config.Labels
+}
+
+// ExecuteJob executes a job, continuing the trace it was submitted in
 func (w *Worker) ExecuteJob(ctx context.Context, j *job.Job) (*job.JobResult, error) {
-	if !w.CanAcceptJob() {
-		return nil, fmt.Errorf("worker %s cannot accept job: at capacity or unhealthy", w.id)
+	ctx, span := tracing.Start(ctx, j, "job.run", trace.WithAttributes(attribute.String("worker.id", w.id)))
+	result, err := w.executeJob(ctx, j)
+	tracing.End(span, err)
+	return result, err
+}
+
+// executeJob reserves room for a job, runs it and reports the result
+func (w *Worker) executeJob(ctx context.Context, j *job.Job) (*job.JobResult, error) {
+	if !w.IsHealthy() {
+		return nil, fmt.Errorf("worker %s cannot accept job: unhealthy", w.id)
+	}
+	if w.IsDraining() {
+		return nil, fmt.Errorf("worker %s cannot accept job: draining", w.id)
 	}
 
-	// Add job to current jobs
+	// Give the job its own context so it can be cancelled independently
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Reserve capacity and add the job to current jobs in one step so
+	// concurrent submissions can't overcommit the worker
 	w.currentJobsMux.Lock()
+	if w.weightedLoad()+j.Weight() > w.GetCapacity() {
+		w.currentJobsMux.Unlock()
+		return nil, fmt.Errorf("worker %s cannot accept job: at capacity", w.id)
+	}
 	w.currentJobs[j.ID] = j
+	w.jobCancels[j.ID] = cancel
 	w.currentJobsMux.Unlock()
 
 	// Remove job from current jobs when done
 	defer func() {
 		w.currentJobsMux.Lock()
 		delete(w.currentJobs, j.ID)
+		delete(w.jobCancels, j.ID)
 		w.currentJobsMux.Unlock()
 	}()
 
-	// Update job status to running
+	// Update job status to running unless the scheduler already did on assignment
 	j.WorkerID = w.id
-	if err := j.UpdateStatus(job.JobStatusRunning); err != nil {
-		return nil, fmt.Errorf("failed to update job status: %v", err)
+	if !j.IsRunning() {
+		if err := j.UpdateStatus(job.JobStatusRunning); err != nil {
+			return nil, fmt.Errorf("failed to update job status: %v", err)
+		}
 	}
 
-	fmt.Printf("Worker %s executing job %s (%s)\n", w.id, j.ID, j.Type)
+	w.logger.Info("executing job", "job_id", j.ID, "type", j.Type)
 
 	// Execute the job
 	result, err := w.executor.Execute(ctx, j)
+	j.RecordStepProgress(result)
+	w.reportResult(ctx, result)
 	if err != nil {
-		fmt.Printf("Worker %s failed to execute job %s: %v\n", w.id, j.ID, err)
+		w.logger.Error("job execution failed", "job_id", j.ID, "error", err)
 		return result, err
 	}
 
-	fmt.Printf("Worker %s completed job %s with status %s\n", w.id, j.ID, result.Status)
+	w.logger.Info("job finished", "job_id", j.ID, "status", result.Status, "duration", result.Duration)
 	return result, nil
 }
 
+// reportResult hands a finished job's result to the scheduler so it can be
+// stored, if the scheduler client supports it. The report is sent even when
+// the job was cancelled, so it must not share the job's context.
+func (w *Worker) reportResult(ctx context.Context, result *job.JobResult) {
+	reporter, ok := w.scheduler.(interface {
+		SaveResult(ctx context.Context, result *job.JobResult) error
+	})
+	if !ok || result == nil {
+		return
+	}
+
+	if err := reporter.SaveResult(context.WithoutCancel(ctx), result); err != nil {
+		w.logger.Error("failed to report job result", "job_id", result.JobID, "error", err)
+	}
+}
+
+// register announces this worker to the scheduler, if the scheduler client
+// supports it. Failing to register is logged rather than fatal: the
+// scheduler may already know the worker from before a restart.
+func (w *Worker) register(ctx context.Context) {
+	registrar, ok := w.scheduler.(interface {
+		Register(ctx context.Context, registration *job.WorkerRegistration) error
+	})
+	if !ok {
+		return
+	}
+
+	registration := &job.WorkerRegistration{ID: w.id, Capacity: w.GetCapacity(), Labels: w.Labels(), Address: w.advertisedAddress()}
+	if err := registrar.Register(ctx, registration); err != nil {
+		w.logger.Warn("failed to register with the scheduler", "error", err)
+	}
+}
+
+// advertisedAddress returns the base URL the scheduler can reach the
+// worker's API on, empty if the API is disabled. A wildcard listen address
+// is advertised under the host's name.
+func (w *Worker) advertisedAddress() string {
+	if w.server == nil || w.server.Addr() == "" {
+		return ""
+	}
+
+	host, port, err := net.SplitHostPort(w.server.Addr())
+	if err != nil {
+		return ""
+	}
+	if ip := net.ParseIP(host); ip == nil || ip.IsUnspecified() {
+		if hostname, err := os.Hostname(); err == nil {
+			host = hostname
+		}
+	}
+	return "http://" + net.JoinHostPort(host, port)
+}
+
+// releaseJobs asks the scheduler to queue this worker's unfinished jobs
+// again, if the scheduler client supports it
+func (w *Worker) releaseJobs(ctx context.Context) {
+	releaser, ok := w.scheduler.(interface {
+		ReleaseJobs(ctx context.Context, workerID string) error
+	})
+	if !ok {
+		return
+	}
+
+	if err := releaser.ReleaseJobs(ctx, w.id); err != nil {
+		w.logger.Error("failed to hand jobs back to the scheduler", "error", err)
+	}
+}
+
 // GetCurrentJobs returns the jobs currently being executed
 func (w *Worker) GetCurrentJobs() []*job.Job {
 	w.currentJobsMux.RLock()
@@ -159,11 +378,114 @@ func (w *Worker) GetCurrentJobs() []*job.Job {
 	return jobs
 }
 
+// CancelJob cancels a running job, killing its process
+func (w *Worker) CancelJob(jobID string) error {
+	w.currentJobsMux.RLock()
+	cancel, exists := w.jobCancels[jobID]
+	w.currentJobsMux.RUnlock()
+
+	if !exists {
+		return job.NewJobNotFoundError(jobID)
+	}
+
+	cancel()
+	return nil
+}
+
+// SignalJob sends a signal to the processes of a running command or script job
+func (w *Worker) SignalJob(jobID string, sig syscall.Signal) error {
+	w.currentJobsMux.RLock()
+	j, exists := w.currentJobs[jobID]
+	w.currentJobsMux.RUnlock()
+
+	if !exists {
+		return job.NewJobNotFoundError(jobID)
+	}
+	if !j.CanBeSignaled() {
+		return job.NewValidationError(fmt.Sprintf("cannot signal %s job %s", j.Type, jobID))
+	}
+
+	signaler, ok := w.executor.(interface {
+		Signal(jobID string, sig syscall.Signal) error
+	})
+	if !ok {
+		return fmt.Errorf("executor %s cannot signal jobs", w.executor.Name())
+	}
+
+	return signaler.Signal(jobID, sig)
+}
+
+// ExtendJobTimeout pushes back the deadline of a running job so it isn't
+// killed for timing out, and returns the new deadline
+func (w *Worker) ExtendJobTimeout(jobID string, extra time.Duration) (time.Time, error) {
+	w.currentJobsMux.RLock()
+	_, exists := w.currentJobs[jobID]
+	w.currentJobsMux.RUnlock()
+
+	if !exists {
+		return time.Time{}, job.NewJobNotFoundError(jobID)
+	}
+
+	extender, ok := w.executor.(interface {
+		ExtendTimeout(jobID string, extra time.Duration) (time.Time, error)
+	})
+	if !ok {
+		return time.Time{}, fmt.Errorf("executor %s cannot extend job timeouts", w.executor.Name())
+	}
+
+	return extender.ExtendTimeout(jobID, extra)
+}
+
+// JobLogFile returns the path of a job's log file on this host
+func (w *Worker) JobLogFile(jobID string) (string, error) {
+	// Job IDs become file names, so refuse anything that could escape the log directory
+	if jobID == "" || strings.ContainsAny(jobID, `/\`) || strings.Contains(jobID, "..") {
+		return "", job.NewValidationError("invalid job ID: " + jobID)
+	}
+
+	logger, ok := w.executor.(interface{ LogPath(jobID string) string })
+	if !ok {
+		return "", fmt.Errorf("executor %s does not keep job logs", w.executor.Name())
+	}
+
+	path := logger.LogPath(jobID)
+	if _, err := os.Stat(path); err != nil {
+		return "", job.NewJobNotFoundError(jobID)
+	}
+	return path, nil
+}
+
+// FollowJobOutput returns the recent output of a running job and a channel
+// receiving its output as it is written, until the job finishes. Call
+// cancel to stop following.
+func (w *Worker) FollowJobOutput(jobID string) ([]byte, <-chan []byte, func(), error) {
+	follower, ok := w.executor.(interface {
+		SubscribeOutput(jobID string) ([]byte, <-chan []byte, func(), error)
+	})
+	if !ok {
+		return nil, nil, nil, fmt.Errorf("executor %s cannot stream job output", w.executor.Name())
+	}
+
+	return follower.SubscribeOutput(jobID)
+}
+
+// cancelAllJobs cancels the context of every running job, killing their
+// processes, and returns how many were cancelled
+func (w *Worker) cancelAllJobs() int {
+	w.currentJobsMux.RLock()
+	defer w.currentJobsMux.RUnlock()
+
+	for _, cancel := range w.jobCancels {
+		cancel()
+	}
+	return len(w.jobCancels)
+}
+
 // UpdateHeartbeat updates the last heartbeat time
 func (w *Worker) UpdateHeartbeat() {
 	w.heartbeatMux.Lock()
 	defer w.heartbeatMux.Unlock()
-	w.lastHeartbeat = time.Now()
+	w.lastHeartbeat = time.Now().UTC()
 }
 
 // GetLastHeartbeat returns the last heartbeat time
@@ -180,62 +502,237 @@ func (w *Worker) SetHealthy(healthy bool) {
 	w.isHealthy = healthy
 }
 
-// heartbeatLoop sends periodic heartbeats to the scheduler
+// heartbeatLoop sends periodic heartbeats to the scheduler, backing off
+// exponentially while the scheduler is unreachable
 func (w *Worker) heartbeatLoop(ctx context.Context) {
-	ticker := time.NewTicker(w.config.HeartbeatInterval)
-	defer ticker.Stop()
+	failures := 0
 
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		case <-ticker.C:
+		case <-w.clock.After(w.heartbeatDelay(failures)):
 			if !w.isRunning {
 				return
 			}
 
-			w.sendHeartbeat()
+			if err := w.sendHeartbeat(ctx); err != nil {
+				failures++
+				w.logger.Warn("heartbeat failed", "consecutive_failures", failures, "error", err)
+
+				if failures == w.config.HeartbeatFailureThreshold {
+					w.logger.Error("marking worker unhealthy after failed heartbeats", "consecutive_failures", failures)
+					w.SetHealthy(false)
+				}
+				continue
+			}
+
+			if failures >= w.config.HeartbeatFailureThreshold && w.config.HeartbeatFailureThreshold > 0 {
+				w.SetHealthy(true)
+			}
+			failures = 0
+		}
+	}
+}
+
+// heartbeatDelay returns how long to wait before the next heartbeat: the
+// configured interval doubled per consecutive failure up to the maximum
+// backoff, with random jitter so reconnecting workers don't stampede
+func (w *Worker) heartbeatDelay(failures int) time.Duration {
+	delay := w.config.HeartbeatInterval
+	for i := 0; i < failures; i++ {
+		delay *= 2
+		if w.config.HeartbeatMaxBackoff > 0 && delay >= w.config.HeartbeatMaxBackoff {
+			delay = w.config.HeartbeatMaxBackoff
+			break
 		}
 	}
+
+	if jitter := w.config.HeartbeatJitter; jitter > 0 {
+		// Scale by a random factor in [1-jitter, 1+jitter)
+		delay = time.Duration(float64(delay) * (1 + jitter*(2*w.random()-1)))
+	}
+
+	return delay
+}
+
+// Notify asks the worker to poll for jobs now instead of waiting for the next
+// poll interval. It never blocks; wake-ups arriving while one is already
+// pending are coalesced.
+func (w *Worker) Notify() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
 }
 
-// jobPollingLoop polls for new jobs from the scheduler
+// GetLastPoll returns when the worker last polled the scheduler for jobs
+func (w *Worker) GetLastPoll() time.Time {
+	w.heartbeatMux.RLock()
+	defer w.heartbeatMux.RUnlock()
+	return w.lastPoll
+}
+
+// GetPollInterval returns the current delay between polls for jobs
+func (w *Worker) GetPollInterval() time.Duration {
+	w.heartbeatMux.RLock()
+	defer w.heartbeatMux.RUnlock()
+	if w.pollInterval == 0 {
+		return w.config.JobPollInterval
+	}
+	return w.pollInterval
+}
+
+// jobPollingLoop polls for new jobs from the scheduler, and straight away
+// when the scheduler pushes a wake-up. The delay between polls adapts to
+// the queue: it grows while polls come back empty and drops back to the
+// minimum as soon as one returns work.
 func (w *Worker) jobPollingLoop(ctx context.Context) {
-	ticker := time.NewTicker(w.config.JobPollInterval)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.clock.After(w.GetPollInterval()):
+		case <-w.wake:
+		}
+
+		if !w.isRunning {
+			return
+		}
+
+		interval := w.nextPollInterval(w.GetPollInterval(), w.pollForJobs(ctx))
+		w.heartbeatMux.Lock()
+		w.pollInterval = interval
+		w.heartbeatMux.Unlock()
+	}
+}
+
+// pollOutcome is what a poll for jobs found
+type pollOutcome int
+
+const (
+	pollSkipped  pollOutcome = iota // The worker was full and didn't ask
+	pollEmpty                       // The scheduler had nothing to hand out
+	pollReceived                    // The scheduler handed out a job
+)
+
+// nextPollInterval returns the delay before the next poll: the configured
+// minimum after receiving work, one step longer (up to the maximum) after an
+// empty poll, and unchanged when the worker was too busy to poll
+func (w *Worker) nextPollInterval(current time.Duration, outcome pollOutcome) time.Duration {
+	minInterval, maxInterval := w.config.JobPollInterval, w.config.JobPollMaxInterval
+	if maxInterval <= minInterval {
+		return minInterval
+	}
+
+	switch outcome {
+	case pollReceived:
+		return minInterval
+	case pollEmpty:
+		return min(current+w.config.JobPollStep, maxInterval)
+	default:
+		return current
+	}
+}
+
+// logCleanupLoop periodically deletes job logs older than the retention period
+func (w *Worker) logCleanupLoop(ctx context.Context) {
+	ticker := w.clock.NewTicker(logCleanupInterval)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		case <-ticker.C:
-			if !w.isRunning {
-				return
-			}
-
-			w.pollForJobs(ctx)
+		case <-ticker.C():
+			w.cleanupJobLogs()
 		}
 	}
 }
 
-// sendHeartbeat sends a heartbeat to the scheduler
-func (w *Worker) sendHeartbeat() {
-	// TODO: Implement HTTP client to send heartbeat to scheduler
-	// For now, just update local heartbeat
+// cleanupJobLogs deletes job logs last written before the retention period
+func (w *Worker) cleanupJobLogs() int {
+	dir := filepath.Join(w.config.WorkingDirectory, jobLogDir)
+	removed, err := removeFilesOlderThan(dir, ".log", w.clock.Now().Add(-w.config.LogRetention))
+	if err != nil {
+		w.logger.Error("job log cleanup failed", "error", err)
+	}
+	return removed
+}
+
+// sendHeartbeat sends a heartbeat to the scheduler, with the worker's load
+// when the client can carry it. A scheduler that no longer knows the worker,
+// e.g. after it restarted or reaped the worker as stale, is asked to
+// register it again before the heartbeat is retried.
+func (w *Worker) sendHeartbeat(ctx context.Context) error {
+	err := w.heartbeat(ctx)
+	if isNotFound(err) {
+		w.logger.Warn("scheduler does not know the worker, registering again")
+		w.register(ctx)
+		err = w.heartbeat(ctx)
+	}
+	if err != nil {
+		return err
+	}
+
 	w.UpdateHeartbeat()
-	fmt.Printf("Worker %s sent heartbeat\n", w.id)
+	return nil
+}
+
+// heartbeat sends a single heartbeat
+func (w *Worker) heartbeat(ctx context.Context) error {
+	if reporter, ok := w.scheduler.(interface {
+		HeartbeatLoad(ctx context.Context, workerID string, load int) error
+	}); ok {
+		return reporter.HeartbeatLoad(ctx, w.id, w.GetWeightedLoad())
+	}
+	return w.scheduler.Heartbeat(ctx, w.id)
 }
 
-// pollForJobs polls the scheduler for new jobs
-func (w *Worker) pollForJobs(ctx context.Context) {
+// pollForJobs polls the scheduler for new jobs and starts any it hands out
+func (w *Worker) pollForJobs(ctx context.Context) pollOutcome {
 	if !w.CanAcceptJob() {
-		return // Skip polling if we can't accept jobs
+		return pollSkipped // Skip polling if we can't accept jobs
+	}
+
+	w.heartbeatMux.Lock()
+	w.lastPoll = w.clock.Now()
+	w.heartbeatMux.Unlock()
+
+	poller, ok := w.scheduler.(interface {
+		PollJob(ctx context.Context, workerID string) (*job.Job, error)
+	})
+	if !ok {
+		w.logger.Debug("scheduler client cannot poll for jobs")
+		return pollEmpty
 	}
 
-	// TODO: Implement HTTP client to poll scheduler for jobs
-	// For now, this is a placeholder
-	fmt.Printf("Worker %s polling for jobs (capacity: %d/%d)\n",
-		w.id, w.GetCurrentLoad(), w.GetCapacity())
+	j, err := poller.PollJob(ctx, w.id)
+	if err != nil {
+		w.logger.Warn("failed to poll for jobs", "error", err)
+		return pollEmpty
+	}
+	if j == nil {
+		return pollEmpty
+	}
+
+	go func() {
+		if _, err := w.ExecuteJob(ctx, j); err != nil {
+			w.logger.Error("failed to execute polled job", "job_id", j.ID, "error", err)
+		}
+	}()
+	return pollReceived
+}
+
+// stopServer shuts down the worker's API server if it was started
+func (w *Worker) stopServer(ctx context.Context) {
+	if w.server == nil {
+		return
+	}
+
+	if err := w.server.Shutdown(ctx); err != nil {
+		w.logger.Error("worker API server shutdown failed", "error", err)
+	}
 }
 
 // ensureWorkingDirectory creates the working directory if it doesn't exist
@@ -245,14 +742,23 @@ func (w *Worker) ensureWorkingDirectory() error {
 
 // GetInfo returns worker information
 func (w *Worker) GetInfo() map[string]interface{} {
-	return map[string]interface{}{
+	info := map[string]interface{}{
 		"id":             w.ID(),
 		"healthy":        w.IsHealthy(),
 		"capacity":       w.GetCapacity(),
 		"current_load":   w.GetCurrentLoad(),
+		"weighted_load":  w.GetWeightedLoad(),
 		"can_accept":     w.CanAcceptJob(),
+		"draining":       w.IsDraining(),
 		"last_heartbeat": w.GetLastHeartbeat(),
-		"current_jobs":   len(w.currentJobs),
+		"last_poll":      w.GetLastPoll(),
+		"poll_interval":  w.GetPollInterval().String(),
+		"current_jobs":   w.GetCurrentLoad(),
 		"working_dir":    w.config.WorkingDirectory,
+		"labels":         w.Labels(),
+	}
+	if logs, ok := w.executor.(interface{ LogBytesDropped() int64 }); ok {
+		info["log_bytes_dropped"] = logs.LogBytesDropped()
 	}
+	return info
 }
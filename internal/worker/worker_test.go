@@ -0,0 +1,776 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"infinitrain/internal/api"
+	"infinitrain/internal/clock"
+	"infinitrain/internal/config"
+	"infinitrain/internal/scheduler"
+	"infinitrain/pkg/job"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// newTestWorker creates a running worker driven by a fake clock
+func newTestWorker(t *testing.T) (*Worker, *clock.Fake) {
+	t.Helper()
+
+	executor, cfg := newTestExecutor(t)
+	cfg.HeartbeatInterval = 30 * time.Second
+	cfg.JobPollInterval = 5 * time.Second
+	cfg.ShutdownTimeout = 10 * time.Second
+	cfg.ShutdownPollInterval = 1 * time.Second
+
+	fake := clock.NewFake(time.Now())
+	w := NewWorker(cfg, executor)
+	w.clock = fake
+	w.isRunning = true
+	return w, fake
+}
+
+// startJob executes a command job in the background and waits until the worker has picked it up
+func startJob(t *testing.T, w *Worker, command string) <-chan *job.JobResult {
+	t.Helper()
+
+	j := &job.Job{
+		ID:      job.GenerateJobID(),
+		Type:    job.JobTypeCommand,
+		Command: command,
+		Status:  job.JobStatusQueued,
+	}
+
+	done := make(chan *job.JobResult, 1)
+	go func() {
+		result, _ := w.ExecuteJob(context.Background(), j)
+		done <- result
+	}()
+
+	waitFor(t, func() bool { return w.GetCurrentLoad() == 1 })
+	return done
+}
+
+// waitFor polls a condition in real time, failing the test if it never becomes true
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for condition")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestWorker_StopReturnsEarlyWhenJobsFinish(t *testing.T) {
+	w, fake := newTestWorker(t)
+	start := fake.Now()
+
+	done := startJob(t, w, "sleep 0.1")
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- w.Stop(context.Background()) }()
+
+	// Stop waits on both the shutdown timeout and the poll ticker
+	fake.WaitForWaiters(2)
+
+	result := <-done
+	if result.Status != job.JobStatusCompleted {
+		t.Fatalf("Expected quick job to complete, got %v: %s", result.Status, result.Error)
+	}
+
+	fake.Advance(w.config.ShutdownPollInterval)
+
+	select {
+	case err := <-stopped:
+		if err != nil {
+			t.Fatalf("Stop() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected Stop to return once the job finished")
+	}
+
+	if elapsed := fake.Now().Sub(start); elapsed >= w.config.ShutdownTimeout {
+		t.Errorf("Expected Stop to return before the %v timeout, took %v", w.config.ShutdownTimeout, elapsed)
+	}
+}
+
+func TestWorker_StopCancelsStuckJobsAtTimeout(t *testing.T) {
+	w, fake := newTestWorker(t)
+	stub := &stubSchedulerClient{}
+	w.scheduler = stub
+
+	done := startJob(t, w, "sleep 30")
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- w.Stop(context.Background()) }()
+
+	fake.WaitForWaiters(2)
+	fake.Advance(w.config.ShutdownTimeout)
+
+	select {
+	case err := <-stopped:
+		if err != nil {
+			t.Fatalf("Stop() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected Stop to return at the shutdown timeout")
+	}
+
+	select {
+	case result := <-done:
+		if result.Status != job.JobStatusFailed {
+			t.Errorf("Expected cancelled job to fail, got %v", result.Status)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the stuck job's process to be killed")
+	}
+
+	if load := w.GetCurrentLoad(); load != 0 {
+		t.Errorf("Expected no running jobs after Stop, got %d", load)
+	}
+
+	// The cancelled job is handed back to the scheduler to be queued again
+	if released := stub.releasedBy(); len(released) != 1 || released[0] != w.ID() {
+		t.Errorf("Expected the worker to release its jobs once, got %v", released)
+	}
+}
+
+func TestWorker_StopPollInterval(t *testing.T) {
+	w, fake := newTestWorker(t)
+	w.config.ShutdownTimeout = 300 * time.Millisecond
+	w.config.ShutdownPollInterval = 0
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- w.Stop(context.Background()) }()
+
+	// Without a configured interval, a thirtieth of the timeout is used
+	fake.WaitForWaiters(2)
+	fake.Advance(10 * time.Millisecond)
+
+	select {
+	case err := <-stopped:
+		if err != nil {
+			t.Fatalf("Stop() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected Stop to check for running jobs after a thirtieth of the timeout")
+	}
+}
+
+// flakySchedulerClient fails heartbeats while failing is set and reports each attempt
+type flakySchedulerClient struct {
+	mutex    sync.Mutex
+	failing  bool
+	attempts chan struct{}
+}
+
+func newFlakySchedulerClient() *flakySchedulerClient {
+	return &flakySchedulerClient{attempts: make(chan struct{}, 16)}
+}
+
+func (c *flakySchedulerClient) Heartbeat(ctx context.Context, workerID string) error {
+	c.mutex.Lock()
+	failing := c.failing
+	c.mutex.Unlock()
+
+	c.attempts <- struct{}{}
+	if failing {
+		return errors.New("scheduler unavailable")
+	}
+	return nil
+}
+
+func (c *flakySchedulerClient) setFailing(failing bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.failing = failing
+}
+
+func TestWorker_HeartbeatDelay(t *testing.T) {
+	w, _ := newTestWorker(t)
+	w.config.HeartbeatInterval = 10 * time.Second
+	w.config.HeartbeatMaxBackoff = 60 * time.Second
+
+	tests := []struct {
+		name     string
+		failures int
+		jitter   float64
+		random   float64
+		expected time.Duration
+	}{
+		{"no failures", 0, 0, 0, 10 * time.Second},
+		{"one failure doubles", 1, 0, 0, 20 * time.Second},
+		{"two failures quadruple", 2, 0, 0, 40 * time.Second},
+		{"capped at max backoff", 3, 0, 0, 60 * time.Second},
+		{"stays capped", 10, 0, 0, 60 * time.Second},
+		{"jitter low end", 0, 0.1, 0, 9 * time.Second},
+		{"jitter midpoint", 1, 0.1, 0.5, 20 * time.Second},
+		{"jitter high end", 0, 0.1, 1, 11 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w.config.HeartbeatJitter = tt.jitter
+			w.random = func() float64 { return tt.random }
+
+			if delay := w.heartbeatDelay(tt.failures); delay != tt.expected {
+				t.Errorf("heartbeatDelay(%d) = %v, want %v", tt.failures, delay, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWorker_HeartbeatBackoffAndReset(t *testing.T) {
+	w, fake := newTestWorker(t)
+	w.config.HeartbeatInterval = 10 * time.Second
+	w.config.HeartbeatMaxBackoff = 30 * time.Second
+	w.config.HeartbeatFailureThreshold = 2
+	w.config.HeartbeatJitter = 0
+	scheduler := newFlakySchedulerClient()
+	scheduler.setFailing(true)
+	w.scheduler = scheduler
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.heartbeatLoop(ctx)
+
+	// expectHeartbeatAfter checks that no heartbeat is sent just before the
+	// expected delay and that one is sent exactly when it elapses
+	expectHeartbeatAfter := func(delay time.Duration) {
+		t.Helper()
+
+		fake.WaitForWaiters(1)
+		fake.Advance(delay - time.Millisecond)
+		select {
+		case <-scheduler.attempts:
+			t.Fatalf("Heartbeat sent before the expected %v delay", delay)
+		case <-time.After(20 * time.Millisecond):
+		}
+
+		fake.Advance(time.Millisecond)
+		select {
+		case <-scheduler.attempts:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Expected a heartbeat after %v", delay)
+		}
+	}
+
+	expectHeartbeatAfter(10 * time.Second)
+	expectHeartbeatAfter(20 * time.Second)
+	waitFor(t, func() bool { return !w.IsHealthy() })
+
+	expectHeartbeatAfter(30 * time.Second)
+	expectHeartbeatAfter(30 * time.Second)
+
+	// A successful heartbeat restores health and the base interval
+	scheduler.setFailing(false)
+	expectHeartbeatAfter(30 * time.Second)
+	waitFor(t, w.IsHealthy)
+
+	expectHeartbeatAfter(10 * time.Second)
+	expectHeartbeatAfter(10 * time.Second)
+}
+
+func TestWorker_RegistersWithScheduler(t *testing.T) {
+	ctx := context.Background()
+	store := scheduler.NewMemoryStore()
+	queue := scheduler.NewPriorityQueue()
+	registry := scheduler.NewMemoryWorkerRegistry()
+	manager := scheduler.NewManager(store, queue, registry)
+	server := httptest.NewServer(api.NewServer(config.LoadConfig(), store, queue, manager, registry).SetupRoutes())
+	defer server.Close()
+
+	w, _ := newTestWorker(t)
+	w.scheduler = newHTTPSchedulerClient(server.URL, "")
+	w.server = NewServer(w)
+	if err := w.server.Start("127.0.0.1:0"); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer w.server.Shutdown(ctx)
+
+	// The registration tells the scheduler where the worker's API is
+	w.register(ctx)
+	registered, err := registry.GetWorker(ctx, w.ID())
+	if err != nil {
+		t.Fatalf("Expected the worker to be registered, got %v", err)
+	}
+	if address := registered.(*scheduler.RemoteWorker).Address(); address != "http://"+w.server.Addr() {
+		t.Errorf("Expected the worker's API address, got %q", address)
+	}
+
+	// A scheduler that lost the worker gets it registered again, and the
+	// heartbeat reports the worker's load
+	if err := registry.Unregister(ctx, w.ID()); err != nil {
+		t.Fatalf("Unregister() error = %v", err)
+	}
+	done := startJob(t, w, "sleep 10")
+	if err := w.sendHeartbeat(ctx); err != nil {
+		t.Fatalf("sendHeartbeat() error = %v", err)
+	}
+	registered, err = registry.GetWorker(ctx, w.ID())
+	if err != nil {
+		t.Fatalf("Expected the worker to register again, got %v", err)
+	}
+	if registered.GetCurrentLoad() != 1 {
+		t.Errorf("Expected the heartbeat to report a load of 1, got %d", registered.GetCurrentLoad())
+	}
+
+	w.cancelAllJobs()
+	<-done
+}
+
+func TestWorker_SchedulerCancelStopsRunningJob(t *testing.T) {
+	ctx := context.Background()
+	store := scheduler.NewMemoryStore()
+	queue := scheduler.NewPriorityQueue()
+	registry := scheduler.NewMemoryWorkerRegistry()
+	manager := scheduler.NewManager(store, queue, registry)
+	server := httptest.NewServer(api.NewServer(config.LoadConfig(), store, queue, manager, registry).SetupRoutes())
+	defer server.Close()
+
+	w, _ := newTestWorker(t)
+	w.scheduler = newHTTPSchedulerClient(server.URL, "")
+	w.server = NewServer(w)
+	if err := w.server.Start("127.0.0.1:0"); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer w.server.Shutdown(ctx)
+	w.register(ctx)
+
+	submitted, err := manager.Submit(ctx, &job.JobRequest{Type: job.JobTypeCommand, Command: "sleep 10"})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	claimed, err := store.AssignWorker(ctx, submitted.ID, w.ID())
+	if err != nil {
+		t.Fatalf("AssignWorker() error = %v", err)
+	}
+	done := make(chan *job.JobResult, 1)
+	go func() {
+		result, _ := w.ExecuteJob(context.Background(), claimed)
+		done <- result
+	}()
+	waitFor(t, func() bool { return w.GetCurrentLoad() == 1 })
+
+	// Cancelling through the scheduler reaches the worker at the address it
+	// registered and kills the job
+	if err := manager.CancelJob(ctx, submitted.ID); err != nil {
+		t.Fatalf("CancelJob() error = %v", err)
+	}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the cancelled job to stop on the worker")
+	}
+}
+
+func TestWorker_WeightedCapacity(t *testing.T) {
+	tests := []struct {
+		name       string
+		cost       int
+		wantAccept int
+	}{
+		{name: "cost-1 jobs", cost: 1, wantAccept: 4},
+		{name: "cost-2 jobs", cost: 2, wantAccept: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, _ := newTestWorker(t)
+			w.config.MaxConcurrentJobs = 4
+			defer w.cancelAllJobs()
+
+			accepted := 0
+			for i := 0; i < 5; i++ {
+				j := &job.Job{
+					ID:      job.GenerateJobID(),
+					Type:    job.JobTypeCommand,
+					Command: "sleep 30",
+					Cost:    tt.cost,
+					Status:  job.JobStatusQueued,
+				}
+
+				if !w.CanAcceptCost(tt.cost) {
+					if _, err := w.ExecuteJob(context.Background(), j); err == nil {
+						t.Fatal("Expected ExecuteJob to reject a job beyond capacity")
+					}
+					continue
+				}
+
+				go w.ExecuteJob(context.Background(), j)
+				accepted++
+				waitFor(t, func() bool { return w.GetCurrentLoad() == accepted })
+			}
+
+			if accepted != tt.wantAccept {
+				t.Errorf("Expected %d jobs accepted, got %d", tt.wantAccept, accepted)
+			}
+			if load := w.GetWeightedLoad(); load != 4 {
+				t.Errorf("Expected weighted load 4, got %d", load)
+			}
+			if w.CanAcceptJob() {
+				t.Error("Expected a full worker to refuse further jobs")
+			}
+		})
+	}
+}
+
+func TestWorker_DeliveryLatency(t *testing.T) {
+	tests := []struct {
+		mode    scheduler.DeliveryMode
+		latency time.Duration
+	}{
+		{mode: scheduler.DeliveryPoll, latency: 5 * time.Second},
+		{mode: scheduler.DeliveryPush, latency: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			w, fake := newTestWorker(t)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go w.jobPollingLoop(ctx)
+			fake.WaitForWaiters(1)
+
+			registry := scheduler.NewMemoryWorkerRegistry()
+			if err := registry.Register(ctx, w); err != nil {
+				t.Fatalf("Register() error = %v", err)
+			}
+			manager := scheduler.NewManager(scheduler.NewMemoryStore(), scheduler.NewPriorityQueue(), registry)
+			manager.SetDeliveryMode(tt.mode)
+
+			submittedAt := fake.Now()
+			if _, err := manager.Submit(ctx, &job.JobRequest{Type: job.JobTypeCommand, Command: "echo hi"}); err != nil {
+				t.Fatalf("Submit() error = %v", err)
+			}
+
+			// Give a pushed wake-up time to land before falling back to the poll interval
+			deadline := time.Now().Add(100 * time.Millisecond)
+			for w.GetLastPoll().IsZero() && time.Now().Before(deadline) {
+				time.Sleep(5 * time.Millisecond)
+			}
+			if w.GetLastPoll().IsZero() {
+				fake.Advance(w.config.JobPollInterval)
+				waitFor(t, func() bool { return !w.GetLastPoll().IsZero() })
+			}
+
+			if latency := w.GetLastPoll().Sub(submittedAt); latency != tt.latency {
+				t.Errorf("Expected dispatch latency %v in %s mode, got %v", tt.latency, tt.mode, latency)
+			}
+		})
+	}
+}
+
+func TestWorker_PushDeliversJobThroughAPI(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store := scheduler.NewMemoryStore()
+	queue := scheduler.NewPriorityQueue()
+	registry := scheduler.NewMemoryWorkerRegistry()
+	manager := scheduler.NewManager(store, queue, registry)
+	manager.SetDeliveryMode(scheduler.DeliveryPush)
+	server := httptest.NewServer(api.NewServer(config.LoadConfig(), store, queue, manager, registry).SetupRoutes())
+	defer server.Close()
+
+	w, fake := newTestWorker(t)
+	w.scheduler = newHTTPSchedulerClient(server.URL, "")
+	w.server = NewServer(w)
+	if err := w.server.Start("127.0.0.1:0"); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer w.server.Shutdown(ctx)
+	w.register(ctx)
+	go w.jobPollingLoop(ctx)
+	fake.WaitForWaiters(1)
+
+	// Submitting wakes the worker over its API, and its poll claims the job
+	// from the scheduler's API; the fake clock never reaches the poll interval
+	submitted, err := manager.Submit(ctx, &job.JobRequest{Type: job.JobTypeCommand, Command: "echo pushed"})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	waitFor(t, func() bool {
+		j, _ := store.Get(ctx, submitted.ID)
+		return j.Status == job.JobStatusCompleted && w.GetCurrentLoad() == 0
+	})
+
+	j, _ := store.Get(ctx, submitted.ID)
+	if j.WorkerID != w.ID() || !strings.Contains(j.Output, "pushed") {
+		t.Errorf("Expected the job to run on %s, got worker %q with output %q", w.ID(), j.WorkerID, j.Output)
+	}
+}
+
+// stubSchedulerClient hands out queued jobs to polling workers and records
+// the results and jobs they hand back
+type stubSchedulerClient struct {
+	mutex    sync.Mutex
+	jobs     []*job.Job
+	results  []*job.JobResult
+	released []string
+	polls    chan struct{}
+}
+
+func (c *stubSchedulerClient) Heartbeat(ctx context.Context, workerID string) error {
+	return nil
+}
+
+func (c *stubSchedulerClient) PollJob(ctx context.Context, workerID string) (*job.Job, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	defer func() { c.polls <- struct{}{} }()
+
+	if len(c.jobs) == 0 {
+		return nil, nil
+	}
+	j := c.jobs[0]
+	c.jobs = c.jobs[1:]
+	return j, nil
+}
+
+func (c *stubSchedulerClient) SaveResult(ctx context.Context, result *job.JobResult) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.results = append(c.results, result)
+	return nil
+}
+
+func (c *stubSchedulerClient) ReleaseJobs(ctx context.Context, workerID string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.released = append(c.released, workerID)
+	return nil
+}
+
+func (c *stubSchedulerClient) releasedBy() []string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return append([]string(nil), c.released...)
+}
+
+func (c *stubSchedulerClient) reported() []*job.JobResult {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return append([]*job.JobResult(nil), c.results...)
+}
+
+func (c *stubSchedulerClient) add(j *job.Job) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.jobs = append(c.jobs, j)
+}
+
+func TestWorker_ReportsResult(t *testing.T) {
+	w, _ := newTestWorker(t)
+	stub := &stubSchedulerClient{}
+	w.scheduler = stub
+
+	result := <-startJob(t, w, "sleep 0.1")
+	if result == nil || result.Status != job.JobStatusCompleted {
+		t.Fatalf("Expected the job to complete, got %+v", result)
+	}
+
+	reported := stub.reported()
+	if len(reported) != 1 {
+		t.Fatalf("Expected one reported result, got %d", len(reported))
+	}
+	got := reported[0]
+	if got.JobID != result.JobID || got.Status != job.JobStatusCompleted {
+		t.Errorf("Expected the job's result to be reported, got %+v", got)
+	}
+	if got.StartedAt.IsZero() || got.CompletedAt.Before(got.StartedAt) {
+		t.Errorf("Expected start and completion times, got %v and %v", got.StartedAt, got.CompletedAt)
+	}
+}
+
+func TestWorker_Drain(t *testing.T) {
+	w, _ := newTestWorker(t)
+	done := startJob(t, w, "sleep 0.1")
+
+	if err := w.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain() error = %v", err)
+	}
+	if !w.IsHealthy() || !w.IsDraining() || w.CanAcceptJob() {
+		t.Errorf("Expected a healthy, draining worker refusing jobs, got healthy=%v draining=%v can_accept=%v",
+			w.IsHealthy(), w.IsDraining(), w.CanAcceptJob())
+	}
+
+	j := &job.Job{ID: job.GenerateJobID(), Type: job.JobTypeCommand, Command: "true", Status: job.JobStatusQueued}
+	if _, err := w.ExecuteJob(context.Background(), j); err == nil {
+		t.Error("Expected a draining worker to reject new jobs")
+	}
+
+	// The running job still finishes
+	if result := <-done; result == nil || result.Status != job.JobStatusCompleted {
+		t.Errorf("Expected the in-flight job to complete, got %+v", result)
+	}
+}
+
+func TestWorker_AdaptivePollInterval(t *testing.T) {
+	w, fake := newTestWorker(t)
+	w.config.JobPollMaxInterval = 15 * time.Second
+	w.config.JobPollStep = 5 * time.Second
+	stub := &stubSchedulerClient{polls: make(chan struct{}, 16)}
+	w.scheduler = stub
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.jobPollingLoop(ctx)
+
+	// poll advances the clock by the current interval and waits for the
+	// resulting poll to settle on the next interval
+	poll := func(want time.Duration) {
+		t.Helper()
+		fake.WaitForWaiters(1)
+		fake.Advance(w.GetPollInterval())
+		<-stub.polls
+		waitFor(t, func() bool { return w.GetPollInterval() == want })
+	}
+
+	// Empty polls back off one step at a time up to the maximum
+	poll(10 * time.Second)
+	poll(15 * time.Second)
+	poll(15 * time.Second)
+
+	// Receiving work drops straight back to the minimum
+	stub.add(&job.Job{ID: "job-polled", Type: job.JobTypeCommand, Command: "true", Status: job.JobStatusQueued})
+	poll(5 * time.Second)
+	poll(10 * time.Second)
+
+	// The polled job writes to the working directory until it finishes
+	waitFor(t, func() bool { return len(stub.reported()) == 1 && w.GetCurrentLoad() == 0 })
+}
+
+func TestWorker_AdaptivePollIntervalThroughAPI(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store := scheduler.NewMemoryStore()
+	queue := scheduler.NewPriorityQueue()
+	registry := scheduler.NewMemoryWorkerRegistry()
+	manager := scheduler.NewManager(store, queue, registry)
+	server := httptest.NewServer(api.NewServer(config.LoadConfig(), store, queue, manager, registry).SetupRoutes())
+	defer server.Close()
+
+	w, fake := newTestWorker(t)
+	w.config.JobPollMaxInterval = 15 * time.Second
+	w.config.JobPollStep = 5 * time.Second
+	w.scheduler = newHTTPSchedulerClient(server.URL, "")
+	w.register(ctx)
+	go w.jobPollingLoop(ctx)
+
+	poll := func(want time.Duration) {
+		t.Helper()
+		fake.WaitForWaiters(1)
+		fake.Advance(w.GetPollInterval())
+		waitFor(t, func() bool { return w.GetPollInterval() == want })
+	}
+
+	// With the real client an empty queue backs off, and a claimed job
+	// drops the interval back to the minimum
+	poll(10 * time.Second)
+	submitted, err := manager.Submit(ctx, &job.JobRequest{Type: job.JobTypeCommand, Command: "true"})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	poll(5 * time.Second)
+
+	waitFor(t, func() bool {
+		j, _ := store.Get(ctx, submitted.ID)
+		return j.Status == job.JobStatusCompleted && w.GetCurrentLoad() == 0
+	})
+}
+
+func TestWorker_SignalJob(t *testing.T) {
+	w, _ := newTestWorker(t)
+	defer w.cancelAllJobs()
+
+	ready := filepath.Join(w.config.WorkingDirectory, "trap-ready")
+	j := &job.Job{
+		ID:     job.GenerateJobID(),
+		Type:   job.JobTypeScript,
+		Script: "trap 'echo received TERM; exit 0' TERM\ntouch " + ready + "\nwhile true; do sleep 0.05; done\n",
+		Status: job.JobStatusQueued,
+	}
+
+	done := make(chan *job.JobResult, 1)
+	go func() {
+		result, _ := w.ExecuteJob(context.Background(), j)
+		done <- result
+	}()
+
+	// Only signal once the trap is installed
+	waitFor(t, func() bool {
+		_, err := os.Stat(ready)
+		return err == nil
+	})
+
+	if err := w.SignalJob(j.ID, syscall.SIGTERM); err != nil {
+		t.Fatalf("SignalJob() error = %v", err)
+	}
+
+	select {
+	case result := <-done:
+		if result.Status != job.JobStatusCompleted {
+			t.Errorf("Expected the trapping script to exit cleanly, got %v: %s", result.Status, result.Error)
+		}
+		if !strings.Contains(result.Output, "received TERM") {
+			t.Errorf("Expected the script to report the signal, got %q", result.Output)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the signalled script to exit")
+	}
+
+	if err := w.SignalJob(j.ID, syscall.SIGTERM); !job.IsJobNotFoundError(err) {
+		t.Errorf("Expected job not found for a finished job, got %v", err)
+	}
+
+	// Only jobs backed by a process can be signalled
+	httpJob := &job.Job{ID: "job-http", Type: job.JobTypeHTTP}
+	w.currentJobsMux.Lock()
+	w.currentJobs[httpJob.ID] = httpJob
+	w.currentJobsMux.Unlock()
+	if err := w.SignalJob(httpJob.ID, syscall.SIGHUP); !job.IsValidationError(err) {
+		t.Errorf("Expected a validation error signalling an HTTP job, got %v", err)
+	}
+}
+
+func TestWorker_CleanupJobLogs(t *testing.T) {
+	w, fake := newTestWorker(t)
+	w.config.LogRetention = 24 * time.Hour
+
+	dir := filepath.Join(w.config.WorkingDirectory, jobLogDir)
+	if err := ensureDirectory(dir); err != nil {
+		t.Fatalf("failed to create log dir: %v", err)
+	}
+
+	ages := map[string]time.Duration{
+		"job-old.log":    48 * time.Hour,
+		"job-recent.log": time.Hour,
+		"notes.txt":      48 * time.Hour, // Not a job log
+	}
+	for name, age := range ages {
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, []byte("output"), 0644)
+		modified := fake.Now().Add(-age)
+		if err := os.Chtimes(path, modified, modified); err != nil {
+			t.Fatalf("failed to age %s: %v", name, err)
+		}
+	}
+
+	if removed := w.cleanupJobLogs(); removed != 1 {
+		t.Errorf("Expected 1 log removed, got %d", removed)
+	}
+	for name, wantExists := range map[string]bool{"job-old.log": false, "job-recent.log": true, "notes.txt": true} {
+		if _, err := os.Stat(filepath.Join(dir, name)); (err == nil) != wantExists {
+			t.Errorf("Expected %s exists=%v", name, wantExists)
+		}
+	}
+}
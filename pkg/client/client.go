@@ -0,0 +1,200 @@
+// Package client is a typed Go client for the scheduler's REST API
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"infinitrain/pkg/job"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Client calls the scheduler's REST API
+type Client struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+// New creates a client for the scheduler at baseURL, e.g.
+// "http://localhost:8080". A non-empty token is sent as a bearer token with
+// every request.
+func New(baseURL, token string) *Client {
+	return &Client{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		token:   token,
+		http: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// SetHTTPClient sets the HTTP client requests are sent with, e.g. to change
+// the timeout or transport
+func (c *Client) SetHTTPClient(client *http.Client) {
+	c.http = client
+}
+
+// APIError is an error response from the API
+type APIError struct {
+	StatusCode int
+	Message    string // The error message the API returned
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("scheduler returned status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("scheduler returned status %d: %s", e.StatusCode, e.Message)
+}
+
+// IsNotFound reports whether err is the API saying a job or worker doesn't
+// exist
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// WorkerInfo is a worker as listed by the API
+type WorkerInfo struct {
+	ID           string            `json:"id"`
+	Healthy      bool              `json:"healthy"`
+	Capacity     int               `json:"capacity"`
+	CurrentLoad  int               `json:"current_load"`
+	CanAccept    bool              `json:"can_accept"`
+	Draining     bool              `json:"draining"`
+	Labels       map[string]string `json:"labels,omitempty"`
+	HeartbeatAge *float64          `json:"heartbeat_age_seconds,omitempty"` // Seconds since the last heartbeat, when the scheduler tracks it
+}
+
+// Submit submits a job
+func (c *Client) Submit(ctx context.Context, request *job.JobRequest) (*job.Job, error) {
+	var j job.Job
+	if err := c.do(ctx, http.MethodPost, "/jobs", request, &j); err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+// GetJob returns the job with the given ID
+func (c *Client) GetJob(ctx context.Context, id string) (*job.Job, error) {
+	var j job.Job
+	if err := c.do(ctx, http.MethodGet, "/jobs/"+url.PathEscape(id), nil, &j); err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+// ListJobs returns every job matching every filter, oldest first, reading
+// as many pages as it takes. With no filters it returns all jobs.
+func (c *Client) ListJobs(ctx context.Context, filters ...job.Filter) ([]*job.Job, error) {
+	var jobs []*job.Job
+	cursor := ""
+	for {
+		page, err := c.ListJobsPage(ctx, filters, 0, cursor)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, page.Jobs...)
+		if page.NextCursor == "" {
+			return jobs, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// ListJobsPage returns one page of the jobs matching every filter, oldest
+// first. A limit of zero leaves the page size to the API; an empty cursor
+// starts at the first page, and the page's NextCursor selects the next one.
+func (c *Client) ListJobsPage(ctx context.Context, filters []job.Filter, limit int, cursor string) (*job.JobPage, error) {
+	request := struct {
+		Filters []job.Filter `json:"filters"`
+		Limit   int          `json:"limit,omitempty"`
+		Cursor  string       `json:"cursor,omitempty"`
+	}{Filters: filters, Limit: limit, Cursor: cursor}
+
+	var page job.JobPage
+	if err := c.do(ctx, http.MethodPost, "/jobs/search", request, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// CancelJob cancels the job with the given ID
+func (c *Client) CancelJob(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/jobs/"+url.PathEscape(id), nil, nil)
+}
+
+// GetResult returns the result of a finished job
+func (c *Client) GetResult(ctx context.Context, id string) (*job.JobResult, error) {
+	var result job.JobResult
+	if err := c.do(ctx, http.MethodGet, "/jobs/"+url.PathEscape(id)+"/result", nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ListWorkers returns the workers registered with the scheduler
+func (c *Client) ListWorkers(ctx context.Context) ([]WorkerInfo, error) {
+	var response struct {
+		Workers []WorkerInfo `json:"workers"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/workers", nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Workers, nil
+}
+
+// do sends a request to an API path, with body encoded as JSON when it isn't
+// nil, and decodes a successful response into out when it isn't nil. Error
+// responses are returned as an *APIError.
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %v", err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+"/api/v1"+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("request to scheduler failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var errorBody struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&errorBody)
+		return &APIError{StatusCode: resp.StatusCode, Message: errorBody.Error}
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"infinitrain/internal/api"
+	"infinitrain/internal/config"
+	"infinitrain/internal/scheduler"
+	"infinitrain/pkg/job"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newTestAPI serves the REST API backed by in-memory components, requiring
+// token when it isn't empty
+func newTestAPI(t *testing.T, token string) (*httptest.Server, *scheduler.MemoryStore) {
+	t.Helper()
+
+	store := scheduler.NewMemoryStore()
+	queue := scheduler.NewPriorityQueue()
+	registry := scheduler.NewMemoryWorkerRegistry()
+	manager := scheduler.NewManager(store, queue, registry)
+
+	cfg := config.LoadConfig()
+	if token != "" {
+		cfg.Auth.Tokens = []string{token}
+	}
+	server := httptest.NewServer(api.NewServer(cfg, store, queue, manager, registry).SetupRoutes())
+	t.Cleanup(server.Close)
+	return server, store
+}
+
+func TestClient_JobLifecycle(t *testing.T) {
+	ctx := context.Background()
+	server, store := newTestAPI(t, "secret")
+	c := New(server.URL+"/", "secret")
+
+	submitted, err := c.Submit(ctx, &job.JobRequest{Type: job.JobTypeCommand, Command: "echo hi", Tags: []string{"nightly"}})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	if submitted.ID == "" || submitted.Command != "echo hi" {
+		t.Fatalf("Expected the submitted job back, got %+v", submitted)
+	}
+
+	fetched, err := c.GetJob(ctx, submitted.ID)
+	if err != nil {
+		t.Fatalf("GetJob() error = %v", err)
+	}
+	if fetched.ID != submitted.ID || fetched.Status != job.JobStatusQueued {
+		t.Errorf("Expected the queued job, got %s in %s", fetched.ID, fetched.Status)
+	}
+
+	if _, err := c.Submit(ctx, &job.JobRequest{Type: job.JobTypeCommand, Command: "true"}); err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	tagged, err := c.ListJobs(ctx, job.Filter{Field: "tags", Operator: "contains", Value: "nightly"})
+	if err != nil {
+		t.Fatalf("ListJobs() error = %v", err)
+	}
+	if len(tagged) != 1 || tagged[0].ID != submitted.ID {
+		t.Errorf("Expected only the tagged job, got %d jobs", len(tagged))
+	}
+	if all, err := c.ListJobs(ctx); err != nil || len(all) != 2 {
+		t.Errorf("Expected both jobs without filters, got %d (%v)", len(all), err)
+	}
+
+	// The API's conflict comes back as an error carrying its message
+	_, err = c.GetResult(ctx, submitted.ID)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusConflict || apiErr.Message == "" {
+		t.Fatalf("Expected a 409 for an unfinished job, got %v", err)
+	}
+
+	j, _ := store.Get(ctx, submitted.ID)
+	j.Status = job.JobStatusCompleted
+	store.Update(ctx, j)
+	store.SaveResult(ctx, &job.JobResult{JobID: j.ID, Status: job.JobStatusCompleted, Output: "hi\n", StartedAt: time.Now()})
+
+	result, err := c.GetResult(ctx, submitted.ID)
+	if err != nil {
+		t.Fatalf("GetResult() error = %v", err)
+	}
+	if result.Output != "hi\n" || result.Status != job.JobStatusCompleted {
+		t.Errorf("Expected the stored result, got %+v", result)
+	}
+
+	if err := c.CancelJob(ctx, "job-missing"); !IsNotFound(err) {
+		t.Errorf("Expected a not-found error cancelling a missing job, got %v", err)
+	}
+}
+
+func TestClient_ListJobsPages(t *testing.T) {
+	ctx := context.Background()
+	server, store := newTestAPI(t, "")
+	c := New(server.URL, "")
+
+	// More jobs than the API returns in one page
+	created := time.Now().UTC()
+	for i := 0; i < 150; i++ {
+		j := &job.Job{ID: fmt.Sprintf("job-%03d", i), Type: job.JobTypeCommand, Status: job.JobStatusCompleted, CreatedAt: created}
+		if err := store.Create(ctx, j); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	all, err := c.ListJobs(ctx)
+	if err != nil {
+		t.Fatalf("ListJobs() error = %v", err)
+	}
+	if len(all) != 150 || all[0].ID != "job-000" || all[149].ID != "job-149" {
+		t.Fatalf("Expected all 150 jobs in order, got %d", len(all))
+	}
+
+	page, err := c.ListJobsPage(ctx, nil, 40, "")
+	if err != nil {
+		t.Fatalf("ListJobsPage() error = %v", err)
+	}
+	if len(page.Jobs) != 40 || page.Total != 150 || page.NextCursor == "" {
+		t.Fatalf("Expected a first page of 40 of 150 jobs, got %d of %d", len(page.Jobs), page.Total)
+	}
+	next, err := c.ListJobsPage(ctx, nil, 40, page.NextCursor)
+	if err != nil {
+		t.Fatalf("ListJobsPage() error = %v", err)
+	}
+	if len(next.Jobs) != 40 || next.Jobs[0].ID != "job-040" {
+		t.Errorf("Expected the second page to start at job-040, got %d jobs", len(next.Jobs))
+	}
+}
+
+func TestClient_CancelJob(t *testing.T) {
+	ctx := context.Background()
+	server, _ := newTestAPI(t, "")
+	c := New(server.URL, "")
+
+	j, err := c.Submit(ctx, &job.JobRequest{Type: job.JobTypeCommand, Command: "sleep 60"})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	if err := c.CancelJob(ctx, j.ID); err != nil {
+		t.Fatalf("CancelJob() error = %v", err)
+	}
+	if cancelled, _ := c.GetJob(ctx, j.ID); cancelled == nil || cancelled.Status != job.JobStatusCancelled {
+		t.Errorf("Expected the job to be cancelled, got %+v", cancelled)
+	}
+}
+
+func TestClient_ListWorkers(t *testing.T) {
+	ctx := context.Background()
+	server, _ := newTestAPI(t, "")
+	c := New(server.URL, "")
+
+	registration := &job.WorkerRegistration{ID: "w1", Address: "http://w1:8081", Capacity: 4}
+	if err := c.do(ctx, http.MethodPost, "/workers", registration, nil); err != nil {
+		t.Fatalf("Registering a worker failed: %v", err)
+	}
+
+	workers, err := c.ListWorkers(ctx)
+	if err != nil {
+		t.Fatalf("ListWorkers() error = %v", err)
+	}
+	if len(workers) != 1 || workers[0].ID != "w1" || workers[0].Capacity != 4 {
+		t.Errorf("Expected worker w1 with capacity 4, got %+v", workers)
+	}
+}
+
+func TestClient_BearerToken(t *testing.T) {
+	ctx := context.Background()
+	server, _ := newTestAPI(t, "secret")
+
+	_, err := New(server.URL, "wrong").ListWorkers(ctx)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized || apiErr.Message != "missing or invalid bearer token" {
+		t.Errorf("Expected a 401 with the API's message, got %v", err)
+	}
+}
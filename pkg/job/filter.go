@@ -0,0 +1,108 @@
+package job
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Fields whose filter values must be integers or timestamps. Values decoded
+// from JSON arrive as float64 and strings, which never compare equal to or
+// order correctly against the job's int and time.Time fields.
+var (
+	integerFilterFields = map[string]bool{"priority": true}
+	timeFilterFields    = map[string]bool{"created_at": true, "started_at": true, "completed_at": true, "retry_at": true}
+)
+
+// Normalize returns a copy of the filter with its value coerced to the type
+// of the field it targets: JSON numbers become ints for integer fields and
+// RFC 3339 strings become UTC times for timestamp fields. Stores can then
+// compare values without caring how the filter was decoded.
+func (f Filter) Normalize() (Filter, error) {
+	if f.Operator == "exists" || f.Operator == "nexists" {
+		return f, nil
+	}
+
+	var convert func(interface{}) (interface{}, error)
+	switch {
+	case integerFilterFields[f.Field]:
+		convert = toInt
+	case timeFilterFields[f.Field]:
+		convert = toTime
+	default:
+		return f, nil
+	}
+
+	if values, ok := f.Value.([]interface{}); ok {
+		normalized := make([]interface{}, len(values))
+		for i, v := range values {
+			converted, err := convert(v)
+			if err != nil {
+				return f, NewValidationError(fmt.Sprintf("invalid value for %s filter: %v", f.Field, err))
+			}
+			normalized[i] = converted
+		}
+		f.Value = normalized
+		return f, nil
+	}
+
+	converted, err := convert(f.Value)
+	if err != nil {
+		return f, NewValidationError(fmt.Sprintf("invalid value for %s filter: %v", f.Field, err))
+	}
+	f.Value = converted
+	return f, nil
+}
+
+// NormalizeFilters normalizes each filter, stopping at the first invalid one
+func NormalizeFilters(filters []Filter) ([]Filter, error) {
+	normalized := make([]Filter, len(filters))
+	for i, f := range filters {
+		n, err := f.Normalize()
+		if err != nil {
+			return nil, err
+		}
+		normalized[i] = n
+	}
+	return normalized, nil
+}
+
+// toInt coerces whole JSON numbers to int
+func toInt(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case int:
+		return v, nil
+	case int64:
+		return int(v), nil
+	case float64:
+		if v != math.Trunc(v) {
+			return nil, fmt.Errorf("%v is not a whole number", v)
+		}
+		return int(v), nil
+	case json.Number:
+		i, err := v.Int64()
+		if err != nil {
+			return nil, fmt.Errorf("%s is not a whole number", v)
+		}
+		return int(i), nil
+	default:
+		return nil, fmt.Errorf("expected a number, got %T", value)
+	}
+}
+
+// toTime coerces RFC 3339 strings to UTC times
+func toTime(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v.UTC(), nil
+	case string:
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, fmt.Errorf("expected an RFC 3339 timestamp, got %q", v)
+		}
+		return t.UTC(), nil
+	default:
+		return nil, fmt.Errorf("expected an RFC 3339 timestamp, got %T", value)
+	}
+}
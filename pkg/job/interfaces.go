@@ -8,10 +8,10 @@ import (
 type Executor interface {
 	// Execute runs a job and returns the result
 	Execute(ctx context.Context, job *Job) (*JobResult, error)
-	
+
 	// CanExecute checks if this executor can handle the given job type
 	CanExecute(jobType JobType) bool
-	
+
 	// Name returns the name of this executor
 	Name() string
 }
@@ -20,55 +20,87 @@ type Executor interface {
 type Queue interface {
 	// Enqueue adds a job to the queue
 	Enqueue(ctx context.Context, job *Job) error
-	
+
 	// Dequeue removes and returns the next job from the queue
 	Dequeue(ctx context.Context) (*Job, error)
-	
+
 	// Peek returns the next job without removing it from the queue
 	Peek(ctx context.Context) (*Job, error)
-	
+
+	// PeekMatching returns the next job accepted by match without removing it
+	PeekMatching(ctx context.Context, match func(*Job) bool) (*Job, error)
+
+	// DequeueMatching removes and returns the next job accepted by match
+	DequeueMatching(ctx context.Context, match func(*Job) bool) (*Job, error)
+
 	// Size returns the number of jobs in the queue
 	Size(ctx context.Context) (int, error)
-	
+
 	// IsEmpty returns true if the queue is empty
 	IsEmpty(ctx context.Context) (bool, error)
+
+	// Remove removes a job from the queue by ID
+	Remove(ctx context.Context, jobID string) error
+
+	// Reprioritize changes the priority of a queued job and reorders the queue
+	Reprioritize(ctx context.Context, jobID string, priority int) error
+
+	// Stats returns the queue depth, oldest waiting job and dispatch wait times
+	Stats(ctx context.Context) (*QueueStats, error)
 }
 
 // Store defines the interface for job storage and retrieval
 type Store interface {
 	// Create stores a new job
 	Create(ctx context.Context, job *Job) error
-	
+
 	// Get retrieves a job by ID
 	Get(ctx context.Context, jobID string) (*Job, error)
-	
+
+	// Exists reports whether a job is stored without loading it
+	Exists(ctx context.Context, jobID string) (bool, error)
+
 	// Update updates an existing job
 	Update(ctx context.Context, job *Job) error
-	
+
 	// Delete removes a job from storage
 	Delete(ctx context.Context, jobID string) error
-	
+
 	// List returns jobs with optional filtering
 	List(ctx context.Context, filters ...Filter) ([]*Job, error)
-	
+
+	// ListPage returns one page of the jobs matching the options' filters,
+	// ordered by creation time and then ID
+	ListPage(ctx context.Context, opts ListOptions) (*JobPage, error)
+
 	// UpdateStatus updates the status of a job
 	UpdateStatus(ctx context.Context, jobID string, status JobStatus) error
+
+	// SaveResult stores the result of a job's latest run, replacing any
+	// earlier one
+	SaveResult(ctx context.Context, result *JobResult) error
+
+	// GetResult retrieves the stored result of a job
+	GetResult(ctx context.Context, jobID string) (*JobResult, error)
+
+	// Ping checks that the store's backend can be reached
+	Ping(ctx context.Context) error
 }
 
 // Scheduler defines the interface for job scheduling
 type Scheduler interface {
 	// Schedule schedules a job for execution
 	Schedule(ctx context.Context, job *Job) error
-	
+
 	// Cancel cancels a scheduled job
 	Cancel(ctx context.Context, jobID string) error
-	
+
 	// GetNextJob returns the next job to be executed
 	GetNextJob(ctx context.Context) (*Job, error)
-	
+
 	// MarkCompleted marks a job as completed
 	MarkCompleted(ctx context.Context, jobID string, result *JobResult) error
-	
+
 	// MarkFailed marks a job as failed
 	MarkFailed(ctx context.Context, jobID string, err error) error
 }
@@ -77,68 +109,105 @@ type Scheduler interface {
 type Worker interface {
 	// ID returns the unique identifier for this worker
 	ID() string
-	
+
 	// Start starts the worker
 	Start(ctx context.Context) error
-	
+
 	// Stop stops the worker gracefully
 	Stop(ctx context.Context) error
-	
+
 	// IsHealthy returns true if the worker is healthy
 	IsHealthy() bool
-	
+
 	// GetCapacity returns the maximum number of concurrent jobs this worker can handle
 	GetCapacity() int
-	
+
 	// GetCurrentLoad returns the current number of jobs being executed
 	GetCurrentLoad() int
-	
+
 	// CanAcceptJob returns true if the worker can accept a new job
 	CanAcceptJob() bool
+
+	// Labels returns the labels describing this worker (e.g. gpu=true)
+	Labels() map[string]string
 }
 
 // WorkerRegistry defines the interface for managing workers
 type WorkerRegistry interface {
 	// Register adds a worker to the registry
 	Register(ctx context.Context, worker Worker) error
-	
+
 	// Unregister removes a worker from the registry
 	Unregister(ctx context.Context, workerID string) error
-	
+
 	// GetWorker returns a worker by ID
 	GetWorker(ctx context.Context, workerID string) (Worker, error)
-	
+
 	// ListWorkers returns all registered workers
 	ListWorkers(ctx context.Context) ([]Worker, error)
-	
+
 	// GetAvailableWorkers returns workers that can accept new jobs
 	GetAvailableWorkers(ctx context.Context) ([]Worker, error)
-	
+
+	// GetLeastLoadedWorker returns the available worker with the lowest load
+	// relative to its capacity whose labels match the optional selector and
+	// that has room for a job of the given cost
+	GetLeastLoadedWorker(ctx context.Context, selector map[string]string, cost int) (Worker, error)
+
 	// Heartbeat updates the last seen time for a worker
 	Heartbeat(ctx context.Context, workerID string) error
 }
 
 // Filter defines filtering criteria for job queries
 type Filter struct {
-	Field    string      `json:"field"`
-	Operator string      `json:"operator"` // eq, ne, gt, lt, gte, lte, in, contains
-	Value    interface{} `json:"value"`
+	Field    string      `json:"field"`    // A job field, or "annotation:<key>" to match an annotation
+	Operator string      `json:"operator"` // eq, ne, gt, lt, gte, lte, in, contains, exists, nexists; hasall and hasany for tags, matched case-sensitively
+	Value    interface{} `json:"value"`    // Ignored by exists and nexists
+}
+
+// ListOptions selects one page of a job listing. A page starts after the
+// job named by Cursor, or at Offset when no cursor is given.
+type ListOptions struct {
+	Filters []Filter
+	Limit   int    // Jobs per page; zero means no limit
+	Offset  int    // Matching jobs to skip, ignored when Cursor is set
+	Cursor  string // NextCursor of the previous page
+}
+
+// JobPage is one page of a job listing
+type JobPage struct {
+	Jobs       []*Job `json:"jobs"`
+	Total      int    `json:"total"`                 // Jobs matching the filters across all pages
+	NextCursor string `json:"next_cursor,omitempty"` // Empty on the last page
 }
 
 // JobManager combines all job-related operations
 type JobManager interface {
 	// Submit submits a new job
 	Submit(ctx context.Context, request *JobRequest) (*Job, error)
-	
+
 	// GetJob retrieves a job by ID
 	GetJob(ctx context.Context, jobID string) (*Job, error)
-	
+
 	// ListJobs lists jobs with optional filtering
 	ListJobs(ctx context.Context, filters ...Filter) ([]*Job, error)
-	
+
+	// ListJobsPage lists one page of jobs
+	ListJobsPage(ctx context.Context, opts ListOptions) (*JobPage, error)
+
 	// CancelJob cancels a running or pending job
 	CancelJob(ctx context.Context, jobID string) error
-	
+
 	// GetJobResult gets the result of a completed job
 	GetJobResult(ctx context.Context, jobID string) (*JobResult, error)
-} 
\ No newline at end of file
+
+	// UpdatePriority changes the priority of a job that has not started yet
+	UpdatePriority(ctx context.Context, jobID string, priority int) (*Job, error)
+
+	// Requeue runs a failed job again under the same ID
+	Requeue(ctx context.Context, jobID string) (*Job, error)
+
+	// ReleaseWorkerJobs requeues (or fails) every job running on a worker that
+	// has gone away and returns the IDs of the affected jobs
+	ReleaseWorkerJobs(ctx context.Context, workerID string, requeue bool) ([]string, error)
+}
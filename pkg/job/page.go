@@ -0,0 +1,75 @@
+package job
+
+import (
+	"encoding/base64"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Paginate sorts jobs by creation time and then ID and returns the page the
+// options select. Cursors name the last job of a page rather than a
+// position, so jobs created or deleted between requests don't shift later
+// pages onto ones already read.
+func Paginate(jobs []*Job, opts ListOptions) (*JobPage, error) {
+	sort.Slice(jobs, func(a, b int) bool {
+		return listsBefore(jobs[a].CreatedAt, jobs[a].ID, jobs[b].CreatedAt, jobs[b].ID)
+	})
+
+	page := &JobPage{Total: len(jobs)}
+
+	start := 0
+	if opts.Cursor != "" {
+		createdAt, id, err := DecodeCursor(opts.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		start = sort.Search(len(jobs), func(i int) bool {
+			return listsBefore(createdAt, id, jobs[i].CreatedAt, jobs[i].ID)
+		})
+	} else if opts.Offset > 0 {
+		start = min(opts.Offset, len(jobs))
+	}
+
+	end := len(jobs)
+	if opts.Limit > 0 && start+opts.Limit < end {
+		end = start + opts.Limit
+		page.NextCursor = EncodeCursor(jobs[end-1])
+	}
+
+	page.Jobs = jobs[start:end]
+	return page, nil
+}
+
+// listsBefore reports whether a job created at createdA with ID idA comes
+// before one created at createdB with ID idB in listings
+func listsBefore(createdA time.Time, idA string, createdB time.Time, idB string) bool {
+	if !createdA.Equal(createdB) {
+		return createdA.Before(createdB)
+	}
+	return idA < idB
+}
+
+// EncodeCursor returns an opaque cursor pointing just past the job, for
+// stores that page through jobs themselves
+func EncodeCursor(j *Job) string {
+	raw := j.CreatedAt.Format(time.RFC3339Nano) + " " + j.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeCursor returns the creation time and ID a cursor points past
+func DecodeCursor(cursor string) (time.Time, string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, "", NewValidationError("invalid cursor")
+	}
+	timestamp, id, found := strings.Cut(string(raw), " ")
+	if !found {
+		return time.Time{}, "", NewValidationError("invalid cursor")
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, timestamp)
+	if err != nil {
+		return time.Time{}, "", NewValidationError("invalid cursor")
+	}
+	return createdAt, id, nil
+}
@@ -0,0 +1,94 @@
+package job
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ResponsePath is a parsed JSONPath-like expression selecting a value from a
+// JSON document, e.g. $.data.items[0].name. Only object keys and array
+// indexes are supported; a leading $ is optional.
+type ResponsePath struct {
+	Expression string
+	segments   []pathSegment
+}
+
+// pathSegment is one step of a response path: an object key, or an array
+// index when key is empty
+type pathSegment struct {
+	key   string
+	index int
+}
+
+// ParseResponsePath parses a response path expression. Malformed
+// expressions return a validation error.
+func ParseResponsePath(expression string) (*ResponsePath, error) {
+	rest := strings.TrimPrefix(strings.TrimSpace(expression), "$")
+	if rest == "" {
+		return nil, NewValidationError("response_path must select a field, e.g. $.data.id")
+	}
+
+	var segments []pathSegment
+	for rest != "" {
+		switch {
+		case rest[0] == '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end == -1 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, NewValidationError(fmt.Sprintf("invalid response_path %q: empty field name", expression))
+			}
+			segments = append(segments, pathSegment{key: rest[:end]})
+			rest = rest[end:]
+		case rest[0] == '[':
+			end := strings.IndexByte(rest, ']')
+			if end == -1 {
+				return nil, NewValidationError(fmt.Sprintf("invalid response_path %q: unclosed [", expression))
+			}
+			index, err := strconv.Atoi(rest[1:end])
+			if err != nil || index < 0 {
+				return nil, NewValidationError(fmt.Sprintf("invalid response_path %q: %q is not an array index", expression, rest[1:end]))
+			}
+			segments = append(segments, pathSegment{index: index})
+			rest = rest[end+1:]
+		case len(segments) == 0:
+			// Allow paths without the leading $., e.g. data.id
+			rest = "." + rest
+		default:
+			return nil, NewValidationError(fmt.Sprintf("invalid response_path %q: unexpected %q", expression, rest))
+		}
+	}
+
+	return &ResponsePath{Expression: expression, segments: segments}, nil
+}
+
+// Extract returns the value the path selects from a document decoded with
+// encoding/json, or an error naming the first step that doesn't match
+func (p *ResponsePath) Extract(document interface{}) (interface{}, error) {
+	value := document
+	for _, segment := range p.segments {
+		if segment.key != "" {
+			object, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("response_path %s: cannot look up %q in a non-object", p.Expression, segment.key)
+			}
+			if value, ok = object[segment.key]; !ok {
+				return nil, fmt.Errorf("response_path %s: field %q not found", p.Expression, segment.key)
+			}
+			continue
+		}
+
+		array, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("response_path %s: cannot index a non-array with [%d]", p.Expression, segment.index)
+		}
+		if segment.index >= len(array) {
+			return nil, fmt.Errorf("response_path %s: index %d out of range (length %d)", p.Expression, segment.index, len(array))
+		}
+		value = array[segment.index]
+	}
+	return value, nil
+}
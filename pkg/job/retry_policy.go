@@ -0,0 +1,131 @@
+package job
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+)
+
+// DefaultRetryMultiplier is how much the retry delay grows by when a policy
+// leaves its multiplier unset
+const DefaultRetryMultiplier = 2
+
+// RetryPolicy decides how long a failed job waits before each retry and how
+// many retries it gets. Delays grow from BaseDelay by Multiplier with each
+// retry, up to MaxDelay, and Jitter randomizes part of every delay so jobs
+// that failed together don't all retry together. In JSON the delays are
+// duration strings such as "30s".
+type RetryPolicy struct {
+	BaseDelay   time.Duration // Delay before the first retry; 0 retries straight away
+	MaxDelay    time.Duration // Longest delay between retries; 0 for no limit
+	Multiplier  float64       // Growth of the delay with each retry, at least 1; 0 uses DefaultRetryMultiplier
+	Jitter      float64       // Fraction of each delay that is random, from 0 (none) to 1 (full jitter)
+	MaxAttempts *int          // Most runs of a job, the first included; unset leaves the job's retries as they are
+}
+
+// retryPolicyJSON is the wire form of a RetryPolicy
+type retryPolicyJSON struct {
+	BaseDelay   string  `json:"base_delay,omitempty"`
+	MaxDelay    string  `json:"max_delay,omitempty"`
+	Multiplier  float64 `json:"multiplier,omitempty"`
+	Jitter      float64 `json:"jitter,omitempty"`
+	MaxAttempts *int    `json:"max_attempts,omitempty"`
+}
+
+// MarshalJSON writes the policy with its delays as duration strings
+func (p RetryPolicy) MarshalJSON() ([]byte, error) {
+	wire := retryPolicyJSON{
+		Multiplier:  p.Multiplier,
+		Jitter:      p.Jitter,
+		MaxAttempts: p.MaxAttempts,
+	}
+	if p.BaseDelay != 0 {
+		wire.BaseDelay = p.BaseDelay.String()
+	}
+	if p.MaxDelay != 0 {
+		wire.MaxDelay = p.MaxDelay.String()
+	}
+	return json.Marshal(wire)
+}
+
+// UnmarshalJSON reads a policy whose delays are duration strings, rejecting
+// fields a policy doesn't have
+func (p *RetryPolicy) UnmarshalJSON(data []byte) error {
+	var wire retryPolicyJSON
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&wire); err != nil {
+		return fmt.Errorf("invalid retry_policy: %w", err)
+	}
+
+	policy := RetryPolicy{Multiplier: wire.Multiplier, Jitter: wire.Jitter, MaxAttempts: wire.MaxAttempts}
+	for _, field := range []struct {
+		name  string
+		value string
+		into  *time.Duration
+	}{
+		{"base_delay", wire.BaseDelay, &policy.BaseDelay},
+		{"max_delay", wire.MaxDelay, &policy.MaxDelay},
+	} {
+		if field.value == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(field.value)
+		if err != nil {
+			return fmt.Errorf("invalid retry_policy %s: %s", field.name, field.value)
+		}
+		*field.into = parsed
+	}
+
+	*p = policy
+	return nil
+}
+
+// Validate checks that the policy's values are usable
+func (p *RetryPolicy) Validate() error {
+	if p.BaseDelay < 0 || p.MaxDelay < 0 {
+		return NewValidationError("retry_policy delays cannot be negative")
+	}
+	if p.Multiplier != 0 && p.Multiplier < 1 {
+		return NewValidationError(fmt.Sprintf("retry_policy multiplier must be at least 1, got %g", p.Multiplier))
+	}
+	if p.Jitter < 0 || p.Jitter > 1 {
+		return NewValidationError(fmt.Sprintf("retry_policy jitter must be between 0 and 1, got %g", p.Jitter))
+	}
+	if p.MaxAttempts != nil && *p.MaxAttempts < 1 {
+		return NewValidationError("retry_policy max_attempts must be at least 1")
+	}
+	return nil
+}
+
+// Retries returns how many retries the policy's MaxAttempts leaves after the
+// first run, and whether it sets any
+func (p *RetryPolicy) Retries() (int, bool) {
+	if p.MaxAttempts == nil {
+		return 0, false
+	}
+	return max(*p.MaxAttempts-1, 0), true
+}
+
+// Delay returns how long to wait before the given retry, counting from 1.
+// random is a number in [0, 1) that picks where the jittered part of the
+// delay falls: with full jitter the delay is anywhere from zero up to the
+// backoff.
+func (p *RetryPolicy) Delay(retry int, random float64) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier == 0 {
+		multiplier = DefaultRetryMultiplier
+	}
+
+	backoff := float64(p.BaseDelay) * math.Pow(multiplier, float64(max(retry-1, 0)))
+	if p.MaxDelay > 0 && backoff > float64(p.MaxDelay) {
+		backoff = float64(p.MaxDelay)
+	}
+	// Without a maximum, enough retries outgrow a Duration
+	backoff = min(backoff, math.Nextafter(math.MaxInt64, 0))
+
+	fixed := backoff * (1 - p.Jitter)
+	return time.Duration(fixed + (backoff-fixed)*random)
+}
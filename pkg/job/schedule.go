@@ -0,0 +1,65 @@
+package job
+
+import (
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// cronParser accepts standard five-field cron expressions and descriptors such as @hourly
+var cronParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// Schedule is a parsed cron expression evaluated in a specific time zone
+type Schedule struct {
+	Expression string
+	Location   *time.Location
+	schedule   cron.Schedule
+}
+
+// ParseSchedule parses a cron expression in the named IANA time zone. An empty
+// timezone means UTC. Invalid expressions and zones return a validation error.
+func ParseSchedule(expression, timezone string) (*Schedule, error) {
+	if expression == "" {
+		return nil, NewValidationError("cron expression is required")
+	}
+
+	location := time.UTC
+	if timezone != "" {
+		loc, err := time.LoadLocation(timezone)
+		if err != nil {
+			return nil, NewValidationError("invalid timezone: " + timezone)
+		}
+		location = loc
+	}
+
+	parsed, err := cronParser.Parse(expression)
+	if err != nil {
+		return nil, NewValidationError("invalid cron expression: " + err.Error())
+	}
+
+	return &Schedule{
+		Expression: expression,
+		Location:   location,
+		schedule:   parsed,
+	}, nil
+}
+
+// Next returns the first fire time strictly after the given time, or the zero
+// time if the schedule never fires again
+func (s *Schedule) Next(after time.Time) time.Time {
+	return s.schedule.Next(after.In(s.Location))
+}
+
+// NextN returns up to n consecutive fire times after the given time
+func (s *Schedule) NextN(after time.Time, n int) []time.Time {
+	times := make([]time.Time, 0, n)
+	for i := 0; i < n; i++ {
+		next := s.Next(after)
+		if next.IsZero() {
+			break
+		}
+		times = append(times, next)
+		after = next
+	}
+	return times
+}
@@ -1,6 +1,11 @@
 package job
 
 import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
 	"time"
 )
 
@@ -14,12 +19,48 @@ const (
 	JobTypeFile    JobType = "file"
 )
 
+// StepRetryMode controls where a retried multi-step job starts
+type StepRetryMode string
+
+const (
+	StepRetryResume  StepRetryMode = "resume"  // Start again from the step that failed
+	StepRetryRestart StepRetryMode = "restart" // Run every step again from the beginning
+)
+
+// SchedulingMode controls what happens when a job is submitted and no worker can take it
+type SchedulingMode string
+
+const (
+	SchedulingModeQueue     SchedulingMode = "queue"     // Wait in the queue until a worker is free
+	SchedulingModeImmediate SchedulingMode = "immediate" // Reject the submission unless a worker is free now
+)
+
+// LogOverflow decides what happens to a job's output when the worker's log
+// writer falls behind and the buffer between them is full
+type LogOverflow string
+
+const (
+	LogOverflowBlock LogOverflow = "block" // Stall the job's output until the writer catches up
+	LogOverflowDrop  LogOverflow = "drop"  // Discard the output that doesn't fit, counting the bytes
+)
+
+// TerminationReason describes how a job's process ended
+type TerminationReason string
+
+const (
+	TerminationExited    TerminationReason = "exited"    // The process exited on its own with its real exit code
+	TerminationTimeout   TerminationReason = "timeout"   // The job's timeout elapsed (exit code 124)
+	TerminationSignaled  TerminationReason = "signaled"  // The process was killed by a signal (exit code 128+signal)
+	TerminationCancelled TerminationReason = "cancelled" // The job was cancelled and its process killed
+	TerminationNotRun    TerminationReason = "not_run"   // The process could not be started
+)
+
 // JobStatus represents the current status of a job
 type JobStatus string
 
 const (
-	JobStatusPending   JobStatus = "pending"
-	JobStatusQueued    JobStatus = "queued"
+	JobStatusPending   JobStatus = "pending" // Accepted and stored but not yet admitted to the run queue
+	JobStatusQueued    JobStatus = "queued"  // In the run queue waiting to be dispatched to a worker
 	JobStatusRunning   JobStatus = "running"
 	JobStatusCompleted JobStatus = "completed"
 	JobStatusFailed    JobStatus = "failed"
@@ -27,55 +68,334 @@ const (
 	JobStatusRetrying  JobStatus = "retrying"
 )
 
+// JobStatuses lists every status a job can be in
+var JobStatuses = []JobStatus{
+	JobStatusPending,
+	JobStatusQueued,
+	JobStatusRunning,
+	JobStatusRetrying,
+	JobStatusCompleted,
+	JobStatusFailed,
+	JobStatusCancelled,
+}
+
+// MaxTenantLength bounds a tenant name, which is used as a metric label
+const MaxTenantLength = 64
+
+// ValidateTenant checks that a tenant name is short and made of letters,
+// digits, dots, dashes and underscores. An empty name is allowed.
+func ValidateTenant(tenant string) error {
+	if len(tenant) > MaxTenantLength {
+		return NewValidationError(fmt.Sprintf("tenant must be at most %d characters", MaxTenantLength))
+	}
+	for _, r := range tenant {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+		default:
+			return NewValidationError("tenant may only contain letters, digits, '.', '-' and '_': " + tenant)
+		}
+	}
+	return nil
+}
+
+// Bounds on a job's priority; higher priorities are dispatched first
+const (
+	MinPriority = 1
+	MaxPriority = 1000
+)
+
+// ValidatePriority checks that a priority is within MinPriority and MaxPriority
+func ValidatePriority(priority int) error {
+	if priority < MinPriority || priority > MaxPriority {
+		return NewValidationError(fmt.Sprintf("priority must be between %d and %d, got %d", MinPriority, MaxPriority, priority))
+	}
+	return nil
+}
+
 // Job represents a job to be executed
 type Job struct {
-	ID          string            `json:"id"`
-	Type        JobType           `json:"type"`
-	Command     string            `json:"command,omitempty"`
-	Script      string            `json:"script,omitempty"`
-	URL         string            `json:"url,omitempty"`
-	Method      string            `json:"method,omitempty"`
-	FilePath    string            `json:"file_path,omitempty"`
-	Timeout     time.Duration     `json:"timeout"`
-	Retries     int               `json:"retries"`
-	Priority    int               `json:"priority"`
-	Tags        []string          `json:"tags,omitempty"`
-	Environment map[string]string `json:"environment,omitempty"`
-	WorkerID    string            `json:"worker_id,omitempty"`
+	ID           string            `json:"id"`
+	Type         JobType           `json:"type"`
+	Command      string            `json:"command,omitempty"`
+	Shell        bool              `json:"shell,omitempty"`
+	Steps        []string          `json:"steps,omitempty"`
+	StepRetry    StepRetryMode     `json:"step_retry,omitempty"`
+	ResumeStep   int               `json:"resume_step,omitempty"`
+	Script       string            `json:"script,omitempty"`
+	URL          string            `json:"url,omitempty"`
+	Method       string            `json:"method,omitempty"`
+	Body         string            `json:"body,omitempty"`
+	HTTPTimeout  time.Duration     `json:"http_timeout,omitempty"`
+	ResponsePath string            `json:"response_path,omitempty"`
+	FilePath     string            `json:"file_path,omitempty"`
+	FileContent  string            `json:"file_content,omitempty"`
+	WorkingDir   string            `json:"working_dir,omitempty"`
+	Timeout      time.Duration     `json:"timeout"`
+	Retries      int               `json:"retries"`
+	RetryCount   int               `json:"retry_count,omitempty"`  // Retries used so far
+	RetryAt      *time.Time        `json:"retry_at,omitempty"`     // When a retrying job goes back to the queue
+	RetryPolicy  *RetryPolicy      `json:"retry_policy,omitempty"` // Spacing of retries; unset uses the scheduler's policy
+	Priority     int               `json:"priority"`
+	Cost         int               `json:"cost,omitempty"`
+	Tags         []string          `json:"tags,omitempty"`
+	Tenant       string            `json:"tenant,omitempty"` // Who the job runs for; fair-share queues take turns between tenants
+	Environment  map[string]string `json:"environment,omitempty"`
+	Secrets      []string          `json:"secrets,omitempty"` // Names of environment variables the worker fills in from its secret source
+	Interpolate  bool              `json:"interpolate,omitempty"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+	MutexKey     string            `json:"mutex_key,omitempty"`
+	AffinityKey  string            `json:"affinity_key,omitempty"`
+	NodeSelector map[string]string `json:"node_selector,omitempty"`
+	DependsOn    []string          `json:"depends_on,omitempty"`
+	Schedule     string            `json:"schedule,omitempty"`    // Cron expression of a recurring job
+	Timezone     string            `json:"timezone,omitempty"`    // Zone the schedule is evaluated in, UTC if empty
+	NextRunAt    *time.Time        `json:"next_run_at,omitempty"` // When a recurring job next submits a run
+	ParentID     string            `json:"parent_id,omitempty"`   // Recurring job this is a run of
+	Scheduling   SchedulingMode    `json:"scheduling_mode,omitempty"`
+	Durable      bool              `json:"durable,omitempty"`
+	LogOverflow  LogOverflow       `json:"log_overflow,omitempty"`
+	WorkerID     string            `json:"worker_id,omitempty"`
+	Status       JobStatus         `json:"status"`
+	CreatedAt    time.Time         `json:"created_at"`
+	StartedAt    *time.Time        `json:"started_at,omitempty"`
+	CompletedAt  *time.Time        `json:"completed_at,omitempty"`
+	Output       string            `json:"output,omitempty"`
+	OutputBytes  int64             `json:"output_bytes,omitempty"` // Size of the full output, even if Output was truncated
+	OutputLines  int64             `json:"output_lines,omitempty"`
+	Truncated    bool              `json:"output_truncated,omitempty"`  // Output was cut off at the worker's limit
+	LogDropped   int64             `json:"log_bytes_dropped,omitempty"` // Output missing from the log file because the writer fell behind
+	Error        string            `json:"error,omitempty"`
+	ExitCode     int               `json:"exit_code,omitempty"`
+	Termination  TerminationReason `json:"termination_reason,omitempty"`
+	LogFile      string            `json:"log_file,omitempty"`
+	Progress     int               `json:"progress,omitempty"` // Percent complete (0-100)
+	History      []JobAttempt      `json:"history,omitempty"`  // Earlier outcomes: runs requeued in place, statuses forced by dependencies
+	Events       []JobEvent        `json:"events,omitempty"`   // Every status the job has been in, oldest first
+
+	// EffectivePriority is the priority a queued job is dispatched by once
+	// aging is counted. It is filled in for API responses, never stored.
+	EffectivePriority int `json:"effective_priority,omitempty"`
+
+	// TraceParent is the W3C traceparent of the span that submitted the job;
+	// queueing, assignment and execution continue its trace
+	TraceParent string `json:"trace_parent,omitempty"`
+}
+
+// JobEvent records a job entering a status
+type JobEvent struct {
+	Status    JobStatus `json:"status"`
+	Timestamp time.Time `json:"timestamp"`
+	Message   string    `json:"message,omitempty"`   // Why, when known: the error of a failed run, the reason a status was forced
+	WorkerID  string    `json:"worker_id,omitempty"` // Worker the job was assigned to at the time
+}
+
+// JobAttempt records an earlier outcome of a job: a failed run before the
+// job was requeued under the same ID, or a status forced on it because of
+// one of its dependencies
+type JobAttempt struct {
 	Status      JobStatus         `json:"status"`
-	CreatedAt   time.Time         `json:"created_at"`
-	StartedAt   *time.Time        `json:"started_at,omitempty"`
-	CompletedAt *time.Time        `json:"completed_at,omitempty"`
-	Output      string            `json:"output,omitempty"`
+	Reason      string            `json:"reason,omitempty"` // Why the status was forced, e.g. a cancelled dependency
+	WorkerID    string            `json:"worker_id,omitempty"`
 	Error       string            `json:"error,omitempty"`
 	ExitCode    int               `json:"exit_code,omitempty"`
+	Termination TerminationReason `json:"termination_reason,omitempty"`
+	StartedAt   *time.Time        `json:"started_at,omitempty"`
+	CompletedAt *time.Time        `json:"completed_at,omitempty"`
+	RecordedAt  time.Time         `json:"recorded_at"`
+}
+
+// UnmarshalJSON reads an attempt, taking its recorded time from the older
+// requeued_at key when recorded_at is missing, so attempts stored before the
+// key was renamed keep their time
+func (a *JobAttempt) UnmarshalJSON(data []byte) error {
+	type attempt JobAttempt // Drops the method so decoding doesn't recurse
+	var wire struct {
+		attempt
+		RequeuedAt *time.Time `json:"requeued_at"`
+	}
+	if err := json.Unmarshal(data, &wire); err != nil {
+		return err
+	}
+	if wire.RecordedAt.IsZero() && wire.RequeuedAt != nil {
+		wire.RecordedAt = *wire.RequeuedAt
+	}
+	*a = JobAttempt(wire.attempt)
+	return nil
 }
 
 // JobResult represents the result of a job execution
 type JobResult struct {
-	JobID       string        `json:"job_id"`
-	Status      JobStatus     `json:"status"`
-	Output      string        `json:"output"`
-	Error       string        `json:"error"`
-	ExitCode    int           `json:"exit_code"`
-	StartedAt   time.Time     `json:"started_at"`
-	CompletedAt time.Time     `json:"completed_at"`
-	Duration    time.Duration `json:"duration"`
+	JobID       string            `json:"job_id"`
+	Status      JobStatus         `json:"status"`
+	Output      string            `json:"output"`
+	OutputBytes int64             `json:"output_bytes"` // Size of the full output, even if Output was truncated
+	OutputLines int64             `json:"output_lines"`
+	Truncated   bool              `json:"output_truncated,omitempty"`  // Output was cut off at the worker's limit
+	LogDropped  int64             `json:"log_bytes_dropped,omitempty"` // Output missing from the log file because the writer fell behind
+	Error       string            `json:"error"`
+	ExitCode    int               `json:"exit_code"`
+	Termination TerminationReason `json:"termination_reason,omitempty"`
+	LogFile     string            `json:"log_file,omitempty"` // Path of the job's log on the worker host
+	StartedAt   time.Time         `json:"started_at"`
+	CompletedAt time.Time         `json:"completed_at"`
+	Duration    time.Duration     `json:"duration"`
+	Steps       []StepResult      `json:"steps,omitempty"`
+	Stdout      string            `json:"stdout,omitempty"`   // Standard output of a command or script job
+	Stderr      string            `json:"stderr,omitempty"`   // Standard error of a command or script job
+	Response    *HTTPResponse     `json:"response,omitempty"` // Response to an HTTP job's request
+}
+
+// HTTPResponse is the response an HTTP job received, kept apart from the
+// job's formatted output
+type HTTPResponse struct {
+	StatusCode int                 `json:"status_code"`
+	Headers    map[string][]string `json:"headers,omitempty"`
+	Body       string              `json:"body,omitempty"`
+	Truncated  bool                `json:"truncated,omitempty"` // Body was cut off at the worker's output limit
+}
+
+// BlockReason names something that keeps a job from being dispatched
+type BlockReason string
+
+const (
+	BlockedNotQueued  BlockReason = "not_queued" // The job isn't waiting in the run queue
+	BlockedDependency BlockReason = "dependency" // A job it depends on hasn't completed
+	BlockedMutex      BlockReason = "mutex_held" // A running job holds its mutex key
+	BlockedNoWorker   BlockReason = "no_worker"  // No available worker has room for it
+)
+
+// Schedulability explains whether a job can be dispatched and, if not, why
+type Schedulability struct {
+	JobID        string            `json:"job_id"`
+	Status       JobStatus         `json:"status"`
+	Dispatchable bool              `json:"dispatchable"` // Nothing blocks the job; it runs once it reaches the head of the queue
+	Blockers     []Blocker         `json:"blockers,omitempty"`
+	Dependencies []DependencyState `json:"dependencies,omitempty"`
+	WorkerID     string            `json:"worker_id,omitempty"` // Worker that would take the job right now
+	Position     int               `json:"position,omitempty"`  // Place in dispatch order among queued jobs, starting at 1
+}
+
+// Blocker is one reason a job can't be dispatched
+type Blocker struct {
+	Reason BlockReason `json:"reason"`
+	Detail string      `json:"detail"`
+}
+
+// WorkerRegistration is the request a remote worker sends to join the
+// scheduler
+type WorkerRegistration struct {
+	ID       string            `json:"id"`
+	Capacity int               `json:"capacity"`          // Capacity in job cost units
+	Labels   map[string]string `json:"labels,omitempty"`  // Matched against jobs' node selectors
+	Address  string            `json:"address,omitempty"` // Base URL of the worker's own API, if it serves one
+}
+
+// Validate validates a worker registration
+func (wr *WorkerRegistration) Validate() error {
+	if wr.ID == "" {
+		return NewValidationError("worker id is required")
+	}
+	if wr.Capacity <= 0 {
+		return NewValidationError("worker capacity must be positive")
+	}
+	for key := range wr.Labels {
+		if key == "" {
+			return NewValidationError("worker labels cannot have empty names")
+		}
+	}
+	return nil
+}
+
+// DependencyState reports whether one of a job's dependencies has completed
+type DependencyState struct {
+	JobID     string    `json:"job_id"`
+	Status    JobStatus `json:"status,omitempty"` // Empty if the job no longer exists
+	Satisfied bool      `json:"satisfied"`
+}
+
+// StepResult represents the outcome of a single step of a multi-step job
+type StepResult struct {
+	Index    int    `json:"index"`
+	Command  string `json:"command"`
+	Output   string `json:"output"`
+	ExitCode int    `json:"exit_code"`
+}
+
+// QueueStats summarizes the jobs waiting in a queue and how long dispatched
+// jobs waited between enqueue and dispatch
+type QueueStats struct {
+	Depth            int               `json:"depth"`
+	OldestJobID      string            `json:"oldest_job_id,omitempty"`
+	OldestEnqueuedAt time.Time         `json:"oldest_enqueued_at,omitempty"`
+	WaitBuckets      []HistogramBucket `json:"wait_buckets"`
+	WaitCount        int               `json:"wait_count"`
+	WaitSum          time.Duration     `json:"wait_sum"`
+	TenantDepths     map[string]int    `json:"tenant_depths,omitempty"` // Queued jobs per tenant; jobs without one count under ""
+}
+
+// OldestAge returns how long the oldest queued job has been waiting at now
+func (s *QueueStats) OldestAge(now time.Time) time.Duration {
+	if s.OldestEnqueuedAt.IsZero() {
+		return 0
+	}
+	return now.Sub(s.OldestEnqueuedAt)
+}
+
+// HistogramBucket is a cumulative histogram bucket counting observations of
+// at most UpperBound. The implicit unbounded bucket is the total count.
+type HistogramBucket struct {
+	UpperBound time.Duration `json:"upper_bound"`
+	Count      int           `json:"count"`
+}
+
+// httpMethods are the methods HTTP jobs may use
+var httpMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodOptions: true,
 }
 
 // JobRequest represents a request to create a new job
 type JobRequest struct {
-	Type        JobType           `json:"type"`
-	Command     string            `json:"command,omitempty"`
-	Script      string            `json:"script,omitempty"`
-	URL         string            `json:"url,omitempty"`
-	Method      string            `json:"method,omitempty"`
-	FilePath    string            `json:"file_path,omitempty"`
-	Timeout     string            `json:"timeout,omitempty"` // Will be parsed to time.Duration
-	Retries     int               `json:"retries,omitempty"`
-	Priority    int               `json:"priority,omitempty"`
-	Tags        []string          `json:"tags,omitempty"`
-	Environment map[string]string `json:"environment,omitempty"`
+	Type         JobType           `json:"type"`
+	Command      string            `json:"command,omitempty"`
+	Shell        bool              `json:"shell,omitempty"`      // Run commands through the worker's shell instead of splitting on spaces
+	Steps        []string          `json:"steps,omitempty"`      // Commands run in order, stopping at the first failure
+	StepRetry    StepRetryMode     `json:"step_retry,omitempty"` // Defaults to resume
+	Script       string            `json:"script,omitempty"`
+	URL          string            `json:"url,omitempty"`
+	Method       string            `json:"method,omitempty"`
+	Body         string            `json:"body,omitempty"`          // Request body sent by HTTP jobs
+	HTTPTimeout  string            `json:"http_timeout,omitempty"`  // Client timeout of HTTP jobs; defaults to the job's timeout
+	ResponsePath string            `json:"response_path,omitempty"` // Extracts a field from JSON responses, e.g. $.data.id
+	FilePath     string            `json:"file_path,omitempty"`
+	FileContent  string            `json:"file_content,omitempty"` // Written by file jobs' write and append operations
+	WorkingDir   string            `json:"working_dir,omitempty"`  // Overrides the worker's directory for command/script jobs
+	Timeout      string            `json:"timeout,omitempty"`      // Will be parsed to time.Duration
+	Retries      *int              `json:"retries,omitempty"`      // Unset uses the scheduler's default for the job type
+	RetryPolicy  *RetryPolicy      `json:"retry_policy,omitempty"` // Replaces the scheduler's retry delays; max_attempts may stand in for retries, as one more
+	Priority     int               `json:"priority,omitempty"`
+	Cost         int               `json:"cost,omitempty"` // Share of a worker's capacity the job uses, defaults to 1
+	Tags         []string          `json:"tags,omitempty"`
+	Tenant       string            `json:"tenant,omitempty"` // Who the job runs for, e.g. a team or user
+	Environment  map[string]string `json:"environment,omitempty"`
+	Secrets      []string          `json:"secrets,omitempty"`         // Environment variables filled in by the worker's secret source; only names are stored
+	Interpolate  bool              `json:"interpolate,omitempty"`     // Expand $VAR in command, url and file_path from environment
+	Annotations  map[string]string `json:"annotations,omitempty"`     // Free-form metadata, never used for scheduling
+	MutexKey     string            `json:"mutex_key,omitempty"`       // At most one job per key runs at a time
+	AffinityKey  string            `json:"affinity_key,omitempty"`    // Jobs sharing a key prefer the worker that ran the last one
+	NodeSelector map[string]string `json:"node_selector,omitempty"`   // Labels a worker must have to run the job; it stays queued until one can
+	DependsOn    []string          `json:"depends_on,omitempty"`      // IDs of jobs this one depends on
+	Schedule     string            `json:"schedule,omitempty"`        // Cron expression; the job then submits a run each time it fires
+	Timezone     string            `json:"timezone,omitempty"`        // Zone the schedule is evaluated in, UTC if empty
+	ParentID     string            `json:"-"`                         // Set on the runs the cron scheduler submits
+	Scheduling   SchedulingMode    `json:"scheduling_mode,omitempty"` // Defaults to queue
+	Durable      bool              `json:"durable,omitempty"`         // Requeue if orphaned by a scheduler restart, whatever the recovery policy
+	LogOverflow  LogOverflow       `json:"log_overflow,omitempty"`    // Block or drop output when the log writer falls behind; defaults to the worker's setting
 }
 
 // Validate validates a job request
@@ -86,8 +406,16 @@ func (jr *JobRequest) Validate() error {
 
 	switch jr.Type {
 	case JobTypeCommand:
-		if jr.Command == "" {
-			return NewValidationError("command is required for command jobs")
+		if jr.Command == "" && len(jr.Steps) == 0 {
+			return NewValidationError("command or steps is required for command jobs")
+		}
+		if jr.Command != "" && len(jr.Steps) > 0 {
+			return NewValidationError("command and steps are mutually exclusive")
+		}
+		switch jr.StepRetry {
+		case "", StepRetryResume, StepRetryRestart:
+		default:
+			return NewValidationError("unsupported step_retry mode: " + string(jr.StepRetry))
 		}
 	case JobTypeScript:
 		if jr.Script == "" {
@@ -98,7 +426,22 @@ func (jr *JobRequest) Validate() error {
 			return NewValidationError("url is required for HTTP jobs")
 		}
 		if jr.Method == "" {
-			jr.Method = "GET" // Default method
+			jr.Method = http.MethodGet // Default method
+		}
+		jr.Method = strings.ToUpper(jr.Method)
+		if !httpMethods[jr.Method] {
+			return NewValidationError("unsupported HTTP method: " + jr.Method)
+		}
+		if jr.HTTPTimeout != "" {
+			timeout, err := time.ParseDuration(jr.HTTPTimeout)
+			if err != nil || timeout <= 0 {
+				return NewValidationError("invalid http_timeout: " + jr.HTTPTimeout)
+			}
+		}
+		if jr.ResponsePath != "" {
+			if _, err := ParseResponsePath(jr.ResponsePath); err != nil {
+				return err
+			}
 		}
 	case JobTypeFile:
 		if jr.FilePath == "" {
@@ -108,30 +451,145 @@ func (jr *JobRequest) Validate() error {
 		return NewValidationError("unsupported job type: " + string(jr.Type))
 	}
 
+	switch jr.Scheduling {
+	case "", SchedulingModeQueue, SchedulingModeImmediate:
+	default:
+		return NewValidationError("unsupported scheduling_mode: " + string(jr.Scheduling))
+	}
+
+	switch jr.LogOverflow {
+	case "", LogOverflowBlock, LogOverflowDrop:
+	default:
+		return NewValidationError("unsupported log_overflow policy: " + string(jr.LogOverflow))
+	}
+
+	if jr.Schedule != "" {
+		if _, err := ParseSchedule(jr.Schedule, jr.Timezone); err != nil {
+			return err
+		}
+	} else if jr.Timezone != "" {
+		return NewValidationError("timezone is only supported with a schedule")
+	}
+
+	for _, dependency := range jr.DependsOn {
+		if dependency == "" {
+			return NewValidationError("depends_on cannot contain empty job IDs")
+		}
+	}
+
+	for key := range jr.NodeSelector {
+		if key == "" {
+			return NewValidationError("node_selector cannot contain empty label names")
+		}
+	}
+
+	if (jr.Body != "" || jr.HTTPTimeout != "") && jr.Type != JobTypeHTTP {
+		return NewValidationError("body and http_timeout are only supported for HTTP jobs")
+	}
+	if jr.FileContent != "" && jr.Type != JobTypeFile {
+		return NewValidationError("file_content is only supported for file jobs")
+	}
+
+	for _, name := range jr.Secrets {
+		if name == "" || strings.ContainsAny(name, "= ") {
+			return NewValidationError("invalid secret name: " + name)
+		}
+		if _, exists := jr.Environment[name]; exists {
+			return NewValidationError("secret " + name + " cannot also be set in environment")
+		}
+	}
+	if len(jr.Secrets) > 0 && jr.Type == JobTypeFile {
+		return NewValidationError("secrets are not supported for file jobs")
+	}
+
+	if jr.Shell && jr.Type != JobTypeCommand {
+		return NewValidationError("shell is only supported for command jobs")
+	}
+
+	if jr.Retries != nil && *jr.Retries < 0 {
+		return NewValidationError("retries cannot be negative")
+	}
+
+	if jr.RetryPolicy != nil {
+		if err := jr.RetryPolicy.Validate(); err != nil {
+			return err
+		}
+		if retries, ok := jr.RetryPolicy.Retries(); ok && jr.Retries != nil && retries != *jr.Retries {
+			return NewValidationError("retries and retry_policy max_attempts disagree")
+		}
+	}
+
+	if jr.Cost < 0 {
+		return NewValidationError("cost cannot be negative")
+	}
+
+	if err := ValidateTenant(jr.Tenant); err != nil {
+		return err
+	}
+
+	// Zero leaves the priority to the default
+	if jr.Priority != 0 {
+		if err := ValidatePriority(jr.Priority); err != nil {
+			return err
+		}
+	}
+
+	if jr.WorkingDir != "" && !filepath.IsAbs(jr.WorkingDir) {
+		return NewValidationError("working_dir must be an absolute path")
+	}
+
 	return nil
 }
 
 // ToJob converts a JobRequest to a Job with generated ID and timestamps
 func (jr *JobRequest) ToJob() (*Job, error) {
+	return jr.ToJobWithGenerator(DefaultIDGenerator)
+}
+
+// ToJobWithGenerator converts a JobRequest to a Job, taking its ID from the given generator
+func (jr *JobRequest) ToJobWithGenerator(ids IDGenerator) (*Job, error) {
 	if err := jr.Validate(); err != nil {
 		return nil, err
 	}
 
 	job := &Job{
-		ID:          GenerateJobID(),
-		Type:        jr.Type,
-		Command:     jr.Command,
-		Script:      jr.Script,
-		URL:         jr.URL,
-		Method:      jr.Method,
-		FilePath:    jr.FilePath,
-		Retries:     jr.Retries,
-		Priority:    jr.Priority,
-		Tags:        jr.Tags,
-		Environment: jr.Environment,
-		Status:      JobStatusPending,
-		CreatedAt:   time.Now(),
+		ID:           ids.NewID(),
+		Type:         jr.Type,
+		Command:      jr.Command,
+		Shell:        jr.Shell,
+		Steps:        jr.Steps,
+		StepRetry:    jr.StepRetry,
+		Script:       jr.Script,
+		URL:          jr.URL,
+		Method:       jr.Method,
+		Body:         jr.Body,
+		ResponsePath: jr.ResponsePath,
+		FilePath:     jr.FilePath,
+		FileContent:  jr.FileContent,
+		WorkingDir:   jr.WorkingDir,
+		Priority:     jr.Priority,
+		Cost:         jr.Cost,
+		Tags:         jr.Tags,
+		Environment:  jr.Environment,
+		Secrets:      jr.Secrets,
+		Interpolate:  jr.Interpolate,
+		Annotations:  jr.Annotations,
+		MutexKey:     jr.MutexKey,
+		AffinityKey:  jr.AffinityKey,
+		NodeSelector: jr.NodeSelector,
+		DependsOn:    jr.DependsOn,
+		Schedule:     jr.Schedule,
+		Timezone:     jr.Timezone,
+		ParentID:     jr.ParentID,
+		Scheduling:   jr.Scheduling,
+		Durable:      jr.Durable,
+		LogOverflow:  jr.LogOverflow,
+		RetryPolicy:  jr.RetryPolicy,
+		Tenant:       jr.Tenant,
+		Status:       JobStatusPending,
+		CreatedAt:    time.Now().UTC(),
 	}
+	job.recordEvent("", job.CreatedAt)
 
 	// Parse timeout
 	if jr.Timeout != "" {
@@ -144,10 +602,24 @@ func (jr *JobRequest) ToJob() (*Job, error) {
 		job.Timeout = 5 * time.Minute // Default timeout
 	}
 
+	if jr.HTTPTimeout != "" {
+		job.HTTPTimeout, _ = time.ParseDuration(jr.HTTPTimeout) // Checked by Validate
+	}
+
+	if jr.Retries != nil {
+		job.Retries = *jr.Retries
+	} else if jr.RetryPolicy != nil {
+		job.Retries, _ = jr.RetryPolicy.Retries()
+	}
+
 	// Set default priority if not specified
 	if job.Priority == 0 {
 		job.Priority = 1
 	}
 
+	if job.Cost == 0 {
+		job.Cost = 1
+	}
+
 	return job, nil
-} 
\ No newline at end of file
+}
@@ -1,11 +1,14 @@
 package job
 
 import (
+	"encoding/json"
+	"strings"
 	"testing"
 	"time"
 )
 
 func TestJobRequest_Validate(t *testing.T) {
+	two, three := 2, 3
 	tests := []struct {
 		name    string
 		request JobRequest
@@ -35,6 +38,68 @@ func TestJobRequest_Validate(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "negative priority",
+			request: JobRequest{
+				Type:     JobTypeCommand,
+				Command:  "true",
+				Priority: -1,
+			},
+			wantErr: true,
+		},
+		{
+			name: "priority above maximum",
+			request: JobRequest{
+				Type:     JobTypeCommand,
+				Command:  "true",
+				Priority: MaxPriority + 1,
+			},
+			wantErr: true,
+		},
+		{
+			name:    "valid tenant",
+			request: JobRequest{Type: JobTypeCommand, Command: "true", Tenant: "team-a.research_1"},
+			wantErr: false,
+		},
+		{
+			name:    "tenant with invalid characters",
+			request: JobRequest{Type: JobTypeCommand, Command: "true", Tenant: "team a/{x}"},
+			wantErr: true,
+		},
+		{
+			name:    "tenant too long",
+			request: JobRequest{Type: JobTypeCommand, Command: "true", Tenant: strings.Repeat("t", MaxTenantLength+1)},
+			wantErr: true,
+		},
+		{
+			name: "retry policy multiplier below 1",
+			request: JobRequest{
+				Type:        JobTypeCommand,
+				Command:     "true",
+				RetryPolicy: &RetryPolicy{Multiplier: 0.5},
+			},
+			wantErr: true,
+		},
+		{
+			name: "retry policy max attempts one more than retries",
+			request: JobRequest{
+				Type:        JobTypeCommand,
+				Command:     "true",
+				Retries:     &two,
+				RetryPolicy: &RetryPolicy{MaxAttempts: &three},
+			},
+			wantErr: false,
+		},
+		{
+			name: "retry policy max attempts disagree with retries",
+			request: JobRequest{
+				Type:        JobTypeCommand,
+				Command:     "true",
+				Retries:     &three,
+				RetryPolicy: &RetryPolicy{MaxAttempts: &three},
+			},
+			wantErr: true,
+		},
 		{
 			name: "empty type",
 			request: JobRequest{
@@ -56,6 +121,15 @@ func TestJobRequest_Validate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "relative working directory",
+			request: JobRequest{
+				Type:       JobTypeCommand,
+				Command:    "ls",
+				WorkingDir: "checkout",
+			},
+			wantErr: true,
+		},
 		{
 			name: "HTTP job without URL",
 			request: JobRequest{
@@ -63,6 +137,109 @@ func TestJobRequest_Validate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "HTTP job with response path",
+			request: JobRequest{
+				Type:         JobTypeHTTP,
+				URL:          "https://example.com",
+				ResponsePath: "$.data.items[0].id",
+			},
+			wantErr: false,
+		},
+		{
+			name: "HTTP job with malformed response path",
+			request: JobRequest{
+				Type:         JobTypeHTTP,
+				URL:          "https://example.com",
+				ResponsePath: "$.items[first]",
+			},
+			wantErr: true,
+		},
+		{
+			name: "HTTP POST with a body and timeout",
+			request: JobRequest{
+				Type:        JobTypeHTTP,
+				URL:         "https://example.com",
+				Method:      "post",
+				Body:        `{"id": 1}`,
+				HTTPTimeout: "10s",
+			},
+			wantErr: false,
+		},
+		{
+			name: "HTTP job with unknown method",
+			request: JobRequest{
+				Type:   JobTypeHTTP,
+				URL:    "https://example.com",
+				Method: "FETCH",
+			},
+			wantErr: true,
+		},
+		{
+			name: "HTTP job with invalid timeout",
+			request: JobRequest{
+				Type:        JobTypeHTTP,
+				URL:         "https://example.com",
+				HTTPTimeout: "soon",
+			},
+			wantErr: true,
+		},
+		{
+			name: "body on a command job",
+			request: JobRequest{
+				Type:    JobTypeCommand,
+				Command: "ls",
+				Body:    "payload",
+			},
+			wantErr: true,
+		},
+		{
+			name: "secret also set in environment",
+			request: JobRequest{
+				Type:        JobTypeCommand,
+				Command:     "deploy",
+				Environment: map[string]string{"TOKEN": "plain"},
+				Secrets:     []string{"TOKEN"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "recurring job",
+			request: JobRequest{
+				Type:     JobTypeCommand,
+				Command:  "backup",
+				Schedule: "0 */5 * * *",
+				Timezone: "Europe/Berlin",
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid schedule",
+			request: JobRequest{
+				Type:     JobTypeCommand,
+				Command:  "backup",
+				Schedule: "every five minutes",
+			},
+			wantErr: true,
+		},
+		{
+			name: "timezone without schedule",
+			request: JobRequest{
+				Type:     JobTypeCommand,
+				Command:  "backup",
+				Timezone: "UTC",
+			},
+			wantErr: true,
+		},
+		{
+			name: "file content on a command job",
+			request: JobRequest{
+				Type:        JobTypeCommand,
+				Command:     "backup",
+				FileContent: "data",
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -76,12 +253,13 @@ func TestJobRequest_Validate(t *testing.T) {
 }
 
 func TestJobRequest_ToJob(t *testing.T) {
+	retries := 3
 	request := JobRequest{
 		Type:     JobTypeCommand,
 		Command:  "echo 'hello'",
 		Timeout:  "5m",
 		Priority: 2,
-		Retries:  3,
+		Retries:  &retries,
 		Tags:     []string{"test", "example"},
 	}
 
@@ -151,6 +329,30 @@ func TestJob_UpdateStatus(t *testing.T) {
 	if err == nil {
 		t.Error("Expected error when transitioning from terminal state")
 	}
+
+	// Only the successful transition is recorded
+	if len(job.Events) != 1 || job.Events[0].Status != JobStatusQueued {
+		t.Errorf("Expected a single queued event, got %+v", job.Events)
+	}
+}
+
+func TestJob_EventsNotShared(t *testing.T) {
+	original := &Job{ID: "test-job", Status: JobStatusPending}
+	if err := original.UpdateStatus(JobStatusQueued); err != nil {
+		t.Fatalf("UpdateStatus() error = %v", err)
+	}
+
+	copied := *original
+	if err := original.UpdateStatus(JobStatusRunning); err != nil {
+		t.Fatalf("UpdateStatus() error = %v", err)
+	}
+	if err := copied.UpdateStatus(JobStatusCancelled); err != nil {
+		t.Fatalf("UpdateStatus() error = %v", err)
+	}
+
+	if original.Events[1].Status != JobStatusRunning || copied.Events[1].Status != JobStatusCancelled {
+		t.Errorf("Expected each copy to keep its own events, got %+v and %+v", original.Events, copied.Events)
+	}
 }
 
 func TestJob_StatusMethods(t *testing.T) {
@@ -199,3 +401,149 @@ func TestGenerateJobID(t *testing.T) {
 		t.Error("Expected job ID to have reasonable length")
 	}
 }
+
+func TestJob_RecordStepProgress(t *testing.T) {
+	failedAtSecond := &JobResult{
+		Status: JobStatusFailed,
+		Steps: []StepResult{
+			{Index: 0, ExitCode: 0},
+			{Index: 1, ExitCode: 1},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		mode   StepRetryMode
+		result *JobResult
+		want   int
+	}{
+		{name: "resume by default", result: failedAtSecond, want: 1},
+		{name: "explicit resume", mode: StepRetryResume, result: failedAtSecond, want: 1},
+		{name: "restart", mode: StepRetryRestart, result: failedAtSecond, want: 0},
+		{name: "completed", result: &JobResult{Status: JobStatusCompleted}, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j := &Job{Steps: []string{"a", "b", "c"}, StepRetry: tt.mode}
+			j.RecordStepProgress(tt.result)
+			if j.ResumeStep != tt.want {
+				t.Errorf("Expected resume step %d, got %d", tt.want, j.ResumeStep)
+			}
+		})
+	}
+}
+
+func TestJobRequest_ToJobTimestampsAreUTC(t *testing.T) {
+	request := JobRequest{Type: JobTypeCommand, Command: "true"}
+
+	j, err := request.ToJob()
+	if err != nil {
+		t.Fatalf("JobRequest.ToJob() error = %v", err)
+	}
+	if j.CreatedAt.Location() != time.UTC {
+		t.Errorf("Expected created_at in UTC, got %v", j.CreatedAt.Location())
+	}
+
+	j.UpdateStatus(JobStatusQueued)
+	j.UpdateStatus(JobStatusRunning)
+	if j.StartedAt.Location() != time.UTC {
+		t.Errorf("Expected started_at in UTC, got %v", j.StartedAt.Location())
+	}
+}
+
+func TestRetryPolicy_JSON(t *testing.T) {
+	attempts := 4
+	policy := RetryPolicy{BaseDelay: 30 * time.Second, MaxDelay: 10 * time.Minute, Multiplier: 3, Jitter: 0.5, MaxAttempts: &attempts}
+	data, err := json.Marshal(policy)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	want := `{"base_delay":"30s","max_delay":"10m0s","multiplier":3,"jitter":0.5,"max_attempts":4}`
+	if string(data) != want {
+		t.Errorf("Expected %s, got %s", want, data)
+	}
+
+	var decoded RetryPolicy
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if decoded.BaseDelay != policy.BaseDelay || decoded.MaxDelay != policy.MaxDelay || decoded.Multiplier != 3 ||
+		decoded.Jitter != 0.5 || decoded.MaxAttempts == nil || *decoded.MaxAttempts != 4 {
+		t.Errorf("Expected the policy to round-trip, got %+v", decoded)
+	}
+
+	for _, bad := range []string{`{"base_delay":"soon"}`, `{"base_delay":30}`, `{"backoff":"1s"}`} {
+		if err := json.Unmarshal([]byte(bad), &decoded); err == nil {
+			t.Errorf("Expected %s to be rejected", bad)
+		}
+	}
+}
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	fixed := RetryPolicy{BaseDelay: 10 * time.Second, MaxDelay: time.Minute}
+	for retry, want := range map[int]time.Duration{1: 10 * time.Second, 2: 20 * time.Second, 3: 40 * time.Second, 4: time.Minute, 60: time.Minute} {
+		if got := fixed.Delay(retry, 0.99); got != want {
+			t.Errorf("Delay(%d) = %v, want %v", retry, got, want)
+		}
+	}
+
+	// Full jitter spreads the delay from zero up to the backoff
+	full := RetryPolicy{BaseDelay: 10 * time.Second, Multiplier: 3, Jitter: 1}
+	if got := full.Delay(2, 0); got != 0 {
+		t.Errorf("Expected no delay at the bottom of the range, got %v", got)
+	}
+	if got := full.Delay(2, 0.5); got != 15*time.Second {
+		t.Errorf("Expected half the 30s backoff, got %v", got)
+	}
+
+	// Half jitter keeps the first half of the backoff fixed
+	half := RetryPolicy{BaseDelay: 10 * time.Second, Jitter: 0.5}
+	if got := half.Delay(1, 0); got != 5*time.Second {
+		t.Errorf("Expected the fixed half of the backoff, got %v", got)
+	}
+
+	unbounded := RetryPolicy{BaseDelay: time.Hour}
+	if got := unbounded.Delay(1000, 0); got <= 0 {
+		t.Errorf("Expected a huge retry count to stay positive, got %v", got)
+	}
+}
+
+func TestJobAttempt_UnmarshalLegacyKey(t *testing.T) {
+	recorded := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "recorded_at", data: `{"status":"failed","error":"exit status 1","recorded_at":"2024-03-01T12:00:00Z"}`},
+		{name: "requeued_at", data: `{"status":"failed","error":"exit status 1","requeued_at":"2024-03-01T12:00:00Z"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempt JobAttempt
+			if err := json.Unmarshal([]byte(tt.data), &attempt); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if !attempt.RecordedAt.Equal(recorded) || attempt.Status != JobStatusFailed || attempt.Error != "exit status 1" {
+				t.Errorf("Expected the attempt recorded at %v, got %+v", recorded, attempt)
+			}
+		})
+	}
+}
+
+func TestTruncatedKeyMatches(t *testing.T) {
+	for name, value := range map[string]interface{}{
+		"job":    &Job{Truncated: true},
+		"result": &JobResult{Truncated: true},
+	} {
+		data, err := json.Marshal(value)
+		if err != nil {
+			t.Fatalf("%s: Marshal() error = %v", name, err)
+		}
+		if !strings.Contains(string(data), `"output_truncated":true`) {
+			t.Errorf("%s: expected output_truncated, got %s", name, data)
+		}
+	}
+}
@@ -3,20 +3,40 @@ package job
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"strings"
+	"syscall"
 	"time"
 )
 
+// IDGenerator produces unique job IDs
+type IDGenerator interface {
+	// NewID returns a new unique job ID
+	NewID() string
+}
+
+// IDGeneratorFunc adapts an ordinary function to the IDGenerator interface
+type IDGeneratorFunc func() string
+
+// NewID calls f()
+func (f IDGeneratorFunc) NewID() string {
+	return f()
+}
+
+// DefaultIDGenerator generates IDs with GenerateJobID
+var DefaultIDGenerator IDGenerator = IDGeneratorFunc(GenerateJobID)
+
 // GenerateJobID generates a unique job ID
 func GenerateJobID() string {
 	// Generate timestamp prefix
 	timestamp := time.Now().Unix()
-	
+
 	// Generate random suffix
 	randomBytes := make([]byte, 4)
 	rand.Read(randomBytes)
 	randomHex := hex.EncodeToString(randomBytes)
-	
+
 	return fmt.Sprintf("job-%d-%s", timestamp, randomHex)
 }
 
@@ -40,6 +60,47 @@ func IsValidationError(err error) bool {
 	return ok
 }
 
+// ErrQueueEmpty is returned when dequeuing from or peeking at an empty queue
+var ErrQueueEmpty = errors.New("queue is empty")
+
+// ErrNoWorkerAvailable is returned when no registered worker can accept a job
+var ErrNoWorkerAvailable = errors.New("no worker available")
+
+// ErrWorkerLimitReached is returned when registering a worker would exceed
+// the registry's configured maximum
+var ErrWorkerLimitReached = errors.New("worker limit reached")
+
+// MatchesSelector reports whether labels contain every key/value pair in selector.
+// An empty selector matches any labels.
+func MatchesSelector(labels, selector map[string]string) bool {
+	for key, value := range selector {
+		if labels[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// ConflictError represents an operation that conflicts with a job's current state
+type ConflictError struct {
+	Message string
+}
+
+func (e ConflictError) Error() string {
+	return e.Message
+}
+
+// NewConflictError creates a new conflict error
+func NewConflictError(message string) error {
+	return ConflictError{Message: message}
+}
+
+// IsConflictError checks if an error is a conflict error
+func IsConflictError(err error) bool {
+	_, ok := err.(ConflictError)
+	return ok
+}
+
 // JobNotFoundError represents a job not found error
 type JobNotFoundError struct {
 	JobID string
@@ -137,16 +198,68 @@ func IsTimeoutError(err error) bool {
 	return ok
 }
 
+// signalsByName lists the signals that may be sent to a running job
+var signalsByName = map[string]syscall.Signal{
+	"SIGHUP":  syscall.SIGHUP,
+	"SIGINT":  syscall.SIGINT,
+	"SIGQUIT": syscall.SIGQUIT,
+	"SIGKILL": syscall.SIGKILL,
+	"SIGUSR1": syscall.SIGUSR1,
+	"SIGUSR2": syscall.SIGUSR2,
+	"SIGTERM": syscall.SIGTERM,
+	"SIGCONT": syscall.SIGCONT,
+	"SIGSTOP": syscall.SIGSTOP,
+}
+
+// ParseSignal resolves a signal name such as "SIGHUP" or "hup" to a signal
+// that may be sent to a running job
+func ParseSignal(name string) (syscall.Signal, error) {
+	normalized := strings.ToUpper(strings.TrimSpace(name))
+	if !strings.HasPrefix(normalized, "SIG") {
+		normalized = "SIG" + normalized
+	}
+
+	sig, ok := signalsByName[normalized]
+	if !ok {
+		return 0, NewValidationError("unsupported signal: " + name)
+	}
+	return sig, nil
+}
+
+// IsValid reports whether the status is one a job can be in
+func (s JobStatus) IsValid() bool {
+	switch s {
+	case JobStatusPending, JobStatusQueued, JobStatusRunning, JobStatusRetrying,
+		JobStatusCompleted, JobStatusFailed, JobStatusCancelled:
+		return true
+	default:
+		return false
+	}
+}
+
+// CanRetry reports whether a failed run of the job has retries left
+func (j *Job) CanRetry() bool {
+	return j.RetryCount < j.Retries
+}
+
+// CanBeSignaled reports whether the job runs as an OS process that can receive signals
+func (j *Job) CanBeSignaled() bool {
+	return j.Type == JobTypeCommand || j.Type == JobTypeScript
+}
+
 // Helper functions for job status transitions
 func (j *Job) CanTransitionTo(newStatus JobStatus) bool {
 	switch j.Status {
 	case JobStatusPending:
-		return newStatus == JobStatusQueued || newStatus == JobStatusCancelled
+		return newStatus == JobStatusQueued || newStatus == JobStatusCancelled ||
+			newStatus == JobStatusFailed // A dependency failed or was cancelled
 	case JobStatusQueued:
-		return newStatus == JobStatusRunning || newStatus == JobStatusCancelled
+		return newStatus == JobStatusRunning || newStatus == JobStatusCancelled ||
+			newStatus == JobStatusFailed // A dependency failed or was cancelled
 	case JobStatusRunning:
-		return newStatus == JobStatusCompleted || newStatus == JobStatusFailed || 
-			   newStatus == JobStatusCancelled || newStatus == JobStatusRetrying
+		return newStatus == JobStatusCompleted || newStatus == JobStatusFailed ||
+			newStatus == JobStatusCancelled || newStatus == JobStatusRetrying ||
+			newStatus == JobStatusQueued // Requeued when its worker goes away
 	case JobStatusRetrying:
 		return newStatus == JobStatusQueued || newStatus == JobStatusFailed || newStatus == JobStatusCancelled
 	case JobStatusCompleted, JobStatusFailed, JobStatusCancelled:
@@ -161,11 +274,16 @@ func (j *Job) UpdateStatus(newStatus JobStatus) error {
 	if !j.CanTransitionTo(newStatus) {
 		return NewValidationError(fmt.Sprintf("cannot transition from %s to %s", j.Status, newStatus))
 	}
-	
+
 	j.Status = newStatus
-	
+
 	// Update timestamps based on status
-	now := time.Now()
+	now := time.Now().UTC()
+	message := ""
+	if newStatus == JobStatusFailed || newStatus == JobStatusRetrying {
+		message = j.Error
+	}
+	j.recordEvent(message, now)
 	switch newStatus {
 	case JobStatusRunning:
 		if j.StartedAt == nil {
@@ -175,22 +293,141 @@ func (j *Job) UpdateStatus(newStatus JobStatus) error {
 		if j.CompletedAt == nil {
 			j.CompletedAt = &now
 		}
+		if newStatus == JobStatusCompleted {
+			j.Progress = 100
+		}
+	}
+
+	return nil
+}
+
+// recordEvent appends an event for the job's current status. The events are
+// copied rather than appended in place, as copies of a job share the slice
+// and an append to one must not show up in another.
+func (j *Job) recordEvent(message string, at time.Time) {
+	events := make([]JobEvent, len(j.Events), len(j.Events)+1)
+	copy(events, j.Events)
+	j.Events = append(events, JobEvent{Status: j.Status, Timestamp: at, Message: message, WorkerID: j.WorkerID})
+}
+
+// RecordStepProgress sets the step a retry of a multi-step job starts from,
+// based on the result of the previous attempt and the job's retry mode
+func (j *Job) RecordStepProgress(result *JobResult) {
+	if len(j.Steps) == 0 || result == nil {
+		return
+	}
+
+	if j.StepRetry == StepRetryRestart || result.Status == JobStatusCompleted || len(result.Steps) == 0 {
+		j.ResumeStep = 0
+		return
+	}
+
+	// The last recorded step is the one that failed
+	j.ResumeStep = result.Steps[len(result.Steps)-1].Index
+}
+
+// Requeue returns a running job to the queue after its worker went away,
+// clearing the assignment so it can be dispatched again
+func (j *Job) Requeue() error {
+	if err := j.UpdateStatus(JobStatusQueued); err != nil {
+		return err
+	}
+
+	j.WorkerID = ""
+	j.StartedAt = nil
+	j.RetryAt = nil
+	return nil
+}
+
+// Reset returns a failed job to the queue under the same ID, recording the
+// failed run in its history and clearing the fields a new run will set
+func (j *Job) Reset() error {
+	if j.Status != JobStatusFailed {
+		return NewValidationError(fmt.Sprintf("cannot reset job in status %s, only failed jobs can be requeued", j.Status))
+	}
+
+	j.History = append(j.History, JobAttempt{
+		Status:      j.Status,
+		WorkerID:    j.WorkerID,
+		Error:       j.Error,
+		ExitCode:    j.ExitCode,
+		Termination: j.Termination,
+		StartedAt:   j.StartedAt,
+		CompletedAt: j.CompletedAt,
+		RecordedAt:  time.Now().UTC(),
+	})
+
+	j.Status = JobStatusQueued
+	j.WorkerID = ""
+	j.recordEvent("requeued after a failed run", time.Now().UTC())
+	j.StartedAt = nil
+	j.CompletedAt = nil
+	j.Output = ""
+	j.OutputBytes = 0
+	j.OutputLines = 0
+	j.Truncated = false
+	j.LogDropped = 0
+	j.Error = ""
+	j.ExitCode = 0
+	j.Termination = ""
+	j.LogFile = ""
+	j.Progress = 0
+	j.ResumeStep = 0
+	j.RetryCount = 0
+	return nil
+}
+
+// Abandon moves a job that can no longer run because of one of its
+// dependencies to the given status, recording the reason in its history
+func (j *Job) Abandon(status JobStatus, reason string) error {
+	if err := j.UpdateStatus(status); err != nil {
+		return err
 	}
-	
+
+	j.Error = reason
+	j.Events[len(j.Events)-1].Message = reason
+	j.History = append(j.History, JobAttempt{
+		Status:     status,
+		Reason:     reason,
+		RecordedAt: time.Now().UTC(),
+	})
 	return nil
 }
 
+// NormalizeTimestamps converts the job's timestamps to UTC so stored and
+// emitted times never depend on the host's local zone
+func (j *Job) NormalizeTimestamps() {
+	j.CreatedAt = j.CreatedAt.UTC()
+	if j.StartedAt != nil {
+		startedAt := j.StartedAt.UTC()
+		j.StartedAt = &startedAt
+	}
+	if j.CompletedAt != nil {
+		completedAt := j.CompletedAt.UTC()
+		j.CompletedAt = &completedAt
+	}
+}
+
+// Weight returns the share of a worker's capacity the job uses, treating an
+// unset cost as 1
+func (j *Job) Weight() int {
+	if j.Cost <= 0 {
+		return 1
+	}
+	return j.Cost
+}
+
 // GetDuration returns the duration of the job execution
 func (j *Job) GetDuration() time.Duration {
 	if j.StartedAt == nil {
 		return 0
 	}
-	
+
 	endTime := time.Now()
 	if j.CompletedAt != nil {
 		endTime = *j.CompletedAt
 	}
-	
+
 	return endTime.Sub(*j.StartedAt)
 }
 
@@ -204,7 +441,58 @@ func (j *Job) IsRunning() bool {
 	return j.Status == JobStatusRunning
 }
 
+// IsRecurring returns true if the job is a cron schedule that submits runs
+// rather than running itself
+func (j *Job) IsRecurring() bool {
+	return j.Schedule != ""
+}
+
+// RunRequest builds the request that submits one run of a recurring job: a
+// copy of the job without its schedule, pointing back at it
+func (j *Job) RunRequest() *JobRequest {
+	retries := j.Retries
+	var httpTimeout string
+	if j.HTTPTimeout > 0 {
+		httpTimeout = j.HTTPTimeout.String()
+	}
+	return &JobRequest{
+		Type:         j.Type,
+		Command:      j.Command,
+		Shell:        j.Shell,
+		Steps:        j.Steps,
+		StepRetry:    j.StepRetry,
+		Script:       j.Script,
+		URL:          j.URL,
+		Method:       j.Method,
+		Body:         j.Body,
+		HTTPTimeout:  httpTimeout,
+		ResponsePath: j.ResponsePath,
+		FilePath:     j.FilePath,
+		FileContent:  j.FileContent,
+		WorkingDir:   j.WorkingDir,
+		Timeout:      j.Timeout.String(),
+		Retries:      &retries,
+		RetryPolicy:  j.RetryPolicy,
+		Priority:     j.Priority,
+		Cost:         j.Cost,
+		Tags:         j.Tags,
+		Tenant:       j.Tenant,
+		Environment:  j.Environment,
+		Secrets:      j.Secrets,
+		Interpolate:  j.Interpolate,
+		Annotations:  j.Annotations,
+		MutexKey:     j.MutexKey,
+		AffinityKey:  j.AffinityKey,
+		NodeSelector: j.NodeSelector,
+		DependsOn:    j.DependsOn,
+		ParentID:     j.ID,
+		Scheduling:   j.Scheduling,
+		Durable:      j.Durable,
+		LogOverflow:  j.LogOverflow,
+	}
+}
+
 // IsPending returns true if the job is pending or queued
 func (j *Job) IsPending() bool {
 	return j.Status == JobStatusPending || j.Status == JobStatusQueued
-} 
\ No newline at end of file
+}
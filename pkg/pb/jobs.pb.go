@@ -0,0 +1,1787 @@
+// gRPC interface to the scheduler. It offers the same job operations as the
+// REST API under /api/v1, backed by the same store, manager and workers.
+//
+// Go code is generated into pkg/pb with protoc-gen-go and protoc-gen-go-grpc:
+//
+//   protoc -I proto --go_out=. --go_opt=module=infinitrain \
+//     --go-grpc_out=. --go-grpc_opt=module=infinitrain \
+//     infinitrain/v1/jobs.proto
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.8
+// 	protoc        (unknown)
+// source: infinitrain/v1/jobs.proto
+
+package pb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// JobRequest mirrors the JSON body of POST /api/v1/jobs
+type JobRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Type           string                 `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	Command        string                 `protobuf:"bytes,2,opt,name=command,proto3" json:"command,omitempty"`
+	Shell          bool                   `protobuf:"varint,3,opt,name=shell,proto3" json:"shell,omitempty"`
+	Steps          []string               `protobuf:"bytes,4,rep,name=steps,proto3" json:"steps,omitempty"`
+	StepRetry      string                 `protobuf:"bytes,5,opt,name=step_retry,json=stepRetry,proto3" json:"step_retry,omitempty"`
+	Script         string                 `protobuf:"bytes,6,opt,name=script,proto3" json:"script,omitempty"`
+	Url            string                 `protobuf:"bytes,7,opt,name=url,proto3" json:"url,omitempty"`
+	Method         string                 `protobuf:"bytes,8,opt,name=method,proto3" json:"method,omitempty"`
+	Body           string                 `protobuf:"bytes,9,opt,name=body,proto3" json:"body,omitempty"`
+	HttpTimeout    string                 `protobuf:"bytes,10,opt,name=http_timeout,json=httpTimeout,proto3" json:"http_timeout,omitempty"`
+	ResponsePath   string                 `protobuf:"bytes,11,opt,name=response_path,json=responsePath,proto3" json:"response_path,omitempty"`
+	FilePath       string                 `protobuf:"bytes,12,opt,name=file_path,json=filePath,proto3" json:"file_path,omitempty"`
+	FileContent    string                 `protobuf:"bytes,13,opt,name=file_content,json=fileContent,proto3" json:"file_content,omitempty"`
+	WorkingDir     string                 `protobuf:"bytes,14,opt,name=working_dir,json=workingDir,proto3" json:"working_dir,omitempty"`
+	Timeout        string                 `protobuf:"bytes,15,opt,name=timeout,proto3" json:"timeout,omitempty"`
+	Retries        *int32                 `protobuf:"varint,16,opt,name=retries,proto3,oneof" json:"retries,omitempty"` // Unset uses the scheduler's default for the job type
+	Priority       int32                  `protobuf:"varint,17,opt,name=priority,proto3" json:"priority,omitempty"`
+	Cost           int32                  `protobuf:"varint,18,opt,name=cost,proto3" json:"cost,omitempty"`
+	Tags           []string               `protobuf:"bytes,19,rep,name=tags,proto3" json:"tags,omitempty"`
+	Environment    map[string]string      `protobuf:"bytes,20,rep,name=environment,proto3" json:"environment,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Secrets        []string               `protobuf:"bytes,21,rep,name=secrets,proto3" json:"secrets,omitempty"`
+	Interpolate    bool                   `protobuf:"varint,22,opt,name=interpolate,proto3" json:"interpolate,omitempty"`
+	Annotations    map[string]string      `protobuf:"bytes,23,rep,name=annotations,proto3" json:"annotations,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	MutexKey       string                 `protobuf:"bytes,24,opt,name=mutex_key,json=mutexKey,proto3" json:"mutex_key,omitempty"`
+	AffinityKey    string                 `protobuf:"bytes,25,opt,name=affinity_key,json=affinityKey,proto3" json:"affinity_key,omitempty"`
+	NodeSelector   map[string]string      `protobuf:"bytes,26,rep,name=node_selector,json=nodeSelector,proto3" json:"node_selector,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	DependsOn      []string               `protobuf:"bytes,27,rep,name=depends_on,json=dependsOn,proto3" json:"depends_on,omitempty"`
+	Schedule       string                 `protobuf:"bytes,28,opt,name=schedule,proto3" json:"schedule,omitempty"`
+	Timezone       string                 `protobuf:"bytes,29,opt,name=timezone,proto3" json:"timezone,omitempty"`
+	SchedulingMode string                 `protobuf:"bytes,30,opt,name=scheduling_mode,json=schedulingMode,proto3" json:"scheduling_mode,omitempty"`
+	Durable        bool                   `protobuf:"varint,31,opt,name=durable,proto3" json:"durable,omitempty"`
+	LogOverflow    string                 `protobuf:"bytes,32,opt,name=log_overflow,json=logOverflow,proto3" json:"log_overflow,omitempty"`
+	RetryPolicy    *RetryPolicy           `protobuf:"bytes,33,opt,name=retry_policy,json=retryPolicy,proto3" json:"retry_policy,omitempty"` // Unset uses the scheduler's retry delays
+	Tenant         string                 `protobuf:"bytes,34,opt,name=tenant,proto3" json:"tenant,omitempty"`                              // Who the job runs for; fair-share queues take turns between tenants
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *JobRequest) Reset() {
+	*x = JobRequest{}
+	mi := &file_infinitrain_v1_jobs_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *JobRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*JobRequest) ProtoMessage() {}
+
+func (x *JobRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_infinitrain_v1_jobs_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use JobRequest.ProtoReflect.Descriptor instead.
+func (*JobRequest) Descriptor() ([]byte, []int) {
+	return file_infinitrain_v1_jobs_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *JobRequest) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *JobRequest) GetCommand() string {
+	if x != nil {
+		return x.Command
+	}
+	return ""
+}
+
+func (x *JobRequest) GetShell() bool {
+	if x != nil {
+		return x.Shell
+	}
+	return false
+}
+
+func (x *JobRequest) GetSteps() []string {
+	if x != nil {
+		return x.Steps
+	}
+	return nil
+}
+
+func (x *JobRequest) GetStepRetry() string {
+	if x != nil {
+		return x.StepRetry
+	}
+	return ""
+}
+
+func (x *JobRequest) GetScript() string {
+	if x != nil {
+		return x.Script
+	}
+	return ""
+}
+
+func (x *JobRequest) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *JobRequest) GetMethod() string {
+	if x != nil {
+		return x.Method
+	}
+	return ""
+}
+
+func (x *JobRequest) GetBody() string {
+	if x != nil {
+		return x.Body
+	}
+	return ""
+}
+
+func (x *JobRequest) GetHttpTimeout() string {
+	if x != nil {
+		return x.HttpTimeout
+	}
+	return ""
+}
+
+func (x *JobRequest) GetResponsePath() string {
+	if x != nil {
+		return x.ResponsePath
+	}
+	return ""
+}
+
+func (x *JobRequest) GetFilePath() string {
+	if x != nil {
+		return x.FilePath
+	}
+	return ""
+}
+
+func (x *JobRequest) GetFileContent() string {
+	if x != nil {
+		return x.FileContent
+	}
+	return ""
+}
+
+func (x *JobRequest) GetWorkingDir() string {
+	if x != nil {
+		return x.WorkingDir
+	}
+	return ""
+}
+
+func (x *JobRequest) GetTimeout() string {
+	if x != nil {
+		return x.Timeout
+	}
+	return ""
+}
+
+func (x *JobRequest) GetRetries() int32 {
+	if x != nil && x.Retries != nil {
+		return *x.Retries
+	}
+	return 0
+}
+
+func (x *JobRequest) GetPriority() int32 {
+	if x != nil {
+		return x.Priority
+	}
+	return 0
+}
+
+func (x *JobRequest) GetCost() int32 {
+	if x != nil {
+		return x.Cost
+	}
+	return 0
+}
+
+func (x *JobRequest) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *JobRequest) GetEnvironment() map[string]string {
+	if x != nil {
+		return x.Environment
+	}
+	return nil
+}
+
+func (x *JobRequest) GetSecrets() []string {
+	if x != nil {
+		return x.Secrets
+	}
+	return nil
+}
+
+func (x *JobRequest) GetInterpolate() bool {
+	if x != nil {
+		return x.Interpolate
+	}
+	return false
+}
+
+func (x *JobRequest) GetAnnotations() map[string]string {
+	if x != nil {
+		return x.Annotations
+	}
+	return nil
+}
+
+func (x *JobRequest) GetMutexKey() string {
+	if x != nil {
+		return x.MutexKey
+	}
+	return ""
+}
+
+func (x *JobRequest) GetAffinityKey() string {
+	if x != nil {
+		return x.AffinityKey
+	}
+	return ""
+}
+
+func (x *JobRequest) GetNodeSelector() map[string]string {
+	if x != nil {
+		return x.NodeSelector
+	}
+	return nil
+}
+
+func (x *JobRequest) GetDependsOn() []string {
+	if x != nil {
+		return x.DependsOn
+	}
+	return nil
+}
+
+func (x *JobRequest) GetSchedule() string {
+	if x != nil {
+		return x.Schedule
+	}
+	return ""
+}
+
+func (x *JobRequest) GetTimezone() string {
+	if x != nil {
+		return x.Timezone
+	}
+	return ""
+}
+
+func (x *JobRequest) GetSchedulingMode() string {
+	if x != nil {
+		return x.SchedulingMode
+	}
+	return ""
+}
+
+func (x *JobRequest) GetDurable() bool {
+	if x != nil {
+		return x.Durable
+	}
+	return false
+}
+
+func (x *JobRequest) GetLogOverflow() string {
+	if x != nil {
+		return x.LogOverflow
+	}
+	return ""
+}
+
+func (x *JobRequest) GetRetryPolicy() *RetryPolicy {
+	if x != nil {
+		return x.RetryPolicy
+	}
+	return nil
+}
+
+func (x *JobRequest) GetTenant() string {
+	if x != nil {
+		return x.Tenant
+	}
+	return ""
+}
+
+// RetryPolicy mirrors the retry_policy object of a job request
+type RetryPolicy struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	BaseDelay     *durationpb.Duration   `protobuf:"bytes,1,opt,name=base_delay,json=baseDelay,proto3" json:"base_delay,omitempty"`
+	MaxDelay      *durationpb.Duration   `protobuf:"bytes,2,opt,name=max_delay,json=maxDelay,proto3" json:"max_delay,omitempty"`
+	Multiplier    float64                `protobuf:"fixed64,3,opt,name=multiplier,proto3" json:"multiplier,omitempty"`
+	Jitter        float64                `protobuf:"fixed64,4,opt,name=jitter,proto3" json:"jitter,omitempty"`
+	MaxAttempts   *int32                 `protobuf:"varint,5,opt,name=max_attempts,json=maxAttempts,proto3,oneof" json:"max_attempts,omitempty"` // Most runs of the job, the first included; unset leaves its retries as they are
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RetryPolicy) Reset() {
+	*x = RetryPolicy{}
+	mi := &file_infinitrain_v1_jobs_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RetryPolicy) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RetryPolicy) ProtoMessage() {}
+
+func (x *RetryPolicy) ProtoReflect() protoreflect.Message {
+	mi := &file_infinitrain_v1_jobs_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RetryPolicy.ProtoReflect.Descriptor instead.
+func (*RetryPolicy) Descriptor() ([]byte, []int) {
+	return file_infinitrain_v1_jobs_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *RetryPolicy) GetBaseDelay() *durationpb.Duration {
+	if x != nil {
+		return x.BaseDelay
+	}
+	return nil
+}
+
+func (x *RetryPolicy) GetMaxDelay() *durationpb.Duration {
+	if x != nil {
+		return x.MaxDelay
+	}
+	return nil
+}
+
+func (x *RetryPolicy) GetMultiplier() float64 {
+	if x != nil {
+		return x.Multiplier
+	}
+	return 0
+}
+
+func (x *RetryPolicy) GetJitter() float64 {
+	if x != nil {
+		return x.Jitter
+	}
+	return 0
+}
+
+func (x *RetryPolicy) GetMaxAttempts() int32 {
+	if x != nil && x.MaxAttempts != nil {
+		return *x.MaxAttempts
+	}
+	return 0
+}
+
+// Job mirrors the JSON form of a job returned by the REST API
+type Job struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	Id                string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Type              string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Command           string                 `protobuf:"bytes,3,opt,name=command,proto3" json:"command,omitempty"`
+	Shell             bool                   `protobuf:"varint,4,opt,name=shell,proto3" json:"shell,omitempty"`
+	Steps             []string               `protobuf:"bytes,5,rep,name=steps,proto3" json:"steps,omitempty"`
+	Script            string                 `protobuf:"bytes,6,opt,name=script,proto3" json:"script,omitempty"`
+	Url               string                 `protobuf:"bytes,7,opt,name=url,proto3" json:"url,omitempty"`
+	Method            string                 `protobuf:"bytes,8,opt,name=method,proto3" json:"method,omitempty"`
+	FilePath          string                 `protobuf:"bytes,9,opt,name=file_path,json=filePath,proto3" json:"file_path,omitempty"`
+	WorkingDir        string                 `protobuf:"bytes,10,opt,name=working_dir,json=workingDir,proto3" json:"working_dir,omitempty"`
+	Timeout           *durationpb.Duration   `protobuf:"bytes,11,opt,name=timeout,proto3" json:"timeout,omitempty"`
+	Retries           int32                  `protobuf:"varint,12,opt,name=retries,proto3" json:"retries,omitempty"`
+	RetryCount        int32                  `protobuf:"varint,13,opt,name=retry_count,json=retryCount,proto3" json:"retry_count,omitempty"`
+	Priority          int32                  `protobuf:"varint,14,opt,name=priority,proto3" json:"priority,omitempty"`
+	Cost              int32                  `protobuf:"varint,15,opt,name=cost,proto3" json:"cost,omitempty"`
+	Tags              []string               `protobuf:"bytes,16,rep,name=tags,proto3" json:"tags,omitempty"`
+	Environment       map[string]string      `protobuf:"bytes,17,rep,name=environment,proto3" json:"environment,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Secrets           []string               `protobuf:"bytes,18,rep,name=secrets,proto3" json:"secrets,omitempty"`
+	Annotations       map[string]string      `protobuf:"bytes,19,rep,name=annotations,proto3" json:"annotations,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	NodeSelector      map[string]string      `protobuf:"bytes,20,rep,name=node_selector,json=nodeSelector,proto3" json:"node_selector,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	DependsOn         []string               `protobuf:"bytes,21,rep,name=depends_on,json=dependsOn,proto3" json:"depends_on,omitempty"`
+	Schedule          string                 `protobuf:"bytes,22,opt,name=schedule,proto3" json:"schedule,omitempty"`
+	ParentId          string                 `protobuf:"bytes,23,opt,name=parent_id,json=parentId,proto3" json:"parent_id,omitempty"`
+	WorkerId          string                 `protobuf:"bytes,24,opt,name=worker_id,json=workerId,proto3" json:"worker_id,omitempty"`
+	Status            string                 `protobuf:"bytes,25,opt,name=status,proto3" json:"status,omitempty"`
+	CreatedAt         *timestamppb.Timestamp `protobuf:"bytes,26,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	StartedAt         *timestamppb.Timestamp `protobuf:"bytes,27,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`
+	CompletedAt       *timestamppb.Timestamp `protobuf:"bytes,28,opt,name=completed_at,json=completedAt,proto3" json:"completed_at,omitempty"`
+	Output            string                 `protobuf:"bytes,29,opt,name=output,proto3" json:"output,omitempty"`
+	OutputBytes       int64                  `protobuf:"varint,30,opt,name=output_bytes,json=outputBytes,proto3" json:"output_bytes,omitempty"`
+	OutputTruncated   bool                   `protobuf:"varint,31,opt,name=output_truncated,json=outputTruncated,proto3" json:"output_truncated,omitempty"`
+	Error             string                 `protobuf:"bytes,32,opt,name=error,proto3" json:"error,omitempty"`
+	ExitCode          int32                  `protobuf:"varint,33,opt,name=exit_code,json=exitCode,proto3" json:"exit_code,omitempty"`
+	TerminationReason string                 `protobuf:"bytes,34,opt,name=termination_reason,json=terminationReason,proto3" json:"termination_reason,omitempty"`
+	Progress          int32                  `protobuf:"varint,35,opt,name=progress,proto3" json:"progress,omitempty"`
+	Tenant            string                 `protobuf:"bytes,36,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *Job) Reset() {
+	*x = Job{}
+	mi := &file_infinitrain_v1_jobs_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Job) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Job) ProtoMessage() {}
+
+func (x *Job) ProtoReflect() protoreflect.Message {
+	mi := &file_infinitrain_v1_jobs_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Job.ProtoReflect.Descriptor instead.
+func (*Job) Descriptor() ([]byte, []int) {
+	return file_infinitrain_v1_jobs_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Job) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Job) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Job) GetCommand() string {
+	if x != nil {
+		return x.Command
+	}
+	return ""
+}
+
+func (x *Job) GetShell() bool {
+	if x != nil {
+		return x.Shell
+	}
+	return false
+}
+
+func (x *Job) GetSteps() []string {
+	if x != nil {
+		return x.Steps
+	}
+	return nil
+}
+
+func (x *Job) GetScript() string {
+	if x != nil {
+		return x.Script
+	}
+	return ""
+}
+
+func (x *Job) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *Job) GetMethod() string {
+	if x != nil {
+		return x.Method
+	}
+	return ""
+}
+
+func (x *Job) GetFilePath() string {
+	if x != nil {
+		return x.FilePath
+	}
+	return ""
+}
+
+func (x *Job) GetWorkingDir() string {
+	if x != nil {
+		return x.WorkingDir
+	}
+	return ""
+}
+
+func (x *Job) GetTimeout() *durationpb.Duration {
+	if x != nil {
+		return x.Timeout
+	}
+	return nil
+}
+
+func (x *Job) GetRetries() int32 {
+	if x != nil {
+		return x.Retries
+	}
+	return 0
+}
+
+func (x *Job) GetRetryCount() int32 {
+	if x != nil {
+		return x.RetryCount
+	}
+	return 0
+}
+
+func (x *Job) GetPriority() int32 {
+	if x != nil {
+		return x.Priority
+	}
+	return 0
+}
+
+func (x *Job) GetCost() int32 {
+	if x != nil {
+		return x.Cost
+	}
+	return 0
+}
+
+func (x *Job) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *Job) GetEnvironment() map[string]string {
+	if x != nil {
+		return x.Environment
+	}
+	return nil
+}
+
+func (x *Job) GetSecrets() []string {
+	if x != nil {
+		return x.Secrets
+	}
+	return nil
+}
+
+func (x *Job) GetAnnotations() map[string]string {
+	if x != nil {
+		return x.Annotations
+	}
+	return nil
+}
+
+func (x *Job) GetNodeSelector() map[string]string {
+	if x != nil {
+		return x.NodeSelector
+	}
+	return nil
+}
+
+func (x *Job) GetDependsOn() []string {
+	if x != nil {
+		return x.DependsOn
+	}
+	return nil
+}
+
+func (x *Job) GetSchedule() string {
+	if x != nil {
+		return x.Schedule
+	}
+	return ""
+}
+
+func (x *Job) GetParentId() string {
+	if x != nil {
+		return x.ParentId
+	}
+	return ""
+}
+
+func (x *Job) GetWorkerId() string {
+	if x != nil {
+		return x.WorkerId
+	}
+	return ""
+}
+
+func (x *Job) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Job) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Job) GetStartedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StartedAt
+	}
+	return nil
+}
+
+func (x *Job) GetCompletedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CompletedAt
+	}
+	return nil
+}
+
+func (x *Job) GetOutput() string {
+	if x != nil {
+		return x.Output
+	}
+	return ""
+}
+
+func (x *Job) GetOutputBytes() int64 {
+	if x != nil {
+		return x.OutputBytes
+	}
+	return 0
+}
+
+func (x *Job) GetOutputTruncated() bool {
+	if x != nil {
+		return x.OutputTruncated
+	}
+	return false
+}
+
+func (x *Job) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *Job) GetExitCode() int32 {
+	if x != nil {
+		return x.ExitCode
+	}
+	return 0
+}
+
+func (x *Job) GetTerminationReason() string {
+	if x != nil {
+		return x.TerminationReason
+	}
+	return ""
+}
+
+func (x *Job) GetProgress() int32 {
+	if x != nil {
+		return x.Progress
+	}
+	return 0
+}
+
+func (x *Job) GetTenant() string {
+	if x != nil {
+		return x.Tenant
+	}
+	return ""
+}
+
+// JobResult mirrors the JSON form of a job's result
+type JobResult struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	JobId             string                 `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	Status            string                 `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	Output            string                 `protobuf:"bytes,3,opt,name=output,proto3" json:"output,omitempty"`
+	OutputBytes       int64                  `protobuf:"varint,4,opt,name=output_bytes,json=outputBytes,proto3" json:"output_bytes,omitempty"`
+	OutputLines       int64                  `protobuf:"varint,5,opt,name=output_lines,json=outputLines,proto3" json:"output_lines,omitempty"`
+	OutputTruncated   bool                   `protobuf:"varint,6,opt,name=output_truncated,json=outputTruncated,proto3" json:"output_truncated,omitempty"`
+	Error             string                 `protobuf:"bytes,7,opt,name=error,proto3" json:"error,omitempty"`
+	ExitCode          int32                  `protobuf:"varint,8,opt,name=exit_code,json=exitCode,proto3" json:"exit_code,omitempty"`
+	TerminationReason string                 `protobuf:"bytes,9,opt,name=termination_reason,json=terminationReason,proto3" json:"termination_reason,omitempty"`
+	StartedAt         *timestamppb.Timestamp `protobuf:"bytes,10,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`
+	CompletedAt       *timestamppb.Timestamp `protobuf:"bytes,11,opt,name=completed_at,json=completedAt,proto3" json:"completed_at,omitempty"`
+	Duration          *durationpb.Duration   `protobuf:"bytes,12,opt,name=duration,proto3" json:"duration,omitempty"`
+	Stdout            string                 `protobuf:"bytes,13,opt,name=stdout,proto3" json:"stdout,omitempty"`
+	Stderr            string                 `protobuf:"bytes,14,opt,name=stderr,proto3" json:"stderr,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *JobResult) Reset() {
+	*x = JobResult{}
+	mi := &file_infinitrain_v1_jobs_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *JobResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*JobResult) ProtoMessage() {}
+
+func (x *JobResult) ProtoReflect() protoreflect.Message {
+	mi := &file_infinitrain_v1_jobs_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use JobResult.ProtoReflect.Descriptor instead.
+func (*JobResult) Descriptor() ([]byte, []int) {
+	return file_infinitrain_v1_jobs_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *JobResult) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+func (x *JobResult) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *JobResult) GetOutput() string {
+	if x != nil {
+		return x.Output
+	}
+	return ""
+}
+
+func (x *JobResult) GetOutputBytes() int64 {
+	if x != nil {
+		return x.OutputBytes
+	}
+	return 0
+}
+
+func (x *JobResult) GetOutputLines() int64 {
+	if x != nil {
+		return x.OutputLines
+	}
+	return 0
+}
+
+func (x *JobResult) GetOutputTruncated() bool {
+	if x != nil {
+		return x.OutputTruncated
+	}
+	return false
+}
+
+func (x *JobResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *JobResult) GetExitCode() int32 {
+	if x != nil {
+		return x.ExitCode
+	}
+	return 0
+}
+
+func (x *JobResult) GetTerminationReason() string {
+	if x != nil {
+		return x.TerminationReason
+	}
+	return ""
+}
+
+func (x *JobResult) GetStartedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StartedAt
+	}
+	return nil
+}
+
+func (x *JobResult) GetCompletedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CompletedAt
+	}
+	return nil
+}
+
+func (x *JobResult) GetDuration() *durationpb.Duration {
+	if x != nil {
+		return x.Duration
+	}
+	return nil
+}
+
+func (x *JobResult) GetStdout() string {
+	if x != nil {
+		return x.Stdout
+	}
+	return ""
+}
+
+func (x *JobResult) GetStderr() string {
+	if x != nil {
+		return x.Stderr
+	}
+	return ""
+}
+
+type SubmitJobRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Job           *JobRequest            `protobuf:"bytes,1,opt,name=job,proto3" json:"job,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubmitJobRequest) Reset() {
+	*x = SubmitJobRequest{}
+	mi := &file_infinitrain_v1_jobs_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubmitJobRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubmitJobRequest) ProtoMessage() {}
+
+func (x *SubmitJobRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_infinitrain_v1_jobs_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubmitJobRequest.ProtoReflect.Descriptor instead.
+func (*SubmitJobRequest) Descriptor() ([]byte, []int) {
+	return file_infinitrain_v1_jobs_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *SubmitJobRequest) GetJob() *JobRequest {
+	if x != nil {
+		return x.Job
+	}
+	return nil
+}
+
+type GetJobRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetJobRequest) Reset() {
+	*x = GetJobRequest{}
+	mi := &file_infinitrain_v1_jobs_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetJobRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetJobRequest) ProtoMessage() {}
+
+func (x *GetJobRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_infinitrain_v1_jobs_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetJobRequest.ProtoReflect.Descriptor instead.
+func (*GetJobRequest) Descriptor() ([]byte, []int) {
+	return file_infinitrain_v1_jobs_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *GetJobRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+// ListJobsRequest takes the same filters as GET /api/v1/jobs
+type ListJobsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Status        string                 `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
+	WorkerId      string                 `protobuf:"bytes,2,opt,name=worker_id,json=workerId,proto3" json:"worker_id,omitempty"`
+	Tags          []string               `protobuf:"bytes,3,rep,name=tags,proto3" json:"tags,omitempty"`     // Jobs must carry every tag
+	Limit         int32                  `protobuf:"varint,4,opt,name=limit,proto3" json:"limit,omitempty"`  // Jobs per page; zero returns every match
+	Cursor        string                 `protobuf:"bytes,5,opt,name=cursor,proto3" json:"cursor,omitempty"` // next_cursor of the previous page
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListJobsRequest) Reset() {
+	*x = ListJobsRequest{}
+	mi := &file_infinitrain_v1_jobs_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListJobsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListJobsRequest) ProtoMessage() {}
+
+func (x *ListJobsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_infinitrain_v1_jobs_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListJobsRequest.ProtoReflect.Descriptor instead.
+func (*ListJobsRequest) Descriptor() ([]byte, []int) {
+	return file_infinitrain_v1_jobs_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ListJobsRequest) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *ListJobsRequest) GetWorkerId() string {
+	if x != nil {
+		return x.WorkerId
+	}
+	return ""
+}
+
+func (x *ListJobsRequest) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *ListJobsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *ListJobsRequest) GetCursor() string {
+	if x != nil {
+		return x.Cursor
+	}
+	return ""
+}
+
+type ListJobsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Jobs          []*Job                 `protobuf:"bytes,1,rep,name=jobs,proto3" json:"jobs,omitempty"`
+	Total         int32                  `protobuf:"varint,2,opt,name=total,proto3" json:"total,omitempty"`
+	NextCursor    string                 `protobuf:"bytes,3,opt,name=next_cursor,json=nextCursor,proto3" json:"next_cursor,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListJobsResponse) Reset() {
+	*x = ListJobsResponse{}
+	mi := &file_infinitrain_v1_jobs_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListJobsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListJobsResponse) ProtoMessage() {}
+
+func (x *ListJobsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_infinitrain_v1_jobs_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListJobsResponse.ProtoReflect.Descriptor instead.
+func (*ListJobsResponse) Descriptor() ([]byte, []int) {
+	return file_infinitrain_v1_jobs_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ListJobsResponse) GetJobs() []*Job {
+	if x != nil {
+		return x.Jobs
+	}
+	return nil
+}
+
+func (x *ListJobsResponse) GetTotal() int32 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *ListJobsResponse) GetNextCursor() string {
+	if x != nil {
+		return x.NextCursor
+	}
+	return ""
+}
+
+type CancelJobRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelJobRequest) Reset() {
+	*x = CancelJobRequest{}
+	mi := &file_infinitrain_v1_jobs_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelJobRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelJobRequest) ProtoMessage() {}
+
+func (x *CancelJobRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_infinitrain_v1_jobs_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelJobRequest.ProtoReflect.Descriptor instead.
+func (*CancelJobRequest) Descriptor() ([]byte, []int) {
+	return file_infinitrain_v1_jobs_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *CancelJobRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type CancelJobResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelJobResponse) Reset() {
+	*x = CancelJobResponse{}
+	mi := &file_infinitrain_v1_jobs_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelJobResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelJobResponse) ProtoMessage() {}
+
+func (x *CancelJobResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_infinitrain_v1_jobs_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelJobResponse.ProtoReflect.Descriptor instead.
+func (*CancelJobResponse) Descriptor() ([]byte, []int) {
+	return file_infinitrain_v1_jobs_proto_rawDescGZIP(), []int{9}
+}
+
+type GetJobResultRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	JobId         string                 `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetJobResultRequest) Reset() {
+	*x = GetJobResultRequest{}
+	mi := &file_infinitrain_v1_jobs_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetJobResultRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetJobResultRequest) ProtoMessage() {}
+
+func (x *GetJobResultRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_infinitrain_v1_jobs_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetJobResultRequest.ProtoReflect.Descriptor instead.
+func (*GetJobResultRequest) Descriptor() ([]byte, []int) {
+	return file_infinitrain_v1_jobs_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *GetJobResultRequest) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+type RegisterWorkerRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Capacity      int32                  `protobuf:"varint,2,opt,name=capacity,proto3" json:"capacity,omitempty"`
+	Labels        map[string]string      `protobuf:"bytes,3,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Address       string                 `protobuf:"bytes,4,opt,name=address,proto3" json:"address,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RegisterWorkerRequest) Reset() {
+	*x = RegisterWorkerRequest{}
+	mi := &file_infinitrain_v1_jobs_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RegisterWorkerRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegisterWorkerRequest) ProtoMessage() {}
+
+func (x *RegisterWorkerRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_infinitrain_v1_jobs_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegisterWorkerRequest.ProtoReflect.Descriptor instead.
+func (*RegisterWorkerRequest) Descriptor() ([]byte, []int) {
+	return file_infinitrain_v1_jobs_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *RegisterWorkerRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *RegisterWorkerRequest) GetCapacity() int32 {
+	if x != nil {
+		return x.Capacity
+	}
+	return 0
+}
+
+func (x *RegisterWorkerRequest) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *RegisterWorkerRequest) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+type RegisterWorkerResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Capacity      int32                  `protobuf:"varint,2,opt,name=capacity,proto3" json:"capacity,omitempty"`
+	Labels        map[string]string      `protobuf:"bytes,3,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Address       string                 `protobuf:"bytes,4,opt,name=address,proto3" json:"address,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RegisterWorkerResponse) Reset() {
+	*x = RegisterWorkerResponse{}
+	mi := &file_infinitrain_v1_jobs_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RegisterWorkerResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegisterWorkerResponse) ProtoMessage() {}
+
+func (x *RegisterWorkerResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_infinitrain_v1_jobs_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegisterWorkerResponse.ProtoReflect.Descriptor instead.
+func (*RegisterWorkerResponse) Descriptor() ([]byte, []int) {
+	return file_infinitrain_v1_jobs_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *RegisterWorkerResponse) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *RegisterWorkerResponse) GetCapacity() int32 {
+	if x != nil {
+		return x.Capacity
+	}
+	return 0
+}
+
+func (x *RegisterWorkerResponse) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *RegisterWorkerResponse) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+type WatchJobRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchJobRequest) Reset() {
+	*x = WatchJobRequest{}
+	mi := &file_infinitrain_v1_jobs_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchJobRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchJobRequest) ProtoMessage() {}
+
+func (x *WatchJobRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_infinitrain_v1_jobs_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchJobRequest.ProtoReflect.Descriptor instead.
+func (*WatchJobRequest) Descriptor() ([]byte, []int) {
+	return file_infinitrain_v1_jobs_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *WatchJobRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type JobStatusUpdate struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	JobId         string                 `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	Status        string                 `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	WorkerId      string                 `protobuf:"bytes,3,opt,name=worker_id,json=workerId,proto3" json:"worker_id,omitempty"`
+	Error         string                 `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *JobStatusUpdate) Reset() {
+	*x = JobStatusUpdate{}
+	mi := &file_infinitrain_v1_jobs_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *JobStatusUpdate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*JobStatusUpdate) ProtoMessage() {}
+
+func (x *JobStatusUpdate) ProtoReflect() protoreflect.Message {
+	mi := &file_infinitrain_v1_jobs_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use JobStatusUpdate.ProtoReflect.Descriptor instead.
+func (*JobStatusUpdate) Descriptor() ([]byte, []int) {
+	return file_infinitrain_v1_jobs_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *JobStatusUpdate) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+func (x *JobStatusUpdate) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *JobStatusUpdate) GetWorkerId() string {
+	if x != nil {
+		return x.WorkerId
+	}
+	return ""
+}
+
+func (x *JobStatusUpdate) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+var File_infinitrain_v1_jobs_proto protoreflect.FileDescriptor
+
+const file_infinitrain_v1_jobs_proto_rawDesc = "" +
+	"\n" +
+	"\x19infinitrain/v1/jobs.proto\x12\x0einfinitrain.v1\x1a\x1egoogle/protobuf/duration.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\xd0\n" +
+	"\n" +
+	"\n" +
+	"JobRequest\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12\x18\n" +
+	"\acommand\x18\x02 \x01(\tR\acommand\x12\x14\n" +
+	"\x05shell\x18\x03 \x01(\bR\x05shell\x12\x14\n" +
+	"\x05steps\x18\x04 \x03(\tR\x05steps\x12\x1d\n" +
+	"\n" +
+	"step_retry\x18\x05 \x01(\tR\tstepRetry\x12\x16\n" +
+	"\x06script\x18\x06 \x01(\tR\x06script\x12\x10\n" +
+	"\x03url\x18\a \x01(\tR\x03url\x12\x16\n" +
+	"\x06method\x18\b \x01(\tR\x06method\x12\x12\n" +
+	"\x04body\x18\t \x01(\tR\x04body\x12!\n" +
+	"\fhttp_timeout\x18\n" +
+	" \x01(\tR\vhttpTimeout\x12#\n" +
+	"\rresponse_path\x18\v \x01(\tR\fresponsePath\x12\x1b\n" +
+	"\tfile_path\x18\f \x01(\tR\bfilePath\x12!\n" +
+	"\ffile_content\x18\r \x01(\tR\vfileContent\x12\x1f\n" +
+	"\vworking_dir\x18\x0e \x01(\tR\n" +
+	"workingDir\x12\x18\n" +
+	"\atimeout\x18\x0f \x01(\tR\atimeout\x12\x1d\n" +
+	"\aretries\x18\x10 \x01(\x05H\x00R\aretries\x88\x01\x01\x12\x1a\n" +
+	"\bpriority\x18\x11 \x01(\x05R\bpriority\x12\x12\n" +
+	"\x04cost\x18\x12 \x01(\x05R\x04cost\x12\x12\n" +
+	"\x04tags\x18\x13 \x03(\tR\x04tags\x12M\n" +
+	"\venvironment\x18\x14 \x03(\v2+.infinitrain.v1.JobRequest.EnvironmentEntryR\venvironment\x12\x18\n" +
+	"\asecrets\x18\x15 \x03(\tR\asecrets\x12 \n" +
+	"\vinterpolate\x18\x16 \x01(\bR\vinterpolate\x12M\n" +
+	"\vannotations\x18\x17 \x03(\v2+.infinitrain.v1.JobRequest.AnnotationsEntryR\vannotations\x12\x1b\n" +
+	"\tmutex_key\x18\x18 \x01(\tR\bmutexKey\x12!\n" +
+	"\faffinity_key\x18\x19 \x01(\tR\vaffinityKey\x12Q\n" +
+	"\rnode_selector\x18\x1a \x03(\v2,.infinitrain.v1.JobRequest.NodeSelectorEntryR\fnodeSelector\x12\x1d\n" +
+	"\n" +
+	"depends_on\x18\x1b \x03(\tR\tdependsOn\x12\x1a\n" +
+	"\bschedule\x18\x1c \x01(\tR\bschedule\x12\x1a\n" +
+	"\btimezone\x18\x1d \x01(\tR\btimezone\x12'\n" +
+	"\x0fscheduling_mode\x18\x1e \x01(\tR\x0eschedulingMode\x12\x18\n" +
+	"\adurable\x18\x1f \x01(\bR\adurable\x12!\n" +
+	"\flog_overflow\x18  \x01(\tR\vlogOverflow\x12>\n" +
+	"\fretry_policy\x18! \x01(\v2\x1b.infinitrain.v1.RetryPolicyR\vretryPolicy\x12\x16\n" +
+	"\x06tenant\x18\" \x01(\tR\x06tenant\x1a>\n" +
+	"\x10EnvironmentEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1a>\n" +
+	"\x10AnnotationsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1a?\n" +
+	"\x11NodeSelectorEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01B\n" +
+	"\n" +
+	"\b_retries\"\xf0\x01\n" +
+	"\vRetryPolicy\x128\n" +
+	"\n" +
+	"base_delay\x18\x01 \x01(\v2\x19.google.protobuf.DurationR\tbaseDelay\x126\n" +
+	"\tmax_delay\x18\x02 \x01(\v2\x19.google.protobuf.DurationR\bmaxDelay\x12\x1e\n" +
+	"\n" +
+	"multiplier\x18\x03 \x01(\x01R\n" +
+	"multiplier\x12\x16\n" +
+	"\x06jitter\x18\x04 \x01(\x01R\x06jitter\x12&\n" +
+	"\fmax_attempts\x18\x05 \x01(\x05H\x00R\vmaxAttempts\x88\x01\x01B\x0f\n" +
+	"\r_max_attempts\"\x98\v\n" +
+	"\x03Job\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12\x18\n" +
+	"\acommand\x18\x03 \x01(\tR\acommand\x12\x14\n" +
+	"\x05shell\x18\x04 \x01(\bR\x05shell\x12\x14\n" +
+	"\x05steps\x18\x05 \x03(\tR\x05steps\x12\x16\n" +
+	"\x06script\x18\x06 \x01(\tR\x06script\x12\x10\n" +
+	"\x03url\x18\a \x01(\tR\x03url\x12\x16\n" +
+	"\x06method\x18\b \x01(\tR\x06method\x12\x1b\n" +
+	"\tfile_path\x18\t \x01(\tR\bfilePath\x12\x1f\n" +
+	"\vworking_dir\x18\n" +
+	" \x01(\tR\n" +
+	"workingDir\x123\n" +
+	"\atimeout\x18\v \x01(\v2\x19.google.protobuf.DurationR\atimeout\x12\x18\n" +
+	"\aretries\x18\f \x01(\x05R\aretries\x12\x1f\n" +
+	"\vretry_count\x18\r \x01(\x05R\n" +
+	"retryCount\x12\x1a\n" +
+	"\bpriority\x18\x0e \x01(\x05R\bpriority\x12\x12\n" +
+	"\x04cost\x18\x0f \x01(\x05R\x04cost\x12\x12\n" +
+	"\x04tags\x18\x10 \x03(\tR\x04tags\x12F\n" +
+	"\venvironment\x18\x11 \x03(\v2$.infinitrain.v1.Job.EnvironmentEntryR\venvironment\x12\x18\n" +
+	"\asecrets\x18\x12 \x03(\tR\asecrets\x12F\n" +
+	"\vannotations\x18\x13 \x03(\v2$.infinitrain.v1.Job.AnnotationsEntryR\vannotations\x12J\n" +
+	"\rnode_selector\x18\x14 \x03(\v2%.infinitrain.v1.Job.NodeSelectorEntryR\fnodeSelector\x12\x1d\n" +
+	"\n" +
+	"depends_on\x18\x15 \x03(\tR\tdependsOn\x12\x1a\n" +
+	"\bschedule\x18\x16 \x01(\tR\bschedule\x12\x1b\n" +
+	"\tparent_id\x18\x17 \x01(\tR\bparentId\x12\x1b\n" +
+	"\tworker_id\x18\x18 \x01(\tR\bworkerId\x12\x16\n" +
+	"\x06status\x18\x19 \x01(\tR\x06status\x129\n" +
+	"\n" +
+	"created_at\x18\x1a \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"started_at\x18\x1b \x01(\v2\x1a.google.protobuf.TimestampR\tstartedAt\x12=\n" +
+	"\fcompleted_at\x18\x1c \x01(\v2\x1a.google.protobuf.TimestampR\vcompletedAt\x12\x16\n" +
+	"\x06output\x18\x1d \x01(\tR\x06output\x12!\n" +
+	"\foutput_bytes\x18\x1e \x01(\x03R\voutputBytes\x12)\n" +
+	"\x10output_truncated\x18\x1f \x01(\bR\x0foutputTruncated\x12\x14\n" +
+	"\x05error\x18  \x01(\tR\x05error\x12\x1b\n" +
+	"\texit_code\x18! \x01(\x05R\bexitCode\x12-\n" +
+	"\x12termination_reason\x18\" \x01(\tR\x11terminationReason\x12\x1a\n" +
+	"\bprogress\x18# \x01(\x05R\bprogress\x12\x16\n" +
+	"\x06tenant\x18$ \x01(\tR\x06tenant\x1a>\n" +
+	"\x10EnvironmentEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1a>\n" +
+	"\x10AnnotationsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1a?\n" +
+	"\x11NodeSelectorEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x86\x04\n" +
+	"\tJobResult\x12\x15\n" +
+	"\x06job_id\x18\x01 \x01(\tR\x05jobId\x12\x16\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\x12\x16\n" +
+	"\x06output\x18\x03 \x01(\tR\x06output\x12!\n" +
+	"\foutput_bytes\x18\x04 \x01(\x03R\voutputBytes\x12!\n" +
+	"\foutput_lines\x18\x05 \x01(\x03R\voutputLines\x12)\n" +
+	"\x10output_truncated\x18\x06 \x01(\bR\x0foutputTruncated\x12\x14\n" +
+	"\x05error\x18\a \x01(\tR\x05error\x12\x1b\n" +
+	"\texit_code\x18\b \x01(\x05R\bexitCode\x12-\n" +
+	"\x12termination_reason\x18\t \x01(\tR\x11terminationReason\x129\n" +
+	"\n" +
+	"started_at\x18\n" +
+	" \x01(\v2\x1a.google.protobuf.TimestampR\tstartedAt\x12=\n" +
+	"\fcompleted_at\x18\v \x01(\v2\x1a.google.protobuf.TimestampR\vcompletedAt\x125\n" +
+	"\bduration\x18\f \x01(\v2\x19.google.protobuf.DurationR\bduration\x12\x16\n" +
+	"\x06stdout\x18\r \x01(\tR\x06stdout\x12\x16\n" +
+	"\x06stderr\x18\x0e \x01(\tR\x06stderr\"@\n" +
+	"\x10SubmitJobRequest\x12,\n" +
+	"\x03job\x18\x01 \x01(\v2\x1a.infinitrain.v1.JobRequestR\x03job\"\x1f\n" +
+	"\rGetJobRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\x88\x01\n" +
+	"\x0fListJobsRequest\x12\x16\n" +
+	"\x06status\x18\x01 \x01(\tR\x06status\x12\x1b\n" +
+	"\tworker_id\x18\x02 \x01(\tR\bworkerId\x12\x12\n" +
+	"\x04tags\x18\x03 \x03(\tR\x04tags\x12\x14\n" +
+	"\x05limit\x18\x04 \x01(\x05R\x05limit\x12\x16\n" +
+	"\x06cursor\x18\x05 \x01(\tR\x06cursor\"r\n" +
+	"\x10ListJobsResponse\x12'\n" +
+	"\x04jobs\x18\x01 \x03(\v2\x13.infinitrain.v1.JobR\x04jobs\x12\x14\n" +
+	"\x05total\x18\x02 \x01(\x05R\x05total\x12\x1f\n" +
+	"\vnext_cursor\x18\x03 \x01(\tR\n" +
+	"nextCursor\"\"\n" +
+	"\x10CancelJobRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\x13\n" +
+	"\x11CancelJobResponse\",\n" +
+	"\x13GetJobResultRequest\x12\x15\n" +
+	"\x06job_id\x18\x01 \x01(\tR\x05jobId\"\xe3\x01\n" +
+	"\x15RegisterWorkerRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1a\n" +
+	"\bcapacity\x18\x02 \x01(\x05R\bcapacity\x12I\n" +
+	"\x06labels\x18\x03 \x03(\v21.infinitrain.v1.RegisterWorkerRequest.LabelsEntryR\x06labels\x12\x18\n" +
+	"\aaddress\x18\x04 \x01(\tR\aaddress\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xe5\x01\n" +
+	"\x16RegisterWorkerResponse\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1a\n" +
+	"\bcapacity\x18\x02 \x01(\x05R\bcapacity\x12J\n" +
+	"\x06labels\x18\x03 \x03(\v22.infinitrain.v1.RegisterWorkerResponse.LabelsEntryR\x06labels\x12\x18\n" +
+	"\aaddress\x18\x04 \x01(\tR\aaddress\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"!\n" +
+	"\x0fWatchJobRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"s\n" +
+	"\x0fJobStatusUpdate\x12\x15\n" +
+	"\x06job_id\x18\x01 \x01(\tR\x05jobId\x12\x16\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\x12\x1b\n" +
+	"\tworker_id\x18\x03 \x01(\tR\bworkerId\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error2\xb0\x04\n" +
+	"\n" +
+	"JobService\x12B\n" +
+	"\tSubmitJob\x12 .infinitrain.v1.SubmitJobRequest\x1a\x13.infinitrain.v1.Job\x12<\n" +
+	"\x06GetJob\x12\x1d.infinitrain.v1.GetJobRequest\x1a\x13.infinitrain.v1.Job\x12M\n" +
+	"\bListJobs\x12\x1f.infinitrain.v1.ListJobsRequest\x1a .infinitrain.v1.ListJobsResponse\x12P\n" +
+	"\tCancelJob\x12 .infinitrain.v1.CancelJobRequest\x1a!.infinitrain.v1.CancelJobResponse\x12N\n" +
+	"\fGetJobResult\x12#.infinitrain.v1.GetJobResultRequest\x1a\x19.infinitrain.v1.JobResult\x12_\n" +
+	"\x0eRegisterWorker\x12%.infinitrain.v1.RegisterWorkerRequest\x1a&.infinitrain.v1.RegisterWorkerResponse\x12N\n" +
+	"\bWatchJob\x12\x1f.infinitrain.v1.WatchJobRequest\x1a\x1f.infinitrain.v1.JobStatusUpdate0\x01B\x14Z\x12infinitrain/pkg/pbb\x06proto3"
+
+var (
+	file_infinitrain_v1_jobs_proto_rawDescOnce sync.Once
+	file_infinitrain_v1_jobs_proto_rawDescData []byte
+)
+
+func file_infinitrain_v1_jobs_proto_rawDescGZIP() []byte {
+	file_infinitrain_v1_jobs_proto_rawDescOnce.Do(func() {
+		file_infinitrain_v1_jobs_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_infinitrain_v1_jobs_proto_rawDesc), len(file_infinitrain_v1_jobs_proto_rawDesc)))
+	})
+	return file_infinitrain_v1_jobs_proto_rawDescData
+}
+
+var file_infinitrain_v1_jobs_proto_msgTypes = make([]protoimpl.MessageInfo, 23)
+var file_infinitrain_v1_jobs_proto_goTypes = []any{
+	(*JobRequest)(nil),             // 0: infinitrain.v1.JobRequest
+	(*RetryPolicy)(nil),            // 1: infinitrain.v1.RetryPolicy
+	(*Job)(nil),                    // 2: infinitrain.v1.Job
+	(*JobResult)(nil),              // 3: infinitrain.v1.JobResult
+	(*SubmitJobRequest)(nil),       // 4: infinitrain.v1.SubmitJobRequest
+	(*GetJobRequest)(nil),          // 5: infinitrain.v1.GetJobRequest
+	(*ListJobsRequest)(nil),        // 6: infinitrain.v1.ListJobsRequest
+	(*ListJobsResponse)(nil),       // 7: infinitrain.v1.ListJobsResponse
+	(*CancelJobRequest)(nil),       // 8: infinitrain.v1.CancelJobRequest
+	(*CancelJobResponse)(nil),      // 9: infinitrain.v1.CancelJobResponse
+	(*GetJobResultRequest)(nil),    // 10: infinitrain.v1.GetJobResultRequest
+	(*RegisterWorkerRequest)(nil),  // 11: infinitrain.v1.RegisterWorkerRequest
+	(*RegisterWorkerResponse)(nil), // 12: infinitrain.v1.RegisterWorkerResponse
+	(*WatchJobRequest)(nil),        // 13: infinitrain.v1.WatchJobRequest
+	(*JobStatusUpdate)(nil),        // 14: infinitrain.v1.JobStatusUpdate
+	nil,                            // 15: infinitrain.v1.JobRequest.EnvironmentEntry
+	nil,                            // 16: infinitrain.v1.JobRequest.AnnotationsEntry
+	nil,                            // 17: infinitrain.v1.JobRequest.NodeSelectorEntry
+	nil,                            // 18: infinitrain.v1.Job.EnvironmentEntry
+	nil,                            // 19: infinitrain.v1.Job.AnnotationsEntry
+	nil,                            // 20: infinitrain.v1.Job.NodeSelectorEntry
+	nil,                            // 21: infinitrain.v1.RegisterWorkerRequest.LabelsEntry
+	nil,                            // 22: infinitrain.v1.RegisterWorkerResponse.LabelsEntry
+	(*durationpb.Duration)(nil),    // 23: google.protobuf.Duration
+	(*timestamppb.Timestamp)(nil),  // 24: google.protobuf.Timestamp
+}
+var file_infinitrain_v1_jobs_proto_depIdxs = []int32{
+	15, // 0: infinitrain.v1.JobRequest.environment:type_name -> infinitrain.v1.JobRequest.EnvironmentEntry
+	16, // 1: infinitrain.v1.JobRequest.annotations:type_name -> infinitrain.v1.JobRequest.AnnotationsEntry
+	17, // 2: infinitrain.v1.JobRequest.node_selector:type_name -> infinitrain.v1.JobRequest.NodeSelectorEntry
+	1,  // 3: infinitrain.v1.JobRequest.retry_policy:type_name -> infinitrain.v1.RetryPolicy
+	23, // 4: infinitrain.v1.RetryPolicy.base_delay:type_name -> google.protobuf.Duration
+	23, // 5: infinitrain.v1.RetryPolicy.max_delay:type_name -> google.protobuf.Duration
+	23, // 6: infinitrain.v1.Job.timeout:type_name -> google.protobuf.Duration
+	18, // 7: infinitrain.v1.Job.environment:type_name -> infinitrain.v1.Job.EnvironmentEntry
+	19, // 8: infinitrain.v1.Job.annotations:type_name -> infinitrain.v1.Job.AnnotationsEntry
+	20, // 9: infinitrain.v1.Job.node_selector:type_name -> infinitrain.v1.Job.NodeSelectorEntry
+	24, // 10: infinitrain.v1.Job.created_at:type_name -> google.protobuf.Timestamp
+	24, // 11: infinitrain.v1.Job.started_at:type_name -> google.protobuf.Timestamp
+	24, // 12: infinitrain.v1.Job.completed_at:type_name -> google.protobuf.Timestamp
+	24, // 13: infinitrain.v1.JobResult.started_at:type_name -> google.protobuf.Timestamp
+	24, // 14: infinitrain.v1.JobResult.completed_at:type_name -> google.protobuf.Timestamp
+	23, // 15: infinitrain.v1.JobResult.duration:type_name -> google.protobuf.Duration
+	0,  // 16: infinitrain.v1.SubmitJobRequest.job:type_name -> infinitrain.v1.JobRequest
+	2,  // 17: infinitrain.v1.ListJobsResponse.jobs:type_name -> infinitrain.v1.Job
+	21, // 18: infinitrain.v1.RegisterWorkerRequest.labels:type_name -> infinitrain.v1.RegisterWorkerRequest.LabelsEntry
+	22, // 19: infinitrain.v1.RegisterWorkerResponse.labels:type_name -> infinitrain.v1.RegisterWorkerResponse.LabelsEntry
+	4,  // 20: infinitrain.v1.JobService.SubmitJob:input_type -> infinitrain.v1.SubmitJobRequest
+	5,  // 21: infinitrain.v1.JobService.GetJob:input_type -> infinitrain.v1.GetJobRequest
+	6,  // 22: infinitrain.v1.JobService.ListJobs:input_type -> infinitrain.v1.ListJobsRequest
+	8,  // 23: infinitrain.v1.JobService.CancelJob:input_type -> infinitrain.v1.CancelJobRequest
+	10, // 24: infinitrain.v1.JobService.GetJobResult:input_type -> infinitrain.v1.GetJobResultRequest
+	11, // 25: infinitrain.v1.JobService.RegisterWorker:input_type -> infinitrain.v1.RegisterWorkerRequest
+	13, // 26: infinitrain.v1.JobService.WatchJob:input_type -> infinitrain.v1.WatchJobRequest
+	2,  // 27: infinitrain.v1.JobService.SubmitJob:output_type -> infinitrain.v1.Job
+	2,  // 28: infinitrain.v1.JobService.GetJob:output_type -> infinitrain.v1.Job
+	7,  // 29: infinitrain.v1.JobService.ListJobs:output_type -> infinitrain.v1.ListJobsResponse
+	9,  // 30: infinitrain.v1.JobService.CancelJob:output_type -> infinitrain.v1.CancelJobResponse
+	3,  // 31: infinitrain.v1.JobService.GetJobResult:output_type -> infinitrain.v1.JobResult
+	12, // 32: infinitrain.v1.JobService.RegisterWorker:output_type -> infinitrain.v1.RegisterWorkerResponse
+	14, // 33: infinitrain.v1.JobService.WatchJob:output_type -> infinitrain.v1.JobStatusUpdate
+	27, // [27:34] is the sub-list for method output_type
+	20, // [20:27] is the sub-list for method input_type
+	20, // [20:20] is the sub-list for extension type_name
+	20, // [20:20] is the sub-list for extension extendee
+	0,  // [0:20] is the sub-list for field type_name
+}
+
+func init() { file_infinitrain_v1_jobs_proto_init() }
+func file_infinitrain_v1_jobs_proto_init() {
+	if File_infinitrain_v1_jobs_proto != nil {
+		return
+	}
+	file_infinitrain_v1_jobs_proto_msgTypes[0].OneofWrappers = []any{}
+	file_infinitrain_v1_jobs_proto_msgTypes[1].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_infinitrain_v1_jobs_proto_rawDesc), len(file_infinitrain_v1_jobs_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   23,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_infinitrain_v1_jobs_proto_goTypes,
+		DependencyIndexes: file_infinitrain_v1_jobs_proto_depIdxs,
+		MessageInfos:      file_infinitrain_v1_jobs_proto_msgTypes,
+	}.Build()
+	File_infinitrain_v1_jobs_proto = out.File
+	file_infinitrain_v1_jobs_proto_goTypes = nil
+	file_infinitrain_v1_jobs_proto_depIdxs = nil
+}